@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml"
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/spf13/cobra"
+)
+
+var (
+	composeConfig string
+	composeOutput string
+)
+
+var composeCmd = &cobra.Command{
+	Use:     "compose",
+	Short:   "Fetch and compose the subgraph SDLs into a supergraph file for supergraph_file",
+	Example: `  go-graphql-federation-gateway compose --config gateway.yaml --out supergraph.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		b, err := os.ReadFile(composeConfig)
+		if err != nil {
+			return fmt.Errorf("failed to read gateway settings file: %w", err)
+		}
+		var settings gateway.GatewayOption
+		if err := yaml.Unmarshal(b, &settings); err != nil {
+			return fmt.Errorf("failed to unmarshal gateway settings: %w", err)
+		}
+
+		bundle, err := gateway.ComposeSupergraph(settings)
+		if err != nil {
+			return err
+		}
+		if err := bundle.WriteFile(composeOutput); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "wrote %s (schema hash %s)\n", composeOutput, bundle.SchemaHash)
+		return nil
+	},
+}
+
+func init() {
+	composeCmd.Flags().StringVar(&composeConfig, "config", "gateway.yaml", "gateway settings file")
+	composeCmd.Flags().StringVar(&composeOutput, "out", "supergraph.json", "output file")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/spf13/cobra"
+)
+
+var configFile string
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with gateway configuration files",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:     "validate",
+	Short:   "Check a gateway configuration file and report every problem with its line",
+	Example: `  go-graphql-federation-gateway config validate --file gateway.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		b, err := os.ReadFile(configFile)
+		if err != nil {
+			return fmt.Errorf("failed to read gateway settings file: %w", err)
+		}
+		if _, err := gateway.LoadGatewayOption(b); err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%s is invalid:\n%w", configFile, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", configFile)
+		return nil
+	},
+}
+
+func init() {
+	configValidateCmd.Flags().StringVar(&configFile, "file", "gateway.yaml", "gateway configuration file")
+	configCmd.AddCommand(configValidateCmd)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pinSupergraph    string
+	pinQueryFile     string
+	pinOperationName string
+	pinOutputDir     string
+)
+
+var pinCmd = &cobra.Command{
+	Use:     "pin",
+	Short:   "Plan an operation against a supergraph file and write the plan for pinned_plans",
+	Example: `  go-graphql-federation-gateway pin --supergraph supergraph.json --query-file topProducts.graphql --out-dir plans`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bundle, err := gateway.LoadSupergraphBundle(pinSupergraph)
+		if err != nil {
+			return err
+		}
+		query, err := os.ReadFile(pinQueryFile)
+		if err != nil {
+			return fmt.Errorf("failed to read query file: %w", err)
+		}
+
+		hash, plan, err := gateway.PinPlan(bundle, string(query), pinOperationName)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(pinOutputDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		path := filepath.Join(pinOutputDir, hash+".json")
+		if err := os.WriteFile(path, plan, 0o644); err != nil {
+			return fmt.Errorf("failed to write pinned plan: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "wrote %s (schema hash %s)\n", path, bundle.SchemaHash)
+		return nil
+	},
+}
+
+func init() {
+	pinCmd.Flags().StringVar(&pinSupergraph, "supergraph", "supergraph.json", "supergraph file written by compose")
+	pinCmd.Flags().StringVar(&pinQueryFile, "query-file", "", "file holding the exact query text clients send")
+	pinCmd.Flags().StringVar(&pinOperationName, "operation-name", "", "operation to plan when the query has several")
+	pinCmd.Flags().StringVar(&pinOutputDir, "out-dir", "plans", "pinned_plans directory")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+	"github.com/spf13/cobra"
+)
+
+var (
+	visualizeSubGraphs []string
+	visualizeQuery     string
+	visualizeFormat    string
+	visualizeCosts     bool
+)
+
+var visualizeCmd = &cobra.Command{
+	Use:   "visualize",
+	Short: "Export the composed supergraph or a query plan as DOT/Mermaid",
+	Example: `  go-graphql-federation-gateway visualize --subgraph products=products.graphql --subgraph reviews=reviews.graphql
+  go-graphql-federation-gateway visualize --subgraph products=products.graphql --query '{ topProducts { name } }' --format mermaid
+  go-graphql-federation-gateway visualize --subgraph products=products.graphql --query '{ topProducts { name } }' --costs`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, err := Visualize(visualizeSubGraphs, visualizeQuery, visualizeFormat, visualizeCosts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), out)
+		return nil
+	},
+}
+
+func init() {
+	visualizeCmd.Flags().StringArrayVar(&visualizeSubGraphs, "subgraph", nil, "subgraph SDL as name=path (repeatable)")
+	visualizeCmd.Flags().StringVar(&visualizeQuery, "query", "", "query to plan; when empty the supergraph is exported")
+	visualizeCmd.Flags().StringVar(&visualizeFormat, "format", "dot", "output format: dot or mermaid")
+	visualizeCmd.Flags().BoolVar(&visualizeCosts, "costs", false, "annotate the steps of the query plan with their estimated costs")
+}
+
+// Visualize composes the given subgraph SDL files and renders either the supergraph or,
+// when query is set, its query plan in the requested format, with the estimated cost of
+// every step when costs is set.
+func Visualize(subGraphFlags []string, query, format string, costs bool) (string, error) {
+	if len(subGraphFlags) == 0 {
+		return "", fmt.Errorf("at least one --subgraph name=path is required")
+	}
+
+	subGraphs := make([]*graph.SubGraphV2, 0, len(subGraphFlags))
+	for _, flag := range subGraphFlags {
+		name, path, ok := strings.Cut(flag, "=")
+		if !ok || name == "" || path == "" {
+			return "", fmt.Errorf("invalid --subgraph %q, expected name=path", flag)
+		}
+		sdl, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read SDL for subgraph %q: %w", name, err)
+		}
+		sg, err := graph.NewSubGraphV2(name, sdl, "")
+		if err != nil {
+			return "", fmt.Errorf("failed to build subgraph %q: %w", name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		return "", fmt.Errorf("composition failed: %w", err)
+	}
+
+	v := superGraph.Visualize()
+	title := "supergraph"
+	if query != "" {
+		p := parser.New(lexer.New(query))
+		doc := p.ParseDocument()
+		if len(p.Errors()) > 0 {
+			return "", fmt.Errorf("failed to parse query: %v", p.Errors())
+		}
+		queryPlanner := planner.NewPlannerV2(superGraph)
+		plan, err := queryPlanner.Plan(doc, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to plan query: %w", err)
+		}
+		v = plan.Visualize()
+		if costs {
+			v = queryPlanner.VisualizeWithCosts(plan)
+		}
+		title = "plan"
+	}
+
+	switch format {
+	case "dot":
+		return v.DOT(title), nil
+	case "mermaid":
+		return v.Mermaid(), nil
+	default:
+		return "", fmt.Errorf("unknown format %q, expected dot or mermaid", format)
+	}
+}
@@ -0,0 +1,126 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// contextGroup is the representations of an entity step sharing the same @fromContext
+// values, sent in one _entities request.
+type contextGroup struct {
+	variables       map[string]interface{}
+	representations []map[string]interface{}
+	positions       []int // Positions of the representations in the step
+}
+
+// processContextualEntityStep resolves an entity step with @fromContext arguments. The
+// arguments are variables of the whole _entities request, so representations are
+// grouped by their context values and each group is requested with its own values. The
+// results are reassembled in representation order, with the paths of subgraph errors
+// mapped to match. Such steps bypass the entity cache, whose entries are not keyed by
+// context.
+func (e *ExecutorV2) processContextualEntityStep(
+	ctx context.Context,
+	execCtx *ExecutionContext,
+	step *planner.StepV2,
+	set *representationSet,
+	variables map[string]interface{},
+) error {
+	var groups []*contextGroup
+	byValues := make(map[string]*contextGroup)
+	for i, rep := range set.representations {
+		key, err := json.Marshal(set.contexts[i])
+		if err != nil {
+			e.recordError(execCtx, step, fmt.Errorf("failed to encode context values: %w", err))
+			return err
+		}
+		group, ok := byValues[string(key)]
+		if !ok {
+			group = &contextGroup{variables: make(map[string]interface{}, len(variables)+len(set.contexts[i]))}
+			for k, v := range variables {
+				group.variables[k] = v
+			}
+			for k, v := range set.contexts[i] {
+				group.variables[k] = v
+			}
+			byValues[string(key)] = group
+			groups = append(groups, group)
+		}
+		group.representations = append(group.representations, rep)
+		group.positions = append(group.positions, i)
+	}
+
+	entities := make([]interface{}, len(set.representations))
+	var errs []interface{}
+	for _, group := range groups {
+		query, queryVars, err := e.buildQuery(step, group.representations, group.variables, execCtx.plan.OperationType)
+		if err != nil {
+			e.recordError(execCtx, step, fmt.Errorf("failed to build entity query: %w", err))
+			return err
+		}
+		result, err := e.fetchEntities(ctx, execCtx, step, query, queryVars)
+		if err != nil {
+			// Record error but continue with partial response
+			e.recordError(execCtx, step, err)
+			e.setNullForFailedStep(execCtx, step)
+			return nil
+		}
+
+		if data, ok := result["data"].(map[string]interface{}); ok {
+			fetched, _ := data["_entities"].([]interface{})
+			for j, entity := range fetched {
+				if j < len(group.positions) {
+					entities[group.positions[j]] = entity
+				}
+			}
+		}
+		groupErrs, _ := result["errors"].([]interface{})
+		for _, groupErr := range groupErrs {
+			errs = append(errs, mapEntityErrorPath(groupErr, func(index int) int {
+				if index >= 0 && index < len(group.positions) {
+					return group.positions[index]
+				}
+				return index
+			}))
+		}
+	}
+
+	result := map[string]interface{}{
+		"data": map[string]interface{}{"_entities": entities},
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+	e.storeStepResult(execCtx, step, result)
+	return nil
+}
+
+// contextValues returns the variables of the @fromContext arguments of step, selected
+// from ancestors, the objects at the prefixes of its insertion path. It returns nil and
+// the name of the field an ancestor lacks when a value cannot be selected.
+func contextValues(step *planner.StepV2, ancestors []map[string]interface{}) (map[string]interface{}, string) {
+	values := make(map[string]interface{}, len(step.Contexts))
+	for _, c := range step.Contexts {
+		if c.Depth >= len(ancestors) {
+			return nil, ""
+		}
+		for _, sel := range c.Selection {
+			field, ok := sel.(*ast.Field)
+			if !ok {
+				continue
+			}
+			name := field.Name.String()
+			value, exists := ancestors[c.Depth][name]
+			if !exists {
+				return nil, name
+			}
+			values[c.Variable] = projectValue(value, field.SelectionSet)
+			break
+		}
+	}
+	return values, ""
+}
@@ -0,0 +1,64 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// Endpoints are the URLs a subgraph serves each operation type on, e.g. read replicas
+// for queries and the primary for mutations. Empty URLs fall back to the host of the
+// subgraph.
+type Endpoints struct {
+	Query        string
+	Mutation     string
+	Subscription string // Subscribed to over a WebSocket; other operations sent to a ws:// or wss:// URL use http:// or https://
+}
+
+// URL returns the URL the steps of an operation of operationType are sent to. Entity
+// steps follow their operation, so the entities of a mutation are read from the same
+// endpoint that wrote them.
+func (e Endpoints) URL(operationType, host string) string {
+	var url string
+	switch operationType {
+	case string(ast.Mutation):
+		url = e.Mutation
+	case string(ast.Subscription):
+		url = httpURL(e.Subscription)
+	default:
+		url = e.Query
+	}
+	if url == "" {
+		return host
+	}
+	return url
+}
+
+// httpURL returns url with a WebSocket scheme replaced by its HTTP counterpart. The
+// executor sends every operation as an HTTP request, which GraphQL servers accept on
+// their WebSocket endpoint.
+func httpURL(url string) string {
+	switch {
+	case strings.HasPrefix(url, "ws://"):
+		return "http://" + strings.TrimPrefix(url, "ws://")
+	case strings.HasPrefix(url, "wss://"):
+		return "https://" + strings.TrimPrefix(url, "wss://")
+	}
+	return url
+}
+
+// SubscriptionURL returns the WebSocket URL subscriptions are sent to: the
+// Subscription URL, or host, with an HTTP scheme replaced by its WebSocket counterpart.
+func (e Endpoints) SubscriptionURL(host string) string {
+	url := e.Subscription
+	if url == "" {
+		url = host
+	}
+	switch {
+	case strings.HasPrefix(url, "http://"):
+		return "ws://" + strings.TrimPrefix(url, "http://")
+	case strings.HasPrefix(url, "https://"):
+		return "wss://" + strings.TrimPrefix(url, "https://")
+	}
+	return url
+}
@@ -0,0 +1,184 @@
+package executor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+// defaultEntityBatchConcurrency caps concurrent chunk requests when MaxConcurrency is unset.
+const defaultEntityBatchConcurrency = 4
+
+// EntityBatching splits the representations of an entity step into chunks sent as
+// separate _entities requests, for subgraphs that limit the request body size.
+type EntityBatching struct {
+	// MaxRepresentations is the largest number of representations sent in one request;
+	// zero or less sends them all at once.
+	MaxRepresentations int
+	// SubGraphMaxRepresentations overrides MaxRepresentations per subgraph name.
+	SubGraphMaxRepresentations map[string]int
+	// MaxConcurrency caps how many chunks of one step are requested at once.
+	MaxConcurrency int
+}
+
+// chunkSize returns the number of representations sent per request to subGraphName,
+// or zero when its requests are not chunked.
+func (b *EntityBatching) chunkSize(subGraphName string) int {
+	if b == nil {
+		return 0
+	}
+	if size, ok := b.SubGraphMaxRepresentations[subGraphName]; ok {
+		return size
+	}
+	return b.MaxRepresentations
+}
+
+func (b *EntityBatching) concurrency() int {
+	if b.MaxConcurrency <= 0 {
+		return defaultEntityBatchConcurrency
+	}
+	return b.MaxConcurrency
+}
+
+// fetchEntities sends the _entities query of step, split into chunks of representations
+// when entity batching applies to its subgraph. The chunk results are reassembled in
+// representation order, with the paths of subgraph errors shifted to match. A chunk that
+// fails leaves its entities null and is reported as an error; the step fails only when
+// every chunk does.
+func (e *ExecutorV2) fetchEntities(
+	ctx context.Context,
+	execCtx *ExecutionContext,
+	step *planner.StepV2,
+	query string,
+	queryVars map[string]interface{},
+) (map[string]interface{}, error) {
+	representations, _ := queryVars["representations"].([]map[string]interface{})
+	size := e.EntityBatching.chunkSize(step.SubGraph.Name)
+	if size <= 0 || len(representations) <= size {
+		return e.fetch(ctx, execCtx, step, query, queryVars)
+	}
+
+	type chunkResult struct {
+		result map[string]interface{}
+		err    error
+	}
+	starts := make([]int, 0, (len(representations)+size-1)/size)
+	for start := 0; start < len(representations); start += size {
+		starts = append(starts, start)
+	}
+	results := make([]chunkResult, len(starts))
+
+	sem := make(chan struct{}, e.EntityBatching.concurrency())
+	var wg sync.WaitGroup
+	for i, start := range starts {
+		end := min(start+size, len(representations))
+		vars := make(map[string]interface{}, len(queryVars))
+		for k, v := range queryVars {
+			vars[k] = v
+		}
+		vars["representations"] = representations[start:end]
+
+		wg.Add(1)
+		go func(i int, vars map[string]interface{}) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result, err := e.fetch(ctx, execCtx, step, query, vars)
+			results[i] = chunkResult{result: result, err: err}
+		}(i, vars)
+	}
+	wg.Wait()
+
+	entities := make([]interface{}, len(representations))
+	var errs []interface{}
+	var firstErr error
+	failed := 0
+	for i, start := range starts {
+		chunk := results[i]
+		if chunk.err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = chunk.err
+			}
+			errs = append(errs, map[string]interface{}{
+				"message": chunk.err.Error(),
+				"path":    []interface{}{"_entities", start},
+			})
+			continue
+		}
+
+		if data, ok := chunk.result["data"].(map[string]interface{}); ok {
+			fetched, _ := data["_entities"].([]interface{})
+			for j, entity := range fetched {
+				if start+j < len(entities) {
+					entities[start+j] = entity
+				}
+			}
+		}
+		chunkErrs, _ := chunk.result["errors"].([]interface{})
+		for _, chunkErr := range chunkErrs {
+			errs = append(errs, shiftEntityErrorPath(chunkErr, start))
+		}
+	}
+	if failed == len(starts) {
+		return nil, firstErr
+	}
+
+	result := map[string]interface{}{
+		"data": map[string]interface{}{"_entities": entities},
+	}
+	if len(errs) > 0 {
+		result["errors"] = errs
+	}
+	return result, nil
+}
+
+// shiftEntityErrorPath offsets the _entities index in the path of a subgraph error by
+// the position of its chunk in the step's representations.
+func shiftEntityErrorPath(err interface{}, offset int) interface{} {
+	if offset == 0 {
+		return err
+	}
+	return mapEntityErrorPath(err, func(index int) int { return index + offset })
+}
+
+// mapEntityErrorPath rewrites the _entities index in the path of a subgraph error with
+// position.
+func mapEntityErrorPath(err interface{}, position func(int) int) interface{} {
+	errMap, ok := err.(map[string]interface{})
+	if !ok {
+		return err
+	}
+	path, _ := errMap["path"].([]interface{})
+	if len(path) < 2 || path[0] != "_entities" {
+		return err
+	}
+
+	var index int
+	switch v := path[1].(type) {
+	case json.Number:
+		i, parseErr := v.Int64()
+		if parseErr != nil {
+			return err
+		}
+		index = int(i)
+	case float64:
+		index = int(v)
+	case int:
+		index = v
+	default:
+		return err
+	}
+
+	shifted := make(map[string]interface{}, len(errMap))
+	for k, v := range errMap {
+		shifted[k] = v
+	}
+	newPath := make([]interface{}, len(path))
+	copy(newPath, path)
+	newPath[1] = position(index)
+	shifted["path"] = newPath
+	return shifted
+}
@@ -0,0 +1,421 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// EntityCache caches entities returned by subgraph _entities requests, keyed by
+// typename, key fields and a hash of the selection requested from the subgraph and of
+// the headers that can change its answer (see entityCacheVaryHeaders), so entities
+// fetched with one client's credentials are never served to another.
+// TTLs come from @cacheControl(maxAge:) hints in the subgraph schema, falling back to
+// DefaultTTL; entities without a positive TTL or with scope PRIVATE are not cached.
+type EntityCache struct {
+	DefaultTTL time.Duration // TTL for entities without a cache hint; zero disables caching them
+	MaxEntries int           // Maximum number of cached entities; zero means unlimited
+
+	mu      sync.Mutex
+	entries map[string]*entityCacheEntry
+	// byEntity indexes cache keys by typename and key fields for invalidation.
+	byEntity map[string]map[string]map[string]struct{}
+	now      func() time.Time
+}
+
+type entityCacheEntry struct {
+	typeName  string
+	entityKey string
+	entity    map[string]interface{}
+	expiresAt time.Time
+}
+
+// NewEntityCache creates an empty entity cache.
+func NewEntityCache(defaultTTL time.Duration, maxEntries int) *EntityCache {
+	return &EntityCache{
+		DefaultTTL: defaultTTL,
+		MaxEntries: maxEntries,
+		entries:    make(map[string]*entityCacheEntry),
+		byEntity:   make(map[string]map[string]map[string]struct{}),
+		now:        time.Now,
+	}
+}
+
+// get returns a copy of the cached entity stored under key, if it has not expired.
+func (c *EntityCache) get(key string) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(key)
+		return nil, false
+	}
+	return deepCopyMap(entry.entity), true
+}
+
+// set stores a copy of entity under key for ttl.
+func (c *EntityCache) set(key, typeName, entityKey string, entity map[string]interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && c.MaxEntries > 0 && len(c.entries) >= c.MaxEntries {
+		c.evict()
+	}
+
+	c.entries[key] = &entityCacheEntry{
+		typeName:  typeName,
+		entityKey: entityKey,
+		entity:    deepCopyMap(entity),
+		expiresAt: c.now().Add(ttl),
+	}
+	if c.byEntity[typeName] == nil {
+		c.byEntity[typeName] = make(map[string]map[string]struct{})
+	}
+	if c.byEntity[typeName][entityKey] == nil {
+		c.byEntity[typeName][entityKey] = make(map[string]struct{})
+	}
+	c.byEntity[typeName][entityKey][key] = struct{}{}
+}
+
+// evict drops expired entries, or an arbitrary entry when none has expired.
+// The caller must hold c.mu.
+func (c *EntityCache) evict() {
+	now := c.now()
+	evicted := false
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			c.remove(key)
+			evicted = true
+		}
+	}
+	if evicted {
+		return
+	}
+	for key := range c.entries {
+		c.remove(key)
+		return
+	}
+}
+
+// remove deletes key and its index entries. The caller must hold c.mu.
+func (c *EntityCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	if keys := c.byEntity[entry.typeName][entry.entityKey]; keys != nil {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.byEntity[entry.typeName], entry.entityKey)
+		}
+	}
+	if len(c.byEntity[entry.typeName]) == 0 {
+		delete(c.byEntity, entry.typeName)
+	}
+}
+
+// Invalidate purges the cached entities of typeName whose key fields equal key, for every
+// selection. A nil key purges all entities of typeName. It returns the number of purged entries.
+func (c *EntityCache) Invalidate(typeName string, key map[string]interface{}) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var cacheKeys []string
+	if key == nil {
+		for _, keys := range c.byEntity[typeName] {
+			for cacheKey := range keys {
+				cacheKeys = append(cacheKeys, cacheKey)
+			}
+		}
+	} else {
+		representation := map[string]interface{}{"__typename": typeName}
+		for k, v := range key {
+			representation[k] = v
+		}
+		for cacheKey := range c.byEntity[typeName][entityCacheEntityKey(representation)] {
+			cacheKeys = append(cacheKeys, cacheKey)
+		}
+	}
+
+	for _, cacheKey := range cacheKeys {
+		c.remove(cacheKey)
+	}
+	return len(cacheKeys)
+}
+
+// Purge removes every cached entity and returns the number of purged entries.
+func (c *EntityCache) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.entries)
+	c.entries = make(map[string]*entityCacheEntry)
+	c.byEntity = make(map[string]map[string]map[string]struct{})
+	return n
+}
+
+// Len returns the number of cached entities, including expired ones not yet evicted.
+func (c *EntityCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// processCachedEntityStep resolves an entity step through the entity cache: cached
+// entities are reused and only the missing representations are sent to the subgraph.
+// Fetched entities are cached unless the subgraph response carries errors.
+func (e *ExecutorV2) processCachedEntityStep(
+	ctx context.Context,
+	execCtx *ExecutionContext,
+	step *planner.StepV2,
+	representations []map[string]interface{},
+	variables map[string]interface{},
+) error {
+	query, queryVars, err := e.buildQuery(step, representations, variables, execCtx.plan.OperationType)
+	if err != nil {
+		e.recordError(execCtx, step, fmt.Errorf("failed to build entity query: %w", err))
+		return err
+	}
+
+	selectionHash := entityCacheSelectionHash(step.SubGraph.Name, query, queryVars, entityCacheVaryHeaders(ctx))
+	entities := make([]interface{}, len(representations))
+	entityKeys := make([]string, len(representations))
+	missing := make([]int, 0, len(representations))
+	for i, rep := range representations {
+		entityKeys[i] = entityCacheEntityKey(rep)
+		if entity, ok := e.EntityCache.get(selectionHash + entityKeys[i]); ok {
+			entities[i] = entity
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if stats := GetExecutionStatsFromContext(ctx); stats != nil {
+		stats.recordCache(len(representations)-len(missing), len(missing))
+	}
+
+	result := map[string]interface{}{}
+	if len(missing) > 0 {
+		missingReps := make([]map[string]interface{}, 0, len(missing))
+		for _, i := range missing {
+			missingReps = append(missingReps, representations[i])
+		}
+		queryVars["representations"] = missingReps
+
+		result, err = e.fetchEntities(ctx, execCtx, step, query, queryVars)
+		if err != nil {
+			e.recordError(execCtx, step, err)
+			e.setNullForFailedStep(execCtx, step)
+			return nil // Don't propagate error, allow partial response
+		}
+
+		var fetched []interface{}
+		if data, ok := result["data"].(map[string]interface{}); ok {
+			fetched, _ = data["_entities"].([]interface{})
+		}
+		_, hasErrors := result["errors"]
+		ttl := entityCacheTTL(step, e.EntityCache.DefaultTTL)
+		for j, i := range missing {
+			if j >= len(fetched) {
+				break
+			}
+			entities[i] = fetched[j]
+			if entity, ok := fetched[j].(map[string]interface{}); ok && !hasErrors && ttl > 0 {
+				e.EntityCache.set(selectionHash+entityKeys[i], step.ParentType, entityKeys[i], entity, ttl)
+			}
+		}
+	}
+
+	// Results are merged positionally, so the response lists every representation.
+	merged := make(map[string]interface{}, len(result)+1)
+	for k, v := range result {
+		merged[k] = v
+	}
+	merged["data"] = map[string]interface{}{"_entities": entities}
+
+	e.storeStepResult(execCtx, step, merged)
+	return nil
+}
+
+// entityCacheEntityKey identifies an entity by its representation (typename and key
+// fields). Map keys are marshalled in sorted order, so the result is canonical.
+func entityCacheEntityKey(representation map[string]interface{}) string {
+	b, err := json.Marshal(representation)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// entityCacheCredentialHeaders are the client request headers carrying credentials.
+var entityCacheCredentialHeaders = []string{"Authorization", "Cookie"}
+
+// entityCacheVaryHeaders returns the headers of the request of ctx that can change the
+// entities a subgraph returns: those sent with every subgraph request, and the client's
+// credentials when its request headers are handed over. Headers added by transports
+// (e.g. subgraph request hooks) are not seen; fields depending on them must be marked
+// @cacheControl(scope: PRIVATE).
+func entityCacheVaryHeaders(ctx context.Context) http.Header {
+	vary := make(http.Header)
+	for k, v := range GetSubgraphHeadersFromContext(ctx) {
+		vary[k] = v
+	}
+	requestHeader := GetRequestHeaderFromContext(ctx)
+	for _, k := range entityCacheCredentialHeaders {
+		if v := requestHeader.Values(k); len(v) > 0 {
+			vary[k] = v
+		}
+	}
+	return vary
+}
+
+// entityCacheSelectionHash hashes what a step requests for each entity: the subgraph,
+// the selection of the built query, the variables forwarded with it and the headers the
+// answer varies on.
+func entityCacheSelectionHash(subGraphName, query string, variables map[string]interface{}, header http.Header) string {
+	forwarded := make(map[string]interface{}, len(variables))
+	for k, v := range variables {
+		if k != "representations" {
+			forwarded[k] = v
+		}
+	}
+	vars, _ := json.Marshal(forwarded)
+	headers, _ := json.Marshal(header)
+
+	h := sha256.New()
+	h.Write([]byte(subGraphName))
+	h.Write([]byte{0})
+	h.Write([]byte(query))
+	h.Write([]byte{0})
+	h.Write(vars)
+	h.Write([]byte{0})
+	h.Write(headers)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// entityCacheTTL returns how long entities fetched by step may be cached: the smallest
+// @cacheControl(maxAge:) of the entity type and the fields selected on it in the step's
+// subgraph schema, or defaultTTL when there is no hint. PRIVATE scope disables caching.
+func entityCacheTTL(step *planner.StepV2, defaultTTL time.Duration) time.Duration {
+	if step.SubGraph == nil || step.SubGraph.Schema == nil {
+		return defaultTTL
+	}
+
+	selected := make(map[string]bool)
+	for _, sel := range step.SelectionSet {
+		if field, ok := sel.(*ast.Field); ok {
+			selected[field.Name.String()] = true
+		}
+	}
+
+	maxAge := -1
+	private := false
+	apply := func(directives []*ast.Directive) {
+		age, isPrivate, ok := cacheControlHint(directives)
+		if !ok {
+			return
+		}
+		if isPrivate {
+			private = true
+		}
+		if age >= 0 && (maxAge < 0 || age < maxAge) {
+			maxAge = age
+		}
+	}
+
+	for _, def := range step.SubGraph.Schema.Definitions {
+		var directives []*ast.Directive
+		var fields []*ast.FieldDefinition
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			if d.Name.String() != step.ParentType {
+				continue
+			}
+			directives, fields = d.Directives, d.Fields
+		case *ast.ObjectTypeExtension:
+			if d.Name.String() != step.ParentType {
+				continue
+			}
+			directives, fields = d.Directives, d.Fields
+		default:
+			continue
+		}
+
+		apply(directives)
+		for _, field := range fields {
+			if selected[field.Name.String()] {
+				apply(field.Directives)
+			}
+		}
+	}
+
+	if private {
+		return 0
+	}
+	if maxAge < 0 {
+		return defaultTTL
+	}
+	return time.Duration(maxAge) * time.Second
+}
+
+// cacheControlHint reads a @cacheControl directive. maxAge is -1 when not given.
+func cacheControlHint(directives []*ast.Directive) (maxAge int, private bool, ok bool) {
+	for _, d := range directives {
+		if d.Name != "cacheControl" {
+			continue
+		}
+		maxAge = -1
+		for _, arg := range d.Arguments {
+			value := strings.Trim(arg.Value.String(), "\"")
+			switch arg.Name.String() {
+			case "maxAge":
+				if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+					maxAge = n
+				}
+			case "scope":
+				private = value == "PRIVATE"
+			}
+		}
+		return maxAge, private, true
+	}
+	return -1, false, false
+}
+
+// deepCopyMap copies m and every nested map and slice.
+func deepCopyMap(m map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		copied[k] = deepCopyValue(v)
+	}
+	return copied
+}
+
+func deepCopyValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(value)
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, item := range value {
+			copied[i] = deepCopyValue(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"log"
+	"regexp"
+)
+
+// RedactedErrorMessage replaces the message of redacted subgraph errors without a
+// message of their own.
+const RedactedErrorMessage = "Internal server error"
+
+// ErrorRule classifies the subgraph errors it matches: errors whose extensions.code is
+// MatchCode, when set, and whose message matches MatchMessage, when set.
+type ErrorRule struct {
+	MatchCode    string
+	MatchMessage *regexp.Regexp
+
+	Code      string // Code given to matching errors; empty keeps theirs
+	Message   string // Message given to matching errors; empty keeps theirs unless redacted
+	Retryable bool   // Whether the operation may succeed when sent again, set as extensions.retryable
+	Redact    bool   // Hide the message and extensions of matching errors from clients; they are logged
+}
+
+// ErrorMapping rewrites the errors of one subgraph with the first rule matching each.
+type ErrorMapping struct {
+	Rules           []ErrorRule
+	RedactUnmatched bool // Redact the errors no rule matches
+	// Retries is the number of times a query or an entity request is sent again when
+	// its response only has retryable errors. Mutations are never retried.
+	Retries int
+}
+
+// match returns the first rule matching err, or nil.
+func (m *ErrorMapping) match(err GraphQLError) *ErrorRule {
+	code, _ := err.Extensions["code"].(string)
+	for i := range m.Rules {
+		rule := &m.Rules[i]
+		if rule.MatchCode != "" && rule.MatchCode != code {
+			continue
+		}
+		if rule.MatchMessage != nil && !rule.MatchMessage.MatchString(err.Message) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// apply rewrites err, an error returned by subGraphName, with its rule. Redacted errors
+// keep their code and the subgraph name; the original error is logged.
+func (m *ErrorMapping) apply(subGraphName string, err GraphQLError) GraphQLError {
+	if m == nil {
+		return err
+	}
+	rule := m.match(err)
+	redact := m.RedactUnmatched
+	if rule != nil {
+		redact = rule.Redact
+	}
+
+	if redact {
+		log.Printf("redacted error of subgraph %q: %s (extensions: %v)", subGraphName, err.Message, err.Extensions)
+		redacted := map[string]interface{}{"serviceName": subGraphName}
+		if code, ok := err.Extensions["code"]; ok {
+			redacted["code"] = code
+		}
+		err.Message = RedactedErrorMessage
+		err.Extensions = redacted
+	}
+	if rule == nil {
+		return err
+	}
+	if rule.Code != "" {
+		err.Extensions["code"] = rule.Code
+	}
+	if rule.Message != "" {
+		err.Message = rule.Message
+	}
+	err.Extensions["retryable"] = rule.Retryable
+	return err
+}
+
+// retryable reports whether result has errors that are all retryable.
+func (m *ErrorMapping) retryable(result map[string]interface{}) bool {
+	errs, _ := result["errors"].([]interface{})
+	if len(errs) == 0 {
+		return false
+	}
+	for _, item := range errs {
+		errMap, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		message, _ := errMap["message"].(string)
+		extensions, _ := errMap["extensions"].(map[string]interface{})
+		rule := m.match(GraphQLError{Message: message, Extensions: extensions})
+		if rule == nil || !rule.Retryable {
+			return false
+		}
+	}
+	return true
+}
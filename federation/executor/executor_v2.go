@@ -3,11 +3,14 @@ package executor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/goccy/go-json"
 
@@ -24,12 +27,72 @@ type GraphQLError struct {
 	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
+// UnfetchableFieldErrorCode is the extensions.code of the errors reported for root fields
+// pruned from the plan because no subgraph resolves them.
+const UnfetchableFieldErrorCode = "UNFETCHABLE_FIELD"
+
+// InternalServerErrorCode is the extensions.code of the errors recorded when the gateway
+// fails to build a subgraph request or to merge its response.
+const InternalServerErrorCode = "INTERNAL_SERVER_ERROR"
+
+// SubgraphHTTPErrorCode is the extensions.code of the errors recorded for subgraph
+// requests that failed at the HTTP level.
+const SubgraphHTTPErrorCode = "SUBGRAPH_HTTP_ERROR"
+
+// SubgraphHTTPError is a subgraph request that could not be sent, or whose response
+// could not be read as a GraphQL response.
+type SubgraphHTTPError struct {
+	StatusCode int // Status of the response; 0 when none was received
+	Err        error
+}
+
+func (e *SubgraphHTTPError) Error() string { return e.Err.Error() }
+
+func (e *SubgraphHTTPError) Unwrap() error { return e.Err }
+
+// ErrOperationCancelled is returned by Execute when its context is cancelled, e.g.
+// because the client closed the connection. Outstanding subgraph requests are aborted
+// and the remaining steps are skipped.
+var ErrOperationCancelled = errors.New("operation cancelled")
+
 // ExecutorV2 executes a query plan by orchestrating requests to subgraphs.
 type ExecutorV2 struct {
 	httpClient   *http.Client
 	pool         sync.Pool
 	queryBuilder *QueryBuilderV2
 	superGraph   *graph.SuperGraphV2
+
+	// EntityCache serves entity steps from cached _entities results when set.
+	EntityCache *EntityCache
+
+	// SubGraphClients maps subgraph name → client used for its requests; subgraphs
+	// without an entry use the client given to NewExecutorV2.
+	SubGraphClients map[string]*http.Client
+
+	// Failover maps subgraph name → fallback hosts tried when its host fails.
+	Failover map[string]*Failover
+
+	// Endpoints maps subgraph name → URLs used instead of its host per operation type.
+	Endpoints map[string]Endpoints
+
+	// EntityBatching splits large _entities requests into chunks when set.
+	EntityBatching *EntityBatching
+
+	// Limiter bounds the concurrent subgraph requests when set.
+	Limiter *RequestLimiter
+
+	// Faults injects failures into subgraph requests when set.
+	Faults *FaultInjector
+
+	// Verifier checks subgraph responses against their subgraph schema when set.
+	Verifier *ResponseVerifier
+
+	// ErrorMapping maps subgraph name → rules rewriting and classifying its errors.
+	ErrorMapping map[string]*ErrorMapping
+
+	// SharedFragments writes the selection sets repeated in a subgraph query once, as
+	// fragments, when set.
+	SharedFragments bool
 }
 
 // NewExecutorV2 creates a new ExecutorV2 instance.
@@ -39,8 +102,12 @@ func NewExecutorV2(httpClient *http.Client, superGraph *graph.SuperGraphV2) *Exe
 		pool: sync.Pool{
 			New: func() interface{} {
 				return &ExecutionContext{
-					results: make(map[int]interface{}),
-					errors:  make([]GraphQLError, 0, 8), // Pre-allocate small capacity
+					results:     make(map[int]interface{}),
+					errors:      make([]GraphQLError, 0, 8), // Pre-allocate small capacity
+					fetches:     make(map[int]*FetchTrace),
+					skipped:     make(map[int][]int),
+					batches:     make(map[int][]int),
+					unreachable: make(map[int]bool),
 				}
 			},
 		},
@@ -49,13 +116,30 @@ func NewExecutorV2(httpClient *http.Client, superGraph *graph.SuperGraphV2) *Exe
 	}
 }
 
+// buildQuery builds the query of step with the query builder, sharing fragments when
+// SharedFragments is set.
+func (e *ExecutorV2) buildQuery(step *planner.StepV2, representations []map[string]interface{}, variables map[string]interface{}, operationType string) (string, map[string]interface{}, error) {
+	if e.SharedFragments {
+		return e.queryBuilder.BuildSharingFragments(step, representations, variables, operationType)
+	}
+	return e.queryBuilder.Build(step, representations, variables, operationType)
+}
+
 // ExecutionContext holds the execution state.
 type ExecutionContext struct {
 	ctx     context.Context
 	plan    *planner.PlanV2
 	results map[int]interface{} // Step ID -> Result
 	errors  []GraphQLError      // Accumulated errors
-	mu      sync.RWMutex
+	fetches map[int]*FetchTrace // Step ID -> fetch trace (federated tracing only)
+	skipped map[int][]int       // Step ID -> positions of the objects sent without a representation
+	batches map[int][]int       // Step ID -> end position of the objects of each insertion path of a batched step
+	// unreachable holds the root steps whose fetch failed without a response.
+	unreachable map[int]bool
+	mu          sync.RWMutex
+
+	// preset maps step ID → result received before execution, e.g. a subscription event.
+	preset map[int]map[string]interface{}
 }
 
 // Execute executes a query plan and returns the merged result.
@@ -64,6 +148,17 @@ func (e *ExecutorV2) Execute(
 	ctx context.Context,
 	plan *planner.PlanV2,
 	variables map[string]interface{},
+) (map[string]interface{}, error) {
+	return e.execute(ctx, plan, variables, nil)
+}
+
+// execute implements Execute. The steps with a preset result store it instead of
+// fetching it from their subgraph.
+func (e *ExecutorV2) execute(
+	ctx context.Context,
+	plan *planner.PlanV2,
+	variables map[string]interface{},
+	preset map[int]map[string]interface{},
 ) (map[string]interface{}, error) {
 	// Validate DAG
 	if err := e.validateDAG(plan); err != nil {
@@ -76,18 +171,34 @@ func (e *ExecutorV2) Execute(
 		// Clear context before returning to pool to prevent memory leaks
 		execCtx.ctx = nil
 		execCtx.plan = nil
+		execCtx.preset = nil
 		// Clear map entries (reuse underlying storage)
 		for k := range execCtx.results {
 			delete(execCtx.results, k)
 		}
 		// Reset slice but keep capacity
 		execCtx.errors = execCtx.errors[:0]
+		for k := range execCtx.fetches {
+			delete(execCtx.fetches, k)
+		}
+		for k := range execCtx.skipped {
+			delete(execCtx.skipped, k)
+		}
+		for k := range execCtx.batches {
+			delete(execCtx.batches, k)
+		}
+		for k := range execCtx.unreachable {
+			delete(execCtx.unreachable, k)
+		}
 		e.pool.Put(execCtx)
 	}()
 
+	start := time.Now()
+
 	// Set context and plan
 	execCtx.ctx = ctx
 	execCtx.plan = plan
+	execCtx.preset = preset
 
 	// Clear results and errors (should already be cleared from previous use)
 	for k := range execCtx.results {
@@ -95,8 +206,27 @@ func (e *ExecutorV2) Execute(
 	}
 	execCtx.errors = execCtx.errors[:0]
 
-	// Execute root steps (don't fail on error, collect them)
-	_ = e.executeSteps(execCtx, plan.RootStepIndexes, variables)
+	// Execute root steps (don't fail on error, collect them).
+	// Root mutation fields must execute serially in document order, so each
+	// mutation root step (and its entity steps) completes before the next starts.
+	if plan.OperationType == string(ast.Mutation) {
+		for _, stepID := range plan.RootStepIndexes {
+			_ = e.executeSteps(execCtx, []int{stepID}, variables)
+		}
+	} else {
+		_ = e.executeSteps(execCtx, plan.RootStepIndexes, variables)
+	}
+
+	// The client went away: nobody reads the response, so don't build it
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, fmt.Errorf("%w: %w", ErrOperationCancelled, ctx.Err())
+	}
+
+	if IsPlanFailureEnabled(ctx) {
+		if failure := e.planFailure(execCtx); failure != nil {
+			return nil, failure
+		}
+	}
 
 	// Build final response from root step results
 	response := make(map[string]interface{})
@@ -111,12 +241,28 @@ func (e *ExecutorV2) Execute(
 		if stepData, ok := stepResult.(map[string]interface{}); ok {
 			if stepDataMap, ok := stepData["data"].(map[string]interface{}); ok {
 				for k, v := range stepDataMap {
+					// A root field fanned out to several subgraphs is in several results
+					if existing, ok := data[k]; ok {
+						v = mergeFannedOut(existing, v)
+					}
 					data[k] = v
 				}
 			}
 		}
 	}
 
+	// Fields pruned by the planner resolve to null with an error saying why
+	for _, field := range plan.PrunedFields {
+		data[field.ResponseKey] = nil
+		execCtx.mu.Lock()
+		execCtx.errors = append(execCtx.errors, GraphQLError{
+			Message:    fmt.Sprintf("field %s is not resolved by any subgraph and was skipped", field.Coordinate),
+			Path:       []interface{}{field.ResponseKey},
+			Extensions: map[string]interface{}{"code": UnfetchableFieldErrorCode},
+		})
+		execCtx.mu.Unlock()
+	}
+
 	response["data"] = data
 
 	// Add errors if any occurred
@@ -127,7 +273,35 @@ func (e *ExecutorV2) Execute(
 	execCtx.mu.RUnlock()
 
 	// Prune response to remove fields not requested in original query
-	return e.pruneResponse(response, plan), nil
+	pruned := e.pruneResponse(response, plan, IsKeepTypenameEnabled(ctx))
+
+	// Attach the stitched federated trace when ftv1 collection is enabled
+	if IsFederatedTracingEnabled(ctx) {
+		pruned["extensions"] = map[string]interface{}{
+			FederatedTraceExtension: e.buildFederatedTrace(execCtx, start, time.Now()),
+		}
+	}
+
+	return pruned, nil
+}
+
+// mergeFannedOut merges the answers of two subgraphs to a root field fanned out to both,
+// such as a Relay node field: each answers null for the objects of the other's types, so
+// the non-null answer is kept, element by element for lists of equal length. When both
+// answered, the first one wins.
+func mergeFannedOut(existing, v interface{}) interface{} {
+	if existing == nil {
+		return v
+	}
+	existingList, ok := existing.([]interface{})
+	list, isList := v.([]interface{})
+	if !ok || !isList || len(existingList) != len(list) {
+		return existing
+	}
+	for i := range existingList {
+		existingList[i] = mergeFannedOut(existingList[i], list[i])
+	}
+	return existingList
 }
 
 // validateDAG validates that the plan is a directed acyclic graph (no cycles).
@@ -188,6 +362,10 @@ func (e *ExecutorV2) executeSteps(
 	if len(stepIDs) == 0 {
 		return nil
 	}
+	// Skip the remaining waves once the operation is cancelled
+	if err := execCtx.ctx.Err(); errors.Is(err, context.Canceled) {
+		return err
+	}
 
 	// Execute all steps in this group in parallel
 	eg, ctx := errgroup.WithContext(execCtx.ctx)
@@ -251,6 +429,12 @@ func (e *ExecutorV2) processStep(
 	step *planner.StepV2,
 	variables map[string]interface{},
 ) error {
+	// Representations steps are resolved locally from the plan input
+	if step.StepType == planner.StepTypeRepresentations {
+		e.storeRepresentations(execCtx, step)
+		return nil
+	}
+
 	// Guard against nil subgraph
 	if step.SubGraph == nil {
 		err := fmt.Errorf("step %d has nil subgraph", step.ID)
@@ -258,44 +442,125 @@ func (e *ExecutorV2) processStep(
 		return err
 	}
 
+	if result, ok := execCtx.preset[step.ID]; ok {
+		e.storeVerifiedResult(execCtx, step, result)
+		return nil
+	}
+
 	var query string
 	var queryVars map[string]interface{}
 	var err error
 
 	if step.StepType == planner.StepTypeQuery {
 		// Root query - pass operation type from plan
-		query, queryVars, err = e.queryBuilder.Build(step, nil, variables, execCtx.plan.OperationType)
+		query, queryVars, err = e.buildQuery(step, nil, variables, execCtx.plan.OperationType)
 		if err != nil {
 			e.recordError(execCtx, step, fmt.Errorf("failed to build root query: %w", err))
 			return err
 		}
 	} else {
 		// Entity query - need to extract representations from parent results
-		representations := e.extractRepresentations(execCtx, step)
+		set := e.extractRepresentations(execCtx, step)
+		if len(set.missing) > 0 {
+			e.recordError(execCtx, step, fmt.Errorf("cannot resolve %s fields from %s: required field %s is missing", step.ParentType, step.SubGraph.Name, strings.Join(set.missing, ", ")))
+		}
+		if len(set.skipped) > 0 {
+			execCtx.mu.Lock()
+			execCtx.skipped[step.ID] = set.skipped
+			execCtx.mu.Unlock()
+		}
+		if len(step.BatchedPaths) > 0 {
+			execCtx.mu.Lock()
+			execCtx.batches[step.ID] = set.ends
+			execCtx.mu.Unlock()
+		}
+		representations := set.representations
 		if len(representations) == 0 {
-			// No entities to fetch, skip this step
+			// No entities to fetch, skip this step; the objects without a representation
+			// still get their fields set to null
+			result := map[string]interface{}{"data": map[string]interface{}{}}
+			if len(set.skipped) > 0 {
+				result["data"] = map[string]interface{}{"_entities": []interface{}{}}
+				e.storeVerifiedResult(execCtx, step, result)
+				return nil
+			}
 			execCtx.mu.Lock()
-			execCtx.results[step.ID] = map[string]interface{}{"data": map[string]interface{}{}}
+			execCtx.results[step.ID] = result
 			execCtx.mu.Unlock()
 			return nil
 		}
 
-		query, queryVars, err = e.queryBuilder.Build(step, representations, variables, execCtx.plan.OperationType)
+		if len(step.Contexts) > 0 {
+			return e.processContextualEntityStep(ctx, execCtx, step, set, variables)
+		}
+
+		if e.EntityCache != nil && !step.BypassCache {
+			return e.processCachedEntityStep(ctx, execCtx, step, representations, variables)
+		}
+
+		query, queryVars, err = e.buildQuery(step, representations, variables, execCtx.plan.OperationType)
 		if err != nil {
 			e.recordError(execCtx, step, fmt.Errorf("failed to build entity query: %w", err))
 			return err
 		}
 	}
 
-	// Send request to subgraph
-	result, err := e.sendRequest(ctx, step.SubGraph.Host, query, queryVars)
+	var result map[string]interface{}
+	if step.StepType == planner.StepTypeQuery {
+		result, err = e.fetch(ctx, execCtx, step, query, queryVars)
+	} else {
+		result, err = e.fetchEntities(ctx, execCtx, step, query, queryVars)
+	}
 	if err != nil {
 		// Record error but continue with partial response
 		e.recordError(execCtx, step, err)
+		if step.StepType == planner.StepTypeQuery {
+			execCtx.mu.Lock()
+			execCtx.unreachable[step.ID] = true
+			execCtx.mu.Unlock()
+		}
 		e.setNullForFailedStep(execCtx, step)
 		return nil // Don't propagate error, allow partial response
 	}
 
+	e.storeStepResult(execCtx, step, result)
+	return nil
+}
+
+// fetch sends the query of step to its subgraph, bounded by the per-subgraph timeout
+// if configured and failing over to its fallback hosts, and records the fetch trace when federated tracing is enabled and the
+// call statistics and the request when a collector or a request log is attached to ctx.
+func (e *ExecutorV2) fetch(
+	ctx context.Context,
+	execCtx *ExecutionContext,
+	step *planner.StepV2,
+	query string,
+	queryVars map[string]interface{},
+) (map[string]interface{}, error) {
+	if log := GetSubgraphRequestLogFromContext(ctx); log != nil {
+		log.record(step.ID, step.SubGraph.Name, query, queryVars)
+	}
+	fetchStart := time.Now()
+	result, err := e.send(ctx, step, execCtx.plan.OperationType, query, queryVars)
+	if IsFederatedTracingEnabled(ctx) {
+		e.recordFetchTrace(execCtx, step, fetchStart, result, err)
+	}
+	if stats := GetExecutionStatsFromContext(ctx); stats != nil {
+		stats.recordCall(step.SubGraph.Name, time.Since(fetchStart), result, err)
+	}
+	return result, err
+}
+
+// storeStepResult records the subgraph errors of result and stores it, merging entity
+// results into the root result.
+func (e *ExecutorV2) storeStepResult(execCtx *ExecutionContext, step *planner.StepV2, result map[string]interface{}) {
+	e.Verifier.verify(execCtx.ctx, step, result)
+	e.storeVerifiedResult(execCtx, step, result)
+}
+
+// storeVerifiedResult implements storeStepResult for results verified when received,
+// such as preset results.
+func (e *ExecutorV2) storeVerifiedResult(execCtx *ExecutionContext, step *planner.StepV2, result map[string]interface{}) {
 	// Check if result contains errors
 	if errors, hasErrors := result["errors"]; hasErrors && errors != nil {
 		// Record GraphQL errors from subgraph
@@ -310,60 +575,53 @@ func (e *ExecutorV2) processStep(
 	} else {
 		// Merge entity results into parent
 		if err := e.mergeEntityResults(execCtx, step, result); err != nil {
+			GetMergeTraceFromContext(execCtx.ctx).record(step, -1, MergeOutcomeFailed, "", "", err)
 			e.recordError(execCtx, step, fmt.Errorf("failed to merge entity results: %w", err))
 			e.setNullForFailedStep(execCtx, step)
-			return nil // Don't propagate error
+			return // Don't propagate error
 		}
 		execCtx.mu.Lock()
 		execCtx.results[step.ID] = result
 		execCtx.mu.Unlock()
 	}
+}
 
-	return nil
+// storeRepresentations stores the plan's input representations as the result of a
+// StepTypeRepresentations step, in the same shape as a root query result.
+// Each representation is copied because entity results are merged into them in place.
+func (e *ExecutorV2) storeRepresentations(execCtx *ExecutionContext, step *planner.StepV2) {
+	entities := make([]interface{}, 0, len(execCtx.plan.Representations))
+	for _, rep := range execCtx.plan.Representations {
+		entity := make(map[string]interface{}, len(rep))
+		for k, v := range rep {
+			entity[k] = v
+		}
+		entities = append(entities, entity)
+	}
+
+	execCtx.mu.Lock()
+	execCtx.results[step.ID] = map[string]interface{}{
+		"data": map[string]interface{}{"_entities": entities},
+	}
+	execCtx.mu.Unlock()
 }
 
 // recordError records an error in the execution context with path information.
 func (e *ExecutorV2) recordError(execCtx *ExecutionContext, step *planner.StepV2, err error) {
 	if step.StepType == planner.StepTypeEntity && len(step.SelectionSet) > 0 {
-		// For entity steps, record errors for each field (excluding key fields)
-		basePath := e.buildErrorPath(step)
-		for _, sel := range step.SelectionSet {
-			if field, ok := sel.(*ast.Field); ok {
-				fieldName := field.Name.String()
-				if field.Alias != nil && field.Alias.String() != "" {
-					fieldName = field.Alias.String()
-				}
-				// Skip __typename and common key fields (id, _id, etc.)
-				if fieldName == "__typename" || fieldName == "id" || fieldName == "_id" {
-					continue
-				}
-				fieldPath := make([]interface{}, len(basePath))
-				copy(fieldPath, basePath)
-				fieldPath = append(fieldPath, fieldName)
-
-				graphqlErr := GraphQLError{
-					Message: err.Error(),
-					Path:    fieldPath,
-					Extensions: map[string]interface{}{
-						"serviceName": step.SubGraph.Name,
-					},
-				}
-
-				execCtx.mu.Lock()
-				execCtx.errors = append(execCtx.errors, graphqlErr)
-				execCtx.mu.Unlock()
-			}
+		// For entity steps, record errors for each field (excluding key fields) at each
+		// insertion path
+		for _, at := range stepsAtInsertionPaths(step) {
+			e.recordEntityError(execCtx, at, err)
 		}
 	} else {
 		// For root steps, record a single error
 		path := e.buildErrorPath(step)
 
 		graphqlErr := GraphQLError{
-			Message: err.Error(),
-			Path:    path,
-			Extensions: map[string]interface{}{
-				"serviceName": step.SubGraph.Name,
-			},
+			Message:    err.Error(),
+			Path:       path,
+			Extensions: e.errorExtensions(step, err),
 		}
 
 		execCtx.mu.Lock()
@@ -372,6 +630,76 @@ func (e *ExecutorV2) recordError(execCtx *ExecutionContext, step *planner.StepV2
 	}
 }
 
+// recordEntityError records err for each field of an entity step, excluding key fields,
+// at the insertion path of step.
+func (e *ExecutorV2) recordEntityError(execCtx *ExecutionContext, step *planner.StepV2, err error) {
+	basePath := e.buildErrorPath(step)
+	for _, sel := range step.SelectionSet {
+		if field, ok := sel.(*ast.Field); ok {
+			fieldName := field.Name.String()
+			if field.Alias != nil && field.Alias.String() != "" {
+				fieldName = field.Alias.String()
+			}
+			// Skip __typename and common key fields (id, _id, etc.)
+			if fieldName == "__typename" || fieldName == "id" || fieldName == "_id" {
+				continue
+			}
+			fieldPath := make([]interface{}, len(basePath))
+			copy(fieldPath, basePath)
+			fieldPath = append(fieldPath, fieldName)
+
+			graphqlErr := GraphQLError{
+				Message:    err.Error(),
+				Path:       fieldPath,
+				Extensions: e.errorExtensions(step, err),
+			}
+
+			execCtx.mu.Lock()
+			execCtx.errors = append(execCtx.errors, graphqlErr)
+			execCtx.mu.Unlock()
+		}
+	}
+}
+
+// stepsAtInsertionPaths returns step itself, or a copy of step at each of its insertion
+// paths when it is batched, so that the objects of each path are handled as those of a
+// step of their own.
+func stepsAtInsertionPaths(step *planner.StepV2) []*planner.StepV2 {
+	if len(step.BatchedPaths) == 0 {
+		return []*planner.StepV2{step}
+	}
+	steps := make([]*planner.StepV2, 0, len(step.BatchedPaths)+1)
+	for _, path := range step.InsertionPaths() {
+		at := *step
+		at.InsertionPath = path
+		at.BatchedPaths = nil
+		steps = append(steps, &at)
+	}
+	return steps
+}
+
+// errorExtensions builds the extensions of an error recorded for step: the subgraph
+// name and a code telling whether the request timed out, was shed, failed at the HTTP
+// level, or whether the gateway failed to build or merge it.
+func (e *ExecutorV2) errorExtensions(step *planner.StepV2, err error) map[string]interface{} {
+	extensions := map[string]interface{}{
+		"serviceName": step.SubGraph.Name,
+	}
+	if isTimeoutError(err) {
+		extensions["code"] = TimeoutErrorCode
+	} else if errors.Is(err, ErrSubgraphOverloaded) {
+		extensions["code"] = OverloadedErrorCode
+	} else if httpErr := (*SubgraphHTTPError)(nil); errors.As(err, &httpErr) {
+		extensions["code"] = SubgraphHTTPErrorCode
+		if httpErr.StatusCode != 0 {
+			extensions["http"] = map[string]interface{}{"status": httpErr.StatusCode}
+		}
+	} else {
+		extensions["code"] = InternalServerErrorCode
+	}
+	return extensions
+}
+
 // recordSubgraphErrors records errors from subgraph response.
 func (e *ExecutorV2) recordSubgraphErrors(execCtx *ExecutionContext, step *planner.StepV2, errors interface{}) {
 	errorList, ok := errors.([]interface{})
@@ -409,6 +737,7 @@ func (e *ExecutorV2) recordSubgraphErrors(execCtx *ExecutionContext, step *plann
 				graphqlErr.Extensions[k] = v
 			}
 		}
+		graphqlErr = e.ErrorMapping[step.SubGraph.Name].apply(step.SubGraph.Name, graphqlErr)
 
 		execCtx.mu.Lock()
 		execCtx.errors = append(execCtx.errors, graphqlErr)
@@ -430,7 +759,7 @@ func (e *ExecutorV2) buildErrorPath(step *planner.StepV2) []interface{} {
 
 	for _, segment := range pathSegments {
 		// Skip root type names (Query, Mutation, Subscription)
-		if segment == "Query" || segment == "Mutation" || segment == "Subscription" {
+		if e.superGraph.IsRootType(segment) {
 			continue
 		}
 		path = append(path, segment)
@@ -466,15 +795,12 @@ func (e *ExecutorV2) setNullForFailedStep(execCtx *ExecutionContext, step *plann
 			return
 		}
 
-		// Find root step result
+		// Find the result of the root step the entity step descends from
 		var rootStepID int
 		var rootResult interface{}
-		for _, s := range execCtx.plan.Steps {
-			if len(s.DependsOn) == 0 {
-				rootStepID = s.ID
-				rootResult = execCtx.results[s.ID]
-				break
-			}
+		if root := rootStepOf(execCtx.plan, step); root != nil {
+			rootStepID = root.ID
+			rootResult = execCtx.results[root.ID]
 		}
 
 		if rootResult == nil {
@@ -494,44 +820,19 @@ func (e *ExecutorV2) setNullForFailedStep(execCtx *ExecutionContext, step *plann
 			return
 		}
 
-		// Navigate to target entity using InsertionPath
-		mergePath := make([]string, 0)
-		for i, segment := range step.InsertionPath {
-			if i == 0 && (segment == "Query" || segment == "Mutation" || segment == "Subscription") {
-				continue
-			}
-			mergePath = append(mergePath, segment)
-		}
-
-		// Navigate to the target entity
-		var current interface{} = rootData
-		for _, segment := range mergePath {
-			if currentMap, ok := current.(map[string]interface{}); ok {
-				if next, exists := currentMap[segment]; exists {
-					current = next
-				} else {
-					execCtx.results[step.ID] = map[string]interface{}{"data": map[string]interface{}{}}
-					return
-				}
-			} else if currentArray, ok := current.([]interface{}); ok {
-				// If it's an array, set null for each item
-				for _, item := range currentArray {
-					if itemMap, ok := item.(map[string]interface{}); ok {
-						e.setNullFieldsInEntity(itemMap, step.SelectionSet)
-					}
+		for _, insertionPath := range step.InsertionPaths() {
+			// Navigate to target entity using the insertion path
+			mergePath := make([]string, 0)
+			for i, segment := range insertionPath {
+				if i == 0 && e.superGraph.IsRootType(segment) {
+					continue
 				}
-				execCtx.results[rootStepID] = rootResultMap
-				execCtx.results[step.ID] = map[string]interface{}{"data": map[string]interface{}{}}
-				return
-			} else {
-				execCtx.results[step.ID] = map[string]interface{}{"data": map[string]interface{}{}}
-				return
+				mergePath = append(mergePath, segment)
 			}
-		}
 
-		// Set null for each field in the selection set
-		if entityMap, ok := current.(map[string]interface{}); ok {
-			e.setNullFieldsInEntity(entityMap, step.SelectionSet)
+			// Set null for each field in the selection set of the target entities, through
+			// lists at any depth
+			e.setNullAtPath(rootData, mergePath, step.SelectionSet)
 		}
 
 		// Update root result
@@ -540,6 +841,59 @@ func (e *ExecutorV2) setNullForFailedStep(execCtx *ExecutionContext, step *plann
 	}
 }
 
+// setNullAtPath sets the fields of selectionSet to null in the objects at path below
+// value, descending into the lists on the way.
+func (e *ExecutorV2) setNullAtPath(value interface{}, path []string, selectionSet []ast.Selection) {
+	forEachObject(value, func(obj map[string]interface{}) {
+		if len(path) == 0 {
+			e.setNullFieldsInEntity(obj, selectionSet)
+			return
+		}
+		if next, exists := obj[path[0]]; exists {
+			e.setNullAtPath(next, path[1:], selectionSet)
+		}
+	})
+}
+
+// forEachObject calls fn with value when it is an object, or with each object of value
+// when it is a list, descending into nested lists such as the values of a [[Product]]
+// field, in order. Null elements are skipped. The nesting of lists in responses follows
+// the list wrappers of the field types, so this walks the objects of any field the same
+// way when extracting representations and merging the entities resolved for them.
+func forEachObject(value interface{}, fn func(map[string]interface{})) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		fn(v)
+	case []interface{}:
+		for _, item := range v {
+			forEachObject(item, fn)
+		}
+	}
+}
+
+// rootStepOf returns the root step step descends from, following the first dependency
+// of every step. It returns nil when a dependency is missing from the plan.
+func rootStepOf(plan *planner.PlanV2, step *planner.StepV2) *planner.StepV2 {
+	byID := make(map[int]*planner.StepV2, len(plan.Steps))
+	for _, s := range plan.Steps {
+		byID[s.ID] = s
+	}
+
+	current := step
+	// The plan is a DAG, so the chain is at most one step per plan step long.
+	for i := 0; i < len(plan.Steps) && len(current.DependsOn) > 0; i++ {
+		parent, ok := byID[current.DependsOn[0]]
+		if !ok {
+			return nil
+		}
+		current = parent
+	}
+	if len(current.DependsOn) > 0 {
+		return nil
+	}
+	return current
+}
+
 // setNullFieldsInEntity sets null for fields in an entity map.
 func (e *ExecutorV2) setNullFieldsInEntity(entityMap map[string]interface{}, selectionSet []ast.Selection) {
 	for _, sel := range selectionSet {
@@ -557,62 +911,96 @@ func (e *ExecutorV2) setNullFieldsInEntity(entityMap map[string]interface{}, sel
 	}
 }
 
-// extractRepresentations extracts entity representations from parent step results.
-func (e *ExecutorV2) extractRepresentations(execCtx *ExecutionContext, step *planner.StepV2) []map[string]interface{} {
-	representations := make([]map[string]interface{}, 0)
+// representationSet collects the representations of an entity step in the order of the
+// objects they are built from. The positions of the objects without a representation are
+// kept in skipped, so that the entities returned for the others are merged into the right
+// objects.
+type representationSet struct {
+	representations []map[string]interface{}
+	contexts        []map[string]interface{} // @fromContext variables of each representation
+	skipped         []int
+	missing         []string // Required fields the skipped objects lack
+	ends            []int    // End position of the objects of each insertion path
+}
+
+// add appends the representation of the next object, or skips it when rep is nil.
+// missing names the required field the object lacks, if that is why rep is nil.
+func (s *representationSet) add(rep map[string]interface{}, missing string) {
+	if rep != nil {
+		s.representations = append(s.representations, rep)
+		return
+	}
+	s.skipped = append(s.skipped, len(s.representations)+len(s.skipped))
+	if missing != "" && !slices.Contains(s.missing, missing) {
+		s.missing = append(s.missing, missing)
+	}
+}
+
+// extractRepresentations extracts entity representations from parent step results, at
+// each insertion path of step in turn.
+func (e *ExecutorV2) extractRepresentations(execCtx *ExecutionContext, step *planner.StepV2) *representationSet {
+	representations := &representationSet{}
 
 	execCtx.mu.RLock()
 	defer execCtx.mu.RUnlock()
 
+	for _, at := range stepsAtInsertionPaths(step) {
+		e.extractRepresentationsAt(execCtx, at, representations)
+		representations.ends = append(representations.ends, len(representations.representations)+len(representations.skipped))
+	}
+	return representations
+}
+
+// extractRepresentationsAt adds the representations of the objects at the insertion path
+// of step to representations.
+func (e *ExecutorV2) extractRepresentationsAt(execCtx *ExecutionContext, step *planner.StepV2, representations *representationSet) {
 	// Get parent step results
 	if len(step.DependsOn) == 0 {
-		return representations
+		return
 	}
 
-	// For entity steps, we need to extract from the root step's result (which has been merged)
-	// Find the root step (ID 0 or any step with no dependencies)
+	// For entity steps, we need to extract from the root step's result (which has been merged).
+	// The root step is found through the DependsOn chain, since serial mutation fields each
+	// have their own root step.
 	var rootResult interface{}
-	for _, s := range execCtx.plan.Steps {
-		if len(s.DependsOn) == 0 {
-			if result, exists := execCtx.results[s.ID]; exists {
-				rootResult = result
-				break
-			}
-		}
+	if root := rootStepOf(execCtx.plan, step); root != nil {
+		rootResult = execCtx.results[root.ID]
 	}
 
 	if rootResult == nil {
-		return representations
+		return
 	}
 
 	// Navigate to the insertion path
 	var current interface{} = rootResult
 
 	// Extract data field
+	var ancestors []map[string]interface{}
 	if resultMap, ok := current.(map[string]interface{}); ok {
 		if data, ok := resultMap["data"].(map[string]interface{}); ok {
 			current = data
+			ancestors = append(ancestors, data)
 		} else {
-			return representations
+			return
 		}
 	}
 
 	// Navigate through the insertion path (skip "Query" or root type)
 	for i, pathSegment := range step.InsertionPath {
 		// Skip root type names (Query, Mutation, Subscription)
-		if i == 0 && (pathSegment == "Query" || pathSegment == "Mutation" || pathSegment == "Subscription") {
+		if i == 0 && e.superGraph.IsRootType(pathSegment) {
 			continue
 		}
 
 		currentMap, ok := current.(map[string]interface{})
 		if !ok {
 			// Current is not a map, something went wrong
-			return representations
+			return
 		}
 
 		next, exists := currentMap[pathSegment]
 		if !exists {
-			return representations
+			return
 		}
 
 		// IMPORTANT: Check if next is an array BEFORE moving to it
@@ -621,75 +1009,58 @@ func (e *ExecutorV2) extractRepresentations(execCtx *ExecutionContext, step *pla
 			// Remaining path segments AFTER this array segment
 			remainingPath := step.InsertionPath[i+1:]
 
-			// For each array element, navigate the remaining path
-			for _, elem := range arr {
-				elemMap, ok := elem.(map[string]interface{})
-				if !ok {
-					continue
-				}
-
-				// Navigate through remaining path in this element, handling nested arrays
-				elemResults := e.navigatePathWithArrays(elemMap, remainingPath, step)
-				representations = append(representations, elemResults...)
-			}
+			// For each object of the array, however deeply nested, navigate the
+			// remaining path, handling nested arrays
+			forEachObject(arr, func(elemMap map[string]interface{}) {
+				e.navigatePathWithArrays(elemMap, remainingPath, step, representations, append(ancestors, elemMap))
+			})
 
-			return representations
+			return
 		}
 
 		current = next
+		if nextMap, ok := next.(map[string]interface{}); ok {
+			ancestors = append(ancestors, nextMap)
+		}
 	}
 
 	// Extract representations from entities
-	// Get @key fields from entity definition
-	// We need to get the entity from the subgraph that owns it, not step.SubGraph
-	ownerSubGraph := e.superGraph.GetEntityOwnerSubGraph(step.ParentType)
-	if ownerSubGraph == nil {
-		return representations
-	}
-
-	entity, exists := ownerSubGraph.GetEntity(step.ParentType)
-	if !exists || len(entity.Keys) == 0 {
-		return representations
+	keyField, ok := e.representationKey(step)
+	if !ok {
+		return
 	}
 
-	keyField := entity.Keys[0].FieldSet
-
 	// Handle both single entity and list of entities
 	switch v := current.(type) {
 	case map[string]interface{}:
 		// Single entity
-		if rep := e.buildRepresentation(v, step.ParentType, keyField); rep != nil {
-			representations = append(representations, rep)
+		if !e.matchesEntityType(v, step) {
+			break
 		}
+		e.addEntity(representations, v, ancestors, step, keyField)
 	case []interface{}:
-		// List of entities
-		for _, item := range v {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				if rep := e.buildRepresentation(itemMap, step.ParentType, keyField); rep != nil {
-					representations = append(representations, rep)
-				}
+		// List of entities, possibly nested
+		forEachObject(v, func(itemMap map[string]interface{}) {
+			if e.matchesEntityType(itemMap, step) {
+				e.addEntity(representations, itemMap, append(ancestors, itemMap), step, keyField)
 			}
-		}
+		})
 	}
-
-	return representations
 }
 
-// navigatePathWithArrays navigates through a path that may contain nested arrays
-func (e *ExecutorV2) navigatePathWithArrays(current map[string]interface{}, path []string, step *planner.StepV2) []map[string]interface{} {
-	representations := make([]map[string]interface{}, 0)
-
+// navigatePathWithArrays navigates through a path that may contain nested arrays, adding
+// the representations of the objects at its end to representations. ancestors are the
+// objects navigated through from the data of the root result, current last.
+func (e *ExecutorV2) navigatePathWithArrays(current map[string]interface{}, path []string, step *planner.StepV2, representations *representationSet, ancestors []map[string]interface{}) {
 	if len(path) == 0 {
 		// Reached the end - extract representation from current
-		if ownerSubGraph := e.superGraph.GetEntityOwnerSubGraph(step.ParentType); ownerSubGraph != nil {
-			if entity, exists := ownerSubGraph.GetEntity(step.ParentType); exists && len(entity.Keys) > 0 {
-				keyField := entity.Keys[0].FieldSet
-				if rep := e.buildRepresentation(current, step.ParentType, keyField); rep != nil {
-					representations = append(representations, rep)
-				}
-			}
+		if !e.matchesEntityType(current, step) {
+			return
+		}
+		if keyField, ok := e.representationKey(step); ok {
+			e.addEntity(representations, current, ancestors, step, keyField)
 		}
-		return representations
+		return
 	}
 
 	segment := path[0]
@@ -697,31 +1068,74 @@ func (e *ExecutorV2) navigatePathWithArrays(current map[string]interface{}, path
 
 	next, exists := current[segment]
 	if !exists {
-		return representations
+		return
 	}
 
 	// Check if next is an array
 	if arr, isArray := next.([]interface{}); isArray {
-		// Process each array element with remaining path
-		for _, elem := range arr {
-			if elemMap, ok := elem.(map[string]interface{}); ok {
-				elemResults := e.navigatePathWithArrays(elemMap, remainingPath, step)
-				representations = append(representations, elemResults...)
-			}
-		}
+		// Process each object of the array, however deeply nested, with remaining path
+		forEachObject(arr, func(elemMap map[string]interface{}) {
+			e.navigatePathWithArrays(elemMap, remainingPath, step, representations, append(ancestors, elemMap))
+		})
 	} else if nextMap, ok := next.(map[string]interface{}); ok {
 		// Continue navigating
-		representations = e.navigatePathWithArrays(nextMap, remainingPath, step)
+		e.navigatePathWithArrays(nextMap, remainingPath, step, representations, append(ancestors, nextMap))
 	}
+}
 
-	return representations
+// addEntity adds the representation of entity to set. ancestors are the objects at the
+// prefixes of the insertion path of step, entity last, from which the @fromContext
+// arguments of step are set.
+func (e *ExecutorV2) addEntity(set *representationSet, entity map[string]interface{}, ancestors []map[string]interface{}, step *planner.StepV2, keyField string) {
+	rep, missing := e.buildRepresentation(entity, step, keyField)
+	if rep != nil && len(step.Contexts) > 0 {
+		var values map[string]interface{}
+		if values, missing = contextValues(step, ancestors); values == nil {
+			rep = nil
+		} else {
+			set.contexts = append(set.contexts, values)
+		}
+	}
+	set.add(rep, missing)
 }
 
-// buildRepresentation builds a representation for an entity.
+// representationKey returns the @key field set the representations of step are built
+// from. It is the first key of the entity in the step's subgraph, which is the key the
+// planner selects in the parent step, so entities extended through a key other than the
+// owner's first one, such as a composite key, are resolved. Without a definition in the
+// step's subgraph, the first key of the owning subgraph is used.
+func (e *ExecutorV2) representationKey(step *planner.StepV2) (string, bool) {
+	if step.SubGraph != nil {
+		if entity, exists := step.SubGraph.GetEntity(step.ParentType); exists && len(entity.Keys) > 0 {
+			return entity.Keys[0].FieldSet, true
+		}
+	}
+	ownerSubGraph := e.superGraph.GetEntityOwnerSubGraph(step.ParentType)
+	if ownerSubGraph == nil {
+		return "", false
+	}
+	entity, exists := ownerSubGraph.GetEntity(step.ParentType)
+	if !exists || len(entity.Keys) == 0 {
+		return "", false
+	}
+	return entity.Keys[0].FieldSet, true
+}
+
+// matchesEntityType reports whether entity is resolved by step. Objects returned for an
+// abstract field are resolved by the step of their own __typename, each dispatched to
+// the owner of that implementation.
+func (e *ExecutorV2) matchesEntityType(entity map[string]interface{}, step *planner.StepV2) bool {
+	typeName, ok := entity["__typename"].(string)
+	return !ok || typeName == step.ParentType || e.superGraph.IsAbstractType(step.ParentType)
+}
+
+// buildRepresentation builds a representation for an entity, carrying the fields step
+// requires, through @requires, alongside the keys. It returns nil when a key field is
+// missing, and nil with the path of the field when a required field is missing.
 // keyField can be a single field or composite keys separated by space (e.g., "number departureDate")
-func (e *ExecutorV2) buildRepresentation(entity map[string]interface{}, typeName string, keyField string) map[string]interface{} {
+func (e *ExecutorV2) buildRepresentation(entity map[string]interface{}, step *planner.StepV2, keyField string) (map[string]interface{}, string) {
 	representation := map[string]interface{}{
-		"__typename": typeName,
+		"__typename": step.ParentType,
 	}
 
 	// Handle composite keys by splitting on whitespace
@@ -733,11 +1147,92 @@ func (e *ExecutorV2) buildRepresentation(entity map[string]interface{}, typeName
 			representation[fieldName] = keyValue
 		} else {
 			// Missing required key field
-			return nil
+			return nil, ""
 		}
 	}
 
-	return representation
+	if missing := e.missingField(entity, step.Requires, step.ParentType); missing != "" {
+		return nil, missing
+	}
+	for fieldName, value := range projectSelections(entity, step.Requires) {
+		representation[fieldName] = value
+	}
+
+	return representation, ""
+}
+
+// missingField returns the path of the first field of selections, on typeName, that
+// value lacks or holds null for while its type is non-null, or "" when none is.
+func (e *ExecutorV2) missingField(value interface{}, selections []ast.Selection, typeName string) string {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if missing := e.missingField(item, selections, typeName); missing != "" {
+				return missing
+			}
+		}
+	case map[string]interface{}:
+		for _, sel := range selections {
+			field, ok := sel.(*ast.Field)
+			if !ok {
+				continue
+			}
+			name := field.Name.String()
+			fieldValue, exists := v[name]
+			fieldDef := e.superGraph.FieldDefinition(typeName, name)
+			if !exists {
+				return name
+			}
+			if fieldDef == nil {
+				continue
+			}
+			if _, nonNull := fieldDef.Type.(*ast.NonNullType); nonNull && fieldValue == nil {
+				return name
+			}
+			if missing := e.missingField(fieldValue, field.SelectionSet, getNamedType(fieldDef.Type)); missing != "" {
+				return name + "." + missing
+			}
+		}
+	}
+	return ""
+}
+
+// projectSelections returns the values of entity selected by selections, recursing into
+// nested objects and lists. Fields absent from entity are left out.
+func projectSelections(entity map[string]interface{}, selections []ast.Selection) map[string]interface{} {
+	projected := make(map[string]interface{}, len(selections))
+	for _, sel := range selections {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+		name := field.Name.String()
+		value, exists := entity[name]
+		if !exists {
+			continue
+		}
+		projected[name] = projectValue(value, field.SelectionSet)
+	}
+	return projected
+}
+
+// projectValue applies selections to value when it is an object or a list of objects.
+func projectValue(value interface{}, selections []ast.Selection) interface{} {
+	if len(selections) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return projectSelections(v, selections)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = projectValue(item, selections)
+		}
+		return items
+	default:
+		return value
+	}
 }
 
 // mergeEntityResults merges entity query results back into parent results.
@@ -750,17 +1245,14 @@ func (e *ExecutorV2) mergeEntityResults(execCtx *ExecutionContext, step *planner
 		return nil
 	}
 
-	// Always merge into the root step (Step 0), not the immediate parent
-	// This is because nested entity steps (e.g., Step 2 depends on Step 1)
+	// Always merge into the root step the entity step descends from, not the immediate
+	// parent. This is because nested entity steps (e.g., Step 2 depends on Step 1)
 	// cannot merge into Step 1's _entities result format
 	var rootStepID int
 	var rootResult interface{}
-	for _, s := range execCtx.plan.Steps {
-		if len(s.DependsOn) == 0 {
-			rootStepID = s.ID
-			rootResult = execCtx.results[s.ID]
-			break
-		}
+	if root := rootStepOf(execCtx.plan, step); root != nil {
+		rootStepID = root.ID
+		rootResult = execCtx.results[root.ID]
 	}
 
 	if rootResult == nil {
@@ -778,22 +1270,55 @@ func (e *ExecutorV2) mergeEntityResults(execCtx *ExecutionContext, step *planner
 		return fmt.Errorf("root result does not have data field")
 	}
 
+	trace := GetMergeTraceFromContext(execCtx.ctx)
+
 	// Extract _entities from entity query result
 	resultData, ok := result["data"].(map[string]interface{})
 	if !ok {
+		trace.record(step, -1, MergeOutcomeNoEntities, "", "", nil)
 		return nil // No data to merge
 	}
 
 	entitiesData, ok := resultData["_entities"]
 	if !ok {
+		trace.record(step, -1, MergeOutcomeNoEntities, "", "", nil)
 		return nil // No entities to merge
 	}
+	if skipped := execCtx.skipped[step.ID]; len(skipped) > 0 {
+		entitiesData = e.withSkippedEntities(entitiesData, skipped, step)
+	}
+
+	// The entities of a batched step are split between its insertion paths, in the order
+	// their representations were extracted
+	entities, isList := entitiesData.([]interface{})
+	ends := execCtx.batches[step.ID]
+	start := 0
+	for i, at := range stepsAtInsertionPaths(step) {
+		data := entitiesData
+		if isList && i < len(ends) && len(step.BatchedPaths) > 0 {
+			end := max(start, min(ends[i], len(entities)))
+			data = entities[start:end]
+			start = end
+		}
+		if err := e.mergeEntitiesAt(rootData, at, data, trace); err != nil {
+			return err
+		}
+	}
 
+	// Update the root step's result to reflect the merge
+	execCtx.results[rootStepID] = rootResultMap
+
+	return nil
+}
+
+// mergeEntitiesAt merges entitiesData, the entities resolved for the objects at the
+// insertion path of step, into rootData.
+func (e *ExecutorV2) mergeEntitiesAt(rootData map[string]interface{}, step *planner.StepV2, entitiesData interface{}, trace *MergeTrace) error {
 	// Build merge path (skip root type name)
 	mergePath := make([]string, 0)
 	for i, segment := range step.InsertionPath {
 		// Skip root type names (Query, Mutation, Subscription)
-		if i == 0 && (segment == "Query" || segment == "Mutation" || segment == "Subscription") {
+		if i == 0 && e.superGraph.IsRootType(segment) {
 			continue
 		}
 		mergePath = append(mergePath, segment)
@@ -854,22 +1379,22 @@ func (e *ExecutorV2) mergeEntityResults(execCtx *ExecutionContext, step *planner
 		// The remaining path after the array
 		remainingPath := mergePath[firstArrayIndex+1:]
 
-		// Merge entities into the nested structure
+		// Merge entities into the nested structure, in the order the representations
+		// were extracted
 		entityIndex := 0
-		for _, elem := range arrayData {
-			elemMap, ok := elem.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
+		forEachObject(arrayData, func(elemMap map[string]interface{}) {
 			// Recursively merge entities into potentially nested arrays
-			entityIndex = e.mergeIntoNestedArrays(elemMap, entities, remainingPath, entityIndex, step)
+			entityIndex = e.mergeIntoNestedArrays(elemMap, entities, remainingPath, entityIndex, step, trace)
+		})
+		for ; entityIndex < len(entities); entityIndex++ {
+			trace.record(step, entityIndex, MergeOutcomeUnusedEntity, "", "", nil)
 		}
 
 	} else if current == nil {
 		// Path doesn't exist yet, treat as single object and let Merge handle it
 		entities, ok := entitiesData.([]interface{})
 		if !ok || len(entities) == 0 {
+			trace.record(step, 0, MergeOutcomeMissingEntity, "", "", nil)
 			return nil
 		}
 
@@ -878,18 +1403,23 @@ func (e *ExecutorV2) mergeEntityResults(execCtx *ExecutionContext, step *planner
 			return fmt.Errorf("first entity is not a map")
 		}
 
+		before := trace.snapshot(nil)
 		if err := Merge(rootData, firstEntity, mergePath); err != nil {
 			return fmt.Errorf("failed to merge entity object: %w", err)
 		}
+		trace.record(step, 0, MergeOutcomeMerged, before, trace.snapshot(valueAtPath(rootData, mergePath)), nil)
 	} else if _, isArray := current.([]interface{}); isArray {
 		// Target is an array, merge entities directly
+		before := trace.snapshot(current)
 		if err := Merge(rootData, entitiesData, mergePath); err != nil {
 			return fmt.Errorf("failed to merge entities array: %w", err)
 		}
+		trace.record(step, -1, MergeOutcomeMerged, before, trace.snapshot(valueAtPath(rootData, mergePath)), nil)
 	} else {
 		// Target is a single object, merge first entity
 		entities, ok := entitiesData.([]interface{})
 		if !ok || len(entities) == 0 {
+			trace.record(step, 0, MergeOutcomeMissingEntity, "", "", nil)
 			return nil
 		}
 
@@ -899,36 +1429,66 @@ func (e *ExecutorV2) mergeEntityResults(execCtx *ExecutionContext, step *planner
 			return fmt.Errorf("first entity is not a map")
 		}
 
+		before := trace.snapshot(current)
 		if err := Merge(rootData, firstEntity, mergePath); err != nil {
 			return fmt.Errorf("failed to merge entity object: %w", err)
 		}
+		trace.record(step, 0, MergeOutcomeMerged, before, trace.snapshot(valueAtPath(rootData, mergePath)), nil)
 	}
 
-	// Update the root step's result to reflect the merge
-	execCtx.results[rootStepID] = rootResultMap
-
 	return nil
 }
 
+// withSkippedEntities returns the entities of step with an object setting the fields of
+// step to null inserted at each skipped position, so that they line up with the objects
+// the representations were built from.
+func (e *ExecutorV2) withSkippedEntities(entitiesData interface{}, skipped []int, step *planner.StepV2) interface{} {
+	entities, ok := entitiesData.([]interface{})
+	if !ok {
+		return entitiesData
+	}
+	result := make([]interface{}, 0, len(entities)+len(skipped))
+	for _, position := range skipped {
+		for len(result) < position && len(entities) > 0 {
+			result = append(result, entities[0])
+			entities = entities[1:]
+		}
+		null := make(map[string]interface{})
+		e.setNullFieldsInEntity(null, step.SelectionSet)
+		result = append(result, null)
+	}
+	return append(result, entities...)
+}
+
 // mergeIntoNestedArrays recursively merges entities into potentially nested array structures
-// Returns the next entity index to use
+// Returns the next entity index to use. Merges are recorded in trace when set.
 func (e *ExecutorV2) mergeIntoNestedArrays(
 	current map[string]interface{},
 	entities []interface{},
 	path []string,
 	entityIndex int,
 	step *planner.StepV2,
+	trace *MergeTrace,
 ) int {
 	if len(path) == 0 {
 		// Reached the target - merge the entity here
+		if !e.matchesEntityType(current, step) {
+			trace.record(step, -1, MergeOutcomeTypeMismatch, trace.snapshot(current), "", nil)
+			return entityIndex
+		}
 		if entityIndex < len(entities) {
 			if entityMap, ok := entities[entityIndex].(map[string]interface{}); ok {
+				before := trace.snapshot(current)
 				// Deep merge entity fields into current
 				// Use the Merge function to properly handle nested structures
 				Merge(current, entityMap, []string{})
+				trace.record(step, entityIndex, MergeOutcomeMerged, before, trace.snapshot(current), nil)
+			} else {
+				trace.record(step, entityIndex, MergeOutcomeNullEntity, trace.snapshot(current), "", nil)
 			}
 			return entityIndex + 1
 		}
+		trace.record(step, entityIndex, MergeOutcomeMissingEntity, trace.snapshot(current), "", nil)
 		return entityIndex
 	}
 
@@ -937,32 +1497,41 @@ func (e *ExecutorV2) mergeIntoNestedArrays(
 
 	next, exists := current[segment]
 	if !exists {
+		trace.record(step, -1, MergeOutcomeMissingPath, trace.snapshot(current), "", nil)
 		return entityIndex
 	}
 
 	// Check if next is an array
 	if arr, isArray := next.([]interface{}); isArray {
-		// Process each array element
-		for _, elem := range arr {
-			if elemMap, ok := elem.(map[string]interface{}); ok {
-				entityIndex = e.mergeIntoNestedArrays(elemMap, entities, remainingPath, entityIndex, step)
-			}
-		}
+		// Process each object of the array, however deeply nested
+		forEachObject(arr, func(elemMap map[string]interface{}) {
+			entityIndex = e.mergeIntoNestedArrays(elemMap, entities, remainingPath, entityIndex, step, trace)
+		})
 	} else if nextMap, ok := next.(map[string]interface{}); ok {
 		// Continue navigating
-		entityIndex = e.mergeIntoNestedArrays(nextMap, entities, remainingPath, entityIndex, step)
+		entityIndex = e.mergeIntoNestedArrays(nextMap, entities, remainingPath, entityIndex, step, trace)
 	}
 
 	return entityIndex
 }
 
-// sendRequest sends a GraphQL request to a subgraph.
-func (e *ExecutorV2) sendRequest(
+// clientFor returns the HTTP client for requests to the named subgraph.
+func (e *ExecutorV2) clientFor(subGraphName string) *http.Client {
+	if client, ok := e.SubGraphClients[subGraphName]; ok {
+		return client
+	}
+	return e.httpClient
+}
+
+// doRequest sends a GraphQL request to a subgraph and returns its response and HTTP
+// status.
+func (e *ExecutorV2) doRequest(
 	ctx context.Context,
+	client *http.Client,
 	host string,
 	query string,
 	variables map[string]interface{},
-) (map[string]interface{}, error) {
+) (map[string]interface{}, int, error) {
 	// Build request body
 	reqBody := map[string]interface{}{
 		"query": query,
@@ -970,45 +1539,73 @@ func (e *ExecutorV2) sendRequest(
 	if len(variables) > 0 {
 		reqBody["variables"] = variables
 	}
+	if extensions := GetSubgraphExtensionsFromContext(ctx); len(extensions) > 0 {
+		reqBody["extensions"] = extensions
+	}
 
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, 0, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", host, bytes.NewReader(bodyBytes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	for k, v := range GetSubgraphHeadersFromContext(ctx) {
+		req.Header[k] = v
+	}
 	req.Header.Set("Content-Type", "application/json")
+	if IsFederatedTracingEnabled(ctx) {
+		req.Header.Set(FederatedTraceHeader, FederatedTraceExtension)
+	}
 
 	// Send request
-	resp, err := e.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, 0, &SubgraphHTTPError{Err: fmt.Errorf("failed to send request: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, resp.StatusCode, &SubgraphHTTPError{StatusCode: resp.StatusCode, Err: fmt.Errorf("failed to read response: %w", err)}
 	}
 
-	// Parse response
+	// Parse response. Numbers are kept as json.Number so 64-bit IDs and BigInt values
+	// survive merging and re-encoding without being rounded to float64.
 	var result map[string]interface{}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	dec := json.NewDecoder(bytes.NewReader(respBody))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
+		return nil, resp.StatusCode, &SubgraphHTTPError{StatusCode: resp.StatusCode, Err: fmt.Errorf("failed to unmarshal response: %w", err)}
 	}
 
-	return result, nil
+	return result, resp.StatusCode, nil
+}
+
+type keepTypenameContextKey struct{}
+
+// SetKeepTypenameToContext keeps __typename in every object of the responses of
+// executions using ctx, even where the client did not select it, e.g. for clients
+// with a normalized cache.
+func SetKeepTypenameToContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keepTypenameContextKey{}, true)
+}
+
+// IsKeepTypenameEnabled reports whether __typename is kept in the responses for ctx.
+func IsKeepTypenameEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(keepTypenameContextKey{}).(bool)
+	return enabled
 }
 
 // pruneResponse removes fields from response that were not in the original query.
-// This removes __typename and key fields that were added by the planner for entity resolution.
-func (e *ExecutorV2) pruneResponse(resp map[string]interface{}, plan *planner.PlanV2) map[string]interface{} {
+// This removes __typename and key fields that were added by the planner for entity
+// resolution; __typename is kept in every object when keepTypename is set.
+func (e *ExecutorV2) pruneResponse(resp map[string]interface{}, plan *planner.PlanV2, keepTypename bool) map[string]interface{} {
 	data, ok := resp["data"].(map[string]interface{})
 	if !ok {
 		return resp
@@ -1031,7 +1628,7 @@ func (e *ExecutorV2) pruneResponse(resp map[string]interface{}, plan *planner.Pl
 	expandedSelections := expandFragmentsInSelections(op.SelectionSet, fragmentDefs)
 
 	// Prune the data based on the expanded selection set
-	prunedData := e.pruneObject(data, expandedSelections)
+	prunedData := e.pruneObject(data, expandedSelections, keepTypename)
 
 	result := make(map[string]interface{})
 	result["data"] = prunedData
@@ -1042,8 +1639,11 @@ func (e *ExecutorV2) pruneResponse(resp map[string]interface{}, plan *planner.Pl
 	return result
 }
 
-// pruneObject recursively prunes an object based on the selection set.
-func (e *ExecutorV2) pruneObject(obj interface{}, selections []ast.Selection) interface{} {
+// pruneObject recursively prunes an object based on the selection set. Fields are
+// looked up by response key first, so an aliased field never takes the value of an
+// unaliased field of the same name, and fields selected several times under one
+// response key, e.g. through fragments, keep the union of their selections.
+func (e *ExecutorV2) pruneObject(obj interface{}, selections []ast.Selection, keepTypename bool) interface{} {
 	if obj == nil {
 		return nil
 	}
@@ -1051,6 +1651,9 @@ func (e *ExecutorV2) pruneObject(obj interface{}, selections []ast.Selection) in
 	switch v := obj.(type) {
 	case map[string]interface{}:
 		result := make(map[string]interface{})
+		if typename, ok := v["__typename"]; ok && keepTypename {
+			result["__typename"] = typename
+		}
 		for _, sel := range selections {
 			field, ok := sel.(*ast.Field)
 			if !ok {
@@ -1063,27 +1666,31 @@ func (e *ExecutorV2) pruneObject(obj interface{}, selections []ast.Selection) in
 				lookupKey = field.Alias.String()
 			}
 
-			value, exists := v[fieldName]
+			value, exists := v[lookupKey]
 			if !exists && lookupKey != fieldName {
-				value, exists = v[lookupKey]
+				value, exists = v[fieldName]
 			}
 			if !exists {
 				continue
 			}
 
 			// Recursively prune child selections
-			if len(field.SelectionSet) > 0 {
-				result[lookupKey] = e.pruneObject(value, field.SelectionSet)
-			} else {
+			if len(field.SelectionSet) == 0 {
 				result[lookupKey] = value
+				continue
+			}
+			pruned := e.pruneObject(value, field.SelectionSet, keepTypename)
+			if existing, ok := result[lookupKey]; ok {
+				pruned = mergePrunedValues(existing, pruned)
 			}
+			result[lookupKey] = pruned
 		}
 		return result
 
 	case []interface{}:
 		result := make([]interface{}, len(v))
 		for i, item := range v {
-			result[i] = e.pruneObject(item, selections)
+			result[i] = e.pruneObject(item, selections, keepTypename)
 		}
 		return result
 
@@ -1092,6 +1699,35 @@ func (e *ExecutorV2) pruneObject(obj interface{}, selections []ast.Selection) in
 	}
 }
 
+// mergePrunedValues merges two prunings of the same value under different selections.
+func mergePrunedValues(a, b interface{}) interface{} {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			return b
+		}
+		for key, value := range bv {
+			if existing, ok := av[key]; ok {
+				value = mergePrunedValues(existing, value)
+			}
+			av[key] = value
+		}
+		return av
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return b
+		}
+		for i := range av {
+			av[i] = mergePrunedValues(av[i], bv[i])
+		}
+		return av
+	default:
+		return b
+	}
+}
+
 // getOperationFromDocument extracts the operation from a document.
 func getOperationFromDocument(doc *ast.Document) *ast.OperationDefinition {
 	if doc == nil {
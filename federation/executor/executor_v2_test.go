@@ -3,10 +3,15 @@ package executor_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
@@ -697,3 +702,198 @@ func jsonEqual(a, b interface{}) bool {
 	bJSON, _ := json.Marshal(b)
 	return string(aJSON) == string(bJSON)
 }
+
+func TestExecutorV2_MutationSerialExecution(t *testing.T) {
+	var mu sync.Mutex
+	order := make([]string, 0)
+
+	newServer := func(name string, delay time.Duration, data map[string]interface{}) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			order = append(order, name+":start")
+			mu.Unlock()
+			time.Sleep(delay)
+			mu.Lock()
+			order = append(order, name+":end")
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		}))
+	}
+
+	slow := newServer("createProduct", 50*time.Millisecond, map[string]interface{}{
+		"createProduct": map[string]interface{}{"__typename": "Product", "id": "1"},
+	})
+	defer slow.Close()
+	fast := newServer("createUser", 0, map[string]interface{}{
+		"createUser": map[string]interface{}{"__typename": "User", "id": "u1"},
+	})
+	defer fast.Close()
+	reviews := newServer("productReviews", 0, map[string]interface{}{
+		"_entities": []interface{}{map[string]interface{}{"__typename": "Product", "id": "1", "reviewCount": 3}},
+	})
+	defer reviews.Close()
+	profiles := newServer("userProfile", 0, map[string]interface{}{
+		"_entities": []interface{}{map[string]interface{}{"__typename": "User", "id": "u1", "nickname": "neo"}},
+	})
+	defer profiles.Close()
+
+	plan := &planner.PlanV2{
+		Steps: []*planner.StepV2{
+			{
+				ID:         0,
+				StepType:   planner.StepTypeQuery,
+				SubGraph:   createMockSubgraph("products", slow.URL),
+				ParentType: "Mutation",
+				SelectionSet: []ast.Selection{
+					&ast.Field{
+						Name: &ast.Name{Value: "createProduct"},
+						SelectionSet: []ast.Selection{
+							&ast.Field{Name: &ast.Name{Value: "__typename"}},
+							&ast.Field{Name: &ast.Name{Value: "id"}},
+						},
+					},
+				},
+				DependsOn: []int{},
+				Path:      []string{"Mutation"},
+			},
+			{
+				ID:         1,
+				StepType:   planner.StepTypeQuery,
+				SubGraph:   createMockSubgraph("accounts", fast.URL),
+				ParentType: "Mutation",
+				SelectionSet: []ast.Selection{
+					&ast.Field{
+						Name: &ast.Name{Value: "createUser"},
+						SelectionSet: []ast.Selection{
+							&ast.Field{Name: &ast.Name{Value: "__typename"}},
+							&ast.Field{Name: &ast.Name{Value: "id"}},
+						},
+					},
+				},
+				DependsOn: []int{},
+				Path:      []string{"Mutation"},
+			},
+			{
+				ID:         2,
+				StepType:   planner.StepTypeEntity,
+				SubGraph:   createMockSubgraphWithEntity("reviews", reviews.URL, "Product", []string{"id"}),
+				ParentType: "Product",
+				SelectionSet: []ast.Selection{
+					&ast.Field{Name: &ast.Name{Value: "reviewCount"}},
+				},
+				DependsOn:     []int{0},
+				Path:          []string{"Mutation", "createProduct"},
+				InsertionPath: []string{"Mutation", "createProduct"},
+			},
+			{
+				ID:         3,
+				StepType:   planner.StepTypeEntity,
+				SubGraph:   createMockSubgraphWithEntity("profiles", profiles.URL, "User", []string{"id"}),
+				ParentType: "User",
+				SelectionSet: []ast.Selection{
+					&ast.Field{Name: &ast.Name{Value: "nickname"}},
+				},
+				DependsOn:     []int{1},
+				Path:          []string{"Mutation", "createUser"},
+				InsertionPath: []string{"Mutation", "createUser"},
+			},
+		},
+		RootStepIndexes: []int{0, 1},
+		OperationType:   "mutation",
+	}
+
+	exec := executor.NewExecutorV2(http.DefaultClient, createMockSuperGraphV2())
+	resp, err := exec.Execute(context.Background(), plan, nil)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	expected := []string{
+		"createProduct:start", "createProduct:end", "productReviews:start", "productReviews:end",
+		"createUser:start", "createUser:end", "userProfile:start", "userProfile:end",
+	}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected serial execution %v, got %v", expected, order)
+	}
+
+	// Each entity step merges into the result of its own mutation field
+	data, _ := resp["data"].(map[string]interface{})
+	product, _ := data["createProduct"].(map[string]interface{})
+	if fmt.Sprint(product["reviewCount"]) != "3" {
+		t.Errorf("expected createProduct.reviewCount to be merged, got %v", data["createProduct"])
+	}
+	user, _ := data["createUser"].(map[string]interface{})
+	if user["nickname"] != "neo" {
+		t.Errorf("expected createUser.nickname to be merged, got %v", data["createUser"])
+	}
+	if _, ok := resp["errors"]; ok {
+		t.Errorf("expected no errors, got %v", resp["errors"])
+	}
+}
+
+// TestExecutorV2_Cancellation tests that steps after a client disconnect are skipped.
+func TestExecutorV2_Cancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var hosts []string
+	client := &http.Client{
+		Transport: testRoundTripper(func(req *http.Request) (*http.Response, error) {
+			hosts = append(hosts, req.URL.Host)
+			// The client disconnects once the root step has its response
+			cancel()
+			body := `{"data":{"product":{"__typename":"Product","id":"p1"}}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+
+	plan := &planner.PlanV2{
+		Steps: []*planner.StepV2{
+			{
+				ID:       0,
+				StepType: planner.StepTypeQuery,
+				SubGraph: createMockSubgraph("products", "http://products"),
+				SelectionSet: []ast.Selection{
+					&ast.Field{
+						Name: &ast.Name{Value: "product"},
+						SelectionSet: []ast.Selection{
+							&ast.Field{Name: &ast.Name{Value: "__typename"}},
+							&ast.Field{Name: &ast.Name{Value: "id"}},
+						},
+					},
+				},
+				DependsOn: []int{},
+				Path:      []string{"Query"},
+			},
+			{
+				ID:         1,
+				StepType:   planner.StepTypeEntity,
+				SubGraph:   createMockSubgraph("reviews", "http://reviews"),
+				ParentType: "Product",
+				SelectionSet: []ast.Selection{
+					&ast.Field{Name: &ast.Name{Value: "__typename"}},
+					&ast.Field{Name: &ast.Name{Value: "id"}},
+					&ast.Field{Name: &ast.Name{Value: "reviewCount"}},
+				},
+				DependsOn:     []int{0},
+				Path:          []string{"Query", "product"},
+				InsertionPath: []string{"Query", "product"},
+			},
+		},
+		RootStepIndexes: []int{0},
+	}
+
+	exec := executor.NewExecutorV2(client, createMockSuperGraphV2())
+	_, err := exec.Execute(ctx, plan, nil)
+	if !errors.Is(err, executor.ErrOperationCancelled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+	if len(hosts) != 1 || hosts[0] != "products" {
+		t.Errorf("expected only the root step to be fetched, got requests to %v", hosts)
+	}
+}
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// Failover retries the requests of a subgraph on fallback hosts when a host fails with
+// a transport error, a timeout or a 5xx status. Once the primary host fails it is
+// skipped for Cooldown, so requests go straight to the fallbacks.
+type Failover struct {
+	Hosts    []string      // Fallback hosts, tried in order after the primary
+	Cooldown time.Duration // How long the primary host is skipped after a failure
+
+	// OnFailover, when set, is called each time a request that failed on from is retried
+	// on to, e.g. to count failovers.
+	OnFailover func(ctx context.Context, subGraphName, from, to string)
+
+	mu            sync.Mutex
+	cooldownUntil time.Time
+}
+
+// NewFailover returns a Failover to hosts, skipping a failed primary for cooldown.
+func NewFailover(hosts []string, cooldown time.Duration) *Failover {
+	return &Failover{Hosts: hosts, Cooldown: cooldown}
+}
+
+// hosts returns the hosts to try in order, without primary while it cools down.
+func (f *Failover) hosts(primary string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Now().Before(f.cooldownUntil) && len(f.Hosts) > 0 {
+		return f.Hosts
+	}
+	return append([]string{primary}, f.Hosts...)
+}
+
+// primaryFailed starts the cooldown of the primary host.
+func (f *Failover) primaryFailed() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cooldownUntil = time.Now().Add(f.Cooldown)
+}
+
+// send sends the query of step to the endpoint of its subgraph for operationType,
+// failing over to the fallback hosts of the subgraph when it has any. The last result or error is returned when every host
+// fails. Each attempt is bounded by the per-subgraph timeout, the whole send by the
+// timeout hinted on the step, and the request first takes a slot of the request limiter.
+// Queries answered with retryable errors only are sent again, as configured by the error
+// mapping of the subgraph.
+func (e *ExecutorV2) send(ctx context.Context, step *planner.StepV2, operationType, query string, queryVars map[string]interface{}) (map[string]interface{}, error) {
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+	result, err := e.sendToHosts(ctx, step, operationType, query, queryVars)
+
+	mapping := e.ErrorMapping[step.SubGraph.Name]
+	if mapping == nil || operationType == string(ast.Mutation) {
+		return result, err
+	}
+	for i := 0; i < mapping.Retries && err == nil && ctx.Err() == nil && mapping.retryable(result); i++ {
+		retried, retryErr := e.sendToHosts(ctx, step, operationType, query, queryVars)
+		if retryErr != nil {
+			break
+		}
+		result = retried
+	}
+	return result, err
+}
+
+// sendToHosts implements send for one request.
+func (e *ExecutorV2) sendToHosts(ctx context.Context, step *planner.StepV2, operationType, query string, queryVars map[string]interface{}) (map[string]interface{}, error) {
+	name := step.SubGraph.Name
+	primary := e.Endpoints[name].URL(operationType, step.SubGraph.Host)
+	release, err := e.Limiter.acquire(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	client := e.clientFor(name)
+
+	failover := e.Failover[name]
+	if failover == nil {
+		result, _, err := e.attempt(ctx, client, name, primary, query, queryVars)
+		return result, err
+	}
+
+	var result map[string]interface{}
+	hosts := failover.hosts(primary)
+	for i, host := range hosts {
+		if i > 0 && failover.OnFailover != nil {
+			failover.OnFailover(ctx, name, hosts[i-1], host)
+		}
+
+		var status int
+		result, status, err = e.attempt(ctx, client, name, host, query, queryVars)
+		if err == nil && status < http.StatusInternalServerError {
+			return result, nil
+		}
+		// A request aborted because the client went away says nothing about the primary.
+		if host == primary && !errors.Is(ctx.Err(), context.Canceled) {
+			failover.primaryFailed()
+		}
+		// The operation itself is over; another host cannot help.
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return result, err
+}
+
+// attempt sends one request to host, bounded by the timeout of the subgraph.
+func (e *ExecutorV2) attempt(ctx context.Context, client *http.Client, subGraphName, host, query string, queryVars map[string]interface{}) (map[string]interface{}, int, error) {
+	reqCtx, cancel := withSubgraphTimeout(ctx, subGraphName)
+	defer cancel()
+	return e.Faults.do(reqCtx, subGraphName, func() (map[string]interface{}, int, error) {
+		return e.doRequest(reqCtx, client, host, query, queryVars)
+	})
+}
@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInjectedFault is the cause of subgraph requests failed by a FaultInjector.
+var ErrInjectedFault = errors.New("injected fault")
+
+// Fault describes the failures injected into the requests to one subgraph.
+type Fault struct {
+	Latency     time.Duration // Delay added before every request, within its timeout
+	ErrorRate   float64       // Fraction of requests failed with ErrorStatus without being sent
+	ErrorStatus int           // HTTP status of injected errors; 500 when zero
+	DropRate    float64       // Fraction of requests sent whose response is discarded
+}
+
+// FaultInjector fails subgraph requests on purpose, so partial failure handling can be
+// tested without touching the subgraphs. Its faults can be replaced while requests run.
+type FaultInjector struct {
+	faults atomic.Pointer[map[string]Fault]
+}
+
+// NewFaultInjector returns an injector applying faults, keyed by subgraph name.
+func NewFaultInjector(faults map[string]Fault) *FaultInjector {
+	f := &FaultInjector{}
+	f.SetFaults(faults)
+	return f
+}
+
+// Faults returns the faults currently injected, keyed by subgraph name.
+func (f *FaultInjector) Faults() map[string]Fault {
+	faults := make(map[string]Fault)
+	for name, fault := range *f.faults.Load() {
+		faults[name] = fault
+	}
+	return faults
+}
+
+// SetFaults replaces the injected faults; subgraphs without an entry are left alone.
+func (f *FaultInjector) SetFaults(faults map[string]Fault) {
+	copied := make(map[string]Fault, len(faults))
+	for name, fault := range faults {
+		copied[name] = fault
+	}
+	f.faults.Store(&copied)
+}
+
+// do sends a request to subGraphName with send, injecting its fault.
+func (f *FaultInjector) do(ctx context.Context, subGraphName string, send func() (map[string]interface{}, int, error)) (map[string]interface{}, int, error) {
+	if f == nil {
+		return send()
+	}
+	fault, ok := (*f.faults.Load())[subGraphName]
+	if !ok {
+		return send()
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, 0, &SubgraphHTTPError{Err: fmt.Errorf("failed to send request: %w", ctx.Err())}
+		}
+	}
+	if fault.ErrorRate > 0 && rand.Float64() < fault.ErrorRate {
+		status := fault.ErrorStatus
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		return nil, status, &SubgraphHTTPError{StatusCode: status, Err: fmt.Errorf("%w: status %d", ErrInjectedFault, status)}
+	}
+	if fault.DropRate > 0 && rand.Float64() < fault.DropRate {
+		if _, _, err := send(); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, &SubgraphHTTPError{Err: fmt.Errorf("failed to read response: %w: response dropped", ErrInjectedFault)}
+	}
+	return send()
+}
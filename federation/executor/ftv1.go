@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"context"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+// FederatedTraceHeader asks a subgraph to return an Apollo federated trace (ftv1)
+// in the extensions of its response.
+const FederatedTraceHeader = "apollo-federation-include-trace"
+
+// FederatedTraceExtension is the response extensions key under which subgraphs
+// return ftv1 traces and the gateway returns the stitched trace.
+const FederatedTraceExtension = "ftv1"
+
+// FederatedTrace is the stitched trace of one operation: the gateway timing plus one
+// FetchTrace per subgraph fetch, nested along the query plan dependencies.
+type FederatedTrace struct {
+	OperationName string        `json:"operationName,omitempty"`
+	OperationType string        `json:"operationType"`
+	StartTime     time.Time     `json:"startTime"`
+	EndTime       time.Time     `json:"endTime"`
+	DurationNs    int64         `json:"durationNs"`
+	Fetches       []*FetchTrace `json:"fetches"`
+}
+
+// FetchTrace is the trace of a single subgraph fetch (plan step).
+// FTV1 holds the base64-encoded protobuf trace returned by the subgraph, if any.
+type FetchTrace struct {
+	StepID        int           `json:"stepId"`
+	ServiceName   string        `json:"serviceName"`
+	Path          []interface{} `json:"path,omitempty"`
+	StartOffsetNs int64         `json:"startOffsetNs"`
+	DurationNs    int64         `json:"durationNs"`
+	FTV1          string        `json:"ftv1,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	Children      []*FetchTrace `json:"children,omitempty"`
+
+	start time.Time
+}
+
+type federatedTracingContextKey struct{}
+
+// SetFederatedTracingToContext enables ftv1 collection for executions using ctx.
+func SetFederatedTracingToContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, federatedTracingContextKey{}, true)
+}
+
+// IsFederatedTracingEnabled reports whether ftv1 collection is enabled for ctx.
+func IsFederatedTracingEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(federatedTracingContextKey{}).(bool)
+	return enabled
+}
+
+// recordFetchTrace stores the trace of a finished fetch for step.
+func (e *ExecutorV2) recordFetchTrace(execCtx *ExecutionContext, step *planner.StepV2, start time.Time, result map[string]interface{}, err error) {
+	fetch := &FetchTrace{
+		StepID:      step.ID,
+		ServiceName: step.SubGraph.Name,
+		Path:        e.buildErrorPath(step),
+		DurationNs:  time.Since(start).Nanoseconds(),
+		start:       start,
+	}
+	if err != nil {
+		fetch.Error = err.Error()
+	}
+	if extensions, ok := result["extensions"].(map[string]interface{}); ok {
+		fetch.FTV1, _ = extensions[FederatedTraceExtension].(string)
+	}
+
+	execCtx.mu.Lock()
+	execCtx.fetches[step.ID] = fetch
+	execCtx.mu.Unlock()
+}
+
+// buildFederatedTrace stitches the recorded fetches into a tree following the plan:
+// a fetch is nested under the first step it depends on.
+func (e *ExecutorV2) buildFederatedTrace(execCtx *ExecutionContext, start, end time.Time) *FederatedTrace {
+	trace := &FederatedTrace{
+		OperationName: execCtx.plan.OperationName,
+		OperationType: execCtx.plan.OperationType,
+		StartTime:     start,
+		EndTime:       end,
+		DurationNs:    end.Sub(start).Nanoseconds(),
+		Fetches:       make([]*FetchTrace, 0),
+	}
+
+	execCtx.mu.RLock()
+	defer execCtx.mu.RUnlock()
+
+	for _, step := range execCtx.plan.Steps {
+		fetch, ok := execCtx.fetches[step.ID]
+		if !ok {
+			continue
+		}
+		fetch.StartOffsetNs = fetch.start.Sub(start).Nanoseconds()
+
+		var parent *FetchTrace
+		for _, depID := range step.DependsOn {
+			if parent, ok = execCtx.fetches[depID]; ok {
+				break
+			}
+		}
+		if parent != nil {
+			parent.Children = append(parent.Children, fetch)
+		} else {
+			trace.Fetches = append(trace.Fetches, fetch)
+		}
+	}
+
+	return trace
+}
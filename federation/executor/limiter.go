@@ -0,0 +1,132 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// OverloadedErrorCode is the extensions.code set on errors of subgraph requests shed
+// because the request limits were saturated.
+const OverloadedErrorCode = "SUBGRAPH_OVERLOADED"
+
+// ErrSubgraphOverloaded is returned for subgraph requests shed by a RequestLimiter.
+var ErrSubgraphOverloaded = errors.New("too many concurrent subgraph requests")
+
+// RequestLimiter bounds the concurrent outbound subgraph requests of an executor, in
+// total and per subgraph. A request over a limit waits for a free slot in a queue; it is
+// shed with ErrSubgraphOverloaded when the queue is full or it waited QueueTimeout.
+type RequestLimiter struct {
+	// OnShed, when set, is called each time a request to subGraphName is shed, e.g. to
+	// count them.
+	OnShed func(ctx context.Context, subGraphName string)
+
+	global    *semaphore
+	subGraphs map[string]*semaphore
+}
+
+// semaphore is a counting semaphore with a bounded queue of waiters.
+type semaphore struct {
+	slots        chan struct{}
+	maxQueue     int64
+	queueTimeout time.Duration
+	waiting      atomic.Int64
+}
+
+// NewRequestLimiter returns a limiter allowing maxRequests concurrent requests in total
+// and subGraphMax[name] to each listed subgraph; zero or less means unlimited. Up to
+// maxQueue requests wait for a slot of each limit (unbounded when zero or less), each
+// for at most queueTimeout (until the operation ends when zero).
+func NewRequestLimiter(maxRequests int, subGraphMax map[string]int, maxQueue int, queueTimeout time.Duration) *RequestLimiter {
+	newSemaphore := func(size int) *semaphore {
+		if size <= 0 {
+			return nil
+		}
+		return &semaphore{
+			slots:        make(chan struct{}, size),
+			maxQueue:     int64(maxQueue),
+			queueTimeout: queueTimeout,
+		}
+	}
+
+	l := &RequestLimiter{
+		global:    newSemaphore(maxRequests),
+		subGraphs: make(map[string]*semaphore),
+	}
+	for name, size := range subGraphMax {
+		if sem := newSemaphore(size); sem != nil {
+			l.subGraphs[name] = sem
+		}
+	}
+	return l
+}
+
+// acquire takes a slot of the limit of subGraphName and then of the global limit, so a
+// request waiting on a saturated subgraph does not hold a global slot. The returned
+// release frees both.
+func (l *RequestLimiter) acquire(ctx context.Context, subGraphName string) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	sub := l.subGraphs[subGraphName]
+	if err := sub.acquire(ctx); err != nil {
+		return nil, l.shed(ctx, subGraphName, err)
+	}
+	if err := l.global.acquire(ctx); err != nil {
+		sub.release()
+		return nil, l.shed(ctx, subGraphName, err)
+	}
+	return func() {
+		l.global.release()
+		sub.release()
+	}, nil
+}
+
+func (l *RequestLimiter) shed(ctx context.Context, subGraphName string, err error) error {
+	if errors.Is(err, ErrSubgraphOverloaded) && l.OnShed != nil {
+		l.OnShed(ctx, subGraphName)
+	}
+	return err
+}
+
+// acquire takes a slot, queueing when none is free. A nil semaphore is unlimited.
+func (s *semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if waiting := s.waiting.Add(1); s.maxQueue > 0 && waiting > s.maxQueue {
+		s.waiting.Add(-1)
+		return fmt.Errorf("%w: queue is full", ErrSubgraphOverloaded)
+	}
+	defer s.waiting.Add(-1)
+
+	var timeout <-chan time.Time
+	if s.queueTimeout > 0 {
+		timer := time.NewTimer(s.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return fmt.Errorf("%w: waited %s for a free slot", ErrSubgraphOverloaded, s.queueTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *semaphore) release() {
+	if s != nil {
+		<-s.slots
+	}
+}
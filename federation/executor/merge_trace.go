@@ -0,0 +1,132 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+// Outcomes of a MergeOperation.
+const (
+	MergeOutcomeMerged        = "merged"        // The entity was merged into the object
+	MergeOutcomeTypeMismatch  = "typeMismatch"  // The object is not of the entity type of the step; nothing was merged
+	MergeOutcomeNullEntity    = "nullEntity"    // The subgraph returned null or a non-object for the entity
+	MergeOutcomeMissingEntity = "missingEntity" // The subgraph returned fewer entities than there are objects
+	MergeOutcomeUnusedEntity  = "unusedEntity"  // The subgraph returned more entities than there are objects
+	MergeOutcomeMissingPath   = "missingPath"   // The response has no object at the insertion path
+	MergeOutcomeNoEntities    = "noEntities"    // The subgraph response has no _entities
+	MergeOutcomeFailed        = "failed"        // The merge failed with Error
+)
+
+// MergeOperation is one merge of the result of an entity step into the response. Before
+// and After hash the merge target before and after a merge, so a merge that changed
+// nothing has equal hashes; operations that merged nothing only have Before.
+type MergeOperation struct {
+	Step          int      `json:"step"`
+	SubGraph      string   `json:"subgraph"`
+	InsertionPath []string `json:"insertionPath"`
+	EntityIndex   int      `json:"entityIndex"` // Index in the _entities of the step; -1 for the whole list or none
+	Outcome       string   `json:"outcome"`
+	Before        string   `json:"before,omitempty"`
+	After         string   `json:"after,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// MergeTrace collects the merge operations of one execution, to debug entity data that
+// silently fails to appear in responses. It is safe for concurrent use by the steps of
+// one plan.
+type MergeTrace struct {
+	mu         sync.Mutex
+	operations []MergeOperation
+}
+
+// NewMergeTrace creates an empty merge trace.
+func NewMergeTrace() *MergeTrace {
+	return &MergeTrace{}
+}
+
+type mergeTraceContextKey struct{}
+
+// SetMergeTraceToContext makes executions using ctx record their merge operations in
+// trace.
+func SetMergeTraceToContext(ctx context.Context, trace *MergeTrace) context.Context {
+	return context.WithValue(ctx, mergeTraceContextKey{}, trace)
+}
+
+// GetMergeTraceFromContext returns the merge trace attached to ctx, or nil.
+func GetMergeTraceFromContext(ctx context.Context) *MergeTrace {
+	trace, _ := ctx.Value(mergeTraceContextKey{}).(*MergeTrace)
+	return trace
+}
+
+// Operations returns the recorded merge operations ordered by step, in the order each
+// step merged its entities.
+func (t *MergeTrace) Operations() []MergeOperation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	operations := append([]MergeOperation(nil), t.operations...)
+	sort.SliceStable(operations, func(i, j int) bool {
+		return operations[i].Step < operations[j].Step
+	})
+	return operations
+}
+
+// record records a merge operation of step, with the snapshots of its target before and
+// after the merge. A nil trace records nothing.
+func (t *MergeTrace) record(step *planner.StepV2, entityIndex int, outcome string, before, after string, err error) {
+	if t == nil {
+		return
+	}
+	op := MergeOperation{
+		Step:          step.ID,
+		InsertionPath: step.InsertionPath,
+		EntityIndex:   entityIndex,
+		Outcome:       outcome,
+		Before:        before,
+		After:         after,
+	}
+	if step.SubGraph != nil {
+		op.SubGraph = step.SubGraph.Name
+	}
+	if err != nil {
+		op.Error = err.Error()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.operations = append(t.operations, op)
+}
+
+// snapshot returns a hash of value for a MergeOperation, or "" without a trace. Map keys
+// are encoded sorted, so equal values have equal hashes.
+func (t *MergeTrace) snapshot(value interface{}) string {
+	if t == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// valueAtPath returns the value at path in data, or nil when there is none.
+func valueAtPath(data map[string]interface{}, path []string) interface{} {
+	var current interface{} = data
+	for _, segment := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[segment]
+	}
+	return current
+}
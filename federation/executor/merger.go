@@ -4,6 +4,35 @@ import (
 	"fmt"
 )
 
+// mergeValue merges source into target and returns the result. Objects are merged field
+// by field and lists of equal length element by element, so that fields fetched by
+// different steps for the same nested object are all kept; other values are replaced
+// by source.
+func mergeValue(target, source interface{}) interface{} {
+	switch t := target.(type) {
+	case map[string]interface{}:
+		sourceMap, ok := source.(map[string]interface{})
+		if !ok {
+			return source
+		}
+		for k, v := range sourceMap {
+			t[k] = mergeValue(t[k], v)
+		}
+		return t
+	case []interface{}:
+		sourceList, ok := source.([]interface{})
+		if !ok || len(sourceList) != len(t) {
+			return source
+		}
+		for i := range t {
+			t[i] = mergeValue(t[i], sourceList[i])
+		}
+		return t
+	default:
+		return source
+	}
+}
+
 // Merge merges source data into target data at the specified path.
 // This function implements the recursive merge logic as described in the design document.
 // If path is empty, it merges at the root level.
@@ -17,7 +46,7 @@ func Merge(target map[string]interface{}, source interface{}, path []string) err
 			return fmt.Errorf("source must be a map when path is empty")
 		}
 		for k, v := range sourceMap {
-			target[k] = v
+			target[k] = mergeValue(target[k], v)
 		}
 		return nil
 	}
@@ -66,7 +95,7 @@ func Merge(target map[string]interface{}, source interface{}, path []string) err
 					return fmt.Errorf("source list element at index %d is not a map", i)
 				}
 				for k, v := range sourceElem {
-					targetElem[k] = v
+					targetElem[k] = mergeValue(targetElem[k], v)
 				}
 			} else {
 				// Recursively merge into the element
@@ -87,7 +116,7 @@ func Merge(target map[string]interface{}, source interface{}, path []string) err
 				return fmt.Errorf("source must be a map when merging into an object")
 			}
 			for k, v := range sourceMap {
-				obj[k] = v
+				obj[k] = mergeValue(obj[k], v)
 			}
 			return nil
 		}
@@ -0,0 +1,212 @@
+package executor
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// OrderedResponse wraps an execution result so that it encodes with object fields in
+// the order the client selected them, instead of the sorted key order used for Go maps.
+// Top-level keys are written as data, errors, extensions, then any others sorted; the
+// keys of each error as message, locations, path, extensions, then any others sorted.
+// Fields the document did not select, and the keys of extensions, are sorted, so equal
+// responses always encode to the same bytes.
+type OrderedResponse struct {
+	Response map[string]interface{}
+	Document *ast.Document
+}
+
+var _ json.Marshaler = OrderedResponse{}
+
+// MarshalJSON implements json.Marshaler.
+func (r OrderedResponse) MarshalJSON() ([]byte, error) {
+	var selections []ast.Selection
+	if op := getOperationFromDocument(r.Document); op != nil {
+		fragmentDefs := collectFragmentDefinitionsFromDocument(r.Document)
+		selections = expandFragmentsInSelections(op.SelectionSet, fragmentDefs)
+	}
+
+	keys := orderedKeys(r.Response, []string{"data", "errors", "extensions"})
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeKey(&buf, key); err != nil {
+			return nil, err
+		}
+		var err error
+		switch key {
+		case "data":
+			err = writeOrderedValue(&buf, r.Response[key], selections)
+		case "errors":
+			err = writeErrors(&buf, r.Response[key])
+		default:
+			err = writeValue(&buf, r.Response[key])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// writeOrderedValue writes value, ordering the fields of every object by selections.
+func writeOrderedValue(buf *bytes.Buffer, value interface{}, selections []ast.Selection) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		order, children := selectionOrder(selections)
+		keys := orderedKeys(v, order)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeKey(buf, key); err != nil {
+				return err
+			}
+			if err := writeOrderedValue(buf, v[key], children[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeOrderedValue(buf, item, selections); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	default:
+		return writeValue(buf, v)
+	}
+}
+
+// errorKeyOrder is the order of the well-known keys of a GraphQL error.
+var errorKeyOrder = []string{"message", "locations", "path", "extensions"}
+
+// writeErrors writes the errors of a response, ordering the keys of errors held as maps
+// by errorKeyOrder. Errors of other types are encoded as they are.
+func writeErrors(buf *bytes.Buffer, value interface{}) error {
+	var errs []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		errs = v
+	case []map[string]interface{}:
+		errs = make([]interface{}, 0, len(v))
+		for _, e := range v {
+			errs = append(errs, e)
+		}
+	default:
+		return writeValue(buf, value)
+	}
+
+	buf.WriteByte('[')
+	for i, e := range errs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			if err := writeValue(buf, e); err != nil {
+				return err
+			}
+			continue
+		}
+		buf.WriteByte('{')
+		for j, key := range orderedKeys(m, errorKeyOrder) {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeKey(buf, key); err != nil {
+				return err
+			}
+			if err := writeValue(buf, m[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// selectionOrder returns the response keys of selections in document order, together
+// with the merged child selections of each key (a key may be selected more than once).
+func selectionOrder(selections []ast.Selection) ([]string, map[string][]ast.Selection) {
+	order := make([]string, 0, len(selections))
+	children := make(map[string][]ast.Selection, len(selections))
+	for _, sel := range selections {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+		key := field.Name.String()
+		if field.Alias != nil && field.Alias.String() != "" {
+			key = field.Alias.String()
+		}
+		if _, seen := children[key]; !seen {
+			order = append(order, key)
+		}
+		children[key] = append(children[key], field.SelectionSet...)
+	}
+	return order, children
+}
+
+// orderedKeys returns the keys of m: first those listed in order, then the rest sorted.
+func orderedKeys(m map[string]interface{}, order []string) []string {
+	keys := make([]string, 0, len(m))
+	listed := make(map[string]bool, len(order))
+	for _, key := range order {
+		if _, ok := m[key]; ok && !listed[key] {
+			keys = append(keys, key)
+		}
+		listed[key] = true
+	}
+
+	rest := make([]string, 0)
+	for key := range m {
+		if !listed[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// writeKey writes an object key followed by a colon.
+func writeKey(buf *bytes.Buffer, key string) error {
+	if err := writeValue(buf, key); err != nil {
+		return err
+	}
+	buf.WriteByte(':')
+	return nil
+}
+
+// writeValue writes value using the standard encoder.
+func writeValue(buf *bytes.Buffer, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
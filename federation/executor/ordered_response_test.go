@@ -0,0 +1,109 @@
+package executor_test
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+func TestOrderedResponse_MarshalJSON(t *testing.T) {
+	query := `
+		query {
+			zeta { b a }
+			alpha: product { name id ...Extra }
+			...RootExtra
+		}
+		fragment Extra on Product { price }
+		fragment RootExtra on Query { middle }
+	`
+
+	l := lexer.New(query)
+	p := parser.New(l)
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse error: %v", p.Errors())
+	}
+
+	resp := map[string]interface{}{
+		"errors": []interface{}{map[string]interface{}{"message": "boom"}},
+		"data": map[string]interface{}{
+			"middle": 1,
+			"alpha":  map[string]interface{}{"price": 10, "id": "1", "name": "Widget"},
+			"zeta": []interface{}{
+				map[string]interface{}{"a": 1, "b": 2},
+				map[string]interface{}{"a": 3, "b": 4},
+			},
+		},
+	}
+
+	got, err := json.Marshal(executor.OrderedResponse{Response: resp, Document: doc})
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	want := `{"data":{"zeta":[{"b":2,"a":1},{"b":4,"a":3}],"alpha":{"name":"Widget","id":"1","price":10},"middle":1},"errors":[{"message":"boom"}]}`
+	if string(got) != want {
+		t.Errorf("unexpected encoding\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestOrderedResponse_MarshalJSON_Canonical(t *testing.T) {
+	p := parser.New(lexer.New(`{ product { name } }`))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse error: %v", p.Errors())
+	}
+
+	tests := []struct {
+		name string
+		resp map[string]interface{}
+		want string
+	}{
+		{
+			name: "error keys in specification order",
+			resp: map[string]interface{}{
+				"errors": []interface{}{map[string]interface{}{
+					"extensions": map[string]interface{}{"serviceName": "products", "code": "X"},
+					"path":       []interface{}{"product"},
+					"message":    "boom",
+					"locations":  []interface{}{map[string]interface{}{"line": 1, "column": 3}},
+					"custom":     true,
+				}},
+				"data": map[string]interface{}{"product": nil},
+			},
+			want: `{"data":{"product":null},"errors":[{"message":"boom","locations":[{"column":3,"line":1}],"path":["product"],"extensions":{"code":"X","serviceName":"products"},"custom":true}]}`,
+		},
+		{
+			name: "error maps without a document",
+			resp: map[string]interface{}{
+				"errors": []map[string]interface{}{{"extensions": map[string]string{"code": "X"}, "message": "bad"}},
+			},
+			want: `{"errors":[{"message":"bad","extensions":{"code":"X"}}]}`,
+		},
+		{
+			name: "unselected fields sorted after selected ones",
+			resp: map[string]interface{}{
+				"data": map[string]interface{}{"product": map[string]interface{}{"zip": 1, "name": "a", "extra": 2}},
+			},
+			want: `{"data":{"product":{"name":"a","extra":2,"zip":1}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				got, err := json.Marshal(executor.OrderedResponse{Response: tt.resp, Document: doc})
+				if err != nil {
+					t.Fatalf("MarshalJSON failed: %v", err)
+				}
+				if string(got) != tt.want {
+					t.Fatalf("unexpected encoding\n got: %s\nwant: %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+// PlanFailedError is returned by Execute, for contexts set with
+// SetPlanFailureToContext, when every root step of the plan failed, e.g. because every
+// subgraph it calls is down. Errors holds the errors recorded by the steps.
+type PlanFailedError struct {
+	Errors    []GraphQLError
+	SubGraphs []string // Subgraphs of the failed root steps, in plan order
+	// Unavailable reports that no root step received a response from its subgraph, as
+	// opposed to responses carrying errors and no data.
+	Unavailable bool
+}
+
+func (e *PlanFailedError) Error() string {
+	return fmt.Sprintf("every root step of the plan failed (%d errors)", len(e.Errors))
+}
+
+type planFailureContextKey struct{}
+
+// SetPlanFailureToContext makes executions using ctx return a *PlanFailedError
+// instead of a response whose root fields are all null.
+func SetPlanFailureToContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, planFailureContextKey{}, true)
+}
+
+// IsPlanFailureEnabled reports whether executions using ctx return a *PlanFailedError.
+func IsPlanFailureEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(planFailureContextKey{}).(bool)
+	return enabled
+}
+
+// planFailure returns the failure of the executed plan when every root step failed,
+// or nil. Root steps fail when their fetch failed or their response has no data.
+// Representations steps never fail, and expired deadlines are reported as timeouts.
+func (e *ExecutorV2) planFailure(execCtx *ExecutionContext) *PlanFailedError {
+	plan := execCtx.plan
+	if len(plan.RootStepIndexes) == 0 || execCtx.ctx.Err() != nil {
+		return nil
+	}
+
+	execCtx.mu.RLock()
+	defer execCtx.mu.RUnlock()
+
+	failure := &PlanFailedError{Unavailable: true}
+	for _, stepID := range plan.RootStepIndexes {
+		step := plan.Steps[stepID]
+		if step.StepType != planner.StepTypeQuery {
+			return nil
+		}
+		if !execCtx.unreachable[stepID] {
+			result, _ := execCtx.results[stepID].(map[string]interface{})
+			if _, ok := result["data"].(map[string]interface{}); ok {
+				return nil
+			}
+			failure.Unavailable = false
+		}
+		if step.SubGraph != nil {
+			failure.SubGraphs = append(failure.SubGraphs, step.SubGraph.Name)
+		}
+	}
+	failure.Errors = append([]GraphQLError(nil), execCtx.errors...)
+	return failure
+}
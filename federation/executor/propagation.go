@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+)
+
+type subgraphExtensionsContextKey struct{}
+
+type subgraphHeadersContextKey struct{}
+
+// SetSubgraphExtensionsToContext attaches extensions sent in the body of every subgraph
+// request made with ctx.
+func SetSubgraphExtensionsToContext(ctx context.Context, extensions map[string]interface{}) context.Context {
+	return context.WithValue(ctx, subgraphExtensionsContextKey{}, extensions)
+}
+
+// GetSubgraphExtensionsFromContext returns the subgraph request extensions attached to ctx.
+func GetSubgraphExtensionsFromContext(ctx context.Context) map[string]interface{} {
+	extensions, _ := ctx.Value(subgraphExtensionsContextKey{}).(map[string]interface{})
+	return extensions
+}
+
+// SetSubgraphHeadersToContext attaches headers set on every subgraph request made with ctx.
+func SetSubgraphHeadersToContext(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, subgraphHeadersContextKey{}, header)
+}
+
+// GetSubgraphHeadersFromContext returns the subgraph request headers attached to ctx.
+func GetSubgraphHeadersFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(subgraphHeadersContextKey{}).(http.Header)
+	return header
+}
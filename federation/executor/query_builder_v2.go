@@ -2,8 +2,10 @@ package executor
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/goccy/go-json"
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
 	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
 	"github.com/n9te9/graphql-parser/ast"
@@ -29,11 +31,35 @@ func (qb *QueryBuilderV2) Build(
 	representations []map[string]interface{},
 	variables map[string]interface{},
 	operationType string,
+) (string, map[string]interface{}, error) {
+	return qb.build(step, representations, variables, operationType, false)
+}
+
+// BuildSharingFragments is Build writing the field selection sets repeated in the query
+// once, as fragments spread where they are repeated, when that makes the query shorter.
+// Client fragments are inlined by the planner, so this keeps the queries of operations
+// reusing fragments, such as _entities queries for several boundary fields, small.
+func (qb *QueryBuilderV2) BuildSharingFragments(
+	step *planner.StepV2,
+	representations []map[string]interface{},
+	variables map[string]interface{},
+	operationType string,
+) (string, map[string]interface{}, error) {
+	return qb.build(step, representations, variables, operationType, true)
+}
+
+// build implements Build and BuildSharingFragments.
+func (qb *QueryBuilderV2) build(
+	step *planner.StepV2,
+	representations []map[string]interface{},
+	variables map[string]interface{},
+	operationType string,
+	share bool,
 ) (string, map[string]interface{}, error) {
 	if step.StepType == planner.StepTypeQuery {
-		return qb.buildRootQuery(step, variables, operationType)
+		return qb.buildRootQuery(step, variables, operationType, share)
 	}
-	return qb.buildEntityQuery(step, representations, variables)
+	return qb.buildEntityQuery(step, representations, variables, share)
 }
 
 // buildRootQuery builds a root query or mutation from selections.
@@ -41,6 +67,7 @@ func (qb *QueryBuilderV2) buildRootQuery(
 	step *planner.StepV2,
 	variables map[string]interface{},
 	operationType string,
+	share bool,
 ) (string, map[string]interface{}, error) {
 	var sb strings.Builder
 
@@ -74,13 +101,23 @@ func (qb *QueryBuilderV2) buildRootQuery(
 	sb.WriteString(" {\n")
 
 	// Write selections
+	var fragments *sharedFragments
+	if share {
+		var err error
+		if fragments, err = qb.findSharedFragments(step, step.SelectionSet, step.ParentType, "\t", nil); err != nil {
+			return "", nil, err
+		}
+	}
 	for _, sel := range step.SelectionSet {
-		if err := qb.writeSelection(&sb, sel, "\t", step, step.ParentType); err != nil {
+		if err := qb.writeSelection(&sb, sel, "\t", step, step.ParentType, nil, fragments); err != nil {
 			return "", nil, err
 		}
 	}
 
 	sb.WriteString("}")
+	if err := qb.writeFragments(&sb, step, nil, fragments); err != nil {
+		return "", nil, err
+	}
 	return sb.String(), variables, nil
 }
 
@@ -94,6 +131,7 @@ func (qb *QueryBuilderV2) collectVariables(selections []ast.Selection) []string
 	for v := range vars {
 		result = append(result, v)
 	}
+	sort.Strings(result)
 	return result
 }
 
@@ -144,14 +182,25 @@ func (qb *QueryBuilderV2) inferVariableType(varName string, variables map[string
 	}
 
 	// Fallback: infer from value
+	return qb.inferVariableTypeFromValue(varName, variables)
+}
+
+// inferVariableTypeFromValue infers the type of a variable from its value,
+// defaulting to String.
+func (qb *QueryBuilderV2) inferVariableTypeFromValue(varName string, variables map[string]interface{}) string {
 	if val, ok := variables[varName]; ok {
-		switch val.(type) {
+		switch v := val.(type) {
 		case string:
 			return "String"
 		case int, int32, int64:
 			return "Int"
 		case float32, float64:
 			return "Float"
+		case json.Number:
+			if _, err := v.Int64(); err == nil {
+				return "Int"
+			}
+			return "Float"
 		case bool:
 			return "Boolean"
 		}
@@ -179,24 +228,15 @@ func (qb *QueryBuilderV2) getVariableTypeFromSchema(varName string, step *planne
 
 // getArgumentTypeFromSchema gets the argument type from schema.
 func (qb *QueryBuilderV2) getArgumentTypeFromSchema(step *planner.StepV2, parentType, fieldName, argName string) string {
-	if step.SubGraph == nil || step.SubGraph.Schema == nil {
+	field := qb.getFieldDefinition(step, parentType, fieldName)
+	if field == nil {
 		return ""
 	}
 
-	// Find the parent type definition
-	for _, def := range step.SubGraph.Schema.Definitions {
-		if objType, ok := def.(*ast.ObjectTypeDefinition); ok && objType.Name.String() == parentType {
-			// Find the field
-			for _, field := range objType.Fields {
-				if field.Name.String() == fieldName {
-					// Find the argument
-					for _, arg := range field.Arguments {
-						if arg.Name.String() == argName {
-							return arg.Type.String()
-						}
-					}
-				}
-			}
+	// Find the argument
+	for _, arg := range field.Arguments {
+		if arg.Name.String() == argName {
+			return arg.Type.String()
 		}
 	}
 
@@ -205,24 +245,23 @@ func (qb *QueryBuilderV2) getArgumentTypeFromSchema(step *planner.StepV2, parent
 
 // getFieldType gets the field type name from schema.
 func (qb *QueryBuilderV2) getFieldType(step *planner.StepV2, parentType, fieldName string) string {
-	if step.SubGraph == nil || step.SubGraph.Schema == nil {
+	field := qb.getFieldDefinition(step, parentType, fieldName)
+	if field == nil {
 		return ""
 	}
 
-	// Find the parent type definition
-	for _, def := range step.SubGraph.Schema.Definitions {
-		if objType, ok := def.(*ast.ObjectTypeDefinition); ok && objType.Name.String() == parentType {
-			// Find the field
-			for _, field := range objType.Fields {
-				if field.Name.String() == fieldName {
-					// Extract the base type name (without [] or !)
-					return qb.extractBaseTypeName(field.Type.String())
-				}
-			}
-		}
+	// Extract the base type name (without [] or !)
+	return qb.extractBaseTypeName(field.Type.String())
+}
+
+// getFieldDefinition finds parentType.fieldName in the step's subgraph schema, including
+// type extensions (entities extended by the subgraph).
+func (qb *QueryBuilderV2) getFieldDefinition(step *planner.StepV2, parentType, fieldName string) *ast.FieldDefinition {
+	if step.SubGraph == nil || step.SubGraph.Schema == nil {
+		return nil
 	}
 
-	return ""
+	return step.SubGraph.FieldDefinition(parentType, fieldName)
 }
 
 // extractBaseTypeName extracts the base type name from a type string.
@@ -241,13 +280,32 @@ func (qb *QueryBuilderV2) buildEntityQuery(
 	step *planner.StepV2,
 	representations []map[string]interface{},
 	variables map[string]interface{},
+	share bool,
 ) (string, map[string]interface{}, error) {
 	if len(representations) == 0 {
 		return "", nil, fmt.Errorf("representations cannot be empty for entity query")
 	}
 
+	// Variables used by arguments inside the entity selections are forwarded under
+	// generated names, so they cannot collide with $representations.
+	entityVars := qb.collectEntityVariables(step, variables)
+	renames := make(map[string]string, len(entityVars))
+	newVariables := make(map[string]interface{}, len(entityVars)+1)
+
 	var sb strings.Builder
-	sb.WriteString("query ($representations: [_Any!]!) {\n")
+	sb.WriteString("query ($representations: [_Any!]!")
+	for i, v := range entityVars {
+		varName := fmt.Sprintf("v%d", i)
+		renames[v.name] = varName
+		if val, ok := variables[v.name]; ok {
+			newVariables[varName] = val
+		}
+		sb.WriteString(", $")
+		sb.WriteString(varName)
+		sb.WriteString(": ")
+		sb.WriteString(v.typ)
+	}
+	sb.WriteString(") {\n")
 	sb.WriteString("\t_entities(representations: $representations) {\n")
 
 	// Write inline fragment
@@ -256,8 +314,15 @@ func (qb *QueryBuilderV2) buildEntityQuery(
 	sb.WriteString(" {\n")
 
 	// Write selections
+	var fragments *sharedFragments
+	if share {
+		var err error
+		if fragments, err = qb.findSharedFragments(step, step.SelectionSet, step.ParentType, "\t\t\t", renames); err != nil {
+			return "", nil, err
+		}
+	}
 	for _, sel := range step.SelectionSet {
-		if err := qb.writeSelection(&sb, sel, "\t\t\t", step, step.ParentType); err != nil {
+		if err := qb.writeSelection(&sb, sel, "\t\t\t", step, step.ParentType, renames, fragments); err != nil {
 			return "", nil, err
 		}
 	}
@@ -265,19 +330,75 @@ func (qb *QueryBuilderV2) buildEntityQuery(
 	sb.WriteString("\t\t}\n")
 	sb.WriteString("\t}\n")
 	sb.WriteString("}")
-
-	// Add representations to variables
-	newVariables := make(map[string]interface{})
-	for k, v := range variables {
-		newVariables[k] = v
+	if err := qb.writeFragments(&sb, step, renames, fragments); err != nil {
+		return "", nil, err
 	}
+
 	newVariables["representations"] = representations
 
 	return sb.String(), newVariables, nil
 }
 
-// writeSelection writes a selection to the string builder.
-func (qb *QueryBuilderV2) writeSelection(sb *strings.Builder, sel ast.Selection, indent string, step *planner.StepV2, parentType string) error {
+// entityVariable is a client variable used inside the selections of an entity step.
+type entityVariable struct {
+	name string
+	typ  string
+}
+
+// collectEntityVariables returns the variables used by arguments in the selections of an
+// entity step in document order, typed from the subgraph schema when the variable is
+// passed directly as an argument and inferred from its value otherwise.
+func (qb *QueryBuilderV2) collectEntityVariables(step *planner.StepV2, variables map[string]interface{}) []entityVariable {
+	result := make([]entityVariable, 0)
+	seen := make(map[string]bool)
+
+	var walk func(selections []ast.Selection, parentType string)
+	walk = func(selections []ast.Selection, parentType string) {
+		for _, sel := range selections {
+			switch s := sel.(type) {
+			case *ast.Field:
+				for _, arg := range s.Arguments {
+					used := make(map[string]bool)
+					qb.collectVariablesFromValue(arg.Value, used)
+					names := make([]string, 0, len(used))
+					for name := range used {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+
+					for _, name := range names {
+						if seen[name] {
+							continue
+						}
+						seen[name] = true
+
+						typ := ""
+						if variable, ok := arg.Value.(*ast.Variable); ok && variable.Name == name {
+							typ = qb.getArgumentTypeFromSchema(step, parentType, s.Name.String(), arg.Name.String())
+						}
+						if typ == "" {
+							typ = qb.inferVariableTypeFromValue(name, variables)
+						}
+						result = append(result, entityVariable{name: name, typ: typ})
+					}
+				}
+				if len(s.SelectionSet) > 0 {
+					walk(s.SelectionSet, qb.getFieldType(step, parentType, s.Name.String()))
+				}
+			case *ast.InlineFragment:
+				walk(s.SelectionSet, s.TypeCondition.Name.String())
+			}
+		}
+	}
+	walk(step.SelectionSet, step.ParentType)
+
+	return result
+}
+
+// writeSelection writes a selection to the string builder. Variables named in renames
+// are written under their new names, and the selection sets of fields in fragments,
+// when set, as spreads of their fragment.
+func (qb *QueryBuilderV2) writeSelection(sb *strings.Builder, sel ast.Selection, indent string, step *planner.StepV2, parentType string, renames map[string]string, fragments *sharedFragments) error {
 	switch s := sel.(type) {
 	case *ast.Field:
 		fieldName := s.Name.String()
@@ -288,13 +409,23 @@ func (qb *QueryBuilderV2) writeSelection(sb *strings.Builder, sel ast.Selection,
 
 		sb.WriteString(indent)
 
+		// Fields renamed in the supergraph, such as prefixed root fields of stitched
+		// services, are sent under their remote name and aliased back
+		remoteName := fieldName
+		if step.SubGraph != nil {
+			remoteName = step.SubGraph.RemoteFieldName(parentType, fieldName)
+		}
+
 		// Write alias if present
 		if s.Alias != nil && s.Alias.String() != "" {
 			sb.WriteString(s.Alias.String())
 			sb.WriteString(": ")
+		} else if remoteName != fieldName {
+			sb.WriteString(fieldName)
+			sb.WriteString(": ")
 		}
 
-		sb.WriteString(s.Name.String())
+		sb.WriteString(remoteName)
 
 		// Write arguments if present
 		if len(s.Arguments) > 0 {
@@ -305,7 +436,7 @@ func (qb *QueryBuilderV2) writeSelection(sb *strings.Builder, sel ast.Selection,
 				}
 				sb.WriteString(arg.Name.String())
 				sb.WriteString(": ")
-				qb.writeValue(sb, arg.Value)
+				qb.writeValue(sb, arg.Value, renames)
 			}
 			sb.WriteString(")")
 		}
@@ -315,9 +446,13 @@ func (qb *QueryBuilderV2) writeSelection(sb *strings.Builder, sel ast.Selection,
 			// Get the field type for sub-selections
 			fieldType := qb.getFieldType(step, parentType, fieldName)
 			sb.WriteString(" {\n")
-			for _, subSel := range s.SelectionSet {
-				if err := qb.writeSelection(sb, subSel, indent+"\t", step, fieldType); err != nil {
-					return err
+			if name := fragments.spread(s); name != "" {
+				sb.WriteString(indent + "\t..." + name + "\n")
+			} else {
+				for _, subSel := range s.SelectionSet {
+					if err := qb.writeSelection(sb, subSel, indent+"\t", step, fieldType, renames, fragments); err != nil {
+						return err
+					}
 				}
 			}
 			sb.WriteString(indent)
@@ -332,7 +467,7 @@ func (qb *QueryBuilderV2) writeSelection(sb *strings.Builder, sel ast.Selection,
 		sb.WriteString(typeCondition)
 		sb.WriteString(" {\n")
 		for _, subSel := range s.SelectionSet {
-			if err := qb.writeSelection(sb, subSel, indent+"\t", step, typeCondition); err != nil {
+			if err := qb.writeSelection(sb, subSel, indent+"\t", step, typeCondition, renames, fragments); err != nil {
 				return err
 			}
 		}
@@ -349,8 +484,8 @@ func (qb *QueryBuilderV2) writeSelection(sb *strings.Builder, sel ast.Selection,
 	return nil
 }
 
-// writeValue writes a value to the string builder.
-func (qb *QueryBuilderV2) writeValue(sb *strings.Builder, val ast.Value) {
+// writeValue writes a value to the string builder, renaming variables found in renames.
+func (qb *QueryBuilderV2) writeValue(sb *strings.Builder, val ast.Value, renames map[string]string) {
 	switch v := val.(type) {
 	case *ast.StringValue:
 		sb.WriteString("\"")
@@ -364,14 +499,18 @@ func (qb *QueryBuilderV2) writeValue(sb *strings.Builder, val ast.Value) {
 		sb.WriteString(fmt.Sprintf("%t", v.Value))
 	case *ast.Variable:
 		sb.WriteString("$")
-		sb.WriteString(v.Name)
+		if renamed, ok := renames[v.Name]; ok {
+			sb.WriteString(renamed)
+		} else {
+			sb.WriteString(v.Name)
+		}
 	case *ast.ListValue:
 		sb.WriteString("[")
 		for i, item := range v.Values {
 			if i > 0 {
 				sb.WriteString(", ")
 			}
-			qb.writeValue(sb, item)
+			qb.writeValue(sb, item, renames)
 		}
 		sb.WriteString("]")
 	case *ast.ObjectValue:
@@ -382,7 +521,7 @@ func (qb *QueryBuilderV2) writeValue(sb *strings.Builder, val ast.Value) {
 			}
 			sb.WriteString(field.Name.String())
 			sb.WriteString(": ")
-			qb.writeValue(sb, field.Value)
+			qb.writeValue(sb, field.Value, renames)
 		}
 		sb.WriteString("}")
 	case *ast.EnumValue:
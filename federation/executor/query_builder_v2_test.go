@@ -1,12 +1,16 @@
 package executor_test
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
 	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
 	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
 	"github.com/n9te9/graphql-parser/token"
 )
 
@@ -196,3 +200,431 @@ func TestBuildQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildQuery_EntityArgumentForwarding(t *testing.T) {
+	subGraph, err := graph.NewSubGraphV2("reviews", []byte(`
+		type Review {
+			body(truncate: Int): String!
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews(first: Int!, filter: String): [Review!]!
+		}
+	`), "http://reviews.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+
+	step := &planner.StepV2{
+		ID:         1,
+		StepType:   planner.StepTypeEntity,
+		SubGraph:   subGraph,
+		ParentType: "Product",
+		SelectionSet: []ast.Selection{
+			&ast.Field{
+				Name: &ast.Name{Value: "reviews"},
+				Arguments: []*ast.Argument{
+					{Name: &ast.Name{Value: "first"}, Value: &ast.Variable{Name: "first"}},
+					{Name: &ast.Name{Value: "filter"}, Value: &ast.Variable{Name: "representations"}},
+				},
+				SelectionSet: []ast.Selection{
+					&ast.Field{
+						Name: &ast.Name{Value: "body"},
+						Arguments: []*ast.Argument{
+							{Name: &ast.Name{Value: "truncate"}, Value: &ast.Variable{Name: "first"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	representations := []map[string]interface{}{{"__typename": "Product", "id": "1"}}
+	variables := map[string]interface{}{"first": 5, "representations": "recent", "unused": true}
+
+	qb := executor.NewQueryBuilderV2(nil)
+	query, queryVars, err := qb.Build(step, representations, variables, "query")
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"query ($representations: [_Any!]!, $v0: Int!, $v1: String)",
+		"reviews(first: $v0, filter: $v1)",
+		"body(truncate: $v0)",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected query to contain %q, got:\n%s", want, query)
+		}
+	}
+
+	if queryVars["v0"] != 5 || queryVars["v1"] != "recent" {
+		t.Errorf("expected renamed variables to carry client values, got %v", queryVars)
+	}
+	if reps, ok := queryVars["representations"].([]map[string]interface{}); !ok || len(reps) != 1 {
+		t.Errorf("expected representations to be the entity representations, got %v", queryVars["representations"])
+	}
+	if _, ok := queryVars["unused"]; ok {
+		t.Errorf("expected unused client variables not to be forwarded, got %v", queryVars)
+	}
+}
+
+func TestBuildSharingFragments(t *testing.T) {
+	subGraph, err := graph.NewSubGraphV2("products", []byte(`
+		type Dimensions {
+			widthInCentimeters: Int
+			heightInCentimeters: Int
+			depthInCentimeters: Int
+			weightInGrams: Int
+		}
+
+		type Product {
+			id: ID!
+			name: String
+			dimensions: Dimensions
+			packaging: Dimensions
+		}
+
+		type Query {
+			products: [Product]
+			product(id: ID!): Product
+		}
+	`), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+
+	field := func(name string, selections ...ast.Selection) *ast.Field {
+		return &ast.Field{Name: &ast.Name{Value: name}, SelectionSet: selections}
+	}
+	dimensions := func(name string) *ast.Field {
+		return field(name, field("widthInCentimeters"), field("heightInCentimeters"), field("depthInCentimeters"), field("weightInGrams"))
+	}
+	product := func(name string) *ast.Field {
+		return field(name, field("id"), field("name"), dimensions("dimensions"), dimensions("packaging"))
+	}
+	featured := product("product")
+	featured.Alias = &ast.Name{Value: "featured"}
+	featured.Arguments = []*ast.Argument{{Name: &ast.Name{Value: "id"}, Value: &ast.Variable{Name: "id"}}}
+	step := &planner.StepV2{
+		ID:           1,
+		StepType:     planner.StepTypeQuery,
+		SubGraph:     subGraph,
+		ParentType:   "Query",
+		SelectionSet: []ast.Selection{product("products"), featured, field("products", field("id")), field("products", field("id"))},
+	}
+
+	qb := executor.NewQueryBuilderV2(nil)
+	query, _, err := qb.BuildSharingFragments(step, nil, map[string]interface{}{"id": "1"}, "query")
+	if err != nil {
+		t.Fatalf("BuildSharingFragments failed: %v", err)
+	}
+
+	// The products share one fragment, spreading the fragment of their dimensions
+	if strings.Count(query, "...f0") != 2 || strings.Count(query, "...f1") != 2 {
+		t.Errorf("expected the products and their dimensions to be spread twice, got:\n%s", query)
+	}
+	for _, want := range []string{"fragment f0 on Product {", "fragment f1 on Dimensions {", "featured: product(id: $id)"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected query to contain %q, got:\n%s", want, query)
+		}
+	}
+	if strings.Count(query, "widthInCentimeters") != 1 {
+		t.Errorf("expected the dimensions to be written once, got:\n%s", query)
+	}
+	// The repeated products { id } is too short to save bytes
+	if strings.Count(query, "fragment ") != 2 {
+		t.Errorf("expected two fragments, got:\n%s", query)
+	}
+
+	plain, _, err := qb.Build(step, nil, map[string]interface{}{"id": "1"}, "query")
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if strings.Contains(plain, "fragment") || len(plain) <= len(query) {
+		t.Errorf("expected Build to inline every selection set in a longer query, got:\n%s", plain)
+	}
+	if got, want := checkQuery(t, subGraph, query), checkQuery(t, subGraph, plain); got != want {
+		t.Errorf("expected the fragments to select what Build inlines\nwith fragments: %s\ninlined:        %s", got, want)
+	}
+}
+
+func TestBuildSharingFragments_EntityQuery(t *testing.T) {
+	subGraph, err := graph.NewSubGraphV2("inventory", []byte(`
+		type Dimensions {
+			widthInCentimeters: Int
+			heightInCentimeters: Int
+			depthInCentimeters: Int
+			lengthInCentimeters: Int
+			weightInGrams: Int
+			dimensionalWeightOfTheOuterCartonInKilograms: Int
+		}
+
+		type Shipment {
+			carrier: String
+			dimensions: Dimensions
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			dimensions: Dimensions
+			shipment: Shipment
+		}
+	`), "http://inventory.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+
+	field := func(name string, selections ...ast.Selection) *ast.Field {
+		return &ast.Field{Name: &ast.Name{Value: name}, SelectionSet: selections}
+	}
+	aliased := func(alias string, f *ast.Field) *ast.Field {
+		f.Alias = &ast.Name{Value: alias}
+		return f
+	}
+
+	// Twelve selection sets of dimensions, each selected twice, so that more than ten
+	// fragments are spread
+	names := []string{"widthInCentimeters", "heightInCentimeters", "depthInCentimeters", "lengthInCentimeters", "weightInGrams"}
+	var selections []ast.Selection
+	count := 0
+	for mask := 0; mask < 1<<len(names) && count < 12; mask++ {
+		var subset []string
+		for i, name := range names {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, name)
+			}
+		}
+		if len(subset) < 3 {
+			continue
+		}
+		for _, suffix := range []string{"a", "b"} {
+			var fields []ast.Selection
+			for _, name := range subset {
+				fields = append(fields, field(name))
+			}
+			selections = append(selections, aliased(fmt.Sprintf("d%d%s", count, suffix), field("dimensions", fields...)))
+		}
+		count++
+	}
+	// A shipment selected twice shares a fragment spreading the one of its dimensions
+	shipment := func(alias string) *ast.Field {
+		return aliased(alias, field("shipment", field("carrier"), field("dimensions", field("widthInCentimeters"), field("heightInCentimeters"), field("depthInCentimeters"))))
+	}
+	selections = append(selections, shipment("outbound"), shipment("inbound"))
+	// Spread last, as f13, a fragment of this single field would lengthen the query
+	const long = "dimensionalWeightOfTheOuterCartonInKilograms"
+	selections = append(selections, aliased("w0", field("dimensions", field(long))), aliased("w1", field("dimensions", field(long))))
+
+	step := &planner.StepV2{
+		ID:           1,
+		StepType:     planner.StepTypeEntity,
+		SubGraph:     subGraph,
+		ParentType:   "Product",
+		SelectionSet: selections,
+	}
+	representations := []map[string]interface{}{{"__typename": "Product", "id": "1"}}
+
+	qb := executor.NewQueryBuilderV2(nil)
+	query, _, err := qb.BuildSharingFragments(step, representations, nil, "query")
+	if err != nil {
+		t.Fatalf("BuildSharingFragments failed: %v", err)
+	}
+	plain, _, err := qb.Build(step, representations, nil, "query")
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if got := strings.Count(query, "\nfragment "); got != 13 {
+		t.Errorf("expected 13 fragments, got %d:\n%s", got, query)
+	}
+	if !strings.Contains(query, "fragment f12 on ") {
+		t.Errorf("expected fragments to be numbered past ten, got:\n%s", query)
+	}
+	if strings.Count(query, long) != 2 {
+		t.Errorf("expected %s to be written inline, got:\n%s", long, query)
+	}
+	if len(query) >= len(plain) {
+		t.Errorf("expected sharing fragments to shorten the query from %d bytes, got %d bytes", len(plain), len(query))
+	}
+	if got, want := checkQuery(t, subGraph, query), checkQuery(t, subGraph, plain); got != want {
+		t.Errorf("expected the fragments to select what Build inlines\nwith fragments: %s\ninlined:        %s", got, want)
+	}
+}
+
+func TestBuildSharingFragments_NeverLonger(t *testing.T) {
+	subGraph, err := graph.NewSubGraphV2("catalog", []byte(`
+		type Price {
+			a: Int
+			bb: Int
+			ccc: Int
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			price: Price
+		}
+
+		type Query {
+			product: Product
+		}
+	`), "http://catalog.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+
+	qb := executor.NewQueryBuilderV2(nil)
+	for _, stepType := range []planner.StepType{planner.StepTypeQuery, planner.StepTypeEntity} {
+		for fields := 1; fields <= 3; fields++ {
+			for copies := 2; copies <= 4; copies++ {
+				var selections []ast.Selection
+				for c := 0; c < copies; c++ {
+					price := &ast.Field{Alias: &ast.Name{Value: fmt.Sprintf("p%d", c)}, Name: &ast.Name{Value: "price"}}
+					for _, name := range []string{"a", "bb", "ccc"}[:fields] {
+						price.SelectionSet = append(price.SelectionSet, &ast.Field{Name: &ast.Name{Value: name}})
+					}
+					selections = append(selections, price)
+				}
+				step := &planner.StepV2{ID: 1, StepType: stepType, SubGraph: subGraph, ParentType: "Product", SelectionSet: selections}
+				var representations []map[string]interface{}
+				if stepType == planner.StepTypeQuery {
+					step.ParentType = "Query"
+					step.SelectionSet = []ast.Selection{&ast.Field{Name: &ast.Name{Value: "product"}, SelectionSet: selections}}
+				} else {
+					representations = []map[string]interface{}{{"__typename": "Product", "id": "1"}}
+				}
+
+				query, _, err := qb.BuildSharingFragments(step, representations, nil, "query")
+				if err != nil {
+					t.Fatalf("BuildSharingFragments failed: %v", err)
+				}
+				plain, _, err := qb.Build(step, representations, nil, "query")
+				if err != nil {
+					t.Fatalf("Build failed: %v", err)
+				}
+				if len(query) > len(plain) {
+					t.Errorf("%d copies of %d fields: expected at most %d bytes, got %d:\n%s", copies, fields, len(plain), len(query), query)
+				}
+			}
+		}
+	}
+}
+
+// checkQuery parses query and checks it against the schema of subGraph: each selected
+// field is declared on its parent type, with a selection set exactly when its type has
+// fields, and each fragment is defined once, on the type of the fields spreading it,
+// and spread. It returns the selections of the operation with fragments inlined.
+func checkQuery(t *testing.T, subGraph *graph.SubGraphV2, query string) string {
+	t.Helper()
+	p := parser.New(lexer.New(query))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("failed to parse query: %v\n%s", p.Errors(), query)
+	}
+
+	var op *ast.OperationDefinition
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.OperationDefinition:
+			op = d
+		case *ast.FragmentDefinition:
+			if _, ok := fragments[d.Name.String()]; ok {
+				t.Errorf("fragment %s is defined twice", d.Name.String())
+			}
+			fragments[d.Name.String()] = d
+		}
+	}
+	if op == nil {
+		t.Fatalf("expected an operation, got:\n%s", query)
+	}
+
+	composite := make(map[string]bool)
+	for _, def := range subGraph.Schema.Definitions {
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			composite[d.Name.String()] = true
+		case *ast.ObjectTypeExtension:
+			composite[d.Name.String()] = true
+		}
+	}
+
+	spread := make(map[string]bool)
+	var walk func(selections []ast.Selection, parentType string, depth int) string
+	walk = func(selections []ast.Selection, parentType string, depth int) string {
+		if depth > 32 {
+			t.Fatalf("fragments spread in cycle:\n%s", query)
+		}
+		var sb strings.Builder
+		for _, sel := range selections {
+			switch s := sel.(type) {
+			case *ast.Field:
+				name := s.Name.String()
+				if s.Alias != nil && s.Alias.String() != "" {
+					sb.WriteString(s.Alias.String() + ":")
+				}
+				sb.WriteString(name)
+				fieldType := "_Entity"
+				if name != "_entities" {
+					def := subGraph.FieldDefinition(parentType, name)
+					if def == nil {
+						t.Errorf("field %s is not declared on type %s", name, parentType)
+						continue
+					}
+					fieldType = namedType(def.Type)
+				}
+				if hasSelections := len(s.SelectionSet) > 0; hasSelections != (composite[fieldType] || fieldType == "_Entity") {
+					t.Errorf("field %s.%s of type %s is selected with selections %v", parentType, name, fieldType, hasSelections)
+				}
+				if len(s.SelectionSet) > 0 {
+					sb.WriteString("{" + walk(s.SelectionSet, fieldType, depth+1) + "}")
+				}
+				sb.WriteString(" ")
+			case *ast.InlineFragment:
+				typeCondition := s.TypeCondition.Name.String()
+				if parentType != "_Entity" && typeCondition != parentType {
+					t.Errorf("fragment on %s is spread in %s", typeCondition, parentType)
+				}
+				sb.WriteString("...on " + typeCondition + "{" + walk(s.SelectionSet, typeCondition, depth+1) + "} ")
+			case *ast.FragmentSpread:
+				name := s.Name.String()
+				fragment, ok := fragments[name]
+				if !ok {
+					t.Errorf("fragment %s is not defined", name)
+					continue
+				}
+				if typeCondition := fragment.TypeCondition.Name.String(); typeCondition != parentType {
+					t.Errorf("fragment %s on %s is spread in %s", name, typeCondition, parentType)
+				}
+				spread[name] = true
+				sb.WriteString(walk(fragment.SelectionSet, parentType, depth+1))
+			}
+		}
+		return sb.String()
+	}
+
+	rootType := "Query"
+	if op.Operation == ast.Mutation {
+		rootType = "Mutation"
+	}
+	selections := walk(op.SelectionSet, rootType, 0)
+	for name := range fragments {
+		if !spread[name] {
+			t.Errorf("fragment %s is defined but not spread", name)
+		}
+	}
+	return selections
+}
+
+// namedType returns the name of the named type of typ, unwrapping lists and non-null.
+func namedType(typ ast.Type) string {
+	switch t := typ.(type) {
+	case *ast.NonNullType:
+		return namedType(t.Type)
+	case *ast.ListType:
+		return namedType(t.Type)
+	case *ast.NamedType:
+		return t.Name.String()
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// SubgraphRequest is the GraphQL document and variables one step of a plan sent to its
+// subgraph. The variables of an entity step carry its representations.
+type SubgraphRequest struct {
+	Step      int                    `json:"step"`
+	SubGraph  string                 `json:"subgraph"`
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// SubgraphRequestLog collects the requests sent to subgraphs by one execution. It is
+// safe for concurrent use by the steps of one plan.
+type SubgraphRequestLog struct {
+	mu       sync.Mutex
+	requests []SubgraphRequest
+}
+
+// NewSubgraphRequestLog creates an empty request log.
+func NewSubgraphRequestLog() *SubgraphRequestLog {
+	return &SubgraphRequestLog{}
+}
+
+type subgraphRequestLogContextKey struct{}
+
+// SetSubgraphRequestLogToContext makes executions using ctx record the requests they
+// send to subgraphs in log.
+func SetSubgraphRequestLogToContext(ctx context.Context, log *SubgraphRequestLog) context.Context {
+	return context.WithValue(ctx, subgraphRequestLogContextKey{}, log)
+}
+
+// GetSubgraphRequestLogFromContext returns the request log attached to ctx, or nil.
+func GetSubgraphRequestLogFromContext(ctx context.Context) *SubgraphRequestLog {
+	log, _ := ctx.Value(subgraphRequestLogContextKey{}).(*SubgraphRequestLog)
+	return log
+}
+
+// Requests returns the recorded requests ordered by step.
+func (l *SubgraphRequestLog) Requests() []SubgraphRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	requests := append([]SubgraphRequest(nil), l.requests...)
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].Step < requests[j].Step
+	})
+	return requests
+}
+
+// record records the request of step to subGraphName.
+func (l *SubgraphRequestLog) record(step int, subGraphName, query string, variables map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, SubgraphRequest{
+		Step:      step,
+		SubGraph:  subGraphName,
+		Query:     query,
+		Variables: variables,
+	})
+}
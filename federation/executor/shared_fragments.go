@@ -0,0 +1,200 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// sharedFragments are the field selection sets of a step query written once, as named
+// fragments, and spread in the fields selecting them.
+type sharedFragments struct {
+	byField map[*ast.Field]*sharedFragment
+	used    []*sharedFragment // In the order their names are assigned
+}
+
+// sharedFragment is a fragment of a step query.
+type sharedFragment struct {
+	name       string // Assigned when first spread
+	typeName   string
+	selections []ast.Selection
+}
+
+// spread returns the name of the fragment written for the selection set of field, or ""
+// when it is written inline.
+func (f *sharedFragments) spread(field *ast.Field) string {
+	if f == nil {
+		return ""
+	}
+	fragment, ok := f.byField[field]
+	if !ok {
+		return ""
+	}
+	if fragment.name == "" {
+		fragment.name = fragmentName(len(f.used))
+		f.used = append(f.used, fragment)
+	}
+	return fragment.name
+}
+
+// selectionOccurrence is a field with a selection set at one place of a step query.
+type selectionOccurrence struct {
+	field       *ast.Field
+	key         string                 // The type of the field and its selection set as written
+	depth       int                    // The indentation of the field, in tabs
+	descendants []*selectionOccurrence // The occurrences in its selection set, at any depth
+	covered     bool                   // Not written, an ancestor being spread instead
+}
+
+// findSharedFragments returns the fragments of the fields of selections, of parentType
+// and written at indent, whose selection set is repeated so that writing it once
+// shortens the query, or nil. Larger selection sets are shared first, and the fields
+// repeated inside them are only counted once.
+func (qb *QueryBuilderV2) findSharedFragments(step *planner.StepV2, selections []ast.Selection, parentType, indent string, renames map[string]string) (*sharedFragments, error) {
+	byKey := make(map[string][]*selectionOccurrence)
+	if _, err := qb.collectSelectionOccurrences(step, selections, parentType, len(indent), renames, byKey); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for key, occurrences := range byKey {
+		if len(occurrences) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	// Names are assigned in the order fragments are spread, so the longest one that
+	// can be assigned is counted for each of them.
+	nameLen := len(fragmentName(len(keys) - 1))
+
+	fragments := &sharedFragments{byField: make(map[*ast.Field]*sharedFragment)}
+	for _, key := range keys {
+		var live []*selectionOccurrence
+		for _, occ := range byKey[key] {
+			if _, shared := fragments.byField[occ.field]; !occ.covered && !shared {
+				live = append(live, occ)
+			}
+		}
+		if len(live) < 2 {
+			continue
+		}
+		typeName, text, _ := strings.Cut(key, "\n")
+		if sharedSize(live, typeName, text, nameLen) >= inlineSize(live, text) {
+			continue
+		}
+		fragment := &sharedFragment{typeName: typeName, selections: live[0].field.SelectionSet}
+		for i, occ := range live {
+			fragments.byField[occ.field] = fragment
+			for _, descendant := range occ.descendants {
+				if i == 0 {
+					// Written in the fragment definition, whose fields are indented once
+					descendant.depth -= occ.depth
+					continue
+				}
+				descendant.covered = true
+			}
+		}
+	}
+	if len(fragments.byField) == 0 {
+		return nil, nil
+	}
+	return fragments, nil
+}
+
+// inlineSize returns the size of the selection set text, written at the depth of each
+// of occurrences.
+func inlineSize(occurrences []*selectionOccurrence, text string) int {
+	lines := strings.Count(text, "\n")
+	size := 0
+	for _, occ := range occurrences {
+		size += len(text) + lines*(occ.depth+1)
+	}
+	return size
+}
+
+// sharedSize returns the size of a spread of a fragment at the depth of each of
+// occurrences, and of the definition of the fragment on typeName.
+func sharedSize(occurrences []*selectionOccurrence, typeName, text string, nameLen int) int {
+	size := len("\n\nfragment ") + nameLen + len(" on ") + len(typeName) + len(" {\n") + len(text) + strings.Count(text, "\n") + len("}")
+	for _, occ := range occurrences {
+		size += occ.depth + 1 + len("...") + nameLen + len("\n")
+	}
+	return size
+}
+
+// fragmentName returns the name of the i-th fragment spread in a query.
+func fragmentName(i int) string {
+	return fmt.Sprintf("f%d", i)
+}
+
+// collectSelectionOccurrences adds the occurrences of the fields of selections, written
+// at depth, to byKey, and returns them with their descendants.
+func (qb *QueryBuilderV2) collectSelectionOccurrences(step *planner.StepV2, selections []ast.Selection, parentType string, depth int, renames map[string]string, byKey map[string][]*selectionOccurrence) ([]*selectionOccurrence, error) {
+	var occurrences []*selectionOccurrence
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if len(s.SelectionSet) == 0 {
+				continue
+			}
+			fieldType := qb.getFieldType(step, parentType, s.Name.String())
+			descendants, err := qb.collectSelectionOccurrences(step, s.SelectionSet, fieldType, depth+1, renames, byKey)
+			if err != nil {
+				return nil, err
+			}
+			if fieldType == "" {
+				occurrences = append(occurrences, descendants...)
+				continue
+			}
+			var sb strings.Builder
+			for _, subSel := range s.SelectionSet {
+				if err := qb.writeSelection(&sb, subSel, "", step, fieldType, renames, nil); err != nil {
+					return nil, err
+				}
+			}
+			occ := &selectionOccurrence{field: s, key: fieldType + "\n" + sb.String(), depth: depth, descendants: descendants}
+			byKey[occ.key] = append(byKey[occ.key], occ)
+			occurrences = append(occurrences, occ)
+			occurrences = append(occurrences, descendants...)
+
+		case *ast.InlineFragment:
+			typeCondition := parentType
+			if s.TypeCondition != nil {
+				typeCondition = s.TypeCondition.Name.String()
+			}
+			descendants, err := qb.collectSelectionOccurrences(step, s.SelectionSet, typeCondition, depth+1, renames, byKey)
+			if err != nil {
+				return nil, err
+			}
+			occurrences = append(occurrences, descendants...)
+		}
+	}
+	return occurrences, nil
+}
+
+// writeFragments writes the definitions of the fragments spread in the query, including
+// those spread in other fragments.
+func (qb *QueryBuilderV2) writeFragments(sb *strings.Builder, step *planner.StepV2, renames map[string]string, fragments *sharedFragments) error {
+	if fragments == nil {
+		return nil
+	}
+	for i := 0; i < len(fragments.used); i++ {
+		fragment := fragments.used[i]
+		sb.WriteString("\n\nfragment " + fragment.name + " on " + fragment.typeName + " {\n")
+		for _, sel := range fragment.selections {
+			if err := qb.writeSelection(sb, sel, "\t", step, fragment.typeName, renames, fragments); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("}")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ExecutionStats collects per-request execution statistics: one SubgraphCallStats per
+// subgraph fetched and the entity cache hits and misses. It is safe for concurrent use
+// by the steps of one plan.
+type ExecutionStats struct {
+	mu          sync.Mutex
+	subgraphs   map[string]*SubgraphCallStats
+	cacheHits   int
+	cacheMisses int
+}
+
+// SubgraphCallStats summarises the requests sent to one subgraph.
+type SubgraphCallStats struct {
+	Calls      int   `json:"calls"`
+	Errors     int   `json:"errors"`
+	DurationNs int64 `json:"durationNs"`
+}
+
+// NewExecutionStats creates an empty statistics collector.
+func NewExecutionStats() *ExecutionStats {
+	return &ExecutionStats{subgraphs: make(map[string]*SubgraphCallStats)}
+}
+
+type executionStatsContextKey struct{}
+
+// SetExecutionStatsToContext makes executions using ctx record their statistics in stats.
+func SetExecutionStatsToContext(ctx context.Context, stats *ExecutionStats) context.Context {
+	return context.WithValue(ctx, executionStatsContextKey{}, stats)
+}
+
+// GetExecutionStatsFromContext returns the statistics collector attached to ctx, or nil.
+func GetExecutionStatsFromContext(ctx context.Context) *ExecutionStats {
+	stats, _ := ctx.Value(executionStatsContextKey{}).(*ExecutionStats)
+	return stats
+}
+
+// Subgraphs returns a copy of the per-subgraph call statistics.
+func (s *ExecutionStats) Subgraphs() map[string]SubgraphCallStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subgraphs := make(map[string]SubgraphCallStats, len(s.subgraphs))
+	for name, stats := range s.subgraphs {
+		subgraphs[name] = *stats
+	}
+	return subgraphs
+}
+
+// Requests returns the number of requests sent to subgraphs.
+func (s *ExecutionStats) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := 0
+	for _, stats := range s.subgraphs {
+		requests += stats.Calls
+	}
+	return requests
+}
+
+// CacheHits returns how many entities were served from the entity cache.
+func (s *ExecutionStats) CacheHits() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cacheHits
+}
+
+// CacheMisses returns how many cacheable entities had to be fetched from a subgraph.
+func (s *ExecutionStats) CacheMisses() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cacheMisses
+}
+
+// recordCall records one request to subGraphName. A transport error or a response
+// carrying GraphQL errors counts as an error.
+func (s *ExecutionStats) recordCall(subGraphName string, d time.Duration, result map[string]interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.subgraphs[subGraphName]
+	if !ok {
+		stats = &SubgraphCallStats{}
+		s.subgraphs[subGraphName] = stats
+	}
+	stats.Calls++
+	stats.DurationNs += d.Nanoseconds()
+	if errs, hasErrors := result["errors"]; err != nil || (hasErrors && errs != nil) {
+		stats.Errors++
+	}
+}
+
+// recordCache records the entity cache lookups of one entity step.
+func (s *ExecutionStats) recordCache(hits, misses int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cacheHits += hits
+	s.cacheMisses += misses
+}
@@ -0,0 +1,309 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/graphqlws"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/websocket"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// subscriptionHandshakeTimeout bounds the WebSocket handshake with a subgraph and the
+// wait for its connection_ack.
+const subscriptionHandshakeTimeout = 10 * time.Second
+
+// SubscriptionError is returned by Subscribe when the subgraph rejects the
+// subscription with an error message.
+type SubscriptionError struct {
+	Errors []interface{} // GraphQL errors sent by the subgraph
+}
+
+func (e *SubscriptionError) Error() string {
+	return fmt.Sprintf("subscription failed with %d errors", len(e.Errors))
+}
+
+// maxSubscriptionBatch bounds the events of a subscription enriched together.
+const maxSubscriptionBatch = 100
+
+// Subscribe runs the subscription plan over a graphql-transport-ws connection to the
+// subgraph owning its root field and calls onEvent with the pruned response of every
+// event, in order. The entity steps of the plan enrich every event with the fields of
+// other subgraphs; events received while others are being enriched are enriched
+// together, with one _entities request per step. It returns nil when the subgraph
+// completes the subscription, the error of onEvent if it fails, and ctx.Err() once ctx
+// is done.
+func (e *ExecutorV2) Subscribe(
+	ctx context.Context,
+	plan *planner.PlanV2,
+	variables map[string]interface{},
+	onEvent func(map[string]interface{}) error,
+) error {
+	if plan.OperationType != string(ast.Subscription) {
+		return fmt.Errorf("invalid plan: %s is not a subscription", plan.OperationType)
+	}
+	if len(plan.RootStepIndexes) != 1 {
+		return errors.New("invalid plan: a subscription must select fields of a single subgraph")
+	}
+	if err := e.validateDAG(plan); err != nil {
+		return fmt.Errorf("invalid plan: %w", err)
+	}
+	step := plan.Steps[plan.RootStepIndexes[0]]
+	if step.SubGraph == nil {
+		return fmt.Errorf("step %d has nil subgraph", step.ID)
+	}
+
+	query, queryVars, err := e.buildQuery(step, nil, variables, plan.OperationType)
+	if err != nil {
+		return fmt.Errorf("failed to build root query: %w", err)
+	}
+
+	url := e.Endpoints[step.SubGraph.Name].SubscriptionURL(step.SubGraph.Host)
+	conn, err := e.dialSubscription(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", step.SubGraph.Name, err)
+	}
+	defer conn.Close(websocket.CloseNormal, "") //nolint:errcheck
+
+	// Closing the connection unblocks the reads once ctx is done or enrichment failed.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close(websocket.CloseGoingAway, "") //nolint:errcheck
+	})
+	defer stop()
+
+	payload := graphqlws.SubscribePayload{Query: query, Variables: queryVars}
+	if extensions := GetSubgraphExtensionsFromContext(ctx); len(extensions) > 0 {
+		payload.Extensions = extensions
+	}
+	if err := graphqlws.Write(conn, graphqlws.Message{ID: "1", Type: graphqlws.Subscribe, Payload: payload}); err != nil {
+		return subscriptionReadError(ctx, err)
+	}
+
+	events := make(chan map[string]interface{}, maxSubscriptionBatch)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- e.readEvents(ctx, conn, step, events)
+		close(events)
+	}()
+
+	for event := range events {
+		batch := []map[string]interface{}{event}
+	drain:
+		for len(batch) < maxSubscriptionBatch {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, event)
+			default:
+				break drain
+			}
+		}
+
+		resps, err := e.executeEvents(ctx, plan, variables, step, batch)
+		if err != nil {
+			return err
+		}
+		for _, resp := range resps {
+			if err := onEvent(resp); err != nil {
+				return err
+			}
+		}
+	}
+	return <-readErr
+}
+
+// readEvents sends the results of the next messages of conn to events, verified
+// against the schema of the subgraph of step, until the subscription ends.
+func (e *ExecutorV2) readEvents(ctx context.Context, conn *websocket.Conn, step *planner.StepV2, events chan<- map[string]interface{}) error {
+	for {
+		msg, err := graphqlws.Read(conn)
+		if err != nil {
+			return subscriptionReadError(ctx, err)
+		}
+		switch msg.Type {
+		case graphqlws.Ping:
+			if err := graphqlws.Write(conn, graphqlws.Message{Type: graphqlws.Pong}); err != nil {
+				return subscriptionReadError(ctx, err)
+			}
+		case graphqlws.Next:
+			var result map[string]interface{}
+			if err := graphqlws.DecodePayload(msg.Payload, &result); err != nil {
+				return fmt.Errorf("invalid next message from %s: %w", step.SubGraph.Name, err)
+			}
+			e.Verifier.verify(ctx, step, result)
+			select {
+			case events <- result:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case graphqlws.Error:
+			var errs []interface{}
+			graphqlws.DecodePayload(msg.Payload, &errs) //nolint:errcheck
+			return &SubscriptionError{Errors: errs}
+		case graphqlws.Complete:
+			return nil
+		}
+	}
+}
+
+// executeEvents executes plan for every event of batch, the results of its root step,
+// and returns their responses in order. The events of a batch are executed as one
+// event whose root fields are lists of their values, so each entity step sends one
+// request for the whole batch; the response is then split per event. Events with
+// errors are executed on their own, so their errors keep their paths.
+func (e *ExecutorV2) executeEvents(
+	ctx context.Context,
+	plan *planner.PlanV2,
+	variables map[string]interface{},
+	step *planner.StepV2,
+	batch []map[string]interface{},
+) ([]map[string]interface{}, error) {
+	var combinable []int
+	for i, event := range batch {
+		if _, ok := event["data"].(map[string]interface{}); ok && event["errors"] == nil {
+			combinable = append(combinable, i)
+		}
+	}
+
+	resps := make([]map[string]interface{}, len(batch))
+	if len(plan.Steps) > 1 && len(combinable) > 1 {
+		combined, err := e.executeCombined(ctx, plan, variables, step, batch, combinable)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range combinable {
+			resps[i] = combined[j]
+		}
+	}
+	for i, event := range batch {
+		if resps[i] != nil {
+			continue
+		}
+		resp, err := e.execute(ctx, plan, variables, map[int]map[string]interface{}{step.ID: event})
+		if err != nil {
+			return nil, err
+		}
+		resps[i] = resp
+	}
+	return resps, nil
+}
+
+// executeCombined executes the events of batch at indexes as one and splits the
+// response. Errors with the index of an event after their root field belong to that
+// event; the other errors belong to every event.
+func (e *ExecutorV2) executeCombined(
+	ctx context.Context,
+	plan *planner.PlanV2,
+	variables map[string]interface{},
+	step *planner.StepV2,
+	batch []map[string]interface{},
+	indexes []int,
+) ([]map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	for j, i := range indexes {
+		for key, value := range batch[i]["data"].(map[string]interface{}) {
+			values, ok := data[key].([]interface{})
+			if !ok {
+				values = make([]interface{}, len(indexes))
+			}
+			values[j] = value
+			data[key] = values
+		}
+	}
+
+	resp, err := e.execute(ctx, plan, variables, map[int]map[string]interface{}{step.ID: {"data": data}})
+	if err != nil {
+		return nil, err
+	}
+
+	combinedData, _ := resp["data"].(map[string]interface{})
+	eventErrors := make([][]GraphQLError, len(indexes))
+	errs, _ := resp["errors"].([]GraphQLError)
+	for _, err := range errs {
+		if len(err.Path) >= 2 {
+			if j, ok := err.Path[1].(int); ok && j < len(indexes) {
+				err.Path = append([]interface{}{err.Path[0]}, err.Path[2:]...)
+				eventErrors[j] = append(eventErrors[j], err)
+				continue
+			}
+		}
+		for j := range eventErrors {
+			eventErrors[j] = append(eventErrors[j], err)
+		}
+	}
+
+	resps := make([]map[string]interface{}, len(indexes))
+	for j := range indexes {
+		eventData := make(map[string]interface{}, len(combinedData))
+		for key, values := range combinedData {
+			if list, ok := values.([]interface{}); ok && len(list) == len(indexes) {
+				eventData[key] = list[j]
+			} else {
+				eventData[key] = values
+			}
+		}
+		eventResp := make(map[string]interface{}, len(resp))
+		for key, value := range resp {
+			eventResp[key] = value
+		}
+		eventResp["data"] = eventData
+		delete(eventResp, "errors")
+		if len(eventErrors[j]) > 0 {
+			eventResp["errors"] = eventErrors[j]
+		}
+		resps[j] = eventResp
+	}
+	return resps, nil
+}
+
+// dialSubscription opens a graphql-transport-ws connection to url, forwarding the
+// subgraph headers of ctx, and waits for the subgraph to acknowledge it.
+func (e *ExecutorV2) dialSubscription(ctx context.Context, url string) (*websocket.Conn, error) {
+	handshakeCtx, cancel := context.WithTimeout(ctx, subscriptionHandshakeTimeout)
+	defer cancel()
+
+	header := make(http.Header)
+	for k, v := range GetSubgraphHeadersFromContext(ctx) {
+		header[k] = v
+	}
+	conn, err := websocket.Dial(handshakeCtx, url, []string{graphqlws.Subprotocol}, header)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, _ := handshakeCtx.Deadline()
+	conn.SetReadDeadline(deadline) //nolint:errcheck
+	if err := graphqlws.Write(conn, graphqlws.Message{Type: graphqlws.ConnectionInit}); err != nil {
+		conn.Close(websocket.CloseNormal, "") //nolint:errcheck
+		return nil, err
+	}
+	for {
+		msg, err := graphqlws.Read(conn)
+		if err != nil {
+			conn.Close(websocket.CloseNormal, "") //nolint:errcheck
+			return nil, fmt.Errorf("no connection_ack: %w", err)
+		}
+		if msg.Type == graphqlws.ConnectionAck {
+			break
+		}
+	}
+	conn.SetReadDeadline(time.Time{}) //nolint:errcheck
+	return conn, nil
+}
+
+// subscriptionReadError returns the error ending a subscription: ctx.Err() when the
+// connection was closed because ctx is done.
+func subscriptionReadError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("subscription connection failed: %w", err)
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// TimeoutErrorCode is the extensions.code set on errors caused by an expired
+// operation or subgraph deadline.
+const TimeoutErrorCode = "GATEWAY_TIMEOUT"
+
+type subgraphTimeoutsContextKey struct{}
+
+// SetSubgraphTimeoutsToContext attaches per-subgraph request timeouts (subgraph name →
+// timeout) to ctx. Each subgraph request is bounded by its timeout in addition to any
+// deadline already carried by ctx.
+func SetSubgraphTimeoutsToContext(ctx context.Context, timeouts map[string]time.Duration) context.Context {
+	return context.WithValue(ctx, subgraphTimeoutsContextKey{}, timeouts)
+}
+
+// GetSubgraphTimeoutsFromContext returns the per-subgraph timeouts attached to ctx.
+func GetSubgraphTimeoutsFromContext(ctx context.Context) map[string]time.Duration {
+	t, ok := ctx.Value(subgraphTimeoutsContextKey{}).(map[string]time.Duration)
+	if !ok {
+		return nil
+	}
+
+	return t
+}
+
+// withSubgraphTimeout bounds ctx by the timeout configured for subGraphName, if any.
+func withSubgraphTimeout(ctx context.Context, subGraphName string) (context.Context, context.CancelFunc) {
+	if d, ok := GetSubgraphTimeoutsFromContext(ctx)[subGraphName]; ok && d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, func() {}
+}
+
+// isTimeoutError reports whether err was caused by an expired deadline.
+func isTimeoutError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,387 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+// ResponseMismatch is a value of a subgraph response that the schema of the subgraph
+// does not allow, a sign that the subgraph drifted from the SDL it was composed with.
+type ResponseMismatch struct {
+	SubGraph string
+	Path     []interface{} // Path of the value in the data of the subgraph response
+	Reason   string
+}
+
+// ResponseVerifier checks subgraph responses against the schema of their subgraph: that
+// returned fields exist on their type, that values have the kind of their type (object,
+// list, enum value or built-in scalar), that non-null fields are not null and that
+// selected fields are present. Every response is walked, so it is meant for staging.
+// Responses are never modified; mismatches are only reported.
+type ResponseVerifier struct {
+	onMismatch func(ctx context.Context, mismatch ResponseMismatch)
+
+	mu      sync.Mutex
+	schemas map[string]*verifierSchema // Subgraph name → index of its schema
+}
+
+// NewResponseVerifier returns a verifier calling onMismatch for every mismatch found.
+// onMismatch is called from the goroutines executing steps.
+func NewResponseVerifier(onMismatch func(ctx context.Context, mismatch ResponseMismatch)) *ResponseVerifier {
+	return &ResponseVerifier{
+		onMismatch: onMismatch,
+		schemas:    make(map[string]*verifierSchema),
+	}
+}
+
+type verifierKind int
+
+const (
+	verifierKindScalar verifierKind = iota
+	verifierKindObject
+	verifierKindInterface
+	verifierKindUnion
+	verifierKindEnum
+)
+
+// verifierSchema indexes the output types of one subgraph schema.
+type verifierSchema struct {
+	doc   *ast.Document // Indexed document, replaced when the subgraph is updated
+	types map[string]*verifierType
+}
+
+type verifierType struct {
+	kind     verifierKind
+	fields   map[string]ast.Type // Object and interface fields
+	values   map[string]bool     // Enum values
+	possible map[string]bool     // Object types of an interface or union
+}
+
+// schema returns the index of the schema of subGraph, building it on first use and
+// whenever the subgraph was recomposed with another document.
+func (v *ResponseVerifier) schema(name string, doc *ast.Document) *verifierSchema {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if s, ok := v.schemas[name]; ok && s.doc == doc {
+		return s
+	}
+	s := newVerifierSchema(doc)
+	v.schemas[name] = s
+	return s
+}
+
+func newVerifierSchema(doc *ast.Document) *verifierSchema {
+	s := &verifierSchema{doc: doc, types: make(map[string]*verifierType)}
+	typ := func(name string, kind verifierKind) *verifierType {
+		t, ok := s.types[name]
+		if !ok {
+			t = &verifierType{
+				fields:   make(map[string]ast.Type),
+				values:   make(map[string]bool),
+				possible: make(map[string]bool),
+			}
+			s.types[name] = t
+		}
+		t.kind = kind
+		return t
+	}
+	object := func(name string, interfaces []*ast.NamedType, fields []*ast.FieldDefinition) {
+		t := typ(name, verifierKindObject)
+		for _, field := range fields {
+			t.fields[field.Name.String()] = field.Type
+		}
+		for _, iface := range interfaces {
+			typ(iface.Name.String(), verifierKindInterface).possible[name] = true
+		}
+	}
+	iface := func(name string, fields []*ast.FieldDefinition) {
+		t := typ(name, verifierKindInterface)
+		for _, field := range fields {
+			t.fields[field.Name.String()] = field.Type
+		}
+	}
+	union := func(name string, members []*ast.NamedType) {
+		t := typ(name, verifierKindUnion)
+		for _, member := range members {
+			t.possible[member.Name.String()] = true
+		}
+	}
+	enum := func(name string, values []*ast.EnumValueDefinition) {
+		t := typ(name, verifierKindEnum)
+		for _, value := range values {
+			t.values[value.Name.String()] = true
+		}
+	}
+
+	if doc == nil {
+		return s
+	}
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			object(d.Name.String(), d.Interfaces, d.Fields)
+		case *ast.ObjectTypeExtension:
+			object(d.Name.String(), d.Interfaces, d.Fields)
+		case *ast.InterfaceTypeDefinition:
+			iface(d.Name.String(), d.Fields)
+		case *ast.InterfaceTypeExtension:
+			iface(d.Name.String(), d.Fields)
+		case *ast.UnionTypeDefinition:
+			union(d.Name.String(), d.Types)
+		case *ast.UnionTypeExtension:
+			union(d.Name.String(), d.Types)
+		case *ast.EnumTypeDefinition:
+			enum(d.Name.String(), d.Values)
+		case *ast.EnumTypeExtension:
+			enum(d.Name.String(), d.Values)
+		case *ast.ScalarTypeDefinition:
+			typ(d.Name.String(), verifierKindScalar)
+		}
+	}
+	return s
+}
+
+// verify reports the mismatches of result, the response of step, against the schema
+// of its subgraph. v may be nil.
+func (v *ResponseVerifier) verify(ctx context.Context, step *planner.StepV2, result map[string]interface{}) {
+	if v == nil || step.SubGraph == nil {
+		return
+	}
+	data, ok := result["data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	w := &responseWalk{
+		verifier: v,
+		ctx:      ctx,
+		subGraph: step.SubGraph.Name,
+		schema:   v.schema(step.SubGraph.Name, step.SubGraph.Schema),
+	}
+	switch step.StepType {
+	case planner.StepTypeQuery:
+		w.object(step.ParentType, data, step.SelectionSet, nil)
+	case planner.StepTypeEntity:
+		entities, ok := data["_entities"].([]interface{})
+		if !ok {
+			w.report([]interface{}{"_entities"}, "expected a list")
+			return
+		}
+		for i, entity := range entities {
+			w.named(step.ParentType, entity, step.SelectionSet, []interface{}{"_entities", i})
+		}
+	}
+}
+
+// responseWalk walks one subgraph response.
+type responseWalk struct {
+	verifier *ResponseVerifier
+	ctx      context.Context
+	subGraph string
+	schema   *verifierSchema
+}
+
+func (w *responseWalk) report(path []interface{}, reason string) {
+	if w.verifier.onMismatch == nil {
+		return
+	}
+	w.verifier.onMismatch(w.ctx, ResponseMismatch{
+		SubGraph: w.subGraph,
+		Path:     append([]interface{}(nil), path...),
+		Reason:   reason,
+	})
+}
+
+// value checks value against the type t of the field at path.
+func (w *responseWalk) value(t ast.Type, value interface{}, selections []ast.Selection, path []interface{}) {
+	switch typ := t.(type) {
+	case *ast.NonNullType:
+		if value == nil {
+			w.report(path, fmt.Sprintf("null for non-null type %s", typ.String()))
+			return
+		}
+		w.value(typ.Type, value, selections, path)
+	case *ast.ListType:
+		if value == nil {
+			return
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			w.report(path, fmt.Sprintf("expected a list for type %s", typ.String()))
+			return
+		}
+		for i, item := range items {
+			w.value(typ.Type, item, selections, append(path, i))
+		}
+	case *ast.NamedType:
+		w.named(typ.Name.String(), value, selections, path)
+	}
+}
+
+// named checks value against the named type typeName. Custom scalars and types the
+// subgraph schema does not define accept any value.
+func (w *responseWalk) named(typeName string, value interface{}, selections []ast.Selection, path []interface{}) {
+	if value == nil {
+		return
+	}
+	if ok, builtin := builtinScalarValue(typeName, value); builtin {
+		if !ok {
+			w.report(path, fmt.Sprintf("invalid %s value", typeName))
+		}
+		return
+	}
+
+	t, ok := w.schema.types[typeName]
+	if !ok {
+		return
+	}
+	switch t.kind {
+	case verifierKindEnum:
+		if s, ok := value.(string); !ok || !t.values[s] {
+			w.report(path, fmt.Sprintf("invalid %s value", typeName))
+		}
+	case verifierKindObject, verifierKindInterface, verifierKindUnion:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			w.report(path, fmt.Sprintf("expected an object of type %s", typeName))
+			return
+		}
+		w.object(typeName, obj, selections, path)
+	}
+}
+
+// object checks the fields of obj, a value of typeName, selected with selections.
+func (w *responseWalk) object(typeName string, obj map[string]interface{}, selections []ast.Selection, path []interface{}) {
+	t, ok := w.schema.types[typeName]
+	if !ok {
+		return
+	}
+	if t.kind != verifierKindObject {
+		if name, ok := obj["__typename"].(string); ok {
+			if !t.possible[name] {
+				w.report(path, fmt.Sprintf("__typename %s is not a possible type of %s", name, typeName))
+				return
+			}
+			typeName = name
+			if t, ok = w.schema.types[name]; !ok {
+				return
+			}
+		}
+	}
+
+	fields := w.selectedFields(typeName, selections, nil, false)
+	byKey := make(map[string]*selectedField, len(fields))
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+
+	for key, value := range obj {
+		name := key
+		var children []ast.Selection
+		if f, ok := byKey[key]; ok {
+			name, children = f.name, f.selections
+		}
+		if name == "__typename" || t.kind == verifierKindUnion {
+			continue
+		}
+		fieldType, ok := t.fields[name]
+		if !ok {
+			w.report(append(path, key), fmt.Sprintf("field %s is not defined on type %s", name, typeName))
+			continue
+		}
+		w.value(fieldType, value, children, append(path, key))
+	}
+
+	for _, f := range fields {
+		if _, ok := obj[f.key]; !ok && !f.conditional {
+			w.report(append(path, f.key), fmt.Sprintf("selected field %s is missing", f.name))
+		}
+	}
+}
+
+// selectedField is a field of a selection set, merged across its occurrences.
+type selectedField struct {
+	key         string // Response key: the alias or the field name
+	name        string
+	selections  []ast.Selection
+	conditional bool // Selected under @skip or @include only
+}
+
+// selectedFields returns the fields selections select on typeName, in document order,
+// including those of inline fragments applying to it.
+func (w *responseWalk) selectedFields(typeName string, selections []ast.Selection, fields []*selectedField, conditional bool) []*selectedField {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			key := s.Name.String()
+			if s.Alias != nil && s.Alias.String() != "" {
+				key = s.Alias.String()
+			}
+			var field *selectedField
+			for _, f := range fields {
+				if f.key == key {
+					field = f
+				}
+			}
+			if field == nil {
+				field = &selectedField{key: key, name: s.Name.String(), conditional: true}
+				fields = append(fields, field)
+			}
+			field.selections = append(field.selections, s.SelectionSet...)
+			field.conditional = field.conditional && (conditional || len(s.Directives) > 0)
+		case *ast.InlineFragment:
+			if s.TypeCondition != nil {
+				condition := s.TypeCondition.Name.String()
+				if t, ok := w.schema.types[condition]; condition != typeName && (!ok || !t.possible[typeName]) {
+					continue
+				}
+			}
+			fields = w.selectedFields(typeName, s.SelectionSet, fields, conditional || len(s.Directives) > 0)
+		}
+	}
+	return fields
+}
+
+// builtinScalarValue reports whether value is valid for the built-in scalar typeName,
+// and whether typeName is a built-in scalar at all. Numbers are json.Number when the
+// response was decoded with UseNumber.
+func builtinScalarValue(typeName string, value interface{}) (ok, builtin bool) {
+	switch typeName {
+	case "String":
+		_, ok = value.(string)
+	case "Boolean":
+		_, ok = value.(bool)
+	case "ID":
+		switch value.(type) {
+		case string, json.Number, float64:
+			ok = true
+		}
+	case "Int":
+		switch v := value.(type) {
+		case json.Number:
+			_, err := v.Int64()
+			ok = err == nil
+		case float64:
+			ok = v == math.Trunc(v)
+		}
+	case "Float":
+		switch v := value.(type) {
+		case json.Number:
+			_, err := v.Float64()
+			ok = err == nil
+		case float64:
+			ok = true
+		}
+	default:
+		return false, false
+	}
+	return ok, true
+}
@@ -0,0 +1,351 @@
+// Package federation is the library API for embedding schema composition, query
+// planning and execution in other servers, without the gateway's HTTP handler or its
+// YAML configuration. Its interfaces and option structs stay backwards compatible
+// across minor releases; the graph, planner and executor packages it builds on are
+// lower level and may change.
+package federation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/operation"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+// Supergraph is a composed schema with the subgraphs resolving each field.
+type Supergraph struct {
+	superGraph *graph.SuperGraphV2
+	schemaHash string // Identifies the subgraph SDLs it was composed from
+}
+
+// SDL returns the composed schema in SDL.
+func (s *Supergraph) SDL() string {
+	return s.superGraph.SDL()
+}
+
+// FieldOwners returns the names of the subgraphs resolving the field, in composition
+// order. It is empty when the field does not exist.
+func (s *Supergraph) FieldOwners(typeName, fieldName string) []string {
+	var names []string
+	for _, subGraph := range s.superGraph.GetSubGraphsForField(typeName, fieldName) {
+		names = append(names, subGraph.Name)
+	}
+	return names
+}
+
+// MarshalComposition serializes the field ownership of the supergraph for
+// ComposerOptions.Composition.
+func (s *Supergraph) MarshalComposition() ([]byte, error) {
+	return s.superGraph.MarshalComposition(s.schemaHash)
+}
+
+// Plan is the query plan of one operation. It does not depend on the variables of the
+// request, so it can be cached and executed concurrently.
+type Plan struct {
+	plan *planner.PlanV2
+}
+
+// OperationType returns the type of the planned operation: query, mutation or
+// subscription.
+func (p *Plan) OperationType() string {
+	return p.plan.OperationType
+}
+
+// OperationName returns the name of the planned operation, empty when it is anonymous.
+func (p *Plan) OperationName() string {
+	return p.plan.OperationName
+}
+
+// Steps returns the requests the plan sends to subgraphs.
+func (p *Plan) Steps() []Step {
+	steps := make([]Step, 0, len(p.plan.Steps))
+	for _, step := range p.plan.Steps {
+		s := Step{
+			ID:        step.ID,
+			Entity:    step.StepType == planner.StepTypeEntity,
+			Path:      append([]string(nil), step.Path...),
+			DependsOn: append([]int(nil), step.DependsOn...),
+		}
+		if step.SubGraph != nil {
+			s.Subgraph = step.SubGraph.Name
+		}
+		steps = append(steps, s)
+	}
+	return steps
+}
+
+// Step is one request of a plan to a subgraph.
+type Step struct {
+	ID        int
+	Subgraph  string
+	Entity    bool     // Fetches _entities rather than root fields
+	Path      []string // Response path the step resolves
+	DependsOn []int    // IDs of the steps that must complete first
+}
+
+// Subgraph is a subgraph to compose.
+type Subgraph struct {
+	Name string
+	URL  string // GraphQL endpoint the executor sends requests to
+	SDL  string
+}
+
+// SchemaComposer composes subgraphs into a supergraph.
+type SchemaComposer interface {
+	Compose(ctx context.Context, subgraphs []Subgraph) (*Supergraph, error)
+}
+
+// Planner plans operations against a supergraph.
+type Planner interface {
+	Plan(ctx context.Context, req Request) (*Plan, error)
+}
+
+// Response is the result of an operation, with data and errors like a GraphQL response.
+// It encodes to JSON with fields in the order the operation selected them.
+type Response struct {
+	ordered executor.OrderedResponse
+}
+
+var _ json.Marshaler = Response{}
+
+// Data returns the data of the response, nil when nothing was resolved.
+func (r Response) Data() map[string]any {
+	data, _ := r.ordered.Response["data"].(map[string]any)
+	return data
+}
+
+// Errors returns the errors of the response.
+func (r Response) Errors() []Error {
+	var errs []Error
+	switch v := r.ordered.Response["errors"].(type) {
+	case []executor.GraphQLError:
+		for _, e := range v {
+			errs = append(errs, Error{Message: e.Message, Path: e.Path, Extensions: e.Extensions})
+		}
+	case []any:
+		for _, e := range v {
+			switch e := e.(type) {
+			case executor.GraphQLError:
+				errs = append(errs, Error{Message: e.Message, Path: e.Path, Extensions: e.Extensions})
+			case map[string]any:
+				message, _ := e["message"].(string)
+				path, _ := e["path"].([]any)
+				extensions, _ := e["extensions"].(map[string]any)
+				errs = append(errs, Error{Message: message, Path: path, Extensions: extensions})
+			}
+		}
+	}
+	return errs
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Response) MarshalJSON() ([]byte, error) {
+	return r.ordered.MarshalJSON()
+}
+
+// Error is a GraphQL error of a Response.
+type Error struct {
+	Message    string
+	Path       []any
+	Extensions map[string]any
+}
+
+// Executor executes plans by sending requests to the subgraphs. Subgraph failures are
+// reported in the errors of the response; the error is only set when no response could
+// be built at all.
+type Executor interface {
+	Execute(ctx context.Context, plan *Plan, variables map[string]any) (Response, error)
+}
+
+// Transport sends the HTTP requests of the executor to subgraphs. Any http.RoundTripper
+// is a Transport.
+type Transport interface {
+	RoundTrip(req *http.Request) (*http.Response, error)
+}
+
+// Request is an operation to plan.
+type Request struct {
+	Query         string
+	OperationName string // Required when Query holds several operations
+
+	// OverrideLabels are the progressive @override labels active for the request.
+	OverrideLabels map[string]bool
+}
+
+// ComposerOptions configures a SchemaComposer.
+type ComposerOptions struct {
+	// Composition is the output of Supergraph.MarshalComposition for earlier subgraphs.
+	// It is reused, skipping the slowest part of composition, while their SDLs are
+	// unchanged, and ignored otherwise.
+	Composition []byte
+}
+
+// PlannerOptions configures a Planner. The zero value plans like the gateway's default
+// configuration.
+type PlannerOptions struct {
+	CostModel        *CostModel    // Chooses among subgraphs resolving a shareable root field
+	OwnerStrategy    OwnerStrategy // Chooses among subgraphs resolving a shareable field
+	PruneUnfetchable bool          // Drops root fields no subgraph resolves instead of failing
+}
+
+// CostModel estimates the cost of a plan to choose among subgraphs resolving the same
+// field.
+type CostModel struct {
+	SubgraphLatency  map[string]float64 // Subgraph name → relative latency weight (default 1)
+	ListSizeEstimate float64            // Estimated number of items in a list field (default 1)
+}
+
+// OwnerStrategy decides which subgraph resolves a field that several subgraphs can
+// resolve.
+type OwnerStrategy string
+
+const (
+	// OwnerStrategyLocal resolves the field in the step already fetching its parent
+	// when possible. It is the default.
+	OwnerStrategyLocal OwnerStrategy = "local"
+	// OwnerStrategyFirst always resolves the field from its first owner in composition
+	// order.
+	OwnerStrategyFirst OwnerStrategy = "first"
+	// OwnerStrategyCheapest resolves the field like OwnerStrategyLocal, and otherwise
+	// from the owner the CostModel rates cheapest.
+	OwnerStrategyCheapest OwnerStrategy = "cheapest"
+)
+
+// ExecutorOptions configures an Executor.
+type ExecutorOptions struct {
+	// Transport sends the requests to subgraphs without an entry in SubgraphTransports.
+	// When nil, http.DefaultTransport is used.
+	Transport Transport
+	// SubgraphTransports are the transports of individual subgraphs, keyed by name.
+	SubgraphTransports map[string]Transport
+	// SubgraphTimeouts bound each request to a subgraph, keyed by name. Requests are
+	// otherwise bounded by the context given to Execute only.
+	SubgraphTimeouts map[string]time.Duration
+}
+
+// NewSchemaComposer returns a SchemaComposer.
+func NewSchemaComposer(opts ComposerOptions) SchemaComposer {
+	return &composer{opts: opts}
+}
+
+type composer struct {
+	opts ComposerOptions
+}
+
+// Compose implements SchemaComposer.
+func (c *composer) Compose(ctx context.Context, subgraphs []Subgraph) (*Supergraph, error) {
+	sources := make([]graph.SubGraphSource, 0, len(subgraphs))
+	sdls := make(map[string]string, len(subgraphs))
+	for _, subgraph := range subgraphs {
+		sources = append(sources, graph.SubGraphSource{Name: subgraph.Name, SDL: []byte(subgraph.SDL), Host: subgraph.URL})
+		sdls[subgraph.Name] = subgraph.SDL
+	}
+	subGraphs, err := graph.NewSubGraphsV2(sources)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	schemaHash := registry.SchemaHash(sdls)
+	if c.opts.Composition != nil {
+		superGraph, err := graph.NewSuperGraphV2FromComposition(subGraphs, c.opts.Composition, schemaHash)
+		if err == nil {
+			return &Supergraph{superGraph: superGraph, schemaHash: schemaHash}, nil
+		}
+	}
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		return nil, fmt.Errorf("composition failed: %w", err)
+	}
+	return &Supergraph{superGraph: superGraph, schemaHash: schemaHash}, nil
+}
+
+// NewPlanner returns a Planner for superGraph. An unknown OwnerStrategy is reported by
+// every call to Plan.
+func NewPlanner(superGraph *Supergraph, opts PlannerOptions) Planner {
+	p := planner.NewPlannerV2(superGraph.superGraph)
+	if opts.CostModel != nil {
+		p.CostModel = &planner.CostModel{
+			SubGraphLatency:  opts.CostModel.SubgraphLatency,
+			ListSizeEstimate: opts.CostModel.ListSizeEstimate,
+		}
+	}
+	strategy, err := planner.ParseOwnerStrategy(string(opts.OwnerStrategy))
+	p.OwnerStrategy = strategy
+	p.PruneUnfetchable = opts.PruneUnfetchable
+	return &queryPlanner{planner: p, err: err}
+}
+
+type queryPlanner struct {
+	planner *planner.PlannerV2
+	err     error // Invalid options
+}
+
+// Plan implements Planner.
+func (p *queryPlanner) Plan(ctx context.Context, req Request) (*Plan, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parse := parser.New(lexer.New(req.Query))
+	doc := parse.ParseDocument()
+	if len(parse.Errors()) > 0 {
+		return nil, fmt.Errorf("failed to parse query: %v", parse.Errors())
+	}
+	doc, _, err := operation.Select(doc, req.OperationName)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := p.planner.WithOverrideLabels(req.OverrideLabels).Plan(doc, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{plan: plan}, nil
+}
+
+// NewExecutor returns an Executor for plans of superGraph.
+func NewExecutor(superGraph *Supergraph, opts ExecutorOptions) Executor {
+	transport := opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	e := executor.NewExecutorV2(&http.Client{Transport: transport}, superGraph.superGraph)
+	if len(opts.SubgraphTransports) > 0 {
+		e.SubGraphClients = make(map[string]*http.Client, len(opts.SubgraphTransports))
+		for name, t := range opts.SubgraphTransports {
+			e.SubGraphClients[name] = &http.Client{Transport: t}
+		}
+	}
+	return &planExecutor{executor: e, timeouts: opts.SubgraphTimeouts}
+}
+
+type planExecutor struct {
+	executor *executor.ExecutorV2
+	timeouts map[string]time.Duration
+}
+
+// Execute implements Executor.
+func (e *planExecutor) Execute(ctx context.Context, plan *Plan, variables map[string]any) (Response, error) {
+	if len(e.timeouts) > 0 {
+		ctx = executor.SetSubgraphTimeoutsToContext(ctx, e.timeouts)
+	}
+	resp, err := e.executor.Execute(ctx, plan.plan, variables)
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{ordered: executor.OrderedResponse{Response: resp, Document: plan.plan.OriginalDocument}}, nil
+}
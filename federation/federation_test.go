@@ -0,0 +1,169 @@
+package federation_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation"
+)
+
+const productsSDL = `
+	type Product @key(fields: "id") {
+		id: ID!
+		name: String!
+	}
+
+	type Query {
+		topProducts: [Product!]!
+	}
+`
+
+const inventorySDL = `
+	extend type Product @key(fields: "id") {
+		id: ID! @external
+		inStock: Boolean!
+	}
+`
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestLibrary(t *testing.T) {
+	newServer := func(data map[string]any) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{"data": data}) //nolint:errcheck
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	products := newServer(map[string]any{"topProducts": []any{
+		map[string]any{"__typename": "Product", "id": "1", "name": "Table"},
+	}})
+	inventory := newServer(map[string]any{"_entities": []any{
+		map[string]any{"__typename": "Product", "id": "1", "inStock": true},
+	}})
+	subgraphs := []federation.Subgraph{
+		{Name: "products", URL: products.URL, SDL: productsSDL},
+		{Name: "inventory", URL: inventory.URL, SDL: inventorySDL},
+	}
+	ctx := context.Background()
+
+	superGraph, err := federation.NewSchemaComposer(federation.ComposerOptions{}).Compose(ctx, subgraphs)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+
+	t.Run("plans and executes an operation", func(t *testing.T) {
+		plan, err := federation.NewPlanner(superGraph, federation.PlannerOptions{}).Plan(ctx, federation.Request{
+			Query:         "query A { topProducts { name } } query B { topProducts { name inStock } }",
+			OperationName: "B",
+		})
+		if err != nil {
+			t.Fatalf("Plan failed: %v", err)
+		}
+		steps := plan.Steps()
+		if len(steps) != 2 || steps[0].Subgraph != "products" || steps[1].Subgraph != "inventory" || !steps[1].Entity {
+			t.Fatalf("expected a products query step and an inventory entity step, got %+v", steps)
+		}
+
+		inventoryTransport := &countingTransport{}
+		resp, err := federation.NewExecutor(superGraph, federation.ExecutorOptions{
+			SubgraphTransports: map[string]federation.Transport{"inventory": inventoryTransport},
+		}).Execute(ctx, plan, nil)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		got, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatalf("failed to encode response: %v", err)
+		}
+		if want := `{"data":{"topProducts":[{"name":"Table","inStock":true}]}}`; string(got) != want {
+			t.Errorf("expected response %s, got %s", want, got)
+		}
+		if errs := resp.Errors(); len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+		if _, ok := resp.Data()["topProducts"]; !ok {
+			t.Errorf("expected data with topProducts, got %v", resp.Data())
+		}
+		if n := inventoryTransport.requests.Load(); n != 1 {
+			t.Errorf("expected the inventory transport to send 1 request, got %d", n)
+		}
+	})
+
+	t.Run("reuses a composition of the same subgraphs", func(t *testing.T) {
+		composition, err := superGraph.MarshalComposition()
+		if err != nil {
+			t.Fatalf("MarshalComposition failed: %v", err)
+		}
+		reused, err := federation.NewSchemaComposer(federation.ComposerOptions{Composition: composition}).Compose(ctx, subgraphs)
+		if err != nil {
+			t.Fatalf("Compose failed: %v", err)
+		}
+		if owners := reused.FieldOwners("Product", "inStock"); len(owners) != 1 || owners[0] != "inventory" {
+			t.Errorf("expected Product.inStock to be owned by inventory, got %v", owners)
+		}
+		if reused.SDL() != superGraph.SDL() {
+			t.Errorf("expected the reused composition to have the same SDL, got %s", reused.SDL())
+		}
+
+		// A composition for other SDLs is ignored.
+		changed := []federation.Subgraph{subgraphs[0], {Name: "inventory", URL: inventory.URL, SDL: inventorySDL + "\nextend type Query { stock: Int }"}}
+		recomposed, err := federation.NewSchemaComposer(federation.ComposerOptions{Composition: composition}).Compose(ctx, changed)
+		if err != nil {
+			t.Fatalf("Compose failed: %v", err)
+		}
+		if owners := recomposed.FieldOwners("Query", "stock"); len(owners) != 1 || owners[0] != "inventory" {
+			t.Errorf("expected Query.stock to be owned by inventory, got %v", owners)
+		}
+	})
+
+	t.Run("respects the context", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := federation.NewSchemaComposer(federation.ComposerOptions{}).Compose(cancelled, subgraphs); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected Compose to be cancelled, got %v", err)
+		}
+		_, err := federation.NewPlanner(superGraph, federation.PlannerOptions{}).Plan(cancelled, federation.Request{Query: "{ topProducts { name } }"})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected Plan to be cancelled, got %v", err)
+		}
+	})
+
+	t.Run("rejects an unknown owner strategy", func(t *testing.T) {
+		p := federation.NewPlanner(superGraph, federation.PlannerOptions{OwnerStrategy: "fastest"})
+		if _, err := p.Plan(ctx, federation.Request{Query: "{ topProducts { name } }"}); err == nil {
+			t.Error("expected the owner strategy to be rejected")
+		}
+		p = federation.NewPlanner(superGraph, federation.PlannerOptions{
+			CostModel:     &federation.CostModel{SubgraphLatency: map[string]float64{"inventory": 2}},
+			OwnerStrategy: federation.OwnerStrategyCheapest,
+		})
+		if _, err := p.Plan(ctx, federation.Request{Query: "{ topProducts { name } }"}); err != nil {
+			t.Errorf("expected the cheapest strategy to plan, got %v", err)
+		}
+	})
+
+	t.Run("reports invalid requests", func(t *testing.T) {
+		p := federation.NewPlanner(superGraph, federation.PlannerOptions{})
+		for _, query := range []string{"{ topProducts { ", "query A { topProducts { name } } query B { topProducts { id } }"} {
+			if _, err := p.Plan(ctx, federation.Request{Query: query}); err == nil || strings.Contains(err.Error(), "panic") {
+				t.Errorf("expected %q to be rejected, got %v", query, err)
+			}
+		}
+	})
+}
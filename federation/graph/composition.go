@@ -0,0 +1,121 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goccy/go-json"
+)
+
+// CompositionFormatVersion is the version of the serialized composition format written
+// by MarshalComposition. It is bumped whenever the format changes incompatibly.
+const CompositionFormatVersion = 1
+
+var (
+	// ErrCompositionVersion is returned by NewSuperGraphV2FromComposition for compositions
+	// written in another format version.
+	ErrCompositionVersion = errors.New("unsupported composition format version")
+	// ErrCompositionMismatch is returned by NewSuperGraphV2FromComposition for compositions
+	// built from other subgraph SDLs.
+	ErrCompositionMismatch = errors.New("composition was built from different subgraphs")
+)
+
+// serializedComposition is the JSON form of the field ownership of a SuperGraphV2, the
+// most expensive part of composition. Subgraphs are stored by name.
+type serializedComposition struct {
+	Version              int                                      `json:"version"`
+	SchemaHash           string                                   `json:"schemaHash"`
+	Ownership            map[string][]string                      `json:"ownership"`
+	ProgressiveOverrides map[string]serializedProgressiveOverride `json:"progressiveOverrides,omitempty"`
+}
+
+type serializedProgressiveOverride struct {
+	Label  string   `json:"label"`
+	Owners []string `json:"owners"`
+}
+
+// MarshalComposition serializes the field ownership of sg so that a restarted gateway
+// can skip computing it. schemaHash identifies the subgraph SDLs sg was composed from
+// (for example registry.SchemaHash); NewSuperGraphV2FromComposition rejects the data
+// once they change.
+func (sg *SuperGraphV2) MarshalComposition(schemaHash string) ([]byte, error) {
+	sc := serializedComposition{
+		Version:              CompositionFormatVersion,
+		SchemaHash:           schemaHash,
+		Ownership:            make(map[string][]string, len(sg.Ownership)),
+		ProgressiveOverrides: make(map[string]serializedProgressiveOverride, len(sg.progressiveOverrides)),
+	}
+	for key, owners := range sg.Ownership {
+		sc.Ownership[key] = subGraphNames(owners)
+	}
+	for key, override := range sg.progressiveOverrides {
+		sc.ProgressiveOverrides[key] = serializedProgressiveOverride{Label: override.label, Owners: subGraphNames(override.owners)}
+	}
+	return json.Marshal(sc)
+}
+
+// NewSuperGraphV2FromComposition is NewSuperGraphV2 restoring the field ownership from
+// data written by MarshalComposition instead of computing it. The schema is still
+// composed from subGraphs, which must have been parsed from the SDLs identified by
+// schemaHash.
+func NewSuperGraphV2FromComposition(subGraphs []*SubGraphV2, data []byte, schemaHash string) (*SuperGraphV2, error) {
+	var sc serializedComposition
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("failed to decode composition: %w", err)
+	}
+	if sc.Version != CompositionFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrCompositionVersion, sc.Version)
+	}
+	if sc.SchemaHash != schemaHash {
+		return nil, ErrCompositionMismatch
+	}
+
+	sg := &SuperGraphV2{
+		SubGraphs:            subGraphs,
+		Ownership:            make(map[string][]*SubGraphV2, len(sc.Ownership)),
+		progressiveOverrides: make(map[string]progressiveOverride, len(sc.ProgressiveOverrides)),
+	}
+	if err := sg.composeSchema(); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*SubGraphV2, len(subGraphs))
+	for _, subGraph := range subGraphs {
+		byName[subGraph.Name] = subGraph
+	}
+	lookup := func(names []string) ([]*SubGraphV2, error) {
+		owners := make([]*SubGraphV2, 0, len(names))
+		for _, name := range names {
+			subGraph, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown subgraph %q", ErrCompositionMismatch, name)
+			}
+			owners = append(owners, subGraph)
+		}
+		return owners, nil
+	}
+	for key, names := range sc.Ownership {
+		owners, err := lookup(names)
+		if err != nil {
+			return nil, err
+		}
+		sg.Ownership[key] = owners
+	}
+	for key, override := range sc.ProgressiveOverrides {
+		owners, err := lookup(override.Owners)
+		if err != nil {
+			return nil, err
+		}
+		sg.progressiveOverrides[key] = progressiveOverride{label: override.Label, owners: owners}
+	}
+	sg.buildIndex()
+	return sg, nil
+}
+
+func subGraphNames(subGraphs []*SubGraphV2) []string {
+	names := make([]string, 0, len(subGraphs))
+	for _, subGraph := range subGraphs {
+		names = append(names, subGraph.Name)
+	}
+	return names
+}
@@ -0,0 +1,95 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+)
+
+func TestSuperGraphV2_MarshalComposition(t *testing.T) {
+	productsSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			price: Float!
+		}
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+	productsV2Schema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			name: String! @override(from: "products", label: "percent(25)")
+		}
+	`
+	newSubGraphs := func(t *testing.T) []*graph.SubGraphV2 {
+		t.Helper()
+		products, err := graph.NewSubGraphV2("products", []byte(productsSchema), "http://products.example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 failed for products: %v", err)
+		}
+		productsV2, err := graph.NewSubGraphV2("products-v2", []byte(productsV2Schema), "http://products-v2.example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 failed for products-v2: %v", err)
+		}
+		return []*graph.SubGraphV2{products, productsV2}
+	}
+
+	superGraph, err := graph.NewSuperGraphV2(newSubGraphs(t))
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	data, err := superGraph.MarshalComposition("hash-1")
+	if err != nil {
+		t.Fatalf("MarshalComposition failed: %v", err)
+	}
+
+	t.Run("restores the ownership against new subgraphs", func(t *testing.T) {
+		subGraphs := newSubGraphs(t)
+		restored, err := graph.NewSuperGraphV2FromComposition(subGraphs, data, "hash-1")
+		if err != nil {
+			t.Fatalf("NewSuperGraphV2FromComposition failed: %v", err)
+		}
+
+		if len(restored.Ownership) != len(superGraph.Ownership) {
+			t.Errorf("expected %d owned fields, got %d", len(superGraph.Ownership), len(restored.Ownership))
+		}
+		owner := restored.GetFieldOwnerSubGraph("Product", "price")
+		if owner != subGraphs[0] {
+			t.Errorf("expected Product.price to be owned by the given products subgraph, got %v", owner)
+		}
+		if got := restored.RootTypeName("query"); got != "Query" {
+			t.Errorf("expected the query root type to be composed, got %q", got)
+		}
+		if restored.SDL() != superGraph.SDL() {
+			t.Errorf("expected the composed schema to match\n%s\ngot\n%s", superGraph.SDL(), restored.SDL())
+		}
+
+		active := restored.WithOverrideLabels(map[string]bool{"percent(25)": true})
+		if owner := active.GetFieldOwnerSubGraph("Product", "name"); owner == nil || owner.Name != "products-v2" {
+			t.Errorf("expected the progressive override to be restored, got %v", owner)
+		}
+	})
+
+	t.Run("rejects other schemas", func(t *testing.T) {
+		_, err := graph.NewSuperGraphV2FromComposition(newSubGraphs(t), data, "hash-2")
+		if !errors.Is(err, graph.ErrCompositionMismatch) {
+			t.Errorf("expected ErrCompositionMismatch, got %v", err)
+		}
+
+		_, err = graph.NewSuperGraphV2FromComposition(newSubGraphs(t)[:1], data, "hash-1")
+		if !errors.Is(err, graph.ErrCompositionMismatch) {
+			t.Errorf("expected ErrCompositionMismatch for a missing subgraph, got %v", err)
+		}
+	})
+
+	t.Run("rejects other format versions", func(t *testing.T) {
+		_, err := graph.NewSuperGraphV2FromComposition(newSubGraphs(t), []byte(`{"version":999,"schemaHash":"hash-1"}`), "hash-1")
+		if !errors.Is(err, graph.ErrCompositionVersion) {
+			t.Errorf("expected ErrCompositionVersion, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// ContextArgument is a field argument marked with @fromContext: instead of being set by
+// the client, it receives the value selected from the nearest ancestor object setting
+// the context with @context.
+type ContextArgument struct {
+	Name      string   // Argument name
+	Context   string   // Context name, as set with @context(name:)
+	Selection FieldSet // Fields selected from the object setting the context
+}
+
+// parseContextArguments returns the @fromContext arguments of field.
+func parseContextArguments(field *ast.FieldDefinition) []ContextArgument {
+	var args []ContextArgument
+	for _, arg := range field.Arguments {
+		for _, d := range arg.Directives {
+			if d.Name != "fromContext" {
+				continue
+			}
+			for _, darg := range d.Arguments {
+				if darg.Name.String() != "field" {
+					continue
+				}
+				if context, selection, ok := parseFromContext(strings.Trim(darg.Value.String(), "\"")); ok {
+					args = append(args, ContextArgument{Name: arg.Name.String(), Context: context, Selection: selection})
+				}
+			}
+		}
+	}
+	return args
+}
+
+// parseFromContext parses the field argument of @fromContext, such as
+// "$userContext { currency }", into the context name and the selection.
+func parseFromContext(value string) (string, FieldSet, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "$") {
+		return "", nil, false
+	}
+	name, selection, _ := strings.Cut(value[1:], "{")
+	name = strings.TrimSpace(name)
+	selection = strings.TrimSpace(selection)
+	selection = strings.TrimSpace(strings.TrimSuffix(selection, "}"))
+	if name == "" || selection == "" {
+		return "", nil, false
+	}
+	return name, ParseFieldSet(selection), true
+}
+
+// isContextArgument reports whether arg is set through @fromContext.
+func isContextArgument(arg *ast.InputValueDefinition) bool {
+	return hasDirective(arg.Directives, "fromContext")
+}
+
+// withoutContextArguments returns args without the @fromContext arguments, which are not
+// part of the composed schema: clients cannot set them.
+func withoutContextArguments(args []*ast.InputValueDefinition) []*ast.InputValueDefinition {
+	for i, arg := range args {
+		if !isContextArgument(arg) {
+			continue
+		}
+		kept := append([]*ast.InputValueDefinition{}, args[:i]...)
+		for _, arg := range args[i+1:] {
+			if !isContextArgument(arg) {
+				kept = append(kept, arg)
+			}
+		}
+		return kept
+	}
+	return args
+}
+
+// collectContexts records the contexts def sets with @context.
+func (sg *SubGraphV2) collectContexts(def ast.Definition) {
+	var directives []*ast.Directive
+	switch d := def.(type) {
+	case *ast.ObjectTypeDefinition:
+		directives = d.Directives
+	case *ast.ObjectTypeExtension:
+		directives = d.Directives
+	case *ast.InterfaceTypeDefinition:
+		directives = d.Directives
+	case *ast.UnionTypeDefinition:
+		directives = d.Directives
+	default:
+		return
+	}
+	for _, d := range directives {
+		if d.Name != "context" {
+			continue
+		}
+		for _, arg := range d.Arguments {
+			if arg.Name.String() == "name" {
+				typeName := definitionName(def)
+				sg.contexts[typeName] = append(sg.contexts[typeName], strings.Trim(arg.Value.String(), "\""))
+			}
+		}
+	}
+}
+
+// SetsContext reports whether typeName sets the context named name in any subgraph.
+func (sg *SuperGraphV2) SetsContext(typeName, name string) bool {
+	for _, subGraph := range sg.SubGraphs {
+		for _, context := range subGraph.contexts[typeName] {
+			if context == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package graph
+
+import "strings"
+
+// FieldSet is a parsed federation field set such as "id author { id name }", as used by
+// @provides.
+type FieldSet []*FieldSetField
+
+// FieldSetField is a field of a FieldSet with its nested selections.
+type FieldSetField struct {
+	Name       string
+	Selections FieldSet
+}
+
+// ParseFieldSet parses a federation field set. Nested selections in braces are attached
+// to the field they follow.
+func ParseFieldSet(fieldSet string) FieldSet {
+	fieldSet = strings.NewReplacer("{", " { ", "}", " } ").Replace(fieldSet)
+	fields, _ := parseFieldSetTokens(strings.Fields(fieldSet))
+	return fields
+}
+
+// parseFieldSetTokens parses tokens up to a closing brace and returns the fields and the
+// tokens after it.
+func parseFieldSetTokens(tokens []string) (FieldSet, []string) {
+	var fields FieldSet
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		tokens = tokens[1:]
+		switch tok {
+		case "{":
+			var selections FieldSet
+			selections, tokens = parseFieldSetTokens(tokens)
+			if len(fields) > 0 {
+				last := fields[len(fields)-1]
+				last.Selections = append(last.Selections, selections...)
+			}
+		case "}":
+			return fields, tokens
+		default:
+			fields = append(fields, &FieldSetField{Name: tok})
+		}
+	}
+	return fields, tokens
+}
+
+// Field returns the field of fs named name.
+func (fs FieldSet) Field(name string) (*FieldSetField, bool) {
+	for _, f := range fs {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the names of the top-level fields of fs.
+func (fs FieldSet) Names() []string {
+	names := make([]string, 0, len(fs))
+	for _, f := range fs {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// String returns fs in field set syntax.
+func (fs FieldSet) String() string {
+	parts := make([]string, 0, len(fs))
+	for _, f := range fs {
+		if len(f.Selections) > 0 {
+			parts = append(parts, f.Name+" { "+f.Selections.String()+" }")
+		} else {
+			parts = append(parts, f.Name)
+		}
+	}
+	return strings.Join(parts, " ")
+}
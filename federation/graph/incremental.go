@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// WithSubGraph returns the super graph of the subgraphs of sg with subGraph replacing
+// the subgraph of the same name, or added when there is none. Only the types subGraph
+// defines or extends, before or after the update, are recomposed, and only the
+// ownership of their fields is recomputed: the composition of a type depends on the
+// subgraphs declaring it alone. The other composed definitions and ownership entries,
+// and the other subgraphs, are shared with sg, which must not be modified afterwards.
+// Types keep their position in the composed schema; new types come last.
+func (sg *SuperGraphV2) WithSubGraph(subGraph *SubGraphV2) (*SuperGraphV2, error) {
+	subGraphs := make([]*SubGraphV2, 0, len(sg.SubGraphs)+1)
+	var previous *SubGraphV2
+	for _, s := range sg.SubGraphs {
+		if s.Name == subGraph.Name {
+			previous = s
+			s = subGraph
+		}
+		subGraphs = append(subGraphs, s)
+	}
+	if previous == nil {
+		subGraphs = append(subGraphs, subGraph)
+	}
+
+	affected := definitionNames(subGraph.Schema)
+	if previous != nil {
+		for name := range definitionNames(previous.Schema) {
+			affected[name] = true
+		}
+	}
+
+	// Recompose the affected types from every subgraph declaring them.
+	declarations := make([]*ast.Document, 0, len(subGraphs))
+	for _, s := range subGraphs {
+		doc := &ast.Document{}
+		for _, def := range s.Schema.Definitions {
+			if affected[definitionName(def)] {
+				doc.Definitions = append(doc.Definitions, def)
+			}
+		}
+		declarations = append(declarations, doc)
+	}
+	partial := composeDefinitions(declarations)
+	recomposed := make(map[string]ast.Definition, len(partial))
+	for _, def := range partial {
+		recomposed[definitionName(def)] = def
+	}
+
+	next := &SuperGraphV2{
+		SubGraphs:            subGraphs,
+		Schema:               &ast.Document{Definitions: make([]ast.Definition, 0, len(sg.Schema.Definitions)+len(recomposed))},
+		Ownership:            make(map[string][]*SubGraphV2, len(sg.Ownership)),
+		progressiveOverrides: make(map[string]progressiveOverride, len(sg.progressiveOverrides)),
+	}
+	placed := make(map[string]bool, len(recomposed))
+	for _, def := range sg.Schema.Definitions {
+		// The schema definition is rebuilt with the root types below.
+		if _, ok := def.(*ast.SchemaDefinition); ok {
+			continue
+		}
+		name := definitionName(def)
+		if !affected[name] {
+			next.Schema.Definitions = append(next.Schema.Definitions, def)
+			continue
+		}
+		if def, ok := recomposed[name]; ok {
+			next.Schema.Definitions = append(next.Schema.Definitions, def)
+			placed[name] = true
+		}
+	}
+	for _, def := range partial {
+		if !placed[definitionName(def)] {
+			next.Schema.Definitions = append(next.Schema.Definitions, def)
+		}
+	}
+	if err := next.composeRootTypes(); err != nil {
+		return nil, err
+	}
+
+	for key, owners := range sg.Ownership {
+		if typeName, _, _ := strings.Cut(key, "."); !affected[typeName] {
+			next.Ownership[key] = owners
+		}
+	}
+	for key, override := range sg.progressiveOverrides {
+		if typeName, _, _ := strings.Cut(key, "."); !affected[typeName] {
+			next.progressiveOverrides[key] = override
+		}
+	}
+	next.buildOwnership(partial)
+	next.buildIndex()
+
+	return next, nil
+}
+
+// definitionNames returns the names of the types and directives doc declares.
+func definitionNames(doc *ast.Document) map[string]bool {
+	names := make(map[string]bool)
+	if doc == nil {
+		return names
+	}
+	for _, def := range doc.Definitions {
+		if name := definitionName(def); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// definitionName returns the name of the type def defines or extends, or @ followed by
+// the name of the directive it defines. Schema definitions have no name.
+func definitionName(def ast.Definition) string {
+	switch d := def.(type) {
+	case *ast.ObjectTypeDefinition:
+		return d.Name.String()
+	case *ast.ObjectTypeExtension:
+		return d.Name.String()
+	case *ast.InterfaceTypeDefinition:
+		return d.Name.String()
+	case *ast.InterfaceTypeExtension:
+		return d.Name.String()
+	case *ast.UnionTypeDefinition:
+		return d.Name.String()
+	case *ast.UnionTypeExtension:
+		return d.Name.String()
+	case *ast.EnumTypeDefinition:
+		return d.Name.String()
+	case *ast.EnumTypeExtension:
+		return d.Name.String()
+	case *ast.InputObjectTypeDefinition:
+		return d.Name.String()
+	case *ast.InputObjectTypeExtension:
+		return d.Name.String()
+	case *ast.ScalarTypeDefinition:
+		return d.Name.String()
+	case *ast.ScalarTypeExtension:
+		return d.Name.String()
+	case *ast.DirectiveDefinition:
+		return "@" + d.Name.String()
+	}
+	return ""
+}
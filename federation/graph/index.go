@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// schemaIndex indexes the composed schema for the lookups planning and execution make
+// for every field of a request, which would otherwise scan Schema.Definitions.
+type schemaIndex struct {
+	types        map[string]ast.Definition                  // Composed type definitions by name
+	fields       map[string]map[string]*ast.FieldDefinition // Fields of object and interface types
+	entityOwners map[string]*SubGraphV2                     // GetEntityOwnerSubGraph by entity name
+}
+
+// buildIndex indexes the composed schema and the entities of the subgraphs. It must run
+// once composition is complete.
+func (sg *SuperGraphV2) buildIndex() {
+	sg.index = newSchemaIndex(sg)
+	sg.internOwnership()
+}
+
+func newSchemaIndex(sg *SuperGraphV2) *schemaIndex {
+	idx := &schemaIndex{
+		types:        make(map[string]ast.Definition),
+		fields:       indexFields(sg.Schema),
+		entityOwners: make(map[string]*SubGraphV2),
+	}
+	if sg.Schema != nil {
+		for _, def := range sg.Schema.Definitions {
+			if _, ok := def.(*ast.DirectiveDefinition); ok {
+				continue
+			}
+			// A type split across definitions is found by its first one, as by a scan.
+			if name := definitionName(def); name != "" {
+				if _, ok := idx.types[name]; !ok {
+					idx.types[name] = def
+				}
+			}
+		}
+	}
+	for _, subGraph := range sg.SubGraphs {
+		for name := range subGraph.entities {
+			if _, ok := idx.entityOwners[name]; !ok {
+				idx.entityOwners[name] = sg.findEntityOwnerSubGraph(name)
+			}
+		}
+	}
+	return idx
+}
+
+// lookup returns the index of sg, built on the fly for super graphs assembled without
+// a constructor.
+func (sg *SuperGraphV2) lookup() *schemaIndex {
+	if sg.index != nil {
+		return sg.index
+	}
+	return newSchemaIndex(sg)
+}
+
+// indexFields returns the fields of the object and interface types of doc, and of the
+// object types it extends, by type and field name. The first declaration of a field wins.
+func indexFields(doc *ast.Document) map[string]map[string]*ast.FieldDefinition {
+	index := make(map[string]map[string]*ast.FieldDefinition)
+	if doc == nil {
+		return index
+	}
+	for _, def := range doc.Definitions {
+		var typeName string
+		var fields []*ast.FieldDefinition
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			typeName, fields = d.Name.String(), d.Fields
+		case *ast.ObjectTypeExtension:
+			typeName, fields = d.Name.String(), d.Fields
+		case *ast.InterfaceTypeDefinition:
+			typeName, fields = d.Name.String(), d.Fields
+		default:
+			continue
+		}
+		byName, ok := index[typeName]
+		if !ok {
+			byName = make(map[string]*ast.FieldDefinition, len(fields))
+			index[typeName] = byName
+		}
+		for _, field := range fields {
+			if _, ok := byName[field.Name.String()]; !ok {
+				byName[field.Name.String()] = field
+			}
+		}
+	}
+	return index
+}
+
+// internOwnership makes the fields owned by the same subgraphs share one owner list.
+// Most fields of a type have the same owners, so this keeps the ownership map of a large
+// graph to a few distinct lists. The shared lists have no spare capacity, so appending
+// to one copies it.
+func (sg *SuperGraphV2) internOwnership() {
+	shared := make(map[string][]*SubGraphV2)
+	var sb strings.Builder
+	for key, owners := range sg.Ownership {
+		sb.Reset()
+		for _, owner := range owners {
+			sb.WriteString(owner.Name)
+			sb.WriteByte(0)
+		}
+		if list, ok := shared[sb.String()]; ok {
+			sg.Ownership[key] = list
+			continue
+		}
+		owners = owners[:len(owners):len(owners)]
+		shared[sb.String()] = owners
+		sg.Ownership[key] = owners
+	}
+}
+
+// TypeDefinition returns the definition of the type named typeName in the composed
+// schema, or nil when there is none.
+func (sg *SuperGraphV2) TypeDefinition(typeName string) ast.Definition {
+	return sg.lookup().types[typeName]
+}
+
+// FieldDefinition returns the definition of typeName.fieldName in the composed schema,
+// where typeName is an object or interface type, or nil when there is none.
+func (sg *SuperGraphV2) FieldDefinition(typeName, fieldName string) *ast.FieldDefinition {
+	return sg.lookup().fields[typeName][fieldName]
+}
+
+// FieldDefinition returns the definition of typeName.fieldName in the subgraph schema,
+// declared on an object or interface type or an object type extension, or nil when the
+// subgraph does not declare it.
+func (sg *SubGraphV2) FieldDefinition(typeName, fieldName string) *ast.FieldDefinition {
+	fields := sg.fields
+	if fields == nil {
+		// Subgraphs assembled without NewSubGraphV2 are not indexed.
+		fields = indexFields(sg.Schema)
+	}
+	return fields[typeName][fieldName]
+}
+
+// declaresFields reports whether the subgraph schema declares fields of typeName, on
+// an object or interface type or an object type extension.
+func (sg *SubGraphV2) declaresFields(typeName string) bool {
+	fields := sg.fields
+	if fields == nil {
+		fields = indexFields(sg.Schema)
+	}
+	return len(fields[typeName]) > 0
+}
@@ -0,0 +1,55 @@
+package graph
+
+import "github.com/n9te9/graphql-parser/ast"
+
+// PossibleTypes returns the object types of the composed schema implementing the
+// interface or belonging to the union typeName, in schema order.
+func (sg *SuperGraphV2) PossibleTypes(typeName string) []string {
+	return possibleTypes(sg.Schema, typeName)
+}
+
+// PossibleTypes returns the object types the subgraph schema declares as implementing
+// the interface or belonging to the union typeName, in schema order.
+func (sg *SubGraphV2) PossibleTypes(typeName string) []string {
+	return possibleTypes(sg.Schema, typeName)
+}
+
+// possibleTypes returns the object types of doc implementing the interface or belonging
+// to the union typeName. An object type declared more than once is listed once.
+func possibleTypes(doc *ast.Document, typeName string) []string {
+	if doc == nil {
+		return nil
+	}
+	var types []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			types = append(types, name)
+		}
+	}
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			for _, iface := range d.Interfaces {
+				if iface.Name.String() == typeName {
+					add(d.Name.String())
+				}
+			}
+		case *ast.ObjectTypeExtension:
+			for _, iface := range d.Interfaces {
+				if iface.Name.String() == typeName {
+					add(d.Name.String())
+				}
+			}
+		case *ast.UnionTypeDefinition:
+			if d.Name.String() != typeName {
+				continue
+			}
+			for _, member := range d.Types {
+				add(member.Name.String())
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,443 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// federationLink is prepended to the printed SDL so that consumers treat the
+// composed schema as a Federation v2 subgraph.
+const federationLink = `extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key", "@shareable", "@tag"])`
+
+// federationLinkInaccessible is federationLink for SDL printing @inaccessible elements.
+const federationLinkInaccessible = `extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key", "@shareable", "@tag", "@inaccessible"])`
+
+// PrintOptions selects what PrintSDL prints besides the public schema.
+type PrintOptions struct {
+	// Federation prints the federation machinery: the @link and @key directives, the
+	// _Any, _Entity and _Service types and the _entities and _service query fields.
+	Federation bool
+	// Inaccessible prints the @inaccessible types and fields, marked @inaccessible.
+	Inaccessible bool
+}
+
+// sdlPrinter prints a composed schema.
+type sdlPrinter struct {
+	keys         bool // Print @link and @key directives
+	machinery    bool // Print the _entities and _service fields and their types
+	inaccessible bool // Print @inaccessible types and fields
+
+	hiddenTypes map[string]bool // @inaccessible types, hiding the fields returning them
+}
+
+// SDL prints the composed schema as a Federation v2 subgraph SDL.
+// Entity @key directives are kept (deduplicated, all resolvable) so the gateway
+// can itself be composed into a parent gateway, while subgraph-local directives
+// such as @external, @requires, @provides and @override are dropped because the
+// gateway resolves every field itself. @inaccessible types and fields are omitted.
+func (sg *SuperGraphV2) SDL() string {
+	return sdlPrinter{keys: true}.print(sg)
+}
+
+// PrintSDL prints the composed schema for codegen and documentation tools. Without
+// options it prints the public schema only; subgraph-local directives are dropped as
+// in SDL.
+func (sg *SuperGraphV2) PrintSDL(opts PrintOptions) string {
+	return sdlPrinter{
+		keys:         opts.Federation,
+		machinery:    opts.Federation,
+		inaccessible: opts.Inaccessible,
+	}.print(sg)
+}
+
+func (p sdlPrinter) print(sg *SuperGraphV2) string {
+	if !p.inaccessible {
+		p.hiddenTypes = inaccessibleTypes(sg.Schema)
+	}
+
+	var sb strings.Builder
+	if p.keys {
+		if p.inaccessible {
+			sb.WriteString(federationLinkInaccessible)
+		} else {
+			sb.WriteString(federationLink)
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, def := range sg.Schema.Definitions {
+		var printed string
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			printed = p.printObjectType(d)
+		case *ast.InterfaceTypeDefinition:
+			if p.included(d.Directives) {
+				printed = "interface " + d.Name.String() + p.printTypeDirectives(d.Directives) + p.printFieldDefinitions(d.Fields)
+			}
+		case *ast.UnionTypeDefinition:
+			if p.included(d.Directives) {
+				printed = p.printUnionType(d)
+			}
+		case *ast.EnumTypeDefinition:
+			if p.included(d.Directives) {
+				printed = printEnumType(d)
+			}
+		case *ast.InputObjectTypeDefinition:
+			if p.included(d.Directives) {
+				printed = printInputObjectType(d)
+			}
+		case *ast.ScalarTypeDefinition:
+			if p.included(d.Directives) {
+				printed = "scalar " + d.Name.String() + printSpecifiedBy(d.Directives)
+			}
+		case *ast.SchemaDefinition:
+			printed = printSchemaDefinition(d)
+		}
+		if printed == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(printed)
+		sb.WriteString("\n")
+	}
+
+	if p.machinery {
+		sb.WriteString("\n")
+		sb.WriteString(p.printMachinery(sg))
+	}
+
+	return sb.String()
+}
+
+// printSpecifiedBy prints the @specifiedBy directive of a custom scalar, which links
+// to the specification of its values.
+func printSpecifiedBy(directives []*ast.Directive) string {
+	for _, d := range directives {
+		if d.Name == "specifiedBy" {
+			return " " + printDirective(d)
+		}
+	}
+	return ""
+}
+
+// included reports whether a type or field with directives is printed.
+func (p sdlPrinter) included(directives []*ast.Directive) bool {
+	return p.inaccessible || !hasDirective(directives, "inaccessible")
+}
+
+// inaccessibleTypes returns the names of the types marked @inaccessible in schema.
+func inaccessibleTypes(schema *ast.Document) map[string]bool {
+	hidden := make(map[string]bool)
+	for _, def := range schema.Definitions {
+		var name string
+		var directives []*ast.Directive
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			name, directives = d.Name.String(), d.Directives
+		case *ast.InterfaceTypeDefinition:
+			name, directives = d.Name.String(), d.Directives
+		case *ast.UnionTypeDefinition:
+			name, directives = d.Name.String(), d.Directives
+		case *ast.EnumTypeDefinition:
+			name, directives = d.Name.String(), d.Directives
+		case *ast.InputObjectTypeDefinition:
+			name, directives = d.Name.String(), d.Directives
+		case *ast.ScalarTypeDefinition:
+			name, directives = d.Name.String(), d.Directives
+		}
+		if name != "" && hasDirective(directives, "inaccessible") {
+			hidden[name] = true
+		}
+	}
+	return hidden
+}
+
+// printMachinery prints the types and query fields a subgraph adds for federation.
+func (p sdlPrinter) printMachinery(sg *SuperGraphV2) string {
+	var entities []string
+	for _, def := range sg.Schema.Definitions {
+		d, ok := def.(*ast.ObjectTypeDefinition)
+		if ok && hasDirective(d.Directives, "key") && p.included(d.Directives) {
+			entities = append(entities, d.Name.String())
+		}
+	}
+	sort.Strings(entities)
+	entities = uniqueStrings(entities)
+
+	var sb strings.Builder
+	sb.WriteString("scalar _Any\n\ntype _Service {\n  sdl: String\n}\n")
+	entitiesField := ""
+	if len(entities) > 0 {
+		sb.WriteString("\nunion _Entity = ")
+		sb.WriteString(strings.Join(entities, " | "))
+		sb.WriteString("\n")
+		entitiesField = "  _entities(representations: [_Any!]!): [_Entity]!\n"
+	}
+	sb.WriteString("\nextend type ")
+	sb.WriteString(sg.RootTypeName(ast.Query))
+	sb.WriteString(" {\n")
+	sb.WriteString(entitiesField)
+	sb.WriteString("  _service: _Service!\n}\n")
+	return sb.String()
+}
+
+// uniqueStrings removes adjacent duplicates from sorted values.
+func uniqueStrings(values []string) []string {
+	out := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// printSchemaDefinition prints the root operation types of a schema definition.
+func printSchemaDefinition(def *ast.SchemaDefinition) string {
+	if len(def.OperationTypes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("schema {\n")
+	for _, ot := range def.OperationTypes {
+		operation, ok := tokenOperation(ot.Operation)
+		if !ok {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", operation, ot.Type.Name.String()))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// printObjectType prints an object type with its @key and @tag directives.
+func (p sdlPrinter) printObjectType(def *ast.ObjectTypeDefinition) string {
+	name := def.Name.String()
+	if strings.HasPrefix(name, "_") || !p.included(def.Directives) {
+		// Federation internals (_Service, _Entity) are not part of the public SDL;
+		// printMachinery prints them.
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("type ")
+	sb.WriteString(name)
+	if len(def.Interfaces) > 0 {
+		names := make([]string, 0, len(def.Interfaces))
+		for _, iface := range def.Interfaces {
+			names = append(names, iface.String())
+		}
+		sb.WriteString(" implements ")
+		sb.WriteString(strings.Join(names, " & "))
+	}
+	sb.WriteString(p.printTypeDirectives(def.Directives))
+	sb.WriteString(p.printFieldDefinitions(def.Fields))
+	return sb.String()
+}
+
+// printUnionType prints a union type definition, deduplicating member types
+// that were contributed by more than one subgraph.
+func (p sdlPrinter) printUnionType(def *ast.UnionTypeDefinition) string {
+	seen := make(map[string]bool)
+	members := make([]string, 0, len(def.Types))
+	for _, t := range def.Types {
+		name := t.String()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		members = append(members, name)
+	}
+	return "union " + def.Name.String() + p.printTypeDirectives(def.Directives) + " = " + strings.Join(members, " | ")
+}
+
+// printEnumType prints an enum type definition, deduplicating values.
+func printEnumType(def *ast.EnumTypeDefinition) string {
+	var sb strings.Builder
+	sb.WriteString("enum ")
+	sb.WriteString(def.Name.String())
+	sb.WriteString(" {\n")
+	seen := make(map[string]bool)
+	for _, val := range def.Values {
+		name := val.Name.String()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		sb.WriteString("  ")
+		sb.WriteString(name)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// printInputObjectType prints an input object type definition, deduplicating fields.
+func printInputObjectType(def *ast.InputObjectTypeDefinition) string {
+	var sb strings.Builder
+	sb.WriteString("input ")
+	sb.WriteString(def.Name.String())
+	sb.WriteString(" {\n")
+	seen := make(map[string]bool)
+	for _, field := range def.Fields {
+		name := field.Name.String()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		sb.WriteString("  ")
+		sb.WriteString(name)
+		sb.WriteString(": ")
+		sb.WriteString(printType(field.Type))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// printFieldDefinitions prints a field block, skipping @inaccessible fields unless
+// they are printed and keeping only directives that are meaningful to a consumer of
+// the gateway.
+func (p sdlPrinter) printFieldDefinitions(fields []*ast.FieldDefinition) string {
+	var sb strings.Builder
+	sb.WriteString(" {\n")
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		name := field.Name.String()
+		if seen[name] || !p.included(field.Directives) || p.hiddenTypes[namedType(field.Type)] {
+			continue
+		}
+		seen[name] = true
+
+		sb.WriteString("  ")
+		sb.WriteString(name)
+		if len(field.Arguments) > 0 {
+			args := make([]string, 0, len(field.Arguments))
+			for _, arg := range field.Arguments {
+				args = append(args, arg.Name.String()+": "+printType(arg.Type))
+			}
+			sb.WriteString("(")
+			sb.WriteString(strings.Join(args, ", "))
+			sb.WriteString(")")
+		}
+		sb.WriteString(": ")
+		sb.WriteString(printType(field.Type))
+		for _, d := range field.Directives {
+			if d.Name == "tag" || d.Name == "deprecated" || (d.Name == "inaccessible" && p.inaccessible) {
+				sb.WriteString(" ")
+				sb.WriteString(printDirective(d))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// printTypeDirectives prints the type-level directives kept in the gateway SDL.
+// @key directives are deduplicated by field set and printed without the
+// resolvable argument, since the gateway can resolve every key it exposes.
+func (p sdlPrinter) printTypeDirectives(directives []*ast.Directive) string {
+	var sb strings.Builder
+	seenKeys := make(map[string]bool)
+	keys := make([]string, 0)
+	inaccessible := false
+	for _, d := range directives {
+		switch d.Name {
+		case "key":
+			if !p.keys {
+				continue
+			}
+			for _, arg := range d.Arguments {
+				if arg.Name.String() != "fields" {
+					continue
+				}
+				fieldSet := strings.Trim(arg.Value.String(), "\"")
+				if !seenKeys[fieldSet] {
+					seenKeys[fieldSet] = true
+					keys = append(keys, fieldSet)
+				}
+			}
+		case "tag":
+			sb.WriteString(" ")
+			sb.WriteString(printDirective(d))
+		case "inaccessible":
+			// Types merged from several subgraphs may carry it more than once.
+			if p.inaccessible && !inaccessible {
+				inaccessible = true
+				sb.WriteString(" @inaccessible")
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	var keyDirectives strings.Builder
+	for _, fieldSet := range keys {
+		keyDirectives.WriteString(fmt.Sprintf(" @key(fields: %q)", fieldSet))
+	}
+	return keyDirectives.String() + sb.String()
+}
+
+// printDirective prints a directive usage with its arguments.
+func printDirective(d *ast.Directive) string {
+	if len(d.Arguments) == 0 {
+		return "@" + d.Name
+	}
+	args := make([]string, 0, len(d.Arguments))
+	for _, arg := range d.Arguments {
+		args = append(args, arg.Name.String()+": "+printValue(arg.Value))
+	}
+	return "@" + d.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// printType prints a type reference such as "[Product!]!".
+func printType(t ast.Type) string {
+	switch typ := t.(type) {
+	case *ast.NamedType:
+		return typ.Name.String()
+	case *ast.ListType:
+		return "[" + printType(typ.Type) + "]"
+	case *ast.NonNullType:
+		return printType(typ.Type) + "!"
+	default:
+		return ""
+	}
+}
+
+// printValue prints a literal value in GraphQL syntax.
+func printValue(val ast.Value) string {
+	switch v := val.(type) {
+	case *ast.StringValue:
+		return fmt.Sprintf("%q", v.Value)
+	case *ast.IntValue:
+		return fmt.Sprintf("%d", v.Value)
+	case *ast.FloatValue:
+		return fmt.Sprintf("%g", v.Value)
+	case *ast.BooleanValue:
+		return fmt.Sprintf("%t", v.Value)
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.Variable:
+		return "$" + v.Name
+	case *ast.ListValue:
+		items := make([]string, 0, len(v.Values))
+		for _, item := range v.Values {
+			items = append(items, printValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *ast.ObjectValue:
+		fields := make([]string, 0, len(v.Fields))
+		for _, field := range v.Fields {
+			fields = append(fields, field.Name.String()+": "+printValue(field.Value))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return "null"
+	}
+}
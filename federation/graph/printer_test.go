@@ -0,0 +1,137 @@
+package graph_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+)
+
+func TestSuperGraphV2_SDL(t *testing.T) {
+	productSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			secret: String @inaccessible
+			releasedAt: DateTime
+		}
+
+		scalar DateTime
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+
+	reviewSchema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review!]!
+		}
+
+		type Review {
+			id: ID!
+			body: String!
+			createdAt: DateTime!
+		}
+
+		scalar DateTime @specifiedBy(url: "https://scalars.graphql.org/andimarek/date-time")
+	`
+
+	productSG, err := graph.NewSubGraphV2("product", []byte(productSchema), "http://product.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for product: %v", err)
+	}
+	reviewSG, err := graph.NewSubGraphV2("review", []byte(reviewSchema), "http://review.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for review: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{productSG, reviewSG})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	sdl := superGraph.SDL()
+
+	for _, want := range []string{
+		`@link(url: "https://specs.apollo.dev/federation/v2.3"`,
+		`type Product @key(fields: "id") {`,
+		"reviews: [Review!]!",
+		"product(id: ID!): Product",
+		"type Review {",
+		`scalar DateTime @specifiedBy(url: "https://scalars.graphql.org/andimarek/date-time")`,
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("expected SDL to contain %q, got:\n%s", want, sdl)
+		}
+	}
+
+	if strings.Count(sdl, "scalar DateTime") != 1 {
+		t.Errorf("expected DateTime to be declared once, got:\n%s", sdl)
+	}
+	if strings.Count(sdl, `@key(fields: "id")`) != 1 {
+		t.Errorf("expected @key to be deduplicated, got:\n%s", sdl)
+	}
+	for _, unwanted := range []string{"@external", "secret"} {
+		if strings.Contains(sdl, unwanted) {
+			t.Errorf("expected SDL not to contain %q, got:\n%s", unwanted, sdl)
+		}
+	}
+
+	// The printed SDL must itself be a valid subgraph schema.
+	if _, err := graph.NewSubGraphV2("gateway", []byte(sdl), "http://gateway.example.com"); err != nil {
+		t.Errorf("printed SDL does not parse: %v", err)
+	}
+}
+
+func TestSuperGraphV2_PrintSDL(t *testing.T) {
+	productSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			secret: String @inaccessible
+			internal: InternalInfo
+		}
+
+		type InternalInfo @inaccessible {
+			owner: String
+		}
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+	productSG, err := graph.NewSubGraphV2("product", []byte(productSchema), "http://product.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{productSG})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	public := superGraph.PrintSDL(graph.PrintOptions{})
+	for _, unwanted := range []string{"@link", "@key", "_entities", "_Service", "secret", "InternalInfo"} {
+		if strings.Contains(public, unwanted) {
+			t.Errorf("expected the public SDL not to contain %q, got:\n%s", unwanted, public)
+		}
+	}
+	if !strings.Contains(public, "type Product {") {
+		t.Errorf("expected the public SDL to contain Product, got:\n%s", public)
+	}
+
+	full := superGraph.PrintSDL(graph.PrintOptions{Federation: true, Inaccessible: true})
+	for _, want := range []string{
+		`type Product @key(fields: "id") {`,
+		"secret: String @inaccessible",
+		"type InternalInfo @inaccessible {",
+		"scalar _Any",
+		"union _Entity = Product",
+		"_entities(representations: [_Any!]!): [_Entity]!",
+		"_service: _Service!",
+	} {
+		if !strings.Contains(full, want) {
+			t.Errorf("expected the full SDL to contain %q, got:\n%s", want, full)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/token"
+)
+
+// defaultRootTypeNames are the root type names used when no schema definition renames them.
+var defaultRootTypeNames = map[ast.OperationType]string{
+	ast.Query:        "Query",
+	ast.Mutation:     "Mutation",
+	ast.Subscription: "Subscription",
+}
+
+// rootOperations lists the operation types in schema definition order.
+var rootOperations = []ast.OperationType{ast.Query, ast.Mutation, ast.Subscription}
+
+// RootTypeName returns the name of the root type of operation in the composed schema,
+// e.g. "RootQuery" for `schema { query: RootQuery }`, or the default name.
+// A nil super graph uses the default names.
+func (sg *SuperGraphV2) RootTypeName(operation ast.OperationType) string {
+	if sg == nil {
+		return defaultRootTypeNames[operation]
+	}
+	if name, ok := sg.rootTypes[operation]; ok {
+		return name
+	}
+	return defaultRootTypeNames[operation]
+}
+
+// IsRootType reports whether typeName is a root operation type of the composed schema.
+func (sg *SuperGraphV2) IsRootType(typeName string) bool {
+	for _, operation := range rootOperations {
+		if sg.RootTypeName(operation) == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+// composeRootTypes resolves the root operation type names declared by the subgraphs.
+// A subgraph uses the name from its schema definition, or the default name when it
+// defines or extends a type with that name; subgraphs must agree on every name.
+// Non-default names are recorded in a schema definition of the composed schema.
+func (sg *SuperGraphV2) composeRootTypes() error {
+	sg.rootTypes = make(map[ast.OperationType]string)
+	declaredBy := make(map[ast.OperationType]string)
+
+	for _, subGraph := range sg.SubGraphs {
+		for operation, name := range subGraphRootTypes(subGraph.Schema) {
+			if existing, ok := sg.rootTypes[operation]; ok && existing != name {
+				return fmt.Errorf("subgraphs %s and %s use different %s root types: %s and %s",
+					declaredBy[operation], subGraph.Name, operation, existing, name)
+			}
+			sg.rootTypes[operation] = name
+			declaredBy[operation] = subGraph.Name
+		}
+	}
+
+	schemaDef := &ast.SchemaDefinition{}
+	custom := false
+	for _, operation := range rootOperations {
+		name, ok := sg.rootTypes[operation]
+		if !ok {
+			continue
+		}
+		if name != defaultRootTypeNames[operation] {
+			custom = true
+		}
+		schemaDef.OperationTypes = append(schemaDef.OperationTypes, &ast.OperationTypeDefinition{
+			Operation: operationToken(operation),
+			Type: &ast.NamedType{
+				Name: &ast.Name{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name},
+			},
+		})
+	}
+	if custom {
+		sg.Schema.Definitions = append(sg.Schema.Definitions, schemaDef)
+	}
+
+	return nil
+}
+
+// subGraphRootTypes returns the root type names used by a subgraph schema.
+func subGraphRootTypes(doc *ast.Document) map[ast.OperationType]string {
+	roots := make(map[ast.OperationType]string)
+	for _, def := range doc.Definitions {
+		if sd, ok := def.(*ast.SchemaDefinition); ok {
+			for _, ot := range sd.OperationTypes {
+				if operation, ok := tokenOperation(ot.Operation); ok {
+					roots[operation] = ot.Type.Name.String()
+				}
+			}
+		}
+	}
+
+	for _, operation := range rootOperations {
+		if _, ok := roots[operation]; ok {
+			continue
+		}
+		name := defaultRootTypeNames[operation]
+		for _, def := range doc.Definitions {
+			switch d := def.(type) {
+			case *ast.ObjectTypeDefinition:
+				if d.Name.String() == name {
+					roots[operation] = name
+				}
+			case *ast.ObjectTypeExtension:
+				if d.Name.String() == name {
+					roots[operation] = name
+				}
+			}
+		}
+	}
+
+	return roots
+}
+
+// tokenOperation maps a schema definition operation token to its operation type.
+func tokenOperation(t token.TokenType) (ast.OperationType, bool) {
+	switch t {
+	case token.QUERY:
+		return ast.Query, true
+	case token.MUTATION:
+		return ast.Mutation, true
+	case token.SUBSCRIPTION:
+		return ast.Subscription, true
+	}
+	return "", false
+}
+
+// operationToken maps an operation type to its schema definition token.
+func operationToken(operation ast.OperationType) token.TokenType {
+	switch operation {
+	case ast.Mutation:
+		return token.MUTATION
+	case ast.Subscription:
+		return token.SUBSCRIPTION
+	default:
+		return token.QUERY
+	}
+}
@@ -0,0 +1,25 @@
+package graph
+
+import "strings"
+
+// RemoteFieldName returns the name typeName.fieldName has in the schema of the service
+// behind sg. The root fields of stitched services renamed in the supergraph, e.g. with a
+// prefix, carry their remote name in @stitched(field: "users"); other fields keep their
+// name.
+func (sg *SubGraphV2) RemoteFieldName(typeName, fieldName string) string {
+	field := sg.FieldDefinition(typeName, fieldName)
+	if field == nil {
+		return fieldName
+	}
+	for _, d := range field.Directives {
+		if d.Name != "stitched" {
+			continue
+		}
+		for _, arg := range d.Arguments {
+			if arg.Name.String() == "field" {
+				return strings.Trim(arg.Value.String(), "\"")
+			}
+		}
+	}
+	return fieldName
+}
@@ -17,7 +17,8 @@ type EntityKey struct {
 
 // OverrideMetadata represents the @override directive information.
 type OverrideMetadata struct {
-	From string // The source subgraph name (e.g., "products")
+	From  string // The source subgraph name (e.g., "products")
+	Label string // Progressive override label (e.g., "percent(25)"); empty overrides unconditionally
 }
 
 // Field represents field information of an Entity.
@@ -25,13 +26,19 @@ type Field struct {
 	Name        string   // Field name
 	Type        ast.Type // Field type
 	Requires    []string // Fields specified in @requires directive
-	Provides    []string // Fields specified in @provides directive
+	Provides    []string // Top-level fields specified in @provides directive
 	isShareable bool     // Whether @shareable directive is present
 
 	// Federation v2 directives
 	Override       *OverrideMetadata // @override(from: "products")
 	isInaccessible bool              // @inaccessible
 	Tags           []string          // @tag(name: "public")
+
+	// ProvidedFields is the parsed @provides field set, including nested selections.
+	ProvidedFields FieldSet
+
+	// ContextArguments are the arguments set through @fromContext.
+	ContextArguments []ContextArgument
 }
 
 // Entity represents an ObjectType with @key directive.
@@ -46,10 +53,13 @@ type Entity struct {
 
 // SubGraphV2 represents a subgraph information.
 type SubGraphV2 struct {
-	Name     string             // Subgraph name (e.g., "product")
-	Host     string             // Host (e.g., "product.example.com")
-	Schema   *ast.Document      // Schema AST
-	entities map[string]*Entity // Entity map with entity name as key
+	Name     string                                     // Subgraph name (e.g., "product")
+	Host     string                                     // Host (e.g., "product.example.com")
+	Schema   *ast.Document                              // Schema AST
+	entities map[string]*Entity                         // Entity map with entity name as key
+	provides map[string]FieldSet                        // @provides field sets keyed by "Type.field"
+	fields   map[string]map[string]*ast.FieldDefinition // Declared fields by type and field name
+	contexts map[string][]string                        // Contexts set with @context by type name
 
 	// Federation v2 directives
 	ComposeDirectives []string // @composeDirective directives
@@ -72,11 +82,17 @@ func NewSubGraphV2(name string, src []byte, host string) (*SubGraphV2, error) {
 		Host:              host,
 		Schema:            doc,
 		entities:          make(map[string]*Entity),
+		provides:          make(map[string]FieldSet),
+		contexts:          make(map[string][]string),
 		ComposeDirectives: extractSchemaComposeDirectives(doc),
+		fields:            indexFields(doc),
 	}
 
 	// Traverse all type definitions
 	for _, def := range doc.Definitions {
+		sg.collectProvides(def)
+		sg.collectContexts(def)
+
 		// Process ObjectTypeDefinition
 		if objType, ok := def.(*ast.ObjectTypeDefinition); ok {
 			if isEntity(objType.Directives) {
@@ -119,6 +135,56 @@ func NewSubGraphV2(name string, src []byte, host string) (*SubGraphV2, error) {
 	return sg, nil
 }
 
+// SubGraphSource is the schema and host of a subgraph built by NewSubGraphsV2.
+type SubGraphSource struct {
+	Name string
+	SDL  []byte
+	Host string
+}
+
+// NewSubGraphsV2 builds the subgraphs of sources with NewSubGraphV2, concurrently, in
+// the order of sources. The error is that of the first source failing to build.
+func NewSubGraphsV2(sources []SubGraphSource) ([]*SubGraphV2, error) {
+	subGraphs := make([]*SubGraphV2, len(sources))
+	errs := make([]error, len(sources))
+	forEachConcurrently(len(sources), func(i int) {
+		subGraphs[i], errs[i] = NewSubGraphV2(sources[i].Name, sources[i].SDL, sources[i].Host)
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to build subgraph %q: %w", sources[i].Name, err)
+		}
+	}
+	return subGraphs, nil
+}
+
+// collectProvides records the @provides field sets of the fields of def.
+func (sg *SubGraphV2) collectProvides(def ast.Definition) {
+	var typeName string
+	var fields []*ast.FieldDefinition
+	switch d := def.(type) {
+	case *ast.ObjectTypeDefinition:
+		typeName, fields = d.Name.String(), d.Fields
+	case *ast.ObjectTypeExtension:
+		typeName, fields = d.Name.String(), d.Fields
+	case *ast.InterfaceTypeDefinition:
+		typeName, fields = d.Name.String(), d.Fields
+	default:
+		return
+	}
+	for _, field := range fields {
+		if provided := parseField(field).ProvidedFields; len(provided) > 0 {
+			sg.provides[typeName+"."+field.Name.String()] = provided
+		}
+	}
+}
+
+// GetProvidedFields returns the fields this subgraph provides for the object returned by
+// typeName.fieldName through @provides, or nil when the field has no @provides.
+func (sg *SubGraphV2) GetProvidedFields(typeName, fieldName string) FieldSet {
+	return sg.provides[typeName+"."+fieldName]
+}
+
 // GetEntities returns the entities map.
 func (sg *SubGraphV2) GetEntities() map[string]*Entity {
 	return sg.entities
@@ -182,6 +248,8 @@ func parseField(field *ast.FieldDefinition) *Field {
 		isShareable:    false,
 		isInaccessible: false,
 		Tags:           []string{},
+
+		ContextArguments: parseContextArguments(field),
 	}
 
 	// Parse directives
@@ -197,18 +265,25 @@ func parseField(field *ast.FieldDefinition) *Field {
 			// Parse fields argument of @provides directive
 			if len(d.Arguments) > 0 {
 				fieldsVal := strings.Trim(d.Arguments[0].Value.String(), "\"")
-				f.Provides = strings.Fields(fieldsVal)
+				f.ProvidedFields = ParseFieldSet(fieldsVal)
+				f.Provides = f.ProvidedFields.Names()
 			}
 		case "shareable":
 			f.isShareable = true
 		case "override":
-			// Parse from argument of @override directive
+			// Parse from and label arguments of @override directive
+			override := &OverrideMetadata{}
 			for _, arg := range d.Arguments {
-				if arg.Name.String() == "from" {
-					from := strings.Trim(arg.Value.String(), "\"")
-					f.Override = &OverrideMetadata{From: from}
+				switch arg.Name.String() {
+				case "from":
+					override.From = strings.Trim(arg.Value.String(), "\"")
+				case "label":
+					override.Label = strings.Trim(arg.Value.String(), "\"")
 				}
 			}
+			if override.From != "" {
+				f.Override = override
+			}
 		case "inaccessible":
 			f.isInaccessible = true
 		case "tag":
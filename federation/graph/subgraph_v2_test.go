@@ -1,6 +1,7 @@
 package graph_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
@@ -128,6 +129,46 @@ func TestNewSubGraphV2_WithDirectives(t *testing.T) {
 	}
 }
 
+func TestNewSubGraphV2_WithNestedProvides(t *testing.T) {
+	schema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			name: String! @external
+			author: User! @external
+		}
+
+		type User {
+			id: ID!
+			name: String!
+		}
+
+		type Review {
+			id: ID!
+			product: Product @provides(fields: "name author { id name }")
+		}
+	`
+
+	sg, err := graph.NewSubGraphV2("review", []byte(schema), "http://review.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+
+	provided := sg.GetProvidedFields("Review", "product")
+	if got := provided.String(); got != "name author { id name }" {
+		t.Errorf("expected provided fields 'name author { id name }', got %q", got)
+	}
+	author, ok := provided.Field("author")
+	if !ok {
+		t.Fatal("expected author to be provided")
+	}
+	if names := author.Selections.Names(); len(names) != 2 || names[0] != "id" || names[1] != "name" {
+		t.Errorf("expected author { id name } to be provided, got %v", names)
+	}
+	if sg.GetProvidedFields("Review", "id") != nil {
+		t.Error("expected no provided fields for Review.id")
+	}
+}
+
 func TestNewSubGraphV2_WithShareable(t *testing.T) {
 	schema := `
 		type Product @key(fields: "id") {
@@ -363,3 +404,27 @@ func TestNewSubGraphV2_WithComposeDirective(t *testing.T) {
 		t.Errorf("expected compose directive '@custom', got '%s'", composeDirectives[0])
 	}
 }
+
+func TestNewSubGraphsV2(t *testing.T) {
+	subGraphs, err := graph.NewSubGraphsV2([]graph.SubGraphSource{
+		{Name: "products", SDL: []byte(`type Query { product: String }`), Host: "http://products"},
+		{Name: "reviews", SDL: []byte(`type Query { review: String }`), Host: "http://reviews"},
+	})
+	if err != nil {
+		t.Fatalf("NewSubGraphsV2 failed: %v", err)
+	}
+	if len(subGraphs) != 2 || subGraphs[0].Name != "products" || subGraphs[1].Name != "reviews" {
+		t.Fatalf("expected the subgraphs in source order, got %v", subGraphs)
+	}
+	if subGraphs[1].Host != "http://reviews" {
+		t.Errorf("expected host http://reviews, got %s", subGraphs[1].Host)
+	}
+
+	_, err = graph.NewSubGraphsV2([]graph.SubGraphSource{
+		{Name: "products", SDL: []byte(`type Query { product: String }`)},
+		{Name: "broken", SDL: []byte(`type Query {`)},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Errorf("expected an error naming the broken subgraph, got %v", err)
+	}
+}
@@ -2,6 +2,10 @@ package graph
 
 import (
 	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/n9te9/graphql-parser/ast"
 )
@@ -11,13 +15,31 @@ type SuperGraphV2 struct {
 	SubGraphs []*SubGraphV2            // List of subgraphs
 	Schema    *ast.Document            // Composed schema
 	Ownership map[string][]*SubGraphV2 // Field ownership map (e.g., "Product.id" -> [SubGraph])
+
+	// progressiveOverrides holds the fields overridden with @override(label:), keyed like
+	// Ownership. Ownership lists their owners with the label inactive.
+	progressiveOverrides map[string]progressiveOverride
+	// labelVariants caches the super graphs returned by WithOverrideLabels.
+	labelVariants sync.Map
+	// rootTypes maps operation types to the root type names used by the subgraphs.
+	rootTypes map[ast.OperationType]string
+	// index serves the type, field and entity owner lookups.
+	index *schemaIndex
+}
+
+// progressiveOverride is an @override(from:, label:) whose ownership depends on whether
+// the label is active for a request.
+type progressiveOverride struct {
+	label  string
+	owners []*SubGraphV2 // Owners when the label is active
 }
 
 // NewSuperGraphV2 creates a super graph from a list of SubGraphV2 instances.
 func NewSuperGraphV2(subGraphs []*SubGraphV2) (*SuperGraphV2, error) {
 	sg := &SuperGraphV2{
-		SubGraphs: subGraphs,
-		Ownership: make(map[string][]*SubGraphV2),
+		SubGraphs:            subGraphs,
+		Ownership:            make(map[string][]*SubGraphV2),
+		progressiveOverrides: make(map[string]progressiveOverride),
 	}
 
 	// Schema Composition - compose schemas from all subgraphs
@@ -29,6 +51,7 @@ func NewSuperGraphV2(subGraphs []*SubGraphV2) (*SuperGraphV2, error) {
 	if err := sg.buildOwnershipMap(); err != nil {
 		return nil, err
 	}
+	sg.buildIndex()
 
 	return sg, nil
 }
@@ -44,20 +67,112 @@ func (sg *SuperGraphV2) composeSchema() error {
 		Definitions: make([]ast.Definition, 0),
 	}
 
-	// Two-pass merge to ensure ObjectTypeExtensions find their base types regardless
-	// of subgraph iteration order (Go maps are non-deterministic).
-	//
-	// Pass 1: merge all non-extension type definitions so every base type exists.
-	for _, subGraph := range sg.SubGraphs {
-		sg.mergeSchemaDeepPass1(subGraph.Schema)
+	docs := make([]*ast.Document, len(sg.SubGraphs))
+	for i, subGraph := range sg.SubGraphs {
+		docs[i] = subGraph.Schema
 	}
+	sg.Schema.Definitions = composeDefinitions(docs)
 
-	// Pass 2: merge all ObjectTypeExtensions now that base types are present.
-	for _, subGraph := range sg.SubGraphs {
-		sg.mergeSchemaDeepPass2(subGraph.Schema)
+	return sg.composeRootTypes()
+}
+
+// compositionKey identifies the definitions merged together: the type or directive
+// definitions of the same kind and name. Extensions are merged into their object type.
+type compositionKey struct {
+	kind string
+	name string
+}
+
+// compositionGroup holds the declarations of one composed definition, in subgraph order.
+type compositionGroup struct {
+	definitions []ast.Definition // Merged by the first pass
+	extensions  []ast.Definition // ObjectTypeExtensions, merged by the second pass
+}
+
+// composeDefinitions composes the definitions of docs. Definitions are grouped by
+// compositionKey, in the order they are first declared, and each group is merged on its
+// own, concurrently: the composition of a type only depends on its declarations.
+//
+// Each group goes through the two passes of the merge, so ObjectTypeExtensions find
+// their base type regardless of subgraph order: the definitions, then the extensions.
+// Extensions of types no subgraph defines are dropped.
+func composeDefinitions(docs []*ast.Document) []ast.Definition {
+	var groups []*compositionGroup
+	byKey := make(map[compositionKey]*compositionGroup)
+	for _, doc := range docs {
+		for _, def := range doc.Definitions {
+			var kind string
+			switch def.(type) {
+			case *ast.ObjectTypeDefinition:
+				kind = "type"
+			case *ast.InterfaceTypeDefinition:
+				kind = "interface"
+			case *ast.InputObjectTypeDefinition:
+				kind = "input"
+			case *ast.EnumTypeDefinition:
+				kind = "enum"
+			case *ast.ScalarTypeDefinition:
+				kind = "scalar"
+			case *ast.UnionTypeDefinition:
+				kind = "union"
+			case *ast.DirectiveDefinition:
+				kind = "directive"
+			default:
+				continue
+			}
+			key := compositionKey{kind: kind, name: definitionName(def)}
+			group, ok := byKey[key]
+			if !ok {
+				group = &compositionGroup{}
+				byKey[key] = group
+				groups = append(groups, group)
+			}
+			group.definitions = append(group.definitions, def)
+		}
+	}
+	for _, doc := range docs {
+		for _, def := range doc.Definitions {
+			if ext, ok := def.(*ast.ObjectTypeExtension); ok {
+				if group, ok := byKey[compositionKey{kind: "type", name: ext.Name.String()}]; ok {
+					group.extensions = append(group.extensions, def)
+				}
+			}
+		}
 	}
 
-	return nil
+	composed := make([]ast.Definition, len(groups))
+	forEachConcurrently(len(groups), func(i int) {
+		part := &SuperGraphV2{Schema: &ast.Document{}}
+		part.mergeSchemaDeepPass1(&ast.Document{Definitions: groups[i].definitions})
+		part.mergeSchemaDeepPass2(&ast.Document{Definitions: groups[i].extensions})
+		composed[i] = part.Schema.Definitions[0]
+	})
+	return composed
+}
+
+// forEachConcurrently calls fn with every index below n, spread over at most GOMAXPROCS
+// goroutines, and returns once all calls have returned. fn must only modify the state of
+// its index.
+func forEachConcurrently(n int, fn func(i int)) {
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < n; i += workers {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
 }
 
 // mergeSchemaDeep merges a new schema into the existing schema using deep copy.
@@ -135,6 +250,7 @@ func (sg *SuperGraphV2) mergeObjectTypeDefinitionDeep(newDef *ast.ObjectTypeDefi
 		// Copy and merge fields (avoid duplicates)
 		newFields := copyFields(newDef.Fields)
 		existingDef.Fields = mergeFields(existingDef.Fields, newFields)
+		existingDef.Interfaces = mergeInterfaces(existingDef.Interfaces, newDef.Interfaces)
 		// Also copy directives
 		existingDef.Directives = append(existingDef.Directives, copyDirectives(newDef.Directives)...)
 	} else {
@@ -166,6 +282,7 @@ func (sg *SuperGraphV2) mergeObjectTypeExtensionDeep(newExt *ast.ObjectTypeExten
 		// Copy and merge fields (avoid duplicates)
 		newFields := copyFields(newExt.Fields)
 		existingDef.Fields = mergeFields(existingDef.Fields, newFields)
+		existingDef.Interfaces = mergeInterfaces(existingDef.Interfaces, newExt.Interfaces)
 		// Also copy directives
 		existingDef.Directives = append(existingDef.Directives, copyDirectives(newExt.Directives)...)
 	}
@@ -180,7 +297,7 @@ func copyFields(fields []*ast.FieldDefinition) []*ast.FieldDefinition {
 	for i, field := range fields {
 		copied[i] = &ast.FieldDefinition{
 			Name:       field.Name,
-			Arguments:  field.Arguments, // TODO: Implement deep copy if needed
+			Arguments:  withoutContextArguments(field.Arguments),
 			Type:       field.Type,
 			Directives: copyDirectives(field.Directives),
 		}
@@ -203,31 +320,43 @@ func copyDirectives(directives []*ast.Directive) []*ast.Directive {
 	return copied
 }
 
-// mergeFields merges field lists and removes duplicates.
+// mergeFields merges field lists and removes duplicates. Fields keep the order they
+// were first declared in, so the composed schema is the same on every composition.
 func mergeFields(existing, new []*ast.FieldDefinition) []*ast.FieldDefinition {
-	fieldMap := make(map[string]*ast.FieldDefinition)
-
-	// Add existing fields to the map
-	for _, field := range existing {
-		fieldMap[field.Name.String()] = field
-	}
-
-	// Add new fields if they don't already exist
-	for _, field := range new {
-		if _, exists := fieldMap[field.Name.String()]; !exists {
-			fieldMap[field.Name.String()] = field
+	seen := make(map[string]bool, len(existing)+len(new))
+	result := make([]*ast.FieldDefinition, 0, len(existing)+len(new))
+	for _, fields := range [][]*ast.FieldDefinition{existing, new} {
+		for _, field := range fields {
+			if seen[field.Name.String()] {
+				continue
+			}
+			seen[field.Name.String()] = true
+			result = append(result, field)
 		}
 	}
 
-	// Convert map back to slice
-	result := make([]*ast.FieldDefinition, 0, len(fieldMap))
-	for _, field := range fieldMap {
-		result = append(result, field)
-	}
-
 	return result
 }
 
+// mergeInterfaces returns the interfaces of existing followed by those of new it lacks,
+// so that a type implements every interface any subgraph declares for it.
+func mergeInterfaces(existing, new []*ast.NamedType) []*ast.NamedType {
+	for _, iface := range new {
+		found := false
+		for _, e := range existing {
+			if e.Name.String() == iface.Name.String() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			// existing may be the list of a subgraph schema; never append into it
+			existing = append(existing[:len(existing):len(existing)], iface)
+		}
+	}
+	return existing
+}
+
 // mergeInterfaceTypeDefinition merges an InterfaceTypeDefinition.
 func (sg *SuperGraphV2) mergeInterfaceTypeDefinition(newDef *ast.InterfaceTypeDefinition) {
 	var existingDef *ast.InterfaceTypeDefinition
@@ -244,7 +373,10 @@ func (sg *SuperGraphV2) mergeInterfaceTypeDefinition(newDef *ast.InterfaceTypeDe
 		existingDef.Fields = append(existingDef.Fields, newDef.Fields...)
 		existingDef.Directives = append(existingDef.Directives, newDef.Directives...)
 	} else {
-		sg.Schema.Definitions = append(sg.Schema.Definitions, newDef)
+		copiedDef := *newDef
+		copiedDef.Fields = append([]*ast.FieldDefinition(nil), newDef.Fields...)
+		copiedDef.Directives = append([]*ast.Directive(nil), newDef.Directives...)
+		sg.Schema.Definitions = append(sg.Schema.Definitions, &copiedDef)
 	}
 }
 
@@ -264,7 +396,10 @@ func (sg *SuperGraphV2) mergeInputObjectTypeDefinition(newDef *ast.InputObjectTy
 		existingDef.Fields = append(existingDef.Fields, newDef.Fields...)
 		existingDef.Directives = append(existingDef.Directives, newDef.Directives...)
 	} else {
-		sg.Schema.Definitions = append(sg.Schema.Definitions, newDef)
+		copiedDef := *newDef
+		copiedDef.Fields = append([]*ast.InputValueDefinition(nil), newDef.Fields...)
+		copiedDef.Directives = append([]*ast.Directive(nil), newDef.Directives...)
+		sg.Schema.Definitions = append(sg.Schema.Definitions, &copiedDef)
 	}
 }
 
@@ -284,7 +419,10 @@ func (sg *SuperGraphV2) mergeEnumTypeDefinition(newDef *ast.EnumTypeDefinition)
 		existingDef.Values = append(existingDef.Values, newDef.Values...)
 		existingDef.Directives = append(existingDef.Directives, newDef.Directives...)
 	} else {
-		sg.Schema.Definitions = append(sg.Schema.Definitions, newDef)
+		copiedDef := *newDef
+		copiedDef.Values = append([]*ast.EnumValueDefinition(nil), newDef.Values...)
+		copiedDef.Directives = append([]*ast.Directive(nil), newDef.Directives...)
+		sg.Schema.Definitions = append(sg.Schema.Definitions, &copiedDef)
 	}
 }
 
@@ -301,7 +439,20 @@ func (sg *SuperGraphV2) mergeScalarTypeDefinition(newDef *ast.ScalarTypeDefiniti
 	}
 
 	if existingDef == nil {
-		sg.Schema.Definitions = append(sg.Schema.Definitions, newDef)
+		// Copied, so that merging the specification of another subgraph below does
+		// not modify the schema of this one.
+		copiedDef := *newDef
+		copiedDef.Directives = copyDirectives(newDef.Directives)
+		sg.Schema.Definitions = append(sg.Schema.Definitions, &copiedDef)
+		return
+	}
+	// Keep the specification of the scalar when only some subgraphs declare it.
+	if !hasDirective(existingDef.Directives, "specifiedBy") {
+		for _, d := range newDef.Directives {
+			if d.Name == "specifiedBy" {
+				existingDef.Directives = append(existingDef.Directives, d)
+			}
+		}
 	}
 }
 
@@ -321,7 +472,10 @@ func (sg *SuperGraphV2) mergeUnionTypeDefinition(newDef *ast.UnionTypeDefinition
 		existingDef.Types = append(existingDef.Types, newDef.Types...)
 		existingDef.Directives = append(existingDef.Directives, newDef.Directives...)
 	} else {
-		sg.Schema.Definitions = append(sg.Schema.Definitions, newDef)
+		copiedDef := *newDef
+		copiedDef.Types = append([]*ast.NamedType(nil), newDef.Types...)
+		copiedDef.Directives = append([]*ast.Directive(nil), newDef.Directives...)
+		sg.Schema.Definitions = append(sg.Schema.Definitions, &copiedDef)
 	}
 }
 
@@ -345,114 +499,193 @@ func (sg *SuperGraphV2) mergeDirectiveDefinition(newDef *ast.DirectiveDefinition
 // buildOwnershipMap constructs the ownership map.
 // It determines which subgraphs can resolve each field in the composed schema.
 func (sg *SuperGraphV2) buildOwnershipMap() error {
-	// Traverse all type definitions in the composed schema
-	for _, def := range sg.Schema.Definitions {
-		objDef, ok := def.(*ast.ObjectTypeDefinition)
-		if !ok {
-			continue
-		}
-
-		typeName := objDef.Name.String()
-
-		// Traverse all fields of the type
-		for _, field := range objDef.Fields {
-			fieldName := field.Name.String()
-			key := fmt.Sprintf("%s.%s", typeName, fieldName)
-
-			// Check for @override directive
-			var overrideFrom string
-			var overrideSubGraph *SubGraphV2
-
-			for _, subGraph := range sg.SubGraphs {
-				if entity, exists := subGraph.GetEntity(typeName); exists {
-					if entityField, ok := entity.Fields[fieldName]; ok {
-						if override := entityField.GetOverride(); override != nil {
-							overrideFrom = override.From
-							overrideSubGraph = subGraph
-							break
-						}
+	sg.buildOwnership(sg.Schema.Definitions)
+	return nil
+}
+
+// fieldOwnership is the ownership of a field computed by typeOwnership.
+type fieldOwnership struct {
+	key      string
+	owners   []*SubGraphV2
+	override *progressiveOverride // Set for fields with a progressive @override
+}
+
+// buildOwnership adds the owners of the fields of defs, definitions of the composed
+// schema, to the ownership map. The ownership of each type is computed concurrently.
+func (sg *SuperGraphV2) buildOwnership(defs []ast.Definition) {
+	ownerships := make([][]fieldOwnership, len(defs))
+	forEachConcurrently(len(defs), func(i int) {
+		ownerships[i] = sg.typeOwnership(defs[i])
+	})
+
+	for _, fields := range ownerships {
+		for _, field := range fields {
+			if len(field.owners) > 0 {
+				sg.Ownership[field.key] = append(sg.Ownership[field.key], field.owners...)
+			}
+			if field.override != nil {
+				sg.progressiveOverrides[field.key] = *field.override
+			}
+		}
+	}
+}
+
+// typeOwnership returns the owners of the fields of def, a definition of the composed
+// schema. Other definitions than object and interface types have no fields to own.
+func (sg *SuperGraphV2) typeOwnership(def ast.Definition) []fieldOwnership {
+	var typeName string
+	var fields []*ast.FieldDefinition
+	switch typeDef := def.(type) {
+	case *ast.ObjectTypeDefinition:
+		typeName, fields = typeDef.Name.String(), typeDef.Fields
+	case *ast.InterfaceTypeDefinition:
+		typeName, fields = typeDef.Name.String(), typeDef.Fields
+	default:
+		return nil
+	}
+
+	// Only the subgraphs declaring fields of the type can own them.
+	var declaring []*SubGraphV2
+	for _, subGraph := range sg.SubGraphs {
+		if subGraph.declaresFields(typeName) {
+			declaring = append(declaring, subGraph)
+		}
+	}
+
+	ownerships := make([]fieldOwnership, 0, len(fields))
+	// Traverse all fields of the type
+	for _, field := range fields {
+		fieldName := field.Name.String()
+		ownership := fieldOwnership{key: typeName + "." + fieldName}
+
+		// Check for @override directive
+		var overrideFrom, overrideLabel string
+		var overrideSubGraph *SubGraphV2
+
+		for _, subGraph := range declaring {
+			if entity, exists := subGraph.GetEntity(typeName); exists {
+				if entityField, ok := entity.Fields[fieldName]; ok {
+					if override := entityField.GetOverride(); override != nil {
+						overrideFrom = override.From
+						overrideLabel = override.Label
+						overrideSubGraph = subGraph
+						break
 					}
 				}
 			}
+		}
 
-			// Traverse all subgraphs to find those that can resolve this field
-			for _, subGraph := range sg.SubGraphs {
-				// Skip the original owner if @override is present
-				if overrideFrom != "" && subGraph.Name == overrideFrom {
-					continue
+		// A progressive override keeps the original owner while its label is
+		// inactive; the overridden ownership is applied by WithOverrideLabels.
+		var baseOwners []*SubGraphV2
+		if overrideLabel != "" {
+			for _, subGraph := range declaring {
+				if subGraph.Name == overrideFrom && sg.canResolveField(subGraph, typeName, fieldName) {
+					baseOwners = append(baseOwners, subGraph)
 				}
-
-				if sg.canResolveField(subGraph, typeName, fieldName) {
-					sg.Ownership[key] = append(sg.Ownership[key], subGraph)
+			}
+			for _, subGraph := range declaring {
+				if subGraph.Name != overrideFrom && sg.canResolveField(subGraph, typeName, fieldName) {
+					baseOwners = append(baseOwners, subGraph)
 				}
 			}
+		}
 
-			// Ensure the override subgraph is in the ownership list
-			if overrideSubGraph != nil {
-				found := false
-				for _, owner := range sg.Ownership[key] {
-					if owner.Name == overrideSubGraph.Name {
-						found = true
-						break
-					}
-				}
-				if !found {
-					sg.Ownership[key] = append(sg.Ownership[key], overrideSubGraph)
+		// Traverse all subgraphs to find those that can resolve this field
+		for _, subGraph := range declaring {
+			// Skip the original owner if @override is present
+			if overrideFrom != "" && subGraph.Name == overrideFrom {
+				continue
+			}
+
+			if sg.canResolveField(subGraph, typeName, fieldName) {
+				ownership.owners = append(ownership.owners, subGraph)
+			}
+		}
+
+		// Ensure the override subgraph is in the ownership list
+		if overrideSubGraph != nil {
+			found := false
+			for _, owner := range ownership.owners {
+				if owner.Name == overrideSubGraph.Name {
+					found = true
+					break
 				}
 			}
+			if !found {
+				ownership.owners = append(ownership.owners, overrideSubGraph)
+			}
+		}
+
+		if overrideLabel != "" && len(baseOwners) > 0 {
+			ownership.override = &progressiveOverride{label: overrideLabel, owners: ownership.owners}
+			ownership.owners = baseOwners
 		}
+		ownerships = append(ownerships, ownership)
 	}
+	return ownerships
+}
 
-	return nil
+// OverrideLabels returns the sorted labels of all progressive @override directives.
+func (sg *SuperGraphV2) OverrideLabels() []string {
+	seen := make(map[string]bool)
+	labels := make([]string, 0)
+	for _, override := range sg.progressiveOverrides {
+		if !seen[override.label] {
+			seen[override.label] = true
+			labels = append(labels, override.label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
 }
 
-// canResolveField checks if the specified subgraph can resolve the specified field.
-// It returns false if the field has an @external directive.
-func (sg *SuperGraphV2) canResolveField(subGraph *SubGraphV2, typeName, fieldName string) bool {
-	foundType := false
-	// Search for the corresponding type in the subgraph's schema
-	for _, def := range subGraph.Schema.Definitions {
-		// Check ObjectTypeDefinition
-		if objDef, ok := def.(*ast.ObjectTypeDefinition); ok {
-			if objDef.Name.String() == typeName {
-				foundType = true
-				for _, field := range objDef.Fields {
-					if field.Name.String() == fieldName {
-						// Cannot resolve if @external directive exists
-						if hasDirective(field.Directives, "external") {
-							return false
-						}
-						return true
-					}
-				}
-				// Cannot resolve if field not found
-				return false
-			}
+// WithOverrideLabels returns the super graph whose ownership routes every progressive
+// @override with an active label to the overriding subgraph. It returns sg itself when
+// no label is active. Variants are cached, so the result must not be modified.
+func (sg *SuperGraphV2) WithOverrideLabels(active map[string]bool) *SuperGraphV2 {
+	labels := make([]string, 0)
+	for _, label := range sg.OverrideLabels() {
+		if active[label] {
+			labels = append(labels, label)
 		}
 	}
+	if len(labels) == 0 {
+		return sg
+	}
 
-	// If ObjectTypeDefinition not found, check ObjectTypeExtension
-	if !foundType {
-		for _, def := range subGraph.Schema.Definitions {
-			if objExt, ok := def.(*ast.ObjectTypeExtension); ok {
-				if objExt.Name.String() == typeName {
-					for _, field := range objExt.Fields {
-						if field.Name.String() == fieldName {
-							// Cannot resolve if @external directive exists
-							if hasDirective(field.Directives, "external") {
-								return false
-							}
-							return true
-						}
-					}
-					// Cannot resolve if field not found
-					return false
-				}
-			}
+	cacheKey := strings.Join(labels, "\x00")
+	if variant, ok := sg.labelVariants.Load(cacheKey); ok {
+		return variant.(*SuperGraphV2)
+	}
+
+	ownership := make(map[string][]*SubGraphV2, len(sg.Ownership))
+	for key, owners := range sg.Ownership {
+		ownership[key] = owners
+	}
+	for key, override := range sg.progressiveOverrides {
+		if active[override.label] {
+			ownership[key] = override.owners
 		}
 	}
 
-	return false
+	variant := &SuperGraphV2{
+		SubGraphs:            sg.SubGraphs,
+		Schema:               sg.Schema,
+		Ownership:            ownership,
+		progressiveOverrides: sg.progressiveOverrides,
+		rootTypes:            sg.rootTypes,
+		index:                sg.index,
+	}
+	actual, _ := sg.labelVariants.LoadOrStore(cacheKey, variant)
+	return actual.(*SuperGraphV2)
+}
+
+// canResolveField checks if the specified subgraph can resolve the specified field.
+// It returns false if the field has an @external directive.
+func (sg *SuperGraphV2) canResolveField(subGraph *SubGraphV2, typeName, fieldName string) bool {
+	field := subGraph.FieldDefinition(typeName, fieldName)
+	return field != nil && !hasDirective(field.Directives, "external")
 }
 
 // hasDirective checks if a directive with the specified name exists.
@@ -467,8 +700,7 @@ func hasDirective(directives []*ast.Directive, name string) bool {
 
 // GetSubGraphsForField returns the list of subgraphs that can resolve the specified field.
 func (sg *SuperGraphV2) GetSubGraphsForField(typeName, fieldName string) []*SubGraphV2 {
-	key := fmt.Sprintf("%s.%s", typeName, fieldName)
-	return sg.Ownership[key]
+	return sg.Ownership[typeName+"."+fieldName]
 }
 
 // GetEntityOwnerSubGraph returns the subgraph that owns the entity (defines it with @key directive, not extends it).
@@ -476,6 +708,11 @@ func (sg *SuperGraphV2) GetSubGraphsForField(typeName, fieldName string) []*SubG
 // For entities defined in multiple resolvable subgraphs, it returns the first non-extension.
 // Returns nil if the type is not an entity or has no resolvable owners.
 func (sg *SuperGraphV2) GetEntityOwnerSubGraph(typeName string) *SubGraphV2 {
+	return sg.lookup().entityOwners[typeName]
+}
+
+// findEntityOwnerSubGraph computes GetEntityOwnerSubGraph for the index.
+func (sg *SuperGraphV2) findEntityOwnerSubGraph(typeName string) *SubGraphV2 {
 	// First pass: look for non-extension definitions with resolvable keys
 	for _, subGraph := range sg.SubGraphs {
 		if entity, exists := subGraph.GetEntity(typeName); exists && !entity.IsExtension() && entity.IsResolvable() {
@@ -493,17 +730,46 @@ func (sg *SuperGraphV2) GetEntityOwnerSubGraph(typeName string) *SubGraphV2 {
 	return nil
 }
 
+// IsAbstractType checks if a type is an interface or a union in the composed schema.
+func (sg *SuperGraphV2) IsAbstractType(typeName string) bool {
+	switch sg.TypeDefinition(typeName).(type) {
+	case *ast.InterfaceTypeDefinition, *ast.UnionTypeDefinition:
+		return true
+	}
+	return false
+}
+
 // IsEntityType checks if a type is an entity (has @key directive in any subgraph).
 func (sg *SuperGraphV2) IsEntityType(typeName string) bool {
 	return sg.GetEntityOwnerSubGraph(typeName) != nil
 }
 
+// IsInaccessibleType reports whether typeName is marked @inaccessible in the composed
+// schema or in a subgraph schema.
+func (sg *SuperGraphV2) IsInaccessibleType(typeName string) bool {
+	if sg.Schema != nil && inaccessibleTypes(sg.Schema)[typeName] {
+		return true
+	}
+	for _, subGraph := range sg.SubGraphs {
+		if subGraph.Schema != nil && inaccessibleTypes(subGraph.Schema)[typeName] {
+			return true
+		}
+	}
+	return false
+}
+
+// HasProgressiveOverride reports whether typeName.fieldName is the target of a
+// progressive @override, whose owner order routes it by label.
+func (sg *SuperGraphV2) HasProgressiveOverride(typeName, fieldName string) bool {
+	_, ok := sg.progressiveOverrides[typeName+"."+fieldName]
+	return ok
+}
+
 // GetFieldOwnerSubGraph returns the subgraph that owns a specific field.
 // It considers @override directives to determine the correct owner.
 // Returns the first subgraph in the ownership list, or nil if none found.
 func (sg *SuperGraphV2) GetFieldOwnerSubGraph(typeName, fieldName string) *SubGraphV2 {
-	key := fmt.Sprintf("%s.%s", typeName, fieldName)
-	owners := sg.Ownership[key]
+	owners := sg.Ownership[typeName+"."+fieldName]
 	if len(owners) > 0 {
 		return owners[0]
 	}
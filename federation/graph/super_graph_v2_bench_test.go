@@ -0,0 +1,116 @@
+package graph_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+)
+
+// benchmarkSources returns the sources of n subgraphs each owning the given number of
+// object types and as many enums, extending the shared Product entity and adding root
+// fields.
+func benchmarkSources(n, types int) []graph.SubGraphSource {
+	sources := make([]graph.SubGraphSource, 0, n)
+	for i := 0; i < n; i++ {
+		var sdl strings.Builder
+		if i == 0 {
+			sdl.WriteString(`type Product @key(fields: "id") { id: ID! name: String! } type Query { product(id: ID!): Product }` + "\n")
+		} else {
+			fmt.Fprintf(&sdl, `extend type Product @key(fields: "id") { id: ID! @external field%d: String } extend type Query { root%d: String }`+"\n", i, i)
+		}
+		for j := 0; j < types; j++ {
+			fmt.Fprintf(&sdl, "type Type%d_%d { id: ID! a: String b: Int c: [String] }\nenum Enum%d_%d { A B C }\n", i, j, i, j)
+		}
+
+		name := fmt.Sprintf("subgraph%d", i)
+		sources = append(sources, graph.SubGraphSource{Name: name, SDL: []byte(sdl.String()), Host: "http://" + name + ".example.com"})
+	}
+	return sources
+}
+
+// benchmarkSubGraphs returns the subgraphs of benchmarkSources.
+func benchmarkSubGraphs(b *testing.B, n, types int) []*graph.SubGraphV2 {
+	b.Helper()
+
+	subGraphs, err := graph.NewSubGraphsV2(benchmarkSources(n, types))
+	if err != nil {
+		b.Fatalf("NewSubGraphsV2 failed: %v", err)
+	}
+	return subGraphs
+}
+
+// BenchmarkNewSuperGraphV2 composes 40 subgraphs from scratch, as a full recomposition
+// on a subgraph update does.
+func BenchmarkNewSuperGraphV2(b *testing.B) {
+	subGraphs := benchmarkSubGraphs(b, 40, 10)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := graph.NewSuperGraphV2(subGraphs); err != nil {
+			b.Fatalf("NewSuperGraphV2 failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkSuperGraphV2_WithSubGraph recomposes the types of one of 40 subgraphs after
+// it is updated.
+func BenchmarkSuperGraphV2_WithSubGraph(b *testing.B) {
+	subGraphs := benchmarkSubGraphs(b, 40, 10)
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		b.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	updated := subGraphs[20]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := superGraph.WithSubGraph(updated); err != nil {
+			b.Fatalf("WithSubGraph failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkNewSuperGraphV2_Large composes 60 subgraphs declaring 6,000 types, as the
+// gateway does at startup and on reloads of large graphs.
+func BenchmarkNewSuperGraphV2_Large(b *testing.B) {
+	subGraphs := benchmarkSubGraphs(b, 60, 50)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := graph.NewSuperGraphV2(subGraphs); err != nil {
+			b.Fatalf("NewSuperGraphV2 failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkSuperGraphV2_WithSubGraph_Large recomposes the types of one of 60 subgraphs
+// declaring 6,000 types after it is updated.
+func BenchmarkSuperGraphV2_WithSubGraph_Large(b *testing.B) {
+	subGraphs := benchmarkSubGraphs(b, 60, 50)
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		b.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	updated := subGraphs[30]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := superGraph.WithSubGraph(updated); err != nil {
+			b.Fatalf("WithSubGraph failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkNewSubGraphsV2_Large parses the schemas of 60 subgraphs declaring 6,000 types.
+func BenchmarkNewSubGraphsV2_Large(b *testing.B) {
+	sources := benchmarkSources(60, 50)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := graph.NewSubGraphsV2(sources); err != nil {
+			b.Fatalf("NewSubGraphsV2 failed: %v", err)
+		}
+	}
+}
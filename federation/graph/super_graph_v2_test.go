@@ -1,6 +1,8 @@
 package graph_test
 
 import (
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
@@ -470,3 +472,403 @@ func TestNewSuperGraphV2_WithOverride(t *testing.T) {
 		t.Errorf("expected GetFieldOwnerSubGraph to return 'products-v2', got '%s'", nameOwner.Name)
 	}
 }
+
+func TestNewSuperGraphV2_WithProgressiveOverride(t *testing.T) {
+	productV1Schema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			price: Float!
+		}
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+	productV2Schema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			name: String! @override(from: "products", label: "percent(25)")
+			price: Float! @override(from: "products", label: "price-migration")
+		}
+	`
+
+	productV1SG, err := graph.NewSubGraphV2("products", []byte(productV1Schema), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for products: %v", err)
+	}
+	productV2SG, err := graph.NewSubGraphV2("products-v2", []byte(productV2Schema), "http://products-v2.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for products-v2: %v", err)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{productV1SG, productV2SG})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	if got := superGraph.OverrideLabels(); len(got) != 2 || got[0] != "percent(25)" || got[1] != "price-migration" {
+		t.Fatalf("expected override labels [percent(25) price-migration], got %v", got)
+	}
+
+	// With the labels inactive the original owner resolves the fields.
+	if owner := superGraph.GetFieldOwnerSubGraph("Product", "name"); owner == nil || owner.Name != "products" {
+		t.Errorf("expected Product.name to be owned by 'products' while its label is inactive, got %v", owner)
+	}
+	if same := superGraph.WithOverrideLabels(map[string]bool{"unrelated": true}); same != superGraph {
+		t.Error("expected WithOverrideLabels to return the super graph itself when no label is active")
+	}
+
+	active := superGraph.WithOverrideLabels(map[string]bool{"percent(25)": true})
+	if owner := active.GetFieldOwnerSubGraph("Product", "name"); owner == nil || owner.Name != "products-v2" {
+		t.Errorf("expected Product.name to be owned by 'products-v2' while its label is active, got %v", owner)
+	}
+	if owner := active.GetFieldOwnerSubGraph("Product", "price"); owner == nil || owner.Name != "products" {
+		t.Errorf("expected Product.price to stay with 'products', got %v", owner)
+	}
+	if cached := superGraph.WithOverrideLabels(map[string]bool{"percent(25)": true}); cached != active {
+		t.Error("expected WithOverrideLabels to reuse the cached variant")
+	}
+
+	// The base ownership is left untouched.
+	if owner := superGraph.GetFieldOwnerSubGraph("Product", "name"); owner == nil || owner.Name != "products" {
+		t.Errorf("expected base ownership of Product.name to be unchanged, got %v", owner)
+	}
+}
+
+func TestNewSuperGraphV2_CustomRootTypes(t *testing.T) {
+	productsSchema := `
+		schema {
+			query: RootQuery
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type RootQuery {
+			products: [Product]
+		}
+	`
+	reviewsSchema := `
+		schema {
+			query: RootQuery
+		}
+
+		type Review {
+			body: String!
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review]
+		}
+	`
+
+	productsSG, err := graph.NewSubGraphV2("products", []byte(productsSchema), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for products: %v", err)
+	}
+	reviewsSG, err := graph.NewSubGraphV2("reviews", []byte(reviewsSchema), "http://reviews.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for reviews: %v", err)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{productsSG, reviewsSG})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	if got := superGraph.RootTypeName(ast.Query); got != "RootQuery" {
+		t.Errorf("expected query root type 'RootQuery', got %q", got)
+	}
+	if got := superGraph.RootTypeName(ast.Mutation); got != "Mutation" {
+		t.Errorf("expected default mutation root type 'Mutation', got %q", got)
+	}
+	if !superGraph.IsRootType("RootQuery") {
+		t.Error("expected RootQuery to be a root type")
+	}
+	if superGraph.IsRootType("Query") {
+		t.Error("expected Query not to be a root type once renamed")
+	}
+	if sdl := superGraph.SDL(); !strings.Contains(sdl, "schema {\n  query: RootQuery\n}") {
+		t.Errorf("expected SDL to declare the custom query root type, got:\n%s", sdl)
+	}
+
+	conflicting, err := graph.NewSubGraphV2("accounts", []byte(`
+		type User {
+			id: ID!
+		}
+
+		type Query {
+			me: User
+		}
+	`), "http://accounts.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for accounts: %v", err)
+	}
+	if _, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{productsSG, conflicting}); err == nil {
+		t.Error("expected an error when subgraphs use different query root types")
+	}
+}
+
+func TestSuperGraphV2_WithSubGraph(t *testing.T) {
+	newSubGraph := func(name, schema string) *graph.SubGraphV2 {
+		t.Helper()
+		subGraph, err := graph.NewSubGraphV2(name, []byte(schema), "http://"+name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 failed for %s: %v", name, err)
+		}
+		return subGraph
+	}
+
+	products := newSubGraph("products", `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			products: [Product]
+		}
+	`)
+	reviews := newSubGraph("reviews", `
+		type Review {
+			body: String!
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review]
+		}
+
+		extend type Query {
+			reviews: [Review]
+		}
+	`)
+	accounts := newSubGraph("accounts", `
+		type User @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		extend type Query {
+			me: User
+		}
+	`)
+
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{products, reviews, accounts})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	before := superGraph.SDL()
+
+	// reviews drops Review.body, adds Review.rating and a Rating enum, and stops
+	// extending Query.
+	updated := newSubGraph("reviews", `
+		enum Rating {
+			GOOD
+			BAD
+		}
+
+		type Review {
+			rating: Rating
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review]
+		}
+	`)
+
+	tests := []struct {
+		name      string
+		subGraph  *graph.SubGraphV2
+		subGraphs []*graph.SubGraphV2
+	}{
+		{
+			name:      "replaced subgraph",
+			subGraph:  updated,
+			subGraphs: []*graph.SubGraphV2{products, updated, accounts},
+		},
+		{
+			name: "added subgraph",
+			subGraph: newSubGraph("inventory", `
+				extend type Product @key(fields: "id") {
+					id: ID! @external
+					inStock: Boolean
+				}
+			`),
+		},
+	}
+	tests[1].subGraphs = []*graph.SubGraphV2{products, reviews, accounts, tests[1].subGraph}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := superGraph.WithSubGraph(tt.subGraph)
+			if err != nil {
+				t.Fatalf("WithSubGraph failed: %v", err)
+			}
+			want, err := graph.NewSuperGraphV2(tt.subGraphs)
+			if err != nil {
+				t.Fatalf("NewSuperGraphV2 failed: %v", err)
+			}
+
+			if g, w := sdlBlocks(got.SDL()), sdlBlocks(want.SDL()); strings.Join(g, "\n\n") != strings.Join(w, "\n\n") {
+				t.Errorf("expected the composed schema of a full composition\ngot:\n%s\nwant:\n%s", got.SDL(), want.SDL())
+			}
+			if g, w := ownership(got), ownership(want); g != w {
+				t.Errorf("expected the ownership of a full composition\ngot:\n%s\nwant:\n%s", g, w)
+			}
+			if len(got.SubGraphs) != len(tt.subGraphs) {
+				t.Errorf("expected %d subgraphs, got %d", len(tt.subGraphs), len(got.SubGraphs))
+			}
+			if superGraph.SDL() != before {
+				t.Error("expected the original super graph to be left unchanged")
+			}
+		})
+	}
+}
+
+// sdlBlocks returns the sorted top-level definitions of sdl.
+func sdlBlocks(sdl string) []string {
+	blocks := strings.Split(strings.TrimSpace(sdl), "\n\n")
+	sort.Strings(blocks)
+	return blocks
+}
+
+// ownership prints the owners of every field of superGraph, sorted by field.
+func ownership(superGraph *graph.SuperGraphV2) string {
+	keys := make([]string, 0, len(superGraph.Ownership))
+	for key := range superGraph.Ownership {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(key)
+		for _, owner := range superGraph.Ownership[key] {
+			sb.WriteString(" " + owner.Name)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func TestSuperGraphV2_FieldDefinition(t *testing.T) {
+	products, err := graph.NewSubGraphV2("products", []byte(`
+		interface Node {
+			id: ID!
+		}
+
+		type Product implements Node @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			products: [Product]
+		}
+	`), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for products: %v", err)
+	}
+	reviews, err := graph.NewSubGraphV2("reviews", []byte(`
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			rating: Int
+		}
+	`), "http://reviews.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for reviews: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{products, reviews})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	tests := []struct {
+		typeName, fieldName string
+		want                bool
+	}{
+		{"Product", "name", true},
+		{"Product", "rating", true},
+		{"Node", "id", true},
+		{"Product", "missing", false},
+		{"Missing", "id", false},
+	}
+	for _, tt := range tests {
+		if got := superGraph.FieldDefinition(tt.typeName, tt.fieldName) != nil; got != tt.want {
+			t.Errorf("expected FieldDefinition(%q, %q) found to be %v", tt.typeName, tt.fieldName, tt.want)
+		}
+	}
+	if _, ok := superGraph.TypeDefinition("Node").(*ast.InterfaceTypeDefinition); !ok {
+		t.Error("expected Node to be an interface type definition")
+	}
+	if reviews.FieldDefinition("Product", "rating") == nil {
+		t.Error("expected reviews to declare Product.rating in its type extension")
+	}
+	if got := superGraph.GetEntityOwnerSubGraph("Product"); got != products {
+		t.Errorf("expected products to own Product, got %v", got)
+	}
+
+	// Fields with the same owners share one owner list.
+	name, list := superGraph.Ownership["Product.name"], superGraph.Ownership["Query.products"]
+	if len(name) != 1 || len(list) != 1 || &name[0] != &list[0] {
+		t.Error("expected Product.name and Query.products to share their owner list")
+	}
+}
+
+func TestSuperGraphV2_Contexts(t *testing.T) {
+	users, err := graph.NewSubGraphV2("users", []byte(`
+		type User @key(fields: "id") @context(name: "userContext") {
+			id: ID!
+			currency: String!
+		}
+	`), "http://users.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for users: %v", err)
+	}
+	products, err := graph.NewSubGraphV2("products", []byte(`
+		type Product @key(fields: "id") {
+			id: ID!
+			price(unit: String, currency: String! @fromContext(field: "$userContext { currency }")): Float!
+		}
+	`), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for products: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{users, products})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	if !superGraph.SetsContext("User", "userContext") {
+		t.Error("expected User to set userContext")
+	}
+	if superGraph.SetsContext("Product", "userContext") {
+		t.Error("expected Product not to set userContext")
+	}
+
+	entity, ok := products.GetEntity("Product")
+	if !ok {
+		t.Fatal("expected Product to be an entity of products")
+	}
+	args := entity.Fields["price"].ContextArguments
+	if len(args) != 1 || args[0].Name != "currency" || args[0].Context != "userContext" || args[0].Selection.String() != "currency" {
+		t.Errorf("expected currency to be set from userContext { currency }, got %+v", args)
+	}
+
+	price := superGraph.FieldDefinition("Product", "price")
+	if price == nil {
+		t.Fatal("expected Product.price in the composed schema")
+	}
+	if len(price.Arguments) != 1 || price.Arguments[0].Name.String() != "unit" {
+		t.Errorf("expected only the unit argument in the composed schema, got %d arguments", len(price.Arguments))
+	}
+	if products.FieldDefinition("Product", "price") == nil || len(products.FieldDefinition("Product", "price").Arguments) != 2 {
+		t.Error("expected the products schema to keep the currency argument")
+	}
+}
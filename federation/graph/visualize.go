@@ -0,0 +1,209 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// EdgeStyle distinguishes the kinds of edges in a Visualization.
+type EdgeStyle string
+
+const (
+	// EdgeStyleLocal is an edge resolved within a single subgraph.
+	EdgeStyleLocal EdgeStyle = "local"
+	// EdgeStyleCrossSubGraph is an edge that needs an entity fetch from another subgraph.
+	EdgeStyleCrossSubGraph EdgeStyle = "cross"
+	// EdgeStyleProvides is a @provides shortcut that avoids an entity fetch.
+	EdgeStyleProvides EdgeStyle = "provides"
+)
+
+// VisualNode is a node of a Visualization.
+type VisualNode struct {
+	ID    string
+	Label string
+}
+
+// VisualEdge is a directed edge of a Visualization.
+type VisualEdge struct {
+	From  string
+	To    string
+	Label string
+	Style EdgeStyle
+}
+
+// Visualization is a renderable directed graph, exported as DOT or Mermaid.
+type Visualization struct {
+	Nodes []VisualNode
+	Edges []VisualEdge
+}
+
+// Visualize builds a graph of the composed schema: one node per object type listing
+// its fields with their owning subgraphs, and one edge per field returning another
+// object type. Edges that cross subgraphs and @provides shortcuts are styled apart.
+func (sg *SuperGraphV2) Visualize() *Visualization {
+	v := &Visualization{}
+
+	objects := make(map[string]*ast.ObjectTypeDefinition)
+	names := make([]string, 0)
+	for _, def := range sg.Schema.Definitions {
+		objDef, ok := def.(*ast.ObjectTypeDefinition)
+		if !ok || strings.HasPrefix(objDef.Name.String(), "_") {
+			continue
+		}
+		if _, seen := objects[objDef.Name.String()]; !seen {
+			names = append(names, objDef.Name.String())
+		}
+		objects[objDef.Name.String()] = objDef
+	}
+	sort.Strings(names)
+
+	provides := sg.providesShortcuts()
+
+	for _, typeName := range names {
+		objDef := objects[typeName]
+
+		lines := []string{typeName}
+		for _, field := range objDef.Fields {
+			fieldName := field.Name.String()
+			owners := make([]string, 0)
+			for _, owner := range sg.GetSubGraphsForField(typeName, fieldName) {
+				owners = append(owners, owner.Name)
+			}
+			lines = append(lines, fmt.Sprintf("%s [%s]", fieldName, strings.Join(owners, ", ")))
+
+			target := namedType(field.Type)
+			if _, ok := objects[target]; !ok {
+				continue
+			}
+
+			edge := VisualEdge{From: typeName, To: target, Label: fieldName, Style: EdgeStyleLocal}
+			owner := sg.GetFieldOwnerSubGraph(typeName, fieldName)
+			if targetOwner := sg.GetEntityOwnerSubGraph(target); owner != nil && targetOwner != nil && owner.Name != targetOwner.Name {
+				edge.Style = EdgeStyleCrossSubGraph
+				edge.Label = fmt.Sprintf("%s (%s → %s)", fieldName, owner.Name, targetOwner.Name)
+			}
+			v.Edges = append(v.Edges, edge)
+
+			if fields, ok := provides[typeName+"."+fieldName]; ok {
+				v.Edges = append(v.Edges, providesEdges(objects, typeName, target, fields)...)
+			}
+		}
+
+		v.Nodes = append(v.Nodes, VisualNode{ID: typeName, Label: strings.Join(lines, "\n")})
+	}
+
+	return v
+}
+
+// providesShortcuts collects the @provides field sets declared by any subgraph,
+// keyed by "Type.field".
+func (sg *SuperGraphV2) providesShortcuts() map[string]FieldSet {
+	shortcuts := make(map[string]FieldSet)
+	for _, subGraph := range sg.SubGraphs {
+		for key, fields := range subGraph.provides {
+			shortcuts[key] = fields
+		}
+	}
+	return shortcuts
+}
+
+// providesEdges returns the @provides shortcut from typeName to target through
+// fieldName, followed by one shortcut per nested selection of fields, so that provided
+// sub-selections are modeled down to the types they reach.
+func providesEdges(objects map[string]*ast.ObjectTypeDefinition, typeName, target string, fields FieldSet) []VisualEdge {
+	edges := []VisualEdge{{
+		From:  typeName,
+		To:    target,
+		Label: fmt.Sprintf("@provides(%s)", fields),
+		Style: EdgeStyleProvides,
+	}}
+
+	objDef, ok := objects[target]
+	if !ok {
+		return edges
+	}
+	for _, field := range fields {
+		if len(field.Selections) == 0 {
+			continue
+		}
+		for _, def := range objDef.Fields {
+			if def.Name.String() != field.Name {
+				continue
+			}
+			if nested := namedType(def.Type); objects[nested] != nil {
+				edges = append(edges, providesEdges(objects, target, nested, field.Selections)...)
+			}
+		}
+	}
+	return edges
+}
+
+// namedType unwraps list and non-null wrappers and returns the named type.
+func namedType(t ast.Type) string {
+	switch typ := t.(type) {
+	case *ast.NamedType:
+		return typ.Name.String()
+	case *ast.ListType:
+		return namedType(typ.Type)
+	case *ast.NonNullType:
+		return namedType(typ.Type)
+	default:
+		return ""
+	}
+}
+
+// DOT renders the visualization in Graphviz DOT syntax.
+func (v *Visualization) DOT(name string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %q {\n", name)
+	sb.WriteString("  node [shape=box];\n")
+	for _, node := range v.Nodes {
+		fmt.Fprintf(&sb, "  %q [label=%q];\n", node.ID, node.Label)
+	}
+	for _, edge := range v.Edges {
+		attrs := fmt.Sprintf("label=%q", edge.Label)
+		switch edge.Style {
+		case EdgeStyleCrossSubGraph:
+			attrs += ", style=dashed, color=red"
+		case EdgeStyleProvides:
+			attrs += ", style=dotted, color=blue"
+		}
+		fmt.Fprintf(&sb, "  %q -> %q [%s];\n", edge.From, edge.To, attrs)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the visualization as a Mermaid flowchart.
+func (v *Visualization) Mermaid() string {
+	ids := make(map[string]string, len(v.Nodes))
+	for i, node := range v.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	for _, node := range v.Nodes {
+		label := strings.ReplaceAll(mermaidEscape(node.Label), "\n", "<br/>")
+		fmt.Fprintf(&sb, "  %s[\"%s\"]\n", ids[node.ID], label)
+	}
+	for _, edge := range v.Edges {
+		arrow := "-->"
+		switch edge.Style {
+		case EdgeStyleCrossSubGraph:
+			arrow = "-.->"
+		case EdgeStyleProvides:
+			arrow = "==>"
+		}
+		fmt.Fprintf(&sb, "  %s %s|\"%s\"| %s\n", ids[edge.From], arrow, mermaidEscape(edge.Label), ids[edge.To])
+	}
+	return sb.String()
+}
+
+// mermaidEscape escapes characters that break Mermaid quoted labels.
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, "\"", "#quot;")
+}
@@ -0,0 +1,137 @@
+package graph_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+)
+
+func newVisualizeSuperGraph(t *testing.T) *graph.SuperGraphV2 {
+	t.Helper()
+	products, err := graph.NewSubGraphV2("products", []byte(`
+		type Query { topProducts: [Product] }
+		type Product @key(fields: "upc") { upc: String! name: String }
+	`), "http://products")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	reviews, err := graph.NewSubGraphV2("reviews", []byte(`
+		type Review { body: String product: Product @provides(fields: "name") }
+		extend type Product @key(fields: "upc") {
+			upc: String! @external
+			name: String @external
+			reviews: [Review]
+		}
+	`), "http://reviews")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	sg, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{products, reviews})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	return sg
+}
+
+func TestSuperGraphV2_Visualize(t *testing.T) {
+	v := newVisualizeSuperGraph(t).Visualize()
+
+	wantEdges := map[string]graph.EdgeStyle{
+		"Query->Product:topProducts":                   graph.EdgeStyleLocal,
+		"Product->Review:reviews":                      graph.EdgeStyleLocal,
+		"Review->Product:product (reviews → products)": graph.EdgeStyleCrossSubGraph,
+		"Review->Product:@provides(name)":              graph.EdgeStyleProvides,
+	}
+	if len(v.Edges) != len(wantEdges) {
+		t.Fatalf("expected %d edges, got %+v", len(wantEdges), v.Edges)
+	}
+	for _, edge := range v.Edges {
+		key := edge.From + "->" + edge.To + ":" + edge.Label
+		style, ok := wantEdges[key]
+		if !ok {
+			t.Errorf("unexpected edge %s", key)
+			continue
+		}
+		if edge.Style != style {
+			t.Errorf("edge %s: expected style %s, got %s", key, style, edge.Style)
+		}
+	}
+
+	for _, node := range v.Nodes {
+		if node.ID == "Product" && !strings.Contains(node.Label, "reviews [reviews]") {
+			t.Errorf("expected Product node to list field owners, got %q", node.Label)
+		}
+	}
+}
+
+func TestSuperGraphV2_Visualize_NestedProvides(t *testing.T) {
+	products, err := graph.NewSubGraphV2("products", []byte(`
+		type Query { topProducts: [Product] }
+		type Product @key(fields: "upc") { upc: String! name: String maker: Maker }
+		type Maker @key(fields: "id") { id: ID! name: String }
+	`), "http://products")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	reviews, err := graph.NewSubGraphV2("reviews", []byte(`
+		type Query { reviews: [Review] }
+		type Review { body: String product: Product @provides(fields: "name maker { name }") }
+		extend type Product @key(fields: "upc") {
+			upc: String! @external
+			name: String @external
+			maker: Maker @external
+		}
+		extend type Maker @key(fields: "id") { id: ID! @external name: String @external }
+	`), "http://reviews")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	sg, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{products, reviews})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	want := map[string]bool{
+		"Review->Product:@provides(name maker { name })": false,
+		"Product->Maker:@provides(name)":                 false,
+	}
+	for _, edge := range sg.Visualize().Edges {
+		if edge.Style != graph.EdgeStyleProvides {
+			continue
+		}
+		key := edge.From + "->" + edge.To + ":" + edge.Label
+		if _, ok := want[key]; !ok {
+			t.Errorf("unexpected provides edge %s", key)
+			continue
+		}
+		want[key] = true
+	}
+	for key, found := range want {
+		if !found {
+			t.Errorf("expected provides edge %s", key)
+		}
+	}
+}
+
+func TestVisualization_Render(t *testing.T) {
+	v := newVisualizeSuperGraph(t).Visualize()
+
+	dot := v.DOT("supergraph")
+	for _, want := range []string{
+		`digraph "supergraph" {`,
+		`"Review" -> "Product" [label="product (reviews → products)", style=dashed, color=red];`,
+		`"Review" -> "Product" [label="@provides(name)", style=dotted, color=blue];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, dot)
+		}
+	}
+
+	mermaid := v.Mermaid()
+	for _, want := range []string{"flowchart LR", `-.->|"product (reviews → products)"|`, `==>|"@provides(name)"|`} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, mermaid)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package planner
+
+import (
+	"slices"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// fanOutSubGraphs returns the subgraphs a root field of an abstract type is sent to when
+// none of its owners resolves every possible type of the field, e.g. a Relay node field
+// declared by each subgraph for its own types. Each of them is asked for the objects of
+// its own types, and the executor keeps the non-null answers. It returns nil when one
+// owner resolves the field alone: a single owner, a subgraph hinted on the field, an
+// owner implementing every possible type, or a mutation field, which is never repeated.
+func (p *PlannerV2) fanOutSubGraphs(operation ast.OperationType, rootTypeName string, field *ast.Field, subGraphs []*graph.SubGraphV2) []*graph.SubGraphV2 {
+	if operation == ast.Mutation || len(subGraphs) < 2 || hintedSubGraph(field, subGraphs) != nil {
+		return nil
+	}
+	fieldType, err := p.getFieldTypeName(rootTypeName, field.Name.String())
+	if err != nil || !p.SuperGraph.IsAbstractType(fieldType) {
+		return nil
+	}
+
+	possible := p.SuperGraph.PossibleTypes(fieldType)
+	var fanOut []*graph.SubGraphV2
+	for _, sg := range subGraphs {
+		implemented := sg.PossibleTypes(fieldType)
+		if containsAllTypes(implemented, possible) {
+			return nil
+		}
+		if len(implemented) > 0 {
+			fanOut = append(fanOut, sg)
+		}
+	}
+	if len(fanOut) < 2 {
+		return nil
+	}
+	return fanOut
+}
+
+// containsAllTypes reports whether types has every type of required.
+func containsAllTypes(types, required []string) bool {
+	for _, typeName := range required {
+		if !slices.Contains(types, typeName) {
+			return false
+		}
+	}
+	return true
+}
+
+// restrictToSubGraphTypes returns field, of the abstract type fieldType, without the
+// fragments on types subGraph cannot return: a subgraph answering a fanned-out root
+// field only returns objects of its own types, and would reject fragments on types it
+// does not know.
+func (p *PlannerV2) restrictToSubGraphTypes(field *ast.Field, fieldType string, subGraph *graph.SubGraphV2) *ast.Field {
+	restricted := copyField(field)
+	restricted.SelectionSet = p.restrictSelections(field.SelectionSet, fieldType, subGraph)
+	if len(restricted.SelectionSet) == 0 {
+		restricted.SelectionSet = []ast.Selection{newField("__typename")}
+	}
+	return restricted
+}
+
+// restrictSelections returns selections, of parentType, without the fragments on types
+// subGraph cannot return for parentType, at any depth of the fields subGraph declares.
+// The fields it does not declare are resolved by other subgraphs and left as they are.
+// It returns selections itself when nothing is dropped.
+func (p *PlannerV2) restrictSelections(selections []ast.Selection, parentType string, subGraph *graph.SubGraphV2) []ast.Selection {
+	returned := subGraphObjectTypes(subGraph, parentType)
+	var result []ast.Selection
+	for i, sel := range selections {
+		kept := sel
+		switch s := sel.(type) {
+		case *ast.Field:
+			fieldName := s.Name.String()
+			if len(s.SelectionSet) == 0 || subGraph.FieldDefinition(parentType, fieldName) == nil {
+				break
+			}
+			fieldType, err := p.getFieldTypeName(parentType, fieldName)
+			if err != nil {
+				break
+			}
+			if children := p.restrictSelections(s.SelectionSet, fieldType, subGraph); !sameSelections(children, s.SelectionSet) {
+				restricted := copyField(s)
+				restricted.SelectionSet = children
+				if len(children) == 0 {
+					restricted.SelectionSet = []ast.Selection{newField("__typename")}
+				}
+				kept = restricted
+			}
+
+		case *ast.InlineFragment:
+			typeCondition := parentType
+			if s.TypeCondition != nil {
+				typeCondition = s.TypeCondition.Name.String()
+			}
+			if typeCondition != parentType && !overlapsTypes(subGraphObjectTypes(subGraph, typeCondition), returned) {
+				kept = nil
+				break
+			}
+			if children := p.restrictSelections(s.SelectionSet, typeCondition, subGraph); !sameSelections(children, s.SelectionSet) {
+				kept = nil
+				if len(children) > 0 {
+					kept = newInlineFragment(s.TypeCondition, s.Directives, children)
+				}
+			}
+		}
+
+		if kept != sel && result == nil {
+			result = append(make([]ast.Selection, 0, len(selections)), selections[:i]...)
+		}
+		if result != nil && kept != nil {
+			result = append(result, kept)
+		}
+	}
+	if result == nil {
+		return selections
+	}
+	return result
+}
+
+// subGraphObjectTypes returns the object types subGraph may return for typeName: the
+// possible types of an abstract type in its schema, or typeName itself.
+func subGraphObjectTypes(subGraph *graph.SubGraphV2, typeName string) []string {
+	if types := subGraph.PossibleTypes(typeName); len(types) > 0 {
+		return types
+	}
+	return []string{typeName}
+}
+
+// overlapsTypes reports whether a and b have a type in common.
+func overlapsTypes(a, b []string) bool {
+	for _, typeName := range a {
+		if slices.Contains(b, typeName) {
+			return true
+		}
+	}
+	return false
+}
+
+// distributeAbstractFields rewrites the fields of an abstract parentType that subGraph
+// does not resolve, selected without fragments, into fragments on each possible type
+// subGraph implements, so that they are fetched from the owners of those types instead
+// of being dropped. It descends into the fields subGraph resolves itself, and returns
+// selections itself when nothing is rewritten.
+func (p *PlannerV2) distributeAbstractFields(selections []ast.Selection, parentType string, subGraph *graph.SubGraphV2) []ast.Selection {
+	abstract := p.SuperGraph.IsAbstractType(parentType)
+	var result []ast.Selection
+	for i, sel := range selections {
+		var rewritten []ast.Selection
+		switch s := sel.(type) {
+		case *ast.Field:
+			fieldName := s.Name.String()
+			if fieldName == "__typename" {
+				break
+			}
+			if abstract && !containsSubGraph(p.SuperGraph.GetSubGraphsForField(parentType, fieldName), subGraph) {
+				rewritten = []ast.Selection{}
+				for _, typeName := range subGraph.PossibleTypes(parentType) {
+					if p.SuperGraph.FieldDefinition(typeName, fieldName) == nil {
+						continue
+					}
+					typeCondition := &ast.NamedType{Name: newName(typeName)}
+					rewritten = append(rewritten, newInlineFragment(typeCondition, nil, p.distributeAbstractFields([]ast.Selection{s}, typeName, subGraph)))
+				}
+				break
+			}
+			if owner := p.resolvingFieldSubGraph(parentType, s, subGraph, nil); len(s.SelectionSet) == 0 || owner == nil || owner.Name != subGraph.Name {
+				break
+			}
+			fieldType, err := p.getFieldTypeName(parentType, fieldName)
+			if err != nil {
+				break
+			}
+			if children := p.distributeAbstractFields(s.SelectionSet, fieldType, subGraph); !sameSelections(children, s.SelectionSet) {
+				distributed := copyField(s)
+				distributed.SelectionSet = children
+				rewritten = []ast.Selection{distributed}
+			}
+
+		case *ast.InlineFragment:
+			typeCondition := parentType
+			if s.TypeCondition != nil {
+				typeCondition = s.TypeCondition.Name.String()
+			}
+			if children := p.distributeAbstractFields(s.SelectionSet, typeCondition, subGraph); !sameSelections(children, s.SelectionSet) {
+				rewritten = []ast.Selection{newInlineFragment(s.TypeCondition, s.Directives, children)}
+			}
+		}
+
+		if rewritten != nil && result == nil {
+			result = append(make([]ast.Selection, 0, len(selections)), selections[:i]...)
+		}
+		switch {
+		case rewritten != nil:
+			result = append(result, rewritten...)
+		case result != nil:
+			result = append(result, sel)
+		}
+	}
+	if result == nil {
+		return selections
+	}
+	return result
+}
+
+// sameSelections reports whether a and b are the same slice.
+func sameSelections(a, b []ast.Selection) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// containsSubGraph reports whether subGraphs has subGraph.
+func containsSubGraph(subGraphs []*graph.SubGraphV2, subGraph *graph.SubGraphV2) bool {
+	for _, sg := range subGraphs {
+		if sg.Name == subGraph.Name {
+			return true
+		}
+	}
+	return false
+}
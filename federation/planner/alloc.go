@@ -0,0 +1,88 @@
+package planner
+
+import (
+	"sync"
+
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/token"
+)
+
+// slabSize is the number of nodes allocated at once by a slabPool.
+const slabSize = 256
+
+// slab hands out the nodes of one backing array in order.
+type slab[T any] struct {
+	nodes []T
+	next  int
+}
+
+// slabPool allocates planner AST nodes from pooled slabs, so that a plan's selections
+// cost a few large allocations instead of one per node. A node is never handed out twice,
+// so plans may keep their nodes for as long as they like; a slab is garbage collected
+// once none of its nodes is referenced.
+type slabPool[T any] struct {
+	pool sync.Pool
+}
+
+// alloc returns a zeroed node.
+func (p *slabPool[T]) alloc() *T {
+	s, _ := p.pool.Get().(*slab[T])
+	if s == nil || s.next == len(s.nodes) {
+		s = &slab[T]{nodes: make([]T, slabSize)}
+	}
+	node := &s.nodes[s.next]
+	s.next++
+	p.pool.Put(s)
+	return node
+}
+
+// namedField is a field allocated together with its name.
+type namedField struct {
+	field ast.Field
+	name  ast.Name
+}
+
+var (
+	fieldNodes      slabPool[ast.Field]
+	namedFieldNodes slabPool[namedField]
+	nameNodes       slabPool[ast.Name]
+	fragmentNodes   slabPool[ast.InlineFragment]
+)
+
+// newName returns a name node for value.
+func newName(value string) *ast.Name {
+	name := nameNodes.alloc()
+	name.Token = token.Token{Type: token.IDENT, Literal: value}
+	name.Value = value
+	return name
+}
+
+// newField returns a leaf field selection named name.
+func newField(name string) *ast.Field {
+	node := namedFieldNodes.alloc()
+	node.name.Token = token.Token{Type: token.IDENT, Literal: name}
+	node.name.Value = name
+	node.field.Name = &node.name
+	return &node.field
+}
+
+// copyField returns a field with the alias, name, arguments and directives of field,
+// and no selections.
+func copyField(field *ast.Field) *ast.Field {
+	copied := fieldNodes.alloc()
+	copied.Token = field.Token
+	copied.Alias = field.Alias
+	copied.Name = field.Name
+	copied.Arguments = field.Arguments
+	copied.Directives = field.Directives
+	return copied
+}
+
+// newInlineFragment returns an inline fragment on typeCondition.
+func newInlineFragment(typeCondition *ast.NamedType, directives []*ast.Directive, selections []ast.Selection) *ast.InlineFragment {
+	fragment := fragmentNodes.alloc()
+	fragment.TypeCondition = typeCondition
+	fragment.Directives = directives
+	fragment.SelectionSet = selections
+	return fragment
+}
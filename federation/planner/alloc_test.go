@@ -0,0 +1,25 @@
+//go:build !race
+
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+// The race detector drops sync.Pool items at random, so allocation counts are only
+// checked without it.
+func TestNewFieldNode_AllocatesFromSlabs(t *testing.T) {
+	const fields = 1024
+	allocs := testing.AllocsPerRun(10, func() {
+		for i := 0; i < fields; i++ {
+			planner.NewFieldNode("id")
+		}
+	})
+
+	// A field and its name cost two allocations each when allocated one by one.
+	if allocs >= fields/8 {
+		t.Errorf("expected fields to be allocated from slabs, got %.0f allocations for %d fields", allocs, fields)
+	}
+}
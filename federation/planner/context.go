@@ -0,0 +1,203 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// ContextValue is a @fromContext argument of the fields of an entity step, sent as the
+// variable Variable. For each entity its value is selected by Selection from the
+// ancestor object at InsertionPath[:Depth+1], the nearest one setting the context.
+type ContextValue struct {
+	Variable  string
+	Depth     int
+	Selection []ast.Selection
+}
+
+// contextVariablePrefix prefixes the variables of ContextValue. Names starting with two
+// underscores are reserved by GraphQL, so they cannot collide with client variables.
+const contextVariablePrefix = "__context"
+
+// injectContextDependencies sets the @fromContext arguments of the fields of every entity
+// step. The nearest ancestor of the entities whose type sets the context is found along
+// the insertion path; the fields the argument selects from it are injected into the step
+// fetching the ancestor when its subgraph resolves them, and fetched by a context step
+// the entity step depends on otherwise. Arguments whose context no ancestor sets are
+// left unset.
+func (p *PlannerV2) injectContextDependencies(plan *PlanV2, rootTypeName string, selections []ast.Selection) {
+	contextSteps := make(map[string]*StepV2)
+	variables := 0
+
+	for i := 0; i < len(plan.Steps); i++ {
+		step := plan.Steps[i]
+		if step.StepType != StepTypeEntity || len(step.DependsOn) == 0 || step.SubGraph == nil {
+			continue
+		}
+		entity, exists := step.SubGraph.GetEntity(step.ParentType)
+		if !exists {
+			continue
+		}
+
+		var pathTypes []string
+		for j, sel := range step.SelectionSet {
+			field, ok := sel.(*ast.Field)
+			if !ok {
+				continue
+			}
+			fieldMetadata, ok := entity.Fields[field.Name.String()]
+			if !ok || len(fieldMetadata.ContextArguments) == 0 {
+				continue
+			}
+			if pathTypes == nil {
+				pathTypes = p.insertionPathTypes(rootTypeName, selections, step)
+			}
+
+			contextual := copyField(field)
+			contextual.Arguments = append([]*ast.Argument{}, field.Arguments...)
+			contextual.SelectionSet = field.SelectionSet
+			for _, arg := range fieldMetadata.ContextArguments {
+				depth := -1
+				for k := len(pathTypes) - 1; k >= 0; k-- {
+					if p.SuperGraph.SetsContext(pathTypes[k], arg.Context) {
+						depth = k
+						break
+					}
+				}
+				if depth < 0 {
+					continue
+				}
+
+				selection := parseFieldSet(arg.Selection.String())
+				if !p.fetchContext(plan, step, pathTypes[depth], step.InsertionPath[:depth+1], selection, contextSteps) {
+					continue
+				}
+				variable := fmt.Sprintf("%s%d", contextVariablePrefix, variables)
+				variables++
+				contextual.Arguments = append(contextual.Arguments, &ast.Argument{
+					Name:  newName(arg.Name),
+					Value: &ast.Variable{Name: variable},
+				})
+				step.Contexts = append(step.Contexts, ContextValue{Variable: variable, Depth: depth, Selection: selection})
+			}
+			step.SelectionSet[j] = contextual
+		}
+	}
+}
+
+// insertionPathTypes returns the type of the objects at each prefix of the insertion
+// path of step, found by following the path through selections, the expanded selections
+// of the operation. The last type is the entity type of step. It returns nil when the
+// path does not start at the root type.
+func (p *PlannerV2) insertionPathTypes(rootTypeName string, selections []ast.Selection, step *StepV2) []string {
+	path := step.InsertionPath
+	if len(path) == 0 || path[0] != rootTypeName {
+		return nil
+	}
+	types := make([]string, len(path))
+	types[0] = rootTypeName
+	for k := 1; k < len(path); k++ {
+		field, fieldParentType := findFieldByResponseKey(selections, types[k-1], path[k])
+		if field == nil {
+			return nil
+		}
+		fieldType, err := p.getFieldTypeName(fieldParentType, field.Name.String())
+		if err != nil {
+			return nil
+		}
+		types[k] = fieldType
+		selections = field.SelectionSet
+	}
+	types[len(types)-1] = step.ParentType
+	return types
+}
+
+// fetchContext makes the fields of selection available on the typeName objects at
+// ancestorPath before step executes, and reports whether it could.
+func (p *PlannerV2) fetchContext(plan *PlanV2, step *StepV2, typeName string, ancestorPath []string, selection []ast.Selection, contextSteps map[string]*StepV2) bool {
+	provider := contextProvider(plan, step, ancestorPath)
+	if provider == nil {
+		return false
+	}
+	relativePath := ancestorPath[len(provider.InsertionPath):]
+	if len(provider.InsertionPath) == 0 {
+		relativePath = ancestorPath[1:]
+	}
+
+	for _, sel := range selection {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+		if provider.SubGraph != nil && p.canResolveField(typeName, field.Name.String(), provider.SubGraph) {
+			if selections, ok := injectAtPath(provider.SelectionSet, relativePath, []ast.Selection{field}); ok {
+				provider.SelectionSet = selections
+				continue
+			}
+		}
+
+		owner := p.resolvingSubGraph(typeName, field.Name.String(), nil, nil)
+		if owner == nil {
+			return false
+		}
+		keyFields := p.getKeyFields(typeName, owner)
+		if len(keyFields) == 0 {
+			return false
+		}
+		key := fmt.Sprintf("%s:%s:%d:%s", owner.Name, typeName, provider.ID, strings.Join(ancestorPath, "."))
+		contextStep, exists := contextSteps[key]
+		if !exists {
+			contextStep = &StepV2{
+				ID:            len(plan.Steps),
+				SubGraph:      owner,
+				StepType:      StepTypeEntity,
+				ParentType:    typeName,
+				SelectionSet:  fieldSelections(keyFields),
+				Path:          append([]string{}, ancestorPath...),
+				DependsOn:     []int{provider.ID},
+				InsertionPath: append([]string{}, ancestorPath...),
+			}
+			plan.Steps = append(plan.Steps, contextStep)
+			contextSteps[key] = contextStep
+			p.injectKeyFieldsIntoParentStep(provider, typeName, owner, relativePath)
+		}
+		contextStep.SelectionSet = mergeFusedSelections(contextStep.SelectionSet, []ast.Selection{field})
+		if !containsInt(step.DependsOn, contextStep.ID) {
+			step.DependsOn = append(step.DependsOn, contextStep.ID)
+		}
+	}
+	return true
+}
+
+// contextProvider returns the nearest step step depends on, through its first
+// dependencies, whose results hold the objects at ancestorPath.
+func contextProvider(plan *PlanV2, step *StepV2, ancestorPath []string) *StepV2 {
+	current := step
+	for i := 0; i < len(plan.Steps) && len(current.DependsOn) > 0; i++ {
+		current = plan.Steps[current.DependsOn[0]]
+		if len(current.InsertionPath) <= len(ancestorPath) &&
+			strings.Join(current.InsertionPath, ".") == strings.Join(ancestorPath[:len(current.InsertionPath)], ".") {
+			return current
+		}
+	}
+	return nil
+}
+
+// injectAtPath merges injected into the selections of the field at path, following
+// response keys through selections and inline fragments. It reports false when path
+// is not selected.
+func injectAtPath(selections []ast.Selection, path []string, injected []ast.Selection) ([]ast.Selection, bool) {
+	if len(path) == 0 {
+		return mergeFusedSelections(selections, injected), true
+	}
+	field, _ := findFieldByResponseKey(selections, "", path[0])
+	if field == nil {
+		return selections, false
+	}
+	children, ok := injectAtPath(field.SelectionSet, path[1:], injected)
+	if ok {
+		field.SelectionSet = children
+	}
+	return selections, ok
+}
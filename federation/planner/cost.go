@@ -0,0 +1,159 @@
+package planner
+
+import (
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// CostModel estimates the wall-clock cost of resolving a selection from a subgraph.
+// Every request to a subgraph costs its latency weight; a field that has to be fetched
+// from another subgraph adds an entity request to that subgraph, scaled by the estimated
+// number of entities in the batch (each enclosing list field multiplies it).
+type CostModel struct {
+	SubGraphLatency  map[string]float64 // Subgraph name → relative latency weight (default 1)
+	ListSizeEstimate float64            // Estimated number of items in a list field (default 1)
+}
+
+// latency returns the latency weight of subGraph.
+func (c *CostModel) latency(subGraph *graph.SubGraphV2) float64 {
+	if w, ok := c.SubGraphLatency[subGraph.Name]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+// listSize returns the estimated number of items per list field.
+func (c *CostModel) listSize() float64 {
+	if c.ListSizeEstimate > 1 {
+		return c.ListSizeEstimate
+	}
+	return 1
+}
+
+// EstimateCost estimates the cost of executing plan: the latency weight of the subgraph
+// of every step times the estimated number of objects the step is sent. Without a cost
+// model every weight and list size is 1, so the cost is the number of steps.
+func (p *PlannerV2) EstimateCost(plan *PlanV2) float64 {
+	model := p.CostModel
+	if model == nil {
+		model = &CostModel{}
+	}
+
+	cost := 0.0
+	for _, step := range plan.Steps {
+		cost += model.latency(step.SubGraph) * p.stepBatchSize(step, model)
+	}
+	return cost
+}
+
+// stepBatchSize estimates the number of objects step is sent, at all its insertion paths.
+func (p *PlannerV2) stepBatchSize(step *StepV2, model *CostModel) float64 {
+	size := 0.0
+	for _, path := range step.InsertionPaths() {
+		size += p.batchSize(path, model)
+	}
+	return size
+}
+
+// batchSize estimates the number of objects at path, a root type name followed by
+// field names, by multiplying the list size of every list field on it.
+func (p *PlannerV2) batchSize(path []string, model *CostModel) float64 {
+	size := 1.0
+	if len(path) == 0 {
+		return size
+	}
+	typeName := path[0]
+	for _, fieldName := range path[1:] {
+		if p.isListField(typeName, fieldName) {
+			size *= model.listSize()
+		}
+		next, err := p.getFieldTypeName(typeName, fieldName)
+		if err != nil {
+			break
+		}
+		typeName = next
+	}
+	return size
+}
+
+// chooseRootSubGraph picks the subgraph resolving a root field. A subgraph hinted with
+// @gatewayHint always wins. Without a cost model, or when only one subgraph can resolve
+// the field, the first owner is used; otherwise the owner with the lowest estimated cost
+// wins, keeping ownership order on ties.
+func (p *PlannerV2) chooseRootSubGraph(rootTypeName string, field *ast.Field, subGraphs []*graph.SubGraphV2, fragmentDefs map[string]*ast.FragmentDefinition) *graph.SubGraphV2 {
+	if hinted := hintedSubGraph(field, subGraphs); hinted != nil {
+		return hinted
+	}
+	if p.CostModel == nil || len(subGraphs) == 1 {
+		return subGraphs[0]
+	}
+
+	selections := []ast.Selection{field}
+	best := subGraphs[0]
+	bestCost := -1.0
+	for _, candidate := range subGraphs {
+		cost := p.CostModel.latency(candidate) + p.selectionCost(selections, rootTypeName, candidate, 1, fragmentDefs)
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = candidate, cost
+		}
+	}
+	return best
+}
+
+// selectionCost estimates the cost of the entity fetches needed to resolve selections of
+// parentType when they are reached from a step on subGraph, where multiplier is the
+// estimated number of parent objects in the batch.
+func (p *PlannerV2) selectionCost(selections []ast.Selection, parentType string, subGraph *graph.SubGraphV2, multiplier float64, fragmentDefs map[string]*ast.FragmentDefinition) float64 {
+	cost := 0.0
+	for _, sel := range p.expandFragmentsInSelections(selections, parentType, fragmentDefs) {
+		if fragment, ok := sel.(*ast.InlineFragment); ok {
+			cost += p.selectionCost(fragment.SelectionSet, fragment.TypeCondition.Name.String(), subGraph, multiplier, fragmentDefs)
+			continue
+		}
+		field, ok := sel.(*ast.Field)
+		if !ok || field.Name.String() == "__typename" {
+			continue
+		}
+		fieldName := field.Name.String()
+		fieldType, err := p.getFieldTypeName(parentType, fieldName)
+		if err != nil {
+			continue
+		}
+
+		childMultiplier := multiplier
+		if p.isListField(parentType, fieldName) {
+			childMultiplier *= p.CostModel.listSize()
+		}
+
+		// Mirror the planner: a field owned elsewhere, or one returning an entity owned
+		// elsewhere, is resolved by an entity fetch to that owner.
+		owner := subGraph
+		if resolver := p.resolvingSubGraph(parentType, fieldName, subGraph, nil); resolver != nil && resolver.Name != subGraph.Name {
+			owner = resolver
+		} else if entityOwner := p.SuperGraph.GetEntityOwnerSubGraph(fieldType); entityOwner != nil && entityOwner.Name != subGraph.Name {
+			owner = entityOwner
+		}
+		if owner.Name != subGraph.Name {
+			cost += p.CostModel.latency(owner) * childMultiplier
+		}
+
+		if len(field.SelectionSet) > 0 {
+			cost += p.selectionCost(field.SelectionSet, fieldType, owner, childMultiplier, fragmentDefs)
+		}
+	}
+	return cost
+}
+
+// isListField reports whether typeName.fieldName returns a list.
+func (p *PlannerV2) isListField(typeName, fieldName string) bool {
+	field := p.SuperGraph.FieldDefinition(typeName, fieldName)
+	if field == nil {
+		return false
+	}
+	t := field.Type
+	if nonNull, ok := t.(*ast.NonNullType); ok {
+		t = nonNull.Type
+	}
+	_, isList := t.(*ast.ListType)
+	return isList
+}
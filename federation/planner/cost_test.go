@@ -0,0 +1,167 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+func newCostTestPlanner(t *testing.T) *planner.PlannerV2 {
+	t.Helper()
+
+	reviewsSchema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviewCount: Int!
+		}
+
+		type Query {
+			topProducts: [Product!]! @shareable
+			serverTime: String! @shareable
+		}
+	`
+	productsSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			topProducts: [Product!]! @shareable
+			serverTime: String! @shareable
+		}
+	`
+
+	reviews, err := graph.NewSubGraphV2("reviews", []byte(reviewsSchema), "http://reviews.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	products, err := graph.NewSubGraphV2("products", []byte(productsSchema), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{reviews, products})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	return planner.NewPlannerV2(superGraph)
+}
+
+func planQuery(t *testing.T, p *planner.PlannerV2, query string) *planner.PlanV2 {
+	t.Helper()
+
+	ps := parser.New(lexer.New(query))
+	doc := ps.ParseDocument()
+	if len(ps.Errors()) > 0 {
+		t.Fatalf("parse error: %v", ps.Errors())
+	}
+
+	plan, err := p.Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	return plan
+}
+
+func TestPlannerV2_CostModel(t *testing.T) {
+	tests := []struct {
+		name         string
+		costModel    *planner.CostModel
+		query        string
+		wantSubGraph string
+		wantSteps    int
+	}{
+		{
+			name:         "without cost model the first owner resolves the root field",
+			query:        `query { topProducts { id name } }`,
+			wantSubGraph: "reviews",
+			wantSteps:    2,
+		},
+		{
+			name:         "entity owner resolves the root field without an entity fetch",
+			costModel:    &planner.CostModel{},
+			query:        `query { topProducts { id name } }`,
+			wantSubGraph: "products",
+			wantSteps:    1,
+		},
+		{
+			name:         "equal cost keeps ownership order",
+			costModel:    &planner.CostModel{},
+			query:        `query { serverTime }`,
+			wantSubGraph: "reviews",
+			wantSteps:    1,
+		},
+		{
+			name: "latency weight picks the faster subgraph",
+			costModel: &planner.CostModel{
+				SubGraphLatency: map[string]float64{"reviews": 3},
+			},
+			query:        `query { serverTime }`,
+			wantSubGraph: "products",
+			wantSteps:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newCostTestPlanner(t)
+			p.CostModel = tt.costModel
+
+			plan := planQuery(t, p, tt.query)
+
+			if len(plan.Steps) != tt.wantSteps {
+				t.Fatalf("expected %d steps, got %d", tt.wantSteps, len(plan.Steps))
+			}
+			if got := plan.Steps[0].SubGraph.Name; got != tt.wantSubGraph {
+				t.Errorf("expected root step on %s, got %s", tt.wantSubGraph, got)
+			}
+		})
+	}
+}
+
+func TestPlannerV2_EstimateCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		costModel *planner.CostModel
+		query     string
+		want      float64
+	}{
+		{
+			name:  "without cost model every step costs 1",
+			query: `query { topProducts { id name } }`,
+			want:  2,
+		},
+		{
+			name: "entity fetches are multiplied by the list size",
+			costModel: &planner.CostModel{
+				SubGraphLatency:  map[string]float64{"reviews": 2},
+				ListSizeEstimate: 10,
+			},
+			query: `query { topProducts { name reviewCount } }`,
+			// products root step (1) + reviewCount of 10 products on reviews (2 * 10)
+			want: 21,
+		},
+		{
+			name:      "single step",
+			costModel: &planner.CostModel{SubGraphLatency: map[string]float64{"reviews": 3}},
+			query:     `query { serverTime }`,
+			want:      1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newCostTestPlanner(t)
+			p.CostModel = tt.costModel
+
+			plan := planQuery(t, p, tt.query)
+			if got := p.EstimateCost(plan); got != tt.want {
+				t.Errorf("expected cost %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
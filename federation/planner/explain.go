@@ -0,0 +1,125 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+)
+
+// PlanExplanation is the cost analysis of a plan, like the output of SQL EXPLAIN. Costs
+// are estimated with the planner's cost model; see EstimateCost.
+type PlanExplanation struct {
+	Cost             float64           `json:"cost"`             // Estimated cost of all steps
+	CriticalPathCost float64           `json:"criticalPathCost"` // Highest distance of any step
+	SerialDepth      int               `json:"serialDepth"`      // Steps on the longest chain of dependencies
+	Steps            []StepExplanation `json:"steps"`
+}
+
+// StepExplanation is the estimated cost of one step of a plan.
+type StepExplanation struct {
+	ID            int      `json:"id"`
+	Kind          string   `json:"kind"` // query, mutation, entity or representations
+	SubGraph      string   `json:"subGraph,omitempty"`
+	ParentType    string   `json:"parentType"`
+	SelectionSet  string   `json:"selectionSet"`
+	DependsOn     []int    `json:"dependsOn"`
+	InsertionPath []string `json:"insertionPath"`
+	// BatchedPaths are the further insertion paths of the objects sent in the request.
+	BatchedPaths [][]string `json:"batchedPaths,omitempty"`
+	// Latency is the latency weight of the subgraph.
+	Latency float64 `json:"latency"`
+	// BatchSize is the estimated number of objects the step is sent, the product of
+	// the list size estimates of the list fields on its insertion path, summed over its
+	// batched paths.
+	BatchSize float64 `json:"batchSize"`
+	// Cost is Latency times BatchSize.
+	Cost float64 `json:"cost"`
+	// Distance is the cost of the most expensive chain of dependencies ending with the
+	// step, i.e. the estimated time until its results are available.
+	Distance float64 `json:"distance"`
+	// Depth is the number of steps on the longest chain of dependencies ending with the
+	// step; steps of the same depth may run concurrently.
+	Depth int `json:"depth"`
+}
+
+// Explain estimates the cost of every step of plan and of the plan as a whole.
+func (p *PlannerV2) Explain(plan *PlanV2) *PlanExplanation {
+	model := p.CostModel
+	if model == nil {
+		model = &CostModel{}
+	}
+
+	steps := make(map[int]*StepV2, len(plan.Steps))
+	for _, step := range plan.Steps {
+		steps[step.ID] = step
+	}
+
+	explanation := &PlanExplanation{Steps: make([]StepExplanation, 0, len(plan.Steps))}
+	distances := make(map[int]float64, len(plan.Steps))
+	depths := make(map[int]int, len(plan.Steps))
+	var visit func(step *StepV2)
+	visit = func(step *StepV2) {
+		if _, ok := depths[step.ID]; ok {
+			return
+		}
+		// Mark the step before visiting its dependencies so a cycle cannot recurse.
+		depths[step.ID] = 1
+		distance, depth := 0.0, 0
+		for _, depID := range step.DependsOn {
+			dep, ok := steps[depID]
+			if !ok {
+				continue
+			}
+			visit(dep)
+			distance = max(distance, distances[depID])
+			depth = max(depth, depths[depID])
+		}
+		distances[step.ID] = distance + model.latency(step.SubGraph)*p.stepBatchSize(step, model)
+		depths[step.ID] = depth + 1
+	}
+
+	for _, step := range plan.Steps {
+		visit(step)
+		latency := model.latency(step.SubGraph)
+		batchSize := p.stepBatchSize(step, model)
+		cost := latency * batchSize
+		explanation.Steps = append(explanation.Steps, StepExplanation{
+			ID:            step.ID,
+			Kind:          stepKind(plan, step),
+			SubGraph:      stepSubGraphName(step),
+			ParentType:    step.ParentType,
+			SelectionSet:  selectionsString(step.SelectionSet),
+			DependsOn:     step.DependsOn,
+			InsertionPath: step.InsertionPath,
+			BatchedPaths:  step.BatchedPaths,
+			Latency:       latency,
+			BatchSize:     batchSize,
+			Cost:          cost,
+			Distance:      distances[step.ID],
+			Depth:         depths[step.ID],
+		})
+		explanation.Cost += cost
+		explanation.CriticalPathCost = max(explanation.CriticalPathCost, distances[step.ID])
+		explanation.SerialDepth = max(explanation.SerialDepth, depths[step.ID])
+	}
+	return explanation
+}
+
+// VisualizeWithCosts is plan.Visualize with the estimated batch size, cost, distance and
+// depth of every step added to its node.
+func (p *PlannerV2) VisualizeWithCosts(plan *PlanV2) *graph.Visualization {
+	v := plan.Visualize()
+	explanation := p.Explain(plan)
+	for i, step := range explanation.Steps {
+		v.Nodes[i].Label += "\n" + fmt.Sprintf("cost %s × %s = %s, distance %s, depth %d",
+			formatCost(step.Latency), formatCost(step.BatchSize), formatCost(step.Cost), formatCost(step.Distance), step.Depth)
+	}
+	return v
+}
+
+// formatCost formats an estimate without trailing zeros.
+func formatCost(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,45 @@
+package planner_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+func TestPlannerV2_Explain(t *testing.T) {
+	p := newCostTestPlanner(t)
+	p.CostModel = &planner.CostModel{
+		SubGraphLatency:  map[string]float64{"reviews": 3},
+		ListSizeEstimate: 10,
+	}
+	plan := planQuery(t, p, `query { topProducts { name reviewCount } serverTime }`)
+
+	explanation := p.Explain(plan)
+	if explanation.Cost != p.EstimateCost(plan) {
+		t.Errorf("expected the cost of EstimateCost %v, got %v", p.EstimateCost(plan), explanation.Cost)
+	}
+	if explanation.CriticalPathCost != 31 || explanation.SerialDepth != 2 {
+		t.Errorf("expected critical path cost 31 and serial depth 2, got %v and %d", explanation.CriticalPathCost, explanation.SerialDepth)
+	}
+	if len(explanation.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %+v", explanation.Steps)
+	}
+
+	root, entity := explanation.Steps[0], explanation.Steps[1]
+	if root.Kind != "query" || root.SubGraph != "products" || root.Cost != 1 || root.Distance != 1 || root.Depth != 1 {
+		t.Errorf("unexpected root step %+v", root)
+	}
+	if entity.Kind != "entity" || entity.SubGraph != "reviews" || entity.Latency != 3 || entity.BatchSize != 10 ||
+		entity.Cost != 30 || entity.Distance != 31 || entity.Depth != 2 {
+		t.Errorf("unexpected entity step %+v", entity)
+	}
+	if !strings.Contains(entity.SelectionSet, "reviewCount") {
+		t.Errorf("expected the selection set of the step, got %s", entity.SelectionSet)
+	}
+
+	v := p.VisualizeWithCosts(plan)
+	if label := v.Nodes[1].Label; !strings.Contains(label, "cost 3 × 10 = 30, distance 31, depth 2") {
+		t.Errorf("expected the costs in the node label, got %q", label)
+	}
+}
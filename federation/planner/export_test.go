@@ -0,0 +1,7 @@
+package planner
+
+// NewFieldNode exposes newField for allocation tests.
+var NewFieldNode = newField
+
+// ContainsAllTypes exposes containsAllTypes, deciding whether a node field is fanned out.
+var ContainsAllTypes = containsAllTypes
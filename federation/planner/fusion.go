@@ -0,0 +1,181 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// fuseEntitySteps merges sibling entity steps that would send the same representations
+// to the same subgraph: steps with the same subgraph, entity type, dependencies and
+// insertion path become a single _entities request with a merged selection set.
+func fuseEntitySteps(plan *PlanV2) {
+	mergeSteps(plan, func(step *StepV2) (string, bool) {
+		return fusionKey(step), true
+	}, func(target, step *StepV2) {
+		target.SelectionSet = mergeFusedSelections(target.SelectionSet, step.SelectionSet)
+		target.Path = commonPathPrefix(target.Path, step.Path)
+		target.Contexts = append(target.Contexts, step.Contexts...)
+	})
+}
+
+// batchEntitySteps merges sibling entity steps sending the same request for objects at
+// different insertion paths, such as the nodes of several pages of a connection or of
+// aliased copies of a field, so that their representations are sent in one _entities
+// request. The paths of the merged steps are kept as BatchedPaths of the first one.
+// Steps with @fromContext arguments are not batched, as their arguments are set from
+// the ancestors on their own path.
+func batchEntitySteps(plan *PlanV2) {
+	mergeSteps(plan, func(step *StepV2) (string, bool) {
+		if len(step.Contexts) > 0 {
+			return "", false
+		}
+		return batchKey(step), true
+	}, func(target, step *StepV2) {
+		target.BatchedPaths = append(target.BatchedPaths, step.InsertionPaths()...)
+		target.Path = commonPathPrefix(target.Path, step.Path)
+	})
+}
+
+// mergeSteps merges each entity step into the first earlier one with the same key,
+// as returned by key, with merge. Steps for which key reports false are kept as they
+// are. Steps are renumbered afterwards so that a step's ID stays its index in plan.Steps.
+func mergeSteps(plan *PlanV2, key func(step *StepV2) (string, bool), merge func(target, step *StepV2)) {
+	remap := make(map[int]int, len(plan.Steps))
+	merged := make(map[string]*StepV2)
+	steps := make([]*StepV2, 0, len(plan.Steps))
+
+	// Steps are created parents first, so dependencies are remapped before a step is
+	// compared with its siblings; children of merged steps can then merge in turn.
+	for _, step := range plan.Steps {
+		step.DependsOn = remapDependencies(step.DependsOn, remap)
+
+		if step.StepType == StepTypeEntity {
+			if k, ok := key(step); ok {
+				if target, exists := merged[k]; exists {
+					merge(target, step)
+					remap[step.ID] = target.ID
+					continue
+				}
+				merged[k] = step
+			}
+		}
+
+		remap[step.ID] = step.ID
+		steps = append(steps, step)
+	}
+
+	if len(steps) == len(plan.Steps) {
+		return
+	}
+
+	// Requires steps are appended after the steps depending on them, so resolve
+	// dependencies on steps merged later in the loop.
+	for _, step := range steps {
+		step.DependsOn = remapDependencies(step.DependsOn, remap)
+	}
+
+	// Renumber the remaining steps.
+	ids := make(map[int]int, len(steps))
+	for i, step := range steps {
+		ids[step.ID] = i
+	}
+	for id, target := range remap {
+		remap[id] = ids[target]
+	}
+	for i, step := range steps {
+		step.ID = i
+		step.DependsOn = remapDependencies(step.DependsOn, ids)
+	}
+	for i, idx := range plan.RootStepIndexes {
+		plan.RootStepIndexes[i] = remap[idx]
+	}
+	plan.Steps = steps
+}
+
+// fusionKey identifies the _entities request a step sends.
+func fusionKey(step *StepV2) string {
+	deps := append([]int{}, step.DependsOn...)
+	sort.Ints(deps)
+	return fmt.Sprintf("%s:%s:%v:%s", stepSubGraphName(step), step.ParentType, deps, strings.Join(step.InsertionPath, "."))
+}
+
+// batchKey identifies the _entities request a step sends, whatever the objects it is
+// sent for: steps with the same subgraph, entity type, dependencies, selections and
+// required fields only differ by their insertion paths.
+func batchKey(step *StepV2) string {
+	deps := append([]int{}, step.DependsOn...)
+	sort.Ints(deps)
+	return fmt.Sprintf("%s:%s:%v:%s:%s", stepSubGraphName(step), step.ParentType, deps, selectionsString(step.SelectionSet), selectionsString(step.Requires))
+}
+
+// remapDependencies rewrites step IDs through remap, dropping duplicates.
+func remapDependencies(deps []int, remap map[int]int) []int {
+	result := make([]int, 0, len(deps))
+	seen := make(map[int]bool, len(deps))
+	for _, dep := range deps {
+		if id, ok := remap[dep]; ok {
+			dep = id
+		}
+		if !seen[dep] {
+			seen[dep] = true
+			result = append(result, dep)
+		}
+	}
+	return result
+}
+
+// mergeFusedSelections appends additional to existing. Fields with the same response
+// key are merged, recursing into their selection sets; other selections are appended.
+func mergeFusedSelections(existing, additional []ast.Selection) []ast.Selection {
+	merged := append([]ast.Selection{}, existing...)
+	for _, sel := range additional {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			merged = append(merged, sel)
+			continue
+		}
+
+		var match *ast.Field
+		for _, m := range merged {
+			if f, ok := m.(*ast.Field); ok && responseKey(f) == responseKey(field) {
+				match = f
+				break
+			}
+		}
+		if match == nil {
+			merged = append(merged, field)
+			continue
+		}
+		if len(field.SelectionSet) > 0 {
+			// Copy so that selections shared with the original document are not modified.
+			copied := *match
+			copied.SelectionSet = mergeFusedSelections(match.SelectionSet, field.SelectionSet)
+			for i, m := range merged {
+				if m == ast.Selection(match) {
+					merged[i] = &copied
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// responseKey returns the alias of field, or its name when it has none.
+func responseKey(field *ast.Field) string {
+	if field.Alias != nil && field.Alias.String() != "" {
+		return field.Alias.String()
+	}
+	return field.Name.String()
+}
+
+// commonPathPrefix returns the longest common prefix of a and b.
+func commonPathPrefix(a, b []string) []string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return append([]string{}, a[:n]...)
+}
@@ -0,0 +1,127 @@
+package planner_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+func newFusionTestPlanner(t *testing.T) *planner.PlannerV2 {
+	t.Helper()
+
+	productsSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+	reviewsSchema := `
+		type Review {
+			body: String!
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review!]!
+			rating: Float
+		}
+	`
+
+	products, err := graph.NewSubGraphV2("products", []byte(productsSchema), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	reviews, err := graph.NewSubGraphV2("reviews", []byte(reviewsSchema), "http://reviews.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{products, reviews})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	return planner.NewPlannerV2(superGraph)
+}
+
+func TestPlannerV2_FuseSiblingEntitySteps(t *testing.T) {
+	p := newFusionTestPlanner(t)
+	plan := planQuery(t, p, `query { product(id: "1") { name reviews { body } rating } }`)
+
+	if len(plan.Steps) != 2 {
+		t.Fatalf("expected sibling entity steps to be fused into 2 steps, got %d", len(plan.Steps))
+	}
+
+	for i, step := range plan.Steps {
+		if step.ID != i {
+			t.Errorf("expected step %d to have ID %d, got %d", i, i, step.ID)
+		}
+	}
+
+	entityStep := plan.Steps[1]
+	if entityStep.StepType != planner.StepTypeEntity || entityStep.SubGraph.Name != "reviews" {
+		t.Fatalf("expected an entity step on reviews, got %v on %s", entityStep.StepType, entityStep.SubGraph.Name)
+	}
+	if len(entityStep.DependsOn) != 1 || entityStep.DependsOn[0] != 0 {
+		t.Errorf("expected fused step to depend on step 0, got %v", entityStep.DependsOn)
+	}
+
+	fields := make(map[string]int)
+	for _, sel := range entityStep.SelectionSet {
+		if f, ok := sel.(*ast.Field); ok {
+			fields[f.Name.String()]++
+		}
+	}
+	for _, name := range []string{"__typename", "id", "reviews", "rating"} {
+		if fields[name] != 1 {
+			t.Errorf("expected fused selection to contain %s once, got %d", name, fields[name])
+		}
+	}
+}
+
+func TestPlannerV2_FusionKeepsDistinctInsertionPaths(t *testing.T) {
+	p := newFusionTestPlanner(t)
+	plan := planQuery(t, p, `query { a: product(id: "1") { rating } b: product(id: "2") { reviews { body } } }`)
+
+	entitySteps := 0
+	for _, step := range plan.Steps {
+		if step.StepType == planner.StepTypeEntity {
+			entitySteps++
+		}
+	}
+	if entitySteps != 2 {
+		t.Errorf("expected entity steps for different insertion paths to stay separate, got %d", entitySteps)
+	}
+}
+
+func TestPlannerV2_BatchSameRequestAtDistinctInsertionPaths(t *testing.T) {
+	p := newFusionTestPlanner(t)
+	plan := planQuery(t, p, `query { a: product(id: "1") { rating } b: product(id: "2") { rating } }`)
+
+	var entitySteps []*planner.StepV2
+	for _, step := range plan.Steps {
+		if step.StepType == planner.StepTypeEntity {
+			entitySteps = append(entitySteps, step)
+		}
+	}
+	if len(entitySteps) != 1 {
+		t.Fatalf("expected the entity steps to be batched into one, got %d", len(entitySteps))
+	}
+
+	var paths []string
+	for _, path := range entitySteps[0].InsertionPaths() {
+		paths = append(paths, strings.Join(path, "."))
+	}
+	if len(paths) != 2 || paths[0] != "Query.a" || paths[1] != "Query.b" {
+		t.Errorf("expected the batched step to be inserted at Query.a and Query.b, got %v", paths)
+	}
+	if len(plan.Steps) != 2 || entitySteps[0].ID != 1 || len(entitySteps[0].DependsOn) != 1 || entitySteps[0].DependsOn[0] != 0 {
+		t.Errorf("expected the batched step to be step 1 depending on step 0, got %+v", entitySteps[0])
+	}
+}
@@ -0,0 +1,162 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// HintDirective is the client directive giving query plan hints on a field, e.g.
+//
+//	inStock @gatewayHint(subgraph: "inventory", cache: false, timeout: "200ms")
+//
+// subgraph prefers a subgraph among those resolving the field, cache: false bypasses
+// the entity cache for the steps resolving it, and timeout bounds their subgraph
+// requests. The directive is never sent to subgraphs.
+const HintDirective = "gatewayHint"
+
+// fieldHint is a parsed @gatewayHint directive.
+type fieldHint struct {
+	subGraph string
+	noCache  bool
+	timeout  time.Duration
+}
+
+// parseHint reads the @gatewayHint directive in directives. Its arguments must be
+// literals, so that a plan does not depend on variable values.
+func parseHint(directives []*ast.Directive) (fieldHint, bool, error) {
+	for _, d := range directives {
+		if d.Name != HintDirective {
+			continue
+		}
+		var hint fieldHint
+		for _, arg := range d.Arguments {
+			name := arg.Name.String()
+			switch value := arg.Value.(type) {
+			case *ast.StringValue:
+				switch name {
+				case "subgraph":
+					hint.subGraph = value.Value
+					continue
+				case "timeout":
+					timeout, err := time.ParseDuration(value.Value)
+					if err != nil || timeout <= 0 {
+						return fieldHint{}, false, fmt.Errorf("@%s(timeout: %q) is not a positive duration", HintDirective, value.Value)
+					}
+					hint.timeout = timeout
+					continue
+				}
+			case *ast.BooleanValue:
+				if name == "cache" {
+					hint.noCache = !value.Value
+					continue
+				}
+			}
+			return fieldHint{}, false, fmt.Errorf("invalid @%s argument %s: expected subgraph: String, cache: Boolean or timeout: String literals", HintDirective, name)
+		}
+		return hint, true, nil
+	}
+	return fieldHint{}, false, nil
+}
+
+// validateHints checks the @gatewayHint directives of doc, so that planning can read
+// them without failing.
+func (p *PlannerV2) validateHints(doc *ast.Document) error {
+	var walk func(selections []ast.Selection) error
+	walk = func(selections []ast.Selection) error {
+		for _, sel := range selections {
+			switch s := sel.(type) {
+			case *ast.Field:
+				hint, ok, err := parseHint(s.Directives)
+				if err != nil {
+					return err
+				}
+				if ok && hint.subGraph != "" && p.subGraphByName(hint.subGraph) == nil {
+					return fmt.Errorf("@%s(subgraph: %q) on %s names an unknown subgraph", HintDirective, hint.subGraph, s.Name.String())
+				}
+				if err := walk(s.SelectionSet); err != nil {
+					return err
+				}
+			case *ast.InlineFragment:
+				if err := walk(s.SelectionSet); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.OperationDefinition:
+			if err := walk(d.SelectionSet); err != nil {
+				return err
+			}
+		case *ast.FragmentDefinition:
+			if err := walk(d.SelectionSet); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// subGraphByName returns the subgraph named name, or nil.
+func (p *PlannerV2) subGraphByName(name string) *graph.SubGraphV2 {
+	for _, subGraph := range p.SuperGraph.SubGraphs {
+		if subGraph.Name == name {
+			return subGraph
+		}
+	}
+	return nil
+}
+
+// hintedSubGraph returns the subgraph a @gatewayHint on field prefers among owners, or
+// nil when there is no hint or the hinted subgraph does not resolve the field.
+func hintedSubGraph(field *ast.Field, owners []*graph.SubGraphV2) *graph.SubGraphV2 {
+	hint, ok, _ := parseHint(field.Directives)
+	if !ok || hint.subGraph == "" {
+		return nil
+	}
+	for _, owner := range owners {
+		if owner.Name == hint.subGraph {
+			return owner
+		}
+	}
+	return nil
+}
+
+// resolvingFieldSubGraph is resolvingSubGraph honoring the subgraph hinted on field.
+func (p *PlannerV2) resolvingFieldSubGraph(typeName string, field *ast.Field, stepSubGraph *graph.SubGraphV2, involved map[string]bool) *graph.SubGraphV2 {
+	if owner := hintedSubGraph(field, p.SuperGraph.GetSubGraphsForField(typeName, field.Name.String())); owner != nil {
+		return owner
+	}
+	return p.resolvingSubGraph(typeName, field.Name.String(), stepSubGraph, involved)
+}
+
+// applyHints sets the entity cache bypass and the timeout of every step from the
+// @gatewayHint directives of the fields it resolves; the shortest timeout wins.
+func applyHints(plan *PlanV2) {
+	var walk func(step *StepV2, selections []ast.Selection)
+	walk = func(step *StepV2, selections []ast.Selection) {
+		for _, sel := range selections {
+			switch s := sel.(type) {
+			case *ast.Field:
+				if hint, ok, _ := parseHint(s.Directives); ok {
+					step.BypassCache = step.BypassCache || hint.noCache
+					if hint.timeout > 0 && (step.Timeout == 0 || hint.timeout < step.Timeout) {
+						step.Timeout = hint.timeout
+					}
+				}
+				walk(step, s.SelectionSet)
+			case *ast.InlineFragment:
+				walk(step, s.SelectionSet)
+			}
+		}
+	}
+	for _, step := range plan.Steps {
+		walk(step, step.SelectionSet)
+	}
+}
@@ -0,0 +1,79 @@
+package planner_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+func TestPlannerV2_GatewayHint(t *testing.T) {
+	stepSubGraphs := func(plan *planner.PlanV2) []string {
+		var names []string
+		for _, step := range plan.Steps {
+			names = append(names, step.SubGraph.Name)
+		}
+		return names
+	}
+
+	t.Run("subgraph hint overrides the local owner", func(t *testing.T) {
+		p := newOwnerTestPlanner(t)
+		plan := planQuery(t, p, `query { topProducts { name weight @gatewayHint(subgraph: "inventory") } }`)
+
+		if got := strings.Join(stepSubGraphs(plan), ","); got != "products,inventory" {
+			t.Fatalf("expected steps on products,inventory, got %s", got)
+		}
+		topProducts := plan.Steps[0].SelectionSet[0].(*ast.Field)
+		for _, sel := range topProducts.SelectionSet {
+			if field, ok := sel.(*ast.Field); ok && field.Name.String() == "weight" {
+				t.Errorf("expected products not to resolve weight")
+			}
+		}
+	})
+
+	t.Run("subgraph hint not resolving the field is ignored", func(t *testing.T) {
+		p := newOwnerTestPlanner(t)
+		plan := planQuery(t, p, `query { topProducts { name @gatewayHint(subgraph: "inventory") } }`)
+
+		if got := strings.Join(stepSubGraphs(plan), ","); got != "products" {
+			t.Errorf("expected a single products step, got %s", got)
+		}
+	})
+
+	t.Run("cache and timeout hints apply to the resolving step", func(t *testing.T) {
+		p := newOwnerTestPlanner(t)
+		plan := planQuery(t, p, `query {
+			topProducts {
+				name
+				inStock @gatewayHint(cache: false, timeout: "300ms")
+				price @gatewayHint(timeout: "100ms")
+			}
+		}`)
+
+		if got := strings.Join(stepSubGraphs(plan), ","); got != "products,inventory" {
+			t.Fatalf("expected steps on products,inventory, got %s", got)
+		}
+		if plan.Steps[0].BypassCache || plan.Steps[0].Timeout != 0 {
+			t.Errorf("expected no hints on the products step, got %+v", plan.Steps[0])
+		}
+		if !plan.Steps[1].BypassCache || plan.Steps[1].Timeout != 100*time.Millisecond {
+			t.Errorf("expected the inventory step to bypass the cache with the shortest timeout, got %+v", plan.Steps[1])
+		}
+	})
+
+	t.Run("invalid hints fail the plan", func(t *testing.T) {
+		for query, want := range map[string]string{
+			`query { topProducts { name @gatewayHint(subgraph: "reviews") } }`:     `unknown subgraph`,
+			`query { topProducts { name @gatewayHint(timeout: "soon") } }`:         `is not a positive duration`,
+			`query ($c: Boolean) { topProducts { name @gatewayHint(cache: $c) } }`: `invalid @gatewayHint argument cache`,
+			`query { topProducts { name @gatewayHint(retries: 3) } }`:              `invalid @gatewayHint argument retries`,
+		} {
+			_, err := newOwnerTestPlanner(t).Plan(parseQuery(t, query), nil)
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("expected an error containing %q for %s, got %v", want, query, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,335 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// Normalize returns a copy of doc whose operations are normalized for planning:
+// fragment spreads are inlined, inline fragments that do not narrow their parent type are
+// collapsed, fields with the same response key, arguments and directives are merged, and
+// arguments are sorted by name. Equivalent operations normalize to the same document,
+// which keeps subgraph queries free of duplicated fields and gives plan caches a stable
+// key (see NormalizedQuery). doc itself is not modified.
+func (p *PlannerV2) Normalize(doc *ast.Document) *ast.Document {
+	fragmentDefs := p.collectFragmentDefinitions(doc)
+
+	normalized := &ast.Document{Definitions: make([]ast.Definition, 0, len(doc.Definitions))}
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.OperationDefinition:
+			rootTypeName, err := p.getRootTypeName(d)
+			if err != nil {
+				rootTypeName = ""
+			}
+			op := *d
+			op.Directives = normalizeDirectives(d.Directives)
+			op.SelectionSet = p.normalizeSelections(d.SelectionSet, rootTypeName, fragmentDefs, nil)
+			normalized.Definitions = append(normalized.Definitions, &op)
+		case *ast.FragmentDefinition:
+			// Spreads are inlined, so fragment definitions are no longer referenced.
+		default:
+			normalized.Definitions = append(normalized.Definitions, def)
+		}
+	}
+	return normalized
+}
+
+// NormalizedQuery returns the canonical text of the operation in doc named name, or of
+// its first operation when name is empty. Equivalent operations produce the same text,
+// so it can be used as a plan cache key.
+func (p *PlannerV2) NormalizedQuery(doc *ast.Document, name string) (string, error) {
+	for _, def := range p.Normalize(doc).Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok || (name != "" && operationName(op) != name) {
+			continue
+		}
+
+		var sb strings.Builder
+		writeOperation(&sb, op)
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("operation %q not found", name)
+}
+
+// normalizeSelections normalizes selections of parentType. spreading holds the fragments
+// being inlined, to stop at cyclic spreads.
+func (p *PlannerV2) normalizeSelections(selections []ast.Selection, parentType string, fragmentDefs map[string]*ast.FragmentDefinition, spreading map[string]bool) []ast.Selection {
+	result := make([]ast.Selection, 0, len(selections))
+
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			fieldType, _ := p.getFieldTypeName(parentType, sel.Name.String())
+			field := copyField(sel)
+			field.Arguments = sortArguments(sel.Arguments)
+			field.Directives = normalizeDirectives(sel.Directives)
+			if len(sel.SelectionSet) > 0 {
+				field.SelectionSet = p.normalizeSelections(sel.SelectionSet, fieldType, fragmentDefs, spreading)
+			}
+			result = mergeNormalized(result, field)
+
+		case *ast.InlineFragment:
+			result = p.appendNormalizedFragment(result, sel.TypeCondition, sel.Directives, sel.SelectionSet, parentType, fragmentDefs, spreading)
+
+		case *ast.FragmentSpread:
+			fragName := sel.Name.String()
+			fragDef, ok := fragmentDefs[fragName]
+			if !ok || spreading[fragName] {
+				continue
+			}
+			inner := make(map[string]bool, len(spreading)+1)
+			for name := range spreading {
+				inner[name] = true
+			}
+			inner[fragName] = true
+			result = p.appendNormalizedFragment(result, fragDef.TypeCondition, sel.Directives, fragDef.SelectionSet, parentType, fragmentDefs, inner)
+
+		default:
+			result = append(result, sel)
+		}
+	}
+
+	return result
+}
+
+// appendNormalizedFragment appends a fragment on typeCondition to result. A fragment
+// without directives that does not narrow parentType is collapsed into result; other
+// fragments are merged with an equal fragment already in result.
+func (p *PlannerV2) appendNormalizedFragment(result []ast.Selection, typeCondition *ast.NamedType, directives []*ast.Directive, selections []ast.Selection, parentType string, fragmentDefs map[string]*ast.FragmentDefinition, spreading map[string]bool) []ast.Selection {
+	fragmentType := parentType
+	if typeCondition != nil {
+		fragmentType = typeCondition.Name.String()
+	}
+	normalized := p.normalizeSelections(selections, fragmentType, fragmentDefs, spreading)
+
+	if len(directives) == 0 && fragmentType == parentType {
+		for _, sel := range normalized {
+			result = mergeNormalized(result, sel)
+		}
+		return result
+	}
+
+	return mergeNormalized(result, newInlineFragment(typeCondition, normalizeDirectives(directives), normalized))
+}
+
+// mergeNormalized appends sel to selections, merging it into a field with the same
+// response key, name, arguments and directives, or into an inline fragment with the same
+// type condition and directives. Selections are new copies made by normalizeSelections,
+// so merging modifies no shared nodes.
+func mergeNormalized(selections []ast.Selection, sel ast.Selection) []ast.Selection {
+	key, children := selectionIdentity(sel)
+	if key == "" {
+		return append(selections, sel)
+	}
+	for _, existing := range selections {
+		if existingKey, _ := selectionIdentity(existing); existingKey != key {
+			continue
+		}
+		switch e := existing.(type) {
+		case *ast.Field:
+			for _, child := range children {
+				e.SelectionSet = mergeNormalized(e.SelectionSet, child)
+			}
+		case *ast.InlineFragment:
+			for _, child := range children {
+				e.SelectionSet = mergeNormalized(e.SelectionSet, child)
+			}
+		}
+		return selections
+	}
+	return append(selections, sel)
+}
+
+// selectionIdentity returns the key identifying the selections sel can be merged with,
+// and the children of sel. The key is empty for selections that are never merged.
+func selectionIdentity(sel ast.Selection) (string, []ast.Selection) {
+	switch s := sel.(type) {
+	case *ast.Field:
+		return "field:" + responseKey(s) + ":" + s.Name.String() + argumentsString(s.Arguments) + directivesString(s.Directives), s.SelectionSet
+	case *ast.InlineFragment:
+		typeCondition := ""
+		if s.TypeCondition != nil {
+			typeCondition = s.TypeCondition.Name.String()
+		}
+		return "fragment:" + typeCondition + directivesString(s.Directives), s.SelectionSet
+	default:
+		return "", nil
+	}
+}
+
+// sortArguments returns a copy of args sorted by name, with input object fields sorted
+// as well.
+func sortArguments(args []*ast.Argument) []*ast.Argument {
+	if len(args) == 0 {
+		return args
+	}
+	sorted := make([]*ast.Argument, len(args))
+	for i, arg := range args {
+		sorted[i] = &ast.Argument{Token: arg.Token, Name: arg.Name, Value: sortValue(arg.Value)}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name.String() < sorted[j].Name.String() })
+	return sorted
+}
+
+// sortValue returns val with the fields of input objects sorted by name.
+func sortValue(val ast.Value) ast.Value {
+	switch v := val.(type) {
+	case *ast.ObjectValue:
+		fields := make([]*ast.ObjectField, len(v.Fields))
+		for i, field := range v.Fields {
+			fields[i] = &ast.ObjectField{Token: field.Token, Name: field.Name, Value: sortValue(field.Value)}
+		}
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Name.String() < fields[j].Name.String() })
+		return &ast.ObjectValue{Token: v.Token, Fields: fields}
+	case *ast.ListValue:
+		values := make([]ast.Value, len(v.Values))
+		for i, item := range v.Values {
+			values[i] = sortValue(item)
+		}
+		return &ast.ListValue{Token: v.Token, Values: values}
+	default:
+		return val
+	}
+}
+
+// normalizeDirectives returns directives with their arguments sorted.
+func normalizeDirectives(directives []*ast.Directive) []*ast.Directive {
+	if len(directives) == 0 {
+		return directives
+	}
+	normalized := make([]*ast.Directive, len(directives))
+	for i, d := range directives {
+		normalized[i] = &ast.Directive{Token: d.Token, Name: d.Name, Arguments: sortArguments(d.Arguments)}
+	}
+	return normalized
+}
+
+// writeOperation writes op in canonical form.
+func writeOperation(sb *strings.Builder, op *ast.OperationDefinition) {
+	sb.WriteString(string(op.Operation))
+	if op.Name != nil && op.Name.String() != "" {
+		sb.WriteString(" ")
+		sb.WriteString(op.Name.String())
+	}
+	if len(op.VariableDefinitions) > 0 {
+		sb.WriteString("(")
+		for i, v := range op.VariableDefinitions {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("$" + v.Variable.Name + ": " + typeString(v.Type))
+			if v.DefaultValue != nil {
+				sb.WriteString(" = " + valueString(v.DefaultValue))
+			}
+		}
+		sb.WriteString(")")
+	}
+	sb.WriteString(directivesString(op.Directives))
+	writeSelections(sb, op.SelectionSet)
+}
+
+// writeSelections writes selections in canonical form.
+func writeSelections(sb *strings.Builder, selections []ast.Selection) {
+	if len(selections) == 0 {
+		return
+	}
+	sb.WriteString(" {")
+	for _, selection := range selections {
+		sb.WriteString(" ")
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if sel.Alias != nil && sel.Alias.String() != "" && sel.Alias.String() != sel.Name.String() {
+				sb.WriteString(sel.Alias.String() + ": ")
+			}
+			sb.WriteString(sel.Name.String())
+			sb.WriteString(argumentsString(sel.Arguments))
+			sb.WriteString(directivesString(sel.Directives))
+			writeSelections(sb, sel.SelectionSet)
+		case *ast.InlineFragment:
+			sb.WriteString("...")
+			if sel.TypeCondition != nil {
+				sb.WriteString(" on " + sel.TypeCondition.Name.String())
+			}
+			sb.WriteString(directivesString(sel.Directives))
+			writeSelections(sb, sel.SelectionSet)
+		case *ast.FragmentSpread:
+			sb.WriteString("..." + sel.Name.String())
+			sb.WriteString(directivesString(sel.Directives))
+		}
+	}
+	sb.WriteString(" }")
+}
+
+// argumentsString returns the canonical text of args.
+func argumentsString(args []*ast.Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.Name.String() + ": " + valueString(arg.Value)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// directivesString returns the canonical text of directives.
+func directivesString(directives []*ast.Directive) string {
+	var sb strings.Builder
+	for _, d := range directives {
+		sb.WriteString(" @" + d.Name + argumentsString(d.Arguments))
+	}
+	return sb.String()
+}
+
+// valueString returns the canonical text of val.
+func valueString(val ast.Value) string {
+	switch v := val.(type) {
+	case *ast.StringValue:
+		return strconv.Quote(v.Value)
+	case *ast.IntValue:
+		return strconv.FormatInt(v.Value, 10)
+	case *ast.FloatValue:
+		return strconv.FormatFloat(v.Value, 'g', -1, 64)
+	case *ast.BooleanValue:
+		return strconv.FormatBool(v.Value)
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.Variable:
+		return "$" + v.Name
+	case *ast.NullValue:
+		return "null"
+	case *ast.ListValue:
+		parts := make([]string, len(v.Values))
+		for i, item := range v.Values {
+			parts[i] = valueString(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case *ast.ObjectValue:
+		parts := make([]string, len(v.Fields))
+		for i, field := range v.Fields {
+			parts[i] = field.Name.String() + ": " + valueString(field.Value)
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	default:
+		return ""
+	}
+}
+
+// typeString returns the text of a type reference.
+func typeString(t ast.Type) string {
+	switch typ := t.(type) {
+	case *ast.NamedType:
+		return typ.Name.String()
+	case *ast.ListType:
+		return "[" + typeString(typ.Type) + "]"
+	case *ast.NonNullType:
+		return typeString(typ.Type) + "!"
+	default:
+		return ""
+	}
+}
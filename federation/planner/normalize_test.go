@@ -0,0 +1,182 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+func newNormalizePlanner(t *testing.T) *planner.PlannerV2 {
+	t.Helper()
+
+	schema := `
+		interface Media {
+			title: String!
+		}
+
+		type Book implements Media {
+			title: String!
+			author: String!
+		}
+
+		type Movie implements Media {
+			title: String!
+			director: String!
+		}
+
+		type Review {
+			body: String!
+			author: String!
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			price: Int!
+			reviews(first: Int, order: String): [Review!]!
+		}
+
+		type Query {
+			product(id: ID!, locale: String): Product
+			search: [Media!]!
+		}
+	`
+	sg, err := graph.NewSubGraphV2("api", []byte(schema), "http://api.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{sg})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	return planner.NewPlannerV2(superGraph)
+}
+
+func parseQuery(tb testing.TB, query string) *ast.Document {
+	tb.Helper()
+	p := parser.New(lexer.New(query))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		tb.Fatalf("parse error: %v", p.Errors())
+	}
+	return doc
+}
+
+func TestPlannerV2_NormalizedQuery(t *testing.T) {
+	p := newNormalizePlanner(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name: "overlapping fragments are merged",
+			query: `
+				query {
+					product(id: "1") {
+						id
+						name
+						...ProductFields
+						... on Product { name price }
+					}
+				}
+				fragment ProductFields on Product {
+					id
+					reviews { body }
+					reviews { author }
+				}
+			`,
+			want: `query { product(id: "1") { id name reviews { body author } price } }`,
+		},
+		{
+			name:  "arguments are sorted",
+			query: `query { product(locale: "ja", id: "1") { reviews(order: "new", first: 2) { body } } }`,
+			want:  `query { product(id: "1", locale: "ja") { reviews(first: 2, order: "new") { body } } }`,
+		},
+		{
+			name:  "fragments narrowing an interface are kept and merged",
+			query: `query { search { title ... on Book { title } ... on Book { author } ... on Movie { director } } }`,
+			want:  `query { search { title ... on Book { title author } ... on Movie { director } } }`,
+		},
+		{
+			name:  "fields with different arguments or directives are kept apart",
+			query: `query Q($all: Boolean!) { product(id: "1") { name name @include(if: $all) a: reviews(first: 1) { body } a: reviews(first: 1) { author } } }`,
+			want:  `query Q($all: Boolean!) { product(id: "1") { name name @include(if: $all) a: reviews(first: 1) { body author } } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.NormalizedQuery(parseQuery(t, tt.query), "")
+			if err != nil {
+				t.Fatalf("NormalizedQuery failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPlannerV2_NormalizedQuery_Equivalent(t *testing.T) {
+	p := newNormalizePlanner(t)
+
+	a, err := p.NormalizedQuery(parseQuery(t, `{ product(id: "1", locale: "en") { name ... on Product { price } } }`), "")
+	if err != nil {
+		t.Fatalf("NormalizedQuery failed: %v", err)
+	}
+	b, err := p.NormalizedQuery(parseQuery(t, `{ product(locale: "en", id: "1") { ...F name } } fragment F on Product { name price }`), "")
+	if err != nil {
+		t.Fatalf("NormalizedQuery failed: %v", err)
+	}
+	if a != b {
+		t.Errorf("expected equivalent operations to normalize alike, got %s and %s", a, b)
+	}
+}
+
+func TestPlannerV2_Normalize_DoesNotModifyDocument(t *testing.T) {
+	p := newNormalizePlanner(t)
+	doc := parseQuery(t, `{ product(id: "1") { name name reviews { body } reviews { author } } }`)
+
+	p.Normalize(doc)
+
+	op := doc.Definitions[0].(*ast.OperationDefinition)
+	product := op.SelectionSet[0].(*ast.Field)
+	if len(product.SelectionSet) != 4 {
+		t.Errorf("expected the original selections to be kept, got %d", len(product.SelectionSet))
+	}
+}
+
+func TestPlannerV2_Plan_DeduplicatesSelections(t *testing.T) {
+	p := newNormalizePlanner(t)
+	doc := parseQuery(t, `
+		query {
+			product(id: "1") { name ...F ... on Product { name } }
+		}
+		fragment F on Product { name price }
+	`)
+
+	plan, err := p.Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	product := plan.Steps[0].SelectionSet[0].(*ast.Field)
+	counts := make(map[string]int)
+	for _, sel := range product.SelectionSet {
+		counts[sel.(*ast.Field).Name.String()]++
+	}
+	for name, count := range counts {
+		if count != 1 {
+			t.Errorf("expected %s to be selected once, got %d", name, count)
+		}
+	}
+	if counts["name"] != 1 || counts["price"] != 1 {
+		t.Errorf("expected name and price to be selected, got %v", counts)
+	}
+}
@@ -0,0 +1,70 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+)
+
+// OwnerStrategy decides which subgraph resolves a field that several subgraphs can
+// resolve, such as a @shareable field, outside the root operation types.
+type OwnerStrategy string
+
+const (
+	// OwnerStrategyLocal resolves the field in the step already fetching its parent
+	// object when that subgraph can resolve it, collapsing steps, and otherwise from the
+	// first owner in composition order. It is used when no strategy is set.
+	OwnerStrategyLocal OwnerStrategy = "local"
+	// OwnerStrategyFirst always resolves the field from its first owner in composition
+	// order.
+	OwnerStrategyFirst OwnerStrategy = "first"
+	// OwnerStrategyCheapest resolves the field locally like OwnerStrategyLocal, and
+	// otherwise from the owner with the lowest latency weight in the cost model.
+	OwnerStrategyCheapest OwnerStrategy = "cheapest"
+)
+
+// ParseOwnerStrategy returns the strategy named s; an empty name is OwnerStrategyLocal.
+func ParseOwnerStrategy(s string) (OwnerStrategy, error) {
+	switch strategy := OwnerStrategy(s); strategy {
+	case "":
+		return OwnerStrategyLocal, nil
+	case OwnerStrategyLocal, OwnerStrategyFirst, OwnerStrategyCheapest:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown owner strategy %q: expected first, local or cheapest", s)
+}
+
+// chooseOwner picks the subgraph resolving typeName.fieldName among owners for a step
+// on stepSubGraph, which is nil when no step fetches the parent object yet. involved
+// names the subgraphs already fetching other fields of the same objects; an owner among
+// them is preferred to a new one, so the fields share a step. The owner order of a
+// progressive @override decides its routing, so it is always kept.
+func (p *PlannerV2) chooseOwner(typeName, fieldName string, owners []*graph.SubGraphV2, stepSubGraph *graph.SubGraphV2, involved map[string]bool) *graph.SubGraphV2 {
+	if len(owners) == 1 || p.OwnerStrategy == OwnerStrategyFirst || p.SuperGraph.HasProgressiveOverride(typeName, fieldName) {
+		return owners[0]
+	}
+
+	if stepSubGraph != nil {
+		for _, owner := range owners {
+			if owner.Name == stepSubGraph.Name {
+				return owner
+			}
+		}
+	}
+	for _, owner := range owners {
+		if involved[owner.Name] {
+			return owner
+		}
+	}
+
+	if p.OwnerStrategy != OwnerStrategyCheapest || p.CostModel == nil {
+		return owners[0]
+	}
+	best := owners[0]
+	for _, owner := range owners[1:] {
+		if p.CostModel.latency(owner) < p.CostModel.latency(best) {
+			best = owner
+		}
+	}
+	return best
+}
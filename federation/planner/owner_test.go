@@ -0,0 +1,170 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+func newOwnerTestPlanner(t *testing.T) *planner.PlannerV2 {
+	t.Helper()
+
+	pricingSchema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			price: Int! @shareable
+			weight: Int! @shareable
+		}
+	`
+	productsSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			weight: Int! @shareable
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	inventorySchema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean!
+			price: Int! @shareable
+			weight: Int! @shareable
+		}
+	`
+
+	var subGraphs []*graph.SubGraphV2
+	for _, sg := range []struct{ name, schema string }{
+		{"pricing", pricingSchema},
+		{"products", productsSchema},
+		{"inventory", inventorySchema},
+	} {
+		subGraph, err := graph.NewSubGraphV2(sg.name, []byte(sg.schema), "http://"+sg.name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 failed: %v", err)
+		}
+		subGraphs = append(subGraphs, subGraph)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	return planner.NewPlannerV2(superGraph)
+}
+
+func TestParseOwnerStrategy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    planner.OwnerStrategy
+		wantErr bool
+	}{
+		{in: "", want: planner.OwnerStrategyLocal},
+		{in: "local", want: planner.OwnerStrategyLocal},
+		{in: "first", want: planner.OwnerStrategyFirst},
+		{in: "cheapest", want: planner.OwnerStrategyCheapest},
+		{in: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := planner.ParseOwnerStrategy(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseOwnerStrategy(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseOwnerStrategy(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlannerV2_OwnerStrategy(t *testing.T) {
+	tests := []struct {
+		name          string
+		strategy      planner.OwnerStrategy
+		costModel     *planner.CostModel
+		query         string
+		wantSubGraphs []string
+	}{
+		{
+			name:          "first owner fetches a field the parent step resolves",
+			strategy:      planner.OwnerStrategyFirst,
+			query:         `query { topProducts { name weight } }`,
+			wantSubGraphs: []string{"products", "pricing"},
+		},
+		{
+			name:          "local resolves the field in the parent step",
+			strategy:      planner.OwnerStrategyLocal,
+			query:         `query { topProducts { name weight } }`,
+			wantSubGraphs: []string{"products"},
+		},
+		{
+			name:          "empty strategy is local",
+			query:         `query { topProducts { name weight } }`,
+			wantSubGraphs: []string{"products"},
+		},
+		{
+			name:          "first owner adds a step next to a sibling",
+			strategy:      planner.OwnerStrategyFirst,
+			query:         `query { topProducts { inStock price } }`,
+			wantSubGraphs: []string{"products", "inventory", "pricing"},
+		},
+		{
+			name:          "local joins the sibling step",
+			strategy:      planner.OwnerStrategyLocal,
+			query:         `query { topProducts { inStock price } }`,
+			wantSubGraphs: []string{"products", "inventory"},
+		},
+		{
+			name:          "local falls back to the first owner",
+			strategy:      planner.OwnerStrategyLocal,
+			query:         `query { topProducts { price } }`,
+			wantSubGraphs: []string{"products", "pricing"},
+		},
+		{
+			name:     "cheapest picks the owner with the lowest latency",
+			strategy: planner.OwnerStrategyCheapest,
+			costModel: &planner.CostModel{
+				SubGraphLatency: map[string]float64{"pricing": 5},
+			},
+			query:         `query { topProducts { price } }`,
+			wantSubGraphs: []string{"products", "inventory"},
+		},
+		{
+			name:     "cheapest still prefers the parent step",
+			strategy: planner.OwnerStrategyCheapest,
+			costModel: &planner.CostModel{
+				SubGraphLatency: map[string]float64{"products": 5},
+			},
+			query:         `query { topProducts { weight } }`,
+			wantSubGraphs: []string{"products"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newOwnerTestPlanner(t)
+			p.OwnerStrategy = tt.strategy
+			p.CostModel = tt.costModel
+
+			plan := planQuery(t, p, tt.query)
+
+			var got []string
+			for _, step := range plan.Steps {
+				got = append(got, step.SubGraph.Name)
+			}
+			if len(got) != len(tt.wantSubGraphs) {
+				t.Fatalf("expected steps on %v, got %v", tt.wantSubGraphs, got)
+			}
+			for i := range got {
+				if got[i] != tt.wantSubGraphs[i] {
+					t.Errorf("expected steps on %v, got %v", tt.wantSubGraphs, got)
+					break
+				}
+			}
+		})
+	}
+}
@@ -1,13 +1,14 @@
 package planner
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
 	"github.com/n9te9/graphql-parser/ast"
-	"github.com/n9te9/graphql-parser/token"
 )
 
 // StepType indicates the type of a step.
@@ -18,6 +19,9 @@ const (
 	StepTypeQuery StepType = iota
 	// StepTypeEntity represents a step that resolves fields of an entity.
 	StepTypeEntity
+	// StepTypeRepresentations represents a synthetic root step whose result is the
+	// list of representations received by the gateway's own _entities field.
+	StepTypeRepresentations
 )
 
 // StepV2 represents a unit of request to a service.
@@ -30,6 +34,17 @@ type StepV2 struct {
 	Path          []string          // Path to the field
 	DependsOn     []int             // List of dependent step IDs
 	InsertionPath []string          // Path to insert results (for entity resolution)
+	BatchedPaths  [][]string        // Further insertion paths whose objects are sent in the same _entities request
+	Requires      []ast.Selection   // @requires fields sent with each representation
+	Contexts      []ContextValue    // @fromContext arguments set from ancestor objects
+	Timeout       time.Duration     // Bound of the subgraph request, from @gatewayHint(timeout:)
+	BypassCache   bool              // Skip the entity cache, from @gatewayHint(cache: false)
+}
+
+// InsertionPaths returns the insertion paths of the objects the step is sent: its
+// InsertionPath followed by its BatchedPaths.
+func (s *StepV2) InsertionPaths() [][]string {
+	return append([][]string{s.InsertionPath}, s.BatchedPaths...)
 }
 
 // PlanV2 represents a query execution plan.
@@ -38,11 +53,37 @@ type PlanV2 struct {
 	RootStepIndexes  []int         // Indexes of root steps
 	OriginalDocument *ast.Document // Original query document
 	OperationType    string        // Operation type (query, mutation, subscription)
+	OperationName    string        // Operation name, empty for anonymous operations
+
+	// Representations holds the input of a StepTypeRepresentations root step.
+	Representations []map[string]interface{}
+
+	// PrunedFields lists the root fields dropped by a planner with PruneUnfetchable.
+	PrunedFields []PrunedField
+}
+
+// PrunedField is a root field no subgraph resolves, dropped from the plan instead of
+// failing it. The executor returns null for it with an UNFETCHABLE_FIELD error.
+type PrunedField struct {
+	ResponseKey string `json:"responseKey"` // Alias or name of the field in the response
+	Coordinate  string `json:"coordinate"`  // Schema coordinate, e.g. Query.legacyProducts
 }
 
 // PlannerV2 generates query execution plans.
 type PlannerV2 struct {
 	SuperGraph *graph.SuperGraphV2 // Super graph
+	CostModel  *CostModel          // Optional cost model choosing among subgraphs for shareable root fields
+
+	// OwnerStrategy chooses among the subgraphs resolving a shareable non-root field;
+	// empty is OwnerStrategyLocal.
+	OwnerStrategy OwnerStrategy
+
+	// PruneUnfetchable drops root fields no subgraph resolves, e.g. during a partial
+	// schema rollout, instead of failing the plan. They are listed in PlanV2.PrunedFields.
+	PruneUnfetchable bool
+
+	// ctx aborts planning once done; nil outside PlanContext.
+	ctx context.Context
 }
 
 // NewPlannerV2 creates a new PlannerV2 instance.
@@ -52,17 +93,53 @@ func NewPlannerV2(superGraph *graph.SuperGraphV2) *PlannerV2 {
 	}
 }
 
+// WithOverrideLabels returns a planner that routes progressive @override fields whose
+// label is active to the overriding subgraph. It returns p itself when no label is active.
+func (p *PlannerV2) WithOverrideLabels(active map[string]bool) *PlannerV2 {
+	superGraph := p.SuperGraph.WithOverrideLabels(active)
+	if superGraph == p.SuperGraph {
+		return p
+	}
+	labelled := *p
+	labelled.SuperGraph = superGraph
+	return &labelled
+}
+
+// PlanContext is Plan bounded by ctx: planning stops building entity steps once ctx is
+// done and returns its error, so an operation exploding during planning is cut off
+// before any step runs.
+func (p *PlannerV2) PlanContext(ctx context.Context, doc *ast.Document, variables map[string]any) (*PlanV2, error) {
+	if ctx.Done() == nil {
+		return p.Plan(doc, variables)
+	}
+	bounded := *p
+	bounded.ctx = ctx
+	plan, err := bounded.Plan(doc, variables)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return plan, err
+}
+
+// cancelled reports whether the context of PlanContext is done.
+func (p *PlannerV2) cancelled() bool {
+	return p.ctx != nil && p.ctx.Err() != nil
+}
+
 // Plan generates an execution plan from a query document.
 // Following V1's walkRoot/walkResolver pattern: builds new SelectionSets instead of modifying AST.
 func (p *PlannerV2) Plan(doc *ast.Document, variables map[string]any) (*PlanV2, error) {
-	// Get the operation
-	op := p.getOperation(doc)
+	// Plan the normalized operation; the original document is kept for response pruning
+	op := p.getOperation(p.Normalize(doc))
 	if op == nil {
 		return nil, errors.New("no operation found")
 	}
 	if len(op.SelectionSet) == 0 {
 		return nil, errors.New("empty selection")
 	}
+	if err := p.validateHints(doc); err != nil {
+		return nil, err
+	}
 
 	// Collect fragment definitions from the document
 	fragmentDefs := p.collectFragmentDefinitions(doc)
@@ -79,16 +156,20 @@ func (p *PlannerV2) Plan(doc *ast.Document, variables map[string]any) (*PlanV2,
 		RootStepIndexes:  make([]int, 0),
 		OriginalDocument: doc,
 		OperationType:    string(op.Operation),
+		OperationName:    operationName(op),
 	}
 
 	// Step ID counter
 	nextStepID := 0
 
 	// Expand fragments in the root SelectionSet
-	expandedSelections := p.expandFragmentsInSelections(op.SelectionSet, fragmentDefs)
+	expandedSelections := p.expandFragmentsInSelections(op.SelectionSet, rootTypeName, fragmentDefs)
 
-	// Group root fields by responsible subgraph
-	rootFieldsBySubGraph := make(map[*graph.SubGraphV2][]ast.Selection)
+	// Group root fields by responsible subgraph. Root mutation fields must execute
+	// serially in document order, so for mutations only consecutive fields owned by
+	// the same subgraph share a step; the executor then runs root steps in order.
+	serial := op.Operation == ast.Mutation
+	rootGroups := make([]*rootFieldGroup, 0)
 
 	for _, selection := range expandedSelections {
 		field, ok := selection.(*ast.Field)
@@ -106,22 +187,53 @@ func (p *PlannerV2) Plan(doc *ast.Document, variables map[string]any) (*PlanV2,
 		// Get responsible subgraph from ownership map
 		subGraphs := p.SuperGraph.GetSubGraphsForField(rootTypeName, fieldName)
 		if len(subGraphs) == 0 {
-			return nil, fmt.Errorf("no subgraph found for field %s.%s", rootTypeName, fieldName)
+			if !p.PruneUnfetchable {
+				return nil, fmt.Errorf("no subgraph found for field %s.%s", rootTypeName, fieldName)
+			}
+			plan.PrunedFields = append(plan.PrunedFields, PrunedField{
+				ResponseKey: responseKey(field),
+				Coordinate:  rootTypeName + "." + fieldName,
+			})
+			continue
+		}
+
+		// A field of an abstract type whose possible types are split across its owners,
+		// e.g. a Relay node field, is sent to each of them
+		if fanOut := p.fanOutSubGraphs(op.Operation, rootTypeName, field, subGraphs); fanOut != nil {
+			fieldType, _ := p.getFieldTypeName(rootTypeName, fieldName)
+			for _, subGraph := range fanOut {
+				group := findRootFieldGroup(rootGroups, subGraph, serial)
+				if group == nil {
+					group = &rootFieldGroup{subGraph: subGraph}
+					rootGroups = append(rootGroups, group)
+				}
+				group.selections = append(group.selections, p.restrictToSubGraphTypes(field, fieldType, subGraph))
+			}
+			continue
 		}
 
-		// Use the first subgraph (for @shareable fields there may be multiple, but use the first one for now)
-		subGraph := subGraphs[0]
-		rootFieldsBySubGraph[subGraph] = append(rootFieldsBySubGraph[subGraph], selection)
+		// For @shareable root fields several subgraphs may resolve the field; the cost
+		// model picks the cheapest one, otherwise the first owner is used
+		subGraph := p.chooseRootSubGraph(rootTypeName, field, subGraphs, fragmentDefs)
+		group := findRootFieldGroup(rootGroups, subGraph, serial)
+		if group == nil {
+			group = &rootFieldGroup{subGraph: subGraph}
+			rootGroups = append(rootGroups, group)
+		}
+		group.selections = append(group.selections, selection)
 	}
 
 	// Create root steps with filtered SelectionSets
-	for subGraph, selections := range rootFieldsBySubGraph {
+	for _, group := range rootGroups {
+		// Interface fields the subgraph does not resolve are fetched per possible type
+		group.selections = p.distributeAbstractFields(group.selections, rootTypeName, group.subGraph)
+
 		// Build SelectionSet containing only fields owned by this subgraph
-		filteredSelections := p.buildStepSelections(selections, subGraph, rootTypeName, fragmentDefs)
+		filteredSelections := p.buildStepSelections(group.selections, group.subGraph, rootTypeName, fragmentDefs, nil, "")
 
 		step := &StepV2{
 			ID:           nextStepID,
-			SubGraph:     subGraph,
+			SubGraph:     group.subGraph,
 			StepType:     StepTypeQuery,
 			ParentType:   rootTypeName,
 			SelectionSet: filteredSelections,
@@ -137,32 +249,141 @@ func (p *PlannerV2) Plan(doc *ast.Document, variables map[string]any) (*PlanV2,
 	// Find and create entity steps for boundary fields
 	// Process each root step to find boundary fields
 	// Key fields will be injected during entity step creation in findAndBuildEntitySteps()
-	for _, rootStepIdx := range plan.RootStepIndexes {
+	for i, rootStepIdx := range plan.RootStepIndexes {
 		rootStep := plan.Steps[rootStepIdx]
 
 		// Find boundary fields in the original selections (not filtered)
-		originalSelections := rootFieldsBySubGraph[rootStep.SubGraph]
-		p.findAndBuildEntitySteps(originalSelections, rootStep, plan, &nextStepID, rootStep.ParentType, rootStep.Path, fragmentDefs)
+		originalSelections := rootGroups[i].selections
+		p.findAndBuildEntitySteps(originalSelections, rootStep, plan, &nextStepID, rootStep.ParentType, rootStep.Path, fragmentDefs, nil)
+	}
+	if p.cancelled() {
+		return nil, p.ctx.Err()
 	}
 
+	// Set @fromContext arguments from the ancestors setting their context
+	p.injectContextDependencies(plan, rootTypeName, expandedSelections)
+
 	// Inject @requires dependencies into parent steps
 	p.injectRequiresDependencies(plan)
 
-	// TODO: Apply @provides optimization
-	// @provides allows a subgraph to declare that it already provides certain fields
-	// that would normally require a separate fetch from another subgraph.
-	// Implementation would involve:
-	// 1. Scanning steps for fields with @provides directives
-	// 2. Checking if provided fields are queried
-	// 3. Overriding ownership for provided fields to avoid unnecessary entity fetches
-	// 4. Removing or merging entity resolution steps that are no longer needed
-	// This optimization can significantly reduce network calls in federated queries.
-	// For now, @provides directives are parsed and available in entity field metadata,
-	// but the optimization logic is deferred to future implementation.
+	// Fuse sibling entity steps sending the same representations to one subgraph, then
+	// batch those sending the same request for objects at different paths
+	fuseEntitySteps(plan)
+	batchEntitySteps(plan)
+	applyHints(plan)
 
 	return plan, nil
 }
 
+// operationName returns the name of op, or "" for anonymous operations.
+func operationName(op *ast.OperationDefinition) string {
+	if op.Name == nil {
+		return ""
+	}
+	return op.Name.String()
+}
+
+// rootFieldGroup is a set of root fields resolved by one root step.
+type rootFieldGroup struct {
+	subGraph   *graph.SubGraphV2
+	selections []ast.Selection
+}
+
+// findRootFieldGroup returns the group a root field owned by subGraph joins.
+// When serial is set only the last group is eligible, so that field order is kept.
+func findRootFieldGroup(groups []*rootFieldGroup, subGraph *graph.SubGraphV2, serial bool) *rootFieldGroup {
+	if serial {
+		if len(groups) > 0 && groups[len(groups)-1].subGraph == subGraph {
+			return groups[len(groups)-1]
+		}
+		return nil
+	}
+	for _, group := range groups {
+		if group.subGraph == subGraph {
+			return group
+		}
+	}
+	return nil
+}
+
+// PlanEntities generates an execution plan that resolves representations of typeName
+// received by the gateway's own _entities field, so that the gateway can act as a
+// subgraph of a parent gateway. selections are the fields selected for typeName
+// inside the _entities field.
+func (p *PlannerV2) PlanEntities(doc *ast.Document, typeName string, selections []ast.Selection, representations []map[string]interface{}) (*PlanV2, error) {
+	if p.SuperGraph.GetEntityOwnerSubGraph(typeName) == nil {
+		return nil, fmt.Errorf("type %s is not a resolvable entity", typeName)
+	}
+
+	if err := p.validateHints(doc); err != nil {
+		return nil, err
+	}
+
+	fragmentDefs := p.collectFragmentDefinitions(doc)
+	expandedSelections := p.expandFragmentsInSelections(p.normalizeSelections(selections, typeName, fragmentDefs, nil), typeName, fragmentDefs)
+	queryTypeName := p.SuperGraph.RootTypeName(ast.Query)
+
+	// The root step carries no subgraph: its result is the representations themselves,
+	// stored under _entities so entity steps can extract and merge into them.
+	rootStep := &StepV2{
+		ID:         0,
+		StepType:   StepTypeRepresentations,
+		ParentType: queryTypeName,
+		SelectionSet: []ast.Selection{
+			newField("_entities"),
+		},
+		Path:      []string{queryTypeName},
+		DependsOn: []int{},
+	}
+
+	plan := &PlanV2{
+		Steps:            []*StepV2{rootStep},
+		RootStepIndexes:  []int{rootStep.ID},
+		OriginalDocument: doc,
+		OperationType:    string(ast.Query),
+		Representations:  representations,
+	}
+
+	nextStepID := 1
+	p.findAndBuildEntitySteps(expandedSelections, rootStep, plan, &nextStepID, typeName, []string{queryTypeName, "_entities"}, fragmentDefs, nil)
+	p.injectRequiresDependencies(plan)
+	fuseEntitySteps(plan)
+	batchEntitySteps(plan)
+	applyHints(plan)
+
+	return plan, nil
+}
+
+// stepSubGraphName returns the name of the subgraph responsible for step,
+// or an empty string for synthetic steps that are not sent to any subgraph.
+func stepSubGraphName(step *StepV2) string {
+	if step.SubGraph == nil {
+		return ""
+	}
+	return step.SubGraph.Name
+}
+
+// resolvingSubGraph returns the subgraph that resolves typeName.fieldName inside a step
+// on stepSubGraph. Root fields are assigned to root steps up front (possibly by the cost
+// model), so a root step resolves every root field it can; other fields are resolved by
+// the owner the owner strategy chooses, given the subgraphs involved in fetching the
+// same objects.
+func (p *PlannerV2) resolvingSubGraph(typeName, fieldName string, stepSubGraph *graph.SubGraphV2, involved map[string]bool) *graph.SubGraphV2 {
+	subGraphs := p.SuperGraph.GetSubGraphsForField(typeName, fieldName)
+	if len(subGraphs) == 0 {
+		return nil
+	}
+	if stepSubGraph != nil && p.SuperGraph.IsRootType(typeName) {
+		for _, sg := range subGraphs {
+			if sg.Name == stepSubGraph.Name {
+				return sg
+			}
+		}
+		return subGraphs[0]
+	}
+	return p.chooseOwner(typeName, fieldName, subGraphs, stepSubGraph, involved)
+}
+
 // collectFragmentDefinitions extracts all fragment definitions from the document
 func (p *PlannerV2) collectFragmentDefinitions(doc *ast.Document) map[string]*ast.FragmentDefinition {
 	fragments := make(map[string]*ast.FragmentDefinition)
@@ -175,7 +396,10 @@ func (p *PlannerV2) collectFragmentDefinitions(doc *ast.Document) map[string]*as
 }
 
 // expandFragmentsInSelections expands all fragment spreads and inline fragments in selections
-func (p *PlannerV2) expandFragmentsInSelections(selections []ast.Selection, fragmentDefs map[string]*ast.FragmentDefinition) []ast.Selection {
+// of parentType. Fragments narrowing an abstract parentType to one of its possible types
+// are kept as inline fragments, so that the fields of each implementation can be planned
+// against that type; all other fragments are inlined.
+func (p *PlannerV2) expandFragmentsInSelections(selections []ast.Selection, parentType string, fragmentDefs map[string]*ast.FragmentDefinition) []ast.Selection {
 	result := make([]ast.Selection, 0)
 
 	for _, selection := range selections {
@@ -183,22 +407,16 @@ func (p *PlannerV2) expandFragmentsInSelections(selections []ast.Selection, frag
 		case *ast.Field:
 			// For fields, recursively expand child selections
 			if len(sel.SelectionSet) > 0 {
-				newField := &ast.Field{
-					Alias:      sel.Alias,
-					Name:       sel.Name,
-					Arguments:  sel.Arguments,
-					Directives: sel.Directives,
-				}
-				newField.SelectionSet = p.expandFragmentsInSelections(sel.SelectionSet, fragmentDefs)
-				result = append(result, newField)
+				expanded := copyField(sel)
+				fieldType, _ := p.getFieldTypeName(parentType, sel.Name.String())
+				expanded.SelectionSet = p.expandFragmentsInSelections(sel.SelectionSet, fieldType, fragmentDefs)
+				result = append(result, expanded)
 			} else {
 				result = append(result, sel)
 			}
 
 		case *ast.InlineFragment:
-			// Expand inline fragment - just inline its selections
-			expandedSelections := p.expandFragmentsInSelections(sel.SelectionSet, fragmentDefs)
-			result = append(result, expandedSelections...)
+			result = append(result, p.expandFragment(sel.TypeCondition, sel.SelectionSet, parentType, fragmentDefs)...)
 
 		case *ast.FragmentSpread:
 			// Expand fragment spread by looking up the fragment definition
@@ -208,10 +426,7 @@ func (p *PlannerV2) expandFragmentsInSelections(selections []ast.Selection, frag
 				// Fragment not found, skip it
 				continue
 			}
-
-			// Recursively expand the fragment's selections
-			expandedSelections := p.expandFragmentsInSelections(fragDef.SelectionSet, fragmentDefs)
-			result = append(result, expandedSelections...)
+			result = append(result, p.expandFragment(fragDef.TypeCondition, fragDef.SelectionSet, parentType, fragmentDefs)...)
 
 		default:
 			// Unknown selection type, include as-is
@@ -222,9 +437,20 @@ func (p *PlannerV2) expandFragmentsInSelections(selections []ast.Selection, frag
 	return result
 }
 
-// buildStepSelections builds a new SelectionSet containing only fields owned by the given subgraph.
+// expandFragment expands the selections of a fragment on typeCondition within parentType.
+func (p *PlannerV2) expandFragment(typeCondition *ast.NamedType, selections []ast.Selection, parentType string, fragmentDefs map[string]*ast.FragmentDefinition) []ast.Selection {
+	if typeCondition == nil || typeCondition.Name.String() == parentType || !p.SuperGraph.IsAbstractType(parentType) {
+		return p.expandFragmentsInSelections(selections, parentType, fragmentDefs)
+	}
+	return []ast.Selection{newInlineFragment(typeCondition, nil, p.expandFragmentsInSelections(selections, typeCondition.Name.String(), fragmentDefs))}
+}
+
+// buildStepSelections builds a new SelectionSet containing only fields owned by the given subgraph,
+// or provided by it through @provides on an enclosing field (provided). entityType is the
+// entity type the step resolves at this level, if any; @requires fields of other types are
+// left to entity steps of their own.
 // This follows V1's walkRoot pattern: builds new selections instead of modifying existing ones.
-func (p *PlannerV2) buildStepSelections(selections []ast.Selection, subGraph *graph.SubGraphV2, parentType string, fragmentDefs map[string]*ast.FragmentDefinition) []ast.Selection {
+func (p *PlannerV2) buildStepSelections(selections []ast.Selection, subGraph *graph.SubGraphV2, parentType string, fragmentDefs map[string]*ast.FragmentDefinition, provided graph.FieldSet, entityType string) []ast.Selection {
 	result := make([]ast.Selection, 0)
 	hasTypename := false
 
@@ -236,20 +462,19 @@ func (p *PlannerV2) buildStepSelections(selections []ast.Selection, subGraph *gr
 			// Track if __typename is explicitly requested
 			if fieldName == "__typename" {
 				hasTypename = true
-				newField := &ast.Field{
-					Name: &ast.Name{
-						Token: token.Token{Type: token.IDENT, Literal: "__typename"},
-						Value: "__typename",
-					},
-				}
-				result = append(result, newField)
+				result = append(result, newField("__typename"))
 				continue
 			}
 
-			// Check if this field is owned by the current subgraph
-			subGraphs := p.SuperGraph.GetSubGraphsForField(parentType, fieldName)
-			if len(subGraphs) == 0 || subGraphs[0].Name != subGraph.Name {
-				// Not owned by this subgraph, skip it
+			// Check if this field is owned or provided by the current subgraph
+			owner := p.resolvingFieldSubGraph(parentType, sel, subGraph, nil)
+			if _, isProvided := provided.Field(fieldName); !isProvided && (owner == nil || owner.Name != subGraph.Name) {
+				// Not resolved by this subgraph, skip it
+				continue
+			}
+			// Fields with @requires are resolved by an entity step of their own, unless
+			// this step resolves the entity they belong to
+			if parentType != entityType && p.requiresRepresentation(subGraph, parentType, fieldName) {
 				continue
 			}
 
@@ -261,37 +486,32 @@ func (p *PlannerV2) buildStepSelections(selections []ast.Selection, subGraph *gr
 			}
 
 			// Build new field with filtered child selections
-			newField := &ast.Field{
-				Alias:      sel.Alias,
-				Name:       sel.Name,
-				Arguments:  sel.Arguments,
-				Directives: sel.Directives,
-			}
+			stepField := copyField(sel)
 
 			// Recursively process child selections
 			if len(sel.SelectionSet) > 0 && fieldType != "" {
-				childSelections := p.buildStepSelections(sel.SelectionSet, subGraph, fieldType, fragmentDefs)
+				childSelections := p.buildStepSelections(sel.SelectionSet, subGraph, fieldType, fragmentDefs, p.providedChildren(provided, subGraph, parentType, fieldName), "")
 
 				// If no child selections were included but original had children, add __typename
 				if len(childSelections) == 0 {
-					childSelections = append(childSelections, &ast.Field{
-						Name: &ast.Name{
-							Token: token.Token{Type: token.IDENT, Literal: "__typename"},
-							Value: "__typename",
-						},
-					})
+					childSelections = append(childSelections, newField("__typename"))
 				}
 
-				newField.SelectionSet = childSelections
+				stepField.SelectionSet = childSelections
 			}
 
-			result = append(result, newField)
+			result = append(result, stepField)
 
 		case *ast.InlineFragment:
-			// Expand inline fragment selections
+			// Expand inline fragment selections; fragments narrowing an abstract type
+			// are kept so that each implementation selects its own fields
 			typeCondition := sel.TypeCondition.Name.String()
-			expandedSelections := p.buildStepSelections(sel.SelectionSet, subGraph, typeCondition, fragmentDefs)
-			result = append(result, expandedSelections...)
+			expandedSelections := p.buildStepSelections(sel.SelectionSet, subGraph, typeCondition, fragmentDefs, provided, entityType)
+			if typeCondition == parentType || !p.SuperGraph.IsAbstractType(parentType) {
+				result = append(result, expandedSelections...)
+			} else if len(expandedSelections) > 0 {
+				result = append(result, newInlineFragment(sel.TypeCondition, nil, expandedSelections))
+			}
 
 		case *ast.FragmentSpread:
 			// Expand fragment spread by looking up the fragment definition
@@ -304,7 +524,7 @@ func (p *PlannerV2) buildStepSelections(selections []ast.Selection, subGraph *gr
 
 			// Extract selections from the fragment definition
 			typeCondition := fragDef.TypeCondition.Name.String()
-			expandedSelections := p.buildStepSelections(fragDef.SelectionSet, subGraph, typeCondition, fragmentDefs)
+			expandedSelections := p.buildStepSelections(fragDef.SelectionSet, subGraph, typeCondition, fragmentDefs, provided, entityType)
 			result = append(result, expandedSelections...)
 		}
 	}
@@ -312,15 +532,8 @@ func (p *PlannerV2) buildStepSelections(selections []ast.Selection, subGraph *gr
 	// Auto-inject __typename if not explicitly requested
 	// This is needed for entity key field extraction
 	// But skip for root operation types (Query, Mutation, Subscription)
-	isRootType := parentType == "Query" || parentType == "Mutation" || parentType == "Subscription"
-	if !hasTypename && !isRootType && len(result) > 0 {
-		typenameField := &ast.Field{
-			Name: &ast.Name{
-				Token: token.Token{Type: token.IDENT, Literal: "__typename"},
-				Value: "__typename",
-			},
-		}
-		result = append([]ast.Selection{typenameField}, result...)
+	if !hasTypename && !p.SuperGraph.IsRootType(parentType) && len(result) > 0 {
+		result = append([]ast.Selection{newField("__typename")}, result...)
 	}
 
 	return result
@@ -328,6 +541,8 @@ func (p *PlannerV2) buildStepSelections(selections []ast.Selection, subGraph *gr
 
 // findAndBuildEntitySteps finds boundary fields and creates entity resolution steps.
 // This recursively processes the original selections to find fields owned by different subgraphs.
+// provided holds the fields of parentType that parentStep's subgraph provides through
+// @provides on an enclosing field; they are resolved by parentStep without an entity fetch.
 func (p *PlannerV2) findAndBuildEntitySteps(
 	selections []ast.Selection,
 	parentStep *StepV2,
@@ -336,10 +551,23 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 	parentType string,
 	currentPath []string,
 	fragmentDefs map[string]*ast.FragmentDefinition,
+	provided graph.FieldSet,
 ) {
+	if p.cancelled() {
+		return
+	}
 	entityStepsByKey := make(map[string]*StepV2)
+	// Subgraphs with entity steps extending the objects at currentPath
+	involved := make(map[string]bool)
 
 	for _, selection := range selections {
+		// Fields of an implementation of an abstract type are resolved from the same
+		// objects, but against the implementation's own entity definition
+		if fragment, ok := selection.(*ast.InlineFragment); ok && fragment.TypeCondition != nil {
+			p.findAndBuildEntitySteps(fragment.SelectionSet, parentStep, plan, nextStepID, fragment.TypeCondition.Name.String(), currentPath, fragmentDefs, provided)
+			continue
+		}
+
 		field, ok := selection.(*ast.Field)
 		if !ok {
 			continue
@@ -365,12 +593,16 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 		// Build path for this field (use alias for path to support multiple queries with same field)
 		fieldPath := append(append([]string{}, currentPath...), fieldIdentifier)
 
-		// Check who owns this field
-		subGraphs := p.SuperGraph.GetSubGraphsForField(parentType, fieldName)
-		if len(subGraphs) == 0 {
+		// Check who owns this field; a field provided by the parent step's subgraph is
+		// resolved there
+		fieldSubGraph := p.resolvingFieldSubGraph(parentType, field, parentStep.SubGraph, involved)
+		if _, isProvided := provided.Field(fieldName); isProvided {
+			fieldSubGraph = parentStep.SubGraph
+		}
+		if fieldSubGraph == nil {
 			continue
 		}
-		fieldSubGraph := subGraphs[0]
+		childProvided := p.providedChildren(provided, parentStep.SubGraph, parentType, fieldName)
 
 		// Check if the field returns an entity type
 		// If so, we need to check which subgraph owns that entity (has @key)
@@ -382,20 +614,39 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 		isBoundaryField := false
 		targetSubGraph := fieldSubGraph
 
-		if fieldSubGraph.Name != parentStep.SubGraph.Name {
+		if fieldSubGraph.Name != stepSubGraphName(parentStep) {
 			// Case 1: Field is owned by a different subgraph
 			isBoundaryField = true
-		} else if entityOwnerSubGraph != nil && entityOwnerSubGraph.Name != parentStep.SubGraph.Name {
-			// Case 2: Field returns an entity type owned by a different subgraph
+		} else if p.requiresRepresentation(fieldSubGraph, parentType, fieldName) && !resolvesEntityAt(parentStep, parentType, currentPath) {
+			// Case 1b: Field is computed from @requires fields, which the subgraph only
+			// receives in the representations of an entity step
 			isBoundaryField = true
-			targetSubGraph = entityOwnerSubGraph
+		} else if entityOwnerSubGraph != nil && entityOwnerSubGraph.Name != stepSubGraphName(parentStep) {
+			// Case 2: Field returns an entity type owned by a different subgraph. Children
+			// provided by the parent step's subgraph are resolved there; only the rest
+			// needs an entity fetch.
+			providedSelections, remaining := splitProvidedSelections(field.SelectionSet, childProvided)
+			if len(providedSelections) > 0 {
+				p.findAndBuildEntitySteps(providedSelections, parentStep, plan, nextStepID, fieldType, fieldPath, fragmentDefs, childProvided)
+			}
+			if len(providedSelections) == 0 || len(remaining) > 0 {
+				isBoundaryField = true
+				targetSubGraph = entityOwnerSubGraph
+				if len(providedSelections) > 0 {
+					trimmed := copyField(field)
+					trimmed.SelectionSet = remaining
+					field, selection = trimmed, trimmed
+				}
+			} else {
+				continue
+			}
 		}
 
 		// If this field is owned by the parent step's subgraph, recursively process its children
 		if !isBoundaryField {
 			// Same subgraph - recursively process children to find nested boundary fields
 			if len(field.SelectionSet) > 0 {
-				p.findAndBuildEntitySteps(field.SelectionSet, parentStep, plan, nextStepID, fieldType, fieldPath, fragmentDefs)
+				p.findAndBuildEntitySteps(field.SelectionSet, parentStep, plan, nextStepID, fieldType, fieldPath, fragmentDefs, childProvided)
 			}
 		} else {
 			// Different subgraph - this is a boundary field, create entity step
@@ -420,8 +671,9 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 			// - Entity type
 			// - Parent step ID
 			// - Insertion path (not including individual child field names)
-			// Use currentPath (insertion path) + fieldName (boundary field) as the key
-			boundaryFieldPath := append(append([]string{}, currentPath...), fieldName)
+			// Use fieldPath (insertion path + response key of the boundary field) as the
+			// key, so that aliases of the same field get their own step
+			boundaryFieldPath := fieldPath
 			stepKey := fmt.Sprintf("%s:%s:%d:%s", targetSubGraph.Name, entityTypeToResolve, parentStep.ID, strings.Join(boundaryFieldPath, "."))
 
 			existingStep, exists := entityStepsByKey[stepKey]
@@ -446,8 +698,9 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 				} else {
 					// Reference: include only the children of the boundary field
 					entitySelections = p.buildEntityStepSelections(field.SelectionSet, targetSubGraph, entityTypeToResolve, parentStep, entityTypeToResolve, fragmentDefs)
-					// InsertionPath includes the boundary field (e.g., [Query, product, reviews, product])
-					insertionPath = append(currentPath, fieldName)
+					// InsertionPath includes the boundary field (e.g., [Query, product, reviews, product]),
+					// by response key, as the results are merged into the response
+					insertionPath = fieldPath
 				}
 
 				// Create new entity step
@@ -464,6 +717,9 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 				plan.Steps = append(plan.Steps, newStep)
 				entityStepsByKey[stepKey] = newStep
 				*nextStepID++
+				if entityTypeToResolve == parentType {
+					involved[targetSubGraph.Name] = true
+				}
 
 				// Inject key fields into parent step
 				// For the parent step to provide entity representations for the child step,
@@ -473,9 +729,9 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 				// Then we need to inject into "product" field → relative path = [product]
 				var relativePathForParent []string
 				if len(parentStep.InsertionPath) == 0 {
-					// Root step: InsertionPath is empty, currentPath starts with Query
-					// Remove the "Query" prefix to get the path within the SelectionSet
-					if len(currentPath) > 0 && currentPath[0] == "Query" {
+					// Root step: InsertionPath is empty, currentPath starts with the root type
+					// Remove the root type prefix to get the path within the SelectionSet
+					if len(currentPath) > 0 && p.SuperGraph.IsRootType(currentPath[0]) {
 						relativePathForParent = currentPath[1:]
 					} else {
 						relativePathForParent = currentPath
@@ -489,7 +745,7 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 				// Example: Review.product (reference) → inject into [reviews, product]
 				// But for Customer.accounts (extension) → inject into [customer], not [customer, accounts]
 				if isNestedEntity && entityTypeToResolve != parentType {
-					relativePathForParent = append(relativePathForParent, fieldName)
+					relativePathForParent = append(relativePathForParent, fieldIdentifier)
 				}
 
 				p.injectKeyFieldsIntoParentStep(parentStep, entityTypeToResolve, targetSubGraph, relativePathForParent)
@@ -501,11 +757,14 @@ func (p *PlannerV2) findAndBuildEntitySteps(
 					// For entity extensions: the nested selections are relative to the parent type
 					// For entity references: the nested selections are relative to the entity type
 					nestedParentType := entityTypeToResolve
+					var nestedProvided graph.FieldSet
 					if entityTypeToResolve == parentType {
-						// Extension case: fieldType is the type of the extension field
+						// Extension case: fieldType is the type of the extension field, and the
+						// target subgraph may provide some of its fields
 						nestedParentType = fieldType
+						nestedProvided = targetSubGraph.GetProvidedFields(parentType, fieldName)
 					}
-					p.findAndBuildEntitySteps(field.SelectionSet, newStep, plan, nextStepID, nestedParentType, fieldPath, fragmentDefs)
+					p.findAndBuildEntitySteps(field.SelectionSet, newStep, plan, nextStepID, nestedParentType, fieldPath, fragmentDefs, nestedProvided)
 				}
 			}
 		}
@@ -536,12 +795,7 @@ func (p *PlannerV2) buildEntityStepSelections(
 	// First, inject @key fields for the entity
 	keyFields := p.getKeyFields(entityType, subGraph)
 	for _, keyField := range keyFields {
-		result = append(result, &ast.Field{
-			Name: &ast.Name{
-				Token: token.Token{Type: token.IDENT, Literal: keyField},
-				Value: keyField,
-			},
-		})
+		result = append(result, newField(keyField))
 	}
 
 	// Process boundary fields - preserve the field structure with filtered children
@@ -564,27 +818,21 @@ func (p *PlannerV2) buildEntityStepSelections(
 		}
 
 		// Build new field with filtered child selections
-		newField := &ast.Field{
-			Alias:      field.Alias,
-			Name:       field.Name,
-			Arguments:  field.Arguments,
-			Directives: field.Directives,
-		}
+		stepField := copyField(field)
 
 		// Filter child selections by ownership for this subgraph
 		if len(field.SelectionSet) > 0 {
-			filteredChildren := p.buildStepSelections(field.SelectionSet, subGraph, fieldType, fragmentDefs)
-			newField.SelectionSet = filteredChildren
+			filteredChildren := p.buildStepSelections(field.SelectionSet, subGraph, fieldType, fragmentDefs, p.providedChildren(nil, subGraph, parentType, fieldName), "")
+			stepField.SelectionSet = filteredChildren
 
 			// Only include this field if it has children or if it's a leaf field
 			if len(filteredChildren) > 0 {
-				result = append(result, newField)
+				result = append(result, stepField)
 			}
 		} else {
-			// Leaf field - check if it's owned by this subgraph
-			fieldSubGraphs := p.SuperGraph.GetSubGraphsForField(entityType, fieldName)
-			if len(fieldSubGraphs) > 0 && fieldSubGraphs[0].Name == subGraph.Name {
-				result = append(result, newField)
+			// Leaf field - check if it's resolved by this subgraph
+			if owner := p.resolvingFieldSubGraph(entityType, field, subGraph, nil); owner != nil && owner.Name == subGraph.Name {
+				result = append(result, stepField)
 			}
 		}
 	}
@@ -596,7 +844,7 @@ func (p *PlannerV2) buildEntityStepSelections(
 func (p *PlannerV2) mergeSelections(existing, newSels []ast.Selection, subGraph *graph.SubGraphV2, parentType string, fragmentDefs map[string]*ast.FragmentDefinition) []ast.Selection {
 	// Simple implementation: just append and let buildStepSelections deduplicate later
 	merged := append(existing, newSels...)
-	return p.buildStepSelections(merged, subGraph, parentType, fragmentDefs)
+	return p.buildStepSelections(merged, subGraph, parentType, fragmentDefs, nil, parentType)
 }
 
 // getKeyFields returns the @key fields for an entity type.
@@ -634,71 +882,97 @@ func (p *PlannerV2) injectKeyFieldsIntoParentStep(parentStep *StepV2, entityType
 	}
 
 	// Use ensureAndInjectKeyFields to both create missing fields and inject key fields
-	parentStep.SelectionSet = p.ensureAndInjectKeyFields(parentStep.SelectionSet, insertionPath, keyFields)
+	parentStep.SelectionSet = p.ensureAndInjectKeyFields(parentStep.SelectionSet, parentStep.ParentType, insertionPath, entityType, keyFields)
 }
 
 // ensureAndInjectKeyFields recursively ensures fields in the path exist and injects key fields.
 // This function both creates missing boundary fields and injects key fields into them.
-func (p *PlannerV2) ensureAndInjectKeyFields(selections []ast.Selection, path []string, keyFields []string) []ast.Selection {
+// When the boundary field returns an abstract type, the key fields of entityType are
+// injected into an inline fragment on entityType, as they may not exist on the
+// abstract type.
+func (p *PlannerV2) ensureAndInjectKeyFields(selections []ast.Selection, parentType string, path []string, entityType string, keyFields []string) []ast.Selection {
 	if len(path) == 0 {
 		return selections
 	}
 
 	targetField := path[0]
-	var targetFieldNode *ast.Field
-
-	// Find the target field
-	for _, sel := range selections {
-		if field, ok := sel.(*ast.Field); ok {
-			fieldIdentifier := field.Name.String()
-			if field.Alias != nil && field.Alias.String() != "" {
-				fieldIdentifier = field.Alias.String()
-			}
-
-			if fieldIdentifier == targetField {
-				targetFieldNode = field
-				break
-			}
-		}
-	}
+	targetFieldNode, fieldParentType := findFieldByResponseKey(selections, parentType, targetField)
 
 	// If the field doesn't exist, create it
 	if targetFieldNode == nil {
-		targetFieldNode = &ast.Field{
-			Name: &ast.Name{
-				Token: token.Token{Type: token.IDENT, Literal: targetField},
-				Value: targetField,
-			},
-			SelectionSet: make([]ast.Selection, 0),
-		}
+		targetFieldNode = newField(targetField)
 		selections = append(selections, targetFieldNode)
+		fieldParentType = parentType
 	}
+	fieldType, _ := p.getFieldTypeName(fieldParentType, targetFieldNode.Name.String())
 
 	if len(path) == 1 {
 		// We've reached the boundary field, inject key fields into it
-		existingFields := make(map[string]bool)
-		for _, childSel := range targetFieldNode.SelectionSet {
-			if childField, ok := childSel.(*ast.Field); ok {
-				existingFields[childField.Name.String()] = true
+		if fieldType != entityType && p.SuperGraph.IsAbstractType(fieldType) {
+			fragment := findInlineFragment(targetFieldNode.SelectionSet, entityType)
+			if fragment == nil {
+				fragment = newInlineFragment(&ast.NamedType{Name: newName(entityType)}, nil, nil)
+				targetFieldNode.SelectionSet = append(targetFieldNode.SelectionSet, fragment)
 			}
+			targetFieldNode.SelectionSet = appendMissingFields(targetFieldNode.SelectionSet, []string{"__typename"})
+			fragment.SelectionSet = appendMissingFields(fragment.SelectionSet, keyFields)
+		} else {
+			targetFieldNode.SelectionSet = appendMissingFields(targetFieldNode.SelectionSet, keyFields)
 		}
+	} else {
+		// Continue navigating
+		targetFieldNode.SelectionSet = p.ensureAndInjectKeyFields(targetFieldNode.SelectionSet, fieldType, path[1:], entityType, keyFields)
+	}
+
+	return selections
+}
 
-		// Add missing key fields
-		for _, keyField := range keyFields {
-			if !existingFields[keyField] {
-				targetFieldNode.SelectionSet = append(targetFieldNode.SelectionSet, &ast.Field{
-					Name: &ast.Name{
-						Token: token.Token{Type: token.IDENT, Literal: keyField},
-						Value: keyField,
-					},
-				})
+// findFieldByResponseKey returns the field of selections with the given response key,
+// looking into inline fragments, and the type the field is selected on.
+func findFieldByResponseKey(selections []ast.Selection, parentType, key string) (*ast.Field, string) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if responseKey(s) == key {
+				return s, parentType
+			}
+		case *ast.InlineFragment:
+			fragmentType := parentType
+			if s.TypeCondition != nil {
+				fragmentType = s.TypeCondition.Name.String()
+			}
+			if field, fieldParentType := findFieldByResponseKey(s.SelectionSet, fragmentType, key); field != nil {
+				return field, fieldParentType
 			}
 		}
-	} else {
-		// Continue navigating
-		targetFieldNode.SelectionSet = p.ensureAndInjectKeyFields(targetFieldNode.SelectionSet, path[1:], keyFields)
 	}
+	return nil, ""
+}
 
+// findInlineFragment returns the inline fragment on typeCondition in selections.
+func findInlineFragment(selections []ast.Selection, typeCondition string) *ast.InlineFragment {
+	for _, sel := range selections {
+		if fragment, ok := sel.(*ast.InlineFragment); ok && fragment.TypeCondition != nil && fragment.TypeCondition.Name.String() == typeCondition {
+			return fragment
+		}
+	}
+	return nil
+}
+
+// appendMissingFields appends a leaf field for each name not yet selected in selections.
+func appendMissingFields(selections []ast.Selection, names []string) []ast.Selection {
+	existingFields := make(map[string]bool)
+	for _, sel := range selections {
+		if field, ok := sel.(*ast.Field); ok {
+			existingFields[field.Name.String()] = true
+		}
+	}
+
+	for _, name := range names {
+		if !existingFields[name] {
+			selections = append(selections, newField(name))
+		}
+	}
 	return selections
 }
 
@@ -736,35 +1010,15 @@ func (p *PlannerV2) getOperation(doc *ast.Document) *ast.OperationDefinition {
 	return nil
 }
 
-// getRootTypeName returns the root type name from an operation.
+// getRootTypeName returns the root type name from an operation, honouring custom
+// root type names declared with a schema definition.
 func (p *PlannerV2) getRootTypeName(op *ast.OperationDefinition) (string, error) {
-	var rootTypeName string
-
 	switch op.Operation {
-	case ast.Query:
-		rootTypeName = "Query"
-	case ast.Mutation:
-		rootTypeName = "Mutation"
-	case ast.Subscription:
-		rootTypeName = "Subscription"
+	case ast.Query, ast.Mutation, ast.Subscription:
+		return p.SuperGraph.RootTypeName(op.Operation), nil
 	default:
 		return "", fmt.Errorf("unknown operation type: %v", op.Operation)
 	}
-
-	// Get actual type name from SchemaDefinition
-	for _, def := range p.SuperGraph.Schema.Definitions {
-		if sd, ok := def.(*ast.SchemaDefinition); ok {
-			for _, ot := range sd.OperationTypes {
-				if (ot.Operation == token.QUERY && op.Operation == ast.Query) ||
-					(ot.Operation == token.MUTATION && op.Operation == ast.Mutation) ||
-					(ot.Operation == token.SUBSCRIPTION && op.Operation == ast.Subscription) {
-					rootTypeName = ot.Type.Name.String()
-				}
-			}
-		}
-	}
-
-	return rootTypeName, nil
 }
 
 // getFieldTypeName returns the type name of a field.
@@ -773,16 +1027,8 @@ func (p *PlannerV2) getFieldTypeName(parentTypeName, fieldName string) (string,
 		return "String", nil
 	}
 
-	for _, def := range p.SuperGraph.Schema.Definitions {
-		if td, ok := def.(*ast.ObjectTypeDefinition); ok {
-			if td.Name.String() == parentTypeName {
-				for _, field := range td.Fields {
-					if field.Name.String() == fieldName {
-						return p.getNamedType(field.Type), nil
-					}
-				}
-			}
-		}
+	if field := p.SuperGraph.FieldDefinition(parentTypeName, fieldName); field != nil {
+		return p.getNamedType(field.Type), nil
 	}
 
 	return "", fmt.Errorf("field %s not found in type %s", fieldName, parentTypeName)
@@ -802,113 +1048,6 @@ func (p *PlannerV2) getNamedType(t ast.Type) string {
 	}
 }
 
-// injectRequiresDependencies injects @requires fields into parent steps.
-// This ensures that required fields are fetched before they're needed by child steps.
-func (p *PlannerV2) injectRequiresDependencies(plan *PlanV2) {
-	// For each step, check if any field has @requires
-	for _, step := range plan.Steps {
-		// Only entity steps need dependency injection
-		if step.StepType != StepTypeEntity {
-			continue
-		}
-
-		// Get required fields for this step's selections
-		requiredFields := p.collectRequiredFields(step.SelectionSet, step.ParentType, step.SubGraph)
-
-		if len(requiredFields) == 0 {
-			continue
-		}
-
-		// Inject required fields into parent steps (steps this one depends on)
-		for _, parentStepID := range step.DependsOn {
-			parentStep := plan.Steps[parentStepID]
-
-			// Inject into the entity fields within parent step
-			// We need to find fields that return the entity type (step.ParentType)
-			p.injectFieldsIntoSelections(parentStep.SelectionSet, parentStep.ParentType, step.ParentType, requiredFields)
-		}
-	}
-}
-
-// injectFieldsIntoSelections recursively finds fields that return targetTypeName and injects required fields
-func (p *PlannerV2) injectFieldsIntoSelections(selections []ast.Selection, currentTypeName, targetTypeName string, fieldsToInject map[string]bool) {
-	for _, sel := range selections {
-		field, ok := sel.(*ast.Field)
-		if !ok {
-			continue
-		}
-
-		fieldName := field.Name.String()
-
-		// Skip meta fields
-		if fieldName == "__typename" {
-			continue
-		}
-
-		// Get the field's return type
-		fieldTypeName, err := p.getFieldTypeName(currentTypeName, fieldName)
-		if err != nil {
-			continue
-		}
-
-		// If this field's return type matches the target type, inject required fields here
-		if fieldTypeName == targetTypeName {
-			// Inject required fields into this field's selection set
-			for requiredFieldName := range fieldsToInject {
-				if !p.hasFieldInSelectionSet(field.SelectionSet, requiredFieldName) {
-					newField := &ast.Field{
-						Name: &ast.Name{Value: requiredFieldName},
-					}
-					field.SelectionSet = append(field.SelectionSet, newField)
-				}
-			}
-		}
-
-		// Recursively check nested selections
-		if len(field.SelectionSet) > 0 {
-			p.injectFieldsIntoSelections(field.SelectionSet, fieldTypeName, targetTypeName, fieldsToInject)
-		}
-	}
-}
-
-// collectRequiredFields collects all fields specified in @requires directives
-// for the given selection set.
-func (p *PlannerV2) collectRequiredFields(selections []ast.Selection, parentTypeName string, subGraph *graph.SubGraphV2) map[string]bool {
-	required := make(map[string]bool)
-
-	for _, sel := range selections {
-		field, ok := sel.(*ast.Field)
-		if !ok {
-			continue
-		}
-
-		fieldName := field.Name.String()
-
-		// Get entity metadata from subgraph
-		if entity, exists := subGraph.GetEntity(parentTypeName); exists {
-			if fieldMetadata, ok := entity.Fields[fieldName]; ok {
-				// Add all required fields
-				for _, reqField := range fieldMetadata.Requires {
-					required[reqField] = true
-				}
-			}
-		}
-
-		// Recursively check nested selections
-		if len(field.SelectionSet) > 0 {
-			fieldTypeName, err := p.getFieldTypeName(parentTypeName, fieldName)
-			if err == nil {
-				nestedRequired := p.collectRequiredFields(field.SelectionSet, fieldTypeName, subGraph)
-				for reqField := range nestedRequired {
-					required[reqField] = true
-				}
-			}
-		}
-	}
-
-	return required
-}
-
 // hasFieldInSelectionSet checks if a field with the given name exists in the selection set.
 func (p *PlannerV2) hasFieldInSelectionSet(selections []ast.Selection, fieldName string) bool {
 	for _, sel := range selections {
@@ -0,0 +1,176 @@
+package planner_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+func newBenchmarkPlanner(b *testing.B) *planner.PlannerV2 {
+	b.Helper()
+
+	schemas := []struct{ name, sdl string }{
+		{"products", `
+			type Product @key(fields: "upc") {
+				upc: String!
+				name: String!
+				price: Int!
+				weight: Int!
+			}
+
+			type Query {
+				topProducts(first: Int): [Product!]!
+			}
+		`},
+		{"inventory", `
+			extend type Product @key(fields: "upc") {
+				upc: String! @external
+				weight: Int! @external
+				price: Int! @external
+				inStock: Boolean!
+				shippingEstimate: Int! @requires(fields: "price weight")
+			}
+		`},
+		{"reviews", `
+			type Review @key(fields: "id") {
+				id: ID!
+				body: String!
+				author: User!
+				product: Product!
+			}
+
+			extend type User @key(fields: "id") {
+				id: ID! @external
+				reviews: [Review!]!
+			}
+
+			extend type Product @key(fields: "upc") {
+				upc: String! @external
+				reviews: [Review!]!
+			}
+		`},
+		{"accounts", `
+			type User @key(fields: "id") {
+				id: ID!
+				name: String!
+				username: String!
+			}
+
+			type Query {
+				me: User
+			}
+		`},
+	}
+
+	var subGraphs []*graph.SubGraphV2
+	for _, s := range schemas {
+		sg, err := graph.NewSubGraphV2(s.name, []byte(s.sdl), "http://"+s.name+".example.com")
+		if err != nil {
+			b.Fatalf("NewSubGraphV2 for %s failed: %v", s.name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		b.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	return planner.NewPlannerV2(superGraph)
+}
+
+func BenchmarkPlannerV2_Plan(b *testing.B) {
+	p := newBenchmarkPlanner(b)
+	doc := parseQuery(b, `
+		query {
+			topProducts(first: 5) {
+				upc
+				name
+				inStock
+				shippingEstimate
+				reviews {
+					body
+					author { name username }
+				}
+			}
+			me { name reviews { body product { name price } } }
+		}
+	`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Plan(doc, nil); err != nil {
+			b.Fatalf("Plan failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkPlannerV2_PlanLargeSchema plans against 50 subgraphs declaring 20 types each,
+// selecting fields declared last in the composed schema, where looking types and fields
+// up by scanning the schema costs the most.
+func BenchmarkPlannerV2_PlanLargeSchema(b *testing.B) {
+	const subGraphCount, typeCount = 50, 20
+
+	var subGraphs []*graph.SubGraphV2
+	for i := 0; i < subGraphCount; i++ {
+		var sdl strings.Builder
+		if i == 0 {
+			sdl.WriteString(`type Product @key(fields: "id") { id: ID! name: String! } type Query { product(id: ID!): Product }` + "\n")
+		} else {
+			fmt.Fprintf(&sdl, `extend type Product @key(fields: "id") { id: ID! @external detail%d: Detail%d_0 }`+"\n", i, i)
+		}
+		for j := 0; j < typeCount; j++ {
+			fmt.Fprintf(&sdl, "type Detail%d_%d { id: ID! label: String count: Int tags: [String] }\n", i, j)
+		}
+
+		name := fmt.Sprintf("subgraph%d", i)
+		sg, err := graph.NewSubGraphV2(name, []byte(sdl.String()), "http://"+name+".example.com")
+		if err != nil {
+			b.Fatalf("NewSubGraphV2 for %s failed: %v", name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		b.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	p := planner.NewPlannerV2(superGraph)
+
+	var query strings.Builder
+	query.WriteString(`query { product(id: "1") { id name`)
+	for i := subGraphCount - 5; i < subGraphCount; i++ {
+		fmt.Fprintf(&query, " detail%d { id label count tags }", i)
+	}
+	query.WriteString(" } }")
+	doc := parseQuery(b, query.String())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Plan(doc, nil); err != nil {
+			b.Fatalf("Plan failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkFieldAllocation compares allocating planner fields one by one with
+// allocating them from slabs.
+func BenchmarkFieldAllocation(b *testing.B) {
+	b.Run("heap", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sink = &ast.Field{Name: &ast.Name{Value: "id"}}
+		}
+	})
+	b.Run("slab", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sink = planner.NewFieldNode("id")
+		}
+	})
+}
+
+var sink *ast.Field
@@ -0,0 +1,113 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+func TestPlannerV2_FromContext(t *testing.T) {
+	usersSchema := `
+		type User @key(fields: "id") @context(name: "userContext") {
+			id: ID!
+			name: String!
+			cart: [Product!]!
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+		}
+
+		type Query {
+			users: [User!]!
+		}
+	`
+	prefsSchema := `
+		type User @key(fields: "id") {
+			id: ID!
+			currency: String!
+		}
+	`
+	productsSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			price(currency: String! @fromContext(field: "$userContext { currency }")): String!
+		}
+	`
+
+	var subGraphs []*graph.SubGraphV2
+	for _, s := range []struct{ name, sdl string }{
+		{"users", usersSchema},
+		{"prefs", prefsSchema},
+		{"products", productsSchema},
+	} {
+		sg, err := graph.NewSubGraphV2(s.name, []byte(s.sdl), "http://"+s.name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 failed for %s: %v", s.name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	p := parser.New(lexer.New(`{ users { name cart { price } } }`))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse error: %v", p.Errors())
+	}
+	plan, err := planner.NewPlannerV2(superGraph).Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var priceStep, contextStep *planner.StepV2
+	for _, step := range plan.Steps {
+		switch {
+		case step.SubGraph != nil && step.SubGraph.Name == "products":
+			priceStep = step
+		case step.SubGraph != nil && step.SubGraph.Name == "prefs":
+			contextStep = step
+		}
+	}
+	if priceStep == nil || contextStep == nil {
+		t.Fatalf("expected a products step and a prefs context step, got %d steps", len(plan.Steps))
+	}
+
+	if contextStep.ParentType != "User" || !hasFields(contextStep.SelectionSet, "currency") {
+		t.Errorf("expected the context step to fetch User.currency, got %s", contextStep.ParentType)
+	}
+	dependsOnContext := false
+	for _, dep := range priceStep.DependsOn {
+		dependsOnContext = dependsOnContext || dep == contextStep.ID
+	}
+	if !dependsOnContext {
+		t.Errorf("expected the products step to depend on the context step, got %v", priceStep.DependsOn)
+	}
+
+	if len(priceStep.Contexts) != 1 {
+		t.Fatalf("expected 1 context value, got %d", len(priceStep.Contexts))
+	}
+	value := priceStep.Contexts[0]
+	if value.Depth != 1 || !hasFields(value.Selection, "currency") {
+		t.Errorf("expected currency from the users at depth 1, got depth %d", value.Depth)
+	}
+
+	var price *ast.Field
+	for _, sel := range priceStep.SelectionSet {
+		if field, ok := sel.(*ast.Field); ok && field.Name.String() == "price" {
+			price = field
+		}
+	}
+	if price == nil || len(price.Arguments) != 1 || price.Arguments[0].Name.String() != "currency" {
+		t.Fatal("expected price to be selected with the currency argument")
+	}
+	if variable, ok := price.Arguments[0].Value.(*ast.Variable); !ok || variable.Name != value.Variable {
+		t.Errorf("expected currency to be set to $%s", value.Variable)
+	}
+}
@@ -0,0 +1,126 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+// TestPlannerV2_PlanEntities tests planning of representations received by the
+// gateway's own _entities field.
+func TestPlannerV2_PlanEntities(t *testing.T) {
+	productSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+
+	reviewSchema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review!]!
+		}
+
+		type Review {
+			id: ID!
+			body: String!
+		}
+	`
+
+	productSG, err := graph.NewSubGraphV2("products", []byte(productSchema), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for products: %v", err)
+	}
+	reviewSG, err := graph.NewSubGraphV2("reviews", []byte(reviewSchema), "http://reviews.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for reviews: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{productSG, reviewSG})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	query := `query ($representations: [_Any!]!) {
+		_entities(representations: $representations) {
+			... on Product {
+				name
+				reviews { body }
+			}
+		}
+	}`
+	doc := parser.New(lexer.New(query)).ParseDocument()
+
+	var entitiesField *ast.Field
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			entitiesField = op.SelectionSet[0].(*ast.Field)
+		}
+	}
+	fragment := entitiesField.SelectionSet[0].(*ast.InlineFragment)
+
+	representations := []map[string]interface{}{
+		{"__typename": "Product", "id": "1"},
+		{"__typename": "Product", "id": "2"},
+	}
+
+	p := planner.NewPlannerV2(superGraph)
+	plan, err := p.PlanEntities(doc, "Product", fragment.SelectionSet, representations)
+	if err != nil {
+		t.Fatalf("PlanEntities failed: %v", err)
+	}
+
+	if len(plan.RootStepIndexes) != 1 {
+		t.Fatalf("expected 1 root step, got %d", len(plan.RootStepIndexes))
+	}
+	root := plan.Steps[plan.RootStepIndexes[0]]
+	if root.StepType != planner.StepTypeRepresentations {
+		t.Errorf("expected root step to be a representations step, got %v", root.StepType)
+	}
+	if len(plan.Representations) != 2 {
+		t.Errorf("expected 2 representations in plan, got %d", len(plan.Representations))
+	}
+
+	subGraphsUsed := make(map[string]bool)
+	for _, step := range plan.Steps[1:] {
+		if step.StepType != planner.StepTypeEntity {
+			t.Errorf("step %d: expected entity step, got %v", step.ID, step.StepType)
+		}
+		if step.ParentType != "Product" {
+			t.Errorf("step %d: expected parent type Product, got %s", step.ID, step.ParentType)
+		}
+		subGraphsUsed[step.SubGraph.Name] = true
+	}
+	if !subGraphsUsed["products"] || !subGraphsUsed["reviews"] {
+		t.Errorf("expected entity steps for products and reviews, got %v", subGraphsUsed)
+	}
+}
+
+func TestPlannerV2_PlanEntities_NotEntity(t *testing.T) {
+	schema := `
+		type Query {
+			hello: String
+		}
+	`
+	sg, err := graph.NewSubGraphV2("hello", []byte(schema), "http://hello.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{sg})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	p := planner.NewPlannerV2(superGraph)
+	if _, err := p.PlanEntities(&ast.Document{}, "Query", nil, nil); err == nil {
+		t.Fatal("expected error for non-entity type")
+	}
+}
@@ -1,10 +1,12 @@
 package planner_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
 	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
 	"github.com/n9te9/graphql-parser/lexer"
 	"github.com/n9te9/graphql-parser/parser"
 )
@@ -92,3 +94,353 @@ func TestPlannerV2_InterfaceTypes(t *testing.T) {
 		t.Error("expected selection set to have selections")
 	}
 }
+
+// TestPlannerV2_InterfaceImplementationEntities tests that fields selected on the
+// implementations of an interface are resolved by one entity step per implementation.
+func TestPlannerV2_InterfaceImplementationEntities(t *testing.T) {
+	searchSchema := `
+		interface Media {
+			title: String!
+		}
+
+		type Book implements Media @key(fields: "isbn") {
+			isbn: ID!
+			title: String!
+		}
+
+		type Movie implements Media @key(fields: "id") {
+			id: ID!
+			title: String!
+		}
+
+		type Query {
+			search: [Media!]!
+		}
+	`
+	booksSchema := `
+		type Book @key(fields: "isbn") {
+			isbn: ID!
+			author: String!
+		}
+	`
+	moviesSchema := `
+		type Movie @key(fields: "id") {
+			id: ID!
+			director: String!
+		}
+	`
+
+	var subGraphs []*graph.SubGraphV2
+	for _, s := range []struct{ name, sdl string }{
+		{"search", searchSchema},
+		{"books", booksSchema},
+		{"movies", moviesSchema},
+	} {
+		sg, err := graph.NewSubGraphV2(s.name, []byte(s.sdl), "http://"+s.name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 for %s failed: %v", s.name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	l := lexer.New(`query { search { title ... on Book { author } ... on Movie { director } } }`)
+	parser := parser.New(l)
+	doc := parser.ParseDocument()
+	if len(parser.Errors()) > 0 {
+		t.Fatalf("parse error: %v", parser.Errors())
+	}
+
+	plan, err := planner.NewPlannerV2(superGraph).Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if len(plan.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(plan.Steps))
+	}
+
+	want := map[string]string{"books": "Book", "movies": "Movie"}
+	for _, step := range plan.Steps[1:] {
+		if step.StepType != planner.StepTypeEntity {
+			t.Errorf("expected step %d to be an entity step, got %v", step.ID, step.StepType)
+			continue
+		}
+		if want[step.SubGraph.Name] != step.ParentType {
+			t.Errorf("expected the %s step to resolve %s, got %s", step.SubGraph.Name, want[step.SubGraph.Name], step.ParentType)
+		}
+		if len(step.DependsOn) != 1 || step.DependsOn[0] != 0 {
+			t.Errorf("expected the %s step to depend on the root step, got %v", step.SubGraph.Name, step.DependsOn)
+		}
+		if strings.Join(step.InsertionPath, ".") != "Query.search" {
+			t.Errorf("expected the %s step to insert at Query.search, got %v", step.SubGraph.Name, step.InsertionPath)
+		}
+	}
+
+	search := plan.Steps[0].SelectionSet[0].(*ast.Field)
+	keys := map[string]string{"Book": "isbn", "Movie": "id"}
+	for _, sel := range search.SelectionSet {
+		fragment, ok := sel.(*ast.InlineFragment)
+		if !ok {
+			continue
+		}
+		typeName := fragment.TypeCondition.Name.String()
+		for _, fragmentSel := range fragment.SelectionSet {
+			if field, ok := fragmentSel.(*ast.Field); ok && field.Name.String() == keys[typeName] {
+				delete(keys, typeName)
+			}
+		}
+	}
+	if len(keys) != 0 {
+		t.Errorf("expected key fields to be selected in a fragment per implementation, missing %v", keys)
+	}
+}
+
+// TestPlannerV2_InterfaceFieldWithoutFragments tests that an interface field selected
+// without fragments, which the subgraph of the step does not resolve, is fetched from
+// the owner of each possible type.
+func TestPlannerV2_InterfaceFieldWithoutFragments(t *testing.T) {
+	searchSchema := `
+		interface Media {
+			id: ID!
+		}
+
+		type Book implements Media @key(fields: "id") {
+			id: ID!
+		}
+
+		type Movie implements Media @key(fields: "id") {
+			id: ID!
+		}
+
+		type Query {
+			search: [Media!]!
+		}
+	`
+	booksSchema := `
+		interface Media {
+			id: ID!
+			title: String!
+		}
+
+		type Book implements Media @key(fields: "id") {
+			id: ID!
+			title: String!
+		}
+	`
+	moviesSchema := `
+		type Movie @key(fields: "id") {
+			id: ID!
+			title: String!
+		}
+	`
+
+	var subGraphs []*graph.SubGraphV2
+	for _, s := range []struct{ name, sdl string }{
+		{"search", searchSchema},
+		{"books", booksSchema},
+		{"movies", moviesSchema},
+	} {
+		sg, err := graph.NewSubGraphV2(s.name, []byte(s.sdl), "http://"+s.name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 for %s failed: %v", s.name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	l := lexer.New(`query { search { id title } }`)
+	parser := parser.New(l)
+	doc := parser.ParseDocument()
+	if len(parser.Errors()) > 0 {
+		t.Fatalf("parse error: %v", parser.Errors())
+	}
+
+	plan, err := planner.NewPlannerV2(superGraph).Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if len(plan.Steps) != 3 {
+		t.Fatalf("expected a root step and an entity step per implementation, got %d steps", len(plan.Steps))
+	}
+	want := map[string]string{"books": "Book", "movies": "Movie"}
+	for _, step := range plan.Steps[1:] {
+		if want[step.SubGraph.Name] != step.ParentType {
+			t.Errorf("expected the %s step to resolve %s, got %s", step.SubGraph.Name, want[step.SubGraph.Name], step.ParentType)
+		}
+		if strings.Join(step.InsertionPath, ".") != "Query.search" {
+			t.Errorf("expected the %s step to insert at Query.search, got %v", step.SubGraph.Name, step.InsertionPath)
+		}
+	}
+	if root := plan.Steps[0]; root.SubGraph.Name != "search" {
+		t.Errorf("expected the root step on search, got %s", root.SubGraph.Name)
+	}
+}
+
+// TestPlannerV2_NodeFanOutRestrictsNestedFragments tests that a root field fanned out to
+// the subgraphs implementing its possible types is sent to each of them without the
+// fragments on types it cannot return, at any depth.
+func TestPlannerV2_NodeFanOutRestrictsNestedFragments(t *testing.T) {
+	usersSchema := `
+		interface Node {
+			id: ID!
+		}
+
+		interface Content {
+			id: ID!
+		}
+
+		type Post implements Content @key(fields: "id") {
+			id: ID!
+			title: String!
+		}
+
+		type User implements Node @key(fields: "id") {
+			id: ID!
+			username: String!
+			feed: [Content!]!
+		}
+
+		type Query {
+			node(id: ID!): Node @shareable
+		}
+	`
+	productsSchema := `
+		interface Node {
+			id: ID!
+		}
+
+		interface Content {
+			id: ID!
+		}
+
+		type Video implements Content @key(fields: "id") {
+			id: ID!
+			url: String!
+		}
+
+		type Product implements Node @key(fields: "id") {
+			id: ID!
+			name: String!
+			clips: [Content!]!
+		}
+
+		type Query {
+			node(id: ID!): Node @shareable
+		}
+	`
+
+	var subGraphs []*graph.SubGraphV2
+	for _, s := range []struct{ name, sdl string }{
+		{"users", usersSchema},
+		{"products", productsSchema},
+	} {
+		sg, err := graph.NewSubGraphV2(s.name, []byte(s.sdl), "http://"+s.name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 for %s failed: %v", s.name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	l := lexer.New(`query {
+		node(id: "1") {
+			id
+			... on User {
+				username
+				feed {
+					id
+					... on Post { title }
+					... on Video { url }
+				}
+			}
+			... on Product {
+				name
+				clips {
+					... on Video { url }
+					... on Post { title }
+				}
+			}
+		}
+	}`)
+	parser := parser.New(l)
+	doc := parser.ParseDocument()
+	if len(parser.Errors()) > 0 {
+		t.Fatalf("parse error: %v", parser.Errors())
+	}
+
+	plan, err := planner.NewPlannerV2(superGraph).Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	want := map[string][]string{
+		"users":    {"User", "Post"},
+		"products": {"Product", "Video"},
+	}
+	roots := 0
+	for _, step := range plan.Steps {
+		if step.StepType != planner.StepTypeQuery {
+			continue
+		}
+		roots++
+		got := typeConditions(step.SelectionSet)
+		if strings.Join(got, ",") != strings.Join(want[step.SubGraph.Name], ",") {
+			t.Errorf("expected the %s step to select fragments on %v, got %v", step.SubGraph.Name, want[step.SubGraph.Name], got)
+		}
+	}
+	if roots != 2 {
+		t.Errorf("expected node to be sent to both subgraphs, got %d root steps", roots)
+	}
+}
+
+// typeConditions returns the type conditions of the inline fragments of selections, at
+// any depth, in document order.
+func typeConditions(selections []ast.Selection) []string {
+	var types []string
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			types = append(types, typeConditions(s.SelectionSet)...)
+		case *ast.InlineFragment:
+			if s.TypeCondition != nil {
+				types = append(types, s.TypeCondition.Name.String())
+			}
+			types = append(types, typeConditions(s.SelectionSet)...)
+		}
+	}
+	return types
+}
+
+func TestContainsAllTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		types    []string
+		required []string
+		want     bool
+	}{
+		{"same types", []string{"User", "Product"}, []string{"Product", "User"}, true},
+		{"more types", []string{"User", "Product", "Review"}, []string{"Product", "User"}, true},
+		{"as many other types", []string{"User", "Secret"}, []string{"Product", "User"}, false},
+		{"fewer types", []string{"User"}, []string{"Product", "User"}, false},
+		{"nothing required", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planner.ContainsAllTypes(tt.types, tt.required); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -150,3 +150,109 @@ func TestPlannerV2_QueryOperationType(t *testing.T) {
 		t.Fatalf("Expected at least 1 step, got %d", len(plan.Steps))
 	}
 }
+
+// TestPlannerV2_MutationSerialRootSteps tests that root mutation fields are split into
+// root steps that preserve document order, grouping only consecutive fields of one subgraph.
+func TestPlannerV2_MutationSerialRootSteps(t *testing.T) {
+	productsSchema := `
+		type Product {
+			id: ID!
+		}
+
+		type Query {
+			products: [Product!]!
+		}
+
+		type Mutation {
+			createProduct(name: String!): Product
+			renameProduct(id: ID!, name: String!): Product
+			deleteProduct(id: ID!): Boolean
+		}
+	`
+	accountsSchema := `
+		type User {
+			id: ID!
+		}
+
+		type Query {
+			me: User
+		}
+
+		type Mutation {
+			createUser(name: String!): User
+		}
+	`
+
+	products, err := graph.NewSubGraphV2("products", []byte(productsSchema), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	accounts, err := graph.NewSubGraphV2("accounts", []byte(accountsSchema), "http://accounts.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{products, accounts})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	p := planner.NewPlannerV2(superGraph)
+
+	query := `
+		mutation {
+			createProduct(name: "Widget") { id }
+			renameProduct(id: "1", name: "Gadget") { id }
+			createUser(name: "Alice") { id }
+			deleteProduct(id: "2")
+		}
+	`
+
+	l := lexer.New(query)
+	parser := parser.New(l)
+	doc := parser.ParseDocument()
+	if len(parser.Errors()) > 0 {
+		t.Fatalf("parse error: %v", parser.Errors())
+	}
+
+	plan, err := p.Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	expected := []struct {
+		subGraph string
+		fields   []string
+	}{
+		{subGraph: "products", fields: []string{"createProduct", "renameProduct"}},
+		{subGraph: "accounts", fields: []string{"createUser"}},
+		{subGraph: "products", fields: []string{"deleteProduct"}},
+	}
+
+	if len(plan.RootStepIndexes) != len(expected) {
+		t.Fatalf("Expected %d root steps, got %d", len(expected), len(plan.RootStepIndexes))
+	}
+
+	for i, want := range expected {
+		step := plan.Steps[plan.RootStepIndexes[i]]
+		if step.SubGraph.Name != want.subGraph {
+			t.Errorf("root step %d: expected subgraph %s, got %s", i, want.subGraph, step.SubGraph.Name)
+		}
+		fields := make([]string, 0, len(step.SelectionSet))
+		for _, sel := range step.SelectionSet {
+			if field, ok := sel.(*ast.Field); ok {
+				fields = append(fields, field.Name.String())
+			}
+		}
+		if len(fields) != len(want.fields) {
+			t.Errorf("root step %d: expected fields %v, got %v", i, want.fields, fields)
+			continue
+		}
+		for j := range fields {
+			if fields[j] != want.fields[j] {
+				t.Errorf("root step %d: expected fields %v, got %v", i, want.fields, fields)
+				break
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
 	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
 	"github.com/n9te9/graphql-parser/lexer"
 	"github.com/n9te9/graphql-parser/parser"
 )
@@ -140,3 +141,148 @@ func TestPlannerV2_NestedRequires(t *testing.T) {
 		t.Logf("Step %d: SubGraph=%s, Type=%v", i, subgraphName, step.StepType)
 	}
 }
+
+// TestPlannerV2_RequiresChain tests that a chain of @requires across subgraphs is planned
+// as a sequence of steps, each fetching the fields the next one requires.
+func TestPlannerV2_RequiresChain(t *testing.T) {
+	userSchema := `
+		type User @key(fields: "id") {
+			id: ID!
+			username: String!
+		}
+
+		type Query {
+			user(id: ID!): User
+		}
+	`
+
+	purchaseSchema := `
+		type User @key(fields: "id") {
+			id: ID!
+			purchaseHistory: [Purchase!]!
+		}
+
+		type Purchase {
+			id: ID!
+			productId: ID!
+		}
+	`
+
+	reviewSchema := `
+		extend type User @key(fields: "id") {
+			id: ID! @external
+			purchaseHistory: [Purchase!]! @external
+			reviews: [Review!]! @requires(fields: "purchaseHistory { productId }")
+		}
+
+		type Purchase {
+			productId: ID!
+		}
+
+		type Review {
+			id: ID!
+			rating: Int!
+		}
+	`
+
+	analyticsSchema := `
+		extend type User @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review!]! @external
+			reviewCount: Int! @requires(fields: "reviews { rating }")
+		}
+
+		type Review {
+			rating: Int!
+		}
+	`
+
+	var subGraphs []*graph.SubGraphV2
+	for _, s := range []struct{ name, sdl string }{
+		{"users", userSchema},
+		{"purchases", purchaseSchema},
+		{"reviews", reviewSchema},
+		{"analytics", analyticsSchema},
+	} {
+		sg, err := graph.NewSubGraphV2(s.name, []byte(s.sdl), "http://"+s.name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 for %s failed: %v", s.name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	l := lexer.New(`query { user(id: "1") { username reviewCount } }`)
+	parser := parser.New(l)
+	doc := parser.ParseDocument()
+	if len(parser.Errors()) > 0 {
+		t.Fatalf("parse error: %v", parser.Errors())
+	}
+
+	plan, err := planner.NewPlannerV2(superGraph).Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	steps := make(map[string]*planner.StepV2)
+	for _, step := range plan.Steps {
+		if step.SubGraph == nil {
+			continue
+		}
+		if _, dup := steps[step.SubGraph.Name]; dup {
+			t.Fatalf("expected one step per subgraph, got another for %s", step.SubGraph.Name)
+		}
+		steps[step.SubGraph.Name] = step
+	}
+	if len(steps) != 4 {
+		t.Fatalf("expected a step on each of the 4 subgraphs, got %d", len(steps))
+	}
+
+	dependsOn := func(step, dep *planner.StepV2) bool {
+		for _, id := range step.DependsOn {
+			if id == dep.ID {
+				return true
+			}
+		}
+		return false
+	}
+	chain := []string{"users", "purchases", "reviews", "analytics"}
+	for i := 1; i < len(chain); i++ {
+		if !dependsOn(steps[chain[i]], steps[chain[i-1]]) {
+			t.Errorf("expected the %s step to depend on the %s step, got %v", chain[i], chain[i-1], steps[chain[i]].DependsOn)
+		}
+	}
+
+	requires := map[string]string{"reviews": "purchaseHistory", "analytics": "reviews"}
+	for name, field := range requires {
+		step := steps[name]
+		if len(step.Requires) != 1 || step.Requires[0].(*ast.Field).Name.String() != field {
+			t.Errorf("expected the %s step to send %s in its representations, got %v", name, field, step.Requires)
+		}
+		if !hasField(steps[chain[indexOf(chain, name)-1]].SelectionSet, field) {
+			t.Errorf("expected the step before %s to fetch %s", name, field)
+		}
+	}
+}
+
+func hasField(selections []ast.Selection, name string) bool {
+	for _, sel := range selections {
+		if field, ok := sel.(*ast.Field); ok && field.Name.String() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOf(values []string, v string) int {
+	for i, value := range values {
+		if value == v {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,142 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+func newNestedProvidesPlanner(t *testing.T) *planner.PlannerV2 {
+	t.Helper()
+
+	productSchema := `
+		type Product @key(fields: "upc") {
+			upc: String!
+			name: String!
+			price: Int!
+			manufacturer: Manufacturer!
+		}
+
+		type Manufacturer @key(fields: "id") {
+			id: ID!
+			name: String!
+			country: String!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	reviewSchema := `
+		type Review {
+			body: String!
+			product: Product! @provides(fields: "name manufacturer { name }")
+		}
+
+		extend type Product @key(fields: "upc") {
+			upc: String! @external
+			name: String! @external
+			manufacturer: Manufacturer! @external
+		}
+
+		extend type Manufacturer @key(fields: "id") {
+			id: ID! @external
+			name: String! @external
+		}
+
+		type Query {
+			reviews: [Review!]!
+		}
+	`
+
+	var subGraphs []*graph.SubGraphV2
+	for _, s := range []struct{ name, sdl string }{
+		{"products", productSchema},
+		{"reviews", reviewSchema},
+	} {
+		sg, err := graph.NewSubGraphV2(s.name, []byte(s.sdl), "http://"+s.name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 for %s failed: %v", s.name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	return planner.NewPlannerV2(superGraph)
+}
+
+// TestPlannerV2_NestedProvides tests that fields provided through a nested @provides
+// field set are resolved by the providing subgraph instead of an entity fetch.
+func TestPlannerV2_NestedProvides(t *testing.T) {
+	p := newNestedProvidesPlanner(t)
+
+	plan, err := p.Plan(parseQuery(t, `{ reviews { body product { upc name manufacturer { name } } } }`), nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Fatalf("expected provided fields to need no entity fetch, got %d steps", len(plan.Steps))
+	}
+	for _, path := range [][]string{
+		{"reviews", "product", "upc"},
+		{"reviews", "product", "name"},
+		{"reviews", "product", "manufacturer", "name"},
+	} {
+		if findSelectedField(plan.Steps[0].SelectionSet, path...) == nil {
+			t.Errorf("expected the reviews step to select %v", path)
+		}
+	}
+}
+
+func TestPlannerV2_NestedProvides_Partial(t *testing.T) {
+	p := newNestedProvidesPlanner(t)
+
+	plan, err := p.Plan(parseQuery(t, `{ reviews { product { name price manufacturer { name country } } } }`), nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("expected a reviews step and a products step, got %d steps", len(plan.Steps))
+	}
+
+	reviews, products := plan.Steps[0], plan.Steps[1]
+	if products.SubGraph.Name != "products" || products.StepType != planner.StepTypeEntity {
+		t.Fatalf("expected the second step to be a products entity step, got %s", products.SubGraph.Name)
+	}
+	for _, path := range [][]string{
+		{"reviews", "product", "name"},
+		{"reviews", "product", "manufacturer", "name"},
+	} {
+		if findSelectedField(reviews.SelectionSet, path...) == nil {
+			t.Errorf("expected the reviews step to select %v", path)
+		}
+	}
+	for _, path := range [][]string{{"price"}, {"manufacturer", "country"}} {
+		if findSelectedField(products.SelectionSet, path...) == nil {
+			t.Errorf("expected the products step to select %v", path)
+		}
+	}
+	for _, path := range [][]string{{"name"}, {"manufacturer", "name"}} {
+		if findSelectedField(products.SelectionSet, path...) != nil {
+			t.Errorf("expected the products step not to fetch provided field %v", path)
+		}
+	}
+}
+
+func findSelectedField(selections []ast.Selection, path ...string) *ast.Field {
+	for _, sel := range selections {
+		field, ok := sel.(*ast.Field)
+		if !ok || field.Name.String() != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return field
+		}
+		return findSelectedField(field.SelectionSet, path[1:]...)
+	}
+	return nil
+}
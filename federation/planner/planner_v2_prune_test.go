@@ -0,0 +1,75 @@
+package planner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+func TestPlannerV2_PruneUnfetchable(t *testing.T) {
+	const query = `query { serverTime legacy: removedField }`
+
+	t.Run("strict", func(t *testing.T) {
+		p := newCostTestPlanner(t)
+		ps := parser.New(lexer.New(query))
+		doc := ps.ParseDocument()
+		if _, err := p.Plan(doc, nil); err == nil {
+			t.Fatal("expected an error for a root field without subgraph")
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		p := newCostTestPlanner(t)
+		p.PruneUnfetchable = true
+
+		plan := planQuery(t, p, query)
+		if len(plan.Steps) != 1 {
+			t.Fatalf("expected 1 step for serverTime, got %d", len(plan.Steps))
+		}
+		want := []planner.PrunedField{{ResponseKey: "legacy", Coordinate: "Query.removedField"}}
+		if len(plan.PrunedFields) != 1 || plan.PrunedFields[0] != want[0] {
+			t.Errorf("expected pruned fields %v, got %v", want, plan.PrunedFields)
+		}
+	})
+
+	t.Run("every field pruned", func(t *testing.T) {
+		p := newCostTestPlanner(t)
+		p.PruneUnfetchable = true
+
+		plan := planQuery(t, p, `query { removedField }`)
+		if len(plan.Steps) != 0 || len(plan.PrunedFields) != 1 {
+			t.Errorf("expected no steps and 1 pruned field, got %d steps and %v", len(plan.Steps), plan.PrunedFields)
+		}
+	})
+}
+
+func TestPlannerV2_PlanContext(t *testing.T) {
+	const query = `query { topProducts { name reviewCount } }`
+
+	t.Run("live context", func(t *testing.T) {
+		p := newCostTestPlanner(t)
+		doc := parser.New(lexer.New(query)).ParseDocument()
+		plan, err := p.PlanContext(context.Background(), doc, nil)
+		if err != nil {
+			t.Fatalf("PlanContext failed: %v", err)
+		}
+		if want := planQuery(t, p, query); len(plan.Steps) != len(want.Steps) {
+			t.Errorf("expected the %d steps of Plan, got %d", len(want.Steps), len(plan.Steps))
+		}
+	})
+
+	t.Run("done context", func(t *testing.T) {
+		p := newCostTestPlanner(t)
+		doc := parser.New(lexer.New(query)).ParseDocument()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		plan, err := p.PlanContext(ctx, doc, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got plan %v and error %v", plan, err)
+		}
+	})
+}
@@ -116,3 +116,109 @@ func TestPlannerV2_RequiresDependencyInjection(t *testing.T) {
 		t.Error("Expected 'weight' field to be injected into product field's selection set due to @requires, but it was not found")
 	}
 }
+
+// TestPlannerV2_RequiresOnRootFieldOfComputingSubgraph tests that a @requires field of an
+// entity returned by a root field of the computing subgraph is resolved by an entity step
+// depending on the step fetching the required fields, whichever subgraph owns the entity.
+func TestPlannerV2_RequiresOnRootFieldOfComputingSubgraph(t *testing.T) {
+	shippingSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			price: Float! @external
+			weight: Float! @external
+			shippingEstimate: Float! @requires(fields: "price weight")
+		}
+
+		type Query {
+			cheapest: Product
+		}
+	`
+	productSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			price: Float!
+			weight: Float!
+		}
+	`
+
+	shippingSG, err := graph.NewSubGraphV2("shipping", []byte(shippingSchema), "http://shipping.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for shipping: %v", err)
+	}
+	productSG, err := graph.NewSubGraphV2("products", []byte(productSchema), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for products: %v", err)
+	}
+
+	orders := map[string][]*graph.SubGraphV2{
+		"shipping first": {shippingSG, productSG},
+		"products first": {productSG, shippingSG},
+	}
+	for name, subGraphs := range orders {
+		t.Run(name, func(t *testing.T) {
+			superGraph, err := graph.NewSuperGraphV2(subGraphs)
+			if err != nil {
+				t.Fatalf("NewSuperGraphV2 failed: %v", err)
+			}
+
+			doc := parser.New(lexer.New(`{ cheapest { shippingEstimate } }`)).ParseDocument()
+			plan, err := planner.NewPlannerV2(superGraph).Plan(doc, nil)
+			if err != nil {
+				t.Fatalf("Plan failed: %v", err)
+			}
+
+			root := plan.Steps[plan.RootStepIndexes[0]]
+			cheapest := root.SelectionSet[0].(*ast.Field)
+			for _, sel := range cheapest.SelectionSet {
+				if sel.(*ast.Field).Name.String() == "shippingEstimate" {
+					t.Errorf("expected the root step not to select shippingEstimate")
+				}
+			}
+
+			var computing, requires *planner.StepV2
+			for _, step := range plan.Steps {
+				if step.StepType != planner.StepTypeEntity {
+					continue
+				}
+				if step.SubGraph.Name == "shipping" {
+					computing = step
+				} else {
+					requires = step
+				}
+			}
+			if computing == nil || requires == nil {
+				t.Fatalf("expected entity steps on shipping and products, got %d steps", len(plan.Steps))
+			}
+			if len(computing.Requires) != 2 {
+				t.Errorf("expected price and weight as requires, got %d fields", len(computing.Requires))
+			}
+			dependsOnRequires := false
+			for _, dep := range computing.DependsOn {
+				dependsOnRequires = dependsOnRequires || dep == requires.ID
+			}
+			if !dependsOnRequires {
+				t.Errorf("expected the shipping step to depend on the products step, got %v", computing.DependsOn)
+			}
+			if !hasFields(requires.SelectionSet, "price", "weight") {
+				t.Errorf("expected the products step to fetch price and weight")
+			}
+		})
+	}
+}
+
+// hasFields reports whether selections select every field of names.
+func hasFields(selections []ast.Selection, names ...string) bool {
+	for _, name := range names {
+		found := false
+		for _, sel := range selections {
+			if field, ok := sel.(*ast.Field); ok && field.Name.String() == name {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+// TestPlannerV2_CustomRootTypeName tests that a query planned against subgraphs that
+// rename the query root type uses the custom name for root and entity steps.
+func TestPlannerV2_CustomRootTypeName(t *testing.T) {
+	productsSchema := `
+		schema {
+			query: RootQuery
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type RootQuery {
+			products: [Product]
+		}
+	`
+	reviewsSchema := `
+		schema {
+			query: RootQuery
+		}
+
+		type Review {
+			body: String!
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review]
+		}
+	`
+
+	productsSG, err := graph.NewSubGraphV2("products", []byte(productsSchema), "http://products.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for products: %v", err)
+	}
+	reviewsSG, err := graph.NewSubGraphV2("reviews", []byte(reviewsSchema), "http://reviews.example.com")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed for reviews: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{productsSG, reviewsSG})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	query := `
+		query {
+			products {
+				name
+				reviews {
+					body
+				}
+			}
+		}
+	`
+	l := lexer.New(query)
+	ps := parser.New(l)
+	doc := ps.ParseDocument()
+	if len(ps.Errors()) > 0 {
+		t.Fatalf("parse error: %v", ps.Errors())
+	}
+
+	plan, err := planner.NewPlannerV2(superGraph).Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(plan.Steps))
+	}
+
+	root := plan.Steps[0]
+	if root.ParentType != "RootQuery" {
+		t.Errorf("expected root step ParentType 'RootQuery', got %q", root.ParentType)
+	}
+	if root.SubGraph == nil || root.SubGraph.Name != "products" {
+		t.Errorf("expected root step to target 'products', got %v", root.SubGraph)
+	}
+
+	// The key field must be injected into the root selection for the entity hop.
+	products, ok := root.SelectionSet[0].(*ast.Field)
+	if !ok || products.Name.String() != "products" {
+		t.Fatalf("expected root selection 'products', got %v", root.SelectionSet)
+	}
+	hasID := false
+	for _, sel := range products.SelectionSet {
+		if f, ok := sel.(*ast.Field); ok && f.Name.String() == "id" {
+			hasID = true
+		}
+	}
+	if !hasID {
+		t.Error("expected key field 'id' to be injected into products selection")
+	}
+
+	entity := plan.Steps[1]
+	if entity.SubGraph == nil || entity.SubGraph.Name != "reviews" || entity.ParentType != "Product" {
+		t.Errorf("expected Product entity step on 'reviews', got %q on %v", entity.ParentType, entity.SubGraph)
+	}
+}
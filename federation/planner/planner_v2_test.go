@@ -1,6 +1,7 @@
 package planner_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
@@ -734,18 +735,68 @@ func TestPlannerV2_MultiProductsWithAliases(t *testing.T) {
 		t.Errorf("step 0: expected 2 selections (p1 and p2), got %d", len(plan.Steps[0].SelectionSet))
 	}
 
-	// Step 1 以降: Review サービス（エイリアスごとに分かれる、または統合される）
-	hasReviewStep := false
+	// Step 1 以降: Review サービス（エイリアスごとの挿入パスを1つのリクエストにまとめる）
+	var insertionPaths []string
 	for i := 1; i < len(plan.Steps); i++ {
 		if plan.Steps[i].SubGraph.Name == "review" {
-			hasReviewStep = true
+			for _, path := range plan.Steps[i].InsertionPaths() {
+				insertionPaths = append(insertionPaths, strings.Join(path, "."))
+			}
 			if plan.Steps[i].StepType != planner.StepTypeEntity {
 				t.Errorf("step %d: expected StepTypeEntity for review service, got %v", i, plan.Steps[i].StepType)
 			}
 		}
 	}
 
-	if !hasReviewStep {
-		t.Error("expected at least one review service step")
+	if len(insertionPaths) != 2 || insertionPaths[0] != "Query.p1" || insertionPaths[1] != "Query.p2" {
+		t.Errorf("expected the review step to be inserted at each alias, got insertion paths %v", insertionPaths)
+	}
+}
+
+// TestPlannerV2_RootStepsFollowDocumentOrder tests that root steps are created in the
+// order their subgraphs are first referenced by the document, so plans are deterministic.
+func TestPlannerV2_RootStepsFollowDocumentOrder(t *testing.T) {
+	schemas := map[string]string{
+		"a": `type Query { a1: String a2: String }`,
+		"b": `type Query { b1: String }`,
+		"c": `type Query { c1: String }`,
+	}
+
+	subGraphs := make([]*graph.SubGraphV2, 0, len(schemas))
+	for _, name := range []string{"a", "b", "c"} {
+		sg, err := graph.NewSubGraphV2(name, []byte(schemas[name]), "http://"+name)
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 failed: %v", err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	p := planner.NewPlannerV2(superGraph)
+
+	for i := 0; i < 20; i++ {
+		l := lexer.New(`{ c1 a1 b1 a2 }`)
+		ps := parser.New(l)
+		doc := ps.ParseDocument()
+		if len(ps.Errors()) > 0 {
+			t.Fatalf("parse error: %v", ps.Errors())
+		}
+
+		plan, err := p.Plan(doc, nil)
+		if err != nil {
+			t.Fatalf("Plan failed: %v", err)
+		}
+
+		got := make([]string, 0, len(plan.RootStepIndexes))
+		for _, idx := range plan.RootStepIndexes {
+			got = append(got, plan.Steps[idx].SubGraph.Name)
+		}
+		if len(got) != 3 || got[0] != "c" || got[1] != "a" || got[2] != "b" {
+			t.Fatalf("expected root steps [c a b], got %v", got)
+		}
 	}
 }
@@ -0,0 +1,90 @@
+package planner
+
+import (
+	"strings"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// providedChildren returns the fields subGraph provides for the object returned by
+// parentType.fieldName: the nested selections of fieldName in provided, merged with the
+// @provides field set subGraph declares on the field itself. A subgraph providing fields
+// of an entity can also return its keys, so they are included as well.
+func (p *PlannerV2) providedChildren(provided graph.FieldSet, subGraph *graph.SubGraphV2, parentType, fieldName string) graph.FieldSet {
+	var children graph.FieldSet
+	if field, ok := provided.Field(fieldName); ok {
+		children = mergeFieldSets(children, field.Selections)
+	}
+	if subGraph == nil {
+		return children
+	}
+	children = mergeFieldSets(children, subGraph.GetProvidedFields(parentType, fieldName))
+	if len(children) == 0 {
+		return nil
+	}
+
+	fieldType, err := p.getFieldTypeName(parentType, fieldName)
+	if err != nil {
+		return children
+	}
+	if entity, ok := subGraph.GetEntity(fieldType); ok {
+		for _, key := range entity.Keys {
+			children = mergeFieldSets(children, graph.ParseFieldSet(key.FieldSet))
+		}
+	}
+	return children
+}
+
+// mergeFieldSets returns a with the fields of b added, merging the nested selections of
+// fields present in both.
+func mergeFieldSets(a, b graph.FieldSet) graph.FieldSet {
+	if len(b) == 0 {
+		return a
+	}
+	merged := make(graph.FieldSet, 0, len(a)+len(b))
+	merged = append(merged, a...)
+	for _, field := range b {
+		existing, ok := merged.Field(field.Name)
+		if !ok {
+			merged = append(merged, field)
+			continue
+		}
+		combined := &graph.FieldSetField{Name: field.Name, Selections: mergeFieldSets(existing.Selections, field.Selections)}
+		for i, f := range merged {
+			if f == existing {
+				merged[i] = combined
+			}
+		}
+	}
+	return merged
+}
+
+// splitProvidedSelections splits the selections of an entity returned by a field into
+// those provided by the step resolving the field and the remaining ones, which need an
+// entity fetch from the entity's owner. Meta fields such as __typename are left out of
+// both.
+func splitProvidedSelections(selections []ast.Selection, provided graph.FieldSet) ([]ast.Selection, []ast.Selection) {
+	if len(provided) == 0 {
+		return nil, selections
+	}
+
+	var providedSelections, remaining []ast.Selection
+	for _, sel := range selections {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			remaining = append(remaining, sel)
+			continue
+		}
+		name := field.Name.String()
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		if _, isProvided := provided.Field(name); isProvided {
+			providedSelections = append(providedSelections, sel)
+		} else {
+			remaining = append(remaining, sel)
+		}
+	}
+	return providedSelections, remaining
+}
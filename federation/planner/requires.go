@@ -0,0 +1,215 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// injectRequiresDependencies makes the @requires fields of every entity step available
+// in the representations it sends. A required field the parent step can resolve is
+// injected into the parent's selections; otherwise a requires step fetching it from its
+// owner is added at the same insertion path and the entity step depends on it. Steps
+// added here are processed in turn, so chains of @requires across any number of
+// subgraphs resolve hop by hop.
+func (p *PlannerV2) injectRequiresDependencies(plan *PlanV2) {
+	requiresSteps := make(map[string]*StepV2)
+
+	for i := 0; i < len(plan.Steps); i++ {
+		step := plan.Steps[i]
+		if step.StepType != StepTypeEntity || len(step.DependsOn) == 0 || step.SubGraph == nil {
+			continue
+		}
+
+		required := p.collectRequiredFields(step.SelectionSet, step.ParentType, step.SubGraph)
+		if len(required) == 0 {
+			continue
+		}
+		step.Requires = required
+
+		parent := plan.Steps[step.DependsOn[0]]
+		for _, sel := range required {
+			field, ok := sel.(*ast.Field)
+			if !ok {
+				continue
+			}
+			fieldName := field.Name.String()
+
+			if parent.SubGraph != nil && p.canResolveField(step.ParentType, fieldName, parent.SubGraph) {
+				p.provideFields(parent, step, []ast.Selection{field})
+				continue
+			}
+
+			owner := p.resolvingSubGraph(step.ParentType, fieldName, nil, nil)
+			if owner == nil {
+				continue
+			}
+
+			key := fmt.Sprintf("%s:%s:%d:%s", owner.Name, step.ParentType, parent.ID, strings.Join(step.InsertionPath, "."))
+			requiresStep, exists := requiresSteps[key]
+			if !exists {
+				requiresStep = &StepV2{
+					ID:            len(plan.Steps),
+					SubGraph:      owner,
+					StepType:      StepTypeEntity,
+					ParentType:    step.ParentType,
+					SelectionSet:  fieldSelections(p.getKeyFields(step.ParentType, owner)),
+					Path:          append([]string{}, step.Path...),
+					DependsOn:     []int{parent.ID},
+					InsertionPath: append([]string{}, step.InsertionPath...),
+				}
+				plan.Steps = append(plan.Steps, requiresStep)
+				requiresSteps[key] = requiresStep
+				p.provideFields(parent, step, fieldSelections(p.getKeyFields(step.ParentType, owner)))
+			}
+			requiresStep.SelectionSet = mergeFusedSelections(requiresStep.SelectionSet, []ast.Selection{field})
+			if !containsInt(step.DependsOn, requiresStep.ID) {
+				step.DependsOn = append(step.DependsOn, requiresStep.ID)
+			}
+		}
+	}
+}
+
+// provideFields adds selections to the objects of step's entity type within parent, so
+// that parent's result carries them for step's representations.
+func (p *PlannerV2) provideFields(parent, step *StepV2, selections []ast.Selection) {
+	if parent.StepType == StepTypeEntity && parent.ParentType == step.ParentType &&
+		strings.Join(parent.InsertionPath, ".") == strings.Join(step.InsertionPath, ".") {
+		parent.SelectionSet = mergeFusedSelections(parent.SelectionSet, selections)
+		return
+	}
+	p.injectFieldsIntoSelections(parent.SelectionSet, parent.ParentType, step.ParentType, selections)
+}
+
+// canResolveField reports whether subGraph can resolve typeName.fieldName.
+func (p *PlannerV2) canResolveField(typeName, fieldName string, subGraph *graph.SubGraphV2) bool {
+	for _, sg := range p.SuperGraph.GetSubGraphsForField(typeName, fieldName) {
+		if sg.Name == subGraph.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// injectFieldsIntoSelections recursively finds fields that return targetTypeName and
+// merges fieldsToInject into their selection sets.
+func (p *PlannerV2) injectFieldsIntoSelections(selections []ast.Selection, currentTypeName, targetTypeName string, fieldsToInject []ast.Selection) {
+	for _, sel := range selections {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+
+		fieldName := field.Name.String()
+		if fieldName == "__typename" {
+			continue
+		}
+
+		fieldTypeName, err := p.getFieldTypeName(currentTypeName, fieldName)
+		if err != nil {
+			continue
+		}
+
+		if fieldTypeName == targetTypeName {
+			field.SelectionSet = mergeFusedSelections(field.SelectionSet, fieldsToInject)
+		}
+
+		if len(field.SelectionSet) > 0 {
+			p.injectFieldsIntoSelections(field.SelectionSet, fieldTypeName, targetTypeName, fieldsToInject)
+		}
+	}
+}
+
+// collectRequiredFields returns the merged @requires field sets of the fields selected
+// on parentTypeName, as resolved by subGraph.
+func (p *PlannerV2) collectRequiredFields(selections []ast.Selection, parentTypeName string, subGraph *graph.SubGraphV2) []ast.Selection {
+	entity, exists := subGraph.GetEntity(parentTypeName)
+	if !exists {
+		return nil
+	}
+
+	var required []ast.Selection
+	for _, sel := range selections {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+		fieldMetadata, ok := entity.Fields[field.Name.String()]
+		if !ok || len(fieldMetadata.Requires) == 0 {
+			continue
+		}
+		required = mergeFusedSelections(required, parseFieldSet(strings.Join(fieldMetadata.Requires, " ")))
+	}
+	return required
+}
+
+// requiresRepresentation reports whether subGraph resolves typeName.fieldName with
+// @requires: it needs the required fields in a representation, so only an entity step
+// resolving typeName can select the field.
+func (p *PlannerV2) requiresRepresentation(subGraph *graph.SubGraphV2, typeName, fieldName string) bool {
+	entity, exists := subGraph.GetEntity(typeName)
+	if !exists {
+		return false
+	}
+	field, ok := entity.Fields[fieldName]
+	return ok && len(field.Requires) > 0
+}
+
+// resolvesEntityAt reports whether step is the entity step resolving the typeName
+// objects at path.
+func resolvesEntityAt(step *StepV2, typeName string, path []string) bool {
+	return step.StepType == StepTypeEntity && step.ParentType == typeName &&
+		strings.Join(step.InsertionPath, ".") == strings.Join(path, ".")
+}
+
+// parseFieldSet parses a federation field set such as "id items { sku }" into selections.
+func parseFieldSet(fieldSet string) []ast.Selection {
+	fieldSet = strings.NewReplacer("{", " { ", "}", " } ").Replace(fieldSet)
+	selections, _ := parseFieldSetTokens(strings.Fields(fieldSet))
+	return selections
+}
+
+// parseFieldSetTokens parses tokens up to a closing brace and returns the selections and
+// the tokens after it.
+func parseFieldSetTokens(tokens []string) ([]ast.Selection, []string) {
+	var selections []ast.Selection
+	var last *ast.Field
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		tokens = tokens[1:]
+		switch tok {
+		case "{":
+			var children []ast.Selection
+			children, tokens = parseFieldSetTokens(tokens)
+			if last != nil {
+				last.SelectionSet = children
+			}
+		case "}":
+			return selections, tokens
+		default:
+			last = newField(tok)
+			selections = append(selections, last)
+		}
+	}
+	return selections, tokens
+}
+
+// fieldSelections returns a leaf field selection per name.
+func fieldSelections(names []string) []ast.Selection {
+	selections := make([]ast.Selection, 0, len(names))
+	for _, name := range names {
+		selections = append(selections, newField(name))
+	}
+	return selections
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
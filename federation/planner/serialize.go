@@ -0,0 +1,270 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+// PlanFormatVersion is the version of the serialized plan format written by Marshal.
+// It is bumped whenever the format changes incompatibly.
+const PlanFormatVersion = 3
+
+var (
+	// ErrPlanVersion is returned by Unmarshal for plans written in another format version.
+	ErrPlanVersion = errors.New("unsupported plan format version")
+	// ErrPlanSchemaMismatch is returned by Unmarshal for plans built against another schema.
+	ErrPlanSchemaMismatch = errors.New("plan was built for a different schema")
+)
+
+// serializedPlan is the JSON form of a PlanV2. Selection sets and the original document
+// are stored as GraphQL text, and subgraphs by name.
+type serializedPlan struct {
+	Version         int                      `json:"version"`
+	SchemaHash      string                   `json:"schemaHash"`
+	OperationType   string                   `json:"operationType"`
+	OperationName   string                   `json:"operationName,omitempty"`
+	Document        string                   `json:"document,omitempty"`
+	RootStepIndexes []int                    `json:"rootStepIndexes"`
+	Representations []map[string]interface{} `json:"representations,omitempty"`
+	Steps           []serializedStep         `json:"steps"`
+	PrunedFields    []PrunedField            `json:"prunedFields,omitempty"`
+}
+
+type serializedStep struct {
+	ID            int                 `json:"id"`
+	SubGraph      string              `json:"subGraph,omitempty"`
+	StepType      StepType            `json:"stepType"`
+	ParentType    string              `json:"parentType"`
+	SelectionSet  string              `json:"selectionSet"`
+	Path          []string            `json:"path"`
+	DependsOn     []int               `json:"dependsOn"`
+	InsertionPath []string            `json:"insertionPath"`
+	BatchedPaths  [][]string          `json:"batchedPaths,omitempty"`
+	Requires      string              `json:"requires,omitempty"`
+	Contexts      []serializedContext `json:"contexts,omitempty"`
+	Timeout       string              `json:"timeout,omitempty"`
+	BypassCache   bool                `json:"bypassCache,omitempty"`
+}
+
+type serializedContext struct {
+	Variable  string `json:"variable"`
+	Depth     int    `json:"depth"`
+	Selection string `json:"selection"`
+}
+
+// Marshal serializes p so that it can be precomputed offline or shared between gateway
+// replicas. schemaHash identifies the supergraph p was planned against (for example
+// registry.SchemaHash of the subgraph SDLs); Unmarshal rejects the plan once it changes.
+func (p *PlanV2) Marshal(schemaHash string) ([]byte, error) {
+	sp := serializedPlan{
+		Version:         PlanFormatVersion,
+		SchemaHash:      schemaHash,
+		OperationType:   p.OperationType,
+		OperationName:   p.OperationName,
+		RootStepIndexes: p.RootStepIndexes,
+		Representations: p.Representations,
+		Steps:           make([]serializedStep, 0, len(p.Steps)),
+		PrunedFields:    p.PrunedFields,
+	}
+	if p.OriginalDocument != nil {
+		sp.Document = documentString(p.OriginalDocument)
+	}
+
+	for _, step := range p.Steps {
+		var contexts []serializedContext
+		for _, c := range step.Contexts {
+			contexts = append(contexts, serializedContext{Variable: c.Variable, Depth: c.Depth, Selection: selectionsString(c.Selection)})
+		}
+		sp.Steps = append(sp.Steps, serializedStep{
+			ID:            step.ID,
+			SubGraph:      stepSubGraphName(step),
+			StepType:      step.StepType,
+			ParentType:    step.ParentType,
+			SelectionSet:  selectionsString(step.SelectionSet),
+			Path:          step.Path,
+			DependsOn:     step.DependsOn,
+			InsertionPath: step.InsertionPath,
+			BatchedPaths:  step.BatchedPaths,
+			Requires:      selectionsString(step.Requires),
+			Contexts:      contexts,
+			Timeout:       durationString(step.Timeout),
+			BypassCache:   step.BypassCache,
+		})
+	}
+
+	data, err := json.Marshal(sp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	return data, nil
+}
+
+// durationString returns d as text, or "" when it is not set.
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// Unmarshal restores into p a plan written by Marshal, resolving its subgraphs in
+// superGraph. It returns ErrPlanVersion or ErrPlanSchemaMismatch when the plan was
+// written in another format version or for a schema other than schemaHash; callers
+// sharing plans should then plan the operation again.
+func (p *PlanV2) Unmarshal(data []byte, superGraph *graph.SuperGraphV2, schemaHash string) error {
+	var sp serializedPlan
+	if err := json.Unmarshal(data, &sp); err != nil {
+		return fmt.Errorf("failed to unmarshal plan: %w", err)
+	}
+	if sp.Version != PlanFormatVersion {
+		return fmt.Errorf("%w: got %d, want %d", ErrPlanVersion, sp.Version, PlanFormatVersion)
+	}
+	if sp.SchemaHash != schemaHash {
+		return fmt.Errorf("%w: got %q, want %q", ErrPlanSchemaMismatch, sp.SchemaHash, schemaHash)
+	}
+
+	subGraphs := make(map[string]*graph.SubGraphV2, len(superGraph.SubGraphs))
+	for _, sg := range superGraph.SubGraphs {
+		subGraphs[sg.Name] = sg
+	}
+
+	plan := PlanV2{
+		Steps:           make([]*StepV2, 0, len(sp.Steps)),
+		RootStepIndexes: sp.RootStepIndexes,
+		OperationType:   sp.OperationType,
+		OperationName:   sp.OperationName,
+		Representations: sp.Representations,
+		PrunedFields:    sp.PrunedFields,
+	}
+	if sp.Document != "" {
+		doc, err := parseDocument(sp.Document)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan document: %w", err)
+		}
+		plan.OriginalDocument = doc
+	}
+
+	for i, s := range sp.Steps {
+		if s.ID != i {
+			return fmt.Errorf("step %d has ID %d", i, s.ID)
+		}
+		for _, dep := range s.DependsOn {
+			if dep < 0 || dep >= len(sp.Steps) {
+				return fmt.Errorf("step %d depends on unknown step %d", s.ID, dep)
+			}
+		}
+
+		step := &StepV2{
+			ID:            s.ID,
+			StepType:      s.StepType,
+			ParentType:    s.ParentType,
+			Path:          s.Path,
+			DependsOn:     s.DependsOn,
+			InsertionPath: s.InsertionPath,
+			BatchedPaths:  s.BatchedPaths,
+			BypassCache:   s.BypassCache,
+		}
+		if s.Timeout != "" {
+			timeout, err := time.ParseDuration(s.Timeout)
+			if err != nil {
+				return fmt.Errorf("invalid timeout of step %d: %w", s.ID, err)
+			}
+			step.Timeout = timeout
+		}
+		if s.SubGraph != "" {
+			sg, ok := subGraphs[s.SubGraph]
+			if !ok {
+				return fmt.Errorf("%w: subgraph %s not found", ErrPlanSchemaMismatch, s.SubGraph)
+			}
+			step.SubGraph = sg
+		}
+
+		var err error
+		if step.SelectionSet, err = parseSelections(s.SelectionSet); err != nil {
+			return fmt.Errorf("failed to parse selections of step %d: %w", s.ID, err)
+		}
+		if step.Requires, err = parseSelections(s.Requires); err != nil {
+			return fmt.Errorf("failed to parse requires of step %d: %w", s.ID, err)
+		}
+		for _, c := range s.Contexts {
+			if c.Depth < 0 || c.Depth >= len(step.InsertionPath) {
+				return fmt.Errorf("context %s of step %d has invalid depth %d", c.Variable, s.ID, c.Depth)
+			}
+			selection, err := parseSelections(c.Selection)
+			if err != nil {
+				return fmt.Errorf("failed to parse context %s of step %d: %w", c.Variable, s.ID, err)
+			}
+			step.Contexts = append(step.Contexts, ContextValue{Variable: c.Variable, Depth: c.Depth, Selection: selection})
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	for _, idx := range plan.RootStepIndexes {
+		if idx < 0 || idx >= len(plan.Steps) {
+			return fmt.Errorf("unknown root step %d", idx)
+		}
+	}
+
+	*p = plan
+	return nil
+}
+
+// documentString returns the text of the operations and fragments of doc.
+func documentString(doc *ast.Document) string {
+	var sb strings.Builder
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.OperationDefinition:
+			writeOperation(&sb, d)
+		case *ast.FragmentDefinition:
+			sb.WriteString("fragment " + d.Name.String() + " on " + d.TypeCondition.Name.String())
+			sb.WriteString(directivesString(d.Directives))
+			writeSelections(&sb, d.SelectionSet)
+		default:
+			continue
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// selectionsString returns the text of selections, or "" when there are none.
+func selectionsString(selections []ast.Selection) string {
+	var sb strings.Builder
+	writeSelections(&sb, selections)
+	return strings.TrimSpace(sb.String())
+}
+
+// parseSelections parses a selection set written by selectionsString.
+func parseSelections(text string) ([]ast.Selection, error) {
+	if text == "" {
+		return nil, nil
+	}
+	doc, err := parseDocument(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			return op.SelectionSet, nil
+		}
+	}
+	return nil, errors.New("no selection set found")
+}
+
+func parseDocument(text string) (*ast.Document, error) {
+	p := parser.New(lexer.New(text))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("parse error: %v", p.Errors())
+	}
+	return doc, nil
+}
@@ -0,0 +1,204 @@
+package planner_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+func newSerializePlanner(t *testing.T) *planner.PlannerV2 {
+	t.Helper()
+
+	productSchema := `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			weight: Int!
+		}
+
+		type Query {
+			products(first: Int, filter: ProductFilter): [Product!]!
+		}
+
+		input ProductFilter {
+			name: String
+		}
+	`
+	shippingSchema := `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			weight: Int! @external
+			shippingCost: Int! @requires(fields: "weight")
+		}
+	`
+
+	var subGraphs []*graph.SubGraphV2
+	for _, s := range []struct{ name, sdl string }{
+		{"products", productSchema},
+		{"shipping", shippingSchema},
+	} {
+		sg, err := graph.NewSubGraphV2(s.name, []byte(s.sdl), "http://"+s.name+".example.com")
+		if err != nil {
+			t.Fatalf("NewSubGraphV2 for %s failed: %v", s.name, err)
+		}
+		subGraphs = append(subGraphs, sg)
+	}
+	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+	return planner.NewPlannerV2(superGraph)
+}
+
+func TestPlanV2_MarshalUnmarshal(t *testing.T) {
+	p := newSerializePlanner(t)
+	doc := parseQuery(t, `
+		query Products($first: Int = 10) {
+			products(first: $first, filter: {name: "lamp"}) {
+				...ProductFields
+				shippingCost
+			}
+			more: products(first: 5) {
+				...ProductFields
+				shippingCost
+			}
+		}
+
+		fragment ProductFields on Product {
+			id
+			title: name
+		}
+	`)
+
+	plan, err := p.Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	batched := false
+	for _, step := range plan.Steps {
+		batched = batched || len(step.BatchedPaths) > 0
+	}
+	if !batched {
+		t.Fatalf("expected the shipping steps of both fields to be batched")
+	}
+
+	data, err := plan.Marshal("hash-1")
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var restored planner.PlanV2
+	if err := restored.Unmarshal(data, p.SuperGraph, "hash-1"); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if restored.OperationType != plan.OperationType || restored.OperationName != plan.OperationName {
+		t.Errorf("expected operation %s %s, got %s %s", plan.OperationType, plan.OperationName, restored.OperationType, restored.OperationName)
+	}
+	if !reflect.DeepEqual(restored.RootStepIndexes, plan.RootStepIndexes) {
+		t.Errorf("expected root steps %v, got %v", plan.RootStepIndexes, restored.RootStepIndexes)
+	}
+	if len(restored.Steps) != len(plan.Steps) {
+		t.Fatalf("expected %d steps, got %d", len(plan.Steps), len(restored.Steps))
+	}
+	for i, step := range plan.Steps {
+		got := restored.Steps[i]
+		if got.SubGraph != step.SubGraph {
+			t.Errorf("step %d: expected subgraph %s, got %v", i, step.SubGraph.Name, got.SubGraph)
+		}
+		if got.StepType != step.StepType || got.ParentType != step.ParentType {
+			t.Errorf("step %d: expected %v on %s, got %v on %s", i, step.StepType, step.ParentType, got.StepType, got.ParentType)
+		}
+		if !reflect.DeepEqual(got.DependsOn, step.DependsOn) || !reflect.DeepEqual(got.InsertionPath, step.InsertionPath) {
+			t.Errorf("step %d: expected dependencies %v at %v, got %v at %v", i, step.DependsOn, step.InsertionPath, got.DependsOn, got.InsertionPath)
+		}
+		if !reflect.DeepEqual(got.BatchedPaths, step.BatchedPaths) {
+			t.Errorf("step %d: expected batched paths %v, got %v", i, step.BatchedPaths, got.BatchedPaths)
+		}
+		if len(got.Requires) != len(step.Requires) {
+			t.Errorf("step %d: expected %d required fields, got %d", i, len(step.Requires), len(got.Requires))
+		}
+	}
+
+	// A restored plan serializes to the same bytes.
+	again, err := restored.Marshal("hash-1")
+	if err != nil {
+		t.Fatalf("Marshal of restored plan failed: %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("expected restored plan to serialize identically\nwant: %s\ngot:  %s", data, again)
+	}
+
+	var fragments int
+	for _, def := range restored.OriginalDocument.Definitions {
+		if _, ok := def.(*ast.FragmentDefinition); ok {
+			fragments++
+		}
+	}
+	if fragments != 1 {
+		t.Errorf("expected the original document to keep its fragment definition, got %d", fragments)
+	}
+}
+
+func TestPlanV2_MarshalUnmarshal_PrunedFields(t *testing.T) {
+	p := newSerializePlanner(t)
+	p.PruneUnfetchable = true
+	plan, err := p.Plan(parseQuery(t, `{ products { name } old: removed }`), nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	data, err := plan.Marshal("hash-1")
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var restored planner.PlanV2
+	if err := restored.Unmarshal(data, p.SuperGraph, "hash-1"); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(restored.PrunedFields, plan.PrunedFields) {
+		t.Errorf("expected pruned fields %v, got %v", plan.PrunedFields, restored.PrunedFields)
+	}
+}
+
+func TestPlanV2_Unmarshal_SchemaMismatch(t *testing.T) {
+	p := newSerializePlanner(t)
+	plan, err := p.Plan(parseQuery(t, `{ products { name } }`), nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	data, err := plan.Marshal("hash-1")
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var restored planner.PlanV2
+	if err := restored.Unmarshal(data, p.SuperGraph, "hash-2"); !errors.Is(err, planner.ErrPlanSchemaMismatch) {
+		t.Errorf("expected ErrPlanSchemaMismatch, got %v", err)
+	}
+}
+
+func TestPlanV2_Unmarshal_Version(t *testing.T) {
+	p := newSerializePlanner(t)
+
+	var restored planner.PlanV2
+	err := restored.Unmarshal([]byte(`{"version":999,"schemaHash":"hash-1","steps":[]}`), p.SuperGraph, "hash-1")
+	if !errors.Is(err, planner.ErrPlanVersion) {
+		t.Errorf("expected ErrPlanVersion, got %v", err)
+	}
+}
+
+func TestPlanV2_Unmarshal_UnknownSubGraph(t *testing.T) {
+	p := newSerializePlanner(t)
+	data := []byte(`{"version":3,"schemaHash":"hash-1","operationType":"query","rootStepIndexes":[0],"steps":[{"id":0,"subGraph":"reviews","stepType":0,"parentType":"Query","selectionSet":"{ products { id } }"}]}`)
+
+	var restored planner.PlanV2
+	if err := restored.Unmarshal(data, p.SuperGraph, "hash-1"); !errors.Is(err, planner.ErrPlanSchemaMismatch) {
+		t.Errorf("expected ErrPlanSchemaMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// Visualize builds a graph of the plan: one node per step showing its subgraph,
+// parent type and selected fields, and one edge per dependency labelled with the
+// paths where the dependent step's results are inserted. Dependencies on a step of
+// another subgraph are cross-subgraph edges.
+func (p *PlanV2) Visualize() *graph.Visualization {
+	v := &graph.Visualization{}
+
+	steps := make(map[int]*StepV2, len(p.Steps))
+	for _, step := range p.Steps {
+		steps[step.ID] = step
+	}
+
+	for _, step := range p.Steps {
+		id := fmt.Sprintf("step%d", step.ID)
+		lines := []string{
+			fmt.Sprintf("#%d %s [%s]", step.ID, stepKind(p, step), stepSubGraphName(step)),
+			step.ParentType + " { " + strings.Join(selectionNames(step.SelectionSet), " ") + " }",
+		}
+		v.Nodes = append(v.Nodes, graph.VisualNode{ID: id, Label: strings.Join(lines, "\n")})
+
+		var paths []string
+		for _, path := range step.InsertionPaths() {
+			paths = append(paths, strings.Join(path, "."))
+		}
+		for _, depID := range step.DependsOn {
+			edge := graph.VisualEdge{
+				From:  fmt.Sprintf("step%d", depID),
+				To:    id,
+				Label: strings.Join(paths, ", "),
+				Style: graph.EdgeStyleLocal,
+			}
+			if dep, ok := steps[depID]; ok && stepSubGraphName(dep) != stepSubGraphName(step) {
+				edge.Style = graph.EdgeStyleCrossSubGraph
+			}
+			v.Edges = append(v.Edges, edge)
+		}
+	}
+
+	return v
+}
+
+// stepKind describes the type of step: the operation type of root steps, entity or
+// representations.
+func stepKind(p *PlanV2, step *StepV2) string {
+	switch step.StepType {
+	case StepTypeEntity:
+		return "entity"
+	case StepTypeRepresentations:
+		return "representations"
+	}
+	if p.OperationType != "" {
+		return p.OperationType
+	}
+	return "query"
+}
+
+// selectionNames returns the top-level field names of selections, with nested
+// selections abbreviated as "{…}".
+func selectionNames(selections []ast.Selection) []string {
+	names := make([]string, 0, len(selections))
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			name := s.Name.String()
+			if len(s.SelectionSet) > 0 {
+				name += " {…}"
+			}
+			names = append(names, name)
+		case *ast.InlineFragment:
+			names = append(names, "... {…}")
+		case *ast.FragmentSpread:
+			names = append(names, "..."+s.Name.String())
+		}
+	}
+	return names
+}
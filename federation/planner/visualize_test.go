@@ -0,0 +1,56 @@
+package planner_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+func TestPlanV2_Visualize(t *testing.T) {
+	products, err := graph.NewSubGraphV2("products", []byte(`
+		type Query { topProducts: [Product] }
+		type Product @key(fields: "upc") { upc: String! name: String }
+	`), "http://products")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	reviews, err := graph.NewSubGraphV2("reviews", []byte(`
+		type Review { body: String }
+		extend type Product @key(fields: "upc") {
+			upc: String! @external
+			reviews: [Review]
+		}
+	`), "http://reviews")
+	if err != nil {
+		t.Fatalf("NewSubGraphV2 failed: %v", err)
+	}
+	superGraph, err := graph.NewSuperGraphV2([]*graph.SubGraphV2{products, reviews})
+	if err != nil {
+		t.Fatalf("NewSuperGraphV2 failed: %v", err)
+	}
+
+	p := parser.New(lexer.New(`{ topProducts { name reviews { body } } }`))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse error: %v", p.Errors())
+	}
+	plan, err := planner.NewPlannerV2(superGraph).Plan(doc, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	v := plan.Visualize()
+	if len(v.Nodes) != 2 {
+		t.Fatalf("expected 2 step nodes, got %+v", v.Nodes)
+	}
+	if !strings.HasPrefix(v.Nodes[0].Label, "#0 query [products]") || !strings.HasPrefix(v.Nodes[1].Label, "#1 entity [reviews]") {
+		t.Errorf("unexpected node labels: %q, %q", v.Nodes[0].Label, v.Nodes[1].Label)
+	}
+	if len(v.Edges) != 1 || v.Edges[0].From != "step0" || v.Edges[0].To != "step1" || v.Edges[0].Style != graph.EdgeStyleCrossSubGraph {
+		t.Errorf("expected one cross-subgraph edge step0 -> step1, got %+v", v.Edges)
+	}
+}
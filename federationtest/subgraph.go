@@ -0,0 +1,339 @@
+// Package federationtest boots stub federation subgraphs from an SDL and resolver stubs
+// and runs a federation compatibility suite against a gateway, so regressions in
+// entity fetches, representations and directive handling are caught by go test.
+package federationtest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+// FieldResolver resolves a root Query field from its arguments.
+type FieldResolver func(args map[string]any) (any, error)
+
+// EntityResolver resolves an entity from a representation. It returns nil when the
+// entity does not exist.
+type EntityResolver func(representation map[string]any) (map[string]any, error)
+
+// Subgraph describes a stub subgraph. Resolved objects are maps keyed by field name;
+// nested objects and lists of objects are resolved the same way, and a value of type
+// FieldResolver is called with the arguments of the selected field.
+type Subgraph struct {
+	Name     string
+	SDL      string
+	Query    map[string]FieldResolver  // Root Query fields by name
+	Entities map[string]EntityResolver // _entities resolvers by __typename
+}
+
+// Request is a GraphQL request received by a stub subgraph, other than _service.
+type Request struct {
+	Query           string
+	Variables       map[string]any
+	Representations []map[string]any // Representations of an _entities request
+}
+
+// Server serves a stub subgraph over HTTP and records its requests.
+type Server struct {
+	*httptest.Server
+	subgraph Subgraph
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// Start serves sg until the test ends.
+func Start(t testing.TB, sg Subgraph) *Server {
+	t.Helper()
+	s := &Server{subgraph: sg}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns the requests received since the last Reset.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets the received requests.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// graphQLRequest is the body of a request to a stub subgraph.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphQLError is an error in a stub subgraph response.
+type graphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// ServeHTTP executes a GraphQL request against the stub resolvers.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	data, errs, err := s.execute(req)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]any{"errors": []graphQLError{{Message: err.Error()}}}) //nolint:errcheck
+		return
+	}
+	resp := map[string]any{"data": data}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	json.NewEncoder(w).Encode(resp) //nolint:errcheck
+}
+
+// execute runs the operation of req.
+func (s *Server) execute(req graphQLRequest) (map[string]any, []graphQLError, error) {
+	p := parser.New(lexer.New(req.Query))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		return nil, nil, fmt.Errorf("failed to parse query: %v", p.Errors())
+	}
+
+	e := &execution{variables: req.Variables, fragments: make(map[string]*ast.FragmentDefinition)}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			if req.OperationName == "" || (def.Name != nil && def.Name.Value == req.OperationName) {
+				op = def
+			}
+		case *ast.FragmentDefinition:
+			e.fragments[def.Name.Value] = def
+		}
+	}
+	if op == nil {
+		return nil, nil, errors.New("no operation found")
+	}
+	if op.Operation != ast.Query {
+		return nil, nil, fmt.Errorf("%s operations are not supported", op.Operation)
+	}
+
+	data := make(map[string]any)
+	recorded := false
+	for _, field := range e.fields(op.SelectionSet, "Query") {
+		key := responseKey(field)
+		args := e.arguments(field)
+		switch field.Name.Value {
+		case "__typename":
+			data[key] = "Query"
+		case "_service":
+			data[key] = e.complete(map[string]any{"sdl": s.subgraph.SDL}, field.SelectionSet, []any{key})
+		case "_entities":
+			reps := representations(args["representations"])
+			s.record(Request{Query: req.Query, Variables: req.Variables, Representations: reps})
+			recorded = true
+			data[key] = s.entities(e, reps, field, key)
+		default:
+			if !recorded {
+				s.record(Request{Query: req.Query, Variables: req.Variables})
+				recorded = true
+			}
+			resolve, ok := s.subgraph.Query[field.Name.Value]
+			if !ok {
+				return nil, nil, fmt.Errorf("cannot query field %q on type \"Query\"", field.Name.Value)
+			}
+			v, err := resolve(args)
+			if err != nil {
+				e.errors = append(e.errors, graphQLError{Message: err.Error(), Path: []any{key}})
+				data[key] = nil
+				continue
+			}
+			data[key] = e.complete(v, field.SelectionSet, []any{key})
+		}
+	}
+	return data, e.errors, nil
+}
+
+// entities resolves the representations of an _entities field.
+func (s *Server) entities(e *execution, reps []map[string]any, field *ast.Field, key string) []any {
+	entities := make([]any, len(reps))
+	for i, rep := range reps {
+		typename, _ := rep["__typename"].(string)
+		resolve, ok := s.subgraph.Entities[typename]
+		if !ok {
+			e.errors = append(e.errors, graphQLError{Message: fmt.Sprintf("no entity resolver for %q", typename), Path: []any{key, i}})
+			continue
+		}
+		entity, err := resolve(rep)
+		if err != nil {
+			e.errors = append(e.errors, graphQLError{Message: err.Error(), Path: []any{key, i}})
+			continue
+		}
+		if entity == nil {
+			continue
+		}
+		if _, ok := entity["__typename"]; !ok {
+			entity["__typename"] = typename
+		}
+		entities[i] = e.complete(entity, field.SelectionSet, []any{key, i})
+	}
+	return entities
+}
+
+func (s *Server) record(req Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+}
+
+// representations converts the representations argument of _entities.
+func representations(v any) []map[string]any {
+	list, _ := v.([]any)
+	reps := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if rep, ok := item.(map[string]any); ok {
+			reps = append(reps, rep)
+		}
+	}
+	return reps
+}
+
+// execution holds the state of one operation.
+type execution struct {
+	variables map[string]any
+	fragments map[string]*ast.FragmentDefinition
+	errors    []graphQLError
+}
+
+// complete shapes v by selections.
+func (e *execution) complete(v any, selections []ast.Selection, path []any) any {
+	if len(selections) == 0 || v == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case []map[string]any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = e.complete(item, selections, appendPath(path, i))
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = e.complete(item, selections, appendPath(path, i))
+		}
+		return out
+	case map[string]any:
+		typename, _ := v["__typename"].(string)
+		out := make(map[string]any)
+		for _, field := range e.fields(selections, typename) {
+			key := responseKey(field)
+			value := v[field.Name.Value]
+			if resolve, ok := value.(FieldResolver); ok {
+				var err error
+				value, err = resolve(e.arguments(field))
+				if err != nil {
+					e.errors = append(e.errors, graphQLError{Message: err.Error(), Path: appendPath(path, key)})
+					value = nil
+				}
+			}
+			out[key] = e.complete(value, field.SelectionSet, appendPath(path, key))
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// fields flattens selections into the fields selected on an object of typename.
+// Fragments apply when their type condition is typename, or when the object has no
+// __typename.
+func (e *execution) fields(selections []ast.Selection, typename string) []*ast.Field {
+	var fields []*ast.Field
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			fields = append(fields, sel)
+		case *ast.InlineFragment:
+			if sel.TypeCondition == nil || typename == "" || sel.TypeCondition.Name.Value == typename {
+				fields = append(fields, e.fields(sel.SelectionSet, typename)...)
+			}
+		case *ast.FragmentSpread:
+			def, ok := e.fragments[sel.Name.Value]
+			if ok && (typename == "" || def.TypeCondition.Name.Value == typename) {
+				fields = append(fields, e.fields(def.SelectionSet, typename)...)
+			}
+		}
+	}
+	return fields
+}
+
+// arguments evaluates the arguments of field.
+func (e *execution) arguments(field *ast.Field) map[string]any {
+	args := make(map[string]any, len(field.Arguments))
+	for _, arg := range field.Arguments {
+		args[arg.Name.Value] = e.value(arg.Value)
+	}
+	return args
+}
+
+// value evaluates an argument value.
+func (e *execution) value(v ast.Value) any {
+	switch v := v.(type) {
+	case *ast.Variable:
+		return e.variables[v.Name]
+	case *ast.StringValue:
+		return v.Value
+	case *ast.IntValue:
+		return v.Value
+	case *ast.FloatValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.ListValue:
+		list := make([]any, len(v.Values))
+		for i, item := range v.Values {
+			list[i] = e.value(item)
+		}
+		return list
+	case *ast.ObjectValue:
+		obj := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			obj[f.Name.Value] = e.value(f.Value)
+		}
+		return obj
+	default:
+		return nil
+	}
+}
+
+// appendPath returns a copy of path with el appended.
+func appendPath(path []any, el any) []any {
+	return append(append(make([]any, 0, len(path)+1), path...), el)
+}
+
+// responseKey returns the alias of field, or its name.
+func responseKey(field *ast.Field) string {
+	if field.Alias != nil {
+		return field.Alias.Value
+	}
+	return field.Name.Value
+}
@@ -0,0 +1,360 @@
+package federationtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// GatewayFactory builds the gateway under test, serving GraphQL on any POST path, for
+// the compatibility suite's subgraphs.
+type GatewayFactory func(t *testing.T, services []gateway.GatewayService) http.Handler
+
+// The compatibility suite's subgraphs follow the products domain of the Apollo
+// federation subgraph compatibility suite: products owns Product and references User,
+// inventory extends Product with @requires, users owns User, and reviews extends
+// Product through its composite key. Entities are extended with extend type so each
+// has a single owning subgraph.
+const (
+	productsSDL = `
+extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key", "@external", "@provides", "@inaccessible", "@tag"])
+
+type Query {
+	product(id: ID!): Product
+	products: [Product!]!
+}
+
+type Product @key(fields: "id") @key(fields: "sku package") {
+	id: ID!
+	sku: String
+	package: String
+	name: String @tag(name: "public")
+	price: Int
+	weight: Int
+	createdBy: User @provides(fields: "name")
+	internalNotes: String @inaccessible
+}
+
+extend type User @key(fields: "email") {
+	email: ID! @external
+	name: String @external
+}`
+
+	inventorySDL = `
+extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key", "@external", "@requires"])
+
+extend type Product @key(fields: "id") {
+	id: ID! @external
+	price: Int @external
+	weight: Int @external
+	inStock: Boolean
+	shippingEstimate: Int @requires(fields: "price weight")
+}`
+
+	usersSDL = `
+extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key", "@shareable"])
+
+type User @key(fields: "email") {
+	email: ID!
+	name: String @shareable
+	totalProductsCreated: Int
+}`
+
+	reviewsSDL = `
+extend schema @link(url: "https://specs.apollo.dev/federation/v2.3", import: ["@key"])
+
+extend type Product @key(fields: "sku package") {
+	sku: String @external
+	package: String @external
+	reviews: [Review!]!
+}
+
+type Review {
+	body: String!
+}`
+)
+
+// suiteProducts are the products of the suite by id.
+var suiteProducts = map[string]map[string]any{
+	"1": {"id": "1", "sku": "federation", "package": "@apollo/federation", "name": "Federation", "price": 100, "weight": 5, "internalNotes": "secret"},
+	"2": {"id": "2", "sku": "studio", "package": "", "name": "Studio", "price": 200, "weight": 10, "internalNotes": "secret"},
+}
+
+// suiteUser created every product.
+var suiteUser = map[string]any{"email": "support@apollographql.com", "name": "Jane Smith", "totalProductsCreated": 1337}
+
+// product returns a copy of the product with id as resolved by the products subgraph.
+func product(id string) map[string]any {
+	p, ok := suiteProducts[id]
+	if !ok {
+		return nil
+	}
+	out := map[string]any{"__typename": "Product"}
+	for k, v := range p {
+		out[k] = v
+	}
+	out["createdBy"] = map[string]any{"__typename": "User", "email": suiteUser["email"], "name": suiteUser["name"]}
+	return out
+}
+
+// productByKey finds a product by one of its keys in rep.
+func productByKey(rep map[string]any) map[string]any {
+	if id, ok := rep["id"].(string); ok {
+		return product(id)
+	}
+	for id, p := range suiteProducts {
+		if p["sku"] == rep["sku"] && p["package"] == rep["package"] {
+			return product(id)
+		}
+	}
+	return nil
+}
+
+// suiteSubgraphs returns the stub subgraphs of the suite.
+func suiteSubgraphs() []Subgraph {
+	return []Subgraph{
+		{
+			Name: "products",
+			SDL:  productsSDL,
+			Query: map[string]FieldResolver{
+				"product": func(args map[string]any) (any, error) {
+					id, _ := args["id"].(string)
+					if p := product(id); p != nil {
+						return p, nil
+					}
+					return nil, nil
+				},
+				"products": func(args map[string]any) (any, error) {
+					return []any{product("1"), product("2")}, nil
+				},
+			},
+			Entities: map[string]EntityResolver{
+				"Product": func(rep map[string]any) (map[string]any, error) { return productByKey(rep), nil },
+			},
+		},
+		{
+			Name: "inventory",
+			SDL:  inventorySDL,
+			Entities: map[string]EntityResolver{
+				"Product": func(rep map[string]any) (map[string]any, error) {
+					entity := map[string]any{"id": rep["id"], "inStock": rep["id"] == "1"}
+					price, hasPrice := rep["price"].(float64)
+					weight, hasWeight := rep["weight"].(float64)
+					if hasPrice && hasWeight {
+						entity["shippingEstimate"] = int(price*weight) / 10
+					}
+					return entity, nil
+				},
+			},
+		},
+		{
+			Name: "users",
+			SDL:  usersSDL,
+			Entities: map[string]EntityResolver{
+				"User": func(rep map[string]any) (map[string]any, error) {
+					if rep["email"] != suiteUser["email"] {
+						return nil, nil
+					}
+					return map[string]any{"email": suiteUser["email"], "name": suiteUser["name"], "totalProductsCreated": suiteUser["totalProductsCreated"]}, nil
+				},
+			},
+		},
+		{
+			Name: "reviews",
+			SDL:  reviewsSDL,
+			Entities: map[string]EntityResolver{
+				"Product": func(rep map[string]any) (map[string]any, error) {
+					if rep["sku"] != "federation" {
+						return map[string]any{"sku": rep["sku"], "package": rep["package"], "reviews": []any{}}, nil
+					}
+					return map[string]any{"sku": rep["sku"], "package": rep["package"], "reviews": []any{
+						map[string]any{"body": "Ships everywhere"},
+					}}, nil
+				},
+			},
+		},
+	}
+}
+
+// compatibilityCase is an operation of the suite with its expected response and, per
+// subgraph, the representations of the _entities requests it sends, in order.
+type compatibilityCase struct {
+	name            string
+	query           string
+	variables       map[string]any
+	data            string // Expected data as JSON
+	errors          bool   // Whether the response has errors
+	representations map[string][][]map[string]any
+	untouched       []string // Subgraphs that must not be queried
+}
+
+var compatibilityCases = []compatibilityCase{
+	{
+		name:      "root field",
+		query:     `{ product(id: "1") { id name price } }`,
+		data:      `{"product":{"id":"1","name":"Federation","price":100}}`,
+		untouched: []string{"inventory", "users", "reviews"},
+	},
+	{
+		name:      "variables",
+		query:     `query Product($id: ID!) { product(id: $id) { name } }`,
+		variables: map[string]any{"id": "2"},
+		data:      `{"product":{"name":"Studio"}}`,
+	},
+	{
+		name:  "aliases, __typename and fragments",
+		query: `query { p: product(id: "1") { __typename ... on Product { n: name } ...Price } } fragment Price on Product { price }`,
+		data:  `{"p":{"__typename":"Product","n":"Federation","price":100}}`,
+	},
+	{
+		name:  "missing entity",
+		query: `{ product(id: "404") { id name } }`,
+		data:  `{"product":null}`,
+	},
+	{
+		name:  "@key entity fetch",
+		query: `{ product(id: "1") { name inStock } }`,
+		data:  `{"product":{"name":"Federation","inStock":true}}`,
+		representations: map[string][][]map[string]any{
+			"inventory": {{{"__typename": "Product", "id": "1"}}},
+		},
+	},
+	{
+		name:  "batched representations",
+		query: `{ products { id inStock } }`,
+		data:  `{"products":[{"id":"1","inStock":true},{"id":"2","inStock":false}]}`,
+		representations: map[string][][]map[string]any{
+			"inventory": {{{"__typename": "Product", "id": "1"}, {"__typename": "Product", "id": "2"}}},
+		},
+	},
+	{
+		name:  "@requires",
+		query: `{ product(id: "1") { shippingEstimate } }`,
+		data:  `{"product":{"shippingEstimate":50}}`,
+		representations: map[string][][]map[string]any{
+			"inventory": {{{"__typename": "Product", "id": "1", "price": 100, "weight": 5}}},
+		},
+	},
+	{
+		name:      "@provides",
+		query:     `{ product(id: "1") { createdBy { email name } } }`,
+		data:      `{"product":{"createdBy":{"email":"support@apollographql.com","name":"Jane Smith"}}}`,
+		untouched: []string{"users"},
+	},
+	{
+		name:  "field not provided",
+		query: `{ product(id: "1") { createdBy { totalProductsCreated } } }`,
+		data:  `{"product":{"createdBy":{"totalProductsCreated":1337}}}`,
+		representations: map[string][][]map[string]any{
+			"users": {{{"__typename": "User", "email": "support@apollographql.com"}}},
+		},
+	},
+	{
+		name:  "composite key",
+		query: `{ product(id: "1") { reviews { body } } }`,
+		data:  `{"product":{"reviews":[{"body":"Ships everywhere"}]}}`,
+		representations: map[string][][]map[string]any{
+			"reviews": {{{"__typename": "Product", "sku": "federation", "package": "@apollo/federation"}}},
+		},
+	},
+	{
+		name:   "@inaccessible",
+		query:  `{ product(id: "1") { internalNotes } }`,
+		errors: true,
+	},
+}
+
+// RunCompatibilitySuite starts the suite's stub subgraphs, builds the gateway with
+// newGateway and checks the response of every operation of the suite, and the
+// representations the gateway sends to each subgraph.
+func RunCompatibilitySuite(t *testing.T, newGateway GatewayFactory) {
+	servers := make(map[string]*Server)
+	var services []gateway.GatewayService
+	for _, sg := range suiteSubgraphs() {
+		s := Start(t, sg)
+		servers[sg.Name] = s
+		services = append(services, gateway.GatewayService{Name: sg.Name, Host: s.URL})
+	}
+	gw := newGateway(t, services)
+
+	for _, tc := range compatibilityCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, s := range servers {
+				s.Reset()
+			}
+
+			body, err := json.Marshal(map[string]any{"query": tc.query, "variables": tc.variables})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+
+			var resp struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []any           `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+			}
+			if tc.errors {
+				if len(resp.Errors) == 0 {
+					t.Errorf("expected errors, got %s", rec.Body.String())
+				}
+				return
+			}
+			if len(resp.Errors) > 0 {
+				t.Errorf("unexpected errors: %s", rec.Body.String())
+			}
+			if !jsonEqual(t, resp.Data, tc.data) {
+				t.Errorf("unexpected data\n got: %s\nwant: %s", resp.Data, tc.data)
+			}
+
+			for name, want := range tc.representations {
+				var got [][]map[string]any
+				for _, req := range servers[name].Requests() {
+					if req.Representations != nil {
+						got = append(got, req.Representations)
+					}
+				}
+				if !jsonEqual(t, mustMarshal(t, got), string(mustMarshal(t, want))) {
+					t.Errorf("unexpected representations sent to %s\n got: %s\nwant: %s", name, mustMarshal(t, got), mustMarshal(t, want))
+				}
+			}
+			for _, name := range tc.untouched {
+				if reqs := servers[name].Requests(); len(reqs) > 0 {
+					t.Errorf("expected no request to %s, got %d", name, len(reqs))
+				}
+			}
+		})
+	}
+}
+
+// jsonEqual reports whether got and want encode the same JSON value.
+func jsonEqual(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		panic(fmt.Sprintf("invalid expected JSON %s: %v", want, err))
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,20 @@
+package federationtest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federationtest"
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGatewayCompatibility(t *testing.T) {
+	federationtest.RunCompatibilitySuite(t, func(t *testing.T, services []gateway.GatewayService) http.Handler {
+		gw, err := gateway.NewGateway(gateway.GatewayOption{Endpoint: "/graphql", Services: services})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		t.Cleanup(func() { gw.Close() })
+		return gw
+	})
+}
@@ -0,0 +1,271 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// AccessLogOption configures the access log of the GraphQL endpoint.
+type AccessLogOption struct {
+	Enable bool   `yaml:"enable" default:"false"`
+	Format string `yaml:"format" default:"json" validate:"oneof=json|common|combined"` // json, common or combined
+	Output string `yaml:"output"`                                                      // stdout (default), stderr or a file path
+	// SampleRate logs one request in SampleRate; every request when 0 or 1.
+	SampleRate int `yaml:"sample_rate" default:"1"`
+}
+
+// Formats of AccessLogOption.
+const (
+	accessLogJSON     = "json"
+	accessLogCommon   = "common"
+	accessLogCombined = "combined"
+)
+
+// accessLogger writes one line per sampled GraphQL request.
+type accessLogger struct {
+	format     string
+	sampleRate uint64
+	requests   atomic.Uint64
+
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer // The file written to, if any
+}
+
+// newAccessLogger returns the access logger of opt, or nil when it is disabled.
+func newAccessLogger(opt AccessLogOption) (*accessLogger, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+	format := opt.Format
+	switch format {
+	case "":
+		format = accessLogJSON
+	case accessLogJSON, accessLogCommon, accessLogCombined:
+	default:
+		return nil, fmt.Errorf("unknown access log format %q: use json, common or combined", opt.Format)
+	}
+	if opt.SampleRate < 0 {
+		return nil, fmt.Errorf("access log sample_rate must not be negative, got %d", opt.SampleRate)
+	}
+
+	l := &accessLogger{format: format, sampleRate: uint64(max(opt.SampleRate, 1))}
+	switch opt.Output {
+	case "", "stdout":
+		l.out = os.Stdout
+	case "stderr":
+		l.out = os.Stderr
+	default:
+		f, err := os.OpenFile(opt.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+		l.out, l.closer = f, f
+	}
+	return l, nil
+}
+
+// close closes the file the log is written to.
+func (l *accessLogger) close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// begin starts the entry of r when r is sampled, returning the writer the response is
+// written to; the entry is written by finish. It returns w and nil for requests that
+// are not sampled.
+func (l *accessLogger) begin(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *accessLogEntry) {
+	if l == nil || (l.requests.Add(1)-1)%l.sampleRate != 0 {
+		return w, nil
+	}
+	entry := &accessLogEntry{
+		logger:          l,
+		request:         r,
+		start:           time.Now(),
+		accessLogWriter: accessLogWriter{ResponseWriter: w},
+	}
+	return &entry.accessLogWriter, entry
+}
+
+// accessLogWriter records the status and size of a response.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// accessLogEntry is the access log entry of one request.
+type accessLogEntry struct {
+	logger  *accessLogger
+	request *http.Request
+	start   time.Time
+	accessLogWriter
+
+	operationNames   []string
+	variablesHashes  []string
+	subgraphRequests *costReport
+	client           ClientInfo
+}
+
+// setClient records the client of the request.
+func (e *accessLogEntry) setClient(client ClientInfo) {
+	if e == nil {
+		return
+	}
+	e.client = client
+}
+
+// addOperation records an operation of the request; batched requests have several.
+func (e *accessLogEntry) addOperation(req graphQLRequest) {
+	if e == nil {
+		return
+	}
+	e.operationNames = append(e.operationNames, req.OperationName)
+	e.variablesHashes = append(e.variablesHashes, variablesHash(req.Variables))
+}
+
+// variablesHash identifies the variables of an operation without logging their values.
+func variablesHash(variables map[string]any) string {
+	if len(variables) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(variables)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// finish writes the entry.
+func (e *accessLogEntry) finish() {
+	if e == nil {
+		return
+	}
+	duration := time.Since(e.start)
+	status := e.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	subgraphRequests := 0
+	if e.subgraphRequests != nil {
+		e.subgraphRequests.mu.Lock()
+		subgraphRequests = e.subgraphRequests.requests
+		e.subgraphRequests.mu.Unlock()
+	}
+	r := e.request
+	operationName := strings.Join(e.operationNames, ",")
+	variables := strings.Join(e.variablesHashes, ",")
+
+	var line []byte
+	if e.logger.format == accessLogJSON {
+		line, _ = json.Marshal(struct {
+			Time             string  `json:"time"`
+			RemoteAddr       string  `json:"remote_addr"`
+			Method           string  `json:"method"`
+			Path             string  `json:"path"`
+			Protocol         string  `json:"protocol"`
+			Status           int     `json:"status"`
+			Bytes            int     `json:"bytes"`
+			DurationMS       float64 `json:"duration_ms"`
+			OperationName    string  `json:"operation_name,omitempty"`
+			VariablesHash    string  `json:"variables_hash,omitempty"`
+			SubgraphRequests int     `json:"subgraph_requests"`
+			ClientName       string  `json:"client_name,omitempty"`
+			ClientVersion    string  `json:"client_version,omitempty"`
+			UserAgent        string  `json:"user_agent,omitempty"`
+			Referer          string  `json:"referer,omitempty"`
+		}{
+			Time:             e.start.UTC().Format(time.RFC3339Nano),
+			RemoteAddr:       remoteHost(r),
+			Method:           r.Method,
+			Path:             r.URL.RequestURI(),
+			Protocol:         r.Proto,
+			Status:           status,
+			Bytes:            e.bytes,
+			DurationMS:       float64(duration.Microseconds()) / 1000,
+			OperationName:    operationName,
+			VariablesHash:    variables,
+			SubgraphRequests: subgraphRequests,
+			ClientName:       e.client.Name,
+			ClientVersion:    e.client.Version,
+			UserAgent:        r.UserAgent(),
+			Referer:          r.Referer(),
+		})
+	} else {
+		// Apache common log format, or combined with the referer and user agent,
+		// followed by the GraphQL fields.
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s - - [%s] %q %d %s", remoteHost(r), e.start.Format("02/Jan/2006:15:04:05 -0700"),
+			r.Method+" "+r.URL.RequestURI()+" "+r.Proto, status, clfBytes(e.bytes))
+		if e.logger.format == accessLogCombined {
+			fmt.Fprintf(&sb, " %q %q", clfString(r.Referer()), clfString(r.UserAgent()))
+		}
+		fmt.Fprintf(&sb, " %q %s %d %d", clfString(operationName), clfString(variables), duration.Milliseconds(), subgraphRequests)
+		line = []byte(sb.String())
+	}
+
+	e.logger.mu.Lock()
+	defer e.logger.mu.Unlock()
+	e.logger.out.Write(append(line, '\n')) //nolint:errcheck
+}
+
+// remoteHost returns the client address of r without its port.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clfString returns s, or "-" for empty values as in the common log format.
+func clfString(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// clfBytes returns the response size in the common log format.
+func clfBytes(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,106 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_AccessLog(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "chair"}}}
+	})
+	const query = `{"query":"query Product($id: ID!) { product(id: $id) { name } }","operationName":"Product","variables":{"id":"1"}}`
+
+	newGateway := func(t *testing.T, opt gateway.AccessLogOption) (http.Handler, string) {
+		t.Helper()
+		opt.Enable = true
+		opt.Output = filepath.Join(t.TempDir(), "access.log")
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:  "/graphql",
+			Services:  []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			AccessLog: opt,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		t.Cleanup(func() { gw.Close() })
+		return gw, opt.Output
+	}
+	serve := func(gw http.Handler) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query))
+		req.Header.Set("User-Agent", "test-client")
+		gw.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	readLines := func(t *testing.T, path string) []string {
+		t.Helper()
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(b)), "\n")
+	}
+
+	t.Run("json", func(t *testing.T) {
+		gw, path := newGateway(t, gateway.AccessLogOption{Format: "json"})
+		serve(gw)
+
+		lines := readLines(t, path)
+		if len(lines) != 1 {
+			t.Fatalf("expected 1 line, got %q", lines)
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+			t.Fatalf("expected a JSON line, got %s", lines[0])
+		}
+		if entry["operation_name"] != "Product" || entry["status"] != float64(200) || entry["subgraph_requests"] != float64(1) {
+			t.Errorf("expected operation Product, status 200 and 1 subgraph request, got %s", lines[0])
+		}
+		if hash, _ := entry["variables_hash"].(string); len(hash) != 16 || strings.Contains(lines[0], `"id"`) {
+			t.Errorf("expected the variables to be logged as a hash only, got %s", lines[0])
+		}
+		if bytes, _ := entry["bytes"].(float64); bytes == 0 {
+			t.Errorf("expected the response size, got %s", lines[0])
+		}
+	})
+
+	t.Run("combined", func(t *testing.T) {
+		gw, path := newGateway(t, gateway.AccessLogOption{Format: "combined"})
+		serve(gw)
+
+		lines := readLines(t, path)
+		pattern := regexp.MustCompile(`^192\.0\.2\.1 - - \[[^\]]+\] "POST /graphql HTTP/1\.1" 200 \d+ "-" "test-client" "Product" [0-9a-f]{16} \d+ 1$`)
+		if len(lines) != 1 || !pattern.MatchString(lines[0]) {
+			t.Errorf("expected a combined log line, got %q", lines)
+		}
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		gw, path := newGateway(t, gateway.AccessLogOption{SampleRate: 3})
+		for range 4 {
+			serve(gw)
+		}
+		if lines := readLines(t, path); len(lines) != 2 {
+			t.Errorf("expected requests 1 and 4 to be logged, got %q", lines)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:  "/graphql",
+			Services:  []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			AccessLog: gateway.AccessLogOption{Enable: true, Format: "xml"},
+		})
+		if err == nil || !strings.Contains(err.Error(), `unknown access log format "xml"`) {
+			t.Fatalf("expected an unknown format error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_AliasedEntityFields(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+	const reviewsSDL = `
+		type Review @key(fields: "id") {
+			id: ID!
+			body: String!
+			product: Product
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review!]!
+		}
+
+		type Query {
+			review(id: ID!): Review
+		}
+	`
+
+	// resolveEntities answers _entities requests with resolve applied to each representation.
+	resolveEntities := func(resolve func(rep map[string]any) map[string]any) func(body map[string]any) any {
+		return func(body map[string]any) any {
+			vars, _ := body["variables"].(map[string]any)
+			reps, _ := vars["representations"].([]any)
+			result := make([]any, 0, len(reps))
+			for _, r := range reps {
+				result = append(result, resolve(r.(map[string]any)))
+			}
+			return map[string]any{"data": map[string]any{"_entities": result}}
+		}
+	}
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		query, _ := body["query"].(string)
+		if strings.Contains(query, "_entities") {
+			return resolveEntities(func(rep map[string]any) map[string]any {
+				return map[string]any{"__typename": "Product", "id": rep["id"], "name": "product-" + rep["id"].(string)}
+			})(body)
+		}
+		return map[string]any{"data": map[string]any{
+			"p1": map[string]any{"__typename": "Product", "id": "1", "name": "product-1"},
+			"p2": map[string]any{"__typename": "Product", "id": "2", "name": "product-2"},
+		}}
+	})
+	reviews := newSubgraphServer(t, reviewsSDL, func(body map[string]any) any {
+		query, _ := body["query"].(string)
+		if strings.Contains(query, "_entities") {
+			return resolveEntities(func(rep map[string]any) map[string]any {
+				return map[string]any{"__typename": "Product", "id": rep["id"], "reviews": []any{
+					map[string]any{"__typename": "Review", "id": "r" + rep["id"].(string), "body": "review of " + rep["id"].(string)},
+				}}
+			})(body)
+		}
+		return map[string]any{"data": map[string]any{"review": map[string]any{
+			"__typename": "Review", "id": "r1",
+			"first":  map[string]any{"__typename": "Product", "id": "1"},
+			"second": map[string]any{"__typename": "Product", "id": "2"},
+		}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "reviews", Host: reviews.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "aliased root fields",
+			query: `{ p1: product(id: \"1\") { name reviews { body } } p2: product(id: \"2\") { name reviews { body } } }`,
+			want:  `{"data":{"p1":{"name":"product-1","reviews":[{"body":"review of 1"}]},"p2":{"name":"product-2","reviews":[{"body":"review of 2"}]}}}`,
+		},
+		{
+			name:  "aliased entity references",
+			query: `{ review(id: \"r1\") { first: product { name } second: product { name } } }`,
+			want:  `{"data":{"review":{"first":{"name":"product-1"},"second":{"name":"product-2"}}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"`+tt.query+`"}`)))
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
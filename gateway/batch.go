@@ -0,0 +1,27 @@
+package gateway
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// executeBatch executes a batched request (a JSON array of operations). Operations
+// share ctx and run concurrently, at most batchMaxConcurrency at a time; responses
+// are returned in request order. Per-operation HTTP statuses are not reported since
+// the batch is answered as a whole.
+func (g *gateway) executeBatch(ctx context.Context, engine *executionEngine, reqs []graphQLRequest) []any {
+	responses := make([]any, len(reqs))
+
+	var eg errgroup.Group
+	eg.SetLimit(g.batchMaxConcurrency)
+	for i, req := range reqs {
+		eg.Go(func() error {
+			_, responses[i] = g.executeRequest(ctx, engine, req)
+			return nil
+		})
+	}
+	eg.Wait() //nolint:errcheck
+
+	return responses
+}
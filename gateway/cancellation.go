@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// statusClientClosedRequest is the non-standard status logged for operations abandoned
+// by the client, following nginx. The client never receives it.
+const statusClientClosedRequest = 499
+
+// cancellationCounter counts operations abandoned because the client closed the
+// connection, in the graphql.request.cancelled metric.
+type cancellationCounter struct {
+	counter metric.Int64Counter
+	labels  metric.MeasurementOption
+}
+
+func newCancellationCounter(labels metric.MeasurementOption) (*cancellationCounter, error) {
+	counter, err := otel.Meter("github.com/n9te9/go-graphql-federation-gateway").Int64Counter(
+		"graphql.request.cancelled",
+		metric.WithDescription("Number of operations whose subgraph requests were aborted because the client disconnected"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cancelled request counter: %w", err)
+	}
+	return &cancellationCounter{counter: counter, labels: labels}, nil
+}
+
+// record counts one cancelled operation of operationType. ctx is already cancelled, so
+// only its values are used.
+func (c *cancellationCounter) record(ctx context.Context, operationType string) {
+	attrs := append([]attribute.KeyValue{attribute.String("graphql.operation.type", operationType)},
+		ClientInfoFromContext(ctx).attributes()...)
+	c.counter.Add(context.WithoutCancel(ctx), 1,
+		metric.WithAttributes(attrs...),
+		c.labels,
+	)
+}
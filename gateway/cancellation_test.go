@@ -0,0 +1,102 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ClientDisconnect(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean!
+		}
+	`
+
+	received := make(chan struct{})
+	aborted := make(chan struct{})
+	products := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": productsSDL}}}) //nolint:errcheck
+			return
+		}
+
+		close(received)
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(5 * time.Second):
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"topProducts": []any{ //nolint:errcheck
+				map[string]any{"__typename": "Product", "id": "1"},
+			}}})
+		}
+	}))
+	t.Cleanup(products.Close)
+
+	var inventoryCalls atomic.Int32
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		inventoryCalls.Add(1)
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "Product", "id": "1", "inStock": true},
+		}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-received
+		cancel()
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ topProducts { id inStock } }"}`)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	gw.ServeHTTP(rec, req)
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the gateway to return on disconnect, took %v", elapsed)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the subgraph request to be aborted")
+	}
+	if n := inventoryCalls.Load(); n != 0 {
+		t.Errorf("expected the entity step to be skipped, got %d inventory requests", n)
+	}
+	if rec.Code != 499 {
+		t.Errorf("expected status 499, got %d", rec.Code)
+	}
+}
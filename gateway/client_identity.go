@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Headers naming the client and its version by default, as sent by Apollo clients.
+const (
+	defaultClientNameHeader    = "apollographql-client-name"
+	defaultClientVersionHeader = "apollographql-client-version"
+)
+
+// ClientIdentityOption configures how clients identify themselves. The first non-empty
+// header of each list names the client and its version; the identity is attached to
+// metrics, spans, usage reports, @rateLimit counters and logs.
+type ClientIdentityOption struct {
+	NameHeaders    []string `yaml:"name_headers"`    // Defaults to apollographql-client-name
+	VersionHeaders []string `yaml:"version_headers"` // Defaults to apollographql-client-version
+}
+
+// ClientInfo identifies the client sending an operation. Fields are empty when the
+// client did not send them.
+type ClientInfo struct {
+	Name    string
+	Version string
+}
+
+type clientInfoContextKey struct{}
+
+// ClientInfoFromContext returns the client of the operation executed with ctx.
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoContextKey{}).(ClientInfo)
+	return info
+}
+
+// attributes returns the span and metric attributes of the fields of c that are set.
+func (c ClientInfo) attributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if c.Name != "" {
+		attrs = append(attrs, attribute.String("graphql.client.name", c.Name))
+	}
+	if c.Version != "" {
+		attrs = append(attrs, attribute.String("graphql.client.version", c.Version))
+	}
+	return attrs
+}
+
+// clientIdentity reads the ClientInfo of requests.
+type clientIdentity struct {
+	nameHeaders    []string
+	versionHeaders []string
+}
+
+func newClientIdentity(opt ClientIdentityOption) *clientIdentity {
+	c := &clientIdentity{nameHeaders: opt.NameHeaders, versionHeaders: opt.VersionHeaders}
+	if len(c.nameHeaders) == 0 {
+		c.nameHeaders = []string{defaultClientNameHeader}
+	}
+	if len(c.versionHeaders) == 0 {
+		c.versionHeaders = []string{defaultClientVersionHeader}
+	}
+	return c
+}
+
+// withClient attaches the client of r to ctx.
+func (c *clientIdentity) withClient(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, clientInfoContextKey{}, ClientInfo{
+		Name:    firstHeader(r.Header, c.nameHeaders),
+		Version: firstHeader(r.Header, c.versionHeaders),
+	})
+}
+
+// firstHeader returns the first non-empty value of names in h.
+func firstHeader(h http.Header, names []string) string {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+type clientExtension struct{}
+
+func (clientExtension) Name() string { return "client" }
+
+func (clientExtension) Extension(ctx context.Context, info *gateway.ExecutionInfo) (any, bool) {
+	if gateway.ClientInfoFromContext(ctx) != info.Client {
+		return "context and execution info disagree", true
+	}
+	return info.Client.Name + "@" + info.Client.Version, true
+}
+
+func TestGateway_ClientIdentity(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "chair"}}}
+	})
+
+	serve := func(t *testing.T, opt gateway.ClientIdentityOption, header http.Header) (string, map[string]any) {
+		t.Helper()
+		accessLog := filepath.Join(t.TempDir(), "access.log")
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:           "/graphql",
+			Services:           []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			ClientIdentity:     opt,
+			AccessLog:          gateway.AccessLogOption{Enable: true, Output: accessLog},
+			ExtensionProviders: []gateway.ExtensionProvider{clientExtension{}},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		t.Cleanup(func() { gw.Close() })
+
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { name } }"}`))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		extensions, _ := resp["extensions"].(map[string]any)
+		client, _ := extensions["client"].(string)
+
+		line, err := os.ReadFile(accessLog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("expected a JSON access log line, got %s", line)
+		}
+		return client, entry
+	}
+
+	t.Run("apollo headers by default", func(t *testing.T) {
+		client, entry := serve(t, gateway.ClientIdentityOption{}, http.Header{
+			"Apollographql-Client-Name":    {"web"},
+			"Apollographql-Client-Version": {"1.2.0"},
+		})
+		if client != "web@1.2.0" {
+			t.Errorf("expected web@1.2.0, got %q", client)
+		}
+		if entry["client_name"] != "web" || entry["client_version"] != "1.2.0" {
+			t.Errorf("expected the client in the access log, got %v", entry)
+		}
+	})
+
+	t.Run("first configured header sent", func(t *testing.T) {
+		opt := gateway.ClientIdentityOption{
+			NameHeaders:    []string{"X-Client-Name", "apollographql-client-name"},
+			VersionHeaders: []string{"X-Client-Version"},
+		}
+		client, _ := serve(t, opt, http.Header{
+			"Apollographql-Client-Name": {"web"},
+			"X-Client-Version":          {"2.0"},
+		})
+		if client != "web@2.0" {
+			t.Errorf("expected web@2.0, got %q", client)
+		}
+
+		client, _ = serve(t, opt, http.Header{
+			"X-Client-Name":             {"ios"},
+			"Apollographql-Client-Name": {"web"},
+		})
+		if client != "ios@" {
+			t.Errorf("expected ios without version, got %q", client)
+		}
+	})
+
+	t.Run("anonymous client", func(t *testing.T) {
+		client, entry := serve(t, gateway.ClientIdentityOption{}, nil)
+		if client != "@" {
+			t.Errorf("expected an empty identity, got %q", client)
+		}
+		if _, ok := entry["client_name"]; ok {
+			t.Errorf("expected no client in the access log, got %v", entry)
+		}
+	})
+}
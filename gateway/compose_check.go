@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// composeCheckPath runs composition against a candidate subgraph SDL without applying it.
+const composeCheckPath = "/admin/compose/check"
+
+// composeCheckRequest is the body of a compose check request.
+type composeCheckRequest struct {
+	Name string `json:"name"`
+	SDL  string `json:"sdl"`
+}
+
+// composeCheckResult reports whether a candidate SDL composes with the live subgraphs.
+type composeCheckResult struct {
+	OK       bool     `json:"ok"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// handleComposeCheck composes the candidate SDL of one subgraph with the current SDLs of
+// the others and reports composition errors and warnings, such as fields removed from the
+// supergraph, without installing anything. It answers 422 when composition fails.
+func (g *gateway) handleComposeCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req composeCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"error": fmt.Sprintf("invalid compose check request: %v", err)}) //nolint:errcheck
+		return
+	}
+	if req.Name == "" || req.SDL == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"error": "name and sdl are required"}) //nolint:errcheck
+		return
+	}
+
+	result := g.checkComposition(req.Name, req.SDL)
+	status := http.StatusOK
+	if !result.OK {
+		status = http.StatusUnprocessableEntity
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result) //nolint:errcheck
+}
+
+// checkComposition composes sdl as subgraph name with the current SDLs of the others.
+func (g *gateway) checkComposition(name, sdl string) composeCheckResult {
+	result := composeCheckResult{Errors: []string{}, Warnings: []string{}}
+	current := g.currentStore()
+
+	hosts := current.hosts
+	if _, ok := current.hosts[name]; !ok {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("subgraph %q is not configured on this gateway", name))
+		hosts = copyMap(current.hosts)
+		hosts[name] = ""
+	}
+
+	sdls := copyMap(current.sdls)
+	sdls[name] = sdl
+	engine, err := buildEngine(sdls, hosts, g.httpClient)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	result.OK = true
+	result.Warnings = append(result.Warnings, removedFields(current.engine.superGraph.Schema, engine.superGraph.Schema)...)
+	return result
+}
+
+// removedFields lists the types and fields of before that are missing from after.
+// Removing them breaks clients that still select them.
+func removedFields(before, after *ast.Document) []string {
+	beforeFields := schemaFields(before)
+	afterFields := schemaFields(after)
+
+	var warnings []string
+	for typeName, fields := range beforeFields {
+		remaining, ok := afterFields[typeName]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("type %s is removed", typeName))
+			continue
+		}
+		for field := range fields {
+			if !remaining[field] {
+				warnings = append(warnings, fmt.Sprintf("field %s.%s is removed", typeName, field))
+			}
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// schemaFields maps the object and interface types of doc to their field names.
+func schemaFields(doc *ast.Document) map[string]map[string]bool {
+	types := make(map[string]map[string]bool)
+	add := func(name string, fields []*ast.FieldDefinition) {
+		if types[name] == nil {
+			types[name] = make(map[string]bool)
+		}
+		for _, f := range fields {
+			types[name][f.Name.String()] = true
+		}
+	}
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			add(d.Name.String(), d.Fields)
+		case *ast.ObjectTypeExtension:
+			add(d.Name.String(), d.Fields)
+		case *ast.InterfaceTypeDefinition:
+			add(d.Name.String(), d.Fields)
+		}
+	}
+	return types
+}
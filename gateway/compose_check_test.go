@@ -0,0 +1,111 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ComposeCheck(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any { return map[string]any{"data": nil} })
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:           "/graphql",
+		EnableSubgraphMode: true,
+		Services:           []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	defer gw.Close()
+
+	check := func(body string) (int, map[string]any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/compose/check", strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, resp
+	}
+	encode := func(name, sdl string) string {
+		b, _ := json.Marshal(map[string]string{"name": name, "sdl": sdl})
+		return string(b)
+	}
+
+	t.Run("removed field", func(t *testing.T) {
+		code, resp := check(encode("products", `
+			type Product @key(fields: "id") {
+				id: ID!
+			}
+
+			type Query {
+				product(id: ID!): Product
+			}
+		`))
+		if code != http.StatusOK || resp["ok"] != true {
+			t.Fatalf("expected the candidate to compose, got %d %v", code, resp)
+		}
+		warnings, _ := resp["warnings"].([]any)
+		if len(warnings) != 1 || warnings[0] != "field Product.name is removed" {
+			t.Errorf("expected a removed field warning, got %v", warnings)
+		}
+	})
+
+	t.Run("new subgraph", func(t *testing.T) {
+		code, resp := check(encode("reviews", `
+			type Review {
+				body: String!
+			}
+
+			extend type Product @key(fields: "id") {
+				id: ID! @external
+				reviews: [Review]
+			}
+		`))
+		if code != http.StatusOK || resp["ok"] != true {
+			t.Fatalf("expected the candidate to compose, got %d %v", code, resp)
+		}
+		warnings, _ := resp["warnings"].([]any)
+		if len(warnings) != 1 || !strings.Contains(warnings[0].(string), "not configured") {
+			t.Errorf("expected an unconfigured subgraph warning, got %v", warnings)
+		}
+	})
+
+	t.Run("composition error", func(t *testing.T) {
+		code, resp := check(encode("accounts", `
+			schema {
+				query: RootQuery
+			}
+
+			type RootQuery {
+				me: String
+			}
+		`))
+		if code != http.StatusUnprocessableEntity || resp["ok"] != false {
+			t.Fatalf("expected composition to fail, got %d %v", code, resp)
+		}
+		if errs, _ := resp["errors"].([]any); len(errs) == 0 {
+			t.Error("expected composition errors")
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		if code, _ := check(`{"name": "products"}`); code != http.StatusBadRequest {
+			t.Errorf("expected 400 without sdl, got %d", code)
+		}
+	})
+
+	// Checks never change the served schema.
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ _service { sdl } }"}`)))
+	if !strings.Contains(rec.Body.String(), "name: String") || strings.Contains(rec.Body.String(), "Review") {
+		t.Errorf("expected the live schema to be unchanged, got %s", rec.Body.String())
+	}
+}
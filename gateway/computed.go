@@ -0,0 +1,615 @@
+package gateway
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/token"
+)
+
+// ComputedFieldOption defines a field the gateway adds to a type and computes from other
+// fields of the same object once the response is merged, so that presentation fields need
+// no subgraph change. The expression reads fields by name, nested ones with dots, and
+// combines them with the functions concat, format, upper, lower and coalesce.
+type ComputedFieldOption struct {
+	Field      string `yaml:"field"`      // Coordinate of the new field, e.g. Product.displayPrice
+	Type       string `yaml:"type"`       // GraphQL type of the field; String when unset
+	Expression string `yaml:"expression"` // e.g. format(price, currency)
+}
+
+// computedField is a compiled ComputedFieldOption.
+type computedField struct {
+	typeName  string
+	fieldName string
+	typ       string
+	expr      computedExpr
+	// requires are the fields the expression reads, selected in place of the field.
+	requires []ast.Selection
+}
+
+// computedFields evaluates the configured computed fields.
+type computedFields struct {
+	fields map[string]*computedField // keyed by coordinate
+	order  []*computedField          // configuration order, for printing
+}
+
+// newComputedFields compiles opts, returning nil when there are none.
+func newComputedFields(opts []ComputedFieldOption) (*computedFields, error) {
+	if len(opts) == 0 {
+		return nil, nil
+	}
+	c := &computedFields{fields: make(map[string]*computedField, len(opts))}
+	for i, opt := range opts {
+		typeName, fieldName, ok := strings.Cut(opt.Field, ".")
+		if !ok || typeName == "" || fieldName == "" {
+			return nil, fmt.Errorf("computed_fields[%d]: field %q is not a Type.field coordinate", i, opt.Field)
+		}
+		if _, exists := c.fields[opt.Field]; exists {
+			return nil, fmt.Errorf("computed_fields[%d]: %s is defined twice", i, opt.Field)
+		}
+		typ := opt.Type
+		if typ == "" {
+			typ = "String"
+		}
+		switch strings.TrimSuffix(typ, "!") {
+		case "String", "Int", "Float", "Boolean", "ID":
+		default:
+			return nil, fmt.Errorf("computed_fields[%d]: type %q of %s is not a built-in scalar", i, typ, opt.Field)
+		}
+		expr, err := parseComputedExpr(opt.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("computed_fields[%d]: invalid expression of %s: %w", i, opt.Field, err)
+		}
+		field := &computedField{typeName: typeName, fieldName: fieldName, typ: typ, expr: expr}
+		var paths [][]string
+		expr.paths(&paths)
+		for _, path := range paths {
+			field.requires = mergeRequiredPath(field.requires, path)
+		}
+		c.fields[opt.Field] = field
+		c.order = append(c.order, field)
+	}
+	return c, nil
+}
+
+// has reports whether typeName.fieldName is a computed field.
+func (c *computedFields) has(typeName, fieldName string) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.fields[typeName+"."+fieldName]
+	return ok
+}
+
+// sdl returns the computed fields as type extensions, to complete the composed schema.
+func (c *computedFields) sdl() string {
+	if c == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, field := range c.order {
+		fmt.Fprintf(&sb, "\nextend type %s {\n  %s: %s\n}\n", field.typeName, field.fieldName, field.typ)
+	}
+	return sb.String()
+}
+
+// rewrite returns the document to plan, with the computed fields its operation named
+// operationName selects replaced by the fields their expressions read, and whether it
+// changed.
+func (c *computedFields) rewrite(doc *ast.Document, operationName string, engine *executionEngine) (*ast.Document, bool) {
+	if c == nil {
+		return doc, false
+	}
+	_, op, err := selectOperation(doc, operationName)
+	if err != nil {
+		return doc, false
+	}
+	w := computedRewriter{fields: c, fragmentDefs: fragmentDefinitions(doc), visited: make(map[string]bool), engine: engine}
+	selections, changed := w.rewriteSelections(op.SelectionSet, engine.superGraph.RootTypeName(op.Operation))
+	if !changed {
+		return doc, false
+	}
+
+	rewrittenOp := *op
+	rewrittenOp.SelectionSet = selections
+	rewritten := &ast.Document{Definitions: make([]ast.Definition, 0, len(doc.Definitions))}
+	for _, def := range doc.Definitions {
+		if def == op {
+			def = &rewrittenOp
+		}
+		rewritten.Definitions = append(rewritten.Definitions, def)
+	}
+	return rewritten, true
+}
+
+// computedRewriter implements computedFields.rewrite.
+type computedRewriter struct {
+	fields       *computedFields
+	fragmentDefs map[string]*ast.FragmentDefinition
+	visited      map[string]bool
+	engine       *executionEngine
+}
+
+// rewriteSelections returns selections on typeName with their computed fields replaced,
+// and whether any changed. Fragment spreads containing computed fields become inline
+// fragments.
+func (w computedRewriter) rewriteSelections(selections []ast.Selection, typeName string) ([]ast.Selection, bool) {
+	kept := make([]ast.Selection, 0, len(selections))
+	var requires []ast.Selection
+	changed := false
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			name := s.Name.String()
+			if field, ok := w.fields.fields[typeName+"."+name]; ok {
+				requires = append(requires, field.requires...)
+				changed = true
+				continue
+			}
+			if len(s.SelectionSet) == 0 {
+				break
+			}
+			fieldDef := w.engine.superGraph.FieldDefinition(typeName, name)
+			if fieldDef == nil {
+				break
+			}
+			if inner, innerChanged := w.rewriteSelections(s.SelectionSet, unwrapNamedType(fieldDef.Type)); innerChanged {
+				field := *s
+				field.SelectionSet = inner
+				sel, changed = &field, true
+			}
+		case *ast.InlineFragment:
+			fragmentType := typeName
+			if s.TypeCondition != nil {
+				fragmentType = s.TypeCondition.Name.String()
+			}
+			if inner, innerChanged := w.rewriteSelections(s.SelectionSet, fragmentType); innerChanged {
+				fragment := *s
+				fragment.SelectionSet = inner
+				sel, changed = &fragment, true
+			}
+		case *ast.FragmentSpread:
+			name := s.Name.String()
+			fragDef, ok := w.fragmentDefs[name]
+			if !ok || w.visited[name] {
+				break
+			}
+			w.visited[name] = true
+			inner, innerChanged := w.rewriteSelections(fragDef.SelectionSet, fragDef.TypeCondition.Name.String())
+			delete(w.visited, name)
+			if innerChanged {
+				sel = &ast.InlineFragment{Token: s.Token, TypeCondition: fragDef.TypeCondition, Directives: s.Directives, SelectionSet: inner}
+				changed = true
+			}
+		}
+		kept = append(kept, sel)
+	}
+	for _, sel := range requires {
+		kept = mergeRequiredSelection(kept, sel.(*ast.Field))
+	}
+	return kept, changed
+}
+
+// mergeRequiredSelection adds required to selections, unless a field with its response
+// key is already selected, in which case their nested selections are merged.
+func mergeRequiredSelection(selections []ast.Selection, required *ast.Field) []ast.Selection {
+	for i, sel := range selections {
+		field, ok := sel.(*ast.Field)
+		if !ok || fieldResponseKey(field) != required.Name.String() {
+			continue
+		}
+		if len(required.SelectionSet) > 0 {
+			merged := *field
+			merged.SelectionSet = append([]ast.Selection{}, field.SelectionSet...)
+			for _, child := range required.SelectionSet {
+				merged.SelectionSet = mergeRequiredSelection(merged.SelectionSet, child.(*ast.Field))
+			}
+			selections[i] = &merged
+		}
+		return selections
+	}
+	return append(selections, required)
+}
+
+// mergeRequiredPath adds the field at path, such as author.name, to selections.
+func mergeRequiredPath(selections []ast.Selection, path []string) []ast.Selection {
+	field := newComputedSelection(path[0])
+	if len(path) > 1 {
+		field.SelectionSet = mergeRequiredPath(nil, path[1:])
+	}
+	return mergeRequiredSelection(selections, field)
+}
+
+func newComputedSelection(name string) *ast.Field {
+	return &ast.Field{Name: &ast.Name{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}}
+}
+
+// apply sets the computed fields the client selected in the data of resp, then drops the
+// fields selected only for them.
+func (c *computedFields) apply(resp map[string]any, doc *ast.Document, op *ast.OperationDefinition, engine *executionEngine) {
+	data, ok := resp["data"].(map[string]any)
+	if !ok {
+		return
+	}
+	w := &computedWalker{fields: c, fragmentDefs: fragmentDefinitions(doc), engine: engine}
+	w.walkObject(data, op.SelectionSet, engine.superGraph.RootTypeName(op.Operation))
+}
+
+type computedWalker struct {
+	fields       *computedFields
+	fragmentDefs map[string]*ast.FragmentDefinition
+	engine       *executionEngine
+}
+
+// walkObject computes the fields of obj selected by selections on typeName and removes
+// the keys they do not select.
+func (w *computedWalker) walkObject(obj map[string]any, selections []ast.Selection, typeName string) {
+	selected := make(map[string]bool)
+	w.walkSelections(obj, selections, typeName, selected, make(map[string]bool))
+	for key := range obj {
+		if !selected[key] {
+			delete(obj, key)
+		}
+	}
+}
+
+func (w *computedWalker) walkSelections(obj map[string]any, selections []ast.Selection, typeName string, selected, visited map[string]bool) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			key := fieldResponseKey(s)
+			selected[key] = true
+			name := s.Name.String()
+			if field, ok := w.fields.fields[typeName+"."+name]; ok {
+				obj[key] = field.evaluate(obj)
+				continue
+			}
+			if len(s.SelectionSet) == 0 {
+				continue
+			}
+			if fieldDef := w.engine.superGraph.FieldDefinition(typeName, name); fieldDef != nil {
+				w.walkValue(obj[key], s.SelectionSet, unwrapNamedType(fieldDef.Type))
+			}
+		case *ast.InlineFragment:
+			fragmentType := typeName
+			if s.TypeCondition != nil {
+				fragmentType = s.TypeCondition.Name.String()
+			}
+			if w.matchesType(obj, fragmentType) {
+				w.walkSelections(obj, s.SelectionSet, fragmentType, selected, visited)
+			}
+		case *ast.FragmentSpread:
+			name := s.Name.String()
+			fragDef, ok := w.fragmentDefs[name]
+			if !ok || visited[name] {
+				continue
+			}
+			fragmentType := fragDef.TypeCondition.Name.String()
+			if w.matchesType(obj, fragmentType) {
+				visited[name] = true
+				w.walkSelections(obj, fragDef.SelectionSet, fragmentType, selected, visited)
+				delete(visited, name)
+			}
+		}
+	}
+}
+
+// matchesType reports whether obj may be of typeName, like transformWalker.matchesType.
+func (w *computedWalker) matchesType(obj map[string]any, typeName string) bool {
+	actual, ok := obj["__typename"].(string)
+	if !ok || actual == typeName {
+		return true
+	}
+	return isPossibleType(w.engine, typeName, actual)
+}
+
+func (w *computedWalker) walkValue(value any, selections []ast.Selection, typeName string) {
+	switch v := value.(type) {
+	case map[string]any:
+		w.walkObject(v, selections, typeName)
+	case []any:
+		for _, item := range v {
+			w.walkValue(item, selections, typeName)
+		}
+	}
+}
+
+// evaluate computes the field on obj, converting the result to its type.
+func (f *computedField) evaluate(obj map[string]any) any {
+	value := f.expr.eval(obj)
+	if value == nil {
+		return nil
+	}
+	switch strings.TrimSuffix(f.typ, "!") {
+	case "String", "ID":
+		return computedString(value)
+	}
+	return value
+}
+
+// computedExpr is a node of a computed field expression.
+type computedExpr interface {
+	eval(obj map[string]any) any
+	paths(paths *[][]string)
+}
+
+// computedPath reads a field, nested through objects.
+type computedPath []string
+
+func (p computedPath) eval(obj map[string]any) any {
+	var value any = obj
+	for _, name := range p {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[name]
+	}
+	return value
+}
+
+func (p computedPath) paths(paths *[][]string) { *paths = append(*paths, p) }
+
+// computedLiteral is a string, number, boolean or null literal.
+type computedLiteral struct{ value any }
+
+func (l computedLiteral) eval(map[string]any) any { return l.value }
+func (l computedLiteral) paths(*[][]string)       {}
+
+// computedCall applies a function to its arguments.
+type computedCall struct {
+	fn   computedFunc
+	args []computedExpr
+}
+
+func (c computedCall) eval(obj map[string]any) any {
+	args := make([]any, len(c.args))
+	for i, arg := range c.args {
+		args[i] = arg.eval(obj)
+	}
+	return c.fn(args)
+}
+
+func (c computedCall) paths(paths *[][]string) {
+	for _, arg := range c.args {
+		arg.paths(paths)
+	}
+}
+
+// computedFunc is a function of computed field expressions. Functions other than
+// coalesce return null when an argument is null.
+type computedFunc func(args []any) any
+
+var computedFuncs = map[string]computedFunc{
+	// concat joins its arguments.
+	"concat": nullSafe(func(args []any) any {
+		var sb strings.Builder
+		for _, arg := range args {
+			sb.WriteString(computedString(arg))
+		}
+		return sb.String()
+	}),
+	// format joins its arguments with spaces, writing numbers with two decimals, as in
+	// format(price, currency) = "12.50 EUR".
+	"format": nullSafe(func(args []any) any {
+		parts := make([]string, len(args))
+		for i, arg := range args {
+			if n, ok := computedNumber(arg); ok {
+				parts[i] = strconv.FormatFloat(n, 'f', 2, 64)
+			} else {
+				parts[i] = computedString(arg)
+			}
+		}
+		return strings.Join(parts, " ")
+	}),
+	"upper": nullSafe(func(args []any) any {
+		if len(args) != 1 {
+			return nil
+		}
+		return strings.ToUpper(computedString(args[0]))
+	}),
+	"lower": nullSafe(func(args []any) any {
+		if len(args) != 1 {
+			return nil
+		}
+		return strings.ToLower(computedString(args[0]))
+	}),
+	// coalesce returns its first non-null argument.
+	"coalesce": func(args []any) any {
+		for _, arg := range args {
+			if arg != nil {
+				return arg
+			}
+		}
+		return nil
+	},
+}
+
+func nullSafe(fn computedFunc) computedFunc {
+	return func(args []any) any {
+		for _, arg := range args {
+			if arg == nil {
+				return nil
+			}
+		}
+		return fn(args)
+	}
+}
+
+// computedNumber returns value as a float when it is a number.
+func computedNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// computedString returns the string form of a scalar value.
+func computedString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// parseComputedExpr parses an expression such as format(price, currency).
+func parseComputedExpr(src string) (computedExpr, error) {
+	p := &computedParser{src: src}
+	expr, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.src[p.pos:], p.pos)
+	}
+	return expr, nil
+}
+
+type computedParser struct {
+	src string
+	pos int
+}
+
+func (p *computedParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space byte, or 0 at the end.
+func (p *computedParser) peek() byte {
+	p.skipSpace()
+	if p.pos == len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *computedParser) expr() (computedExpr, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '"':
+		return p.stringLiteral()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.numberLiteral()
+	case isNameStart(c):
+		return p.nameExpr()
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", c, p.pos)
+}
+
+func (p *computedParser) stringLiteral() (computedExpr, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '"':
+			p.pos++
+			value, err := strconv.Unquote(p.src[start:p.pos])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", p.src[start:p.pos])
+			}
+			return computedLiteral{value: value}, nil
+		}
+	}
+	return nil, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+func (p *computedParser) numberLiteral() (computedExpr, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) && (p.src[p.pos] == '.' || (p.src[p.pos] >= '0' && p.src[p.pos] <= '9')) {
+		p.pos++
+	}
+	if _, err := strconv.ParseFloat(p.src[start:p.pos], 64); err != nil {
+		return nil, fmt.Errorf("invalid number %s", p.src[start:p.pos])
+	}
+	return computedLiteral{value: json.Number(p.src[start:p.pos])}, nil
+}
+
+func (p *computedParser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) && (isNameStart(p.src[p.pos]) || (p.src[p.pos] >= '0' && p.src[p.pos] <= '9')) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// nameExpr parses a literal keyword, a function call or a field path.
+func (p *computedParser) nameExpr() (computedExpr, error) {
+	start := p.pos
+	name := p.name()
+	switch name {
+	case "true":
+		return computedLiteral{value: true}, nil
+	case "false":
+		return computedLiteral{value: false}, nil
+	case "null":
+		return computedLiteral{value: nil}, nil
+	}
+
+	if p.peek() == '(' {
+		fn, ok := computedFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %s at offset %d", name, start)
+		}
+		p.pos++
+		call := computedCall{fn: fn}
+		if p.peek() == ')' {
+			p.pos++
+			return call, nil
+		}
+		for {
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			switch p.peek() {
+			case ',':
+				p.pos++
+			case ')':
+				p.pos++
+				return call, nil
+			default:
+				return nil, fmt.Errorf("expected , or ) at offset %d", p.pos)
+			}
+		}
+	}
+
+	path := computedPath{name}
+	for p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		if p.pos == len(p.src) || !isNameStart(p.src[p.pos]) {
+			return nil, fmt.Errorf("expected a field name at offset %d", p.pos)
+		}
+		path = append(path, p.name())
+	}
+	return path, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,109 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ComputedFields(t *testing.T) {
+	const sdl = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			price: Float
+			currency: String!
+			author: Author!
+		}
+
+		type Author {
+			name: String!
+		}
+
+		type Query {
+			products: [Product!]!
+		}
+	`
+
+	var mu sync.Mutex
+	var queries []string
+	products := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		mu.Lock()
+		queries = append(queries, body["query"].(string))
+		mu.Unlock()
+		return map[string]any{"data": map[string]any{"products": []any{
+			map[string]any{"id": "1", "name": "book", "price": 12.5, "currency": "EUR", "author": map[string]any{"name": "ann"}},
+			map[string]any{"id": "2", "name": "pen", "price": nil, "currency": "USD", "author": map[string]any{"name": "bob"}},
+		}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		ComputedFields: []gateway.ComputedFieldOption{
+			{Field: "Product.displayPrice", Expression: "format(price, currency)"},
+			{Field: "Product.label", Expression: `concat(upper(name), " by ", author.name)`},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query":"{ products { name displayPrice ...Labels } } fragment Labels on Product { label }"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	want := `{"data":{"products":[{"name":"book","displayPrice":"12.50 EUR","label":"BOOK by ann"},{"name":"pen","displayPrice":null,"label":"PEN by bob"}]}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(queries) != 1 {
+		t.Fatalf("expected 1 subgraph request, got %d", len(queries))
+	}
+	for _, field := range []string{"price", "currency", "author"} {
+		if !strings.Contains(queries[0], field) {
+			t.Errorf("expected the subgraph request to select %s, got %s", field, queries[0])
+		}
+	}
+	for _, field := range []string{"displayPrice", "label"} {
+		if strings.Contains(queries[0], field) {
+			t.Errorf("expected the subgraph request not to select %s, got %s", field, queries[0])
+		}
+	}
+}
+
+func TestGateway_ComputedFieldsInvalid(t *testing.T) {
+	products := newSubgraphServer(t, `type Query { name: String }`, func(body map[string]any) any { return nil })
+
+	tests := []struct {
+		name  string
+		field gateway.ComputedFieldOption
+	}{
+		{"coordinate", gateway.ComputedFieldOption{Field: "displayPrice", Expression: "price"}},
+		{"type", gateway.ComputedFieldOption{Field: "Product.displayPrice", Type: "Money", Expression: "price"}},
+		{"function", gateway.ComputedFieldOption{Field: "Product.displayPrice", Expression: "money(price)"}},
+		{"syntax", gateway.ComputedFieldOption{Field: "Product.displayPrice", Expression: "format(price,"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gateway.NewGateway(gateway.GatewayOption{
+				Endpoint:       "/graphql",
+				Services:       []gateway.GatewayService{{Name: "products", Host: products.URL}},
+				ComputedFields: []gateway.ComputedFieldOption{tt.field},
+			})
+			if err == nil || !strings.Contains(err.Error(), "computed_fields[0]") {
+				t.Errorf("expected a computed_fields error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// SubgraphConcurrencyOption bounds the concurrent requests the gateway sends to its
+// subgraphs. Requests over a limit wait in a queue and are shed with a
+// SUBGRAPH_OVERLOADED error when the queue is full or they waited too long.
+type SubgraphConcurrencyOption struct {
+	MaxRequests  int    `yaml:"max_requests" default:"0"`          // Concurrent requests to all subgraphs; 0 is unlimited
+	MaxQueue     int    `yaml:"max_queue" default:"0"`             // Requests waiting for each limit; 0 is unlimited
+	QueueTimeout string `yaml:"queue_timeout" validate:"duration"` // How long a request waits for a slot; empty waits until the operation times out
+}
+
+// newRequestLimiter builds the executor request limiter from the gateway and per
+// service limits. It returns nil when no limit is configured. Shed requests are counted
+// in the graphql.subgraph.shed metric.
+func newRequestLimiter(settings GatewayOption, labels metric.MeasurementOption) (*executor.RequestLimiter, error) {
+	opt := settings.SubgraphConcurrency
+	perSubGraph := make(map[string]int)
+	for _, svc := range settings.Services {
+		if svc.MaxConcurrentRequests > 0 {
+			perSubGraph[svc.Name] = svc.MaxConcurrentRequests
+		}
+	}
+	if opt.MaxRequests <= 0 && len(perSubGraph) == 0 {
+		return nil, nil
+	}
+
+	var queueTimeout time.Duration
+	if opt.QueueTimeout != "" {
+		d, err := time.ParseDuration(opt.QueueTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subgraph_concurrency.queue_timeout: %w", err)
+		}
+		queueTimeout = d
+	}
+
+	counter, err := otel.Meter("github.com/n9te9/go-graphql-federation-gateway").Int64Counter(
+		"graphql.subgraph.shed",
+		metric.WithDescription("Number of subgraph requests shed because the concurrency limits were saturated"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shed request counter: %w", err)
+	}
+
+	limiter := executor.NewRequestLimiter(opt.MaxRequests, perSubGraph, opt.MaxQueue, queueTimeout)
+	limiter.OnShed = func(ctx context.Context, subGraphName string) {
+		counter.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("graphql.subgraph.name", subGraphName),
+		), labels)
+	}
+	return limiter, nil
+}
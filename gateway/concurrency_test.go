@@ -0,0 +1,117 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_SubgraphConcurrency(t *testing.T) {
+	const productsSDL = `
+		type Query {
+			serverTime: String!
+		}
+	`
+
+	var inFlight, maxInFlight atomic.Int32
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return map[string]any{"data": map[string]any{"serverTime": "now"}}
+	})
+
+	run := func(t *testing.T, opt gateway.SubgraphConcurrencyOption, maxPerService, requests int) []map[string]any {
+		t.Helper()
+		inFlight.Store(0)
+		maxInFlight.Store(0)
+
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:            "/graphql",
+			Services:            []gateway.GatewayService{{Name: "products", Host: products.URL, MaxConcurrentRequests: maxPerService}},
+			SubgraphConcurrency: opt,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		resps := make([]map[string]any, requests)
+		var wg sync.WaitGroup
+		for i := range requests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec := httptest.NewRecorder()
+				gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ serverTime }"}`)))
+				json.Unmarshal(rec.Body.Bytes(), &resps[i]) //nolint:errcheck
+			}()
+		}
+		wg.Wait()
+		return resps
+	}
+
+	t.Run("requests over the limit are queued", func(t *testing.T) {
+		resps := run(t, gateway.SubgraphConcurrencyOption{MaxRequests: 2}, 0, 4)
+		for _, resp := range resps {
+			if resp["errors"] != nil {
+				t.Errorf("expected every queued request to succeed, got %v", resp)
+			}
+		}
+		if got := maxInFlight.Load(); got != 2 {
+			t.Errorf("expected at most 2 concurrent subgraph requests, got %d", got)
+		}
+	})
+
+	t.Run("the service limit applies per subgraph", func(t *testing.T) {
+		run(t, gateway.SubgraphConcurrencyOption{MaxRequests: 10}, 1, 3)
+		if got := maxInFlight.Load(); got != 1 {
+			t.Errorf("expected 1 concurrent subgraph request, got %d", got)
+		}
+	})
+
+	t.Run("requests are shed when the queue is full", func(t *testing.T) {
+		resps := run(t, gateway.SubgraphConcurrencyOption{MaxQueue: 1, QueueTimeout: "10ms"}, 1, 3)
+
+		var ok, shed int
+		for _, resp := range resps {
+			errs, _ := resp["errors"].([]any)
+			if len(errs) == 0 {
+				ok++
+				continue
+			}
+			ext, _ := errs[0].(map[string]any)["extensions"].(map[string]any)
+			if ext["code"] != "SUBGRAPH_OVERLOADED" {
+				t.Errorf("expected a SUBGRAPH_OVERLOADED error, got %v", resp)
+			}
+			shed++
+		}
+		if ok != 1 || shed != 2 {
+			t.Errorf("expected 1 request served and 2 shed, got %d and %d", ok, shed)
+		}
+	})
+
+	t.Run("invalid queue timeout", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:            "/graphql",
+			Services:            []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			SubgraphConcurrency: gateway.SubgraphConcurrencyOption{MaxRequests: 1, QueueTimeout: "soon"},
+		})
+		if err == nil {
+			t.Error("expected an error for an invalid queue timeout")
+		}
+	})
+}
@@ -0,0 +1,336 @@
+package gateway
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	yamlast "github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// ConfigError is a problem found in a gateway configuration file.
+type ConfigError struct {
+	Path    string // Key of the problem, e.g. services[1].retry.timeout; empty for the whole file
+	Line    int    // Line of the key in the file; 0 when unknown
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	var sb strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&sb, "line %d: ", e.Line)
+	}
+	if e.Path != "" {
+		sb.WriteString(e.Path + ": ")
+	}
+	sb.WriteString(e.Message)
+	return sb.String()
+}
+
+// ConfigErrors lists every problem found in a gateway configuration file.
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// LoadGatewayOption parses a gateway configuration file strictly. Unknown keys, values of
+// the wrong type, invalid durations, unsupported values and missing required settings
+// are all reported at once as ConfigErrors, each with its key and line.
+//
+// String settings may reference environment variables and secret files, see interpolate;
+// they are resolved before they are checked.
+//
+// Settings are checked against the validate tags of the option structs: duration for
+// Go durations, oneof=a|b for enumerations and required for mandatory values. Empty
+// values are always accepted by duration and oneof.
+func LoadGatewayOption(data []byte) (*GatewayOption, error) {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, ConfigErrors{{Message: yaml.FormatError(err, false, false)}}
+	}
+
+	c := &configChecker{lines: make(map[string]int)}
+	for _, doc := range file.Docs {
+		if doc.Body != nil {
+			c.checkKeys(doc.Body, reflect.TypeOf(GatewayOption{}), "")
+		}
+	}
+
+	var settings GatewayOption
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		c.errs = append(c.errs, ConfigError{Message: yaml.FormatError(err, false, false)})
+		return nil, c.errs
+	}
+	c.interpolate(reflect.ValueOf(&settings).Elem(), "")
+	c.checkValues(reflect.ValueOf(settings), "")
+	c.checkGateway(settings, "")
+	if len(c.errs) > 0 {
+		slices.SortStableFunc(c.errs, func(a, b ConfigError) int {
+			if a.Line != b.Line {
+				return a.Line - b.Line
+			}
+			return strings.Compare(a.Path, b.Path)
+		})
+		return nil, c.errs
+	}
+	return &settings, nil
+}
+
+// configChecker collects the problems of a configuration file.
+type configChecker struct {
+	lines map[string]int // Line of every key path seen in the file
+	errs  ConfigErrors
+}
+
+func (c *configChecker) add(path, format string, args ...any) {
+	c.errs = append(c.errs, ConfigError{Path: path, Line: c.line(path), Message: fmt.Sprintf(format, args...)})
+}
+
+// line returns the line of path, or of its closest parent in the file for missing keys.
+func (c *configChecker) line(path string) int {
+	for path != "" {
+		if line, ok := c.lines[path]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return 0
+}
+
+// yamlKeys returns the fields of struct type t by YAML key, flattening inline fields.
+func yamlKeys(t reflect.Type) map[string]reflect.StructField {
+	keys := make(map[string]reflect.StructField)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			for k, v := range yamlKeys(f.Type) {
+				keys[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		keys[name] = f
+	}
+	return keys
+}
+
+// checkKeys reports the keys of node that t does not declare, recording the line of
+// every key it walks.
+func (c *configChecker) checkKeys(node yamlast.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch n := node.(type) {
+	case *yamlast.AnchorNode:
+		c.checkKeys(n.Value, t, path)
+		return
+	case *yamlast.TagNode:
+		c.checkKeys(n.Value, t, path)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var values []*yamlast.MappingValueNode
+		switch n := node.(type) {
+		case *yamlast.MappingNode:
+			values = n.Values
+		case *yamlast.MappingValueNode:
+			values = []*yamlast.MappingValueNode{n}
+		default:
+			return
+		}
+		keys := yamlKeys(t)
+		for _, mv := range values {
+			key := mv.Key.GetToken().Value
+			if key == "<<" {
+				continue
+			}
+			keyPath := joinConfigPath(path, key)
+			c.lines[keyPath] = mv.Key.GetToken().Position.Line
+			field, ok := keys[key]
+			if !ok {
+				if suggestion := closestKey(key, keys); suggestion != "" {
+					c.add(keyPath, "unknown key %q, did you mean %q?", key, suggestion)
+				} else {
+					c.add(keyPath, "unknown key %q", key)
+				}
+				continue
+			}
+			c.checkKeys(mv.Value, field.Type, keyPath)
+		}
+	case reflect.Slice:
+		if seq, ok := node.(*yamlast.SequenceNode); ok {
+			for i, item := range seq.Values {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				c.lines[itemPath] = item.GetToken().Position.Line
+				c.checkKeys(item, t.Elem(), itemPath)
+			}
+		}
+	case reflect.Map:
+		if m, ok := node.(*yamlast.MappingNode); ok {
+			for _, mv := range m.Values {
+				keyPath := joinConfigPath(path, mv.Key.GetToken().Value)
+				c.lines[keyPath] = mv.Key.GetToken().Position.Line
+				c.checkKeys(mv.Value, t.Elem(), keyPath)
+			}
+		}
+	}
+}
+
+// checkValues reports the values of v breaking the validate tags of their fields.
+func (c *configChecker) checkValues(v reflect.Value, path string) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			c.checkValues(v.Elem(), path)
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			c.checkValues(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			c.checkValues(v.MapIndex(key), joinConfigPath(path, fmt.Sprint(key.Interface())))
+		}
+	case reflect.Struct:
+		for key, field := range yamlKeys(v.Type()) {
+			value := v.FieldByIndex(field.Index)
+			fieldPath := joinConfigPath(path, key)
+			if rule := field.Tag.Get("validate"); rule != "" {
+				c.checkRule(value, rule, fieldPath)
+			}
+			c.checkValues(value, fieldPath)
+		}
+	}
+}
+
+// checkRule reports value when it breaks rule.
+func (c *configChecker) checkRule(value reflect.Value, rule, path string) {
+	if rule == "required" {
+		if value.IsZero() {
+			c.add(path, "is required")
+		}
+		return
+	}
+
+	var values []string
+	switch value.Kind() {
+	case reflect.String:
+		values = []string{value.String()}
+	case reflect.Slice:
+		for i := range value.Len() {
+			values = append(values, value.Index(i).String())
+		}
+	}
+	for _, s := range values {
+		if s == "" {
+			continue
+		}
+		switch {
+		case rule == "duration":
+			if d, err := time.ParseDuration(s); err != nil || d < 0 {
+				c.add(path, "invalid duration %q: use a value such as 500ms, 5s or 1m", s)
+			}
+		case strings.HasPrefix(rule, "oneof="):
+			allowed := strings.Split(strings.TrimPrefix(rule, "oneof="), "|")
+			if !slices.Contains(allowed, s) {
+				c.add(path, "unsupported value %q: use %s", s, strings.Join(allowed, ", "))
+			}
+		}
+	}
+}
+
+// checkGateway reports the settings of o that the validate tags cannot express.
+func (c *configChecker) checkGateway(o GatewayOption, path string) {
+	if o.Port < 0 || o.Port > 65535 {
+		c.add(joinConfigPath(path, "port"), "port %d is out of range", o.Port)
+	}
+	if len(o.Services) == 0 && len(o.Tenants) == 0 {
+		c.add(joinConfigPath(path, "services"), "at least one service is required")
+	}
+
+	names := make(map[string]bool, len(o.Services))
+	for i, svc := range o.Services {
+		svcPath := fmt.Sprintf("%s[%d]", joinConfigPath(path, "services"), i)
+		if svc.Name != "" && names[svc.Name] {
+			c.add(joinConfigPath(svcPath, "name"), "duplicate service name %q", svc.Name)
+		}
+		names[svc.Name] = true
+		if svc.Host == "" {
+			c.add(joinConfigPath(svcPath, "host"), "is required")
+		}
+		if svc.Type == serviceTypeREST && svc.REST == nil {
+			c.add(joinConfigPath(svcPath, "rest"), "is required for services of type rest")
+		}
+	}
+
+	for i, tenant := range o.Tenants {
+		c.checkGateway(tenant.GatewayOption, fmt.Sprintf("%s[%d]", joinConfigPath(path, "tenants"), i))
+	}
+}
+
+// joinConfigPath returns the path of key under path.
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestKey returns the key of keys closest to key, if it is a likely typo.
+func closestKey(key string, keys map[string]reflect.StructField) string {
+	best, bestDistance := "", 3
+	for candidate := range keys {
+		if d := editDistance(key, candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	if bestDistance > 2 {
+		return ""
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
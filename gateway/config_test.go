@@ -0,0 +1,170 @@
+package gateway_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestLoadGatewayOption(t *testing.T) {
+	t.Run("valid configuration", func(t *testing.T) {
+		opt, err := gateway.LoadGatewayOption([]byte(`
+endpoint: /graphql
+port: 9000
+timeout_duration: 5s
+owner_strategy: cheapest
+services:
+  - name: products
+    host: http://localhost:4001
+    retry:
+      attempts: 3
+      timeout: 2s
+  - name: legacy
+    host: http://localhost:4002
+    type: rest
+    rest:
+      sdl: "type Query { legacy: String }"
+`))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(opt.Services) != 2 || opt.Services[0].Retry.Timeout != "2s" {
+			t.Errorf("expected the services to be loaded, got %+v", opt.Services)
+		}
+	})
+
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "unknown key with a suggestion",
+			yaml: `
+endpoint: /graphql
+timeout_duraton: 5s
+services:
+  - name: products
+    host: http://localhost:4001
+    retyr:
+      attempts: 3
+`,
+			want: []string{
+				`line 3: timeout_duraton: unknown key "timeout_duraton", did you mean "timeout_duration"?`,
+				`line 7: services[0].retyr: unknown key "retyr", did you mean "retry"?`,
+			},
+		},
+		{
+			name: "invalid duration",
+			yaml: `
+services:
+  - name: products
+    host: http://localhost:4001
+    retry:
+      timeout: 5 seconds
+`,
+			want: []string{`line 6: services[0].retry.timeout: invalid duration "5 seconds": use a value such as 500ms, 5s or 1m`},
+		},
+		{
+			name: "missing host and name",
+			yaml: `
+services:
+  - name: products
+  - host: http://localhost:4002
+`,
+			want: []string{
+				`line 3: services[0].host: is required`,
+				`line 4: services[1].name: is required`,
+			},
+		},
+		{
+			name: "unsupported value",
+			yaml: `
+owner_strategy: fastest
+services:
+  - name: products
+    host: http://localhost:4001
+`,
+			want: []string{`line 2: owner_strategy: unsupported value "fastest": use first, local, cheapest`},
+		},
+		{
+			name: "out of range port and duplicate service",
+			yaml: `
+port: 70000
+services:
+  - name: products
+    host: http://localhost:4001
+  - name: products
+    host: http://localhost:4002
+`,
+			want: []string{
+				`line 2: port: port 70000 is out of range`,
+				`line 6: services[1].name: duplicate service name "products"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gateway.LoadGatewayOption([]byte(tt.yaml))
+			var errs gateway.ConfigErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected ConfigErrors, got %v", err)
+			}
+			if got := err.Error(); got != strings.Join(tt.want, "\n") {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(tt.want, "\n"), got)
+			}
+		})
+	}
+}
+
+func TestLoadGatewayOption_Interpolation(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secret, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PRODUCTS_HOST", "http://products:4001")
+	t.Setenv("SECRETS_DIR", filepath.Dir(secret))
+
+	opt, err := gateway.LoadGatewayOption([]byte(`
+services:
+  - name: products
+    host: ${PRODUCTS_HOST}/query
+    timeout: ${PRODUCTS_TIMEOUT:-2s}
+    auth:
+      type: bearer
+      token: file://${SECRETS_DIR}/token
+    rest:
+      headers:
+        X-Template: $${literal}
+`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	svc := opt.Services[0]
+	if svc.Host != "http://products:4001/query" {
+		t.Errorf("expected the host to be interpolated, got %q", svc.Host)
+	}
+	if svc.Timeout != "2s" {
+		t.Errorf("expected the default timeout, got %q", svc.Timeout)
+	}
+	if svc.Auth.Token != "s3cr3t" {
+		t.Errorf("expected the token to be read from the secret file, got %q", svc.Auth.Token)
+	}
+	if got := svc.REST.Headers["X-Template"]; got != "${literal}" {
+		t.Errorf("expected an escaped reference, got %q", got)
+	}
+
+	_, err = gateway.LoadGatewayOption([]byte(`
+services:
+  - name: products
+    host: ${UNSET_PRODUCTS_HOST}
+`))
+	want := "line 4: services[0].host: environment variable UNSET_PRODUCTS_HOST is not set"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
@@ -0,0 +1,241 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ConnectionEntities(t *testing.T) {
+	const catalogSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+		}
+
+		type PageInfo {
+			hasNextPage: Boolean!
+			endCursor: String
+		}
+
+		type ProductEdge {
+			cursor: String!
+			node: Product
+		}
+
+		type ProductConnection {
+			edges: [ProductEdge]
+			pageInfo: PageInfo!
+		}
+
+		type Category {
+			name: String
+			products(first: Int, after: String): ProductConnection
+		}
+
+		type Query {
+			products(first: Int, after: String): ProductConnection
+			categories: [Category]
+		}
+	`
+	const productsSDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			name: String
+		}
+	`
+
+	edge := func(id string) map[string]any {
+		return map[string]any{"cursor": "c" + id, "node": map[string]any{"__typename": "Product", "id": id}}
+	}
+	page := func(hasNext bool, end string, edges ...any) map[string]any {
+		if edges == nil {
+			edges = []any{}
+		}
+		return map[string]any{"edges": edges, "pageInfo": map[string]any{"hasNextPage": hasNext, "endCursor": end}}
+	}
+	catalog := newSubgraphServer(t, catalogSDL, func(body map[string]any) any {
+		products := page(true, "c2", edge("1"), map[string]any{"cursor": "cx", "node": nil}, edge("2"))
+		var items []any
+		for _, e := range products["edges"].([]any) {
+			items = append(items, map[string]any{"product": e.(map[string]any)["node"]})
+		}
+		return map[string]any{"data": map[string]any{
+			"products": products,
+			"first":    products,
+			"again":    map[string]any{"items": items},
+			"categories": []any{
+				map[string]any{"name": "books", "products": page(false, "c4", edge("3"), edge("4"))},
+				map[string]any{"name": "empty", "products": page(false, "")},
+				map[string]any{"name": "games", "products": page(true, "c5", edge("5"))},
+			},
+		}}
+	})
+
+	var mu sync.Mutex
+	var entityRequests [][]any
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		mu.Lock()
+		entityRequests = append(entityRequests, reps)
+		mu.Unlock()
+		result := make([]any, 0, len(reps))
+		for _, r := range reps {
+			id := r.(map[string]any)["id"].(string)
+			result = append(result, map[string]any{"__typename": "Product", "id": id, "name": "product-" + id})
+		}
+		return map[string]any{"data": map[string]any{"_entities": result}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "catalog", Host: catalog.URL},
+			{Name: "products", Host: products.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		want     string
+		requests int // Entity requests sent to the products subgraph
+		reps     int // Representations in the first of them
+	}{
+		{
+			name:     "root connection",
+			query:    `{ products(first: 3) { edges { cursor node { name } } pageInfo { hasNextPage endCursor } } }`,
+			want:     `{"data":{"products":{"edges":[{"cursor":"c1","node":{"name":"product-1"}},{"cursor":"cx","node":null},{"cursor":"c2","node":{"name":"product-2"}}],"pageInfo":{"hasNextPage":true,"endCursor":"c2"}}}}`,
+			requests: 1,
+			reps:     2,
+		},
+		{
+			name:     "connection of every object in a list",
+			query:    `{ categories { name products { pageInfo { endCursor } edges { node { id name } cursor } } } }`,
+			want:     `{"data":{"categories":[{"name":"books","products":{"pageInfo":{"endCursor":"c4"},"edges":[{"node":{"id":"3","name":"product-3"},"cursor":"c3"},{"node":{"id":"4","name":"product-4"},"cursor":"c4"}]}},{"name":"empty","products":{"pageInfo":{"endCursor":""},"edges":[]}},{"name":"games","products":{"pageInfo":{"endCursor":"c5"},"edges":[{"node":{"id":"5","name":"product-5"},"cursor":"c5"}]}}]}}`,
+			requests: 1,
+			reps:     3,
+		},
+		{
+			name:     "aliased connection",
+			query:    `{ again: products(first: 1) { items: edges { product: node { name } } } }`,
+			want:     `{"data":{"again":{"items":[{"product":{"name":"product-1"}},{"product":null},{"product":{"name":"product-2"}}]}}}`,
+			requests: 1,
+			reps:     2,
+		},
+		{
+			name:     "aliased connections",
+			query:    `{ first: products(first: 1) { edges { node { name } } } again: products(first: 1) { items: edges { product: node { name } } } }`,
+			want:     `{"data":{"first":{"edges":[{"node":{"name":"product-1"}},{"node":null},{"node":{"name":"product-2"}}]},"again":{"items":[{"product":{"name":"product-1"}},{"product":null},{"product":{"name":"product-2"}}]}}}`,
+			requests: 1,
+			reps:     4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			entityRequests = nil
+			mu.Unlock()
+
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"`+tt.query+`"}`)))
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(entityRequests) != tt.requests {
+				t.Fatalf("expected %d entity request(s), got %d: %v", tt.requests, len(entityRequests), entityRequests)
+			}
+			if len(entityRequests[0]) != tt.reps {
+				t.Errorf("expected %d representations in one batch, got %v", tt.reps, entityRequests[0])
+			}
+		})
+	}
+}
+
+func TestGateway_ConnectionEntities_FailedStep(t *testing.T) {
+	const catalogSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+		}
+
+		type ProductEdge {
+			cursor: String!
+			node: Product
+		}
+
+		type ProductConnection {
+			edges: [ProductEdge]
+		}
+
+		type Query {
+			products(first: Int, after: String): ProductConnection
+		}
+	`
+	const productsSDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			name: String
+		}
+	`
+
+	catalog := newSubgraphServer(t, catalogSDL, func(body map[string]any) any {
+		page := func(id string) map[string]any {
+			return map[string]any{"edges": []any{
+				map[string]any{"cursor": "c" + id, "node": map[string]any{"__typename": "Product", "id": id}},
+			}}
+		}
+		return map[string]any{"data": map[string]any{"first": page("1"), "next": page("2")}}
+	})
+	// The products subgraph serves its SDL but fails every other request.
+	products := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": productsSDL}}}) //nolint:errcheck
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(products.Close)
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "catalog", Host: catalog.URL},
+			{Name: "products", Host: products.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(
+		`{"query":"{ first: products(first: 1) { edges { cursor node { name } } } next: products(after: \"c1\") { edges { cursor node { name } } } }"}`)))
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []any           `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+	}
+	if want := `{"first":{"edges":[{"cursor":"c1","node":{"name":null}}]},"next":{"edges":[{"cursor":"c2","node":{"name":null}}]}}`; string(resp.Data) != want {
+		t.Errorf("expected %s, got %s", want, resp.Data)
+	}
+	if len(resp.Errors) != 2 {
+		t.Errorf("expected the subgraph error at both connections, got %s", rec.Body.String())
+	}
+}
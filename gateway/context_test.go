@@ -0,0 +1,108 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_FromContext(t *testing.T) {
+	const (
+		sdlUsers = `
+			type User @key(fields: "id") @context(name: "userContext") {
+				id: ID!
+				name: String!
+				currency: String!
+				cart: [Product!]!
+			}
+
+			type Product @key(fields: "id") {
+				id: ID!
+			}
+
+			type Query {
+				users: [User!]!
+			}
+		`
+		sdlProducts = `
+			type Product @key(fields: "id") {
+				id: ID!
+				price(currency: String! @fromContext(field: "$userContext { currency }")): String!
+			}
+		`
+	)
+
+	users := newSubgraphServer(t, sdlUsers, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"users": []any{
+			map[string]any{"name": "alice", "currency": "EUR", "cart": []any{
+				map[string]any{"id": "p1"}, map[string]any{"id": "p2"},
+			}},
+			map[string]any{"name": "bob", "currency": "USD", "cart": []any{
+				map[string]any{"id": "p1"},
+			}},
+		}}}
+	})
+
+	var mu sync.Mutex
+	var queries []string
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		query, _ := body["query"].(string)
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		currency := ""
+		for name, value := range vars {
+			if name != "representations" {
+				currency, _ = value.(string)
+			}
+		}
+		reps, _ := vars["representations"].([]any)
+		entities := make([]any, 0, len(reps))
+		for _, rep := range reps {
+			id := rep.(map[string]any)["id"].(string)
+			entities = append(entities, map[string]any{"__typename": "Product", "id": id, "price": id + " in " + currency})
+		}
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "users", Host: users.URL},
+			{Name: "products", Host: products.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query":"{ users { name cart { price } } }"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	want := `{"data":{"users":[{"name":"alice","cart":[{"price":"p1 in EUR"},{"price":"p2 in EUR"}]},{"name":"bob","cart":[{"price":"p1 in USD"}]}]}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(queries) != 2 {
+		t.Fatalf("expected one _entities request per currency, got %d", len(queries))
+	}
+	for _, query := range queries {
+		if !strings.Contains(query, "price(currency: $v0)") {
+			t.Errorf("expected price to receive the context variable, got %s", query)
+		}
+	}
+
+}
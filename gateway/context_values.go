@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ContextKey is a typed key for request-scoped values, such as a tenant ID, auth claims
+// or A/B test buckets, that middleware attaches to the request context. The context of
+// a request flows through planning and execution, so the values can be read in
+// subgraph request hooks, credentials providers and extension providers. Keys are
+// compared by identity; declare them as package-level variables.
+type ContextKey[T any] struct {
+	Name  string // Identifies the value in spans and logs
+	Trace bool   // Add the value to the operation span as gateway.context.<Name>
+	Log   bool   // Add the value to records logged through NewContextLogHandler
+}
+
+// contextValue is a value attached with a ContextKey. Values form a list from the most
+// recently attached one so all of them can be listed.
+type contextValue struct {
+	key    any
+	name   string
+	value  any
+	trace  bool
+	log    bool
+	parent *contextValue
+}
+
+type contextValuesContextKey struct{}
+
+// WithValue returns a copy of ctx carrying v under k.
+func (k *ContextKey[T]) WithValue(ctx context.Context, v T) context.Context {
+	parent, _ := ctx.Value(contextValuesContextKey{}).(*contextValue)
+	ctx = context.WithValue(ctx, k, v)
+	return context.WithValue(ctx, contextValuesContextKey{}, &contextValue{
+		key:    k,
+		name:   k.Name,
+		value:  v,
+		trace:  k.Trace,
+		log:    k.Log,
+		parent: parent,
+	})
+}
+
+// Value returns the value attached to ctx under k and whether there is one.
+func (k *ContextKey[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+// ContextValues returns every value attached to ctx with a ContextKey, by key name.
+func ContextValues(ctx context.Context) map[string]any {
+	values := make(map[string]any)
+	visitContextValues(ctx, func(v *contextValue) {
+		values[v.name] = v.value
+	})
+	return values
+}
+
+// visitContextValues calls fn with the current value of every ContextKey attached to
+// ctx, skipping the values later attached under the same key replaced.
+func visitContextValues(ctx context.Context, fn func(*contextValue)) {
+	seen := make(map[any]bool)
+	for v, _ := ctx.Value(contextValuesContextKey{}).(*contextValue); v != nil; v = v.parent {
+		if seen[v.key] {
+			continue
+		}
+		seen[v.key] = true
+		fn(v)
+	}
+}
+
+// contextValueAttributes returns the span attributes of the traced values of ctx.
+func contextValueAttributes(ctx context.Context) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	visitContextValues(ctx, func(v *contextValue) {
+		if v.trace {
+			attrs = append(attrs, attribute.String("gateway.context."+v.name, fmt.Sprint(v.value)))
+		}
+	})
+	return attrs
+}
+
+// contextLogHandler adds the logged context values to every record.
+type contextLogHandler struct {
+	slog.Handler
+}
+
+// NewContextLogHandler wraps h so records logged with a context, e.g. with
+// slog.InfoContext, carry the client identity and the values attached with ContextKeys
+// whose Log is set.
+func NewContextLogHandler(h slog.Handler) slog.Handler {
+	return &contextLogHandler{Handler: h}
+}
+
+// Handle adds the client identity and the logged context values of ctx to r.
+func (h *contextLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if client := ClientInfoFromContext(ctx); client != (ClientInfo{}) {
+		r.AddAttrs(slog.String("client_name", client.Name), slog.String("client_version", client.Version))
+	}
+	visitContextValues(ctx, func(v *contextValue) {
+		if v.log {
+			r.AddAttrs(slog.Any(v.name, v.value))
+		}
+	})
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a contextLogHandler wrapping h.Handler.WithAttrs(attrs).
+func (h *contextLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextLogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a contextLogHandler wrapping h.Handler.WithGroup(name).
+func (h *contextLogHandler) WithGroup(name string) slog.Handler {
+	return &contextLogHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// SubgraphRequestHook is called with every request sent to a subgraph before its
+// credentials are applied, e.g. to inject headers from values attached with a
+// ContextKey; req.Context() is the context of the operation. Requests fetching the
+// SDL of a subgraph pass through the hooks too, with a context carrying no values.
+// Implementations must be safe for concurrent use.
+type SubgraphRequestHook interface {
+	BeforeSubgraphRequest(subGraphName string, req *http.Request) error
+}
+
+// SubgraphRequestHookFunc adapts a function to a SubgraphRequestHook.
+type SubgraphRequestHookFunc func(subGraphName string, req *http.Request) error
+
+// BeforeSubgraphRequest calls f(subGraphName, req).
+func (f SubgraphRequestHookFunc) BeforeSubgraphRequest(subGraphName string, req *http.Request) error {
+	return f(subGraphName, req)
+}
+
+// subgraphHookTransport runs the subgraph request hooks before sending a request.
+type subgraphHookTransport struct {
+	subGraphName string
+	hooks        []SubgraphRequestHook
+	base         http.RoundTripper
+}
+
+func (t *subgraphHookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	for _, hook := range t.hooks {
+		if err := hook.BeforeSubgraphRequest(t.subGraphName, req); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, fmt.Errorf("subgraph request hook failed: %w", err)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,146 @@
+package gateway_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+var (
+	tenantID  = &gateway.ContextKey[string]{Name: "tenant.id", Trace: true, Log: true}
+	abBuckets = &gateway.ContextKey[[]string]{Name: "ab.buckets"}
+)
+
+func TestContextKey(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := tenantID.Value(ctx); ok {
+		t.Error("expected no tenant ID")
+	}
+
+	ctx = tenantID.WithValue(ctx, "acme")
+	ctx = abBuckets.WithValue(ctx, []string{"checkout-b"})
+	ctx = tenantID.WithValue(ctx, "globex")
+
+	if v, ok := tenantID.Value(ctx); !ok || v != "globex" {
+		t.Errorf("expected the latest tenant ID, got %q", v)
+	}
+	if v, _ := abBuckets.Value(ctx); len(v) != 1 || v[0] != "checkout-b" {
+		t.Errorf("unexpected buckets %v", v)
+	}
+
+	values := gateway.ContextValues(ctx)
+	if len(values) != 2 || values["tenant.id"] != "globex" {
+		t.Errorf("unexpected context values %v", values)
+	}
+
+	// Keys are compared by identity, not by name.
+	other := &gateway.ContextKey[string]{Name: "tenant.id"}
+	if _, ok := other.Value(ctx); ok {
+		t.Error("expected another key with the same name to have no value")
+	}
+}
+
+func TestNewContextLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(gateway.NewContextLogHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+
+	ctx := abBuckets.WithValue(tenantID.WithValue(context.Background(), "acme"), []string{"b"})
+	logger.InfoContext(ctx, "hello")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log record %s: %v", buf.String(), err)
+	}
+	if record["tenant.id"] != "acme" || record["component"] != "test" {
+		t.Errorf("expected the tenant ID in the log record, got %v", record)
+	}
+	if _, ok := record["ab.buckets"]; ok {
+		t.Errorf("expected values without Log to be left out, got %v", record)
+	}
+}
+
+func TestGateway_SubgraphRequestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var tenants []string
+	products := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdlProducts}}}) //nolint:errcheck
+			return
+		}
+		mu.Lock()
+		tenants = append(tenants, r.Header.Get("X-Tenant-ID"))
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "Table"}}}) //nolint:errcheck
+	}))
+	t.Cleanup(products.Close)
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		SubgraphRequestHooks: []gateway.SubgraphRequestHook{
+			gateway.SubgraphRequestHookFunc(func(subGraphName string, req *http.Request) error {
+				if tenant, ok := tenantID.Value(req.Context()); ok {
+					req.Header.Set("X-Tenant-ID", tenant)
+				}
+				return nil
+			}),
+			gateway.SubgraphRequestHookFunc(func(subGraphName string, req *http.Request) error {
+				if tenant, _ := tenantID.Value(req.Context()); tenant == "blocked" {
+					return errors.New("tenant is blocked")
+				}
+				return nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	// The middleware attaches the tenant ID from the request.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tenantID.WithValue(r.Context(), r.Header.Get("X-Tenant"))
+		gw.ServeHTTP(w, r.WithContext(ctx))
+	})
+	post := func(tenant string) map[string]any {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { name } }"}`))
+		req.Header.Set("X-Tenant", tenant)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+
+	post("acme")
+	mu.Lock()
+	if len(tenants) == 0 || tenants[0] != "acme" {
+		t.Errorf("expected the subgraph request to carry the tenant ID, got %v", tenants)
+	}
+	tenants = nil
+	mu.Unlock()
+
+	resp := post("blocked")
+	if errs, _ := resp["errors"].([]any); len(errs) == 0 {
+		t.Errorf("expected a hook error to fail the subgraph request, got %v", resp)
+	}
+	mu.Lock()
+	if len(tenants) != 0 {
+		t.Errorf("expected no request to reach the subgraph, got %v", tenants)
+	}
+	mu.Unlock()
+}
@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOption configures Cross-Origin Resource Sharing for every gateway endpoint.
+type CORSOption struct {
+	Enable           bool     `yaml:"enable" default:"false"`
+	AllowedOrigins   []string `yaml:"allowed_origins"` // Exact origins, "*", or wildcard subdomains such as "https://*.example.com"
+	AllowedMethods   []string `yaml:"allowed_methods"` // Defaults to GET, POST and OPTIONS
+	AllowedHeaders   []string `yaml:"allowed_headers"` // Defaults to Content-Type, Authorization and the CSRF preflight headers
+	ExposedHeaders   []string `yaml:"exposed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials" default:"false"`
+	MaxAge           string   `yaml:"max_age" validate:"duration"` // How long browsers may cache preflight results, e.g. "10m"
+}
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+	defaultCORSHeaders = append([]string{"Content-Type", "Authorization"}, defaultPreflightHeaders...)
+)
+
+// corsPolicy answers preflight requests and adds CORS headers to responses.
+type corsPolicy struct {
+	anyOrigin        bool
+	origins          map[string]bool
+	originPatterns   [][2]string // prefix and suffix around "*"
+	methods          map[string]bool
+	allowMethods     string
+	allowHeaders     map[string]bool
+	allowHeadersList string
+	anyHeader        bool
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// newCORSPolicy returns the policy of opt, or nil when CORS is disabled.
+func newCORSPolicy(opt CORSOption) (*corsPolicy, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+
+	p := &corsPolicy{
+		origins:          make(map[string]bool),
+		methods:          make(map[string]bool),
+		allowHeaders:     make(map[string]bool),
+		exposeHeaders:    strings.Join(opt.ExposedHeaders, ", "),
+		allowCredentials: opt.AllowCredentials,
+	}
+
+	for _, origin := range opt.AllowedOrigins {
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Count(origin, "*") == 1:
+			prefix, suffix, _ := strings.Cut(strings.ToLower(origin), "*")
+			p.originPatterns = append(p.originPatterns, [2]string{prefix, suffix})
+		default:
+			p.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	methods := opt.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	for _, m := range methods {
+		p.methods[strings.ToUpper(m)] = true
+	}
+	p.allowMethods = strings.ToUpper(strings.Join(methods, ", "))
+
+	headers := opt.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	for _, h := range headers {
+		if h == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.allowHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	p.allowHeadersList = strings.Join(headers, ", ")
+
+	if opt.MaxAge != "" {
+		d, err := time.ParseDuration(opt.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cors max_age: %w", err)
+		}
+		p.maxAge = strconv.Itoa(int(d.Seconds()))
+	}
+
+	return p, nil
+}
+
+// isOriginAllowed reports whether requests from origin may read responses.
+func (p *corsPolicy) isOriginAllowed(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, pattern := range p.originPatterns {
+		if len(origin) > len(pattern[0])+len(pattern[1]) && strings.HasPrefix(origin, pattern[0]) && strings.HasSuffix(origin, pattern[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// handle adds the CORS headers for r to w. It reports whether r was a preflight
+// request, which is answered here and must not be processed further.
+func (p *corsPolicy) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if origin == "" || !p.isOriginAllowed(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	if preflight {
+		if !p.methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))] || !p.headersAllowed(r.Header.Get("Access-Control-Request-Headers")) {
+			w.WriteHeader(http.StatusForbidden)
+			return true
+		}
+	}
+
+	if p.anyOrigin && !p.allowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if !preflight {
+		if p.exposeHeaders != "" {
+			h.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+		}
+		return false
+	}
+
+	h.Set("Access-Control-Allow-Methods", p.allowMethods)
+	if p.anyHeader {
+		if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			h.Set("Access-Control-Allow-Headers", requested)
+		}
+	} else {
+		h.Set("Access-Control-Allow-Headers", p.allowHeadersList)
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// headersAllowed reports whether every header in the comma-separated requested list
+// is allowed.
+func (p *corsPolicy) headersAllowed(requested string) bool {
+	if p.anyHeader || requested == "" {
+		return true
+	}
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.TrimSpace(h)
+		if h != "" && !p.allowHeaders[http.CanonicalHeaderKey(h)] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_CORS(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1"}}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		CORS: gateway.CORSOption{
+			Enable:           true,
+			AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+			ExposedHeaders:   []string{"X-Request-Id"},
+			AllowCredentials: true,
+			MaxAge:           "10m",
+		},
+		HTTPPolicy: gateway.HTTPPolicyOption{
+			GraphQL: gateway.EndpointPolicyOption{CSRFPrevention: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/graphql", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("preflight", func(t *testing.T) {
+		rec := preflight("https://app.example.com", http.MethodPost, "content-type, apollo-require-preflight")
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", rec.Code)
+		}
+		want := map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "GET, POST, OPTIONS",
+			"Access-Control-Max-Age":           "600",
+		}
+		for k, v := range want {
+			if got := rec.Header().Get(k); got != v {
+				t.Errorf("expected %s %q, got %q", k, v, got)
+			}
+		}
+		if !strings.Contains(rec.Header().Get("Access-Control-Allow-Headers"), "Apollo-Require-Preflight") {
+			t.Errorf("expected the preflight headers to be allowed, got %q", rec.Header().Get("Access-Control-Allow-Headers"))
+		}
+	})
+
+	t.Run("wildcard subdomain", func(t *testing.T) {
+		if rec := preflight("https://pr-1.preview.example.com", http.MethodPost, ""); rec.Code != http.StatusNoContent {
+			t.Errorf("expected 204, got %d", rec.Code)
+		}
+		if rec := preflight("https://preview.example.com", http.MethodPost, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("expected the bare domain to be rejected, got %d", rec.Code)
+		}
+	})
+
+	t.Run("disallowed preflight", func(t *testing.T) {
+		for name, rec := range map[string]*httptest.ResponseRecorder{
+			"origin": preflight("https://evil.example.com", http.MethodPost, ""),
+			"method": preflight("https://app.example.com", http.MethodDelete, ""),
+			"header": preflight("https://app.example.com", http.MethodPost, "X-Custom"),
+		} {
+			if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+				t.Errorf("%s: expected 403 without CORS headers, got %d %v", name, rec.Code, rec.Header())
+			}
+		}
+	})
+
+	t.Run("actual request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { id } }"}`))
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("expected the origin to be allowed, got %q", got)
+		}
+		if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-Id" {
+			t.Errorf("expected exposed headers, got %q", got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("expected Vary: Origin, got %q", got)
+		}
+	})
+
+	t.Run("rejected request keeps CORS headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{}`))
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || rec.Header().Get("Access-Control-Allow-Origin") == "" {
+			t.Errorf("expected a readable 400 CSRF rejection, got %d %v", rec.Code, rec.Header())
+		}
+	})
+}
+
+func TestNewGateway_InvalidCORSMaxAge(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{}}
+	})
+	_, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		CORS:     gateway.CORSOption{Enable: true, MaxAge: "ten minutes"},
+	})
+	if err == nil {
+		t.Fatal("expected an invalid max_age to be rejected")
+	}
+}
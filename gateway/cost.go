@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Headers reporting the cost of an operation when GatewayOption.CostHeaders is set.
+const (
+	QueryCostHeader          = "X-Query-Cost"
+	SubgraphRequestsHeader   = "X-Subgraph-Requests"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+)
+
+// CostBudgetProvider reports the budget a client has left after an operation, e.g. from
+// a rate limiter charging the operation's cost, so clients can tune their queries.
+type CostBudgetProvider interface {
+	// RemainingBudget returns the remaining budget of the client of ctx, or false when
+	// the client has no budget.
+	RemainingBudget(ctx context.Context, info *ExecutionInfo) (float64, bool)
+}
+
+// costExtension reports the estimated cost, the subgraph requests made and the
+// remaining budget of the client.
+type costExtension struct{}
+
+func (costExtension) Name() string { return "cost" }
+
+func (costExtension) Extension(_ context.Context, info *ExecutionInfo) (any, bool) {
+	cost := map[string]any{
+		"estimated":        info.Cost,
+		"subgraphRequests": info.Stats.Requests(),
+	}
+	if remaining, ok := info.RemainingBudget(); ok {
+		cost["remainingBudget"] = remaining
+	}
+	return cost, true
+}
+
+// costReport sums the cost of the operations of one HTTP request for the cost headers.
+type costReport struct {
+	mu        sync.Mutex
+	cost      float64
+	requests  int
+	remaining float64
+	budget    bool
+}
+
+type costReportContextKey struct{}
+
+// withCostReport attaches an empty costReport to ctx.
+func withCostReport(ctx context.Context) (context.Context, *costReport) {
+	report := &costReport{}
+	return context.WithValue(ctx, costReportContextKey{}, report), report
+}
+
+// hasCostReport reports whether ctx has a costReport to record operations in.
+func hasCostReport(ctx context.Context) bool {
+	_, ok := ctx.Value(costReportContextKey{}).(*costReport)
+	return ok
+}
+
+// recordCost adds the cost of an executed operation to the report of ctx, if any. The
+// remaining budget is the one reported last.
+func recordCost(ctx context.Context, info *ExecutionInfo) {
+	report, ok := ctx.Value(costReportContextKey{}).(*costReport)
+	if !ok {
+		return
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	report.cost += info.Cost
+	report.requests += info.Stats.Requests()
+	if remaining, ok := info.RemainingBudget(); ok {
+		report.remaining, report.budget = remaining, true
+	}
+}
+
+// setHeaders sets the cost headers on h.
+func (r *costReport) setHeaders(h http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h.Set(QueryCostHeader, strconv.FormatFloat(r.cost, 'f', -1, 64))
+	h.Set(SubgraphRequestsHeader, strconv.Itoa(r.requests))
+	if r.budget {
+		h.Set(RateLimitRemainingHeader, strconv.FormatFloat(r.remaining, 'f', -1, 64))
+	}
+}
@@ -0,0 +1,138 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// fixedBudget charges the cost of every operation to a single budget.
+type fixedBudget struct {
+	mu        sync.Mutex
+	remaining float64
+}
+
+func (b *fixedBudget) RemainingBudget(_ context.Context, info *gateway.ExecutionInfo) (float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remaining -= info.Cost
+	return b.remaining, true
+}
+
+func TestGateway_QueryCost(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean!
+		}
+	`
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"topProducts": []any{
+			map[string]any{"__typename": "Product", "id": "1", "name": "a"},
+			map[string]any{"__typename": "Product", "id": "2", "name": "b"},
+		}}}
+	})
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		entities := make([]any, 0, len(reps))
+		for _, rep := range reps {
+			entities = append(entities, map[string]any{"__typename": "Product", "id": rep.(map[string]any)["id"], "inStock": true})
+		}
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+
+	newGateway := func(t *testing.T, opt gateway.GatewayOption) http.Handler {
+		t.Helper()
+		opt.Endpoint = "/graphql"
+		opt.Services = []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL, LatencyWeight: 2},
+		}
+		opt.ListSizeEstimate = 10
+		gw, err := gateway.NewGateway(opt)
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+	post := func(gw http.Handler, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		return rec
+	}
+	const query = `{"query":"{ topProducts { name inStock } }"}`
+
+	t.Run("headers", func(t *testing.T) {
+		budget := &fixedBudget{remaining: 100}
+		gw := newGateway(t, gateway.GatewayOption{CostHeaders: true, CostBudget: budget})
+
+		rec := post(gw, query)
+		// products (1) + inStock of 10 estimated products on inventory (2 * 10)
+		if got := rec.Header().Get(gateway.QueryCostHeader); got != "21" {
+			t.Errorf("expected %s 21, got %q", gateway.QueryCostHeader, got)
+		}
+		if got := rec.Header().Get(gateway.SubgraphRequestsHeader); got != "2" {
+			t.Errorf("expected %s 2, got %q", gateway.SubgraphRequestsHeader, got)
+		}
+		if got := rec.Header().Get(gateway.RateLimitRemainingHeader); got != "79" {
+			t.Errorf("expected %s 79, got %q", gateway.RateLimitRemainingHeader, got)
+		}
+		if strings.Contains(rec.Body.String(), `"extensions"`) {
+			t.Errorf("expected no extensions, got %s", rec.Body.String())
+		}
+
+		rec = post(gw, "["+query+","+query+"]")
+		if got := rec.Header().Get(gateway.QueryCostHeader); got != "42" {
+			t.Errorf("expected the batch cost 42, got %q", got)
+		}
+		if got := rec.Header().Get(gateway.SubgraphRequestsHeader); got != "4" {
+			t.Errorf("expected 4 batch subgraph requests, got %q", got)
+		}
+		if got := rec.Header().Get(gateway.RateLimitRemainingHeader); got != "37" {
+			t.Errorf("expected the last remaining budget 37, got %q", got)
+		}
+	})
+
+	t.Run("extensions", func(t *testing.T) {
+		gw := newGateway(t, gateway.GatewayOption{
+			ResponseExtensions: gateway.ResponseExtensionsOption{Cost: true},
+		})
+
+		rec := post(gw, query)
+		if rec.Header().Get(gateway.QueryCostHeader) != "" {
+			t.Errorf("expected no cost headers, got %v", rec.Header())
+		}
+		var resp struct {
+			Extensions map[string]map[string]any `json:"extensions"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		cost := resp.Extensions["cost"]
+		if cost["estimated"] != float64(21) || cost["subgraphRequests"] != float64(2) {
+			t.Errorf("unexpected extensions.cost: %v", cost)
+		}
+		if _, ok := cost["remainingBudget"]; ok {
+			t.Errorf("expected no remaining budget without a provider, got %v", cost)
+		}
+	})
+}
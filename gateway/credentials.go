@@ -0,0 +1,228 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// CredentialsProvider authorizes outbound requests to a subgraph, e.g. by setting an
+// Authorization header. Implementations must be safe for concurrent use.
+type CredentialsProvider interface {
+	Apply(req *http.Request) error
+}
+
+// CredentialsProviderFunc adapts a function to a CredentialsProvider.
+type CredentialsProviderFunc func(req *http.Request) error
+
+// Apply calls f(req).
+func (f CredentialsProviderFunc) Apply(req *http.Request) error {
+	return f(req)
+}
+
+// SubgraphAuthOption configures the credentials sent with every request to a subgraph,
+// including the _service query fetching its SDL.
+type SubgraphAuthOption struct {
+	Type   string                        `yaml:"type" validate:"oneof=bearer|api_key|oauth2"` // bearer, api_key or oauth2; empty sends no credentials
+	Token  string                        `yaml:"token"`                                       // Static bearer token (bearer)
+	Header string                        `yaml:"header"`                                      // Header carrying the key (api_key), defaults to X-API-Key
+	Key    string                        `yaml:"key"`                                         // API key (api_key)
+	OAuth2 OAuth2ClientCredentialsOption `yaml:"oauth2"`
+}
+
+// OAuth2ClientCredentialsOption configures the OAuth2 client credentials grant. Tokens
+// are cached and fetched again shortly before they expire.
+type OAuth2ClientCredentialsOption struct {
+	TokenURL       string            `yaml:"token_url"`
+	ClientID       string            `yaml:"client_id"`
+	ClientSecret   string            `yaml:"client_secret"`
+	Scopes         []string          `yaml:"scopes"`
+	EndpointParams map[string]string `yaml:"endpoint_params"` // Extra token request parameters, e.g. audience
+	// AuthStyle sends the client credentials as HTTP basic auth ("header") or as form
+	// parameters ("params").
+	AuthStyle     string `yaml:"auth_style" default:"header"`
+	RefreshBefore string `yaml:"refresh_before" default:"30s" validate:"duration"` // Refresh tokens this long before they expire
+}
+
+// newCredentialsProvider returns the provider described by opt, or nil when opt sends
+// no credentials.
+func newCredentialsProvider(opt SubgraphAuthOption) (CredentialsProvider, error) {
+	switch opt.Type {
+	case "":
+		return nil, nil
+	case "bearer":
+		if opt.Token == "" {
+			return nil, fmt.Errorf("bearer auth requires a token")
+		}
+		value := "Bearer " + opt.Token
+		return CredentialsProviderFunc(func(req *http.Request) error {
+			req.Header.Set("Authorization", value)
+			return nil
+		}), nil
+	case "api_key":
+		if opt.Key == "" {
+			return nil, fmt.Errorf("api_key auth requires a key")
+		}
+		header := opt.Header
+		if header == "" {
+			header = "X-API-Key"
+		}
+		return CredentialsProviderFunc(func(req *http.Request) error {
+			req.Header.Set(header, opt.Key)
+			return nil
+		}), nil
+	case "oauth2":
+		return newOAuth2ClientCredentials(opt.OAuth2)
+	default:
+		return nil, fmt.Errorf("unknown auth type %q", opt.Type)
+	}
+}
+
+// credentialsTransport applies a CredentialsProvider to every request.
+type credentialsTransport struct {
+	provider CredentialsProvider
+	base     http.RoundTripper
+}
+
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	if err := t.provider.Apply(req); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("failed to apply subgraph credentials: %w", err)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// oauth2ClientCredentials sends a bearer token obtained with the OAuth2 client
+// credentials grant, cached until shortly before it expires.
+type oauth2ClientCredentials struct {
+	opt           OAuth2ClientCredentialsOption
+	refreshBefore time.Duration
+	client        *http.Client
+	now           func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time // Zero when the token does not expire
+}
+
+// oauth2TokenTimeout bounds a single token request.
+const oauth2TokenTimeout = 10 * time.Second
+
+func newOAuth2ClientCredentials(opt OAuth2ClientCredentialsOption) (*oauth2ClientCredentials, error) {
+	if opt.TokenURL == "" || opt.ClientID == "" {
+		return nil, fmt.Errorf("oauth2 auth requires token_url and client_id")
+	}
+	switch opt.AuthStyle {
+	case "", "header", "params":
+	default:
+		return nil, fmt.Errorf("unknown oauth2 auth_style %q", opt.AuthStyle)
+	}
+
+	refreshBefore := 30 * time.Second
+	if opt.RefreshBefore != "" {
+		d, err := time.ParseDuration(opt.RefreshBefore)
+		if err != nil {
+			return nil, fmt.Errorf("invalid oauth2 refresh_before %q: %w", opt.RefreshBefore, err)
+		}
+		refreshBefore = d
+	}
+
+	return &oauth2ClientCredentials{
+		opt:           opt,
+		refreshBefore: refreshBefore,
+		client:        &http.Client{Timeout: oauth2TokenTimeout},
+		now:           time.Now,
+	}, nil
+}
+
+// Apply sets the Authorization header of req to the current access token.
+func (c *oauth2ClientCredentials) Apply(req *http.Request) error {
+	token, err := c.accessToken(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// accessToken returns the cached token, fetching a new one when it is missing or about
+// to expire. Concurrent callers wait for a single token request.
+func (c *oauth2ClientCredentials) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.expiry.IsZero() || c.now().Before(c.expiry.Add(-c.refreshBefore))) {
+		return c.token, nil
+	}
+
+	token, expiresIn, err := c.fetchToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expiry = time.Time{}
+	if expiresIn > 0 {
+		c.expiry = c.now().Add(expiresIn)
+	}
+	return c.token, nil
+}
+
+// fetchToken requests a token from the token endpoint.
+func (c *oauth2ClientCredentials) fetchToken(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.opt.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.opt.Scopes, " "))
+	}
+	for k, v := range c.opt.EndpointParams {
+		form.Set(k, v)
+	}
+	if c.opt.AuthStyle == "params" {
+		form.Set("client_id", c.opt.ClientID)
+		form.Set("client_secret", c.opt.ClientSecret)
+	}
+
+	// The token request must not be cancelled with the subgraph request that
+	// triggered it, as the token is shared by later requests.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), oauth2TokenTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opt.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to build oauth2 token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.opt.AuthStyle != "params" {
+		req.SetBasicAuth(url.QueryEscape(c.opt.ClientID), url.QueryEscape(c.opt.ClientSecret))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("oauth2 token request failed: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, fmt.Errorf("failed to decode oauth2 token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", 0, fmt.Errorf("oauth2 token response has no access_token")
+	}
+	return body.AccessToken, time.Duration(body.ExpiresIn) * time.Second, nil
+}
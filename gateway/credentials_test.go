@@ -0,0 +1,228 @@
+package gateway_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+const sdlCredentials = `
+	type Query {
+		hello: String
+	}
+`
+
+// newCredentialsSubgraph returns a subgraph recording the given header of every request,
+// including the _service query.
+func newCredentialsSubgraph(t *testing.T, header string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var values []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		values = append(values, r.Header.Get(header))
+		mu.Unlock()
+
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdlCredentials}}}) //nolint:errcheck
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"hello": "world"}}) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, values...)
+	}
+}
+
+func queryHello(t *testing.T, gw http.Handler) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ hello }"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"world"`) {
+		t.Fatalf("expected hello to resolve, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGateway_SubgraphAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   gateway.SubgraphAuthOption
+		header string
+		want   string
+	}{
+		{
+			name:   "bearer",
+			auth:   gateway.SubgraphAuthOption{Type: "bearer", Token: "secret"},
+			header: "Authorization",
+			want:   "Bearer secret",
+		},
+		{
+			name:   "api key with default header",
+			auth:   gateway.SubgraphAuthOption{Type: "api_key", Key: "k1"},
+			header: "X-API-Key",
+			want:   "k1",
+		},
+		{
+			name:   "api key with custom header",
+			auth:   gateway.SubgraphAuthOption{Type: "api_key", Header: "X-Subgraph-Key", Key: "k2"},
+			header: "X-Subgraph-Key",
+			want:   "k2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, values := newCredentialsSubgraph(t, tt.header)
+			gw, err := gateway.NewGateway(gateway.GatewayOption{
+				Endpoint: "/graphql",
+				Services: []gateway.GatewayService{{Name: "hello", Host: srv.URL, Auth: tt.auth}},
+			})
+			if err != nil {
+				t.Fatalf("NewGateway failed: %v", err)
+			}
+			queryHello(t, gw)
+
+			got := values()
+			if len(got) != 2 {
+				t.Fatalf("expected the _service and query requests, got %d", len(got))
+			}
+			for _, v := range got {
+				if v != tt.want {
+					t.Errorf("expected %s %q, got %q", tt.header, tt.want, v)
+				}
+			}
+		})
+	}
+}
+
+func TestGateway_SubgraphAuth_OAuth2(t *testing.T) {
+	tests := []struct {
+		name        string
+		expiresIn   int
+		wantFetches int32
+	}{
+		{name: "cached token", expiresIn: 3600, wantFetches: 1},
+		{name: "token expiring within refresh_before", expiresIn: 10, wantFetches: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches atomic.Int32
+			tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, secret, ok := r.BasicAuth()
+				if !ok || id != "client" || secret != "s3cret" {
+					http.Error(w, "invalid client", http.StatusUnauthorized)
+					return
+				}
+				if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" ||
+					r.PostForm.Get("scope") != "read write" || r.PostForm.Get("audience") != "subgraphs" {
+					http.Error(w, "invalid request", http.StatusBadRequest)
+					return
+				}
+				n := fetches.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+					"access_token": fmt.Sprintf("token-%d", n),
+					"token_type":   "Bearer",
+					"expires_in":   tt.expiresIn,
+				})
+			}))
+			t.Cleanup(tokenServer.Close)
+
+			srv, values := newCredentialsSubgraph(t, "Authorization")
+			gw, err := gateway.NewGateway(gateway.GatewayOption{
+				Endpoint: "/graphql",
+				Services: []gateway.GatewayService{{
+					Name: "hello",
+					Host: srv.URL,
+					Auth: gateway.SubgraphAuthOption{
+						Type: "oauth2",
+						OAuth2: gateway.OAuth2ClientCredentialsOption{
+							TokenURL:       tokenServer.URL,
+							ClientID:       "client",
+							ClientSecret:   "s3cret",
+							Scopes:         []string{"read", "write"},
+							EndpointParams: map[string]string{"audience": "subgraphs"},
+							RefreshBefore:  "30s",
+						},
+					},
+				}},
+			})
+			if err != nil {
+				t.Fatalf("NewGateway failed: %v", err)
+			}
+			queryHello(t, gw)
+			queryHello(t, gw)
+
+			if n := fetches.Load(); n != tt.wantFetches {
+				t.Errorf("expected %d token requests, got %d", tt.wantFetches, n)
+			}
+			got := values()
+			if last := got[len(got)-1]; last != fmt.Sprintf("Bearer token-%d", tt.wantFetches) {
+				t.Errorf("expected the latest token to be sent, got %q", last)
+			}
+		})
+	}
+}
+
+func TestGateway_SubgraphAuth_CustomProvider(t *testing.T) {
+	srv, values := newCredentialsSubgraph(t, "X-Signature")
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{
+			Name: "hello",
+			Host: srv.URL,
+			// Overridden by the custom provider
+			Auth: gateway.SubgraphAuthOption{Type: "bearer", Token: "unused"},
+		}},
+		CredentialsProviders: map[string]gateway.CredentialsProvider{
+			"hello": gateway.CredentialsProviderFunc(func(req *http.Request) error {
+				req.Header.Set("X-Signature", "signed:"+req.URL.Host)
+				return nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	queryHello(t, gw)
+
+	want := "signed:" + strings.TrimPrefix(srv.URL, "http://")
+	for _, v := range values() {
+		if v != want {
+			t.Errorf("expected X-Signature %q, got %q", want, v)
+		}
+	}
+}
+
+func TestGateway_SubgraphAuth_Invalid(t *testing.T) {
+	for _, auth := range []gateway.SubgraphAuthOption{
+		{Type: "bearer"},
+		{Type: "api_key"},
+		{Type: "oauth2"},
+		{Type: "oauth2", OAuth2: gateway.OAuth2ClientCredentialsOption{TokenURL: "http://idp", ClientID: "c", AuthStyle: "cookie"}},
+		{Type: "kerberos"},
+	} {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{{Name: "hello", Host: "http://127.0.0.1:0", Auth: auth}},
+		})
+		if err == nil || !strings.Contains(err.Error(), "invalid auth") {
+			t.Errorf("expected an invalid auth error for %+v, got %v", auth, err)
+		}
+	}
+}
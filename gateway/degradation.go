@@ -0,0 +1,203 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// DegradationOption configures the response to operations whose root steps all failed,
+// e.g. because every subgraph they call is down.
+type DegradationOption struct {
+	// Mode is partial, error or stale. partial answers data with null root fields and the
+	// errors; error answers data null with the errors and a 503, or a 502 when subgraphs
+	// answered with errors only; stale answers the last successful response to the same
+	// query, or as error without one.
+	Mode        string   `yaml:"mode" default:"partial" validate:"oneof=partial|error|stale"`
+	MaxStaleAge string   `yaml:"max_stale_age" default:"5m" validate:"duration"` // stale: age of the oldest response served
+	MaxEntries  int      `yaml:"max_entries" default:"1000"`                     // stale: successful responses kept in memory
+	VaryHeaders []string `yaml:"vary_headers"`                                   // stale: request headers responses depend on; defaults to Authorization and Cookie
+}
+
+// Modes of DegradationOption.
+const (
+	degradationPartial = "partial"
+	degradationError   = "error"
+	degradationStale   = "stale"
+)
+
+// degradationExtension is the response extension describing a degraded response.
+const degradationExtension = "degradation"
+
+// degradation answers the operations whose plan failed entirely, keeping the last
+// successful response of queries in stale mode.
+type degradation struct {
+	stale       bool
+	maxAge      time.Duration
+	maxEntries  int
+	varyHeaders []string
+	now         func() time.Time
+
+	mu        sync.Mutex
+	responses map[string]staleResponse // storeKey → response
+}
+
+// staleResponse is a successful response kept to answer the same query later.
+type staleResponse struct {
+	body   []byte
+	stored time.Time
+}
+
+type degradationVaryContextKey struct{}
+
+// newDegradation returns the degradation of opt, or nil in partial mode.
+func newDegradation(opt DegradationOption) (*degradation, error) {
+	switch opt.Mode {
+	case "", degradationPartial:
+		return nil, nil
+	case degradationError, degradationStale:
+	default:
+		return nil, fmt.Errorf("unknown degradation mode %q: use partial, error or stale", opt.Mode)
+	}
+	d := &degradation{
+		stale:       opt.Mode == degradationStale,
+		maxAge:      5 * time.Minute,
+		maxEntries:  opt.MaxEntries,
+		varyHeaders: opt.VaryHeaders,
+		now:         time.Now,
+		responses:   make(map[string]staleResponse),
+	}
+	if opt.MaxStaleAge != "" {
+		maxAge, err := time.ParseDuration(opt.MaxStaleAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid degradation.max_stale_age %q: %w", opt.MaxStaleAge, err)
+		}
+		d.maxAge = maxAge
+	}
+	if d.maxEntries <= 0 {
+		d.maxEntries = 1000
+	}
+	if len(d.varyHeaders) == 0 {
+		d.varyHeaders = []string{"Authorization", "Cookie"}
+	}
+	return d, nil
+}
+
+// withRequest attaches the values of the vary headers of r to ctx, so responses are
+// only served again to requests sending the same values.
+func (d *degradation) withRequest(ctx context.Context, r *http.Request) context.Context {
+	if d == nil || !d.stale {
+		return ctx
+	}
+	var vary strings.Builder
+	for _, name := range d.varyHeaders {
+		vary.WriteString(strings.Join(r.Header.Values(name), ","))
+		vary.WriteByte(0)
+	}
+	return context.WithValue(ctx, degradationVaryContextKey{}, vary.String())
+}
+
+// start makes the execution of an operation report a plan whose root steps all failed.
+func (d *degradation) start(ctx context.Context) context.Context {
+	if d == nil {
+		return ctx
+	}
+	return executor.SetPlanFailureToContext(ctx)
+}
+
+// store keeps resp, the successful response to req, in stale mode. Responses with
+// errors and responses to other operations than queries are not kept.
+func (d *degradation) store(ctx context.Context, req graphQLRequest, operationType string, resp map[string]any) {
+	if d == nil || !d.stale || operationType != string(ast.Query) {
+		return
+	}
+	if _, hasErrors := resp["errors"]; hasErrors {
+		return
+	}
+	body, err := json.Marshal(map[string]any{"data": resp["data"]})
+	if err != nil {
+		return
+	}
+	key := d.storeKey(ctx, req)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, exists := d.responses[key]; !exists && len(d.responses) >= d.maxEntries {
+		for evicted := range d.responses {
+			delete(d.responses, evicted)
+			break
+		}
+	}
+	d.responses[key] = staleResponse{body: body, stored: d.now()}
+}
+
+// respond answers req, an operation of operationType whose plan failed: with the stale
+// response to the same query when there is a recent enough one, otherwise with data
+// null, the errors of the failure and a 503, or a 502 when subgraphs answered.
+func (d *degradation) respond(ctx context.Context, req graphQLRequest, operationType string, failure *executor.PlanFailedError) (int, map[string]any) {
+	if d.stale && operationType == string(ast.Query) {
+		if resp, age, ok := d.lookup(ctx, req); ok {
+			resp["extensions"] = map[string]any{degradationExtension: map[string]any{
+				"mode":            degradationStale,
+				"ageSeconds":      age.Seconds(),
+				"failedSubgraphs": failure.SubGraphs,
+			}}
+			return http.StatusOK, resp
+		}
+	}
+
+	status := http.StatusBadGateway
+	if failure.Unavailable {
+		status = http.StatusServiceUnavailable
+	}
+	return status, map[string]any{
+		"data":   nil,
+		"errors": failure.Errors,
+		"extensions": map[string]any{degradationExtension: map[string]any{
+			"mode":            degradationError,
+			"failedSubgraphs": failure.SubGraphs,
+		}},
+	}
+}
+
+// lookup returns a copy of the stale response to req and its age, unless it is older
+// than maxAge.
+func (d *degradation) lookup(ctx context.Context, req graphQLRequest) (map[string]any, time.Duration, bool) {
+	d.mu.Lock()
+	stored, ok := d.responses[d.storeKey(ctx, req)]
+	d.mu.Unlock()
+	if !ok {
+		return nil, 0, false
+	}
+	age := d.now().Sub(stored.stored)
+	if age > d.maxAge {
+		return nil, 0, false
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(stored.body, &resp); err != nil {
+		return nil, 0, false
+	}
+	return resp, age, true
+}
+
+// storeKey identifies the responses to req for the vary header values attached to ctx.
+func (d *degradation) storeKey(ctx context.Context, req graphQLRequest) string {
+	vary, _ := ctx.Value(degradationVaryContextKey{}).(string)
+	variables, _ := json.Marshal(req.Variables)
+	h := sha256.New()
+	for _, part := range []string{req.Query, req.OperationName, string(variables), vary} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,198 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// Behaviours of a flakySubgraph.
+const (
+	subgraphUp int32 = iota
+	subgraphDown
+	subgraphErrors
+)
+
+// newFlakySubgraph serves sdl and answers operations with resp while up, with a 503 while
+// down, or with errors and no data.
+func newFlakySubgraph(t *testing.T, sdl string, state *atomic.Int32, resp any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+			return
+		}
+		switch state.Load() {
+		case subgraphDown:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case subgraphErrors:
+			json.NewEncoder(w).Encode(map[string]any{"data": nil, "errors": []any{map[string]any{"message": "database unavailable"}}}) //nolint:errcheck
+		default:
+			json.NewEncoder(w).Encode(resp) //nolint:errcheck
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGateway_Degradation(t *testing.T) {
+	var productsState, reviewsState atomic.Int32
+	products := newFlakySubgraph(t, sdlProducts, &productsState,
+		map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "chair"}}})
+	reviews := newFlakySubgraph(t, sdlReviews, &reviewsState,
+		map[string]any{"data": map[string]any{"reviews": []any{map[string]any{"id": "r1", "body": "great"}}}})
+
+	newGateway := func(t *testing.T, opt gateway.DegradationOption) http.Handler {
+		t.Helper()
+		productsState.Store(subgraphUp)
+		reviewsState.Store(subgraphUp)
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{
+				{Name: "products", Host: products.URL},
+				{Name: "reviews", Host: reviews.URL},
+			},
+			Degradation: opt,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+	serve := func(t *testing.T, gw http.Handler, authorization string) (int, map[string]any) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/graphql",
+			strings.NewReader(`{"query":"{ product(id: \"1\") { name } reviews { body } }"}`))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rec.Code, resp
+	}
+	degradationMode := func(resp map[string]any) any {
+		extensions, _ := resp["extensions"].(map[string]any)
+		degradation, _ := extensions["degradation"].(map[string]any)
+		return degradation["mode"]
+	}
+
+	t.Run("partial by default", func(t *testing.T) {
+		gw := newGateway(t, gateway.DegradationOption{})
+		productsState.Store(subgraphDown)
+		reviewsState.Store(subgraphDown)
+
+		status, resp := serve(t, gw, "")
+		if status != http.StatusOK {
+			t.Errorf("expected 200, got %d", status)
+		}
+		data, ok := resp["data"].(map[string]any)
+		if !ok || data["product"] != nil || data["reviews"] != nil {
+			t.Errorf("expected null root fields, got %v", resp)
+		}
+	})
+
+	t.Run("error mode with every subgraph down", func(t *testing.T) {
+		gw := newGateway(t, gateway.DegradationOption{Mode: "error"})
+		productsState.Store(subgraphDown)
+		reviewsState.Store(subgraphDown)
+
+		status, resp := serve(t, gw, "")
+		if status != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", status)
+		}
+		if data, ok := resp["data"]; !ok || data != nil {
+			t.Errorf("expected data null, got %v", resp)
+		}
+		if errs, _ := resp["errors"].([]any); len(errs) != 2 {
+			t.Errorf("expected the errors of both subgraphs, got %v", resp["errors"])
+		}
+		if mode := degradationMode(resp); mode != "error" {
+			t.Errorf("expected the degradation extension, got %v", resp["extensions"])
+		}
+	})
+
+	t.Run("error mode with subgraphs answering errors", func(t *testing.T) {
+		gw := newGateway(t, gateway.DegradationOption{Mode: "error"})
+		productsState.Store(subgraphDown)
+		reviewsState.Store(subgraphErrors)
+
+		status, resp := serve(t, gw, "")
+		if status != http.StatusBadGateway {
+			t.Errorf("expected 502, got %d", status)
+		}
+		if data, ok := resp["data"]; !ok || data != nil {
+			t.Errorf("expected data null, got %v", resp)
+		}
+	})
+
+	t.Run("error mode with one subgraph up", func(t *testing.T) {
+		gw := newGateway(t, gateway.DegradationOption{Mode: "error"})
+		reviewsState.Store(subgraphDown)
+
+		status, resp := serve(t, gw, "")
+		if status != http.StatusOK || degradationMode(resp) != nil {
+			t.Errorf("expected a partial response, got %d %v", status, resp)
+		}
+		data, _ := resp["data"].(map[string]any)
+		if product, _ := data["product"].(map[string]any); product["name"] != "chair" {
+			t.Errorf("expected the product, got %v", resp)
+		}
+	})
+
+	t.Run("stale mode", func(t *testing.T) {
+		gw := newGateway(t, gateway.DegradationOption{Mode: "stale"})
+		if status, _ := serve(t, gw, "Bearer alice"); status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+		productsState.Store(subgraphDown)
+		reviewsState.Store(subgraphDown)
+
+		status, resp := serve(t, gw, "Bearer alice")
+		if status != http.StatusOK || degradationMode(resp) != "stale" {
+			t.Fatalf("expected a stale response, got %d %v", status, resp)
+		}
+		data, _ := resp["data"].(map[string]any)
+		if product, _ := data["product"].(map[string]any); product["name"] != "chair" {
+			t.Errorf("expected the stale product, got %v", resp)
+		}
+		if _, ok := resp["errors"]; ok {
+			t.Errorf("expected no errors, got %v", resp["errors"])
+		}
+
+		status, resp = serve(t, gw, "Bearer bob")
+		if status != http.StatusServiceUnavailable || degradationMode(resp) != "error" {
+			t.Errorf("expected another user not to receive the stale response, got %d %v", status, resp)
+		}
+	})
+
+	t.Run("stale mode ignores expired responses", func(t *testing.T) {
+		gw := newGateway(t, gateway.DegradationOption{Mode: "stale", MaxStaleAge: "1ns"})
+		serve(t, gw, "")
+		productsState.Store(subgraphDown)
+		reviewsState.Store(subgraphDown)
+
+		if status, _ := serve(t, gw, ""); status != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", status)
+		}
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{Degradation: gateway.DegradationOption{Mode: "retry"}})
+		if err == nil {
+			t.Error("expected an error for an unknown mode")
+		}
+	})
+}
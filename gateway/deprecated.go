@@ -0,0 +1,274 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+const (
+	// defaultDeprecationReason is the reason of a @deprecated directive without one.
+	defaultDeprecationReason = "No longer supported"
+	// deprecatedFieldsPath reports the usage of @deprecated fields.
+	deprecatedFieldsPath = "/admin/deprecated-fields"
+)
+
+// DeprecatedUsageOption configures tracking of selected @deprecated fields.
+type DeprecatedUsageOption struct {
+	Enable       bool   `yaml:"enable" default:"false"`
+	ClientHeader string `yaml:"client_header"` // Request header naming the client; the client identity when unset
+}
+
+// DeprecatedFieldUsage is a @deprecated field selected by an operation.
+type DeprecatedFieldUsage struct {
+	Field  string `json:"field"` // Schema coordinate, e.g. "Product.oldName"
+	Reason string `json:"reason"`
+}
+
+// deprecatedFieldUsages returns the distinct @deprecated fields selected by the
+// operations of doc, in document order.
+func deprecatedFieldUsages(doc *ast.Document, engine *executionEngine) []DeprecatedFieldUsage {
+	if doc == nil || engine == nil {
+		return nil
+	}
+
+	fragmentDefs := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			fragmentDefs[fragDef.Name.String()] = fragDef
+		}
+	}
+
+	var usages []DeprecatedFieldUsage
+	seen := make(map[string]bool)
+	for _, def := range doc.Definitions {
+		opDef, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		rootTypeName := engine.superGraph.RootTypeName(opDef.Operation)
+		collectDeprecatedFields(opDef.SelectionSet, rootTypeName, fragmentDefs, engine, make(map[string]bool), seen, &usages)
+	}
+	return usages
+}
+
+// collectDeprecatedFields appends the @deprecated fields of selections not yet in seen.
+func collectDeprecatedFields(selections []ast.Selection, parentTypeName string, fragmentDefs map[string]*ast.FragmentDefinition, engine *executionEngine, visited, seen map[string]bool, usages *[]DeprecatedFieldUsage) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			fieldDef := findFieldDefinition(parentTypeName, s.Name.String(), engine)
+			if fieldDef == nil {
+				continue
+			}
+			coordinate := parentTypeName + "." + s.Name.String()
+			if reason, ok := deprecationReason(fieldDef.Directives); ok && !seen[coordinate] {
+				seen[coordinate] = true
+				*usages = append(*usages, DeprecatedFieldUsage{Field: coordinate, Reason: reason})
+			}
+			if len(s.SelectionSet) > 0 {
+				collectDeprecatedFields(s.SelectionSet, unwrapNamedType(fieldDef.Type), fragmentDefs, engine, visited, seen, usages)
+			}
+		case *ast.InlineFragment:
+			typeCondition := parentTypeName
+			if s.TypeCondition != nil {
+				typeCondition = s.TypeCondition.Name.String()
+			}
+			collectDeprecatedFields(s.SelectionSet, typeCondition, fragmentDefs, engine, visited, seen, usages)
+		case *ast.FragmentSpread:
+			name := s.Name.String()
+			fragDef, ok := fragmentDefs[name]
+			if !ok || visited[name] {
+				continue
+			}
+			visited[name] = true
+			collectDeprecatedFields(fragDef.SelectionSet, fragDef.TypeCondition.Name.String(), fragmentDefs, engine, visited, seen, usages)
+		}
+	}
+}
+
+// deprecationReason returns the reason of the @deprecated directive in directives.
+func deprecationReason(directives []*ast.Directive) (string, bool) {
+	for _, d := range directives {
+		if d.Name != "deprecated" {
+			continue
+		}
+		for _, arg := range d.Arguments {
+			if arg.Name.String() != "reason" {
+				continue
+			}
+			if s, ok := arg.Value.(*ast.StringValue); ok {
+				return s.Value, true
+			}
+		}
+		return defaultDeprecationReason, true
+	}
+	return "", false
+}
+
+// unwrapNamedType returns the named type wrapped by list and non-null types.
+func unwrapNamedType(t ast.Type) string {
+	switch typ := t.(type) {
+	case *ast.NamedType:
+		return typ.Name.String()
+	case *ast.ListType:
+		return unwrapNamedType(typ.Type)
+	case *ast.NonNullType:
+		return unwrapNamedType(typ.Type)
+	}
+	return ""
+}
+
+// deprecatedUsageKey identifies one usage counter.
+type deprecatedUsageKey struct {
+	field     string
+	operation string
+	client    string
+}
+
+// deprecatedUsageTracker counts selected @deprecated fields per operation and client,
+// and mirrors the counts to the graphql.deprecated_field.usage metric.
+type deprecatedUsageTracker struct {
+	clientHeader string
+	counter      metric.Int64Counter
+	labels       metric.MeasurementOption
+
+	mu      sync.Mutex
+	reasons map[string]string
+	counts  map[deprecatedUsageKey]int64
+}
+
+// newDeprecatedUsageTracker returns a tracker for opt, or nil when tracking is disabled.
+func newDeprecatedUsageTracker(opt DeprecatedUsageOption, labels metric.MeasurementOption) (*deprecatedUsageTracker, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+	counter, err := otel.Meter("github.com/n9te9/go-graphql-federation-gateway").Int64Counter(
+		"graphql.deprecated_field.usage",
+		metric.WithDescription("Number of operations selecting a @deprecated field"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deprecated field usage counter: %w", err)
+	}
+	return &deprecatedUsageTracker{
+		clientHeader: opt.ClientHeader,
+		counter:      counter,
+		labels:       labels,
+		reasons:      make(map[string]string),
+		counts:       make(map[deprecatedUsageKey]int64),
+	}, nil
+}
+
+type clientNameContextKey struct{}
+
+// withClientName attaches the client name sent in r to ctx, when the tracker has a
+// header of its own; otherwise the client identity names the client.
+func (t *deprecatedUsageTracker) withClientName(ctx context.Context, r *http.Request) context.Context {
+	if t.clientHeader == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, clientNameContextKey{}, r.Header.Get(t.clientHeader))
+}
+
+// record counts one use of every field in usages by operation.
+func (t *deprecatedUsageTracker) record(ctx context.Context, operation string, usages []DeprecatedFieldUsage) {
+	if len(usages) == 0 {
+		return
+	}
+	client, ok := ctx.Value(clientNameContextKey{}).(string)
+	if !ok {
+		client = ClientInfoFromContext(ctx).Name
+	}
+
+	t.mu.Lock()
+	for _, usage := range usages {
+		t.reasons[usage.Field] = usage.Reason
+		t.counts[deprecatedUsageKey{field: usage.Field, operation: operation, client: client}]++
+	}
+	t.mu.Unlock()
+
+	for _, usage := range usages {
+		t.counter.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("graphql.field.coordinate", usage.Field),
+			attribute.String("graphql.operation.name", operation),
+			attribute.String("graphql.client.name", client),
+		), t.labels)
+	}
+}
+
+// deprecatedFieldReport is the usage of one @deprecated field.
+type deprecatedFieldReport struct {
+	Field  string                      `json:"field"`
+	Reason string                      `json:"reason"`
+	Count  int64                       `json:"count"`
+	Usages []deprecatedFieldUsageCount `json:"usages"`
+}
+
+// deprecatedFieldUsageCount is how often one operation of one client used a field.
+type deprecatedFieldUsageCount struct {
+	Operation string `json:"operation"`
+	Client    string `json:"client"`
+	Count     int64  `json:"count"`
+}
+
+// report returns the usage of every field seen so far, most used first.
+func (t *deprecatedUsageTracker) report() []deprecatedFieldReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	byField := make(map[string]*deprecatedFieldReport)
+	for key, count := range t.counts {
+		r, ok := byField[key.field]
+		if !ok {
+			r = &deprecatedFieldReport{Field: key.field, Reason: t.reasons[key.field]}
+			byField[key.field] = r
+		}
+		r.Count += count
+		r.Usages = append(r.Usages, deprecatedFieldUsageCount{Operation: key.operation, Client: key.client, Count: count})
+	}
+
+	reports := make([]deprecatedFieldReport, 0, len(byField))
+	for _, r := range byField {
+		sort.Slice(r.Usages, func(i, j int) bool {
+			if r.Usages[i].Count != r.Usages[j].Count {
+				return r.Usages[i].Count > r.Usages[j].Count
+			}
+			if r.Usages[i].Operation != r.Usages[j].Operation {
+				return r.Usages[i].Operation < r.Usages[j].Operation
+			}
+			return r.Usages[i].Client < r.Usages[j].Client
+		})
+		reports = append(reports, *r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Count != reports[j].Count {
+			return reports[i].Count > reports[j].Count
+		}
+		return reports[i].Field < reports[j].Field
+	})
+	return reports
+}
+
+// deprecatedFieldsResponse is the usage report of the @deprecated fields.
+type deprecatedFieldsResponse struct {
+	Fields []deprecatedFieldReport `json:"fields"`
+}
+
+// handleDeprecatedFields processes a GET /admin/deprecated-fields request.
+func (g *gateway) handleDeprecatedFields(w http.ResponseWriter) {
+	if g.deprecatedUsage == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deprecatedFieldsResponse{Fields: g.deprecatedUsage.report()}) //nolint:errcheck
+}
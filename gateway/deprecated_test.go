@@ -0,0 +1,116 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_DeprecatedUsage(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			oldName: String @deprecated(reason: "Use name")
+			sku: String @deprecated
+		}
+
+		type Query {
+			topProducts: [Product!]!
+			products: [Product!]! @deprecated(reason: "Use topProducts")
+		}
+	`
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{
+			"topProducts": []any{map[string]any{"id": "1", "name": "a", "oldName": "a", "sku": "s"}},
+			"products":    []any{},
+		}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:        "/graphql",
+		Services:        []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		DeprecatedUsage: gateway.DeprecatedUsageOption{Enable: true, ClientHeader: "X-Client"},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	query := func(client, body string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		req.Header.Set("X-Client", client)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("query failed with %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	query("web", `{"query":"query Top { topProducts { id oldName ...Sku } } fragment Sku on Product { sku oldName }"}`)
+	query("web", `{"query":"query Top { topProducts { id oldName } }"}`)
+	query("ios", `{"query":"query Names { topProducts { name } }"}`)
+	query("ios", `{"query":"query All { products { id } }"}`)
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/deprecated-fields", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var report struct {
+		Fields []struct {
+			Field  string `json:"field"`
+			Reason string `json:"reason"`
+			Count  int64  `json:"count"`
+			Usages []struct {
+				Operation string `json:"operation"`
+				Client    string `json:"client"`
+				Count     int64  `json:"count"`
+			} `json:"usages"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+
+	if len(report.Fields) != 3 {
+		t.Fatalf("expected 3 deprecated fields, got %s", rec.Body.String())
+	}
+	oldName := report.Fields[0]
+	if oldName.Field != "Product.oldName" || oldName.Reason != "Use name" || oldName.Count != 2 {
+		t.Errorf("expected Product.oldName used twice first, got %+v", oldName)
+	}
+	if len(oldName.Usages) != 1 || oldName.Usages[0].Operation != "Top" || oldName.Usages[0].Client != "web" {
+		t.Errorf("expected usages by Top from web, got %+v", oldName.Usages)
+	}
+	if f := report.Fields[1]; f.Field != "Product.sku" || f.Reason != "No longer supported" || f.Count != 1 {
+		t.Errorf("expected Product.sku with the default reason, got %+v", f)
+	}
+	if f := report.Fields[2]; f.Field != "Query.products" || f.Usages[0].Client != "ios" {
+		t.Errorf("expected Query.products used by ios, got %+v", f)
+	}
+}
+
+func TestGateway_DeprecatedUsageDisabled(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/deprecated-fields", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when tracking is disabled, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// DryRunOption configures dry runs of mutations, requested with a header by pre-flight
+// validation tools. A dry run plans the mutation and sends it to subgraphs with the
+// subgraph header only when every subgraph it reaches supports dry runs
+// (GatewayService.DryRun); otherwise nothing is executed.
+type DryRunOption struct {
+	Enable         bool   `yaml:"enable" default:"false"`
+	RequestHeader  string `yaml:"request_header" default:"X-Dry-Run"`  // Client header requesting a dry run when set to true or 1
+	SubgraphHeader string `yaml:"subgraph_header" default:"X-Dry-Run"` // Header set to true on the subgraph requests of a dry run
+}
+
+// dryRunExtension is the response extension describing a dry run.
+const dryRunExtension = "dryRun"
+
+// dryRun runs the mutations of requests asking for a dry run.
+type dryRun struct {
+	requestHeader  string
+	subgraphHeader string
+	supported      map[string]bool // Subgraphs honoring subgraphHeader
+}
+
+type dryRunContextKey struct{}
+
+// newDryRun returns the dry run support of opt, or nil when it is disabled.
+func newDryRun(opt DryRunOption, services []GatewayService) *dryRun {
+	if !opt.Enable {
+		return nil
+	}
+	d := &dryRun{
+		requestHeader:  opt.RequestHeader,
+		subgraphHeader: opt.SubgraphHeader,
+		supported:      make(map[string]bool),
+	}
+	if d.requestHeader == "" {
+		d.requestHeader = "X-Dry-Run"
+	}
+	if d.subgraphHeader == "" {
+		d.subgraphHeader = "X-Dry-Run"
+	}
+	for _, s := range services {
+		if s.DryRun {
+			d.supported[s.Name] = true
+		}
+	}
+	return d
+}
+
+// withRequest marks ctx when r asks for a dry run.
+func (d *dryRun) withRequest(ctx context.Context, r *http.Request) context.Context {
+	if d == nil {
+		return ctx
+	}
+	if requested, _ := strconv.ParseBool(r.Header.Get(d.requestHeader)); !requested {
+		return ctx
+	}
+	return context.WithValue(ctx, dryRunContextKey{}, true)
+}
+
+// applies reports whether plan is run dry for the request of ctx: only mutations are,
+// since other operations have no side effects.
+func (d *dryRun) applies(ctx context.Context, plan *planner.PlanV2) bool {
+	requested, _ := ctx.Value(dryRunContextKey{}).(bool)
+	return d != nil && requested && plan.OperationType == string(ast.Mutation)
+}
+
+// subgraphs returns the subgraphs plan sends requests to, and those of them without
+// dry-run support.
+func (d *dryRun) subgraphs(plan *planner.PlanV2) (all, unsupported []string) {
+	for _, step := range plan.Steps {
+		if step.SubGraph == nil || slices.Contains(all, step.SubGraph.Name) {
+			continue
+		}
+		all = append(all, step.SubGraph.Name)
+		if !d.supported[step.SubGraph.Name] {
+			unsupported = append(unsupported, step.SubGraph.Name)
+		}
+	}
+	return all, unsupported
+}
+
+// refuse returns the response to a dry run reaching subgraphs without dry-run support;
+// the mutation is not executed.
+func (d *dryRun) refuse(ctx context.Context, all, unsupported []string) (int, map[string]any) {
+	status, resp := requestError(ctx, CodeDryRunUnsupported,
+		fmt.Sprintf("dry run not executed: subgraphs %s do not support dry runs", strings.Join(unsupported, ", ")))
+	resp["extensions"] = map[string]any{
+		dryRunExtension: map[string]any{"executed": false, "subgraphs": all, "unsupportedSubgraphs": unsupported},
+	}
+	return status, resp
+}
+
+// withSubgraphHeader adds the dry-run header to the subgraph requests made with ctx.
+func (d *dryRun) withSubgraphHeader(ctx context.Context) context.Context {
+	header := executor.GetSubgraphHeadersFromContext(ctx).Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set(d.subgraphHeader, "true")
+	return executor.SetSubgraphHeadersToContext(ctx, header)
+}
+
+// addExtension records in resp that the mutation ran dry on subgraphs.
+func (d *dryRun) addExtension(resp map[string]any, subgraphs []string) {
+	extensions, _ := resp["extensions"].(map[string]any)
+	if extensions == nil {
+		extensions = make(map[string]any)
+		resp["extensions"] = extensions
+	}
+	extensions[dryRunExtension] = map[string]any{"executed": true, "subgraphs": subgraphs}
+}
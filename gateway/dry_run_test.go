@@ -0,0 +1,140 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_DryRun(t *testing.T) {
+	const ordersSDL = `
+		type Query {
+			order(id: ID!): Order
+		}
+
+		type Mutation {
+			placeOrder(productId: ID!): Order
+		}
+
+		type Order @key(fields: "id") {
+			id: ID!
+			status: String
+		}`
+	const shippingSDL = `
+		extend type Order @key(fields: "id") {
+			id: ID! @external
+			eta: String
+		}`
+
+	var mu sync.Mutex
+	var dryRunHeaders []string
+	record := func(r *http.Request) {
+		mu.Lock()
+		dryRunHeaders = append(dryRunHeaders, r.Header.Get("X-Dry-Run"))
+		mu.Unlock()
+	}
+	newServer := func(sdl string, resp func(body map[string]any) any) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+			w.Header().Set("Content-Type", "application/json")
+			if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+				json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+				return
+			}
+			record(r)
+			json.NewEncoder(w).Encode(resp(body)) //nolint:errcheck
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	orders := newServer(ordersSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"placeOrder": map[string]any{"__typename": "Order", "id": "o1", "status": "VALIDATED"}}}
+	})
+	shipping := newServer(shippingSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"_entities": []any{map[string]any{"__typename": "Order", "eta": "tomorrow"}}}}
+	})
+
+	serve := func(t *testing.T, shippingDryRun bool, header, query string) map[string]any {
+		t.Helper()
+		mu.Lock()
+		dryRunHeaders = nil
+		mu.Unlock()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{
+				{Name: "orders", Host: orders.URL, DryRun: true},
+				{Name: "shipping", Host: shipping.URL, DryRun: shippingDryRun},
+			},
+			DryRun: gateway.DryRunOption{Enable: true},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		body, _ := json.Marshal(map[string]any{"query": query})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		if header != "" {
+			req.Header.Set("X-Dry-Run", header)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+	const mutation = `mutation { placeOrder(productId: "p1") { status eta } }`
+
+	t.Run("runs dry when every subgraph supports it", func(t *testing.T) {
+		resp := serve(t, true, "true", mutation)
+		if _, ok := resp["errors"]; ok {
+			t.Fatalf("expected no errors, got %v", resp)
+		}
+		dryRun, _ := resp["extensions"].(map[string]any)["dryRun"].(map[string]any)
+		if dryRun["executed"] != true || len(dryRun["subgraphs"].([]any)) != 2 {
+			t.Errorf("expected a dry run on both subgraphs, got %v", resp["extensions"])
+		}
+		if strings.Join(dryRunHeaders, ",") != "true,true" {
+			t.Errorf("expected both subgraph requests to carry the dry-run header, got %q", dryRunHeaders)
+		}
+	})
+
+	t.Run("refuses when a subgraph lacks support", func(t *testing.T) {
+		resp := serve(t, false, "1", mutation)
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 1 || errs[0].(map[string]any)["extensions"].(map[string]any)["code"] != "DRY_RUN_UNSUPPORTED" {
+			t.Fatalf("expected a DRY_RUN_UNSUPPORTED error, got %v", resp)
+		}
+		dryRun, _ := resp["extensions"].(map[string]any)["dryRun"].(map[string]any)
+		if dryRun["executed"] != false || dryRun["unsupportedSubgraphs"].([]any)[0] != "shipping" {
+			t.Errorf("expected shipping to be reported as unsupported, got %v", resp["extensions"])
+		}
+		if len(dryRunHeaders) != 0 {
+			t.Errorf("expected no subgraph request, got %d", len(dryRunHeaders))
+		}
+	})
+
+	t.Run("without the header mutations execute", func(t *testing.T) {
+		resp := serve(t, false, "", mutation)
+		if _, ok := resp["extensions"]; ok {
+			t.Errorf("expected no dry-run extension, got %v", resp)
+		}
+		if strings.Join(dryRunHeaders, ",") != "," {
+			t.Errorf("expected subgraph requests without the dry-run header, got %q", dryRunHeaders)
+		}
+	})
+
+	t.Run("queries are not affected", func(t *testing.T) {
+		resp := serve(t, false, "true", `{ order(id: "o1") { status } }`)
+		if _, ok := resp["extensions"]; ok {
+			t.Errorf("expected no dry-run extension, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+// EmbeddedSubgraph is a subgraph served in process instead of by a remote URL. Requests
+// to it are dispatched to Handler or Resolver without a network round trip.
+type EmbeddedSubgraph struct {
+	// SDL of the subgraph. When empty, it is fetched with a _service query like the SDL
+	// of a remote subgraph.
+	SDL string
+
+	// Handler serves GraphQL requests over HTTP semantics, e.g. a gqlgen server.
+	Handler http.Handler
+
+	// Resolver resolves GraphQL requests directly; used when Handler is nil.
+	Resolver SubgraphResolver
+}
+
+// SubgraphResolver resolves GraphQL requests of an embedded subgraph.
+type SubgraphResolver interface {
+	// Resolve returns the GraphQL response, with data and errors entries. An error is
+	// reported to the gateway as a GraphQL error of the subgraph.
+	Resolve(ctx context.Context, req *SubgraphRequest) (map[string]any, error)
+}
+
+// SubgraphResolverFunc adapts a function to a SubgraphResolver.
+type SubgraphResolverFunc func(ctx context.Context, req *SubgraphRequest) (map[string]any, error)
+
+// Resolve calls f(ctx, req).
+func (f SubgraphResolverFunc) Resolve(ctx context.Context, req *SubgraphRequest) (map[string]any, error) {
+	return f(ctx, req)
+}
+
+// SubgraphRequest is a GraphQL request sent to an embedded subgraph.
+type SubgraphRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+
+	// Header holds the headers the gateway sends to subgraphs, e.g. forwarded client
+	// headers and trace context.
+	Header http.Header `json:"-"`
+}
+
+// ResolverHandler serves GraphQL requests over HTTP with r.
+func ResolverHandler(r SubgraphResolver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body SubgraphRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid GraphQL request", http.StatusBadRequest)
+			return
+		}
+		body.Header = req.Header
+
+		resp, err := r.Resolve(req.Context(), &body)
+		if err != nil {
+			resp = map[string]any{"data": nil, "errors": []map[string]any{{"message": err.Error()}}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	})
+}
+
+// embeddedHost is the placeholder host of an embedded subgraph without one.
+func embeddedHost(name string) string {
+	return "http://" + name + ".embedded/graphql"
+}
+
+// withEmbeddedSubgraphs returns settings with a service for every embedded subgraph,
+// including those serving rest services: listed services keep their settings and get a
+// placeholder host when they have none, the others are added in name order.
+func (o GatewayOption) withEmbeddedSubgraphs() (GatewayOption, error) {
+	o, err := o.withRESTSubgraphs()
+	if err != nil {
+		return o, err
+	}
+	if len(o.EmbeddedSubgraphs) == 0 {
+		return o, nil
+	}
+	for name, sub := range o.EmbeddedSubgraphs {
+		if sub.Handler == nil && sub.Resolver == nil {
+			return o, fmt.Errorf("embedded subgraph %q has neither a handler nor a resolver", name)
+		}
+	}
+
+	services := make([]GatewayService, 0, len(o.Services)+len(o.EmbeddedSubgraphs))
+	listed := make(map[string]bool, len(o.Services))
+	for _, svc := range o.Services {
+		if _, ok := o.EmbeddedSubgraphs[svc.Name]; ok && svc.Host == "" {
+			svc.Host = embeddedHost(svc.Name)
+		}
+		listed[svc.Name] = true
+		services = append(services, svc)
+	}
+	var added []string
+	for name := range o.EmbeddedSubgraphs {
+		if !listed[name] {
+			added = append(added, name)
+		}
+	}
+	slices.Sort(added)
+	for _, name := range added {
+		services = append(services, GatewayService{Name: name, Host: embeddedHost(name)})
+	}
+
+	o.Services = services
+	return o, nil
+}
+
+// schemaSource returns the schema source of svc: the SDL it was embedded with, or
+// newSchemaSource.
+func (o GatewayOption) schemaSource(svc GatewayService, httpClient *http.Client) (registry.SchemaSource, error) {
+	if embedded, ok := o.EmbeddedSubgraphs[svc.Name]; ok && embedded.SDL != "" {
+		return &embeddedSource{name: svc.Name, sdl: embedded.SDL}, nil
+	}
+	return newSchemaSource(svc, httpClient)
+}
+
+// embeddedSource serves the SDL an embedded subgraph was registered with.
+type embeddedSource struct {
+	name string
+	sdl  string
+}
+
+// Name implements registry.SchemaSource.
+func (s *embeddedSource) Name() string {
+	return "embedded:" + s.name
+}
+
+// Fetch implements registry.SchemaSource.
+func (s *embeddedSource) Fetch(ctx context.Context) (string, error) {
+	return s.sdl, nil
+}
+
+// embeddedTransport dispatches requests to an in-process handler.
+type embeddedTransport struct {
+	handler http.Handler
+}
+
+func (t *embeddedTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	// The handler must not modify the request given to a RoundTripper.
+	inReq := req.Clone(req.Context())
+	inReq.RequestURI = req.URL.RequestURI()
+	inReq.RemoteAddr = "embedded"
+	if inReq.Body == nil {
+		inReq.Body = http.NoBody
+	}
+	defer inReq.Body.Close()
+
+	defer func() {
+		if r := recover(); r != nil {
+			resp, err = nil, fmt.Errorf("embedded subgraph panicked: %v", r)
+		}
+	}()
+
+	w := &embeddedResponseWriter{header: make(http.Header)}
+	t.handler.ServeHTTP(w, inReq)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(w.status) + " " + http.StatusText(w.status),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          io.NopCloser(bytes.NewReader(w.body.Bytes())),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// embeddedResponseWriter buffers the response of an embedded subgraph.
+type embeddedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *embeddedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *embeddedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *embeddedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
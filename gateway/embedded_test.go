@@ -0,0 +1,140 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_EmbeddedSubgraphs(t *testing.T) {
+	const productsSDL = `
+		type Query {
+			product(id: ID!): Product
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String
+		}`
+	const reviewsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			reviews: [String]
+		}`
+
+	var productRequests atomic.Int32
+	products := gateway.SubgraphResolverFunc(func(ctx context.Context, req *gateway.SubgraphRequest) (map[string]any, error) {
+		productRequests.Add(1)
+		if req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected the gateway headers, got %v", req.Header)
+		}
+		if reps, ok := req.Variables["representations"].([]any); ok {
+			entities := make([]any, 0, len(reps))
+			for _, rep := range reps {
+				entities = append(entities, map[string]any{"__typename": "Product", "id": rep.(map[string]any)["id"], "name": "Table"})
+			}
+			return map[string]any{"data": map[string]any{"_entities": entities}}, nil
+		}
+		return map[string]any{"data": map[string]any{"product": map[string]any{"__typename": "Product", "id": "1", "name": "Table"}}}, nil
+	})
+
+	reviews := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(body.Query, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": reviewsSDL}}}) //nolint:errcheck
+			return
+		}
+		reps, _ := body.Variables["representations"].([]any)
+		entities := make([]any, 0, len(reps))
+		for _, rep := range reps {
+			id := rep.(map[string]any)["id"]
+			entities = append(entities, map[string]any{"__typename": "Product", "id": id, "reviews": []string{"Sturdy"}})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_entities": entities}}) //nolint:errcheck
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		// A listed embedded service keeps its settings and needs no host.
+		Services: []gateway.GatewayService{{Name: "reviews", Timeout: "1s"}},
+		EmbeddedSubgraphs: map[string]gateway.EmbeddedSubgraph{
+			"products": {SDL: productsSDL, Resolver: products},
+			"reviews":  {Handler: reviews},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { name reviews } }"}`)))
+
+	var resp struct {
+		Data struct {
+			Product struct {
+				Name    string   `json:"name"`
+				Reviews []string `json:"reviews"`
+			} `json:"product"`
+		} `json:"data"`
+		Errors []any `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %s", rec.Body.String())
+	}
+	if resp.Data.Product.Name != "Table" || len(resp.Data.Product.Reviews) != 1 || resp.Data.Product.Reviews[0] != "Sturdy" {
+		t.Errorf("unexpected response: %s", rec.Body.String())
+	}
+	if productRequests.Load() == 0 {
+		t.Error("expected the products resolver to be called")
+	}
+}
+
+func TestGateway_EmbeddedSubgraphErrors(t *testing.T) {
+	t.Run("resolver error", func(t *testing.T) {
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			EmbeddedSubgraphs: map[string]gateway.EmbeddedSubgraph{
+				"products": {
+					SDL: `type Query { hello: String }`,
+					Resolver: gateway.SubgraphResolverFunc(func(ctx context.Context, req *gateway.SubgraphRequest) (map[string]any, error) {
+						return nil, context.DeadlineExceeded
+					}),
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ hello }"}`)))
+		if !strings.Contains(rec.Body.String(), context.DeadlineExceeded.Error()) {
+			t.Errorf("expected the resolver error in the response, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("missing handler", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:          "/graphql",
+			EmbeddedSubgraphs: map[string]gateway.EmbeddedSubgraph{"products": {SDL: `type Query { hello: String }`}},
+		})
+		if err == nil {
+			t.Error("expected an error for an embedded subgraph without handler or resolver")
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// ServiceEndpointsOption overrides the URL operations of each type are sent to, e.g. to
+// send queries to read replicas and mutations to the primary. Unset URLs fall back to
+// GatewayService.Host, which is still used to fetch the SDL.
+type ServiceEndpointsOption struct {
+	Query        string `yaml:"query"`
+	Mutation     string `yaml:"mutation"`
+	Subscription string `yaml:"subscription"` // ws://, wss://, http:// or https:// URL
+}
+
+// newEndpoints returns the endpoints of every service overriding its host.
+func newEndpoints(services []GatewayService) (map[string]executor.Endpoints, error) {
+	endpoints := make(map[string]executor.Endpoints)
+	for _, svc := range services {
+		opt := svc.Endpoints
+		if opt == (ServiceEndpointsOption{}) {
+			continue
+		}
+		for _, u := range []struct {
+			operation, url string
+			schemes        []string
+		}{
+			{"query", opt.Query, []string{"http", "https"}},
+			{"mutation", opt.Mutation, []string{"http", "https"}},
+			{"subscription", opt.Subscription, []string{"ws", "wss", "http", "https"}},
+		} {
+			if u.url == "" {
+				continue
+			}
+			if err := validateEndpoint(u.url, u.schemes); err != nil {
+				return nil, fmt.Errorf("invalid %s endpoint for service %q: %w", u.operation, svc.Name, err)
+			}
+		}
+		endpoints[svc.Name] = opt.executorEndpoints()
+	}
+	return endpoints, nil
+}
+
+func (o ServiceEndpointsOption) executorEndpoints() executor.Endpoints {
+	return executor.Endpoints{Query: o.Query, Mutation: o.Mutation, Subscription: o.Subscription}
+}
+
+// validateEndpoint checks that rawURL is an absolute URL with one of schemes.
+func validateEndpoint(rawURL string, schemes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", rawURL)
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported scheme %q", u.Scheme)
+}
@@ -0,0 +1,120 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ServiceEndpoints(t *testing.T) {
+	const sdl = `
+		type Query {
+			hello: String
+		}
+
+		type Mutation {
+			setHello(value: String!): String
+		}
+
+		type Subscription {
+			helloChanged: String
+		}`
+
+	// Every path of the subgraph answers with the field selected and records the path.
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		query, _ := body["query"].(string)
+		if strings.Contains(query, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+			return
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		data := map[string]any{}
+		for _, field := range []string{"hello", "setHello", "helloChanged"} {
+			if strings.Contains(query, field) {
+				data[field] = r.URL.Path
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data}) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+
+	newGateway := func(t *testing.T, endpoints gateway.ServiceEndpointsOption) (http.Handler, error) {
+		t.Helper()
+		return gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{
+				{Name: "hello", Host: srv.URL + "/graphql", Endpoints: endpoints},
+			},
+		})
+	}
+	send := func(t *testing.T, gw http.Handler, query string) string {
+		t.Helper()
+		mu.Lock()
+		paths = nil
+		mu.Unlock()
+		body, _ := json.Marshal(map[string]any{"query": query})
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		mu.Lock()
+		defer mu.Unlock()
+		if len(paths) != 1 {
+			t.Fatalf("expected one subgraph request, got %v: %s", paths, rec.Body.String())
+		}
+		return paths[0]
+	}
+
+	t.Run("operations are sent to the endpoint of their type", func(t *testing.T) {
+		gw, err := newGateway(t, gateway.ServiceEndpointsOption{
+			Query:        srv.URL + "/read",
+			Mutation:     srv.URL + "/write",
+			Subscription: "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws",
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		for query, want := range map[string]string{
+			`{ hello }`:                          "/read",
+			`mutation { setHello(value: "hi") }`: "/write",
+			`subscription { helloChanged }`:      "/ws",
+		} {
+			if got := send(t, gw, query); got != want {
+				t.Errorf("%s was sent to %s, want %s", query, got, want)
+			}
+		}
+	})
+
+	t.Run("unset endpoints fall back to the host", func(t *testing.T) {
+		gw, err := newGateway(t, gateway.ServiceEndpointsOption{Mutation: srv.URL + "/write"})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		if got := send(t, gw, `{ hello }`); got != "/graphql" {
+			t.Errorf("query was sent to %s, want /graphql", got)
+		}
+	})
+
+	t.Run("invalid endpoints are rejected", func(t *testing.T) {
+		for _, endpoints := range []gateway.ServiceEndpointsOption{
+			{Query: "/read"},
+			{Mutation: "ws://localhost/write"},
+			{Subscription: "ftp://localhost/ws"},
+		} {
+			if _, err := newGateway(t, endpoints); err == nil {
+				t.Errorf("expected %+v to be rejected", endpoints)
+			}
+		}
+	})
+}
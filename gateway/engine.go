@@ -1,12 +1,18 @@
 package gateway
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
 	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
 	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
 )
 
 // executionEngine bundles all read-only components required to serve GraphQL requests.
@@ -14,6 +20,10 @@ type executionEngine struct {
 	planner    *planner.PlannerV2
 	executor   *executor.ExecutorV2
 	superGraph *graph.SuperGraphV2
+	schemaHash string // registry.SchemaHash of the SDLs the engine was built from
+
+	// plans holds the plans of the persisted operations, keyed by warmedPlanKey.
+	plans map[string]*planner.PlanV2
 }
 
 // schemaStore holds the current set of raw SDLs, host URLs, and the pre-built engine.
@@ -29,25 +39,138 @@ type schemaStore struct {
 // The order that subgraphs are processed follows the iteration order of sdls, which is
 // non-deterministic in Go maps; SuperGraphV2 is expected to be order-independent.
 func buildEngine(sdls, hosts map[string]string, httpClient *http.Client) (*executionEngine, error) {
-	subGraphs := make([]*graph.SubGraphV2, 0, len(sdls))
+	return buildCachedEngine(sdls, hosts, httpClient, "")
+}
+
+// buildCachedEngine is buildEngine reusing the composition stored in cacheFile, if set,
+// when it was built from the same SDLs. Otherwise the supergraph is composed and the
+// cache rewritten; failing to read or write the cache only costs the time it saves.
+func buildCachedEngine(sdls, hosts map[string]string, httpClient *http.Client, cacheFile string) (*executionEngine, error) {
+	sources := make([]graph.SubGraphSource, 0, len(sdls))
 	for name, sdl := range sdls {
-		sg, err := graph.NewSubGraphV2(name, []byte(sdl), hosts[name])
+		sources = append(sources, graph.SubGraphSource{Name: name, SDL: []byte(sdl), Host: hosts[name]})
+	}
+	subGraphs, err := graph.NewSubGraphsV2(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaHash := registry.SchemaHash(sdls)
+	superGraph := loadComposition(cacheFile, subGraphs, schemaHash)
+	if superGraph == nil {
+		superGraph, err = graph.NewSuperGraphV2(subGraphs)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build subgraph %q: %w", name, err)
+			return nil, fmt.Errorf("composition failed: %w", err)
+		}
+		writeComposition(cacheFile, superGraph, schemaHash)
+	}
+
+	return newExecutionEngine(superGraph, httpClient, schemaHash), nil
+}
+
+// rebuildEngine builds the engine of sdls replacing the SDLs of current. When a single
+// subgraph was updated or added, only the types it declares are recomposed, with
+// graph.SuperGraphV2.WithSubGraph; other changes compose the supergraph from scratch
+// like buildCachedEngine.
+func rebuildEngine(current *schemaStore, sdls map[string]string, httpClient *http.Client, cacheFile string) (*executionEngine, error) {
+	var changed []string
+	for name, sdl := range sdls {
+		if previous, ok := current.sdls[name]; !ok || previous != sdl {
+			changed = append(changed, name)
+		}
+	}
+	removed := false
+	for name := range current.sdls {
+		if _, ok := sdls[name]; !ok {
+			removed = true
 		}
-		subGraphs = append(subGraphs, sg)
+	}
+	if len(changed) != 1 || removed || current.engine == nil {
+		return buildCachedEngine(sdls, current.hosts, httpClient, cacheFile)
 	}
 
-	superGraph, err := graph.NewSuperGraphV2(subGraphs)
+	name := changed[0]
+	subGraph, err := graph.NewSubGraphV2(name, []byte(sdls[name]), current.hosts[name])
+	if err != nil {
+		return nil, fmt.Errorf("failed to build subgraph %q: %w", name, err)
+	}
+	superGraph, err := current.engine.superGraph.WithSubGraph(subGraph)
 	if err != nil {
 		return nil, fmt.Errorf("composition failed: %w", err)
 	}
 
+	schemaHash := registry.SchemaHash(sdls)
+	writeComposition(cacheFile, superGraph, schemaHash)
+	return newExecutionEngine(superGraph, httpClient, schemaHash), nil
+}
+
+// newExecutionEngine wraps superGraph in an executionEngine with a PlannerV2 and
+// ExecutorV2.
+func newExecutionEngine(superGraph *graph.SuperGraphV2, httpClient *http.Client, schemaHash string) *executionEngine {
 	return &executionEngine{
 		planner:    planner.NewPlannerV2(superGraph),
 		executor:   executor.NewExecutorV2(httpClient, superGraph),
 		superGraph: superGraph,
-	}, nil
+		schemaHash: schemaHash,
+	}
+}
+
+// loadComposition returns the supergraph of subGraphs with the composition stored in
+// cacheFile, or nil when there is no usable one.
+func loadComposition(cacheFile string, subGraphs []*graph.SubGraphV2, schemaHash string) *graph.SuperGraphV2 {
+	if cacheFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(cacheFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err == nil {
+		var superGraph *graph.SuperGraphV2
+		superGraph, err = graph.NewSuperGraphV2FromComposition(subGraphs, data, schemaHash)
+		if err == nil {
+			return superGraph
+		}
+	}
+	// A cache of other SDLs is expected after a schema change; anything else is worth a log.
+	if !errors.Is(err, graph.ErrCompositionMismatch) {
+		log.Printf("ignoring composition cache %s: %v", cacheFile, err)
+	}
+	return nil
+}
+
+// writeComposition stores the composition of superGraph in cacheFile, if set. Like the
+// schema cache, failures are logged, not returned.
+func writeComposition(cacheFile string, superGraph *graph.SuperGraphV2, schemaHash string) {
+	if cacheFile == "" {
+		return
+	}
+	data, err := superGraph.MarshalComposition(schemaHash)
+	if err != nil {
+		log.Printf("failed to encode composition cache %s: %v", cacheFile, err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0o755); err != nil {
+		log.Printf("failed to create composition cache directory for %s: %v", cacheFile, err)
+		return
+	}
+	// Write through a temporary file so replicas sharing the cache never read a partial one.
+	tmp, err := os.CreateTemp(filepath.Dir(cacheFile), filepath.Base(cacheFile)+".*.tmp")
+	if err != nil {
+		log.Printf("failed to write composition cache %s: %v", cacheFile, err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cacheFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name()) //nolint:errcheck
+		log.Printf("failed to write composition cache %s: %v", cacheFile, err)
+	}
 }
 
 // copyMap returns a shallow copy of a string map.
@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// resolveFederationFields serves the federation fields (_service and _entities) on
+// the gateway itself, so that the gateway can be composed as a subgraph of a parent
+// gateway. It reports whether the operation consisted of federation fields only;
+// operations mixing them with regular fields are left to the normal pipeline.
+func (g *gateway) resolveFederationFields(ctx context.Context, doc *ast.Document, variables map[string]any, engine *executionEngine) (map[string]any, bool, error) {
+	op := firstOperation(doc)
+	if op == nil || op.Operation != ast.Query || !isFederationSelection(op.SelectionSet) {
+		return nil, false, nil
+	}
+
+	data := make(map[string]any)
+	var errs []any
+	for _, sel := range op.SelectionSet {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+		responseKey := field.Name.String()
+		if field.Alias != nil && field.Alias.String() != "" {
+			responseKey = field.Alias.String()
+		}
+
+		switch field.Name.String() {
+		case "__typename":
+			data[responseKey] = engine.superGraph.RootTypeName(ast.Query)
+		case "_service":
+			data[responseKey] = map[string]any{"sdl": engine.superGraph.SDL()}
+		case "_entities":
+			entities, entityErrs, err := g.resolveEntities(ctx, doc, field, variables, engine)
+			if err != nil {
+				return nil, true, err
+			}
+			data[responseKey] = entities
+			errs = append(errs, entityErrs...)
+		}
+	}
+
+	resp := map[string]any{"data": data}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	return resp, true, nil
+}
+
+// resolveEntities plans and executes an _entities field received from a parent gateway.
+// Representations are grouped by __typename, resolved with one plan per type, and
+// returned in the order they were received.
+func (g *gateway) resolveEntities(ctx context.Context, doc *ast.Document, field *ast.Field, variables map[string]any, engine *executionEngine) ([]any, []any, error) {
+	representations, err := representationsArgument(field, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Group representations by type, remembering their original positions.
+	typeOrder := make([]string, 0)
+	repsByType := make(map[string][]map[string]any)
+	positionsByType := make(map[string][]int)
+	for i, rep := range representations {
+		typeName, _ := rep["__typename"].(string)
+		if typeName == "" {
+			return nil, nil, fmt.Errorf("representation at index %d has no __typename", i)
+		}
+		if _, ok := repsByType[typeName]; !ok {
+			typeOrder = append(typeOrder, typeName)
+		}
+		repsByType[typeName] = append(repsByType[typeName], rep)
+		positionsByType[typeName] = append(positionsByType[typeName], i)
+	}
+
+	fragmentDefs := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			fragmentDefs[fragDef.Name.String()] = fragDef
+		}
+	}
+
+	responseKey := field.Name.String()
+	if field.Alias != nil && field.Alias.String() != "" {
+		responseKey = field.Alias.String()
+	}
+
+	entities := make([]any, len(representations))
+	var errs []any
+	for _, typeName := range typeOrder {
+		selections := selectionsForType(field.SelectionSet, typeName, fragmentDefs)
+
+		plan, err := engine.planner.PlanEntities(doc, typeName, selections, repsByType[typeName])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, err := engine.executor.Execute(ctx, plan, variables)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var resolved []any
+		if data, ok := resp["data"].(map[string]any); ok {
+			resolved, _ = data[responseKey].([]any)
+		}
+		for i, pos := range positionsByType[typeName] {
+			if i < len(resolved) {
+				entities[pos] = resolved[i]
+			}
+		}
+
+		if respErrs, ok := resp["errors"].([]executor.GraphQLError); ok {
+			for _, respErr := range respErrs {
+				errs = append(errs, respErr)
+			}
+		}
+	}
+
+	return entities, errs, nil
+}
+
+// representationsArgument reads the representations argument of an _entities field.
+// Only variables are supported, which is what federation gateways send.
+func representationsArgument(field *ast.Field, variables map[string]any) ([]map[string]any, error) {
+	for _, arg := range field.Arguments {
+		if arg.Name.String() != "representations" {
+			continue
+		}
+		variable, ok := arg.Value.(*ast.Variable)
+		if !ok {
+			return nil, fmt.Errorf("_entities representations must be passed as a variable")
+		}
+		raw, ok := variables[variable.Name].([]any)
+		if !ok {
+			return nil, fmt.Errorf("variable $%s must be a list of representations", variable.Name)
+		}
+		reps := make([]map[string]any, 0, len(raw))
+		for i, item := range raw {
+			rep, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("representation at index %d is not an object", i)
+			}
+			reps = append(reps, rep)
+		}
+		return reps, nil
+	}
+	return nil, fmt.Errorf("_entities requires a representations argument")
+}
+
+// selectionsForType collects the selections of an _entities field that apply to
+// typeName: fields selected directly plus those inside fragments on typeName.
+func selectionsForType(selections []ast.Selection, typeName string, fragmentDefs map[string]*ast.FragmentDefinition) []ast.Selection {
+	result := make([]ast.Selection, 0)
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			result = append(result, s)
+		case *ast.InlineFragment:
+			if s.TypeCondition == nil || s.TypeCondition.Name.String() == typeName {
+				result = append(result, selectionsForType(s.SelectionSet, typeName, fragmentDefs)...)
+			}
+		case *ast.FragmentSpread:
+			if fragDef, ok := fragmentDefs[s.Name.String()]; ok && fragDef.TypeCondition.Name.String() == typeName {
+				result = append(result, selectionsForType(fragDef.SelectionSet, typeName, fragmentDefs)...)
+			}
+		}
+	}
+	return result
+}
+
+// isFederationSelection reports whether a root selection set consists solely of
+// the federation fields _service and _entities (plus __typename).
+func isFederationSelection(selections []ast.Selection) bool {
+	found := false
+	for _, sel := range selections {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			return false
+		}
+		switch field.Name.String() {
+		case "_service", "_entities":
+			found = true
+		case "__typename":
+		default:
+			return false
+		}
+	}
+	return found
+}
+
+// firstOperation returns the first operation definition of a document.
+func firstOperation(doc *ast.Document) *ast.OperationDefinition {
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			return op
+		}
+	}
+	return nil
+}
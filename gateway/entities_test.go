@@ -0,0 +1,150 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// newSubgraphServer starts a fake subgraph that serves sdl for _service queries
+// and answers every other query with resp.
+func newSubgraphServer(t *testing.T, sdl string, resp func(body map[string]any) any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+			return
+		}
+		json.NewEncoder(w).Encode(resp(body)) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGateway_SubgraphMode(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		entities := make([]any, 0, len(reps))
+		for _, rep := range reps {
+			id := rep.(map[string]any)["id"]
+			entities = append(entities, map[string]any{"__typename": "Product", "id": id, "name": "product-" + id.(string)})
+		}
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:           "/graphql",
+		EnableSubgraphMode: true,
+		Services:           []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	t.Run("_service", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ _service { sdl } }"}`)))
+
+		var resp struct {
+			Data struct {
+				Service struct {
+					SDL string `json:"sdl"`
+				} `json:"_service"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !strings.Contains(resp.Data.Service.SDL, `type Product @key(fields: "id")`) {
+			t.Errorf("expected composed SDL with @key, got %q", resp.Data.Service.SDL)
+		}
+	})
+
+	t.Run("_entities", func(t *testing.T) {
+		body := `{
+			"query": "query ($representations: [_Any!]!) { _entities(representations: $representations) { ... on Product { name } } }",
+			"variables": {"representations": [{"__typename": "Product", "id": "1"}, {"__typename": "Product", "id": "2"}]}
+		}`
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		data, _ := resp["data"].(map[string]any)
+		entities, _ := data["_entities"].([]any)
+		if len(entities) != 2 {
+			t.Fatalf("expected 2 entities, got %s", rec.Body.String())
+		}
+		for i, want := range []string{"product-1", "product-2"} {
+			entity, _ := entities[i].(map[string]any)
+			if entity["name"] != want {
+				t.Errorf("entity %d: got name %v, want %s", i, entity["name"], want)
+			}
+		}
+	})
+}
+
+func TestGateway_SubgraphMode_Inaccessible(t *testing.T) {
+	sdl := `
+extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key", "@inaccessible"])
+
+type Query {
+	product(id: ID!): Product
+}
+
+type Product @key(fields: "id") {
+	id: ID!
+	name: String
+	cost: Int @inaccessible
+}`
+	called := false
+	products := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		called = true
+		return map[string]any{"data": map[string]any{"_entities": []any{map[string]any{"__typename": "Product", "cost": 42}}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:           "/graphql",
+		EnableSubgraphMode: true,
+		Services:           []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	body := `{
+		"query": "query ($representations: [_Any!]!) { _entities(representations: $representations) { ... on Product { cost } } }",
+		"variables": {"representations": [{"__typename": "Product", "id": "1"}]}
+	}`
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+
+	var resp struct {
+		Data   map[string]any `json:"data"`
+		Errors []struct {
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions.Code != gateway.CodeInaccessibleField {
+		t.Fatalf("expected an %s error, got %s", gateway.CodeInaccessibleField, rec.Body.String())
+	}
+	if called {
+		t.Error("expected the subgraph not to be called")
+	}
+}
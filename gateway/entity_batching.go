@@ -0,0 +1,30 @@
+package gateway
+
+import "github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+
+// EntityBatchingOption splits the representations of an entity fetch into several
+// _entities requests, for subgraphs that limit the request body size. Chunks of one
+// fetch are requested concurrently and their results reassembled in order.
+type EntityBatchingOption struct {
+	MaxRepresentations int `yaml:"max_representations" default:"0"` // Representations per _entities request; 0 sends them all at once
+	MaxConcurrency     int `yaml:"max_concurrency" default:"4"`     // Chunks of one fetch requested at once
+}
+
+// newEntityBatching builds the executor entity batching from the gateway and per
+// service limits. It returns nil when no limit is configured.
+func newEntityBatching(settings GatewayOption) *executor.EntityBatching {
+	perSubGraph := make(map[string]int)
+	for _, svc := range settings.Services {
+		if svc.MaxRepresentations > 0 {
+			perSubGraph[svc.Name] = svc.MaxRepresentations
+		}
+	}
+	if settings.EntityBatching.MaxRepresentations <= 0 && len(perSubGraph) == 0 {
+		return nil
+	}
+	return &executor.EntityBatching{
+		MaxRepresentations:         settings.EntityBatching.MaxRepresentations,
+		SubGraphMaxRepresentations: perSubGraph,
+		MaxConcurrency:             settings.EntityBatching.MaxConcurrency,
+	}
+}
@@ -0,0 +1,241 @@
+package gateway_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_EntityBatching(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean!
+		}
+	`
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		list := make([]any, 0, 5)
+		for i := 1; i <= 5; i++ {
+			list = append(list, map[string]any{"__typename": "Product", "id": fmt.Sprint(i)})
+		}
+		return map[string]any{"data": map[string]any{"topProducts": list}}
+	})
+
+	var mu sync.Mutex
+	var chunkSizes []int
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		mu.Lock()
+		chunkSizes = append(chunkSizes, len(reps))
+		mu.Unlock()
+
+		entities := make([]any, 0, len(reps))
+		var errs []any
+		for i, rep := range reps {
+			id := rep.(map[string]any)["id"].(string)
+			if id == "4" {
+				entities = append(entities, nil)
+				errs = append(errs, map[string]any{"message": "stock unavailable", "path": []any{"_entities", i, "inStock"}})
+				continue
+			}
+			entities = append(entities, map[string]any{"__typename": "Product", "id": id, "inStock": id != "2"})
+		}
+		resp := map[string]any{"data": map[string]any{"_entities": entities}}
+		if len(errs) > 0 {
+			resp["errors"] = errs
+		}
+		return resp
+	})
+
+	tests := []struct {
+		name           string
+		batching       gateway.EntityBatchingOption
+		maxPerService  int
+		wantChunkSizes map[int]int
+	}{
+		{
+			name:           "without a limit all representations are sent at once",
+			wantChunkSizes: map[int]int{5: 1},
+		},
+		{
+			name:           "representations are split into chunks",
+			batching:       gateway.EntityBatchingOption{MaxRepresentations: 2, MaxConcurrency: 2},
+			wantChunkSizes: map[int]int{2: 2, 1: 1},
+		},
+		{
+			name:           "the service limit overrides the gateway limit",
+			batching:       gateway.EntityBatchingOption{MaxRepresentations: 2},
+			maxPerService:  3,
+			wantChunkSizes: map[int]int{3: 1, 2: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			chunkSizes = nil
+			mu.Unlock()
+
+			gw, err := gateway.NewGateway(gateway.GatewayOption{
+				Endpoint: "/graphql",
+				Services: []gateway.GatewayService{
+					{Name: "products", Host: products.URL},
+					{Name: "inventory", Host: inventory.URL, MaxRepresentations: tt.maxPerService},
+				},
+				EntityBatching: tt.batching,
+			})
+			if err != nil {
+				t.Fatalf("NewGateway failed: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ topProducts { id inStock } }"}`))
+			gw.ServeHTTP(rec, req)
+
+			var resp struct {
+				Data struct {
+					TopProducts []struct {
+						ID      string `json:"id"`
+						InStock *bool  `json:"inStock"`
+					} `json:"topProducts"`
+				} `json:"data"`
+				Errors []struct {
+					Message string `json:"message"`
+					Path    []any  `json:"path"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+			}
+
+			if len(resp.Data.TopProducts) != 5 {
+				t.Fatalf("expected 5 products, got %s", rec.Body.String())
+			}
+			for i, p := range resp.Data.TopProducts {
+				if p.ID != fmt.Sprint(i+1) {
+					t.Errorf("expected product %d at index %d, got %s", i+1, i, p.ID)
+				}
+				switch p.ID {
+				case "4":
+					if p.InStock != nil {
+						t.Errorf("expected null inStock for product 4, got %v", *p.InStock)
+					}
+				default:
+					if p.InStock == nil || *p.InStock != (p.ID != "2") {
+						t.Errorf("unexpected inStock for product %s: %v", p.ID, p.InStock)
+					}
+				}
+			}
+
+			// The error path indexes the representations of the whole fetch, as without chunking.
+			if len(resp.Errors) != 1 || fmt.Sprint(resp.Errors[0].Path) != fmt.Sprint([]any{"topProducts", "_entities", 3, "inStock"}) {
+				t.Errorf("expected the error of product 4 at its position, got %s", rec.Body.String())
+			}
+
+			mu.Lock()
+			got := make(map[int]int)
+			for _, size := range chunkSizes {
+				got[size]++
+			}
+			mu.Unlock()
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantChunkSizes) {
+				t.Errorf("expected chunk sizes %v, got %v", tt.wantChunkSizes, got)
+			}
+		})
+	}
+}
+
+func TestGateway_EntityBatching_NonIntegerErrorPath(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean!
+		}
+	`
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		list := []any{
+			map[string]any{"__typename": "Product", "id": "1"},
+			map[string]any{"__typename": "Product", "id": "2"},
+			map[string]any{"__typename": "Product", "id": "3"},
+		}
+		return map[string]any{"data": map[string]any{"topProducts": list}}
+	})
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		entities := make([]any, 0, len(reps))
+		var errs []any
+		for _, rep := range reps {
+			id := rep.(map[string]any)["id"].(string)
+			entities = append(entities, map[string]any{"__typename": "Product", "id": id, "inStock": true})
+			if id == "3" {
+				// A subgraph reporting a malformed index in the second chunk
+				errs = append(errs, map[string]any{"message": "stock unavailable", "path": []any{"_entities", 0.5, "inStock"}})
+			}
+		}
+		resp := map[string]any{"data": map[string]any{"_entities": entities}}
+		if len(errs) > 0 {
+			resp["errors"] = errs
+		}
+		return resp
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL},
+		},
+		EntityBatching: gateway.EntityBatchingOption{MaxRepresentations: 2},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ topProducts { id inStock } }"}`)))
+
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Path    []any  `json:"path"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+	}
+
+	// The error is reported unchanged rather than replaced or dropped.
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != "stock unavailable" ||
+		fmt.Sprint(resp.Errors[0].Path) != fmt.Sprint([]any{"topProducts", "_entities", 0.5, "inStock"}) {
+		t.Errorf("expected the subgraph error with its original path, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// entityCacheInvalidatePath is the admin endpoint purging cached entities.
+const entityCacheInvalidatePath = "/entity-cache/invalidate"
+
+// EntityCacheOption configures caching of subgraph _entities results.
+type EntityCacheOption struct {
+	Enable     bool   `yaml:"enable" default:"false"`
+	DefaultTTL string `yaml:"default_ttl" validate:"duration"` // TTL for entities without a @cacheControl hint; empty caches hinted entities only
+	MaxEntries int    `yaml:"max_entries" default:"10000"`     // Maximum number of cached entities
+}
+
+// newEntityCache builds the entity cache for opt, or nil when caching is disabled.
+func newEntityCache(opt EntityCacheOption) (*executor.EntityCache, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+
+	var defaultTTL time.Duration
+	if opt.DefaultTTL != "" {
+		d, err := time.ParseDuration(opt.DefaultTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entity_cache.default_ttl %q: %w", opt.DefaultTTL, err)
+		}
+		defaultTTL = d
+	}
+
+	maxEntries := opt.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return executor.NewEntityCache(defaultTTL, maxEntries), nil
+}
+
+// entityCacheInvalidation is the body of an invalidation request. Without a typename
+// the whole cache is purged; without keys every entity of the type is purged.
+type entityCacheInvalidation struct {
+	Typename string           `json:"typename"`
+	Keys     []map[string]any `json:"keys"`
+}
+
+// entityCacheInvalidateResponse reports the number of purged entities.
+type entityCacheInvalidateResponse struct {
+	OK     bool `json:"ok"`
+	Purged int  `json:"purged"`
+}
+
+// handleEntityCacheInvalidate purges cached entities by type and key fields.
+func (g *gateway) handleEntityCacheInvalidate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if g.entityCache == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"error": "entity cache is disabled"}) //nolint:errcheck
+		return
+	}
+
+	var req entityCacheInvalidation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"error": fmt.Sprintf("invalid invalidation request: %v", err)}) //nolint:errcheck
+		return
+	}
+
+	purged := 0
+	switch {
+	case req.Typename == "":
+		purged = g.entityCache.Purge()
+	case len(req.Keys) == 0:
+		purged = g.entityCache.Invalidate(req.Typename, nil)
+	default:
+		for _, key := range req.Keys {
+			purged += g.entityCache.Invalidate(req.Typename, key)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entityCacheInvalidateResponse{OK: true, Purged: purged}) //nolint:errcheck
+}
@@ -0,0 +1,223 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_EntityCache(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean! @cacheControl(maxAge: 60)
+		}
+	`
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"topProducts": []any{
+			map[string]any{"id": "1", "name": "a"},
+			map[string]any{"id": "2", "name": "b"},
+		}}}
+	})
+
+	var mu sync.Mutex
+	fetched := make([][]string, 0)
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		ids := make([]string, 0, len(reps))
+		entities := make([]any, 0, len(reps))
+		for _, rep := range reps {
+			id := rep.(map[string]any)["id"].(string)
+			ids = append(ids, id)
+			entities = append(entities, map[string]any{"__typename": "Product", "id": id, "inStock": id == "1"})
+		}
+		mu.Lock()
+		fetched = append(fetched, ids)
+		mu.Unlock()
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL},
+		},
+		EntityCache: gateway.EntityCacheOption{Enable: true},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	query := func(t *testing.T) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ topProducts { id inStock } }"}`)))
+		want := `{"data":{"topProducts":[{"id":"1","inStock":true},{"id":"2","inStock":false}]}}`
+		if got := strings.TrimSpace(rec.Body.String()); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+	lastFetch := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(fetched) == 0 {
+			return ""
+		}
+		return strings.Join(fetched[len(fetched)-1], ",")
+	}
+	fetchCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(fetched)
+	}
+
+	query(t)
+	if fetchCount() != 1 || lastFetch() != "1,2" {
+		t.Fatalf("expected one fetch of 1,2, got %d fetches, last %q", fetchCount(), lastFetch())
+	}
+
+	query(t)
+	if fetchCount() != 1 {
+		t.Fatalf("expected cached entities to be reused, got %d fetches", fetchCount())
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/entity-cache/invalidate", strings.NewReader(`{"typename":"Product","keys":[{"id":"1"}]}`)))
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode invalidation response: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp["purged"] != float64(1) {
+		t.Fatalf("expected one purged entity, got %d %v", rec.Code, resp)
+	}
+
+	query(t)
+	if fetchCount() != 2 || lastFetch() != "1" {
+		t.Fatalf("expected only the invalidated entity to be refetched, got %d fetches, last %q", fetchCount(), lastFetch())
+	}
+}
+
+func TestGateway_EntityCacheInvalidateDisabled(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/entity-cache/invalidate", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when the entity cache is disabled, got %d", rec.Code)
+	}
+}
+
+func TestGateway_EntityCacheVariesOnCredentials(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "a"}}}
+	})
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			price: Int!
+		}
+	`
+	var mu sync.Mutex
+	fetches := 0
+	inventory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": inventorySDL}}}) //nolint:errcheck
+			return
+		}
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+		// The price depends on the caller, e.g. a negotiated discount.
+		price := 100
+		if r.Header.Get("X-Customer") == "b" {
+			price = 80
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_entities": []any{ //nolint:errcheck
+			map[string]any{"__typename": "Product", "id": "1", "price": price},
+		}}})
+	}))
+	t.Cleanup(inventory.Close)
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL},
+		},
+		// Entities without a hint are cached for default_ttl.
+		EntityCache:                 gateway.EntityCacheOption{Enable: true, DefaultTTL: "1m"},
+		EnableHangOverRequestHeader: true,
+		ForwardExtensions:           gateway.ExtensionPropagationOption{Headers: map[string]string{"customer": "X-Customer"}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	query := func(t *testing.T, body, authorization, want string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		if got := strings.TrimSpace(rec.Body.String()); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+	fetchCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches
+	}
+
+	const (
+		asA = `{"query":"{ product(id: \"1\") { price } }","extensions":{"customer":"a"}}`
+		asB = `{"query":"{ product(id: \"1\") { price } }","extensions":{"customer":"b"}}`
+	)
+	query(t, asA, "", `{"data":{"product":{"price":100}}}`)
+	query(t, asB, "", `{"data":{"product":{"price":80}}}`)
+	if n := fetchCount(); n != 2 {
+		t.Fatalf("expected entities fetched with other forwarded headers not to be reused, got %d fetches", n)
+	}
+	query(t, asA, "", `{"data":{"product":{"price":100}}}`)
+	if n := fetchCount(); n != 2 {
+		t.Fatalf("expected entities fetched with the same headers to be reused, got %d fetches", n)
+	}
+
+	query(t, asA, "Bearer other", `{"data":{"product":{"price":100}}}`)
+	if n := fetchCount(); n != 3 {
+		t.Fatalf("expected entities fetched with other credentials not to be reused, got %d fetches", n)
+	}
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// ErrorMappingOption configures how the errors a subgraph returns in its GraphQL
+// responses reach clients. Each error is rewritten by the first rule matching it.
+type ErrorMappingOption struct {
+	Rules           []ErrorRuleOption `yaml:"rules"`
+	RedactUnmatched bool              `yaml:"redact_unmatched" default:"false"` // Hide the details of the errors no rule matches
+	Retries         int               `yaml:"retries" default:"0"`              // Times a query answered with retryable errors only is sent again
+}
+
+// ErrorRuleOption matches subgraph errors by code and message, and sets their gateway
+// code, message and classification. A rule without match_code and match_message
+// matches every error.
+type ErrorRuleOption struct {
+	MatchCode    string `yaml:"match_code"`    // extensions.code of the subgraph error
+	MatchMessage string `yaml:"match_message"` // Regular expression matched against the message
+	Code         string `yaml:"code"`          // Gateway error code; empty keeps the subgraph code
+	Message      string `yaml:"message"`       // Message sent to clients; empty keeps the subgraph message
+	Retryable    bool   `yaml:"retryable" default:"false"`
+	Redact       bool   `yaml:"redact" default:"false"` // Hide the message and extensions from clients; they are logged
+}
+
+// newErrorMappings builds the error mapping of every service that configures one.
+func newErrorMappings(services []GatewayService) (map[string]*executor.ErrorMapping, error) {
+	mappings := make(map[string]*executor.ErrorMapping)
+	for _, svc := range services {
+		opt := svc.Errors
+		if len(opt.Rules) == 0 && !opt.RedactUnmatched {
+			continue
+		}
+		if opt.Retries < 0 {
+			return nil, fmt.Errorf("invalid errors.retries %d for service %q", opt.Retries, svc.Name)
+		}
+
+		mapping := &executor.ErrorMapping{RedactUnmatched: opt.RedactUnmatched, Retries: opt.Retries}
+		for i, r := range opt.Rules {
+			rule := executor.ErrorRule{
+				MatchCode: r.MatchCode,
+				Code:      r.Code,
+				Message:   r.Message,
+				Retryable: r.Retryable,
+				Redact:    r.Redact,
+			}
+			if r.MatchMessage != "" {
+				re, err := regexp.Compile(r.MatchMessage)
+				if err != nil {
+					return nil, fmt.Errorf("invalid match_message of error rule %d for service %q: %w", i, svc.Name, err)
+				}
+				rule.MatchMessage = re
+			}
+			mapping.Rules = append(mapping.Rules, rule)
+		}
+		mappings[svc.Name] = mapping
+	}
+	return mappings, nil
+}
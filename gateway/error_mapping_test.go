@@ -0,0 +1,121 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ErrorMapping(t *testing.T) {
+	const sdl = `
+		type Query {
+			product(id: ID!): Product
+		}
+
+		type Mutation {
+			restock(id: ID!): Product
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String
+		}`
+
+	var calls atomic.Int32
+	products := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		calls.Add(1)
+		query, _ := body["query"].(string)
+		switch {
+		case strings.Contains(query, `"flaky"`) || strings.Contains(query, "restock"):
+			if calls.Load() == 1 {
+				return map[string]any{
+					"data":   nil,
+					"errors": []any{map[string]any{"message": "upstream timed out", "extensions": map[string]any{"code": "UPSTREAM_TIMEOUT"}}},
+				}
+			}
+			return map[string]any{"data": map[string]any{"product": map[string]any{"name": "Table"}, "restock": map[string]any{"name": "Table"}}}
+		default:
+			return map[string]any{
+				"data": map[string]any{"product": nil},
+				"errors": []any{
+					map[string]any{"message": "pq: connection refused at 10.0.0.3", "extensions": map[string]any{"code": "DB_ERROR", "stacktrace": []any{"main.go:12"}}},
+					map[string]any{"message": "product 7 does not exist", "extensions": map[string]any{"code": "ROW_NOT_FOUND"}},
+				},
+			}
+		}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{
+			Name: "products",
+			Host: products.URL,
+			Errors: gateway.ErrorMappingOption{
+				RedactUnmatched: true,
+				Retries:         1,
+				Rules: []gateway.ErrorRuleOption{
+					{MatchCode: "ROW_NOT_FOUND", Code: "NOT_FOUND"},
+					{MatchMessage: "timed out$", Code: "SERVICE_UNAVAILABLE", Retryable: true},
+				},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	serve := func(t *testing.T, query string) map[string]any {
+		t.Helper()
+		calls.Store(0)
+		body, _ := json.Marshal(map[string]any{"query": query})
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+
+	t.Run("maps codes and redacts unmatched errors", func(t *testing.T) {
+		resp := serve(t, `{ product(id: "7") { name } }`)
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 2 {
+			t.Fatalf("expected 2 errors, got %v", resp)
+		}
+		redacted := errs[0].(map[string]any)
+		if redacted["message"] != "Internal server error" {
+			t.Errorf("expected a redacted message, got %v", redacted["message"])
+		}
+		if ext := redacted["extensions"].(map[string]any); ext["code"] != "DB_ERROR" || ext["stacktrace"] != nil {
+			t.Errorf("expected only the code and service name to be kept, got %v", ext)
+		}
+		mapped := errs[1].(map[string]any)
+		if ext := mapped["extensions"].(map[string]any); mapped["message"] != "product 7 does not exist" || ext["code"] != "NOT_FOUND" || ext["retryable"] != false {
+			t.Errorf("expected a terminal NOT_FOUND error, got %v", mapped)
+		}
+	})
+
+	t.Run("retries queries with retryable errors", func(t *testing.T) {
+		resp := serve(t, `{ product(id: "flaky") { name } }`)
+		if _, ok := resp["errors"]; ok || calls.Load() != 2 {
+			t.Errorf("expected the query to succeed on its retry, got %v after %d calls", resp, calls.Load())
+		}
+	})
+
+	t.Run("never retries mutations", func(t *testing.T) {
+		resp := serve(t, `mutation { restock(id: "1") { name } }`)
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 1 || calls.Load() != 1 {
+			t.Fatalf("expected one failed attempt, got %v after %d calls", resp, calls.Load())
+		}
+		if ext := errs[0].(map[string]any)["extensions"].(map[string]any); ext["code"] != "SERVICE_UNAVAILABLE" || ext["retryable"] != true {
+			t.Errorf("expected a retryable SERVICE_UNAVAILABLE error, got %v", ext)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// Error codes set in extensions.code of the errors the gateway reports itself. Errors
+// returned by subgraphs keep their own codes; failed subgraph requests are coded
+// SUBGRAPH_HTTP_ERROR, GATEWAY_TIMEOUT or SUBGRAPH_OVERLOADED by the executor.
+const (
+	CodeParseFailed            = "GRAPHQL_PARSE_FAILED"           // The document is not valid GraphQL syntax
+	CodeValidationFailed       = "GRAPHQL_VALIDATION_FAILED"      // The document cannot be executed against the schema
+	CodePersistedQueryNotFound = "PERSISTED_QUERY_NOT_FOUND"      // A persisted query hash was sent without its query
+	CodeBadUserInput           = "BAD_USER_INPUT"                 // A variable or argument value is invalid
+	CodeInaccessibleField      = "INACCESSIBLE_FIELD"             // The document selects an @inaccessible field
+	CodePlanError              = "PLAN_ERROR"                     // No query plan could be built for the document
+	CodeBatchTooLarge          = "BATCH_TOO_LARGE"                // A batched request holds more operations than allowed
+	CodeTooManySubscriptions   = "TOO_MANY_SUBSCRIPTIONS"         // A WebSocket operation exceeds the subscription limits
+	CodeRateLimited            = "RATE_LIMITED"                   // A root field exceeded its @rateLimit for the client
+	CodeListSizeExceeded       = "LIST_SIZE_EXCEEDED"             // A @listSizeLimit root field is selected without a size or above its limit
+	CodeDryRunUnsupported      = "DRY_RUN_UNSUPPORTED"            // A dry run reaches subgraphs without dry-run support and was not executed
+	CodeInternalServerError    = executor.InternalServerErrorCode // The plan could not be executed
+)
+
+// errorCodeStatus is the HTTP status of a response failing with a code before
+// execution, when the client accepts application/graphql-response+json.
+var errorCodeStatus = map[string]int{
+	CodeParseFailed:            http.StatusBadRequest,
+	CodeValidationFailed:       http.StatusBadRequest,
+	CodePersistedQueryNotFound: http.StatusBadRequest,
+	CodeBadUserInput:           http.StatusBadRequest,
+	CodeInaccessibleField:      http.StatusBadRequest,
+	introspectionDisabledCode:  http.StatusBadRequest,
+	CodeBatchTooLarge:          http.StatusBadRequest,
+	CodeListSizeExceeded:       http.StatusBadRequest,
+	CodeDryRunUnsupported:      http.StatusBadRequest,
+	CodeTooManySubscriptions:   http.StatusTooManyRequests,
+	CodePlanError:              http.StatusInternalServerError,
+	CodeInternalServerError:    http.StatusInternalServerError,
+}
+
+// graphQLResponseMediaType is the GraphQL-over-HTTP response media type. Responses in
+// it use the HTTP status to report request errors; application/json responses answer
+// every well-formed request with 200.
+const graphQLResponseMediaType = "application/graphql-response+json"
+
+type graphQLResponseContextKey struct{}
+
+// withResponseMediaType records on ctx whether r accepts graphQLResponseMediaType.
+func withResponseMediaType(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, graphQLResponseContextKey{}, acceptsGraphQLResponse(r))
+}
+
+// usesGraphQLResponse reports whether the response to the request of ctx is encoded as
+// graphQLResponseMediaType.
+func usesGraphQLResponse(ctx context.Context) bool {
+	ok, _ := ctx.Value(graphQLResponseContextKey{}).(bool)
+	return ok
+}
+
+// acceptsGraphQLResponse reports whether the Accept header of r lists
+// graphQLResponseMediaType.
+func acceptsGraphQLResponse(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mediaType == graphQLResponseMediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestError returns the status and body of a request failing with code before
+// execution, with one error per message.
+func requestError(ctx context.Context, code string, messages ...string) (int, map[string]any) {
+	errs := make([]map[string]any, 0, len(messages))
+	for _, message := range messages {
+		errs = append(errs, map[string]any{
+			"message":    message,
+			"extensions": map[string]string{"code": code},
+		})
+	}
+
+	status := http.StatusOK
+	if usesGraphQLResponse(ctx) {
+		if s, ok := errorCodeStatus[code]; ok {
+			status = s
+		}
+	}
+	return status, map[string]any{"errors": errs}
+}
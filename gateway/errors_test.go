@@ -0,0 +1,135 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ErrorCodes(t *testing.T) {
+	const productsSDL = `
+		type Query {
+			serverTime: String!
+		}
+	`
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"serverTime": "now"}}
+	})
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": `type Query { stock: Int }`}}}) //nolint:errcheck
+			return
+		}
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(broken.Close)
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: broken.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		body            string
+		graphQLResponse bool
+		wantStatus      int
+		wantCode        string
+		wantService     string
+	}{
+		{
+			name:       "parse error",
+			body:       `{"query":"{ serverTime("}`,
+			wantStatus: http.StatusOK,
+			wantCode:   gateway.CodeParseFailed,
+		},
+		{
+			name:            "parse error with graphql-response+json",
+			body:            `{"query":"{ serverTime("}`,
+			graphQLResponse: true,
+			wantStatus:      http.StatusBadRequest,
+			wantCode:        gateway.CodeParseFailed,
+		},
+		{
+			name:            "ambiguous operation",
+			body:            `{"query":"query A { serverTime } query B { serverTime }"}`,
+			graphQLResponse: true,
+			wantStatus:      http.StatusBadRequest,
+			wantCode:        gateway.CodeValidationFailed,
+		},
+		{
+			name:            "persisted query hash without query",
+			body:            `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"abc"}}}`,
+			graphQLResponse: true,
+			wantStatus:      http.StatusBadRequest,
+			wantCode:        gateway.CodePersistedQueryNotFound,
+		},
+		{
+			name:            "failed subgraph request",
+			body:            `{"query":"{ serverTime stock }"}`,
+			graphQLResponse: true,
+			wantStatus:      http.StatusOK,
+			wantCode:        "SUBGRAPH_HTTP_ERROR",
+			wantService:     "inventory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			wantContentType := "application/json"
+			if tt.graphQLResponse {
+				req.Header.Set("Accept", "application/graphql-response+json, application/json;q=0.9")
+				wantContentType = "application/graphql-response+json"
+			}
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != wantContentType {
+				t.Errorf("expected Content-Type %s, got %s", wantContentType, got)
+			}
+
+			var resp struct {
+				Errors []struct {
+					Message    string         `json:"message"`
+					Extensions map[string]any `json:"extensions"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+			}
+			if len(resp.Errors) == 0 {
+				t.Fatalf("expected errors, got %s", rec.Body.String())
+			}
+			// Errors are encoded in canonical key order, message first.
+			if !strings.Contains(rec.Body.String(), `"errors":[{"message":`) {
+				t.Errorf("expected errors to start with their message, got %s", rec.Body.String())
+			}
+			for _, e := range resp.Errors {
+				if e.Message == "" || e.Extensions["code"] != tt.wantCode {
+					t.Errorf("expected a message with code %s, got %s", tt.wantCode, rec.Body.String())
+				}
+				if tt.wantService != "" && e.Extensions["serviceName"] != tt.wantService {
+					t.Errorf("expected serviceName %s, got %s", tt.wantService, rec.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_Explain(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean!
+		}
+	`
+
+	var requests atomic.Int32
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		requests.Add(1)
+		return map[string]any{"data": map[string]any{"topProducts": []any{
+			map[string]any{"__typename": "Product", "id": "1", "name": "a"},
+		}}}
+	})
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		requests.Add(1)
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "Product", "id": "1", "inStock": true},
+		}}}
+	})
+
+	newGateway := func(t *testing.T, allowExplain bool) http.Handler {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{
+				{Name: "products", Host: products.URL},
+				{Name: "inventory", Host: inventory.URL, LatencyWeight: 2},
+			},
+			ListSizeEstimate: 10,
+			AllowExplain:     allowExplain,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+	const body = `{"query":"{ topProducts { name inStock } }","extensions":{"explain":true}}`
+
+	t.Run("the plan is explained without executing it", func(t *testing.T) {
+		gw := newGateway(t, true)
+		requests.Store(0)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+
+		var resp struct {
+			Data       any `json:"data"`
+			Extensions struct {
+				Explain struct {
+					Cost             float64 `json:"cost"`
+					CriticalPathCost float64 `json:"criticalPathCost"`
+					SerialDepth      int     `json:"serialDepth"`
+					Steps            []struct {
+						SubGraph  string  `json:"subGraph"`
+						BatchSize float64 `json:"batchSize"`
+						Cost      float64 `json:"cost"`
+						Distance  float64 `json:"distance"`
+						Depth     int     `json:"depth"`
+					} `json:"steps"`
+				} `json:"explain"`
+			} `json:"extensions"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		if n := requests.Load(); n != 0 || resp.Data != nil {
+			t.Errorf("expected no execution, got %d subgraph requests and data %v", n, resp.Data)
+		}
+
+		explain := resp.Extensions.Explain
+		if explain.Cost != 21 || explain.CriticalPathCost != 21 || explain.SerialDepth != 2 || len(explain.Steps) != 2 {
+			t.Fatalf("unexpected explanation %s", rec.Body.String())
+		}
+		if step := explain.Steps[1]; step.SubGraph != "inventory" || step.BatchSize != 10 || step.Cost != 20 || step.Distance != 21 || step.Depth != 2 {
+			t.Errorf("unexpected entity step %+v", step)
+		}
+	})
+
+	t.Run("the extension is ignored unless allowed", func(t *testing.T) {
+		gw := newGateway(t, false)
+		requests.Store(0)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+
+		if strings.Contains(rec.Body.String(), "explain") || requests.Load() != 2 {
+			t.Errorf("expected the operation to be executed, got %s", rec.Body.String())
+		}
+	})
+}
@@ -8,3 +8,20 @@ var BuildEngineForTest = buildEngine
 
 // CopyMapForTest exposes copyMap for external tests.
 var CopyMapForTest = copyMap
+
+// NewSnapshotTransportForTest exposes newSnapshotTransport for external tests.
+var NewSnapshotTransportForTest = newSnapshotTransport
+
+// SelectOperationForTest exposes selectOperation for external tests.
+var SelectOperationForTest = selectOperation
+
+// ParsePercentLabelForTest exposes parsePercentLabel for external tests.
+var ParsePercentLabelForTest = parsePercentLabel
+
+// NewTransportForTest exposes newTransport for external tests.
+var NewTransportForTest = newTransport
+
+// MergeTransportForTest exposes TransportOption.merge for external tests.
+func MergeTransportForTest(opt, defaults TransportOption) TransportOption {
+	return opt.merge(defaults)
+}
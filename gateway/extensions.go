@@ -0,0 +1,181 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// ResponseExtensionsOption toggles the built-in response extension providers, so e.g.
+// development can return timing and subgraph details while production returns none.
+type ResponseExtensionsOption struct {
+	Timing     bool `yaml:"timing" default:"false"`     // Parse, validate, plan and execute durations in extensions.timing
+	Cache      bool `yaml:"cache" default:"false"`      // Entity cache hits and misses in extensions.cache
+	Subgraphs  bool `yaml:"subgraphs" default:"false"`  // Calls, errors and duration per subgraph in extensions.subgraphs
+	Deprecated bool `yaml:"deprecated" default:"false"` // Selected @deprecated fields in extensions.deprecated
+	Cost       bool `yaml:"cost" default:"false"`       // Estimated cost, subgraph requests and remaining budget in extensions.cost
+
+	// SubgraphRequests returns the GraphQL document and variables each step of the plan
+	// sent to its subgraph in extensions.subgraphRequests, to troubleshoot e.g. malformed
+	// _entities queries. The variables carry the representations and client input.
+	SubgraphRequests bool `yaml:"subgraph_requests" default:"false"`
+	// MergeTrace returns how each entity of each entity step was merged into the
+	// response in extensions.mergeTrace, with hashes of the merge targets before and
+	// after, to debug entity data missing from responses.
+	MergeTrace  bool   `yaml:"merge_trace" default:"false"`
+	DebugHeader string `yaml:"debug_header"` // Only return extensions.subgraphRequests and extensions.mergeTrace to requests carrying this header
+}
+
+// ExtensionProvider adds one entry to the extensions object of executed responses.
+type ExtensionProvider interface {
+	// Name is the extensions key the value is returned under.
+	Name() string
+	// Extension returns the value for info, or false to omit the entry.
+	Extension(ctx context.Context, info *ExecutionInfo) (any, bool)
+}
+
+// ExecutionInfo describes one executed operation to extension providers.
+type ExecutionInfo struct {
+	OperationName string
+	OperationType string
+	Document      *ast.Document
+	Timing        ExecutionTiming
+	Stats         *executor.ExecutionStats
+	Cost          float64    // Cost estimated by the planner cost model
+	Client        ClientInfo // Client sending the operation
+
+	engine           *executionEngine
+	remainingBudget  float64
+	hasBudget        bool
+	subgraphRequests []executor.SubgraphRequest
+	mergeTrace       *executor.MergeTrace
+}
+
+// ExecutionTiming breaks down where the gateway spent the time of one operation.
+type ExecutionTiming struct {
+	Parse    time.Duration
+	Validate time.Duration
+	Plan     time.Duration
+	Execute  time.Duration
+	Total    time.Duration
+}
+
+// RemainingBudget returns the budget the client has left, as reported by
+// GatewayOption.CostBudget, or false when it is unknown.
+func (i *ExecutionInfo) RemainingBudget() (float64, bool) {
+	return i.remainingBudget, i.hasBudget
+}
+
+// DeprecatedFields returns the @deprecated fields selected by the operation.
+func (i *ExecutionInfo) DeprecatedFields() []DeprecatedFieldUsage {
+	return deprecatedFieldUsages(i.Document, i.engine)
+}
+
+// SubgraphRequests returns the requests sent to subgraphs by each step when
+// ResponseExtensions.SubgraphRequests is enabled for the request, or nil.
+func (i *ExecutionInfo) SubgraphRequests() []executor.SubgraphRequest {
+	return i.subgraphRequests
+}
+
+// MergeOperations returns the merge operations of the entity steps when
+// ResponseExtensions.MergeTrace is enabled for the request, or nil.
+func (i *ExecutionInfo) MergeOperations() []executor.MergeOperation {
+	if i.mergeTrace == nil {
+		return nil
+	}
+	return i.mergeTrace.Operations()
+}
+
+// newExtensionProviders returns the enabled built-in providers followed by custom.
+func newExtensionProviders(opt ResponseExtensionsOption, custom []ExtensionProvider) []ExtensionProvider {
+	var providers []ExtensionProvider
+	if opt.Timing {
+		providers = append(providers, timingExtension{})
+	}
+	if opt.Cache {
+		providers = append(providers, cacheExtension{})
+	}
+	if opt.Subgraphs {
+		providers = append(providers, subgraphsExtension{})
+	}
+	if opt.Deprecated {
+		providers = append(providers, deprecatedExtension{})
+	}
+	if opt.Cost {
+		providers = append(providers, costExtension{})
+	}
+	if opt.SubgraphRequests {
+		providers = append(providers, subgraphRequestsExtension{})
+	}
+	if opt.MergeTrace {
+		providers = append(providers, mergeTraceExtension{})
+	}
+	return append(providers, custom...)
+}
+
+// applyExtensions adds the entries of every provider to resp["extensions"], keeping
+// entries already present such as the federated trace. Later providers win on conflicts.
+func (g *gateway) applyExtensions(ctx context.Context, resp map[string]any, info *ExecutionInfo) {
+	extensions, _ := resp["extensions"].(map[string]any)
+	for _, provider := range g.extensionProviders {
+		value, ok := provider.Extension(ctx, info)
+		if !ok {
+			continue
+		}
+		if extensions == nil {
+			extensions = make(map[string]any)
+		}
+		extensions[provider.Name()] = value
+	}
+	if extensions != nil {
+		resp["extensions"] = extensions
+	}
+}
+
+// timingExtension reports the execution timing breakdown in nanoseconds.
+type timingExtension struct{}
+
+func (timingExtension) Name() string { return "timing" }
+
+func (timingExtension) Extension(_ context.Context, info *ExecutionInfo) (any, bool) {
+	return map[string]int64{
+		"parseNs":    info.Timing.Parse.Nanoseconds(),
+		"validateNs": info.Timing.Validate.Nanoseconds(),
+		"planNs":     info.Timing.Plan.Nanoseconds(),
+		"executeNs":  info.Timing.Execute.Nanoseconds(),
+		"totalNs":    info.Timing.Total.Nanoseconds(),
+	}, true
+}
+
+// cacheExtension reports entity cache hits and misses.
+type cacheExtension struct{}
+
+func (cacheExtension) Name() string { return "cache" }
+
+func (cacheExtension) Extension(_ context.Context, info *ExecutionInfo) (any, bool) {
+	return map[string]int{
+		"hits":   info.Stats.CacheHits(),
+		"misses": info.Stats.CacheMisses(),
+	}, true
+}
+
+// subgraphsExtension reports the calls made to each subgraph.
+type subgraphsExtension struct{}
+
+func (subgraphsExtension) Name() string { return "subgraphs" }
+
+func (subgraphsExtension) Extension(_ context.Context, info *ExecutionInfo) (any, bool) {
+	return info.Stats.Subgraphs(), true
+}
+
+// deprecatedExtension lists the selected @deprecated fields, if any.
+type deprecatedExtension struct{}
+
+func (deprecatedExtension) Name() string { return "deprecated" }
+
+func (deprecatedExtension) Extension(_ context.Context, info *ExecutionInfo) (any, bool) {
+	usages := info.DeprecatedFields()
+	return usages, len(usages) > 0
+}
@@ -0,0 +1,146 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+type operationNameExtension struct{}
+
+func (operationNameExtension) Name() string { return "operation" }
+
+func (operationNameExtension) Extension(_ context.Context, info *gateway.ExecutionInfo) (any, bool) {
+	return info.OperationType + " " + info.OperationName, true
+}
+
+func TestGateway_ResponseExtensions(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			oldName: String @deprecated(reason: "Use name")
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean! @cacheControl(maxAge: 60)
+		}
+	`
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"topProducts": []any{
+			map[string]any{"id": "1", "oldName": "a"},
+			map[string]any{"id": "2", "oldName": "b"},
+		}}}
+	})
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		entities := make([]any, 0, len(reps))
+		for _, rep := range reps {
+			id := rep.(map[string]any)["id"].(string)
+			entities = append(entities, map[string]any{"__typename": "Product", "id": id, "inStock": true})
+		}
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL},
+		},
+		EntityCache: gateway.EntityCacheOption{Enable: true},
+		ResponseExtensions: gateway.ResponseExtensionsOption{
+			Timing:     true,
+			Cache:      true,
+			Subgraphs:  true,
+			Deprecated: true,
+		},
+		ExtensionProviders: []gateway.ExtensionProvider{operationNameExtension{}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	query := func(t *testing.T) map[string]any {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query Top { topProducts { id oldName inStock } }"}`)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		extensions, ok := resp["extensions"].(map[string]any)
+		if !ok {
+			t.Fatalf("expected extensions, got %s", rec.Body.String())
+		}
+		return extensions
+	}
+
+	extensions := query(t)
+
+	timing, ok := extensions["timing"].(map[string]any)
+	if !ok || timing["totalNs"].(float64) <= 0 {
+		t.Errorf("expected a positive total duration, got %v", extensions["timing"])
+	}
+	if cache := extensions["cache"].(map[string]any); cache["hits"] != float64(0) || cache["misses"] != float64(2) {
+		t.Errorf("expected 0 hits and 2 misses on the first request, got %v", cache)
+	}
+	subgraphs := extensions["subgraphs"].(map[string]any)
+	for _, name := range []string{"products", "inventory"} {
+		stats, ok := subgraphs[name].(map[string]any)
+		if !ok || stats["calls"] != float64(1) || stats["errors"] != float64(0) {
+			t.Errorf("expected one successful call to %s, got %v", name, subgraphs[name])
+		}
+	}
+	deprecated, _ := extensions["deprecated"].([]any)
+	if len(deprecated) != 1 {
+		t.Fatalf("expected one deprecated field, got %v", extensions["deprecated"])
+	}
+	if usage := deprecated[0].(map[string]any); usage["field"] != "Product.oldName" || usage["reason"] != "Use name" {
+		t.Errorf("unexpected deprecated field usage %v", usage)
+	}
+	if extensions["operation"] != "query Top" {
+		t.Errorf("expected the custom provider entry, got %v", extensions["operation"])
+	}
+
+	extensions = query(t)
+	if cache := extensions["cache"].(map[string]any); cache["hits"] != float64(2) || cache["misses"] != float64(0) {
+		t.Errorf("expected 2 hits on the second request, got %v", cache)
+	}
+	if _, ok := extensions["subgraphs"].(map[string]any)["inventory"]; ok {
+		t.Errorf("expected inventory not to be called on a cache hit, got %v", extensions["subgraphs"])
+	}
+}
+
+func TestGateway_ResponseExtensionsDisabled(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1"}}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { id } }"}`)))
+	if want := `{"data":{"product":{"id":"1"}}}`; strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("expected %s without extensions, got %s", want, rec.Body.String())
+	}
+}
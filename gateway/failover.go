@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// FailoverOption lists the fallback hosts of a subgraph. A request that fails on the
+// primary host (connection error, timeout or 5xx) is retried on them in order before
+// the failure is reported in a partial response.
+type FailoverOption struct {
+	Hosts    []string `yaml:"hosts"`
+	Cooldown string   `yaml:"cooldown" default:"30s" validate:"duration"` // How long a failed primary is skipped
+}
+
+// newFailover builds the failover of every service with fallback hosts. Failovers are
+// logged and counted in the graphql.subgraph.failover metric.
+func newFailover(services []GatewayService, labels metric.MeasurementOption) (map[string]*executor.Failover, error) {
+	var counter metric.Int64Counter
+	failovers := make(map[string]*executor.Failover)
+	for _, svc := range services {
+		if len(svc.Failover.Hosts) == 0 {
+			continue
+		}
+		cooldown := 30 * time.Second
+		if svc.Failover.Cooldown != "" {
+			d, err := time.ParseDuration(svc.Failover.Cooldown)
+			if err != nil {
+				return nil, fmt.Errorf("invalid failover cooldown for service %q: %w", svc.Name, err)
+			}
+			cooldown = d
+		}
+
+		if counter == nil {
+			var err error
+			counter, err = otel.Meter("github.com/n9te9/go-graphql-federation-gateway").Int64Counter(
+				"graphql.subgraph.failover",
+				metric.WithDescription("Number of subgraph requests retried on a fallback host"),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create failover counter: %w", err)
+			}
+		}
+
+		failover := executor.NewFailover(svc.Failover.Hosts, cooldown)
+		failover.OnFailover = func(ctx context.Context, subGraphName, from, to string) {
+			log.Printf("subgraph %q failed on %s, failing over to %s", subGraphName, from, to)
+			counter.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("graphql.subgraph.name", subGraphName),
+				attribute.String("graphql.subgraph.host", to),
+			), labels)
+		}
+		failovers[svc.Name] = failover
+	}
+	return failovers, nil
+}
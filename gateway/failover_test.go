@@ -0,0 +1,144 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_Failover(t *testing.T) {
+	const sdl = `
+		type Query {
+			hello: String
+		}`
+
+	// primary serves its SDL but fails every query.
+	var primaryQueries atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+			return
+		}
+		primaryQueries.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(primary.Close)
+
+	var fallbackQueries atomic.Int32
+	fallback := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		fallbackQueries.Add(1)
+		return map[string]any{"data": map[string]any{"hello": "from fallback"}}
+	})
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	query := func(t *testing.T, gw http.Handler) map[string]any {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ hello }"}`)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+	hello := func(resp map[string]any) any {
+		data, _ := resp["data"].(map[string]any)
+		return data["hello"]
+	}
+
+	t.Run("primary cools down after a failure", func(t *testing.T) {
+		primaryQueries.Store(0)
+		fallbackQueries.Store(0)
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{{
+				Name:     "hello",
+				Host:     primary.URL,
+				Failover: gateway.FailoverOption{Hosts: []string{down.URL, fallback.URL}, Cooldown: "1h"},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		for range 2 {
+			if got := hello(query(t, gw)); got != "from fallback" {
+				t.Errorf("expected the fallback response, got %v", got)
+			}
+		}
+		if n := primaryQueries.Load(); n != 1 {
+			t.Errorf("expected the primary to be skipped during its cooldown, got %d queries", n)
+		}
+		if n := fallbackQueries.Load(); n != 2 {
+			t.Errorf("expected 2 fallback queries, got %d", n)
+		}
+	})
+
+	t.Run("primary is retried after the cooldown", func(t *testing.T) {
+		primaryQueries.Store(0)
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{{
+				Name:     "hello",
+				Host:     primary.URL,
+				Failover: gateway.FailoverOption{Hosts: []string{fallback.URL}, Cooldown: "0s"},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		for range 2 {
+			query(t, gw)
+		}
+		if n := primaryQueries.Load(); n != 2 {
+			t.Errorf("expected the primary to be tried on every request, got %d queries", n)
+		}
+	})
+
+	t.Run("partial response when every host fails", func(t *testing.T) {
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{{
+				Name:     "hello",
+				Host:     primary.URL,
+				Failover: gateway.FailoverOption{Hosts: []string{down.URL}},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		resp := query(t, gw)
+		if hello(resp) != nil {
+			t.Errorf("expected hello to be null, got %v", hello(resp))
+		}
+		if errs, _ := resp["errors"].([]any); len(errs) == 0 {
+			t.Errorf("expected an error, got %v", resp)
+		}
+	})
+
+	t.Run("invalid cooldown", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{{
+				Name:     "hello",
+				Host:     fallback.URL,
+				Failover: gateway.FailoverOption{Hosts: []string{primary.URL}, Cooldown: "soon"},
+			}},
+		})
+		if err == nil {
+			t.Error("expected an invalid cooldown error")
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// faultsPath reports and replaces the faults injected into subgraph requests.
+const faultsPath = "/admin/faults"
+
+// FaultInjectionOption configures fault injection into subgraph requests, to test how
+// the graph handles partial failures in staging. Faults are only injected, and the admin
+// endpoint only served, when Enable is set.
+type FaultInjectionOption struct {
+	Enable    bool                   `yaml:"enable" default:"false"`
+	Subgraphs map[string]FaultOption `yaml:"subgraphs"` // Faults keyed by service name
+}
+
+// FaultOption describes the failures injected into the requests to one subgraph. It is
+// also the format of the admin endpoint.
+type FaultOption struct {
+	Latency     string  `yaml:"latency" json:"latency,omitempty" validate:"duration"` // Delay added before every request, e.g. 200ms
+	ErrorRate   float64 `yaml:"error_rate" json:"error_rate,omitempty"`               // Fraction of requests failed without being sent
+	ErrorStatus int     `yaml:"error_status" json:"error_status,omitempty"`           // HTTP status of injected errors; 500 when zero
+	DropRate    float64 `yaml:"drop_rate" json:"drop_rate,omitempty"`                 // Fraction of requests sent whose response is discarded
+}
+
+// newFaultInjector returns nil when fault injection is disabled.
+func newFaultInjector(settings GatewayOption) (*executor.FaultInjector, error) {
+	if !settings.FaultInjection.Enable {
+		return nil, nil
+	}
+	hosts := make(map[string]string, len(settings.Services))
+	for _, svc := range settings.Services {
+		hosts[svc.Name] = svc.Host
+	}
+	faults, err := parseFaults(settings.FaultInjection.Subgraphs, hosts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fault_injection: %w", err)
+	}
+	return executor.NewFaultInjector(faults), nil
+}
+
+// parseFaults converts opts to executor faults, rejecting services missing from hosts.
+func parseFaults(opts map[string]FaultOption, hosts map[string]string) (map[string]executor.Fault, error) {
+	faults := make(map[string]executor.Fault, len(opts))
+	for name, opt := range opts {
+		if _, ok := hosts[name]; !ok {
+			return nil, fmt.Errorf("unknown service %q", name)
+		}
+		fault, err := opt.fault()
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %w", name, err)
+		}
+		faults[name] = fault
+	}
+	return faults, nil
+}
+
+func (o FaultOption) fault() (executor.Fault, error) {
+	fault := executor.Fault{ErrorRate: o.ErrorRate, ErrorStatus: o.ErrorStatus, DropRate: o.DropRate}
+	if o.Latency != "" {
+		d, err := time.ParseDuration(o.Latency)
+		if err != nil {
+			return executor.Fault{}, fmt.Errorf("invalid latency: %w", err)
+		}
+		fault.Latency = d
+	}
+	if o.ErrorRate < 0 || o.ErrorRate > 1 {
+		return executor.Fault{}, fmt.Errorf("error_rate %v is not between 0 and 1", o.ErrorRate)
+	}
+	if o.DropRate < 0 || o.DropRate > 1 {
+		return executor.Fault{}, fmt.Errorf("drop_rate %v is not between 0 and 1", o.DropRate)
+	}
+	if o.ErrorStatus != 0 && (o.ErrorStatus < 100 || o.ErrorStatus > 599) {
+		return executor.Fault{}, fmt.Errorf("invalid error_status %d", o.ErrorStatus)
+	}
+	return fault, nil
+}
+
+// faultOptions converts executor faults back to the admin endpoint format.
+func faultOptions(faults map[string]executor.Fault) map[string]FaultOption {
+	opts := make(map[string]FaultOption, len(faults))
+	for name, fault := range faults {
+		opt := FaultOption{ErrorRate: fault.ErrorRate, ErrorStatus: fault.ErrorStatus, DropRate: fault.DropRate}
+		if fault.Latency > 0 {
+			opt.Latency = fault.Latency.String()
+		}
+		opts[name] = opt
+	}
+	return opts
+}
+
+// faultsResponse lists the injected faults by subgraph.
+type faultsResponse struct {
+	Faults map[string]FaultOption `json:"faults"`
+}
+
+// handleFaults serves GET and PUT /admin/faults. A PUT body replaces every injected
+// fault; an empty object stops injecting them.
+func (g *gateway) handleFaults(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if g.faults == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"error": "fault injection is disabled"}) //nolint:errcheck
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var opts map[string]FaultOption
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"error": fmt.Sprintf("invalid faults: %v", err)}) //nolint:errcheck
+			return
+		}
+		faults, err := parseFaults(opts, g.currentStore().hosts)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"error": fmt.Sprintf("invalid faults: %v", err)}) //nolint:errcheck
+			return
+		}
+		g.faults.SetFaults(faults)
+	}
+
+	json.NewEncoder(w).Encode(faultsResponse{Faults: faultOptions(g.faults.Faults())}) //nolint:errcheck
+}
@@ -0,0 +1,143 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_FaultInjection(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean
+		}
+	`
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"topProducts": []any{
+			map[string]any{"__typename": "Product", "id": "1", "name": "Table"},
+		}}}
+	})
+	var inventoryRequests atomic.Int32
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		inventoryRequests.Add(1)
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "Product", "id": "1", "inStock": true},
+		}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL, Timeout: "50ms"},
+		},
+		FaultInjection: gateway.FaultInjectionOption{
+			Enable:    true,
+			Subgraphs: map[string]gateway.FaultOption{"inventory": {ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	type graphQLError struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	}
+	query := func(t *testing.T) (map[string]any, []graphQLError) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ topProducts { name inStock } }"}`)))
+		var resp struct {
+			Data   map[string]any `json:"data"`
+			Errors []graphQLError `json:"errors"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		product, _ := resp.Data["topProducts"].([]any)[0].(map[string]any)
+		return product, resp.Errors
+	}
+	setFaults := func(t *testing.T, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/faults", strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("configured errors", func(t *testing.T) {
+		inventoryRequests.Store(0)
+		product, errs := query(t)
+		if product["name"] != "Table" || product["inStock"] != nil {
+			t.Errorf("expected a partial response, got %v", product)
+		}
+		if len(errs) != 1 || errs[0].Extensions["code"] != "SUBGRAPH_HTTP_ERROR" || errs[0].Extensions["serviceName"] != "inventory" {
+			t.Fatalf("expected a SUBGRAPH_HTTP_ERROR from inventory, got %v", errs)
+		}
+		if status := errs[0].Extensions["http"].(map[string]any)["status"]; status != float64(http.StatusServiceUnavailable) {
+			t.Errorf("expected status 503, got %v", status)
+		}
+		if n := inventoryRequests.Load(); n != 0 {
+			t.Errorf("expected the failed request not to be sent, got %d requests", n)
+		}
+	})
+
+	t.Run("latency set through the admin endpoint", func(t *testing.T) {
+		if rec := setFaults(t, `{"inventory":{"latency":"200ms"}}`); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		_, errs := query(t)
+		if len(errs) != 1 || errs[0].Extensions["code"] != "GATEWAY_TIMEOUT" {
+			t.Errorf("expected the subgraph timeout to expire, got %v", errs)
+		}
+	})
+
+	t.Run("dropped responses", func(t *testing.T) {
+		setFaults(t, `{"inventory":{"drop_rate":1}}`)
+		inventoryRequests.Store(0)
+		_, errs := query(t)
+		if len(errs) != 1 || !strings.Contains(errs[0].Message, "injected fault") {
+			t.Errorf("expected an injected fault, got %v", errs)
+		}
+		if n := inventoryRequests.Load(); n != 1 {
+			t.Errorf("expected the request to reach the subgraph, got %d requests", n)
+		}
+	})
+
+	t.Run("cleared faults", func(t *testing.T) {
+		rec := setFaults(t, `{}`)
+		if got := strings.TrimSpace(rec.Body.String()); got != `{"faults":{}}` {
+			t.Errorf("expected no faults, got %s", got)
+		}
+		product, errs := query(t)
+		if len(errs) != 0 || product["inStock"] != true {
+			t.Errorf("expected a complete response, got %v and %v", product, errs)
+		}
+	})
+
+	t.Run("invalid faults are rejected", func(t *testing.T) {
+		for _, body := range []string{`{"reviews":{"error_rate":1}}`, `{"inventory":{"drop_rate":2}}`, `{"inventory":{"latency":"soon"}}`} {
+			if rec := setFaults(t, body); rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400 for %s, got %d", body, rec.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_FragmentsInEntitySteps(t *testing.T) {
+	const catalogSDL = `
+		interface Node {
+			id: ID!
+		}
+
+		type Product implements Node @key(fields: "id") {
+			id: ID!
+			name: String
+		}
+
+		type Query {
+			topProducts: [Product]
+			featured: Product
+		}
+	`
+	const reviewsSDL = `
+		type Review {
+			body: String
+			attachment: Attachment
+		}
+
+		union Attachment = Image | Video
+
+		extend type Image @key(fields: "id") {
+			id: ID! @external
+		}
+
+		extend type Video @key(fields: "id") {
+			id: ID! @external
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review]
+		}
+	`
+	const mediaSDL = `
+		type Image @key(fields: "id") {
+			id: ID!
+			url: String
+		}
+
+		type Video @key(fields: "id") {
+			id: ID!
+			duration: Int
+		}
+	`
+
+	var mu sync.Mutex
+	queries := make(map[string][]string)
+	record := func(name string, body map[string]any) {
+		mu.Lock()
+		defer mu.Unlock()
+		q, _ := body["query"].(string)
+		queries[name] = append(queries[name], q)
+	}
+	representations := func(body map[string]any) []map[string]any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		var result []map[string]any
+		for _, r := range reps {
+			result = append(result, r.(map[string]any))
+		}
+		return result
+	}
+
+	catalog := newSubgraphServer(t, catalogSDL, func(body map[string]any) any {
+		record("catalog", body)
+		product := func(id string) map[string]any {
+			return map[string]any{"__typename": "Product", "id": id, "name": "product-" + id}
+		}
+		return map[string]any{"data": map[string]any{
+			"topProducts": []any{product("1"), product("2")},
+			"featured":    product("3"),
+		}}
+	})
+	reviews := newSubgraphServer(t, reviewsSDL, func(body map[string]any) any {
+		record("reviews", body)
+		var entities []any
+		for _, rep := range representations(body) {
+			id := rep["id"].(string)
+			entities = append(entities, map[string]any{"__typename": "Product", "id": id, "reviews": []any{
+				map[string]any{"body": "review-" + id, "attachment": map[string]any{"__typename": "Image", "id": "i" + id}},
+				map[string]any{"body": "clip-" + id, "attachment": map[string]any{"__typename": "Video", "id": "v" + id}},
+			}})
+		}
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+	media := newSubgraphServer(t, mediaSDL, func(body map[string]any) any {
+		record("media", body)
+		var entities []any
+		for _, rep := range representations(body) {
+			id := rep["id"].(string)
+			switch rep["__typename"] {
+			case "Image":
+				entities = append(entities, map[string]any{"__typename": "Image", "id": id, "url": "https://img/" + id})
+			case "Video":
+				entities = append(entities, map[string]any{"__typename": "Video", "id": id, "duration": len(id)})
+			}
+		}
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+
+	query := `
+		query {
+			topProducts { ...ProductParts }
+			featured { ...NodeParts }
+		}
+		fragment NodeParts on Node { id ... on Product { reviews { ...ReviewParts } } }
+		fragment ProductParts on Product { name reviews { ...ReviewParts } latest: reviews { ...ReviewParts } }
+		fragment ReviewParts on Review { body attachment { ...AttachmentParts } }
+		fragment AttachmentParts on Attachment {
+			... on Image { url }
+			... on Video { duration }
+		}
+	`
+	reviewsOf := func(id string) []any {
+		return []any{
+			map[string]any{"body": "review-" + id, "attachment": map[string]any{"url": "https://img/i" + id}},
+			map[string]any{"body": "clip-" + id, "attachment": map[string]any{"duration": float64(2)}},
+		}
+	}
+	expected := map[string]any{
+		"topProducts": []any{
+			map[string]any{"name": "product-1", "reviews": reviewsOf("1"), "latest": reviewsOf("1")},
+			map[string]any{"name": "product-2", "reviews": reviewsOf("2"), "latest": reviewsOf("2")},
+		},
+		"featured": map[string]any{"id": "3", "reviews": reviewsOf("3")},
+	}
+
+	for _, shared := range []bool{false, true} {
+		mu.Lock()
+		queries = make(map[string][]string)
+		mu.Unlock()
+
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:          "/graphql",
+			SubgraphFragments: shared,
+			Services: []gateway.GatewayService{
+				{Name: "catalog", Host: catalog.URL},
+				{Name: "reviews", Host: reviews.URL},
+				{Name: "media", Host: media.URL},
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		body, _ := json.Marshal(map[string]any{"query": query})
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp["errors"] != nil || !reflect.DeepEqual(resp["data"], expected) {
+			t.Errorf("subgraph_fragments %v: expected %v, got %s", shared, expected, rec.Body.String())
+		}
+
+		// The products and the featured node, selecting reviews differently, fetch them apart
+		mu.Lock()
+		reviewsQueries := queries["reviews"]
+		mu.Unlock()
+		if len(reviewsQueries) != 2 {
+			t.Fatalf("subgraph_fragments %v: expected 2 reviews requests, got %v", shared, reviewsQueries)
+		}
+		sharing := false
+		for _, q := range reviewsQueries {
+			if strings.Contains(q, "fragment f0 on Review {") && strings.Count(q, "...f0") == 2 {
+				sharing = true
+			}
+		}
+		if sharing != shared {
+			t.Errorf("subgraph_fragments %v: expected the reviews and latest selections to share a fragment only when enabled, got %v", shared, reviewsQueries)
+		}
+	}
+}
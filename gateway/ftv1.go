@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// FederatedTracingOption configures Apollo federated tracing (ftv1) passthrough.
+type FederatedTracingOption struct {
+	Enable            bool   `yaml:"enable" default:"false"`
+	IncludeInResponse bool   `yaml:"include_in_response" default:"false"` // Return the stitched trace in extensions.ftv1
+	ReportEndpoint    string `yaml:"report_endpoint"`                     // Endpoint receiving stitched traces as JSON
+	APIKey            string `yaml:"api_key"`                             // Sent as X-Api-Key to the report endpoint
+}
+
+// traceReporter sends stitched federated traces to a reporting endpoint.
+type traceReporter struct {
+	endpoint   string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// newTraceReporter returns a reporter for opt, or nil when no endpoint is configured.
+func newTraceReporter(opt FederatedTracingOption, httpClient *http.Client) *traceReporter {
+	if opt.ReportEndpoint == "" {
+		return nil
+	}
+	return &traceReporter{
+		endpoint:   opt.ReportEndpoint,
+		apiKey:     opt.APIKey,
+		httpClient: httpClient,
+	}
+}
+
+// report posts trace to the reporting endpoint.
+func (r *traceReporter) report(ctx context.Context, trace *executor.FederatedTrace) error {
+	body, err := json.Marshal(trace)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create trace report request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("X-Api-Key", r.apiKey)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send trace report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("trace report rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// handleFederatedTrace reports the stitched trace carried in resp and removes it from
+// the response unless it should be returned to the client.
+func (g *gateway) handleFederatedTrace(resp map[string]any) {
+	extensions, ok := resp["extensions"].(map[string]any)
+	if !ok {
+		return
+	}
+	trace, ok := extensions[executor.FederatedTraceExtension].(*executor.FederatedTrace)
+	if !ok {
+		return
+	}
+
+	if g.traceReporter != nil {
+		// Report in the background with its own deadline so the client is not delayed.
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := g.traceReporter.report(ctx, trace); err != nil {
+				log.Printf("federated trace report failed: %v", err)
+			}
+		}()
+	}
+
+	if !g.includeTraceInResponse {
+		delete(extensions, executor.FederatedTraceExtension)
+		if len(extensions) == 0 {
+			delete(resp, "extensions")
+		}
+	}
+}
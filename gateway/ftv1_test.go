@@ -0,0 +1,110 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_FederatedTracing(t *testing.T) {
+	var traceHeader string
+	products := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdlProducts}}}) //nolint:errcheck
+			return
+		}
+		traceHeader = r.Header.Get("apollo-federation-include-trace")
+		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+			"data":       map[string]any{"product": map[string]any{"id": "1", "name": "Widget"}},
+			"extensions": map[string]any{"ftv1": "dGVzdA=="},
+		})
+	}))
+	t.Cleanup(products.Close)
+
+	reports := make(chan map[string]any, 1)
+	var apiKey string
+	reporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("X-Api-Key")
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		reports <- body
+	}))
+	t.Cleanup(reporter.Close)
+
+	serve := func(t *testing.T, opt gateway.FederatedTracingOption) map[string]any {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:         "/graphql",
+			FederatedTracing: opt,
+			Services:         []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query GetProduct { product(id: \"1\") { name } }"}`)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("trace returned in response", func(t *testing.T) {
+		resp := serve(t, gateway.FederatedTracingOption{Enable: true, IncludeInResponse: true})
+		if traceHeader != "ftv1" {
+			t.Errorf("expected subgraph to receive the ftv1 header, got %q", traceHeader)
+		}
+		extensions, _ := resp["extensions"].(map[string]any)
+		trace, _ := extensions["ftv1"].(map[string]any)
+		if trace["operationName"] != "GetProduct" {
+			t.Errorf("expected operationName GetProduct, got %v", trace["operationName"])
+		}
+		fetches, _ := trace["fetches"].([]any)
+		if len(fetches) != 1 {
+			t.Fatalf("expected 1 fetch, got %v", trace)
+		}
+		fetch := fetches[0].(map[string]any)
+		if fetch["serviceName"] != "products" || fetch["ftv1"] != "dGVzdA==" {
+			t.Errorf("unexpected fetch trace: %v", fetch)
+		}
+	})
+
+	t.Run("trace reported and stripped", func(t *testing.T) {
+		resp := serve(t, gateway.FederatedTracingOption{Enable: true, ReportEndpoint: reporter.URL, APIKey: "secret"})
+		if _, ok := resp["extensions"]; ok {
+			t.Errorf("expected trace to be stripped from the response, got %v", resp["extensions"])
+		}
+		select {
+		case report := <-reports:
+			if report["operationType"] != "query" {
+				t.Errorf("unexpected report: %v", report)
+			}
+			if apiKey != "secret" {
+				t.Errorf("expected X-Api-Key to be sent, got %q", apiKey)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected trace to be reported")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		traceHeader = ""
+		resp := serve(t, gateway.FederatedTracingOption{})
+		if traceHeader != "" {
+			t.Errorf("expected no ftv1 header, got %q", traceHeader)
+		}
+		if _, ok := resp["extensions"]; ok {
+			t.Errorf("expected no extensions, got %v", resp["extensions"])
+		}
+	})
+}
@@ -1,10 +1,13 @@
 package gateway
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,29 +15,210 @@ import (
 	"github.com/goccy/go-json"
 
 	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/websocket"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
 	"github.com/n9te9/graphql-parser/ast"
 	"github.com/n9te9/graphql-parser/lexer"
 	"github.com/n9te9/graphql-parser/parser"
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // GatewayService describes a single upstream subgraph.
 type GatewayService struct {
-	Name  string      `yaml:"name"`
-	Host  string      `yaml:"host"`
-	Retry RetryOption `yaml:"retry"`
+	Name    string      `yaml:"name" validate:"required"`
+	Host    string      `yaml:"host"`
+	Retry   RetryOption `yaml:"retry"`
+	Timeout string      `yaml:"timeout" validate:"duration"` // Overrides the operation timeout for requests to this subgraph
+
+	// Source loads the SDL from a schema source (file, http, s3, gcs or configmap)
+	// instead of querying _service on Host.
+	Source *registry.SourceOption `yaml:"source"`
+
+	// Type is "federated" (the default) for Federation subgraphs, "stitched" for plain
+	// GraphQL services: their schema is loaded through standard introspection and their
+	// root fields are delegated as they are, or "rest" for REST APIs resolved as
+	// described by REST.
+	Type string `yaml:"type" validate:"oneof=federated|stitched|rest"`
+
+	// Prefix is prepended to the root fields of a stitched service in the supergraph, so
+	// that they do not collide with the fields of other services.
+	Prefix string `yaml:"prefix"`
+
+	// REST maps the schema of a service of type rest to the endpoints of its API on Host.
+	REST *RESTOption `yaml:"rest"`
+
+	// Transport tunes the connection pool to this subgraph; zero fields fall back to
+	// GatewayOption.Transport.
+	Transport TransportOption `yaml:"transport"`
+
+	// SchemaCache is a file that stores the last SDL fetched from this subgraph. It is
+	// used when the subgraph cannot be reached on startup.
+	SchemaCache string `yaml:"schema_cache"`
+
+	// LatencyWeight is the relative cost of a request to this subgraph, used by the
+	// planner to choose among subgraphs that can resolve the same root field.
+	LatencyWeight float64 `yaml:"latency_weight"`
+
+	// Auth configures the credentials sent with every request to this subgraph.
+	Auth SubgraphAuthOption `yaml:"auth"`
+
+	// Failover lists fallback hosts tried when a request to Host fails.
+	Failover FailoverOption `yaml:"failover"`
+
+	// Endpoints overrides Host per operation type, e.g. for read replicas.
+	Endpoints ServiceEndpointsOption `yaml:"endpoints"`
+
+	// MaxRepresentations overrides EntityBatchingOption.MaxRepresentations for this
+	// subgraph.
+	MaxRepresentations int `yaml:"max_representations"`
+
+	// MaxConcurrentRequests bounds the concurrent requests to this subgraph, sharing the
+	// queue settings of GatewayOption.SubgraphConcurrency.
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+
+	// DryRun declares that this subgraph honors DryRunOption.SubgraphHeader by
+	// validating mutations without side effects.
+	DryRun bool `yaml:"dry_run"`
+
+	// Errors maps the errors of this subgraph to gateway error codes, classifies them
+	// as retryable or terminal and hides their internal details from clients.
+	Errors ErrorMappingOption `yaml:"errors"`
 }
 
 // GatewayOption is the top-level configuration loaded from gateway.yaml.
 type GatewayOption struct {
-	Endpoint                    string               `yaml:"endpoint"`
-	ServiceName                 string               `yaml:"service_name"`
-	Port                        int                  `yaml:"port"`
-	TimeoutDuration             string               `yaml:"timeout_duration"  default:"5s"`
-	RequestTimeout              string               `yaml:"request_timeout"   default:"30s"`
-	EnableHangOverRequestHeader bool                 `yaml:"enable_hang_over_request_header" default:"true"`
-	Services                    []GatewayService     `yaml:"services"`
-	Opentelemetry               OpentelemetrySetting `yaml:"opentelemetry"`
+	Endpoint                    string                     `yaml:"endpoint"`
+	ServiceName                 string                     `yaml:"service_name"`
+	Port                        int                        `yaml:"port"`
+	TimeoutDuration             string                     `yaml:"timeout_duration"  default:"5s" validate:"duration"`
+	RequestTimeout              string                     `yaml:"request_timeout"   default:"30s" validate:"duration"`
+	EnableHangOverRequestHeader bool                       `yaml:"enable_hang_over_request_header" default:"true"`
+	EnableSubgraphMode          bool                       `yaml:"enable_subgraph_mode" default:"false"`
+	DisableIntrospection        bool                       `yaml:"disable_introspection" default:"false"` // Reject operations selecting __schema or __type
+	SuppressSuggestions         bool                       `yaml:"suppress_suggestions" default:"false"`  // Strip "did you mean" hints from error messages
+	Services                    []GatewayService           `yaml:"services"`
+	Opentelemetry               OpentelemetrySetting       `yaml:"opentelemetry"`
+	Snapshot                    SnapshotOption             `yaml:"snapshot"`
+	OperationTimeout            OperationTimeoutOption     `yaml:"operation_timeout"`
+	BatchMaxConcurrency         int                        `yaml:"batch_max_concurrency" default:"10"`
+	BatchMaxSize                int                        `yaml:"batch_max_size" default:"100"` // Operations accepted in one batched request
+	FederatedTracing            FederatedTracingOption     `yaml:"federated_tracing"`
+	ListSizeEstimate            float64                    `yaml:"list_size_estimate"` // Estimated list length for the planner cost model
+	OverrideLabels              map[string]bool            `yaml:"override_labels"`    // Progressive @override labels enabled for every request
+	EntityCache                 EntityCacheOption          `yaml:"entity_cache"`
+	Transport                   TransportOption            `yaml:"transport"` // Default connection pool settings for every subgraph
+	Registry                    registry.StoreOption       `yaml:"registry"`  // Schema version history, shared by replicas with a distributed backend
+	ResponseExtensions          ResponseExtensionsOption   `yaml:"response_extensions"`
+	DeprecatedUsage             DeprecatedUsageOption      `yaml:"deprecated_usage"`
+	HTTPPolicy                  HTTPPolicyOption           `yaml:"http_policy"` // Content-Type and CSRF checks per endpoint
+	CORS                        CORSOption                 `yaml:"cors"`
+	ForwardExtensions           ExtensionPropagationOption `yaml:"forward_extensions"`                                                   // Client request extensions forwarded to subgraphs
+	MetricLabels                map[string]string          `yaml:"metric_labels"`                                                        // Attributes added to every metric the gateway records
+	Tenants                     []TenantOption             `yaml:"tenants"`                                                              // Independent supergraphs served by NewTenantGateway
+	PinnedPlans                 PinnedPlansOption          `yaml:"pinned_plans"`                                                         // Reviewed query plans executed instead of planning their operations
+	PersistedOperations         PersistedOperationsOption  `yaml:"persisted_operations"`                                                 // Operations planned ahead whenever a schema is installed
+	SupergraphFile              string                     `yaml:"supergraph_file"`                                                      // Pre-composed subgraph SDLs used instead of fetching them on startup
+	CompositionCache            string                     `yaml:"composition_cache"`                                                    // File storing the field ownership computed by composition, reused while the SDLs are unchanged
+	CostHeaders                 bool                       `yaml:"cost_headers" default:"false"`                                         // X-Query-Cost, X-Subgraph-Requests and X-RateLimit-Remaining response headers
+	PruneUnfetchableFields      bool                       `yaml:"prune_unfetchable_fields" default:"false"`                             // Return null with an UNFETCHABLE_FIELD error for root fields no subgraph resolves instead of failing the operation
+	LogSubgraphRequests         bool                       `yaml:"log_subgraph_requests" default:"false"`                                // Log the document and variables each step sends to its subgraph at debug level
+	SchemaEndpoint              SchemaEndpointOption       `yaml:"schema_endpoint"`                                                      // Composed schema served as SDL
+	OwnerStrategy               string                     `yaml:"owner_strategy" default:"local" validate:"oneof=first|local|cheapest"` // How the planner picks among the subgraphs resolving a @shareable field: first, local or cheapest
+	EntityBatching              EntityBatchingOption       `yaml:"entity_batching"`                                                      // Chunking of large _entities requests
+	SubgraphConcurrency         SubgraphConcurrencyOption  `yaml:"subgraph_concurrency"`                                                 // Limits on concurrent subgraph requests
+	ResponseTransforms          []ResponseTransformOption  `yaml:"response_transforms"`                                                  // Masking and renaming of response fields
+	ComputedFields              []ComputedFieldOption      `yaml:"computed_fields"`                                                      // Fields computed by the gateway from other fields of the same object
+	Mock                        MockOption                 `yaml:"mock"`                                                                 // Data generated from the composed schema instead of calling subgraphs
+	FaultInjection              FaultInjectionOption       `yaml:"fault_injection"`                                                      // Latency, errors and dropped responses injected into subgraph requests
+	AllowExplain                bool                       `yaml:"allow_explain" default:"false"`                                        // Return the estimated plan costs instead of executing requests with the explain extension
+	BuiltinScalars              []string                   `yaml:"builtin_scalars" validate:"oneof=DateTime|JSON|BigInt"`                // Built-in custom scalars validated and coerced: DateTime, JSON and BigInt
+	VerifySubgraphResponses     bool                       `yaml:"verify_subgraph_responses"`                                            // Log and count subgraph responses not matching their subgraph schema (staging use)
+	Subscriptions               SubscriptionOption         `yaml:"subscriptions"`                                                        // WebSocket transport of subscriptions (graphql-transport-ws)
+	FieldRateLimits             FieldRateLimitOption       `yaml:"field_rate_limits"`                                                    // Per-client limits subgraphs set on root fields with @rateLimit
+	ListSizeLimits              ListSizeLimitOption        `yaml:"list_size_limits"`                                                     // Sizes required on list root fields marked with @listSizeLimit
+	Listener                    ListenerOption             `yaml:"listener"`                                                             // TLS termination, HTTP/2 and the admin port of the server
+	AccessLog                   AccessLogOption            `yaml:"access_log"`                                                           // One line per GraphQL request, in JSON or Apache formats
+	UsageReporting              UsageReportingOption       `yaml:"usage_reporting"`                                                      // Operation usage reported to GraphQL Hive, Apollo GraphOS or a webhook
+	KeepTypename                bool                       `yaml:"keep_typename" default:"false"`                                        // Keep __typename in every response object even when not selected, e.g. for normalized client caches
+	DryRun                      DryRunOption               `yaml:"dry_run"`                                                              // Header-activated dry runs of mutations on subgraphs supporting them
+	PlanCache                   PlanCacheOption            `yaml:"plan_cache"`                                                           // Query plans cached in memory and shared by replicas through Redis or memcached
+	ClientIdentity              ClientIdentityOption       `yaml:"client_identity"`                                                      // Headers naming the client and its version in metrics, spans, usage reports, rate limits and logs
+	Degradation                 DegradationOption          `yaml:"degradation"`                                                          // Response to operations whose root steps all failed: partial data, an error status or a stale response
+	SubgraphLog                 SubgraphLogOption          `yaml:"subgraph_log"`                                                         // Full subgraph exchanges logged when they cross size or latency thresholds or fail
+	RelayNode                   RelayNodeOption            `yaml:"relay_node"`                                                           // Relay node(id:) root field served by the gateway from global IDs
+	SubgraphFragments           bool                       `yaml:"subgraph_fragments" default:"false"`                                   // Write selection sets repeated in a subgraph query once, as fragments, to shorten it
+	QuerySanitization           QuerySanitizationOption    `yaml:"query_sanitization"`                                                   // Literals redacted and variables hashed in query text logged, reported or attached to spans
+
+	// Scalars validates and coerces custom scalar inputs, in addition to the
+	// BuiltinScalars. When nil, only the BuiltinScalars are validated.
+	Scalars *ScalarRegistry `yaml:"-"`
+
+	// OverrideLabelProvider decides custom progressive @override labels per request.
+	// When nil, the labels listed in OverrideLabels are enabled.
+	OverrideLabelProvider OverrideLabelProvider `yaml:"-"`
+
+	// SchemaRegistry keeps the schema version history and shares it between replicas.
+	// When nil, a store is created from Registry.
+	SchemaRegistry registry.Store `yaml:"-"`
+
+	// ExtensionProviders add custom entries to the extensions of executed responses,
+	// after the built-in providers enabled by ResponseExtensions.
+	ExtensionProviders []ExtensionProvider `yaml:"-"`
+
+	// ResponseTransformers rewrite the fields of executed responses, after the rules
+	// configured in ResponseTransforms.
+	ResponseTransformers []ResponseTransformer `yaml:"-"`
+
+	// CredentialsProviders authorize requests to subgraphs, keyed by service name, e.g.
+	// with AWS SigV4 signing. They take precedence over GatewayService.Auth.
+	CredentialsProviders map[string]CredentialsProvider `yaml:"-"`
+
+	// NodeIDCodec decodes the global IDs of the Relay node field. When nil, they are
+	// decoded with the encoding of RelayNode.
+	NodeIDCodec NodeIDCodec `yaml:"-"`
+
+	// SubgraphRequestHooks are called with every subgraph request, in order, before
+	// its credentials are applied.
+	SubgraphRequestHooks []SubgraphRequestHook `yaml:"-"`
+
+	// EmbeddedSubgraphs are subgraphs served in process, keyed by service name. A
+	// service of the same name in Services keeps its settings, and its host is
+	// optional; the others are added to Services.
+	EmbeddedSubgraphs map[string]EmbeddedSubgraph `yaml:"-"`
+
+	// CostBudget reports the budget clients have left after an operation, e.g. from a
+	// rate limiter, in the X-RateLimit-Remaining header and extensions.cost.
+	CostBudget CostBudgetProvider `yaml:"-"`
+
+	// PinnedPlanStore looks up pinned query plans, e.g. in a plan registry. When nil,
+	// plans are read from PinnedPlans.Dir.
+	PinnedPlanStore PinnedPlanStore `yaml:"-"`
+
+	// PlanCacheStore is the plan cache shared by replicas when PlanCache is enabled,
+	// e.g. on another key-value store. When nil, a store is created from PlanCache.L2.
+	PlanCacheStore PlanCacheStore `yaml:"-"`
+
+	// SubscriptionAuthenticator authorizes WebSocket connections from their
+	// connection_init payload. When nil, every connection is accepted.
+	SubscriptionAuthenticator SubscriptionAuthenticator `yaml:"-"`
+}
+
+// OperationTimeoutOption configures per-operation-type execution deadlines and the
+// planning budget. Unset durations fall back to Default; operations have no deadline
+// when both are unset.
+type OperationTimeoutOption struct {
+	// Planning bounds query planning on its own; an operation whose planning exceeds it
+	// is answered 504 without calling any subgraph. Unset, planning is unbounded.
+	Planning     string `yaml:"planning" validate:"duration"`
+	Default      string `yaml:"default" validate:"duration"`
+	Query        string `yaml:"query" validate:"duration"`
+	Mutation     string `yaml:"mutation" validate:"duration"`
+	Subscription string `yaml:"subscription" validate:"duration"`
+	// SoftDeadline returns the data merged so far plus timeout errors instead of
+	// a 504 Gateway Timeout when the deadline expires.
+	SoftDeadline bool `yaml:"soft_deadline" default:"false"`
 }
 
 // OpentelemetrySetting holds OpenTelemetry config.
@@ -71,27 +255,256 @@ type gateway struct {
 	// requests to drain before giving up.
 	requestTimeout time.Duration
 
-	// httpClient is shared across all subgraph requests (SDL fetch and query forwarding).
+	// httpClient is used for subgraph requests without a dedicated client.
 	httpClient *http.Client
-
+	// subGraphClients maps subgraph name → client with its own connection pool, used
+	// for SDL fetches and query forwarding.
+	subGraphClients map[string]*http.Client
+	// failover maps subgraph name → fallback hosts, kept across schema updates.
+	failover map[string]*executor.Failover
+	// endpoints maps subgraph name → URLs per operation type, kept across schema updates.
+	endpoints map[string]executor.Endpoints
+	// entityBatching chunks large _entities requests, kept across schema updates.
+	entityBatching *executor.EntityBatching
+	// limiter bounds concurrent subgraph requests, kept across schema updates.
+	limiter *executor.RequestLimiter
+
+	// faults injects failures into subgraph requests, kept across schema updates; nil
+	// disables fault injection.
+	faults *executor.FaultInjector
+	// verifier checks subgraph responses against their schema, kept across schema
+	// updates; nil disables verification.
+	verifier *executor.ResponseVerifier
+	// subgraphFragments shares the selection sets repeated in subgraph queries as
+	// fragments.
+	subgraphFragments bool
+	// errorMappings rewrite the errors of subgraphs, keyed by service name.
+	errorMappings map[string]*executor.ErrorMapping
+	// cancellations counts operations abandoned by their client.
+	cancellations *cancellationCounter
+
+	// sources maps subgraph name → where its SDL is loaded from.
+	sources map[string]registry.SchemaSource
 	// retryOptions maps subgraph name → SDL fetch retry config.
 	retryOptions map[string]RetryOption
 
+	// schemaCaches maps subgraph name → file storing its last fetched SDL.
+	schemaCaches map[string]string
+	// compositionCache is the file storing the composition of the current SDLs.
+	compositionCache string
+
+	// schemaHealth tracks the freshness of the SDLs of the polled sources.
+	schemaHealth *schemaHealth
+
+	// stopWatchers stops polling the schema sources and the registry subscription.
+	stopWatchers context.CancelFunc
+
+	// schemaRegistry records every applied schema as a version and shares it with the
+	// other replicas. schemaVersion is the registry version currently installed and
+	// is guarded by mu.
+	schemaRegistry registry.Store
+	schemaVersion  int64
+
 	enableComplementRequestId   bool
 	enableHangOverRequestHeader bool
 	enableOpentelemetryTracing  bool
+
+	// enableSubgraphMode exposes _service and _entities on the gateway itself so it
+	// can be composed into a parent gateway (gateway-of-gateways).
+	enableSubgraphMode bool
+
+	// disableIntrospection rejects __schema and __type selections; suppressSuggestions
+	// strips "did you mean" hints from subgraph errors.
+	disableIntrospection bool
+	suppressSuggestions  bool
+
+	// graphQLPolicy and adminPolicy check requests to the GraphQL and admin endpoints;
+	// nil enforces nothing.
+	graphQLPolicy *requestPolicy
+	adminPolicy   *requestPolicy
+
+	// cors answers preflight requests and adds CORS headers; nil disables CORS.
+	cors *corsPolicy
+
+	// forwardExtensions forwards allowlisted client extensions to subgraphs; nil forwards none.
+	forwardExtensions *extensionPropagation
+
+	// scalars validates and coerces custom scalar arguments and variables.
+	scalars *ScalarRegistry
+
+	// operationTimeouts maps operation type ("query", "mutation", "subscription")
+	// → execution deadline. Missing entries mean no deadline.
+	operationTimeouts map[string]time.Duration
+	// subgraphTimeouts maps subgraph name → per-request timeout override.
+	subgraphTimeouts map[string]time.Duration
+	// planningTimeout bounds query planning; zero leaves it unbounded.
+	planningTimeout time.Duration
+	// timeouts counts operations exceeding their planning or execution deadline.
+	timeouts *timeoutCounter
+	// softDeadline returns partial data instead of a 504 when a deadline expires.
+	softDeadline bool
+
+	// batchMaxConcurrency caps how many operations of one batched request run at once.
+	batchMaxConcurrency int
+	// batchMaxSize caps how many operations one batched request may hold.
+	batchMaxSize int
+
+	// enableFederatedTracing collects ftv1 traces from subgraphs and stitches them.
+	enableFederatedTracing bool
+	includeTraceInResponse bool
+	traceReporter          *traceReporter
+
+	// costModel is applied to every planner built for this gateway; nil keeps the
+	// first-owner choice for shareable root fields.
+	costModel *planner.CostModel
+	// pruneUnfetchable is applied to every planner built for this gateway.
+	pruneUnfetchable bool
+	// ownerStrategy is applied to every planner built for this gateway.
+	ownerStrategy planner.OwnerStrategy
+
+	// overrideLabels decides custom progressive @override labels per request.
+	overrideLabels OverrideLabelProvider
+
+	// entityCache is shared by every engine so it survives schema updates; nil disables it.
+	entityCache *executor.EntityCache
+
+	// pinnedPlans executes the reviewed plans pinned for operations; nil disables them.
+	pinnedPlans *pinnedPlans
+	// planCache caches the plans of operations; nil disables it.
+	planCache *planCache
+	// persistedOperations are planned ahead whenever a schema is installed.
+	persistedOperations []persistedOperation
+
+	// extensionProviders populate the extensions of executed responses.
+	extensionProviders []ExtensionProvider
+
+	// costHeaders reports the cost of operations in response headers, with the
+	// remaining budget from costBudget when set.
+	costHeaders bool
+	costBudget  CostBudgetProvider
+
+	// allowExplain answers requests with the explain extension with the cost analysis
+	// of their plan instead of executing it.
+	allowExplain bool
+
+	// keepTypename keeps __typename in every response object; requests can also ask
+	// for it with the keepTypename extension.
+	keepTypename bool
+
+	// dryRun runs mutations dry for requests asking for it; nil disables dry runs.
+	dryRun *dryRun
+
+	// deprecatedUsage counts selected @deprecated fields; nil disables tracking.
+	deprecatedUsage *deprecatedUsageTracker
+
+	// responseTransforms rewrites the fields of executed responses; nil disables it.
+	responseTransforms *responseTransforms
+
+	// computedFields are the fields computed by the gateway; nil when none is configured.
+	computedFields *computedFields
+
+	// relayNode serves the Relay node field from global IDs; nil disables it.
+	relayNode *relayNode
+
+	// mock answers operations with generated data instead of executing them; nil
+	// disables mock mode.
+	mock *mocker
+
+	// subgraphRequests records the requests each step sends to its subgraph; nil
+	// disables it.
+	subgraphRequests *subgraphRequestCapture
+	// mergeTracing records the merges of entity results of traced requests; nil when
+	// disabled.
+	mergeTracing *mergeTracing
+
+	// schemaEndpoint serves the composed schema as SDL; nil disables it.
+	schemaEndpoint *schemaEndpoint
+	// openAPI is the OpenAPI document describing the admin routes, served at openAPIPath.
+	openAPI []byte
+
+	// subscriptions serves operations over WebSocket connections; nil disables the
+	// WebSocket transport.
+	subscriptions *subscriptionServer
+	// fieldRateLimits enforces @rateLimit on root fields; nil disables it.
+	fieldRateLimits *fieldRateLimiter
+	// clientIdentity reads the name and version of the client of requests.
+	clientIdentity *clientIdentity
+	// degradation answers operations whose root steps all failed; nil answers them
+	// with partial data.
+	degradation *degradation
+	// listSizes enforces @listSizeLimit on root fields; nil disables it.
+	listSizes *listSizeGuard
+	// accessLog logs the sampled GraphQL requests; nil disables it.
+	accessLog *accessLogger
+	// usage reports the executed operations to a schema registry; nil disables it.
+	usage *usageReporter
+	// sanitizer sanitizes query text before it is logged, reported or attached to
+	// spans; nil leaves it as it is.
+	sanitizer *querySanitizer
 }
 
 var _ http.Handler = (*gateway)(nil)
 
+// registryTimeout bounds a single schema registry operation.
+const registryTimeout = 5 * time.Second
+
+// newSubGraphClients builds the HTTP client of every subgraph, with its transport
+// settings, credentials and request hooks.
+func newSubGraphClients(settings GatewayOption) (map[string]*http.Client, error) {
+	subGraphClients := make(map[string]*http.Client, len(settings.Services))
+	exchangeLog, err := newSubgraphLog(settings.SubgraphLog, newQuerySanitizer(settings.QuerySanitization))
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range settings.Services {
+		var client *http.Client
+		var err error
+		if embedded, ok := settings.EmbeddedSubgraphs[svc.Name]; ok {
+			handler := embedded.Handler
+			if handler == nil {
+				handler = ResolverHandler(embedded.Resolver)
+			}
+			client, err = newHTTPClientWithTransport(settings, &embeddedTransport{handler: handler})
+		} else {
+			client, err = newHTTPClient(settings, svc.Transport.merge(settings.Transport))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport for service %q: %w", svc.Name, err)
+		}
+		client.Transport = exchangeLog.transport(svc.Name, client.Transport)
+		provider, ok := settings.CredentialsProviders[svc.Name]
+		if !ok {
+			provider, err = newCredentialsProvider(svc.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth for service %q: %w", svc.Name, err)
+			}
+		}
+		if provider != nil {
+			client.Transport = &credentialsTransport{provider: provider, base: client.Transport}
+		}
+		if len(settings.SubgraphRequestHooks) > 0 {
+			client.Transport = &subgraphHookTransport{subGraphName: svc.Name, hooks: settings.SubgraphRequestHooks, base: client.Transport}
+		}
+		subGraphClients[svc.Name] = client
+	}
+	return subGraphClients, nil
+}
+
 // NewGateway builds a gateway by fetching the SDL from every subgraph listed in
 // settings, composing them into a SuperGraph, and wiring up the execution engine.
 func NewGateway(settings GatewayOption) (*gateway, error) {
-	httpClient := &http.Client{
-		Timeout: 3 * time.Second,
+	settings, err := settings.withEmbeddedSubgraphs()
+	if err != nil {
+		return nil, err
 	}
-	if settings.Opentelemetry.TracingSetting.Enable {
-		httpClient.Transport = otelhttp.NewTransport(http.DefaultTransport)
+	labels := metricLabels(settings.MetricLabels)
+	httpClient, err := newHTTPClient(settings, settings.Transport)
+	if err != nil {
+		return nil, err
+	}
+	subGraphClients, err := newSubGraphClients(settings)
+	if err != nil {
+		return nil, err
 	}
 
 	requestTimeout := 30 * time.Second
@@ -101,47 +514,422 @@ func NewGateway(settings GatewayOption) (*gateway, error) {
 		}
 	}
 
+	operationTimeouts, err := parseOperationTimeouts(settings.OperationTimeout)
+	if err != nil {
+		return nil, err
+	}
+	var planningTimeout time.Duration
+	if settings.OperationTimeout.Planning != "" {
+		planningTimeout, err = time.ParseDuration(settings.OperationTimeout.Planning)
+		if err != nil {
+			return nil, fmt.Errorf("invalid planning timeout %q: %w", settings.OperationTimeout.Planning, err)
+		}
+	}
+
+	subgraphTimeouts := make(map[string]time.Duration)
+	for _, svc := range settings.Services {
+		if svc.Timeout == "" {
+			continue
+		}
+		d, err := time.ParseDuration(svc.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for service %q: %w", svc.Name, err)
+		}
+		subgraphTimeouts[svc.Name] = d
+	}
+
+	batchMaxConcurrency := settings.BatchMaxConcurrency
+	if batchMaxConcurrency <= 0 {
+		batchMaxConcurrency = 10
+	}
+	batchMaxSize := settings.BatchMaxSize
+	if batchMaxSize <= 0 {
+		batchMaxSize = 100
+	}
+
+	var bundle *SupergraphBundle
+	if settings.SupergraphFile != "" {
+		bundle, err = LoadSupergraphBundle(settings.SupergraphFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	sdls := make(map[string]string, len(settings.Services))
 	hosts := make(map[string]string, len(settings.Services))
 	retryOptions := make(map[string]RetryOption, len(settings.Services))
+	schemaCaches := make(map[string]string, len(settings.Services))
+	sources := make(map[string]registry.SchemaSource, len(settings.Services))
+	pollIntervals := make(map[string]time.Duration, len(settings.Services))
 
 	for _, svc := range settings.Services {
 		hosts[svc.Name] = svc.Host
 		retryOptions[svc.Name] = svc.Retry
+		schemaCaches[svc.Name] = svc.SchemaCache
 
-		sdl, err := fetchSDL(svc.Host, httpClient, svc.Retry)
+		src, err := settings.schemaSource(svc, subGraphClients[svc.Name])
+		if err != nil {
+			return nil, err
+		}
+		sources[svc.Name] = src
+		if svc.Source != nil {
+			interval, err := svc.Source.Interval()
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema source for service %q: %w", svc.Name, err)
+			}
+			pollIntervals[svc.Name] = interval
+		}
+
+		if sdl, ok := bundle.sdl(svc.Name); ok {
+			sdls[svc.Name] = sdl
+			continue
+		}
+		sdl, err := loadSDL(svc, src)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch SDL for service %q: %w", svc.Name, err)
 		}
 		sdls[svc.Name] = sdl
 	}
 
-	engine, err := buildEngine(sdls, hosts, httpClient)
+	schemaHealth, err := newSchemaHealth(settings.Services, sources, pollIntervals, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerStrategy, err := planner.ParseOwnerStrategy(settings.OwnerStrategy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner_strategy: %w", err)
+	}
+
+	engine, err := buildCachedEngine(sdls, hosts, httpClient, settings.CompositionCache)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build execution engine: %w", err)
 	}
+	costModel := newCostModel(settings)
+	engine.planner.OwnerStrategy = ownerStrategy
+	engine.planner.CostModel = costModel
+	engine.planner.PruneUnfetchable = settings.PruneUnfetchableFields
+	engine.executor.SubGraphClients = subGraphClients
+
+	failover, err := newFailover(settings.Services, labels)
+	if err != nil {
+		return nil, err
+	}
+	engine.executor.Failover = failover
+
+	endpoints, err := newEndpoints(settings.Services)
+	if err != nil {
+		return nil, err
+	}
+	engine.executor.Endpoints = endpoints
+	entityBatching := newEntityBatching(settings)
+	engine.executor.EntityBatching = entityBatching
+
+	limiter, err := newRequestLimiter(settings, labels)
+	if err != nil {
+		return nil, err
+	}
+	engine.executor.Limiter = limiter
+
+	faults, err := newFaultInjector(settings)
+	if err != nil {
+		return nil, err
+	}
+	engine.executor.Faults = faults
+
+	verifier, err := newResponseVerifier(settings, labels)
+	if err != nil {
+		return nil, err
+	}
+	engine.executor.Verifier = verifier
+	engine.executor.SharedFragments = settings.SubgraphFragments
+
+	errorMappings, err := newErrorMappings(settings.Services)
+	if err != nil {
+		return nil, err
+	}
+	engine.executor.ErrorMapping = errorMappings
+
+	entityCache, err := newEntityCache(settings.EntityCache)
+	if err != nil {
+		return nil, err
+	}
+	engine.executor.EntityCache = entityCache
+
+	pinnedPlans, err := newPinnedPlans(settings.PinnedPlans, settings.PinnedPlanStore, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	degradation, err := newDegradation(settings.Degradation)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaEndpoint := newSchemaEndpoint(settings.SchemaEndpoint)
+	openAPI, err := marshalOpenAPI(schemaEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the admin routes: %w", err)
+	}
+
+	planCache, err := newPlanCache(settings.PlanCache, settings.PlanCacheStore)
+	if err != nil {
+		return nil, err
+	}
+
+	deprecatedUsage, err := newDeprecatedUsageTracker(settings.DeprecatedUsage, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	cancellations, err := newCancellationCounter(labels)
+	if err != nil {
+		return nil, err
+	}
+	timeouts, err := newTimeoutCounter(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	responseTransforms, err := newResponseTransforms(settings.ResponseTransforms, settings.ResponseTransformers)
+	if err != nil {
+		return nil, err
+	}
+
+	computedFields, err := newComputedFields(settings.ComputedFields)
+	if err != nil {
+		return nil, err
+	}
+
+	relayNode, err := newRelayNode(settings.RelayNode, settings.NodeIDCodec)
+	if err != nil {
+		return nil, err
+	}
+
+	mock, err := newMocker(settings.Mock)
+	if err != nil {
+		return nil, err
+	}
+
+	persistedOperations, err := loadOperationManifest(settings.PersistedOperations.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	cors, err := newCORSPolicy(settings.CORS)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := newSubscriptionServer(settings.Subscriptions, settings.SubscriptionAuthenticator)
+	if err != nil {
+		return nil, err
+	}
+
+	listSizes, err := newListSizeGuard(settings.ListSizeLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	accessLog, err := newAccessLogger(settings.AccessLog)
+	if err != nil {
+		return nil, err
+	}
+
+	sanitizer := newQuerySanitizer(settings.QuerySanitization)
+	usage, err := newUsageReporter(settings.UsageReporting, httpClient, sanitizer)
+	if err != nil {
+		return nil, err
+	}
 
 	store := &schemaStore{sdls: sdls, hosts: hosts, engine: engine}
 
+	var overrideLabels OverrideLabelProvider = staticOverrideLabels(settings.OverrideLabels)
+	if settings.OverrideLabelProvider != nil {
+		overrideLabels = settings.OverrideLabelProvider
+	}
+
+	scalars := settings.Scalars
+	if scalars == nil {
+		scalars = NewScalarRegistry()
+	}
+	if err := scalars.RegisterBuiltins(settings.BuiltinScalars...); err != nil {
+		return nil, fmt.Errorf("invalid builtin_scalars: %w", err)
+	}
+
 	gw := &gateway{
 		graphQLEndpoint:             settings.Endpoint,
 		serviceName:                 settings.ServiceName,
 		requestTimeout:              requestTimeout,
 		httpClient:                  httpClient,
+		subGraphClients:             subGraphClients,
+		failover:                    failover,
+		endpoints:                   endpoints,
+		entityBatching:              entityBatching,
+		limiter:                     limiter,
+		faults:                      faults,
+		verifier:                    verifier,
+		subgraphFragments:           settings.SubgraphFragments,
+		cancellations:               cancellations,
+		timeouts:                    timeouts,
+		sources:                     sources,
+		schemaHealth:                schemaHealth,
 		retryOptions:                retryOptions,
+		schemaCaches:                schemaCaches,
+		compositionCache:            settings.CompositionCache,
 		enableComplementRequestId:   true,
 		enableHangOverRequestHeader: settings.EnableHangOverRequestHeader,
 		enableOpentelemetryTracing:  settings.Opentelemetry.TracingSetting.Enable,
+		enableSubgraphMode:          settings.EnableSubgraphMode,
+		disableIntrospection:        settings.DisableIntrospection,
+		suppressSuggestions:         settings.SuppressSuggestions,
+		graphQLPolicy:               newRequestPolicy(settings.HTTPPolicy.GraphQL),
+		adminPolicy:                 newRequestPolicy(settings.HTTPPolicy.Admin),
+		cors:                        cors,
+		forwardExtensions:           newExtensionPropagation(settings.ForwardExtensions),
+		scalars:                     scalars,
+		operationTimeouts:           operationTimeouts,
+		planningTimeout:             planningTimeout,
+		subgraphTimeouts:            subgraphTimeouts,
+		softDeadline:                settings.OperationTimeout.SoftDeadline,
+		batchMaxConcurrency:         batchMaxConcurrency,
+		batchMaxSize:                batchMaxSize,
+		enableFederatedTracing:      settings.FederatedTracing.Enable,
+		includeTraceInResponse:      settings.FederatedTracing.IncludeInResponse,
+		traceReporter:               newTraceReporter(settings.FederatedTracing, httpClient),
+		costModel:                   costModel,
+		pruneUnfetchable:            settings.PruneUnfetchableFields,
+		ownerStrategy:               ownerStrategy,
+		overrideLabels:              overrideLabels,
+		entityCache:                 entityCache,
+		pinnedPlans:                 pinnedPlans,
+		planCache:                   planCache,
+		errorMappings:               errorMappings,
+		extensionProviders:          newExtensionProviders(settings.ResponseExtensions, settings.ExtensionProviders),
+		costHeaders:                 settings.CostHeaders,
+		costBudget:                  settings.CostBudget,
+		allowExplain:                settings.AllowExplain,
+		keepTypename:                settings.KeepTypename,
+		dryRun:                      newDryRun(settings.DryRun, settings.Services),
+		deprecatedUsage:             deprecatedUsage,
+		responseTransforms:          responseTransforms,
+		computedFields:              computedFields,
+		relayNode:                   relayNode,
+		mock:                        mock,
+		subgraphRequests:            newSubgraphRequestCapture(settings.ResponseExtensions, settings.LogSubgraphRequests, sanitizer),
+		mergeTracing:                newMergeTracing(settings.ResponseExtensions),
+		schemaEndpoint:              schemaEndpoint,
+		openAPI:                     openAPI,
+		persistedOperations:         persistedOperations,
+		subscriptions:               subscriptions,
+		fieldRateLimits:             newFieldRateLimiter(settings.FieldRateLimits),
+		clientIdentity:              newClientIdentity(settings.ClientIdentity),
+		degradation:                 degradation,
+		listSizes:                   listSizes,
+		accessLog:                   accessLog,
+		usage:                       usage,
+		sanitizer:                   sanitizer,
+	}
+	if err := gw.warmPlans(engine); err != nil {
+		return nil, fmt.Errorf("failed to plan persisted operations: %w", err)
 	}
 	gw.currentSchema.Store(store)
 
+	gw.schemaRegistry = settings.SchemaRegistry
+	if gw.schemaRegistry == nil {
+		gw.schemaRegistry, err = registry.NewStore(settings.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure schema registry: %w", err)
+		}
+	}
+	if err := gw.syncSchemaRegistry(); err != nil {
+		return nil, err
+	}
+
+	gw.startWatchers(pollIntervals)
+
 	return gw, nil
 }
 
+// syncSchemaRegistry installs the current registry version, or publishes the local
+// schema when the registry is empty. A version that does not compose locally is logged
+// and the local schema is kept.
+func (g *gateway) syncSchemaRegistry() error {
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	current, err := g.schemaRegistry.Current(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read schema registry: %w", err)
+	}
+	if current == nil {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.publishSchema(g.currentStore().sdls)
+		return nil
+	}
+	if err := g.installVersion(current); err != nil {
+		log.Printf("failed to install schema version %d from the registry: %v", current.Version, err)
+	}
+	return nil
+}
+
+// startWatchers polls every source with a poll interval and applies its SDL when it
+// changes, and installs the versions other replicas publish to the schema registry.
+func (g *gateway) startWatchers(intervals map[string]time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.stopWatchers = cancel
+
+	go g.schemaRegistry.Subscribe(ctx, func(v *registry.SchemaVersion) { //nolint:errcheck
+		if err := g.installVersion(v); err != nil {
+			log.Printf("failed to install schema version %d from the registry: %v", v.Version, err)
+		}
+	})
+
+	sdls := g.currentStore().sdls
+	for name, interval := range intervals {
+		if interval <= 0 {
+			continue
+		}
+		go registry.Watch(ctx, g.sources[name], interval, sdls[name], func(sdl string) {
+			if err := g.applySDL(name, sdl); err != nil {
+				log.Printf("schema apply failed for %q: %v", name, err)
+			}
+		})
+	}
+}
+
+// Close stops polling the schema sources and disconnects from the schema registry.
+func (g *gateway) Close() error {
+	g.stopWatchers()
+	g.subscriptions.closeAll(websocket.CloseGoingAway, "gateway shutting down")
+	if err := g.schemaHealth.close(); err != nil {
+		return err
+	}
+	g.usage.close()
+	if err := g.accessLog.close(); err != nil {
+		return err
+	}
+	if err := g.planCache.close(); err != nil {
+		return err
+	}
+	return g.schemaRegistry.Close()
+}
+
 // graphQLRequest is the body of an incoming GraphQL request.
 type graphQLRequest struct {
-	Query     string         `json:"query"`
-	Variables map[string]any `json:"variables"`
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    map[string]any `json:"extensions"`
+}
+
+// decodeRequest decodes a request body into v, keeping numbers as json.Number so
+// 64-bit integer variables reach subgraphs without being rounded to float64.
+func decodeRequest(body []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	return dec.Decode(v)
 }
 
 // currentStore returns the active *schemaStore. It panics if nothing has been stored
@@ -150,20 +938,34 @@ func (g *gateway) currentStore() *schemaStore {
 	return g.currentSchema.Load().(*schemaStore)
 }
 
-// ServeHTTP dispatches incoming HTTP requests.
-// POST /{name}/apply  → schema update endpoint
-// POST /*             → GraphQL endpoint
+// ServeHTTP dispatches incoming HTTP requests after answering CORS preflights and
+// applying the endpoint's HTTP policy.
+// adminRoutes                    → admin endpoints, described at GET /admin/openapi.json
+// GET  /schema.graphql           → composed schema SDL (schema_endpoint.path)
+// GET  /* with Upgrade: websocket → GraphQL over WebSocket (subscriptions.enable)
+// POST /*                        → GraphQL endpoint
 func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Route schema-update requests before the method check so apply always works.
-	if r.Method == http.MethodPost {
-		path := strings.TrimPrefix(r.URL.Path, "/")
-		if strings.HasSuffix(path, "/apply") {
-			name := strings.TrimSuffix(path, "/apply")
-			if name != "" {
-				g.handleApply(w, name)
-				return
-			}
-		}
+	if g.cors != nil && g.cors.handle(w, r) {
+		return
+	}
+	// WebSocket connections outlive schema updates, so they are not in flight.
+	if g.subscriptions != nil && websocket.IsUpgrade(r) {
+		g.serveSubscriptions(w, r)
+		return
+	}
+	// Schema tools are not browsers, so the request policy does not apply.
+	if g.schemaEndpoint.matches(r) {
+		g.handleSchema(w, r)
+		return
+	}
+	if !g.enforceRequestPolicy(w, r) {
+		return
+	}
+
+	// Route admin requests before the method check so apply always works.
+	if route := matchAdminRoute(r); route != nil {
+		route.serve(g, w, r)
+		return
 	}
 
 	if r.Method != http.MethodPost {
@@ -175,67 +977,446 @@ func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	g.inFlight.Add(1)
 	defer g.inFlight.Done()
 
+	w, access := g.accessLog.begin(w, r)
+	defer access.finish()
+
 	// Snapshot the engine before processing so a concurrent schema swap
 	// does not affect this request mid-flight.
 	store := g.currentStore()
 	engine := store.engine
 
-	var req graphQLRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	ctx := r.Context()
+	ctx := g.clientIdentity.withClient(r.Context(), r)
+	access.setClient(ClientInfoFromContext(ctx))
 	if g.enableHangOverRequestHeader {
 		ctx = executor.SetRequestHeaderToContext(ctx, r.Header)
 	}
+	if g.deprecatedUsage != nil {
+		ctx = g.deprecatedUsage.withClientName(ctx, r)
+	}
+	ctx = g.subgraphRequests.withRequest(ctx, r)
+	ctx = g.mergeTracing.withRequest(ctx, r)
+	ctx = g.responseTransforms.withRequest(ctx, r)
+	ctx = g.fieldRateLimits.withClient(ctx, r)
+	ctx = g.usage.withClient(ctx, r)
+	ctx = g.dryRun.withRequest(ctx, r)
+	ctx = g.degradation.withRequest(ctx, r)
+	ctx = withResponseMediaType(ctx, r)
+
+	var report *costReport
+	if g.costHeaders || access != nil {
+		ctx, report = withCostReport(ctx)
+		if access != nil {
+			access.subgraphRequests = report
+		}
+	}
 
-	l := lexer.New(req.Query)
-	p := parser.New(l)
-	doc := p.ParseDocument()
-	if len(p.Errors()) > 0 {
+	// Some clients send a JSON array of operations in a single POST.
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []graphQLRequest
+		if err := decodeRequest(trimmed, &reqs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(reqs) > g.batchMaxSize {
+			status, resp := requestError(ctx, CodeBatchTooLarge, fmt.Sprintf("batch of %d operations exceeds the limit of %d", len(reqs), g.batchMaxSize))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(executor.OrderedResponse{Response: resp}) //nolint:errcheck
+			return
+		}
+		for _, req := range reqs {
+			access.addOperation(req)
+		}
+		responses := g.executeBatch(ctx, engine, reqs)
+		if g.costHeaders {
+			report.setHeaders(w.Header())
+		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
-			"errors": p.Errors(),
-		})
+		json.NewEncoder(w).Encode(responses) //nolint:errcheck
 		return
 	}
 
-	// Validate @inaccessible fields using the snapshot engine.
-	if err := g.validateAccessibility(doc, engine); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
-			"errors": []map[string]any{
-				{
-					"message":    err.Error(),
-					"extensions": map[string]string{"code": "INACCESSIBLE_FIELD"},
-				},
-			},
-		})
+	var req graphQLRequest
+	if err := decodeRequest(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	plan, err := engine.planner.Plan(doc, req.Variables)
-	if err != nil {
+	access.addOperation(req)
+	status, resp := g.executeRequest(ctx, engine, req)
+	if g.costHeaders {
+		report.setHeaders(w.Header())
+	}
+	if usesGraphQLResponse(ctx) {
+		w.Header().Set("Content-Type", graphQLResponseMediaType)
+	} else {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
-			"errors": []string{err.Error()},
-		})
-		return
 	}
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+	json.NewEncoder(w).Encode(resp) //nolint:errcheck
+}
 
-	resp, err := engine.executor.Execute(ctx, plan, req.Variables)
+// executeRequest parses, validates, plans and executes a single GraphQL request
+// against engine. It returns the HTTP status and the response body to encode, which
+// always encodes in canonical key order (see executor.OrderedResponse).
+func (g *gateway) executeRequest(ctx context.Context, engine *executionEngine, req graphQLRequest) (int, any) {
+	status, resp := g.execute(ctx, engine, req)
+	if m, ok := resp.(map[string]any); ok {
+		return status, executor.OrderedResponse{Response: m}
+	}
+	return status, resp
+}
+
+// execute implements executeRequest.
+func (g *gateway) execute(ctx context.Context, engine *executionEngine, req graphQLRequest) (int, any) {
+	start := time.Now()
+	// The gateway keeps no persisted queries, so a client sending only the hash must
+	// retry with the query text.
+	if _, ok := req.Extensions["persistedQuery"]; ok && req.Query == "" {
+		return requestError(ctx, CodePersistedQueryNotFound, "PersistedQueryNotFound")
+	}
+
+	validatedOp, status, errResp := g.validateOperation(ctx, engine, req)
+	if validatedOp == nil {
+		return status, errResp
+	}
+	doc, op, parsed := validatedOp.doc, validatedOp.op, validatedOp.parsed
+	req.Variables = validatedOp.variables
+	validated := time.Now()
+
+	// Serve _service / _entities when the gateway is composed as a subgraph, once the
+	// operation passed the same validation and deadline as any other query.
+	if g.enableSubgraphMode {
+		subgraphCtx := ctx
+		if timeout, ok := g.operationTimeouts[string(ast.Query)]; ok {
+			var cancel context.CancelFunc
+			subgraphCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		resp, handled, err := g.resolveFederationFields(subgraphCtx, doc, req.Variables, engine)
+		if handled {
+			if errors.Is(subgraphCtx.Err(), context.DeadlineExceeded) && !g.softDeadline {
+				return deadlineExceeded(string(ast.Query))
+			}
+			if err != nil {
+				return requestError(ctx, CodeBadUserInput, err.Error())
+			}
+			return http.StatusOK, executor.OrderedResponse{Response: resp, Document: doc}
+		}
+	}
+
+	// The Relay node field is served by the gateway unless a subgraph declares it.
+	nodeOperation, err := g.relayNode.handles(op, engine)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
-			"errors": []string{err.Error()},
+		return requestError(ctx, CodeValidationFailed, err.Error())
+	}
+
+	if g.deprecatedUsage != nil {
+		g.deprecatedUsage.record(ctx, operationNameOf(op), deprecatedFieldUsages(doc, engine))
+	}
+
+	// In mock mode the operation is answered without planning or calling subgraphs.
+	if g.mock != nil {
+		resp := g.mock.resolve(doc, op, engine)
+		g.responseTransforms.apply(ctx, resp, doc, op, engine)
+		return http.StatusOK, executor.OrderedResponse{Response: resp, Document: doc}
+	}
+
+	planDoc, planOp, limited, err := g.limitOperation(ctx, engine, doc, op, req.Variables)
+	if err != nil {
+		return requestError(ctx, CodeListSizeExceeded, err.Error())
+	}
+	if planDoc == nil {
+		resp := map[string]any{}
+		addLimitedFields(resp, limited)
+		return http.StatusOK, executor.OrderedResponse{Response: resp, Document: doc}
+	}
+
+	// Computed fields are planned as the fields their expressions read, and set once
+	// the response is merged.
+	planDoc, computed := g.computedFields.rewrite(planDoc, req.OperationName, engine)
+
+	if nodeOperation {
+		return g.executeNode(ctx, engine, req, doc, op, planDoc, limited, computed, start, ExecutionTiming{
+			Parse:    parsed.Sub(start),
+			Validate: validated.Sub(parsed),
 		})
-		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	// Route progressive @override fields according to the labels active for this request.
+	queryPlanner := engine.planner
+	if labels := engine.superGraph.OverrideLabels(); len(labels) > 0 {
+		queryPlanner = queryPlanner.WithOverrideLabels(g.evaluateOverrideLabels(ctx, labels))
+	}
+
+	// Execute the reviewed plan pinned for the operation, if any.
+	var plan *planner.PlanV2
+	if g.pinnedPlans != nil && planDoc == doc {
+		plan = g.pinnedPlans.lookup(ctx, engine, req.Query, operationNameOf(op))
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("graphql.plan.pinned", plan != nil))
+	}
+	// Persisted operations were planned when the schema was installed, without override labels.
+	if plan == nil && queryPlanner == engine.planner && planDoc == doc {
+		plan = engine.warmedPlan(req.Query, operationNameOf(op))
+	}
+	// Other operations are cached on the same terms, in memory and in the shared cache.
+	cachePlan := g.planCache != nil && queryPlanner == engine.planner && planDoc == doc
+	if plan == nil && cachePlan {
+		plan = g.planCache.lookup(ctx, engine, req.Query, operationNameOf(op))
+	}
+	if plan == nil {
+		plan, err = g.plan(ctx, queryPlanner, planDoc, req.Variables)
+		if errors.Is(err, context.DeadlineExceeded) {
+			g.timeouts.record(ctx, timeoutPhasePlanning, string(planOp.Operation))
+			return deadlineExceeded("query planning")
+		}
+		if err != nil {
+			return requestError(ctx, CodePlanError, err.Error())
+		}
+		if cachePlan {
+			g.planCache.store(ctx, engine, req.Query, operationNameOf(op), plan)
+		}
+	}
+	planned := time.Now()
+
+	// Like SQL EXPLAIN, return the estimated costs of the plan without executing it.
+	if explain, _ := req.Extensions["explain"].(bool); explain && g.allowExplain {
+		return http.StatusOK, map[string]any{
+			"extensions": map[string]any{"explain": queryPlanner.Explain(plan)},
+		}
+	}
+
+	// A dry run only executes when every subgraph of the mutation supports it.
+	var dryRunSubgraphs []string
+	dryRun := g.dryRun.applies(ctx, plan)
+	if dryRun {
+		var unsupported []string
+		dryRunSubgraphs, unsupported = g.dryRun.subgraphs(plan)
+		if len(unsupported) > 0 {
+			return g.dryRun.refuse(ctx, dryRunSubgraphs, unsupported)
+		}
+	}
+
+	execCtx := executor.SetSubgraphTimeoutsToContext(ctx, g.subgraphTimeouts)
+	if g.enableFederatedTracing {
+		execCtx = executor.SetFederatedTracingToContext(execCtx)
+	}
+	if keep, _ := req.Extensions["keepTypename"].(bool); keep || g.keepTypename {
+		execCtx = executor.SetKeepTypenameToContext(execCtx)
+	}
+	if g.forwardExtensions != nil {
+		execCtx = g.forwardExtensions.apply(execCtx, req.Extensions)
+	}
+	if dryRun {
+		execCtx = g.dryRun.withSubgraphHeader(execCtx)
+	}
+	var stats *executor.ExecutionStats
+	if len(g.extensionProviders) > 0 || hasCostReport(ctx) || g.usage != nil {
+		stats = executor.NewExecutionStats()
+		execCtx = executor.SetExecutionStatsToContext(execCtx, stats)
+	}
+	execCtx, requestLog := g.subgraphRequests.start(execCtx)
+	execCtx, mergeTrace := g.mergeTracing.start(execCtx)
+	execCtx = g.degradation.start(execCtx)
+	if timeout, ok := g.operationTimeouts[plan.OperationType]; ok {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeout(execCtx, timeout)
+		defer cancel()
+	}
+
+	resp, err := engine.executor.Execute(execCtx, plan, req.Variables)
+	subgraphRequests := g.subgraphRequests.finish(ctx, operationNameOf(op), requestLog)
+	if errors.Is(err, executor.ErrOperationCancelled) {
+		g.cancellations.record(ctx, plan.OperationType)
+		return statusClientClosedRequest, map[string]any{
+			"errors": []map[string]any{{"message": err.Error()}},
+		}
+	}
+	// Only returned with a degradation mode: every root step failed.
+	var failure *executor.PlanFailedError
+	if errors.As(err, &failure) {
+		return g.degradation.respond(ctx, req, plan.OperationType, failure)
+	}
+	if err != nil {
+		return requestError(ctx, CodeInternalServerError, err.Error())
+	}
+	executed := time.Now()
+
+	g.completeResponse(ctx, resp, doc, op, engine, limited, computed)
+	g.degradation.store(ctx, req, plan.OperationType, resp)
+
+	if g.enableFederatedTracing {
+		g.handleFederatedTrace(resp)
+	}
+
+	// Without a soft deadline an expired operation deadline is reported as 504;
+	// otherwise the partial response already carries the timeout errors.
+	if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		g.timeouts.record(ctx, timeoutPhaseExecution, plan.OperationType)
+		if !g.softDeadline {
+			return deadlineExceeded(plan.OperationType)
+		}
+	}
+
+	if stats != nil {
+		info := &ExecutionInfo{
+			OperationName: operationNameOf(op),
+			OperationType: plan.OperationType,
+			Document:      doc,
+			Client:        ClientInfoFromContext(ctx),
+			Timing: ExecutionTiming{
+				Parse:    parsed.Sub(start),
+				Validate: validated.Sub(parsed),
+				Plan:     planned.Sub(validated),
+				Execute:  executed.Sub(planned),
+				Total:    time.Since(start),
+			},
+			Stats:            stats,
+			Cost:             queryPlanner.EstimateCost(plan),
+			engine:           engine,
+			subgraphRequests: subgraphRequests,
+			mergeTrace:       mergeTrace,
+		}
+		g.reportExecution(ctx, resp, info)
+	}
+
+	if dryRun {
+		g.dryRun.addExtension(resp, dryRunSubgraphs)
+	}
+
+	// Encode root and nested fields in the client's document order.
+	return http.StatusOK, executor.OrderedResponse{Response: resp, Document: doc}
+}
+
+// validatedOperation is the operation of a request, parsed and validated.
+type validatedOperation struct {
+	doc       *ast.Document
+	op        *ast.OperationDefinition
+	variables map[string]any // Coerced by the custom scalars
+	parsed    time.Time      // When the document was parsed
+}
+
+// validateOperation parses req, selects its operation and validates it against engine:
+// introspection when it is disabled, @inaccessible fields and custom scalar inputs. It
+// returns nil with the status and body of the error response when req is invalid. The HTTP
+// and WebSocket transports validate their operations with it.
+func (g *gateway) validateOperation(ctx context.Context, engine *executionEngine, req graphQLRequest) (*validatedOperation, int, map[string]any) {
+	p := parser.New(lexer.New(req.Query))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		status, resp := requestError(ctx, CodeParseFailed, p.Errors()...)
+		return nil, status, resp
+	}
+
+	doc, op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		status, resp := requestError(ctx, CodeValidationFailed, err.Error())
+		return nil, status, resp
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("graphql.operation.name", operationNameOf(op)),
+		attribute.String("graphql.operation.type", string(op.Operation)),
+	)
+	if g.sanitizer != nil && g.sanitizer.spanDocument {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("graphql.document", g.sanitizer.query(req.Query)))
+	}
+	trace.SpanFromContext(ctx).SetAttributes(ClientInfoFromContext(ctx).attributes()...)
+	trace.SpanFromContext(ctx).SetAttributes(contextValueAttributes(ctx)...)
+	parsed := time.Now()
+
+	if g.disableIntrospection && isIntrospectionQuery(doc, op) {
+		status, resp := requestError(ctx, introspectionDisabledCode, "GraphQL introspection is not allowed")
+		return nil, status, resp
+	}
+
+	// Validate @inaccessible fields using the snapshot engine.
+	if err := g.validateAccessibility(doc, engine); err != nil {
+		status, resp := requestError(ctx, CodeInaccessibleField, err.Error())
+		return nil, status, resp
+	}
+
+	// Validate custom scalar inputs and forward the coerced variables.
+	variables, err := g.validateScalars(doc, req.Variables, engine)
+	if err != nil {
+		status, resp := requestError(ctx, CodeBadUserInput, err.Error())
+		return nil, status, resp
+	}
+	return &validatedOperation{doc: doc, op: op, variables: variables, parsed: parsed}, 0, nil
+}
+
+// limitOperation applies the limits on root fields to op of doc before planning. It
+// returns the document and operation to plan, and the root fields dropped by rate
+// limits; the document is nil when every root field was dropped. An error rejects the
+// operation.
+func (g *gateway) limitOperation(ctx context.Context, engine *executionEngine, doc *ast.Document, op *ast.OperationDefinition, variables map[string]any) (*ast.Document, *ast.OperationDefinition, []limitedField, error) {
+	// Unbounded @listSizeLimit root fields are rejected, or given their maximum size.
+	planDoc, planOp, err := g.listSizes.apply(doc, op, variables, engine)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Root fields over their @rateLimit for this client are dropped before planning
+	// and resolve to null with an error.
+	planDoc, limited := g.fieldRateLimits.apply(ctx, planDoc, planOp, engine)
+	return planDoc, planOp, limited, nil
+}
+
+// completeResponse completes resp, the response to op of doc: it adds the fields dropped
+// by rate limits, sets the computed fields and applies the response transforms and the
+// suppression of suggestions.
+func (g *gateway) completeResponse(ctx context.Context, resp map[string]any, doc *ast.Document, op *ast.OperationDefinition, engine *executionEngine, limited []limitedField, computed bool) {
+	if len(limited) > 0 {
+		addLimitedFields(resp, limited)
+	}
+	if computed {
+		g.computedFields.apply(resp, doc, op, engine)
+	}
+	g.responseTransforms.apply(ctx, resp, doc, op, engine)
+
+	if g.suppressSuggestions {
+		suppressSuggestions(resp)
+	}
+}
+
+// reportExecution records the cost and usage of an executed operation and adds the
+// extensions of info to resp.
+func (g *gateway) reportExecution(ctx context.Context, resp map[string]any, info *ExecutionInfo) {
+	if g.costBudget != nil {
+		info.remainingBudget, info.hasBudget = g.costBudget.RemainingBudget(ctx, info)
+	}
+	recordCost(ctx, info)
+	g.usage.record(ctx, info, resp)
+	g.applyExtensions(ctx, resp, info)
+}
+
+// plan plans doc within the planning timeout, if any.
+func (g *gateway) plan(ctx context.Context, queryPlanner *planner.PlannerV2, doc *ast.Document, variables map[string]any) (*planner.PlanV2, error) {
+	if g.planningTimeout <= 0 {
+		return queryPlanner.Plan(doc, variables)
+	}
+	planCtx, cancel := context.WithTimeout(ctx, g.planningTimeout)
+	defer cancel()
+	return queryPlanner.PlanContext(planCtx, doc, variables)
+}
+
+// deadlineExceeded is the 504 response to an operation that exceeded its deadline.
+func deadlineExceeded(operationType string) (int, any) {
+	return http.StatusGatewayTimeout, map[string]any{
+		"errors": []map[string]any{
+			{
+				"message":    fmt.Sprintf("%s exceeded its deadline", operationType),
+				"extensions": map[string]string{"code": executor.TimeoutErrorCode},
+			},
+		},
+	}
 }
 
 // handleApply processes a POST /{name}/apply request from a subgraph.
@@ -253,13 +1434,26 @@ func (g *gateway) handleApply(w http.ResponseWriter, name string) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
+	json.NewEncoder(w).Encode(okResponse{OK: true}) //nolint:errcheck
 }
 
-// applySubgraph fetches a fresh SDL for the named subgraph, recomposes the supergraph,
-// waits for currently in-flight requests to complete, and atomically installs the
-// new schema.  A previous schema is kept for panic-time rollback.
-func (g *gateway) applySubgraph(name string) (retErr error) {
+// applySubgraph fetches a fresh SDL for the named subgraph from its schema source and
+// applies it with applySDL.
+func (g *gateway) applySubgraph(name string) error {
+	src, ok := g.sources[name]
+	if !ok {
+		return fmt.Errorf("unknown subgraph %q", name)
+	}
+	newSDL, err := fetchWithRetry(context.Background(), src, g.retryOptions[name])
+	if err != nil {
+		return fmt.Errorf("SDL fetch failed: %w", err)
+	}
+	return g.applySDL(name, newSDL)
+}
+
+// applySDL recomposes the supergraph with newSDL for the named subgraph, installs it
+// with installSDLs and publishes it to the schema registry.
+func (g *gateway) applySDL(name, newSDL string) (retErr error) {
 	// Panic recovery: if anything panics during composition or swap, roll back.
 	defer func() {
 		if r := recover(); r != nil {
@@ -273,22 +1467,77 @@ func (g *gateway) applySubgraph(name string) (retErr error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	current := g.currentStore()
+	newSDLs := copyMap(g.currentStore().sdls)
+	newSDLs[name] = newSDL
 
-	retry := g.retryOptions[name]
-	newSDL, err := fetchSDL(current.hosts[name], g.httpClient, retry)
-	if err != nil {
-		return fmt.Errorf("SDL fetch failed: %w", err)
+	if err := g.installSDLs(newSDLs); err != nil {
+		return err
 	}
+	writeSchemaCache(g.schemaCaches[name], newSDL)
+	g.publishSchema(newSDLs)
+	return nil
+}
 
-	newSDLs := copyMap(current.sdls)
-	newSDLs[name] = newSDL
+// installVersion installs a schema version from the registry unless it is already
+// installed. Versions naming subgraphs this replica has no host for are rejected.
+func (g *gateway) installVersion(v *registry.SchemaVersion) (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic during installation of schema version %d: %v — rolling back", v.Version, r)
+			g.rollbackToPreviousSchema()
+			retErr = fmt.Errorf("panic during schema application: %v", r)
+		}
+	}()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	newEngine, err := buildEngine(newSDLs, current.hosts, g.httpClient)
+	if v.Version == g.schemaVersion {
+		return nil
+	}
+	current := g.currentStore()
+	for name := range v.SDLs {
+		if _, ok := current.hosts[name]; !ok {
+			return fmt.Errorf("schema version %d contains unknown subgraph %q", v.Version, name)
+		}
+	}
+	if registry.SchemaHash(current.sdls) != registry.SchemaHash(v.SDLs) {
+		if err := g.installSDLs(copyMap(v.SDLs)); err != nil {
+			return err
+		}
+	}
+	g.schemaVersion = v.Version
+	return nil
+}
+
+// installSDLs composes newSDLs, recomposing only what an updated subgraph changed, waits
+// for currently in-flight requests to complete, and atomically installs the new schema. A previous schema is kept for panic-time rollback.
+// The caller must hold g.mu.
+func (g *gateway) installSDLs(newSDLs map[string]string) error {
+	current := g.currentStore()
+
+	newEngine, err := rebuildEngine(current, newSDLs, g.httpClient, g.compositionCache)
 	if err != nil {
 		// Composition failed — current schema stays, treated as rollback.
 		return fmt.Errorf("composition failed: %w", err)
 	}
+	newEngine.planner.CostModel = g.costModel
+	newEngine.planner.PruneUnfetchable = g.pruneUnfetchable
+	newEngine.planner.OwnerStrategy = g.ownerStrategy
+	newEngine.executor.EntityCache = g.entityCache
+	newEngine.executor.SubGraphClients = g.subGraphClients
+	newEngine.executor.Failover = g.failover
+	newEngine.executor.Endpoints = g.endpoints
+	newEngine.executor.EntityBatching = g.entityBatching
+	newEngine.executor.Limiter = g.limiter
+	newEngine.executor.Faults = g.faults
+	newEngine.executor.Verifier = g.verifier
+	newEngine.executor.SharedFragments = g.subgraphFragments
+	newEngine.executor.ErrorMapping = g.errorMappings
+	if err := g.warmPlans(newEngine); err != nil {
+		// The new schema breaks persisted operations — current schema stays.
+		return fmt.Errorf("failed to plan persisted operations: %w", err)
+	}
 
 	// Wait for in-flight requests to drain before swapping.
 	done := make(chan struct{})
@@ -306,9 +1555,30 @@ func (g *gateway) applySubgraph(name string) (retErr error) {
 	newStore := &schemaStore{sdls: newSDLs, hosts: current.hosts, engine: newEngine}
 	g.previousSchema.Store(g.currentSchema.Load())
 	g.currentSchema.Store(newStore)
+
+	// Cached entities may no longer match the updated subgraph.
+	if g.entityCache != nil {
+		g.entityCache.Purge()
+	}
+	// Subscriptions were planned against the old schema; clients resubscribe.
+	g.subscriptions.closeAll(websocket.CloseServiceRestart, "schema reloaded")
 	return nil
 }
 
+// publishSchema publishes sdls to the schema registry so other replicas install them.
+// Failures are logged: the schema is already applied locally. The caller must hold g.mu.
+func (g *gateway) publishSchema(sdls map[string]string) {
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	v, err := g.schemaRegistry.Publish(ctx, sdls)
+	if err != nil {
+		log.Printf("failed to publish schema to the registry: %v", err)
+		return
+	}
+	g.schemaVersion = v.Version
+}
+
 // rollbackToPreviousSchema restores the last known-good schema.
 // It is a no-op when no previous schema has been stored.
 func (g *gateway) rollbackToPreviousSchema() {
@@ -331,17 +1601,14 @@ func (g *gateway) Start(port int) error {
 func (g *gateway) validateAccessibility(doc *ast.Document, engine *executionEngine) error {
 	for _, def := range doc.Definitions {
 		if opDef, ok := def.(*ast.OperationDefinition); ok {
-			rootTypeName := "Query"
-			switch opDef.Operation {
-			case ast.Query:
-				rootTypeName = "Query"
-			case ast.Mutation:
-				rootTypeName = "Mutation"
-			case ast.Subscription:
-				rootTypeName = "Subscription"
+			rootTypeName := engine.superGraph.RootTypeName(opDef.Operation)
+			selections := opDef.SelectionSet
+			if g.pruneUnfetchable {
+				// Root fields missing from the schema, e.g. while their removal rolls out,
+				// are pruned by the planner instead.
+				selections = g.knownRootSelections(selections, rootTypeName, engine)
 			}
-
-			if err := g.validateSelectionSet(opDef.SelectionSet, rootTypeName, engine); err != nil {
+			if err := g.validateSelectionSet(selections, rootTypeName, engine); err != nil {
 				return err
 			}
 		}
@@ -349,6 +1616,21 @@ func (g *gateway) validateAccessibility(doc *ast.Document, engine *executionEngi
 	return nil
 }
 
+// knownRootSelections returns selections without the fields missing from rootTypeName.
+func (g *gateway) knownRootSelections(selections []ast.Selection, rootTypeName string, engine *executionEngine) []ast.Selection {
+	known := make([]ast.Selection, 0, len(selections))
+	for _, sel := range selections {
+		if field, ok := sel.(*ast.Field); ok {
+			name := field.Name.String()
+			if name != "__typename" && name != "__schema" && name != "__type" && !g.fieldExistsInSchema(rootTypeName, name, engine) {
+				continue
+			}
+		}
+		known = append(known, sel)
+	}
+	return known
+}
+
 func (g *gateway) validateSelectionSet(selSet []ast.Selection, parentTypeName string, engine *executionEngine) error {
 	if selSet == nil {
 		return nil
@@ -363,6 +1645,29 @@ func (g *gateway) validateSelectionSet(selSet []ast.Selection, parentTypeName st
 				continue
 			}
 
+			// In subgraph mode _entities selects entity types through fragments, which
+			// are checked against those types.
+			if g.enableSubgraphMode && (fieldName == "_service" || fieldName == "_entities") {
+				if err := g.validateSelectionSet(s.SelectionSet, "", engine); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// The node field selects entity types through fragments, which are checked
+			// against those types.
+			if g.relayNode.serves(parentTypeName, fieldName, engine) {
+				if err := g.validateSelectionSet(s.SelectionSet, "", engine); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Computed fields are leaves the gateway resolves itself.
+			if g.computedFields.has(parentTypeName, fieldName) {
+				continue
+			}
+
 			if err := g.checkFieldAccessibility(parentTypeName, fieldName, engine); err != nil {
 				return err
 			}
@@ -397,28 +1702,13 @@ func (g *gateway) validateSelectionSet(selSet []ast.Selection, parentTypeName st
 func (g *gateway) checkFieldAccessibility(typeName, fieldName string, engine *executionEngine) error {
 	// Search the COMPOSED supergraph schema first.
 	// This is the authoritative source: if the field is not here it must not be queried.
-	typeFound := false
-	for _, def := range engine.superGraph.Schema.Definitions {
-		objDef, ok := def.(*ast.ObjectTypeDefinition)
-		if !ok || objDef.Name.String() != typeName {
-			continue
-		}
-		typeFound = true
-		fieldFound := false
-		for _, f := range objDef.Fields {
-			if f.Name.String() != fieldName {
-				continue
-			}
-			fieldFound = true
-			for _, d := range f.Directives {
-				if d.Name == "inaccessible" {
-					return fmt.Errorf("Cannot query field %q on type %q", fieldName, typeName)
-				}
+	_, typeFound := engine.superGraph.TypeDefinition(typeName).(*ast.ObjectTypeDefinition)
+	if field := engine.superGraph.FieldDefinition(typeName, fieldName); typeFound && field != nil {
+		for _, d := range field.Directives {
+			if d.Name == "inaccessible" {
+				return fmt.Errorf("Cannot query field %q on type %q", fieldName, typeName)
 			}
 		}
-		// Field not present in the composed schema for this type definition.
-		// (A type may be split across multiple definitions; keep looking.)
-		_ = fieldFound
 	}
 
 	// Also check entity maps (captures @inaccessible recorded during subgraph parsing).
@@ -444,31 +1734,18 @@ func (g *gateway) checkFieldAccessibility(typeName, fieldName string, engine *ex
 // fieldExistsInSchema reports whether the named field is declared on typeName
 // in the composed supergraph schema.
 func (g *gateway) fieldExistsInSchema(typeName, fieldName string, engine *executionEngine) bool {
-	for _, def := range engine.superGraph.Schema.Definitions {
-		objDef, ok := def.(*ast.ObjectTypeDefinition)
-		if !ok || objDef.Name.String() != typeName {
-			continue
-		}
-		for _, f := range objDef.Fields {
-			if f.Name.String() == fieldName {
-				return true
-			}
-		}
+	if _, ok := engine.superGraph.TypeDefinition(typeName).(*ast.ObjectTypeDefinition); !ok {
+		return false
 	}
-	return false
+	return engine.superGraph.FieldDefinition(typeName, fieldName) != nil
 }
 
 func (g *gateway) getFieldTypeName(typeName, fieldName string, engine *executionEngine) string {
-	for _, def := range engine.superGraph.Schema.Definitions {
-		if objDef, ok := def.(*ast.ObjectTypeDefinition); ok {
-			if objDef.Name.String() == typeName {
-				for _, field := range objDef.Fields {
-					if field.Name.String() == fieldName {
-						return g.unwrapTypeName(field.Type)
-					}
-				}
-			}
-		}
+	if _, ok := engine.superGraph.TypeDefinition(typeName).(*ast.ObjectTypeDefinition); !ok {
+		return ""
+	}
+	if field := engine.superGraph.FieldDefinition(typeName, fieldName); field != nil {
+		return g.unwrapTypeName(field.Type)
 	}
 	return ""
 }
@@ -484,3 +1761,45 @@ func (g *gateway) unwrapTypeName(t ast.Type) string {
 	}
 	return ""
 }
+
+// newCostModel builds the planner cost model from the configured latency weights and
+// list size estimate. It returns nil when none are configured.
+func newCostModel(settings GatewayOption) *planner.CostModel {
+	latency := make(map[string]float64)
+	for _, svc := range settings.Services {
+		if svc.LatencyWeight > 0 {
+			latency[svc.Name] = svc.LatencyWeight
+		}
+	}
+	if len(latency) == 0 && settings.ListSizeEstimate <= 0 {
+		return nil
+	}
+	return &planner.CostModel{
+		SubGraphLatency:  latency,
+		ListSizeEstimate: settings.ListSizeEstimate,
+	}
+}
+
+// parseOperationTimeouts resolves the deadline of each operation type, falling back
+// to opt.Default for types without an explicit value.
+func parseOperationTimeouts(opt OperationTimeoutOption) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for opType, value := range map[string]string{
+		string(ast.Query):        opt.Query,
+		string(ast.Mutation):     opt.Mutation,
+		string(ast.Subscription): opt.Subscription,
+	} {
+		if value == "" {
+			value = opt.Default
+		}
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s timeout %q: %w", opType, value, err)
+		}
+		timeouts[opType] = d
+	}
+	return timeouts, nil
+}
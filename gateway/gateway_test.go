@@ -0,0 +1,284 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_OperationTimeout(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "fast"}}}
+	})
+
+	slowReviews := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdlReviews}}}) //nolint:errcheck
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(500 * time.Millisecond):
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"reviews": []any{}}}) //nolint:errcheck
+	}))
+	t.Cleanup(slowReviews.Close)
+
+	const query = `{"query":"{ product(id: \"1\") { name } reviews { body } }"}`
+
+	serve := func(t *testing.T, opt gateway.GatewayOption) (*httptest.ResponseRecorder, map[string]any) {
+		t.Helper()
+		opt.Endpoint = "/graphql"
+		if len(opt.Services) == 0 {
+			opt.Services = []gateway.GatewayService{
+				{Name: "products", Host: products.URL},
+				{Name: "reviews", Host: slowReviews.URL},
+			}
+		}
+		gw, err := gateway.NewGateway(opt)
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rec, resp
+	}
+
+	hasTimeoutError := func(resp map[string]any) bool {
+		errs, _ := resp["errors"].([]any)
+		for _, e := range errs {
+			ext, _ := e.(map[string]any)["extensions"].(map[string]any)
+			if ext["code"] == "GATEWAY_TIMEOUT" {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("hard deadline returns 504", func(t *testing.T) {
+		rec, resp := serve(t, gateway.GatewayOption{
+			OperationTimeout: gateway.OperationTimeoutOption{Query: "50ms"},
+		})
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected 504, got %d", rec.Code)
+		}
+		if !hasTimeoutError(resp) {
+			t.Errorf("expected GATEWAY_TIMEOUT error, got %v", resp)
+		}
+	})
+
+	t.Run("soft deadline returns partial data", func(t *testing.T) {
+		rec, resp := serve(t, gateway.GatewayOption{
+			OperationTimeout: gateway.OperationTimeoutOption{Query: "50ms", SoftDeadline: true},
+		})
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+		data, _ := resp["data"].(map[string]any)
+		product, _ := data["product"].(map[string]any)
+		if product["name"] != "fast" {
+			t.Errorf("expected data from the fast subgraph, got %v", data)
+		}
+		if !hasTimeoutError(resp) {
+			t.Errorf("expected GATEWAY_TIMEOUT error, got %v", resp)
+		}
+	})
+
+	t.Run("default deadline applies to unset operation types", func(t *testing.T) {
+		rec, _ := serve(t, gateway.GatewayOption{
+			OperationTimeout: gateway.OperationTimeoutOption{Default: "50ms", Mutation: "5s"},
+		})
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected 504, got %d", rec.Code)
+		}
+	})
+
+	t.Run("timeout_duration does not bound operations", func(t *testing.T) {
+		rec, resp := serve(t, gateway.GatewayOption{TimeoutDuration: "50ms"})
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+		if hasTimeoutError(resp) {
+			t.Errorf("expected no GATEWAY_TIMEOUT error, got %v", resp)
+		}
+	})
+
+	t.Run("per-subgraph timeout override", func(t *testing.T) {
+		rec, resp := serve(t, gateway.GatewayOption{
+			OperationTimeout: gateway.OperationTimeoutOption{Query: "5s"},
+			Services: []gateway.GatewayService{
+				{Name: "products", Host: products.URL},
+				{Name: "reviews", Host: slowReviews.URL, Timeout: "50ms"},
+			},
+		})
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rec.Code)
+		}
+		if !hasTimeoutError(resp) {
+			t.Errorf("expected GATEWAY_TIMEOUT error, got %v", resp)
+		}
+	})
+
+	t.Run("planning deadline returns 504 without calling subgraphs", func(t *testing.T) {
+		var calls atomic.Int32
+		countingProducts := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+			calls.Add(1)
+			return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "fast"}}}
+		})
+		rec, resp := serve(t, gateway.GatewayOption{
+			OperationTimeout: gateway.OperationTimeoutOption{Planning: "1ns", Query: "5s"},
+			Services: []gateway.GatewayService{
+				{Name: "products", Host: countingProducts.URL},
+				{Name: "reviews", Host: slowReviews.URL},
+			},
+		})
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected 504, got %d", rec.Code)
+		}
+		if !hasTimeoutError(resp) {
+			t.Errorf("expected GATEWAY_TIMEOUT error, got %v", resp)
+		}
+		if n := calls.Load(); n != 0 {
+			t.Errorf("expected no subgraph request, got %d", n)
+		}
+	})
+
+	t.Run("planning deadline leaves execution budget alone", func(t *testing.T) {
+		rec, resp := serve(t, gateway.GatewayOption{
+			OperationTimeout: gateway.OperationTimeoutOption{Planning: "5s", Query: "50ms"},
+		})
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected 504, got %d", rec.Code)
+		}
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 1 || errs[0].(map[string]any)["message"] != "query exceeded its deadline" {
+			t.Errorf("expected the execution deadline error, got %v", resp)
+		}
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			OperationTimeout: gateway.OperationTimeoutOption{Mutation: "soon"},
+		})
+		if err == nil {
+			t.Error("expected error for invalid mutation timeout")
+		}
+	})
+
+	t.Run("invalid planning timeout", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			OperationTimeout: gateway.OperationTimeoutOption{Planning: "soon"},
+		})
+		if err == nil {
+			t.Error("expected error for invalid planning timeout")
+		}
+	})
+}
+
+func TestGateway_BatchedRequests(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		query, _ := body["query"].(string)
+		id, _ := vars["id"].(string)
+		if id == "" && strings.Contains(query, `"2"`) {
+			id = "2"
+		}
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": id, "name": "product-" + id}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:            "/graphql",
+		BatchMaxConcurrency: 1,
+		Services:            []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	body := `[
+		{"query": "query ($id: ID!) { product(id: $id) { name } }", "variables": {"id": "1"}},
+		{"query": "{ product(id: \"2\") { name } }"},
+		{"query": "{ product("}
+	]`
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected a JSON array response: %v (%s)", err, rec.Body.String())
+	}
+	if len(resp) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(resp))
+	}
+	for i, want := range []string{"product-1", "product-2"} {
+		data, _ := resp[i]["data"].(map[string]any)
+		product, _ := data["product"].(map[string]any)
+		if product["name"] != want {
+			t.Errorf("response %d: expected %s, got %v", i, want, resp[i])
+		}
+	}
+	if resp[2]["errors"] == nil {
+		t.Errorf("expected parse errors for the third operation, got %v", resp[2])
+	}
+}
+
+func TestGateway_BatchMaxSize(t *testing.T) {
+	var requests atomic.Int32
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		requests.Add(1)
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "a"}}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:     "/graphql",
+		BatchMaxSize: 2,
+		Services:     []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	const op = `{"query": "{ product(id: \"1\") { name } }"}`
+	serve := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := serve("[" + op + "," + op + "]"); rec.Code != http.StatusOK || requests.Load() != 2 {
+		t.Fatalf("expected a batch at the limit to be executed, got %d after %d requests", rec.Code, requests.Load())
+	}
+
+	rec := serve("[" + op + "," + op + "," + op + "]")
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected a GraphQL error response: %v (%s)", err, rec.Body.String())
+	}
+	errs, _ := resp["errors"].([]any)
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", resp)
+	}
+	if ext, _ := errs[0].(map[string]any)["extensions"].(map[string]any); ext["code"] != gateway.CodeBatchTooLarge {
+		t.Errorf("expected code %s, got %v", gateway.CodeBatchTooLarge, errs[0])
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected no operation of the rejected batch to be executed, got %d requests", n)
+	}
+}
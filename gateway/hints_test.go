@@ -0,0 +1,78 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_GatewayHint(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		q, _ := body["query"].(string)
+		mu.Lock()
+		queries = append(queries, q)
+		mu.Unlock()
+		if strings.Contains(q, "slow") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "chair"}}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	serve := func(query string) map[string]any {
+		body, _ := json.Marshal(map[string]any{"query": query})
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+
+	t.Run("hints are not sent to subgraphs", func(t *testing.T) {
+		resp := serve(`{ product(id: "1") @gatewayHint(subgraph: "products", cache: false) { name } }`)
+		if _, ok := resp["errors"]; ok {
+			t.Fatalf("expected no errors, got %v", resp)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if last := queries[len(queries)-1]; strings.Contains(last, "gatewayHint") {
+			t.Errorf("expected the hint to be stripped, got %s", last)
+		}
+	})
+
+	t.Run("timeout hint bounds the subgraph request", func(t *testing.T) {
+		resp := serve(`{ slow: product(id: "1") @gatewayHint(timeout: "20ms") { name } }`)
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 1 {
+			t.Fatalf("expected a timeout error, got %v", resp)
+		}
+		if code, _ := errs[0].(map[string]any)["extensions"].(map[string]any)["code"].(string); code != "GATEWAY_TIMEOUT" {
+			t.Errorf("expected GATEWAY_TIMEOUT, got %v", errs[0])
+		}
+	})
+
+	t.Run("invalid hint", func(t *testing.T) {
+		resp := serve(`{ product(id: "1") @gatewayHint(subgraph: "reviews") { name } }`)
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 1 || !strings.Contains(errs[0].(map[string]any)["message"].(string), `@gatewayHint(subgraph: "reviews")`) {
+			t.Errorf("expected an unknown subgraph error, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// HTTPPolicyOption configures the GraphQL-over-HTTP request checks per endpoint: the
+// GraphQL endpoint and the admin endpoints (/admin/*, /entity-cache/invalidate and
+// /{name}/apply).
+type HTTPPolicyOption struct {
+	GraphQL EndpointPolicyOption `yaml:"graphql"`
+	Admin   EndpointPolicyOption `yaml:"admin"`
+}
+
+// EndpointPolicyOption configures the request checks of one endpoint.
+type EndpointPolicyOption struct {
+	// RequireContentType rejects POST requests whose Content-Type is not listed in
+	// AllowedContentTypes with 415 Unsupported Media Type.
+	RequireContentType  bool     `yaml:"require_content_type" default:"false"`
+	AllowedContentTypes []string `yaml:"allowed_content_types"` // Defaults to application/json
+	// CSRFPrevention rejects requests a browser sends cross-origin without a CORS
+	// preflight (GET, or a simple Content-Type) unless one of PreflightHeaders is set.
+	CSRFPrevention   bool     `yaml:"csrf_prevention" default:"false"`
+	PreflightHeaders []string `yaml:"preflight_headers"` // Defaults to X-Apollo-Operation-Name, Apollo-Require-Preflight and GraphQL-Require-Preflight
+}
+
+var (
+	defaultAllowedContentTypes = []string{"application/json"}
+	defaultPreflightHeaders    = []string{"X-Apollo-Operation-Name", "Apollo-Require-Preflight", "GraphQL-Require-Preflight"}
+)
+
+// simpleContentTypes are the request Content-Types a browser sends without a preflight.
+var simpleContentTypes = map[string]bool{
+	"application/x-www-form-urlencoded": true,
+	"multipart/form-data":               true,
+	"text/plain":                        true,
+}
+
+// requestPolicy enforces an EndpointPolicyOption.
+type requestPolicy struct {
+	requireContentType bool
+	contentTypes       map[string]bool
+	csrfPrevention     bool
+	preflightHeaders   []string
+}
+
+// newRequestPolicy returns the policy of opt, or nil when it enforces nothing.
+func newRequestPolicy(opt EndpointPolicyOption) *requestPolicy {
+	if !opt.RequireContentType && !opt.CSRFPrevention {
+		return nil
+	}
+
+	contentTypes := opt.AllowedContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = defaultAllowedContentTypes
+	}
+	preflightHeaders := opt.PreflightHeaders
+	if len(preflightHeaders) == 0 {
+		preflightHeaders = defaultPreflightHeaders
+	}
+
+	p := &requestPolicy{
+		requireContentType: opt.RequireContentType,
+		contentTypes:       make(map[string]bool, len(contentTypes)),
+		csrfPrevention:     opt.CSRFPrevention,
+		preflightHeaders:   preflightHeaders,
+	}
+	for _, ct := range contentTypes {
+		p.contentTypes[strings.ToLower(ct)] = true
+	}
+	return p
+}
+
+// check returns the status and message rejecting r, or 0 when r is allowed.
+func (p *requestPolicy) check(r *http.Request) (int, string) {
+	var mediaType string
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return http.StatusUnsupportedMediaType, fmt.Sprintf("invalid Content-Type %q", ct)
+		}
+		mediaType = mt
+	}
+
+	if p.csrfPrevention && (mediaType == "" || simpleContentTypes[mediaType]) && !p.hasPreflightHeader(r) {
+		return http.StatusBadRequest, fmt.Sprintf(
+			"This operation has been blocked as a potential Cross-Site Request Forgery (CSRF). "+
+				"Specify a Content-Type other than application/x-www-form-urlencoded, multipart/form-data "+
+				"or text/plain, or a non-empty value for one of the headers: %s",
+			strings.Join(p.preflightHeaders, ", "),
+		)
+	}
+
+	if p.requireContentType && r.Method == http.MethodPost && !p.contentTypes[mediaType] {
+		if mediaType == "" {
+			return http.StatusUnsupportedMediaType, "missing Content-Type"
+		}
+		return http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported Content-Type %q", mediaType)
+	}
+
+	return 0, ""
+}
+
+func (p *requestPolicy) hasPreflightHeader(r *http.Request) bool {
+	for _, h := range p.preflightHeaders {
+		if r.Header.Get(h) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isAdminRequest reports whether r targets an admin endpoint rather than GraphQL.
+func isAdminRequest(r *http.Request) bool {
+	for i := range adminRoutes {
+		route := &adminRoutes[i]
+		// Any method targets a fixed admin path; a parameterized one could be the GraphQL
+		// endpoint, so it only targets its route with the route's method.
+		if route.matches(r.URL.Path) && (!route.parameterized() || route.method == r.Method) {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceRequestPolicy applies the policy of the endpoint targeted by r and writes a
+// GraphQL error response when the request is rejected. It reports whether r may proceed.
+func (g *gateway) enforceRequestPolicy(w http.ResponseWriter, r *http.Request) bool {
+	policy := g.graphQLPolicy
+	if isAdminRequest(r) {
+		policy = g.adminPolicy
+	}
+	if policy == nil {
+		return true
+	}
+
+	status, message := policy.check(r)
+	if status == 0 {
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+		"errors": []map[string]any{
+			{
+				"message":    message,
+				"extensions": map[string]string{"code": "BAD_REQUEST"},
+			},
+		},
+	})
+	return false
+}
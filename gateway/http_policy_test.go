@@ -0,0 +1,100 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_HTTPPolicy(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1"}}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:       "/graphql",
+		Services:       []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		FaultInjection: gateway.FaultInjectionOption{Enable: true},
+		HTTPPolicy: gateway.HTTPPolicyOption{
+			GraphQL: gateway.EndpointPolicyOption{RequireContentType: true, CSRFPrevention: true},
+			Admin:   gateway.EndpointPolicyOption{CSRFPrevention: true, PreflightHeaders: []string{"X-Admin"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	const query = `{"query":"{ product(id: \"1\") { id } }"}`
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{"json", http.MethodPost, "/graphql", query, map[string]string{"Content-Type": "application/json; charset=utf-8"}, http.StatusOK},
+		{"missing content type", http.MethodPost, "/graphql", query, nil, http.StatusBadRequest},
+		{"text/plain", http.MethodPost, "/graphql", query, map[string]string{"Content-Type": "text/plain"}, http.StatusBadRequest},
+		{"text/plain with preflight header", http.MethodPost, "/graphql", query, map[string]string{"Content-Type": "text/plain", "Apollo-Require-Preflight": "true"}, http.StatusUnsupportedMediaType},
+		{"unlisted content type", http.MethodPost, "/graphql", query, map[string]string{"Content-Type": "application/xml"}, http.StatusUnsupportedMediaType},
+		{"invalid content type", http.MethodPost, "/graphql", query, map[string]string{"Content-Type": "application/json; ="}, http.StatusUnsupportedMediaType},
+		{"admin get", http.MethodGet, "/admin/schema/versions", "", nil, http.StatusBadRequest},
+		{"admin get with preflight header", http.MethodGet, "/admin/schema/versions", "", map[string]string{"X-Admin": "1"}, http.StatusOK},
+		{"admin faults", http.MethodGet, "/admin/faults", "", nil, http.StatusBadRequest},
+		{"admin faults with preflight header", http.MethodGet, "/admin/faults", "", map[string]string{"X-Admin": "1"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Errors []struct {
+					Message string `json:"message"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Errors) != 1 || resp.Errors[0].Message == "" {
+				t.Errorf("expected a GraphQL error body, got %s", rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected application/json, got %q", ct)
+			}
+		})
+	}
+}
+
+func TestGateway_HTTPPolicyDisabled(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1"}}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { id } }"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected requests to pass without a policy, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,225 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_InterfaceImplementationEntities(t *testing.T) {
+	var mu sync.Mutex
+	var searchQuery string
+	representations := make(map[string][]any)
+	entities := func(name string, resolve func(rep map[string]any) map[string]any) func(body map[string]any) any {
+		return func(body map[string]any) any {
+			vars, _ := body["variables"].(map[string]any)
+			reps, _ := vars["representations"].([]any)
+			mu.Lock()
+			representations[name] = reps
+			mu.Unlock()
+			result := make([]any, 0, len(reps))
+			for _, rep := range reps {
+				result = append(result, resolve(rep.(map[string]any)))
+			}
+			return map[string]any{"data": map[string]any{"_entities": result}}
+		}
+	}
+
+	search := newSubgraphServer(t, `
+		interface Media {
+			title: String!
+		}
+
+		type Book implements Media @key(fields: "isbn") {
+			isbn: ID!
+			title: String!
+		}
+
+		type Movie implements Media @key(fields: "id") {
+			id: ID!
+			title: String!
+		}
+
+		type Query {
+			search: [Media!]!
+		}
+	`, func(body map[string]any) any {
+		mu.Lock()
+		searchQuery, _ = body["query"].(string)
+		mu.Unlock()
+		return map[string]any{"data": map[string]any{"search": []any{
+			map[string]any{"__typename": "Book", "isbn": "b1", "title": "Dune"},
+			map[string]any{"__typename": "Movie", "id": "m1", "title": "Alien"},
+			map[string]any{"__typename": "Book", "isbn": "b2", "title": "Emma"},
+		}}}
+	})
+	books := newSubgraphServer(t, `
+		type Book @key(fields: "isbn") {
+			isbn: ID!
+			author: String!
+		}
+	`, entities("books", func(rep map[string]any) map[string]any {
+		return map[string]any{"__typename": "Book", "isbn": rep["isbn"], "author": "author-" + rep["isbn"].(string)}
+	}))
+	movies := newSubgraphServer(t, `
+		type Movie @key(fields: "id") {
+			id: ID!
+			director: String!
+		}
+	`, entities("movies", func(rep map[string]any) map[string]any {
+		return map[string]any{"__typename": "Movie", "id": rep["id"], "director": "director-" + rep["id"].(string)}
+	}))
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "search", Host: search.URL},
+			{Name: "books", Host: books.URL},
+			{Name: "movies", Host: movies.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	query := `{ search { title ... on Book { author } ... on Movie { director } } }`
+	body, _ := json.Marshal(map[string]string{"query": query})
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	want := `{"data":{"search":[` +
+		`{"title":"Dune","author":"author-b1"},` +
+		`{"title":"Alien","director":"director-m1"},` +
+		`{"title":"Emma","author":"author-b2"}]}}`
+	var got, expected any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	json.Unmarshal([]byte(want), &expected) //nolint:errcheck
+	gotJSON, _ := json.Marshal(got)
+	expectedJSON, _ := json.Marshal(expected)
+	if string(gotJSON) != string(expectedJSON) {
+		t.Errorf("expected %s, got %s", expectedJSON, gotJSON)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(searchQuery, "... on Book") || !strings.Contains(searchQuery, "... on Movie") {
+		t.Errorf("expected the key fields to be selected per implementation, got %s", searchQuery)
+	}
+	for name, wantReps := range map[string]string{
+		"books":  `[{"__typename":"Book","isbn":"b1"},{"__typename":"Book","isbn":"b2"}]`,
+		"movies": `[{"__typename":"Movie","id":"m1"}]`,
+	} {
+		gotReps, _ := json.Marshal(representations[name])
+		if string(gotReps) != wantReps {
+			t.Errorf("expected %s representations %s, got %s", name, wantReps, gotReps)
+		}
+	}
+}
+
+func TestGateway_NodeAcrossSubgraphs(t *testing.T) {
+	var mu sync.Mutex
+	queries := make(map[string]string)
+	record := func(name string, body map[string]any) string {
+		q, _ := body["query"].(string)
+		mu.Lock()
+		queries[name] = q
+		mu.Unlock()
+		return q
+	}
+
+	products := newSubgraphServer(t, `
+		interface Node {
+			id: ID!
+		}
+
+		type Product implements Node @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			node(id: ID!): Node @shareable
+		}
+	`, func(body map[string]any) any {
+		if q := record("products", body); strings.Contains(q, `"p1"`) {
+			return map[string]any{"data": map[string]any{"node": map[string]any{"__typename": "Product", "id": "p1", "name": "Table"}}}
+		}
+		return map[string]any{"data": map[string]any{"node": nil}}
+	})
+	users := newSubgraphServer(t, `
+		interface Node {
+			id: ID!
+		}
+
+		type User implements Node @key(fields: "id") {
+			id: ID!
+			username: String!
+		}
+
+		type Query {
+			node(id: ID!): Node @shareable
+		}
+	`, func(body map[string]any) any {
+		if q := record("users", body); strings.Contains(q, `"u1"`) {
+			return map[string]any{"data": map[string]any{"node": map[string]any{"__typename": "User", "id": "u1", "username": "ada"}}}
+		}
+		return map[string]any{"data": map[string]any{"node": nil}}
+	})
+	reviews := newSubgraphServer(t, `
+		type User @key(fields: "id") {
+			id: ID!
+			reviewCount: Int!
+		}
+	`, func(body map[string]any) any {
+		record("reviews", body)
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "User", "id": "u1", "reviewCount": 3},
+		}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "users", Host: users.URL},
+			{Name: "reviews", Host: reviews.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		id   string
+		want string
+	}{
+		{"u1", `{"data":{"node":{"id":"u1","username":"ada","reviewCount":3}}}`},
+		{"p1", `{"data":{"node":{"id":"p1","name":"Table"}}}`},
+		{"x1", `{"data":{"node":null}}`},
+	} {
+		query := `{ node(id: "` + tc.id + `") { id ... on User { username reviewCount } ... on Product { name } } }`
+		body, _ := json.Marshal(map[string]string{"query": query})
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		if got := strings.TrimSpace(rec.Body.String()); got != tc.want {
+			t.Errorf("node(id: %q): expected %s, got %s", tc.id, tc.want, got)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Contains(queries["products"], "User") || strings.Contains(queries["users"], "Product") {
+		t.Errorf("expected each subgraph to be asked only for its own types, got %v", queries)
+	}
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// secretFilePrefix marks a configuration value read from a file, e.g. a Docker or
+// Kubernetes secret mounted at /run/secrets.
+const secretFilePrefix = "file://"
+
+// interpolate resolves the references of every string setting in v: ${NAME} is replaced
+// with the environment variable NAME, ${NAME:-default} falls back to default when NAME is
+// unset or empty and $${ is a literal ${. A value of the form file:///path, after
+// environment references are resolved, is replaced with the content of the file, without
+// its trailing newline.
+func (c *configChecker) interpolate(v reflect.Value, path string) {
+	switch v.Kind() {
+	case reflect.String:
+		s, err := interpolateValue(v.String())
+		if err != nil {
+			c.add(path, "%v", err)
+			return
+		}
+		v.SetString(s)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			c.interpolate(v.Elem(), path)
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			c.interpolate(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			// Map elements are not addressable: resolve a copy and store it back.
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			c.interpolate(elem, joinConfigPath(path, fmt.Sprint(key.Interface())))
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Struct:
+		for key, field := range yamlKeys(v.Type()) {
+			c.interpolate(v.FieldByIndex(field.Index), joinConfigPath(path, key))
+		}
+	}
+}
+
+// interpolateValue resolves the environment and secret file references of s.
+func interpolateValue(s string) (string, error) {
+	if !strings.Contains(s, "${") && !strings.HasPrefix(s, secretFilePrefix) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			sb.WriteString("${")
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference %q", s[i:])
+			}
+			name, fallback, hasFallback := strings.Cut(s[i+2:i+2+end], ":-")
+			if name == "" {
+				return "", fmt.Errorf("empty reference in %q", s)
+			}
+			value, ok := os.LookupEnv(name)
+			switch {
+			case value == "" && hasFallback:
+				value = fallback
+			case !ok:
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			sb.WriteString(value)
+			i += end + 3
+		default:
+			sb.WriteByte(s[i])
+			i++
+		}
+	}
+
+	value := sb.String()
+	if path, ok := strings.CutPrefix(value, secretFilePrefix); ok {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		value = strings.TrimRight(string(b), "\r\n")
+	}
+	return value, nil
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"regexp"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// introspectionDisabledCode is the extensions.code of rejected introspection queries.
+const introspectionDisabledCode = "INTROSPECTION_DISABLED"
+
+// suggestionPattern matches "did you mean" hints appended to validation errors by
+// subgraphs, e.g. `Cannot query field "nme" on type "Product". Did you mean "name"?`.
+var suggestionPattern = regexp.MustCompile(`(?is)\s*did you mean\b.*$`)
+
+// isIntrospectionQuery reports whether op selects __schema or __type anywhere, directly
+// or through fragments. __typename is not considered introspection.
+func isIntrospectionQuery(doc *ast.Document, op *ast.OperationDefinition) bool {
+	fragmentDefs := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			fragmentDefs[fragDef.Name.String()] = fragDef
+		}
+	}
+	return selectsIntrospection(op.SelectionSet, fragmentDefs, make(map[string]bool))
+}
+
+func selectsIntrospection(selections []ast.Selection, fragmentDefs map[string]*ast.FragmentDefinition, visited map[string]bool) bool {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if name := s.Name.String(); name == "__schema" || name == "__type" {
+				return true
+			}
+			if selectsIntrospection(s.SelectionSet, fragmentDefs, visited) {
+				return true
+			}
+		case *ast.InlineFragment:
+			if selectsIntrospection(s.SelectionSet, fragmentDefs, visited) {
+				return true
+			}
+		case *ast.FragmentSpread:
+			name := s.Name.String()
+			fragDef, ok := fragmentDefs[name]
+			if !ok || visited[name] {
+				continue
+			}
+			visited[name] = true
+			if selectsIntrospection(fragDef.SelectionSet, fragmentDefs, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// suppressSuggestions strips "did you mean" hints from the errors of resp so they
+// cannot be used to enumerate the schema.
+func suppressSuggestions(resp map[string]any) {
+	errs, ok := resp["errors"].([]executor.GraphQLError)
+	if !ok {
+		return
+	}
+	for i := range errs {
+		errs[i].Message = suggestionPattern.ReplaceAllString(errs[i].Message, "")
+	}
+}
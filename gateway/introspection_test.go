@@ -0,0 +1,80 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_DisableIntrospection(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"__typename": "Product", "id": "1"}}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:             "/graphql",
+		Services:             []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		DisableIntrospection: true,
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		rejected bool
+	}{
+		{"schema", `{"query":"{ __schema { types { name } } }"}`, true},
+		{"type", `{"query":"{ __type(name: \"Product\") { name } }"}`, true},
+		{"fragment", `{"query":"{ ...Meta } fragment Meta on Query { __schema { queryType { name } } }"}`, true},
+		{"typename", `{"query":"{ product(id: \"1\") { __typename id } }"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body)))
+			rejected := strings.Contains(rec.Body.String(), "INTROSPECTION_DISABLED")
+			if rejected != tt.rejected {
+				t.Errorf("expected rejected=%v, got %s", tt.rejected, rec.Body.String())
+			}
+			if rejected && strings.Contains(rec.Body.String(), "Product") {
+				t.Errorf("expected a generic error, got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGateway_SuppressSuggestions(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{
+			"data": nil,
+			"errors": []any{map[string]any{
+				"message": `Cannot query field "nme" on type "Product". Did you mean "name"?`,
+			}},
+		}
+	})
+
+	for _, suppress := range []bool{false, true} {
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:            "/graphql",
+			Services:            []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			SuppressSuggestions: suppress,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { id } }"}`)))
+		body := rec.Body.String()
+		if !strings.Contains(body, `Cannot query field \"nme\" on type \"Product\".`) {
+			t.Errorf("expected the subgraph error to be kept, got %s", body)
+		}
+		if got := strings.Contains(body, "Did you mean"); got == suppress {
+			t.Errorf("suppress=%v: unexpected suggestion presence in %s", suppress, body)
+		}
+	}
+}
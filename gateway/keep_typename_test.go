@@ -0,0 +1,104 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_KeepTypename(t *testing.T) {
+	const productsSDL = `
+		type Query {
+			product(id: ID!): Product
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String
+		}`
+	const reviewsSDL = `
+		type Review {
+			body: String
+		}
+
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			reviews: [Review]
+		}`
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		data := map[string]any{}
+		for _, key := range []string{"product", "a", "b"} {
+			if q, _ := body["query"].(string); strings.Contains(q, key+": product") || key == "product" {
+				id := map[string]string{"product": "3", "a": "1", "b": "2"}[key]
+				data[key] = map[string]any{"__typename": "Product", "id": id, "name": "product-" + id}
+			}
+		}
+		return map[string]any{"data": data}
+	})
+	reviews := newSubgraphServer(t, reviewsSDL, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		entities := make([]any, 0, len(reps))
+		for _, rep := range reps {
+			id := rep.(map[string]any)["id"].(string)
+			entities = append(entities, map[string]any{
+				"__typename": "Product",
+				"reviews":    []any{map[string]any{"__typename": "Review", "body": "review-" + id}},
+			})
+		}
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+
+	serve := func(t *testing.T, keepTypename bool, body string) string {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{
+				{Name: "products", Host: products.URL},
+				{Name: "reviews", Host: reviews.URL},
+			},
+			KeepTypename: keepTypename,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		return strings.TrimSpace(rec.Body.String())
+	}
+	const query = `{"query":"{ product(id: \"3\") { name reviews { body } } }"%s}`
+
+	t.Run("injected fields are pruned", func(t *testing.T) {
+		got := serve(t, false, strings.Replace(query, "%s", "", 1))
+		want := `{"data":{"product":{"name":"product-3","reviews":[{"body":"review-3"}]}}}`
+		if got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+
+	t.Run("config keeps __typename", func(t *testing.T) {
+		got := serve(t, true, strings.Replace(query, "%s", "", 1))
+		want := `{"data":{"product":{"name":"product-3","reviews":[{"body":"review-3","__typename":"Review"}],"__typename":"Product"}}}`
+		if got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+
+	t.Run("extension keeps __typename", func(t *testing.T) {
+		got := serve(t, false, strings.Replace(query, "%s", `,"extensions":{"keepTypename":true}`, 1))
+		if !strings.Contains(got, `"__typename":"Product"`) || !strings.Contains(got, `"__typename":"Review"`) {
+			t.Errorf("expected __typename to be kept, got %s", got)
+		}
+	})
+
+	t.Run("aliased key fields are kept", func(t *testing.T) {
+		got := serve(t, false, `{"query":"{ product(id: \"3\") { name } a: product(id: \"1\") { pid: id } ...Q } fragment Q on Query { a: product(id: \"1\") { reviews { body } } }"}`)
+		want := `{"data":{"product":{"name":"product-3"},"a":{"pid":"1","reviews":[{"body":"review-1"}]}}}`
+		if got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+}
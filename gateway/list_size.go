@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/token"
+)
+
+// Modes of ListSizeLimitOption.
+const (
+	listSizeModeReject  = "reject"
+	listSizeModeEnforce = "enforce"
+)
+
+// ListSizeLimitOption enables the guardrails subgraph owners set on list root fields with
+// @listSizeLimit(max: 100): such a field must be given a size, through one of its slicing
+// arguments, of at most max. Operations asking for more are rejected; those giving no
+// size are rejected too, or, in enforce mode, sent with the size set to max.
+type ListSizeLimitOption struct {
+	Enable bool   `yaml:"enable" default:"false"`
+	Mode   string `yaml:"mode" default:"reject" validate:"oneof=reject|enforce"` // reject or enforce
+}
+
+// defaultSlicingArguments are the size arguments of a @listSizeLimit field declaring no
+// slicingArguments.
+var defaultSlicingArguments = []string{"first", "last", "limit"}
+
+// listSizeLimit is a parsed @listSizeLimit directive.
+type listSizeLimit struct {
+	max              int64
+	slicingArguments []string
+}
+
+// listSizeLimitDirective reads the @listSizeLimit directive in directives. Directives
+// without a positive max are ignored.
+func listSizeLimitDirective(directives []*ast.Directive) (listSizeLimit, bool) {
+	for _, d := range directives {
+		if d.Name != "listSizeLimit" {
+			continue
+		}
+		limit := listSizeLimit{slicingArguments: defaultSlicingArguments}
+		for _, arg := range d.Arguments {
+			switch arg.Name.String() {
+			case "max":
+				limit.max, _ = strconv.ParseInt(arg.Value.String(), 10, 64)
+			case "slicingArguments":
+				if list, ok := arg.Value.(*ast.ListValue); ok {
+					limit.slicingArguments = nil
+					for _, v := range list.Values {
+						limit.slicingArguments = append(limit.slicingArguments, strings.Trim(v.String(), "\""))
+					}
+				}
+			}
+		}
+		return limit, limit.max > 0
+	}
+	return listSizeLimit{}, false
+}
+
+// listSizeGuard enforces @listSizeLimit on root fields.
+type listSizeGuard struct {
+	enforce bool
+}
+
+// newListSizeGuard returns the guard for opt, or nil when the limits are disabled.
+func newListSizeGuard(opt ListSizeLimitOption) (*listSizeGuard, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+	switch opt.Mode {
+	case "", listSizeModeReject:
+		return &listSizeGuard{}, nil
+	case listSizeModeEnforce:
+		return &listSizeGuard{enforce: true}, nil
+	}
+	return nil, fmt.Errorf("invalid list_size_limits.mode %q: must be %s or %s", opt.Mode, listSizeModeReject, listSizeModeEnforce)
+}
+
+// apply checks the sizes of the @listSizeLimit root fields op selects. It returns the
+// document and operation to plan, which in enforce mode give the fields without a size
+// their first declared slicing argument set to max, or an error for the first field
+// over its limit.
+func (g *listSizeGuard) apply(doc *ast.Document, op *ast.OperationDefinition, variables map[string]any, engine *executionEngine) (*ast.Document, *ast.OperationDefinition, error) {
+	if g == nil {
+		return doc, op, nil
+	}
+	rootTypeName := engine.superGraph.RootTypeName(op.Operation)
+
+	var err error
+	doc, op = rewriteRootFields(doc, op, func(field *ast.Field) *ast.Field {
+		fieldDef := engine.superGraph.FieldDefinition(rootTypeName, field.Name.String())
+		if fieldDef == nil || err != nil {
+			return field
+		}
+		limit, ok := listSizeLimitDirective(fieldDef.Directives)
+		if !ok {
+			return field
+		}
+		coordinate := rootTypeName + "." + field.Name.String()
+
+		var slicing []string
+		for _, name := range limit.slicingArguments {
+			for _, arg := range fieldDef.Arguments {
+				if arg.Name.String() == name {
+					slicing = append(slicing, name)
+				}
+			}
+		}
+		for _, arg := range field.Arguments {
+			if !slices.Contains(slicing, arg.Name.String()) {
+				continue
+			}
+			size, ok := argumentInt(arg.Value, variables)
+			if !ok {
+				continue
+			}
+			if size > limit.max {
+				err = fmt.Errorf("field %s requests %d items, above its limit of %d", coordinate, size, limit.max)
+			}
+			return field
+		}
+
+		if len(slicing) == 0 {
+			err = fmt.Errorf("field %s declares no slicing argument to limit its size", coordinate)
+			return field
+		}
+		if !g.enforce {
+			err = fmt.Errorf("field %s must be given its size with %s, at most %d", coordinate, strings.Join(slicing, " or "), limit.max)
+			return field
+		}
+		sized := *field
+		sized.Arguments = append(append([]*ast.Argument{}, field.Arguments...), &ast.Argument{
+			Name:  &ast.Name{Token: token.Token{Type: token.IDENT, Literal: slicing[0]}, Value: slicing[0]},
+			Value: &ast.IntValue{Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(limit.max, 10)}, Value: limit.max},
+		})
+		return &sized
+	})
+	return doc, op, err
+}
+
+// argumentInt returns the integer value of an argument, resolving variables. Null and
+// missing values are reported as absent.
+func argumentInt(value ast.Value, variables map[string]any) (int64, bool) {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		return v.Value, true
+	case *ast.Variable:
+		switch n := variables[v.Name].(type) {
+		case json.Number:
+			i, err := n.Int64()
+			return i, err == nil
+		case float64:
+			return int64(n), true
+		case int:
+			return int64(n), true
+		case int64:
+			return n, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,118 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ListSizeLimits(t *testing.T) {
+	const sdl = `
+		type Query {
+			products(first: Int, after: String): [Product] @listSizeLimit(max: 50)
+			tags: [String] @listSizeLimit(max: 10)
+		}
+
+		type Product {
+			id: ID!
+		}`
+
+	var mu sync.Mutex
+	var received []string
+	srv := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		q, _ := body["query"].(string)
+		mu.Lock()
+		received = append(received, q)
+		mu.Unlock()
+		return map[string]any{"data": map[string]any{"products": []any{map[string]any{"id": "1"}}}}
+	})
+
+	newGateway := func(t *testing.T, mode string) http.Handler {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:       "/graphql",
+			Services:       []gateway.GatewayService{{Name: "products", Host: srv.URL}},
+			ListSizeLimits: gateway.ListSizeLimitOption{Enable: true, Mode: mode},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+	query := func(t *testing.T, gw http.Handler, query string) (int, map[string]any) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query))
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return rec.Code, resp
+	}
+	errorCode := func(resp map[string]any) any {
+		errs, _ := resp["errors"].([]any)
+		if len(errs) == 0 {
+			return nil
+		}
+		ext, _ := errs[0].(map[string]any)["extensions"].(map[string]any)
+		return ext["code"]
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		gw := newGateway(t, "reject")
+
+		_, resp := query(t, gw, `{"query":"{ products { id } }"}`)
+		if errorCode(resp) != "LIST_SIZE_EXCEEDED" {
+			t.Errorf("expected a field without a size to be rejected, got %v", resp)
+		}
+		_, resp = query(t, gw, `{"query":"query($n: Int) { products(first: $n) { id } }","variables":{"n":100}}`)
+		if errorCode(resp) != "LIST_SIZE_EXCEEDED" {
+			t.Errorf("expected a size above the limit to be rejected, got %v", resp)
+		}
+		_, resp = query(t, gw, `{"query":"{ tags }"}`)
+		if errorCode(resp) != "LIST_SIZE_EXCEEDED" {
+			t.Errorf("expected a field without slicing arguments to be rejected, got %v", resp)
+		}
+		status, resp := query(t, gw, `{"query":"{ products(first: 20) { id } }"}`)
+		if status != http.StatusOK || resp["errors"] != nil {
+			t.Errorf("expected a size within the limit to execute, got %d %v", status, resp)
+		}
+	})
+
+	t.Run("enforce", func(t *testing.T) {
+		gw := newGateway(t, "enforce")
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+
+		status, resp := query(t, gw, `{"query":"query { ...P } fragment P on Query { products { id } }"}`)
+		if status != http.StatusOK || resp["errors"] != nil {
+			t.Fatalf("expected the field to execute with its maximum size, got %d %v", status, resp)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(received) != 1 || !strings.Contains(received[0], "first: 50") {
+			t.Errorf("expected the subgraph to receive first: 50, got %v", received)
+		}
+
+		_, resp = query(t, gw, `{"query":"{ products(first: 51) { id } }"}`)
+		if errorCode(resp) != "LIST_SIZE_EXCEEDED" {
+			t.Errorf("expected a size above the limit to be rejected, got %v", resp)
+		}
+	})
+
+	if _, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:       "/graphql",
+		Services:       []gateway.GatewayService{{Name: "products", Host: srv.URL}},
+		ListSizeLimits: gateway.ListSizeLimitOption{Enable: true, Mode: "truncate"},
+	}); err == nil {
+		t.Error("expected an invalid mode to be rejected")
+	}
+}
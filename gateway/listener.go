@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ListenerOption configures the inbound listeners of the gateway server.
+type ListenerOption struct {
+	TLS       TLSOption `yaml:"tls"`
+	HTTP2     bool      `yaml:"http2" default:"false"` // Serve HTTP/2, negotiated with ALPN over TLS or as h2c without TLS
+	AdminPort int       `yaml:"admin_port"`            // Serve the admin endpoints on this port only, instead of the GraphQL port
+
+	// TLSConfig terminates TLS with a custom configuration, e.g. the one of an ACME
+	// certificate manager, instead of the files of TLS.
+	TLSConfig *tls.Config `yaml:"-"`
+}
+
+// TLSOption configures TLS termination from certificate files.
+type TLSOption struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`                                     // Require client certificates signed by these CAs (mTLS)
+	MinVersion   string `yaml:"min_version" default:"1.2" validate:"oneof=1.2|1.3"` // 1.2 or 1.3
+}
+
+// tlsVersions are the supported TLSOption.MinVersion values.
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsConfig returns the TLS configuration of the listeners, or nil when they serve
+// plaintext.
+func (o ListenerOption) tlsConfig() (*tls.Config, error) {
+	if o.TLSConfig != nil {
+		return o.TLSConfig.Clone(), nil
+	}
+	if o.TLS.CertFile == "" && o.TLS.KeyFile == "" {
+		if o.TLS.ClientCAFile != "" {
+			return nil, errors.New("tls.client_ca_file requires tls.cert_file and tls.key_file")
+		}
+		return nil, nil
+	}
+	if o.TLS.CertFile == "" || o.TLS.KeyFile == "" {
+		return nil, errors.New("tls.cert_file and tls.key_file must be set together")
+	}
+
+	minVersion, ok := tlsVersions[o.TLS.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tls.min_version %q: use 1.2 or 1.3", o.TLS.MinVersion)
+	}
+	cert, err := tls.LoadX509KeyPair(o.TLS.CertFile, o.TLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   minVersion,
+	}
+
+	if o.TLS.ClientCAFile != "" {
+		pem, err := os.ReadFile(o.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in TLS client CA file %s", o.TLS.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// protocols returns the HTTP versions served by the listeners.
+func (o ListenerOption) protocols(tlsEnabled bool) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if o.HTTP2 {
+		protocols.SetHTTP2(tlsEnabled)
+		protocols.SetUnencryptedHTTP2(!tlsEnabled)
+	}
+	return protocols
+}
+
+// NewServers returns the servers of the gateway listeners described by settings: the
+// GraphQL server on settings.Port, and an admin server on Listener.AdminPort when it is
+// set, each serving its part of handler. Servers with a TLSConfig are started with
+// ListenAndServeTLS("", "").
+func NewServers(settings GatewayOption, handler http.Handler) ([]*http.Server, error) {
+	tlsConfig, err := settings.Listener.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid listener: %w", err)
+	}
+	if settings.Listener.AdminPort != 0 && settings.Listener.AdminPort == settings.Port {
+		return nil, fmt.Errorf("invalid listener: admin_port %d is the GraphQL port", settings.Listener.AdminPort)
+	}
+
+	newServer := func(port int, handler http.Handler) *http.Server {
+		srv := &http.Server{
+			Addr:      fmt.Sprintf(":%d", port),
+			Handler:   handler,
+			Protocols: settings.Listener.protocols(tlsConfig != nil),
+		}
+		if tlsConfig != nil {
+			srv.TLSConfig = tlsConfig.Clone()
+		}
+		return srv
+	}
+
+	if settings.Listener.AdminPort == 0 {
+		return []*http.Server{newServer(settings.Port, handler)}, nil
+	}
+	return []*http.Server{
+		newServer(settings.Port, adminFilter(handler, false)),
+		newServer(settings.Listener.AdminPort, adminFilter(handler, true)),
+	}, nil
+}
+
+// adminFilter serves the requests to handler that target the admin endpoints when
+// admin is set, or the other requests otherwise, and answers 404 Not Found to the rest.
+func adminFilter(handler http.Handler, admin bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAdminRequest(r) != admin {
+			http.NotFound(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,167 @@
+package gateway_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and its key to dir.
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gateway"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestNewServers_TLSAndHTTP2(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	servers, err := gateway.NewServers(gateway.GatewayOption{
+		Port: 8080,
+		Listener: gateway.ListenerOption{
+			TLS:   gateway.TLSOption{CertFile: certFile, KeyFile: keyFile},
+			HTTP2: true,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto)) //nolint:errcheck
+	}))
+	if err != nil {
+		t.Fatalf("NewServers failed: %v", err)
+	}
+	if len(servers) != 1 || servers[0].Addr != ":8080" || servers[0].TLSConfig == nil {
+		t.Fatalf("expected one TLS server on :8080, got %+v", servers)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := servers[0]
+	go srv.ServeTLS(ln, "", "") //nolint:errcheck
+	t.Cleanup(func() { srv.Close() })
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Proto != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2.0, got %s", resp.Proto)
+	}
+}
+
+func TestNewServers_AdminPort(t *testing.T) {
+	servers, err := gateway.NewServers(gateway.GatewayOption{
+		Port:     8080,
+		Listener: gateway.ListenerOption{AdminPort: 9090},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	if err != nil {
+		t.Fatalf("NewServers failed: %v", err)
+	}
+	if len(servers) != 2 || servers[0].Addr != ":8080" || servers[1].Addr != ":9090" {
+		t.Fatalf("expected servers on :8080 and :9090, got %+v", servers)
+	}
+
+	tests := []struct {
+		name   string
+		server *http.Server
+		method string
+		path   string
+		want   int
+	}{
+		{name: "graphql on main port", server: servers[0], method: http.MethodPost, path: "/graphql", want: http.StatusNoContent},
+		{name: "admin on main port", server: servers[0], method: http.MethodGet, path: "/admin/schema/versions", want: http.StatusNotFound},
+		{name: "apply on main port", server: servers[0], method: http.MethodPost, path: "/products/apply", want: http.StatusNotFound},
+		{name: "admin on admin port", server: servers[1], method: http.MethodGet, path: "/admin/schema/versions", want: http.StatusNoContent},
+		{name: "graphql on admin port", server: servers[1], method: http.MethodPost, path: "/graphql", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.server.Handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestNewServers_Invalid(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	tests := []struct {
+		name     string
+		listener gateway.ListenerOption
+		want     string
+	}{
+		{
+			name:     "key without certificate",
+			listener: gateway.ListenerOption{TLS: gateway.TLSOption{KeyFile: keyFile}},
+			want:     "must be set together",
+		},
+		{
+			name:     "unknown min version",
+			listener: gateway.ListenerOption{TLS: gateway.TLSOption{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.0"}},
+			want:     `unsupported tls.min_version "1.0"`,
+		},
+		{
+			name:     "admin port is the graphql port",
+			listener: gateway.ListenerOption{AdminPort: 8080},
+			want:     "admin_port 8080 is the GraphQL port",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gateway.NewServers(gateway.GatewayOption{Port: 8080, Listener: tt.listener}, http.NotFoundHandler())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// mergeTracing records how the results of entity steps are merged into responses, to
+// return them in extensions.mergeTrace.
+type mergeTracing struct {
+	debugHeader string // Only trace requests carrying this header
+}
+
+// newMergeTracing returns nil unless ResponseExtensions.MergeTrace is enabled.
+func newMergeTracing(opt ResponseExtensionsOption) *mergeTracing {
+	if !opt.MergeTrace {
+		return nil
+	}
+	return &mergeTracing{debugHeader: opt.DebugHeader}
+}
+
+type mergeTraceContextKey struct{}
+
+// withRequest marks ctx when the operations of r are traced.
+func (m *mergeTracing) withRequest(ctx context.Context, r *http.Request) context.Context {
+	if m == nil || (m.debugHeader != "" && r.Header.Get(m.debugHeader) == "") {
+		return ctx
+	}
+	return context.WithValue(ctx, mergeTraceContextKey{}, true)
+}
+
+// start attaches a merge trace for one operation to ctx. It returns a nil trace when the
+// operation is not traced.
+func (m *mergeTracing) start(ctx context.Context) (context.Context, *executor.MergeTrace) {
+	if traced, _ := ctx.Value(mergeTraceContextKey{}).(bool); !traced {
+		return ctx, nil
+	}
+	trace := executor.NewMergeTrace()
+	return executor.SetMergeTraceToContext(ctx, trace), trace
+}
+
+// mergeTraceExtension returns the merge operations of the entity steps.
+type mergeTraceExtension struct{}
+
+func (mergeTraceExtension) Name() string { return "mergeTrace" }
+
+func (mergeTraceExtension) Extension(_ context.Context, info *ExecutionInfo) (any, bool) {
+	operations := info.MergeOperations()
+	return operations, len(operations) > 0
+}
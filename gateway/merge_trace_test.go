@@ -0,0 +1,83 @@
+package gateway_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_MergeTraceExtension(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean
+		}
+	`
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"topProducts": []any{
+			map[string]any{"__typename": "Product", "id": "1", "name": "Table"},
+			map[string]any{"__typename": "Product", "id": "2", "name": "Chair"},
+		}}}
+	})
+	// The inventory subgraph drops the second entity.
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "Product", "id": "1", "inStock": true},
+		}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL},
+		},
+		ResponseExtensions: gateway.ResponseExtensionsOption{
+			MergeTrace:  true,
+			DebugHeader: "X-Debug",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	resp := postTopProducts(t, gw, nil)
+	if ext, _ := resp["extensions"].(map[string]any); ext["mergeTrace"] != nil {
+		t.Errorf("expected no merge trace without the debug header, got %v", ext)
+	}
+
+	resp = postTopProducts(t, gw, http.Header{"X-Debug": {"1"}})
+	ext, _ := resp["extensions"].(map[string]any)
+	operations, _ := ext["mergeTrace"].([]any)
+	if len(operations) != 2 {
+		t.Fatalf("expected a merge operation per product, got %v", resp)
+	}
+
+	merged := operations[0].(map[string]any)
+	if merged["step"] != float64(1) || merged["subgraph"] != "inventory" || merged["entityIndex"] != float64(0) || merged["outcome"] != "merged" {
+		t.Errorf("unexpected merge operation %v", merged)
+	}
+	if path, _ := merged["insertionPath"].([]any); len(path) == 0 || path[len(path)-1] != "topProducts" {
+		t.Errorf("expected the insertion path of the step, got %v", merged["insertionPath"])
+	}
+	if merged["before"] == nil || merged["before"] == merged["after"] {
+		t.Errorf("expected the merge to change the snapshot hash, got %v", merged)
+	}
+
+	missing := operations[1].(map[string]any)
+	if missing["entityIndex"] != float64(1) || missing["outcome"] != "missingEntity" {
+		t.Errorf("expected the dropped entity to be reported, got %v", missing)
+	}
+}
@@ -0,0 +1,209 @@
+package gateway
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// defaultMockListLength is the length of mocked lists without a configured length.
+const defaultMockListLength = 2
+
+// mockEpoch is the earliest mocked DateTime.
+var mockEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// MockOption configures mock mode, in which the gateway answers operations with data
+// generated from the composed schema instead of calling subgraphs.
+type MockOption struct {
+	Enable      bool           `yaml:"enable" default:"false"`
+	Seed        uint64         `yaml:"seed" default:"0"`        // Different seeds generate different data
+	ListLength  int            `yaml:"list_length" default:"2"` // Length of mocked lists
+	ListLengths map[string]int `yaml:"list_lengths"`            // Per-field list lengths keyed by coordinate, e.g. Query.topProducts
+}
+
+// mocker generates response data for operations. The value of a field depends only on
+// the seed and its response path, so repeating an operation returns the same data and
+// operations selecting the same field agree on its value.
+type mocker struct {
+	seed        uint64
+	listLength  int
+	listLengths map[string]int
+}
+
+// newMocker returns nil when mock mode is disabled.
+func newMocker(opt MockOption) (*mocker, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+	if opt.ListLength < 0 {
+		return nil, fmt.Errorf("invalid mock list_length %d", opt.ListLength)
+	}
+	for coordinate, n := range opt.ListLengths {
+		if n < 0 {
+			return nil, fmt.Errorf("invalid mock list length %d for %s", n, coordinate)
+		}
+	}
+	listLength := opt.ListLength
+	if listLength == 0 {
+		listLength = defaultMockListLength
+	}
+	return &mocker{seed: opt.Seed, listLength: listLength, listLengths: opt.ListLengths}, nil
+}
+
+// resolve returns a response with mocked data for op.
+func (m *mocker) resolve(doc *ast.Document, op *ast.OperationDefinition, engine *executionEngine) map[string]any {
+	fragmentDefs := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			fragmentDefs[fragDef.Name.String()] = fragDef
+		}
+	}
+	g := &mockGenerator{mocker: m, fragmentDefs: fragmentDefs, engine: engine}
+	data := make(map[string]any)
+	g.object(data, op.SelectionSet, engine.superGraph.RootTypeName(op.Operation), "")
+	return map[string]any{"data": data}
+}
+
+type mockGenerator struct {
+	*mocker
+	fragmentDefs map[string]*ast.FragmentDefinition
+	engine       *executionEngine
+}
+
+// object fills obj with the fields of selections on the object type typeName.
+func (g *mockGenerator) object(obj map[string]any, selections []ast.Selection, typeName, path string) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			fieldName := s.Name.String()
+			key := fieldName
+			if s.Alias != nil && s.Alias.String() != "" {
+				key = s.Alias.String()
+			}
+			switch fieldName {
+			case "__typename":
+				obj[key] = typeName
+				continue
+			case "__schema", "__type":
+				obj[key] = nil
+				continue
+			}
+			fieldDef := findFieldDefinition(typeName, fieldName, g.engine)
+			if fieldDef == nil {
+				continue
+			}
+			obj[key] = g.value(fieldDef.Type, s.SelectionSet, typeName+"."+fieldName, path+"."+fieldName)
+		case *ast.InlineFragment:
+			if s.TypeCondition == nil || isPossibleType(g.engine, s.TypeCondition.Name.String(), typeName) {
+				g.object(obj, s.SelectionSet, typeName, path)
+			}
+		case *ast.FragmentSpread:
+			fragDef, ok := g.fragmentDefs[s.Name.String()]
+			if ok && isPossibleType(g.engine, fragDef.TypeCondition.Name.String(), typeName) {
+				g.object(obj, fragDef.SelectionSet, typeName, path)
+			}
+		}
+	}
+}
+
+// value returns a mocked value of type t. Nullable fields are never null.
+func (g *mockGenerator) value(t ast.Type, selections []ast.Selection, coordinate, path string) any {
+	switch typ := t.(type) {
+	case *ast.NonNullType:
+		return g.value(typ.Type, selections, coordinate, path)
+	case *ast.ListType:
+		n := g.listLength
+		if length, ok := g.listLengths[coordinate]; ok {
+			n = length
+		}
+		items := make([]any, n)
+		for i := range items {
+			items[i] = g.value(typ.Type, selections, coordinate, path+"."+strconv.Itoa(i))
+		}
+		return items
+	case *ast.NamedType:
+		return g.named(typ.Name.String(), selections, coordinate, path)
+	}
+	return nil
+}
+
+// named returns a mocked value of the named type typeName.
+func (g *mockGenerator) named(typeName string, selections []ast.Selection, coordinate, path string) any {
+	r := g.rand(path)
+	switch typeName {
+	case "ID":
+		return strconv.Itoa(r.IntN(10000) + 1)
+	case "String":
+		return fmt.Sprintf("%s %d", coordinate, r.IntN(100)+1)
+	case "Int":
+		return r.IntN(1000)
+	case "Float":
+		return math.Round(r.Float64()*100000) / 100
+	case "Boolean":
+		return r.IntN(2) == 1
+	case "DateTime":
+		return mockEpoch.Add(time.Duration(r.IntN(365*24*60)) * time.Minute).Format(time.RFC3339)
+	case "JSON":
+		return map[string]any{}
+	case "BigInt":
+		return strconv.FormatUint(r.Uint64(), 10)
+	}
+
+	if g.engine.superGraph.IsAbstractType(typeName) {
+		possible := possibleTypes(g.engine, typeName)
+		if len(possible) == 0 {
+			return nil
+		}
+		obj := make(map[string]any)
+		g.object(obj, selections, possible[r.IntN(len(possible))], path)
+		return obj
+	}
+	for _, def := range g.engine.superGraph.Schema.Definitions {
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			if d.Name.String() == typeName {
+				obj := make(map[string]any)
+				g.object(obj, selections, typeName, path)
+				return obj
+			}
+		case *ast.EnumTypeDefinition:
+			if d.Name.String() == typeName && len(d.Values) > 0 {
+				return d.Values[r.IntN(len(d.Values))].Name.String()
+			}
+		}
+	}
+	// Other custom scalars are mocked as strings.
+	return fmt.Sprintf("%s %d", typeName, r.IntN(100)+1)
+}
+
+// rand returns the random source of the field at path.
+func (g *mockGenerator) rand(path string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(path)) //nolint:errcheck
+	return rand.New(rand.NewPCG(g.seed, h.Sum64()))
+}
+
+// possibleTypes returns the object types implementing the interface or belonging to
+// the union typeName, in schema order.
+func possibleTypes(engine *executionEngine, typeName string) []string {
+	return engine.superGraph.PossibleTypes(typeName)
+}
+
+// isPossibleType reports whether a value of the object type objectType is also of
+// typeName, which may be an interface or union.
+func isPossibleType(engine *executionEngine, typeName, objectType string) bool {
+	if typeName == objectType {
+		return true
+	}
+	for _, possible := range possibleTypes(engine, typeName) {
+		if possible == objectType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+func TestGateway_MockMode(t *testing.T) {
+	const productsSDL = `
+		type Query {
+			topProducts: [Product!]!
+			media: [Media!]!
+		}
+
+		enum Category {
+			BOOKS
+			GAMES
+		}
+
+		interface Media {
+			title: String!
+		}
+
+		type Book implements Media {
+			title: String!
+			pages: Int!
+		}
+
+		type Movie implements Media {
+			title: String!
+			minutes: Int!
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String
+			price: Float!
+			category: Category!
+			releasedAt: DateTime
+		}
+
+		scalar DateTime
+	`
+	const inventorySDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			inStock: Boolean!
+		}
+	`
+
+	dir := t.TempDir()
+	service := func(name, sdl string) gateway.GatewayService {
+		path := filepath.Join(dir, name+".graphql")
+		if err := os.WriteFile(path, []byte(sdl), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// The subgraphs do not exist; every request to them fails.
+		return gateway.GatewayService{
+			Name:   name,
+			Host:   "http://" + name + ".invalid/query",
+			Source: &registry.SourceOption{Type: "file", Path: path},
+		}
+	}
+
+	newGateway := func(t *testing.T, opt gateway.MockOption) http.Handler {
+		t.Helper()
+		opt.Enable = true
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{service("products", productsSDL), service("inventory", inventorySDL)},
+			Mock:     opt,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+
+	query := func(t *testing.T, gw http.Handler, q string) (string, map[string]any) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"query": q})
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		if resp["errors"] != nil {
+			t.Fatalf("expected no errors, got %s", rec.Body.String())
+		}
+		return rec.Body.String(), resp
+	}
+
+	const productsQuery = `{ topProducts { __typename id name price category inStock releasedAt } }`
+
+	t.Run("type-aware values across subgraphs", func(t *testing.T) {
+		gw := newGateway(t, gateway.MockOption{ListLengths: map[string]int{"Query.topProducts": 3}})
+		_, resp := query(t, gw, productsQuery)
+
+		products, _ := resp["data"].(map[string]any)["topProducts"].([]any)
+		if len(products) != 3 {
+			t.Fatalf("expected 3 products, got %v", resp)
+		}
+		for _, p := range products {
+			product := p.(map[string]any)
+			if product["__typename"] != "Product" {
+				t.Errorf("expected __typename Product, got %v", product["__typename"])
+			}
+			if _, ok := product["id"].(string); !ok {
+				t.Errorf("expected a string id, got %v", product["id"])
+			}
+			if _, ok := product["price"].(float64); !ok {
+				t.Errorf("expected a numeric price, got %v", product["price"])
+			}
+			if c := product["category"]; c != "BOOKS" && c != "GAMES" {
+				t.Errorf("expected a Category value, got %v", c)
+			}
+			if _, ok := product["inStock"].(bool); !ok {
+				t.Errorf("expected a boolean inStock, got %v", product["inStock"])
+			}
+			if s, _ := product["releasedAt"].(string); !strings.HasPrefix(s, "2024-") {
+				t.Errorf("expected an RFC 3339 releasedAt, got %v", product["releasedAt"])
+			}
+		}
+	})
+
+	t.Run("abstract types resolve to an implementation", func(t *testing.T) {
+		gw := newGateway(t, gateway.MockOption{ListLength: 10})
+		_, resp := query(t, gw, `{ media { __typename title ... on Book { pages } ... on Movie { minutes } } }`)
+
+		for _, m := range resp["data"].(map[string]any)["media"].([]any) {
+			media := m.(map[string]any)
+			switch media["__typename"] {
+			case "Book":
+				if _, ok := media["pages"]; !ok || media["minutes"] != nil {
+					t.Errorf("expected only Book fields, got %v", media)
+				}
+			case "Movie":
+				if _, ok := media["minutes"]; !ok || media["pages"] != nil {
+					t.Errorf("expected only Movie fields, got %v", media)
+				}
+			default:
+				t.Errorf("expected a Media implementation, got %v", media)
+			}
+		}
+	})
+
+	t.Run("deterministic per seed", func(t *testing.T) {
+		first, _ := query(t, newGateway(t, gateway.MockOption{Seed: 1}), productsQuery)
+		again, _ := query(t, newGateway(t, gateway.MockOption{Seed: 1}), productsQuery)
+		other, _ := query(t, newGateway(t, gateway.MockOption{Seed: 2}), productsQuery)
+		if first != again {
+			t.Errorf("expected the same data for the same seed, got %s and %s", first, again)
+		}
+		if first == other {
+			t.Errorf("expected different data for another seed, got %s", other)
+		}
+	})
+}
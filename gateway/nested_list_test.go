@@ -0,0 +1,163 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_NestedListEntities(t *testing.T) {
+	const catalogSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+		}
+
+		type Shelf {
+			rows: [[Product]]
+		}
+
+		type Query {
+			grid: [[Product]]
+			cube: [[[Product!]!]!]!
+			shelves: [Shelf]
+		}
+	`
+	const productsSDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			name: String
+		}
+	`
+
+	product := func(id string) map[string]any { return map[string]any{"__typename": "Product", "id": id} }
+	catalog := newSubgraphServer(t, catalogSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{
+			"grid": []any{[]any{product("1"), nil, product("2")}, nil, []any{}, []any{product("3")}},
+			"cube": []any{[]any{[]any{product("4")}, []any{product("5"), product("6")}}},
+			"shelves": []any{
+				map[string]any{"rows": []any{[]any{product("7")}, []any{product("8")}}},
+			},
+		}}
+	})
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		result := make([]any, 0, len(reps))
+		for _, r := range reps {
+			id := r.(map[string]any)["id"].(string)
+			result = append(result, map[string]any{"__typename": "Product", "id": id, "name": "product-" + id})
+		}
+		return map[string]any{"data": map[string]any{"_entities": result}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "catalog", Host: catalog.URL},
+			{Name: "products", Host: products.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "list of lists",
+			query: `{ grid { name } }`,
+			want:  `{"data":{"grid":[[{"name":"product-1"},null,{"name":"product-2"}],null,[],[{"name":"product-3"}]]}}`,
+		},
+		{
+			name:  "three dimensions",
+			query: `{ cube { id name } }`,
+			want:  `{"data":{"cube":[[[{"id":"4","name":"product-4"}],[{"id":"5","name":"product-5"},{"id":"6","name":"product-6"}]]]}}`,
+		},
+		{
+			name:  "nested below a list",
+			query: `{ shelves { rows { name } } }`,
+			want:  `{"data":{"shelves":[{"rows":[[{"name":"product-7"}],[{"name":"product-8"}]]}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"`+tt.query+`"}`)))
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGateway_NestedListEntities_FailedStep(t *testing.T) {
+	const catalogSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+		}
+
+		type Query {
+			grid: [[Product]]
+		}
+	`
+	const productsSDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			name: String
+		}
+	`
+
+	catalog := newSubgraphServer(t, catalogSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"grid": []any{
+			[]any{map[string]any{"__typename": "Product", "id": "1"}},
+			[]any{map[string]any{"__typename": "Product", "id": "2"}},
+		}}}
+	})
+	// The products subgraph serves its SDL but fails every other request.
+	products := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": productsSDL}}}) //nolint:errcheck
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(products.Close)
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "catalog", Host: catalog.URL},
+			{Name: "products", Host: products.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ grid { name } }"}`)))
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []any           `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+	}
+	if want := `{"grid":[[{"name":null}],[{"name":null}]]}`; string(resp.Data) != want {
+		t.Errorf("expected %s, got %s", want, resp.Data)
+	}
+	if len(resp.Errors) == 0 {
+		t.Errorf("expected the subgraph error, got %s", rec.Body.String())
+	}
+}
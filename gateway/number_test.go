@@ -0,0 +1,86 @@
+package gateway_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_LargeNumbers(t *testing.T) {
+	// 2^53 + 1 is the smallest integer a float64 cannot represent.
+	const id = "9007199254740993"
+
+	var mu sync.Mutex
+	var bodies []string
+	rawServer := func(sdl, response string) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			if strings.Contains(string(body), "_service") {
+				json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+				return
+			}
+			mu.Lock()
+			bodies = append(bodies, string(body))
+			mu.Unlock()
+			w.Write([]byte(response)) //nolint:errcheck
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	products := rawServer(`
+		type Product @key(fields: "id") {
+			id: Int!
+			price: Float!
+		}
+
+		type Query {
+			product(id: Int!): Product
+		}
+	`, `{"data":{"product":{"__typename":"Product","id":`+id+`,"price":12.5}}}`)
+	inventory := rawServer(`
+		extend type Product @key(fields: "id") {
+			id: Int! @external
+			stock: Int!
+		}
+	`, `{"data":{"_entities":[{"__typename":"Product","id":`+id+`,"stock":`+id+`}]}}`)
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "inventory", Host: inventory.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	body := `{"query":"query ($id: Int!) { product(id: $id) { id price stock } }","variables":{"id":` + id + `}}`
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+
+	want := `{"data":{"product":{"id":` + id + `,"price":12.5,"stock":` + id + `}}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 subgraph requests, got %d", len(bodies))
+	}
+	for _, sent := range bodies {
+		if !strings.Contains(sent, id) {
+			t.Errorf("expected the subgraph request to carry %s unchanged, got %s", id, sent)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// openAPIPath serves the OpenAPI document describing the admin routes.
+const openAPIPath = "/admin/openapi.json"
+
+// openAPIDocument returns the OpenAPI 3.1 document describing adminRoutes and, when it
+// is enabled, the schema endpoint. Bodies are described from the Go types the handlers
+// decode and encode.
+func openAPIDocument(schema *schemaEndpoint) map[string]any {
+	paths := make(map[string]map[string]any)
+	for i := range adminRoutes {
+		route := &adminRoutes[i]
+		if paths[route.path] == nil {
+			paths[route.path] = make(map[string]any)
+		}
+		paths[route.path][strings.ToLower(route.method)] = openAPIOperation(route)
+	}
+	if schema != nil {
+		operation := map[string]any{
+			"summary": "Print the composed schema as SDL",
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The composed schema",
+					"content":     map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}},
+				},
+			},
+		}
+		if schema.token != "" {
+			operation["security"] = []any{map[string]any{"bearer": []string{}}}
+			operation["responses"].(map[string]any)["401"] = map[string]any{"description": "The bearer token is missing or wrong"}
+		}
+		paths[schema.path] = map[string]any{"get": operation}
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "GraphQL federation gateway admin API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+	}
+	if schema != nil && schema.token != "" {
+		doc["components"] = map[string]any{"securitySchemes": map[string]any{
+			"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+		}}
+	}
+	return doc
+}
+
+// openAPIOperation describes route as an OpenAPI operation.
+func openAPIOperation(route *adminRoute) map[string]any {
+	operation := map[string]any{"summary": route.summary}
+	if route.parameterized() {
+		name := route.path[strings.IndexByte(route.path, '{')+1 : strings.IndexByte(route.path, '}')]
+		operation["parameters"] = []any{map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		}}
+	}
+	if route.request != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  jsonContent(route.request),
+		}
+	}
+	responses := make(map[string]any, len(route.responses))
+	for _, resp := range route.responses {
+		described := map[string]any{"description": resp.description}
+		switch {
+		case resp.empty:
+		case resp.body != nil:
+			described["content"] = jsonContent(resp.body)
+		default:
+			described["content"] = jsonContent(adminError{})
+		}
+		responses[strconv.Itoa(resp.status)] = described
+	}
+	operation["responses"] = responses
+	return operation
+}
+
+// jsonContent describes a JSON body shaped like v.
+func jsonContent(v any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": jsonSchema(reflect.TypeOf(v))}}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// jsonSchema returns the JSON Schema of the values of t encoded as JSON.
+func jsonSchema(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return map[string]any{"type": "object"}
+		}
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = jsonSchema(field.Type)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	}
+	return map[string]any{}
+}
+
+// handleOpenAPI serves the OpenAPI document of the gateway.
+func (g *gateway) handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(g.openAPI) //nolint:errcheck
+}
+
+// marshalOpenAPI encodes the OpenAPI document of a gateway serving schema.
+func marshalOpenAPI(schema *schemaEndpoint) ([]byte, error) {
+	return json.MarshalIndent(openAPIDocument(schema), "", "  ")
+}
@@ -0,0 +1,95 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_OpenAPI(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": nil}}
+	})
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:       "/graphql",
+		Services:       []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		SchemaEndpoint: gateway.SchemaEndpointOption{Enable: true, Path: "/sdl", Token: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var doc struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode the document: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("expected OpenAPI 3.1.0, got %q", doc.OpenAPI)
+	}
+
+	for path, methods := range map[string][]string{
+		"/{name}/apply":            {"post"},
+		"/admin/schema/versions":   {"get"},
+		"/admin/schema/health":     {"get"},
+		"/admin/deprecated-fields": {"get"},
+		"/admin/faults":            {"get", "put"},
+		"/admin/schema/rollback":   {"post"},
+		"/admin/compose/check":     {"post"},
+		"/entity-cache/invalidate": {"post"},
+		"/admin/openapi.json":      {"get"},
+		"/sdl":                     {"get"},
+	} {
+		for _, method := range methods {
+			if doc.Paths[path][method] == nil {
+				t.Errorf("expected %s %s to be described", strings.ToUpper(method), path)
+			}
+		}
+	}
+	if _, ok := doc.Paths["/graphql"]; ok {
+		t.Error("expected the GraphQL endpoint not to be described")
+	}
+
+	t.Run("request bodies are described from their types", func(t *testing.T) {
+		check, _ := json.Marshal(doc.Paths["/admin/compose/check"]["post"]["requestBody"])
+		for _, property := range []string{`"name":{"type":"string"}`, `"sdl":{"type":"string"}`} {
+			if !strings.Contains(string(check), property) {
+				t.Errorf("expected property %s, got %s", property, check)
+			}
+		}
+		health, _ := json.Marshal(doc.Paths["/admin/schema/health"]["get"]["responses"])
+		if !strings.Contains(string(health), `"last_success":{"format":"date-time","type":"string"}`) {
+			t.Errorf("expected the schema health report, got %s", health)
+		}
+	})
+
+	t.Run("every described route is served", func(t *testing.T) {
+		for path, methods := range doc.Paths {
+			if path == "/sdl" {
+				continue
+			}
+			for method := range methods {
+				target := strings.ReplaceAll(path, "{name}", "products")
+				rec := httptest.NewRecorder()
+				gw.ServeHTTP(rec, httptest.NewRequest(strings.ToUpper(method), target, strings.NewReader(`{}`)))
+				var body map[string]any
+				json.Unmarshal(rec.Body.Bytes(), &body) //nolint:errcheck
+				if _, graphQL := body["errors"]; graphQL || rec.Code == http.StatusMethodNotAllowed {
+					t.Errorf("expected %s %s to be routed to its handler, got %d %s", method, target, rec.Code, rec.Body.String())
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"github.com/n9te9/graphql-parser/ast"
+
+	"github.com/n9te9/go-graphql-federation-gateway/internal/operation"
+)
+
+// selectOperation picks the operation to execute from doc by operationName and returns
+// a document holding only that operation plus every fragment; see operation.Select.
+func selectOperation(doc *ast.Document, operationName string) (*ast.Document, *ast.OperationDefinition, error) {
+	return operation.Select(doc, operationName)
+}
+
+// operationNameOf returns the name of op, or "" for anonymous operations.
+func operationNameOf(op *ast.OperationDefinition) string {
+	return operation.Name(op)
+}
+
+// rewriteRootFields returns doc with every root field op selects, directly or through
+// fragments, replaced by rewrite(field); nil drops the field. Fragment spreads holding a
+// replaced field are inlined. doc and op are returned when rewrite kept every field.
+func rewriteRootFields(doc *ast.Document, op *ast.OperationDefinition, rewrite func(*ast.Field) *ast.Field) (*ast.Document, *ast.OperationDefinition) {
+	w := rootFieldRewriter{fragmentDefs: fragmentDefinitions(doc), visited: make(map[string]bool), rewrite: rewrite}
+	selections, changed := w.rewriteSelections(op.SelectionSet)
+	if !changed {
+		return doc, op
+	}
+
+	rewrittenOp := *op
+	rewrittenOp.SelectionSet = selections
+	rewritten := &ast.Document{Definitions: make([]ast.Definition, 0, len(doc.Definitions))}
+	for _, def := range doc.Definitions {
+		if def == op {
+			def = &rewrittenOp
+		}
+		rewritten.Definitions = append(rewritten.Definitions, def)
+	}
+	return rewritten, &rewrittenOp
+}
+
+// rootFieldRewriter implements rewriteRootFields.
+type rootFieldRewriter struct {
+	fragmentDefs map[string]*ast.FragmentDefinition
+	visited      map[string]bool
+	rewrite      func(*ast.Field) *ast.Field
+}
+
+// rewriteSelections returns selections with their fields rewritten, and whether any
+// changed.
+func (w rootFieldRewriter) rewriteSelections(selections []ast.Selection) ([]ast.Selection, bool) {
+	kept := make([]ast.Selection, 0, len(selections))
+	changed := false
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			field := w.rewrite(s)
+			if field == nil {
+				changed = true
+				continue
+			}
+			if field != s {
+				sel, changed = field, true
+			}
+		case *ast.InlineFragment:
+			if inner, innerChanged := w.rewriteSelections(s.SelectionSet); innerChanged {
+				fragment := *s
+				fragment.SelectionSet = inner
+				sel, changed = &fragment, true
+			}
+		case *ast.FragmentSpread:
+			name := s.Name.String()
+			fragDef, ok := w.fragmentDefs[name]
+			if !ok || w.visited[name] {
+				break
+			}
+			w.visited[name] = true
+			inner, innerChanged := w.rewriteSelections(fragDef.SelectionSet)
+			delete(w.visited, name)
+			if innerChanged {
+				sel = &ast.InlineFragment{Token: s.Token, TypeCondition: fragDef.TypeCondition, Directives: s.Directives, SelectionSet: inner}
+				changed = true
+			}
+		}
+		kept = append(kept, sel)
+	}
+	return kept, changed
+}
+
+// selectsField reports whether selections, part of doc, select any field.
+func selectsField(doc *ast.Document, selections []ast.Selection) bool {
+	return selectsFieldIn(selections, fragmentDefinitions(doc), make(map[string]bool))
+}
+
+func selectsFieldIn(selections []ast.Selection, fragmentDefs map[string]*ast.FragmentDefinition, visited map[string]bool) bool {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			return true
+		case *ast.InlineFragment:
+			if selectsFieldIn(s.SelectionSet, fragmentDefs, visited) {
+				return true
+			}
+		case *ast.FragmentSpread:
+			fragDef, ok := fragmentDefs[s.Name.String()]
+			if ok && !visited[s.Name.String()] {
+				visited[s.Name.String()] = true
+				if selectsFieldIn(fragDef.SelectionSet, fragmentDefs, visited) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// fragmentDefinitions returns the fragments of doc by name.
+func fragmentDefinitions(doc *ast.Document) map[string]*ast.FragmentDefinition {
+	fragmentDefs := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			fragmentDefs[fragDef.Name.String()] = fragDef
+		}
+	}
+	return fragmentDefs
+}
+
+// fieldResponseKey returns the alias of field, or its name.
+func fieldResponseKey(field *ast.Field) string {
+	if field.Alias != nil && field.Alias.String() != "" {
+		return field.Alias.String()
+	}
+	return field.Name.String()
+}
@@ -0,0 +1,124 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestSelectOperation(t *testing.T) {
+	const query = `
+		query First { product(id: "1") { ...Fields } }
+		query Second { product(id: "2") { ...Fields } }
+		fragment Fields on Product { name }
+	`
+
+	parse := func(t *testing.T, q string) *ast.Document {
+		t.Helper()
+		p := parser.New(lexer.New(q))
+		doc := p.ParseDocument()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("parse error: %v", p.Errors())
+		}
+		return doc
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		wantName      string
+		wantErr       string
+	}{
+		{name: "select by name", query: query, operationName: "Second", wantName: "Second"},
+		{name: "ambiguous without name", query: query, wantErr: "must provide operation name"},
+		{name: "unknown name", query: query, operationName: "Third", wantErr: `unknown operation named "Third"`},
+		{name: "single anonymous operation", query: `{ product(id: "1") { name } }`},
+		{name: "single operation ignores missing name", query: `query Only { product(id: "1") { name } }`, wantName: "Only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, op, err := gateway.SelectOperationForTest(parse(t, tt.query), tt.operationName)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			gotName := ""
+			if op.Name != nil {
+				gotName = op.Name.String()
+			}
+			if gotName != tt.wantName {
+				t.Errorf("expected operation %q, got %q", tt.wantName, gotName)
+			}
+
+			ops := 0
+			for _, def := range doc.Definitions {
+				if _, ok := def.(*ast.OperationDefinition); ok {
+					ops++
+				}
+			}
+			if ops != 1 {
+				t.Errorf("expected a document with exactly one operation, got %d", ops)
+			}
+		})
+	}
+}
+
+func TestGateway_OperationName(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		query, _ := body["query"].(string)
+		id := "1"
+		if strings.Contains(query, `"2"`) {
+			id = "2"
+		}
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": id, "name": "product-" + id}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	const query = `query First { product(id: \"1\") { name } } query Second { product(id: \"2\") { name } }`
+
+	serve := func(body string) map[string]any {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	resp := serve(`{"query": "` + query + `", "operationName": "Second"}`)
+	data, _ := resp["data"].(map[string]any)
+	product, _ := data["product"].(map[string]any)
+	if product["name"] != "product-2" {
+		t.Errorf("expected the Second operation to run, got %v", resp)
+	}
+
+	resp = serve(`{"query": "` + query + `"}`)
+	errs, _ := resp["errors"].([]any)
+	if len(errs) != 1 || !strings.Contains(errs[0].(map[string]any)["message"].(string), "must provide operation name") {
+		t.Errorf("expected operation name error, got %v", resp)
+	}
+}
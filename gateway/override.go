@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// OverrideLabelProvider decides whether a custom progressive @override label is active
+// for a request, e.g. by consulting a feature flag service.
+type OverrideLabelProvider interface {
+	IsLabelEnabled(ctx context.Context, label string) bool
+}
+
+// staticOverrideLabels is an OverrideLabelProvider backed by configured flags.
+type staticOverrideLabels map[string]bool
+
+func (s staticOverrideLabels) IsLabelEnabled(_ context.Context, label string) bool {
+	return s[label]
+}
+
+// evaluateOverrideLabels decides which progressive @override labels are active for one
+// request. "percent(N)" labels are active for N percent of requests; any other label is
+// delegated to the label provider.
+func (g *gateway) evaluateOverrideLabels(ctx context.Context, labels []string) map[string]bool {
+	active := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		if percent, ok := parsePercentLabel(label); ok {
+			active[label] = rand.Float64()*100 < percent
+			continue
+		}
+		if g.overrideLabels != nil {
+			active[label] = g.overrideLabels.IsLabelEnabled(ctx, label)
+		}
+	}
+	return active
+}
+
+// parsePercentLabel parses a "percent(N)" label, where N is between 0 and 100.
+func parsePercentLabel(label string) (float64, bool) {
+	if !strings.HasPrefix(label, "percent(") || !strings.HasSuffix(label, ")") {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimPrefix(label, "percent("), ")"), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, false
+	}
+	return percent, true
+}
@@ -0,0 +1,146 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+type labelProviderFunc func(ctx context.Context, label string) bool
+
+func (f labelProviderFunc) IsLabelEnabled(ctx context.Context, label string) bool {
+	return f(ctx, label)
+}
+
+func TestGateway_ProgressiveOverride(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+	newProductsV2SDL := func(label string) string {
+		return `
+			extend type Product @key(fields: "id") {
+				id: ID! @external
+				name: String! @override(from: "products", label: "` + label + `")
+			}
+		`
+	}
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "from-products"}}}
+	})
+
+	serve := func(t *testing.T, label string, opt gateway.GatewayOption) string {
+		t.Helper()
+		productsV2 := newSubgraphServer(t, newProductsV2SDL(label), func(body map[string]any) any {
+			return map[string]any{"data": map[string]any{"_entities": []any{
+				map[string]any{"__typename": "Product", "id": "1", "name": "from-products-v2"},
+			}}}
+		})
+
+		opt.Endpoint = "/graphql"
+		opt.Services = []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "products-v2", Host: productsV2.URL},
+		}
+		gw, err := gateway.NewGateway(opt)
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { name } }"}`)))
+		var resp struct {
+			Data struct {
+				Product struct {
+					Name string `json:"name"`
+				} `json:"product"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp.Data.Product.Name
+	}
+
+	tests := []struct {
+		name  string
+		label string
+		opt   gateway.GatewayOption
+		want  string
+	}{
+		{
+			name:  "inactive flag keeps the original owner",
+			label: "migrate-name",
+			want:  "from-products",
+		},
+		{
+			name:  "configured flag routes to the overriding subgraph",
+			label: "migrate-name",
+			opt:   gateway.GatewayOption{OverrideLabels: map[string]bool{"migrate-name": true}},
+			want:  "from-products-v2",
+		},
+		{
+			name:  "label provider decides custom labels",
+			label: "migrate-name",
+			opt: gateway.GatewayOption{
+				OverrideLabels: map[string]bool{"migrate-name": true},
+				OverrideLabelProvider: labelProviderFunc(func(_ context.Context, label string) bool {
+					return false
+				}),
+			},
+			want: "from-products",
+		},
+		{
+			name:  "percent(0) never overrides",
+			label: "percent(0)",
+			want:  "from-products",
+		},
+		{
+			name:  "percent(100) always overrides",
+			label: "percent(100)",
+			want:  "from-products-v2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(t, tt.label, tt.opt); got != tt.want {
+				t.Errorf("expected name %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePercentLabel(t *testing.T) {
+	tests := []struct {
+		label  string
+		want   float64
+		wantOK bool
+	}{
+		{label: "percent(25)", want: 25, wantOK: true},
+		{label: "percent(12.5)", want: 12.5, wantOK: true},
+		{label: "percent(101)"},
+		{label: "percent(abc)"},
+		{label: "migrate-name"},
+	}
+
+	for _, tt := range tests {
+		got, ok := gateway.ParsePercentLabelForTest(tt.label)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parsePercentLabel(%q) = %v, %v; want %v, %v", tt.label, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+// PinnedPlansOption configures the reviewed query plans executed instead of planning
+// the operations they are pinned for.
+type PinnedPlansOption struct {
+	Dir string `yaml:"dir"` // Directory of <operation hash>.json plans written by the pin command
+}
+
+// PinnedPlanStore looks up pinned query plans. The gateway caches the plans it reads
+// until the schema changes.
+type PinnedPlanStore interface {
+	// PinnedPlan returns the plan serialized with planner.PlanV2.Marshal that is pinned
+	// for the operation whose query text has the OperationHash hash.
+	PinnedPlan(ctx context.Context, hash string) ([]byte, bool, error)
+}
+
+// OperationHash returns the hex SHA-256 digest of query, the key of its pinned plan. It
+// matches the persistedQuery sha256Hash clients send for the same text.
+func OperationHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// DirPinnedPlans reads pinned plans from <hash>.json files in a directory.
+type DirPinnedPlans string
+
+// PinnedPlan reads the plan pinned for hash.
+func (d DirPinnedPlans) PinnedPlan(_ context.Context, hash string) ([]byte, bool, error) {
+	data, err := os.ReadFile(filepath.Join(string(d), hash+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read pinned plan %s: %w", hash, err)
+	}
+	return data, true, nil
+}
+
+// PinPlan plans operationName of query against the supergraph of bundle and returns the
+// operation hash and the serialized plan, to be reviewed and stored as <hash>.json.
+func PinPlan(bundle *SupergraphBundle, query, operationName string) (string, []byte, error) {
+	engine, err := buildEngine(bundle.Subgraphs, map[string]string{}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	p := parser.New(lexer.New(query))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		return "", nil, fmt.Errorf("failed to parse query: %v", p.Errors())
+	}
+	doc, _, err = selectOperation(doc, operationName)
+	if err != nil {
+		return "", nil, err
+	}
+	plan, err := engine.planner.Plan(doc, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to plan query: %w", err)
+	}
+	data, err := plan.Marshal(engine.schemaHash)
+	if err != nil {
+		return "", nil, err
+	}
+	return OperationHash(query), data, nil
+}
+
+// pinnedPlans resolves the pinned plan of an operation against the engine serving it.
+// Plans pinned against another schema are invalidated: they are logged, counted in the
+// graphql.plan.pinned.invalidated metric and the operation is planned as usual.
+type pinnedPlans struct {
+	store       PinnedPlanStore
+	invalidated metric.Int64Counter
+	labels      metric.MeasurementOption
+
+	mu     sync.Mutex
+	engine *executionEngine
+	plans  map[string]*planner.PlanV2 // Operation hash → plan, nil when none is usable
+}
+
+// newPinnedPlans returns the pinned plans of opt or store, or nil when there are none.
+func newPinnedPlans(opt PinnedPlansOption, store PinnedPlanStore, labels metric.MeasurementOption) (*pinnedPlans, error) {
+	if store == nil {
+		if opt.Dir == "" {
+			return nil, nil
+		}
+		store = DirPinnedPlans(opt.Dir)
+	}
+
+	invalidated, err := otel.Meter("github.com/n9te9/go-graphql-federation-gateway").Int64Counter(
+		"graphql.plan.pinned.invalidated",
+		metric.WithDescription("Number of pinned query plans ignored because the schema changed"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pinned plan counter: %w", err)
+	}
+	return &pinnedPlans{store: store, invalidated: invalidated, labels: labels}, nil
+}
+
+// lookup returns the plan pinned for the operation operationName of query, or nil when
+// it has no pinned plan usable with engine.
+func (p *pinnedPlans) lookup(ctx context.Context, engine *executionEngine, query, operationName string) *planner.PlanV2 {
+	hash := OperationHash(query)
+
+	p.mu.Lock()
+	if p.engine != engine {
+		p.engine = engine
+		p.plans = make(map[string]*planner.PlanV2)
+	}
+	plan, ok := p.plans[hash]
+	p.mu.Unlock()
+	if !ok {
+		var cache bool
+		plan, cache = p.load(ctx, engine.superGraph, engine.schemaHash, hash)
+		if cache {
+			p.mu.Lock()
+			if p.engine == engine {
+				p.plans[hash] = plan
+			}
+			p.mu.Unlock()
+		}
+	}
+
+	if plan == nil || plan.OperationName != operationName {
+		return nil
+	}
+	return plan
+}
+
+// load reads and decodes the plan pinned for hash. It reports whether the result may be
+// cached, which it may not be after a store error.
+func (p *pinnedPlans) load(ctx context.Context, superGraph *graph.SuperGraphV2, schemaHash, hash string) (*planner.PlanV2, bool) {
+	data, ok, err := p.store.PinnedPlan(ctx, hash)
+	if err != nil {
+		log.Printf("failed to look up pinned plan %s: %v", hash, err)
+		return nil, false
+	}
+	if !ok {
+		return nil, true
+	}
+
+	var plan planner.PlanV2
+	if err := plan.Unmarshal(data, superGraph, schemaHash); err != nil {
+		log.Printf("ignoring pinned plan %s: %v", hash, err)
+		if errors.Is(err, planner.ErrPlanSchemaMismatch) || errors.Is(err, planner.ErrPlanVersion) {
+			p.invalidated.Add(ctx, 1, metric.WithAttributes(attribute.String("graphql.operation.hash", hash)), p.labels)
+		}
+		return nil, true
+	}
+	return &plan, true
+}
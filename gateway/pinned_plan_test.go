@@ -0,0 +1,173 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// memoryPinnedPlans serves pinned plans from a map and counts lookups.
+type memoryPinnedPlans struct {
+	mu      sync.Mutex
+	plans   map[string][]byte
+	lookups int
+}
+
+func (m *memoryPinnedPlans) PinnedPlan(_ context.Context, hash string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lookups++
+	plan, ok := m.plans[hash]
+	return plan, ok, nil
+}
+
+func TestGateway_PinnedPlans(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		q, _ := body["query"].(string)
+		mu.Lock()
+		queries = append(queries, q)
+		mu.Unlock()
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "Table"}}}
+	})
+	sentQueries := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		sent := strings.Join(queries, "\n")
+		queries = nil
+		return sent
+	}
+
+	selectsName := regexp.MustCompile(`\bname\b`)
+
+	services := []gateway.GatewayService{{Name: "products", Host: products.URL}}
+	bundle, err := gateway.ComposeSupergraph(gateway.GatewayOption{Endpoint: "/graphql", Services: services})
+	if err != nil {
+		t.Fatalf("ComposeSupergraph failed: %v", err)
+	}
+
+	// The plan pinned for the query only fetches the product id, so executing it
+	// instead of planning the query is visible in the subgraph request.
+	const query = `{ product(id: "1") { id name } }`
+	_, reviewed, err := gateway.PinPlan(bundle, `{ product(id: "1") { id } }`, "")
+	if err != nil {
+		t.Fatalf("PinPlan failed: %v", err)
+	}
+	hash := gateway.OperationHash(query)
+
+	post := func(gw http.Handler, body string) map[string]any {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+	body, _ := json.Marshal(map[string]any{"query": query})
+
+	t.Run("pinned plan from a directory", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, hash+".json"), reviewed, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:    "/graphql",
+			Services:    services,
+			PinnedPlans: gateway.PinnedPlansOption{Dir: dir},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		sentQueries()
+		post(gw, string(body))
+		if sent := sentQueries(); selectsName.MatchString(sent) {
+			t.Errorf("expected the pinned plan to be executed, got %s", sent)
+		}
+
+		post(gw, `{"query":"{ product(id: \"2\") { id name } }"}`)
+		if sent := sentQueries(); !selectsName.MatchString(sent) {
+			t.Errorf("expected an operation without a pinned plan to be planned, got %s", sent)
+		}
+	})
+
+	t.Run("pinned plans are cached", func(t *testing.T) {
+		store := &memoryPinnedPlans{plans: map[string][]byte{hash: reviewed}}
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:        "/graphql",
+			Services:        services,
+			PinnedPlanStore: store,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		for range 3 {
+			post(gw, string(body))
+		}
+		if store.lookups != 1 {
+			t.Errorf("expected the pinned plan to be looked up once, got %d lookups", store.lookups)
+		}
+	})
+
+	t.Run("schema change invalidates the pinned plan", func(t *testing.T) {
+		var stale map[string]any
+		if err := json.Unmarshal(reviewed, &stale); err != nil {
+			t.Fatal(err)
+		}
+		stale["schemaHash"] = "previous-schema"
+		data, _ := json.Marshal(stale)
+
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:        "/graphql",
+			Services:        services,
+			PinnedPlanStore: &memoryPinnedPlans{plans: map[string][]byte{hash: data}},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		sentQueries()
+		resp := post(gw, string(body))
+		if sent := sentQueries(); !selectsName.MatchString(sent) {
+			t.Errorf("expected the operation to be planned again, got %s", sent)
+		}
+		product, _ := resp["data"].(map[string]any)["product"].(map[string]any)
+		if product["name"] != "Table" {
+			t.Errorf("unexpected response %v", resp)
+		}
+	})
+
+	t.Run("operation name must match", func(t *testing.T) {
+		const named = `query A { product(id: "1") { id name } } query B { product(id: "1") { name } }`
+		_, plan, err := gateway.PinPlan(bundle, named, "A")
+		if err != nil {
+			t.Fatalf("PinPlan failed: %v", err)
+		}
+		store := &memoryPinnedPlans{plans: map[string][]byte{gateway.OperationHash(named): plan}}
+		gw, err := gateway.NewGateway(gateway.GatewayOption{Endpoint: "/graphql", Services: services, PinnedPlanStore: store})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		req, _ := json.Marshal(map[string]any{"query": named, "operationName": "B"})
+		resp := post(gw, string(req))
+		product, _ := resp["data"].(map[string]any)["product"].(map[string]any)
+		if _, ok := product["id"]; ok {
+			t.Errorf("expected operation B to be planned, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,323 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/memcache"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/redis"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+// PlanCacheOption configures the cache of query plans. Plans are kept in memory (L1)
+// and, with an L2 backend, shared with the other replicas through Redis or memcached, so
+// after a deploy an operation is planned once for the whole fleet.
+type PlanCacheOption struct {
+	Enable     bool              `yaml:"enable" default:"false"`
+	MaxEntries int               `yaml:"max_entries" default:"1000"` // Plans kept in memory
+	L2         PlanCacheL2Option `yaml:"l2"`
+}
+
+// PlanCacheL2Option configures the plan cache shared by replicas. Plans are stored
+// serialized under <prefix>:<schema hash>:<operation hash>/<operation name>.
+type PlanCacheL2Option struct {
+	Backend  string                  `yaml:"backend" validate:"oneof=redis|memcached"` // redis or memcached; empty disables the L2 cache
+	Addr     string                  `yaml:"addr"`                                     // host:port
+	Username string                  `yaml:"username"`                                 // redis: ACL user sent with AUTH
+	Password string                  `yaml:"password"`                                 // redis: AUTH password
+	DB       int                     `yaml:"db"`                                       // redis: database number
+	TLS      registry.RedisTLSOption `yaml:"tls"`                                      // redis: TLS of the connections
+	Prefix   string                  `yaml:"prefix" default:"gateway:plan"`
+	TTL      string                  `yaml:"ttl" default:"24h" validate:"duration"`
+}
+
+// PlanCacheStore is a plan cache shared by gateway replicas, holding plans serialized
+// with planner.PlanV2.Marshal.
+type PlanCacheStore interface {
+	GetPlan(ctx context.Context, key string) ([]byte, bool, error)
+	SetPlan(ctx context.Context, key string, plan []byte, ttl time.Duration) error
+}
+
+// planCache caches the plans of the operations planned by the engine serving them.
+type planCache struct {
+	shared     PlanCacheStore // L2 cache; nil without one
+	prefix     string
+	ttl        time.Duration
+	maxEntries int
+
+	mu     sync.Mutex
+	engine *executionEngine
+	plans  map[string]*planner.PlanV2 // warmedPlanKey → plan
+}
+
+// newPlanCache returns the plan cache of opt, with store as its L2 cache when set, or nil
+// when caching is disabled.
+func newPlanCache(opt PlanCacheOption, store PlanCacheStore) (*planCache, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+	c := &planCache{
+		shared:     store,
+		prefix:     opt.L2.Prefix,
+		ttl:        24 * time.Hour,
+		maxEntries: opt.MaxEntries,
+		plans:      make(map[string]*planner.PlanV2),
+	}
+	if c.prefix == "" {
+		c.prefix = "gateway:plan"
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = 1000
+	}
+	if opt.L2.TTL != "" {
+		ttl, err := time.ParseDuration(opt.L2.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plan_cache.l2.ttl %q: %w", opt.L2.TTL, err)
+		}
+		c.ttl = ttl
+	}
+	if c.shared != nil || opt.L2.Backend == "" {
+		return c, nil
+	}
+
+	if opt.L2.Addr == "" {
+		return nil, fmt.Errorf("plan_cache.l2 requires addr")
+	}
+	switch opt.L2.Backend {
+	case "redis":
+		var tlsConfig *tls.Config
+		if opt.L2.TLS.Enable {
+			var err error
+			tlsConfig, err = redis.TLSConfig(opt.L2.Addr, opt.L2.TLS.ServerName, opt.L2.TLS.CAFile, opt.L2.TLS.InsecureSkipVerify)
+			if err != nil {
+				return nil, err
+			}
+		}
+		c.shared = &redisPlanCache{dial: redis.DialOptions{
+			Addr:     opt.L2.Addr,
+			Username: opt.L2.Username,
+			Password: opt.L2.Password,
+			DB:       opt.L2.DB,
+			TLS:      tlsConfig,
+		}}
+	case "memcached":
+		c.shared = &memcachedPlanCache{addr: opt.L2.Addr}
+	default:
+		return nil, fmt.Errorf("unsupported plan_cache.l2.backend %q", opt.L2.Backend)
+	}
+	return c, nil
+}
+
+// lookup returns the cached plan of the operation operationName of query for engine, or
+// nil when it has not been planned yet.
+func (c *planCache) lookup(ctx context.Context, engine *executionEngine, query, operationName string) *planner.PlanV2 {
+	key := warmedPlanKey(query, operationName)
+
+	c.mu.Lock()
+	if c.engine != engine {
+		c.engine = engine
+		c.plans = make(map[string]*planner.PlanV2)
+	}
+	plan, ok := c.plans[key]
+	c.mu.Unlock()
+	if ok {
+		return plan
+	}
+	if c.shared == nil {
+		return nil
+	}
+
+	data, ok, err := c.shared.GetPlan(ctx, c.storeKey(engine, key))
+	if err != nil {
+		log.Printf("failed to look up cached plan %s: %v", key, err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	plan = &planner.PlanV2{}
+	if err := plan.Unmarshal(data, engine.superGraph, engine.schemaHash); err != nil {
+		if !errors.Is(err, planner.ErrPlanVersion) {
+			log.Printf("ignoring cached plan %s: %v", key, err)
+		}
+		return nil
+	}
+	c.add(engine, key, plan)
+	return plan
+}
+
+// store caches plan, planned by engine for the operation operationName of query.
+func (c *planCache) store(ctx context.Context, engine *executionEngine, query, operationName string, plan *planner.PlanV2) {
+	key := warmedPlanKey(query, operationName)
+	c.add(engine, key, plan)
+	if c.shared == nil {
+		return
+	}
+
+	data, err := plan.Marshal(engine.schemaHash)
+	if err != nil {
+		log.Printf("failed to serialize plan %s: %v", key, err)
+		return
+	}
+	if err := c.shared.SetPlan(ctx, c.storeKey(engine, key), data, c.ttl); err != nil {
+		log.Printf("failed to cache plan %s: %v", key, err)
+	}
+}
+
+// add keeps plan in memory while engine serves requests.
+func (c *planCache) add(engine *executionEngine, key string, plan *planner.PlanV2) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.engine != engine {
+		return
+	}
+	if _, exists := c.plans[key]; !exists && len(c.plans) >= c.maxEntries {
+		for evicted := range c.plans {
+			delete(c.plans, evicted)
+			break
+		}
+	}
+	c.plans[key] = plan
+}
+
+// close closes the connection of the L2 cache.
+func (c *planCache) close() error {
+	if c == nil {
+		return nil
+	}
+	if closer, ok := c.shared.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// storeKey returns the L2 key of the plan stored under key for engine.
+func (c *planCache) storeKey(engine *executionEngine, key string) string {
+	return c.prefix + ":" + engine.schemaHash + ":" + key
+}
+
+// redisPlanCache stores plans in Redis, on one connection reopened after network errors.
+type redisPlanCache struct {
+	dial redis.DialOptions
+
+	mu   sync.Mutex
+	conn *redis.Conn
+}
+
+func (s *redisPlanCache) do(ctx context.Context, args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := redis.Dial(ctx, s.dial)
+		if err != nil {
+			return nil, err
+		}
+		s.conn = conn
+	}
+	reply, err := s.conn.Do(ctx, args...)
+	var redisErr redis.Error
+	if err != nil && !errors.As(err, &redisErr) {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return reply, err
+}
+
+// GetPlan implements PlanCacheStore.
+func (s *redisPlanCache) GetPlan(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	data, _ := reply.(string)
+	return []byte(data), true, nil
+}
+
+// SetPlan implements PlanCacheStore.
+func (s *redisPlanCache) SetPlan(ctx context.Context, key string, plan []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(plan)}
+	if ttl > 0 {
+		args = append(args, "PX", fmt.Sprint(ttl.Milliseconds()))
+	}
+	_, err := s.do(ctx, args...)
+	return err
+}
+
+// Close closes the connection.
+func (s *redisPlanCache) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// memcachedPlanCache stores plans in memcached, on one connection reopened after errors.
+type memcachedPlanCache struct {
+	addr string
+
+	mu   sync.Mutex
+	conn *memcache.Conn
+}
+
+// withConn runs fn on the shared connection, dropping it when fn fails.
+func (s *memcachedPlanCache) withConn(ctx context.Context, fn func(*memcache.Conn) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := memcache.Dial(ctx, s.addr)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if err := fn(s.conn); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// GetPlan implements PlanCacheStore.
+func (s *memcachedPlanCache) GetPlan(ctx context.Context, key string) ([]byte, bool, error) {
+	var data []byte
+	var ok bool
+	err := s.withConn(ctx, func(conn *memcache.Conn) error {
+		var err error
+		data, ok, err = conn.Get(ctx, key)
+		return err
+	})
+	return data, ok, err
+}
+
+// SetPlan implements PlanCacheStore.
+func (s *memcachedPlanCache) SetPlan(ctx context.Context, key string, plan []byte, ttl time.Duration) error {
+	return s.withConn(ctx, func(conn *memcache.Conn) error {
+		return conn.Set(ctx, key, plan, ttl)
+	})
+}
+
+// Close closes the connection.
+func (s *memcachedPlanCache) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,141 @@
+package gateway_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// fakeMemcached implements the get and set commands of memcached.
+type fakeMemcached struct {
+	ln net.Listener
+
+	mu     sync.Mutex
+	values map[string][]byte
+	gets   []string // keys of get commands, with the result: "hit" or "miss"
+	sets   []string // keys of set commands
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	m := &fakeMemcached{ln: ln, values: make(map[string][]byte)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(conn)
+		}
+	}()
+	return m
+}
+
+func (m *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 2 && fields[0] == "get":
+			m.mu.Lock()
+			value, ok := m.values[fields[1]]
+			m.gets = append(m.gets, fields[1]+" "+map[bool]string{true: "hit", false: "miss"}[ok])
+			m.mu.Unlock()
+			if ok {
+				fmt.Fprintf(conn, "VALUE %s 0 %d\r\n%s\r\n", fields[1], len(value), value)
+			}
+			fmt.Fprint(conn, "END\r\n")
+		case len(fields) == 5 && fields[0] == "set":
+			n, _ := strconv.Atoi(fields[4])
+			buf := make([]byte, n+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			m.mu.Lock()
+			m.values[fields[1]] = buf[:n]
+			m.sets = append(m.sets, fields[1])
+			m.mu.Unlock()
+			fmt.Fprint(conn, "STORED\r\n")
+		default:
+			fmt.Fprint(conn, "ERROR\r\n")
+		}
+	}
+}
+
+func (m *fakeMemcached) commands() (gets, sets []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.gets...), append([]string(nil), m.sets...)
+}
+
+func TestGateway_PlanCache(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "Table"}}}
+	})
+	memcached := newFakeMemcached(t)
+
+	newReplica := func(t *testing.T) http.Handler {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			PlanCache: gateway.PlanCacheOption{
+				Enable: true,
+				L2:     gateway.PlanCacheL2Option{Backend: "memcached", Addr: memcached.ln.Addr().String()},
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+	serve := func(t *testing.T, gw http.Handler) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql",
+			strings.NewReader(`{"query":"{ product(id: \"1\") { name } }"}`)))
+		if want := `{"data":{"product":{"name":"Table"}}}`; strings.TrimSpace(rec.Body.String()) != want {
+			t.Fatalf("expected %s, got %s", want, rec.Body.String())
+		}
+	}
+
+	first := newReplica(t)
+	serve(t, first)
+	gets, sets := memcached.commands()
+	if len(gets) != 1 || !strings.HasSuffix(gets[0], " miss") || len(sets) != 1 {
+		t.Fatalf("expected a missed lookup and a stored plan, got gets %v and sets %v", gets, sets)
+	}
+	if !strings.HasPrefix(sets[0], "gateway:plan:") || !strings.HasSuffix(gets[0], sets[0]+" miss") {
+		t.Errorf("expected the plan to be stored under its lookup key, got %q", sets[0])
+	}
+
+	serve(t, first)
+	if gets, _ := memcached.commands(); len(gets) != 1 {
+		t.Errorf("expected the second request to use the in-memory plan, got gets %v", gets)
+	}
+
+	serve(t, newReplica(t))
+	gets, sets = memcached.commands()
+	if len(gets) != 2 || gets[1] != sets[0]+" hit" || len(sets) != 1 {
+		t.Errorf("expected another replica to reuse the shared plan, got gets %v and sets %v", gets, sets)
+	}
+}
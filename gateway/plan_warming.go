@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+)
+
+// PersistedOperationsOption configures the persisted operation manifest. Every operation
+// in it is planned whenever a schema is installed, on startup and after each schema
+// update, so a schema breaking one of them is rejected before clients send it, and
+// requests for them skip planning.
+type PersistedOperationsOption struct {
+	Manifest string `yaml:"manifest"` // Apollo persisted query manifest, e.g. written by generate-persisted-query-manifest
+}
+
+// persistedOperation is an operation of a persisted query manifest.
+type persistedOperation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Body string `json:"body"`
+}
+
+// operationManifest is the Apollo persisted query manifest format.
+type operationManifest struct {
+	Format     string               `json:"format"`
+	Version    int                  `json:"version"`
+	Operations []persistedOperation `json:"operations"`
+}
+
+// loadOperationManifest reads the operations of the manifest at path, or none when path
+// is empty.
+func loadOperationManifest(path string) ([]persistedOperation, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persisted operation manifest: %w", err)
+	}
+	var manifest operationManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode persisted operation manifest %s: %w", path, err)
+	}
+	if manifest.Format != "apollo-persisted-query-manifest" || manifest.Version != 1 {
+		return nil, fmt.Errorf("unsupported persisted operation manifest %s: format %q version %d", path, manifest.Format, manifest.Version)
+	}
+	return manifest.Operations, nil
+}
+
+// warmPlans plans every persisted operation against engine, which must not serve requests
+// yet, and stores the plans in it. The errors of all operations that cannot be planned
+// are returned together.
+func (g *gateway) warmPlans(engine *executionEngine) error {
+	if len(g.persistedOperations) == 0 {
+		return nil
+	}
+
+	engine.plans = make(map[string]*planner.PlanV2, len(g.persistedOperations))
+	var errs []error
+	for _, operation := range g.persistedOperations {
+		plan, err := g.planOperation(engine, operation)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("persisted operation %q (%s): %w", operation.Name, operation.ID, err))
+			continue
+		}
+		engine.plans[warmedPlanKey(operation.Body, plan.OperationName)] = plan
+	}
+	return errors.Join(errs...)
+}
+
+// planOperation validates and plans operation like executeRequest.
+func (g *gateway) planOperation(engine *executionEngine, operation persistedOperation) (*planner.PlanV2, error) {
+	p := parser.New(lexer.New(operation.Body))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("failed to parse: %v", p.Errors())
+	}
+	doc, _, err := selectOperation(doc, operation.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.validateAccessibility(doc, engine); err != nil {
+		return nil, err
+	}
+	return engine.planner.Plan(doc, nil)
+}
+
+// warmedPlan returns the plan of the persisted operation operationName of query, or nil
+// when it is not persisted.
+func (e *executionEngine) warmedPlan(query, operationName string) *planner.PlanV2 {
+	return e.plans[warmedPlanKey(query, operationName)]
+}
+
+func warmedPlanKey(query, operationName string) string {
+	return OperationHash(query) + "/" + operationName
+}
@@ -0,0 +1,106 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+func TestGateway_PersistedOperationPlanWarming(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "hello.graphql")
+	if err := os.WriteFile(schema, []byte("type Query { hello: String }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := newSubgraphServer(t, "", func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"hello": "world"}}
+	})
+
+	writeManifest := func(t *testing.T, bodies ...string) string {
+		t.Helper()
+		operations := make([]map[string]any, 0, len(bodies))
+		for _, body := range bodies {
+			operations = append(operations, map[string]any{
+				"id":   gateway.OperationHash(body),
+				"name": strings.Fields(body)[1],
+				"type": "query",
+				"body": body,
+			})
+		}
+		data, _ := json.Marshal(map[string]any{
+			"format":     "apollo-persisted-query-manifest",
+			"version":    1,
+			"operations": operations,
+		})
+		path := filepath.Join(dir, "manifest.json")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	options := func(manifest string) gateway.GatewayOption {
+		return gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{{
+				Name:   "hello",
+				Host:   srv.URL,
+				Source: &registry.SourceOption{Type: "file", Path: schema},
+			}},
+			PersistedOperations: gateway.PersistedOperationsOption{Manifest: manifest},
+		}
+	}
+
+	t.Run("operations that cannot be planned fail startup", func(t *testing.T) {
+		_, err := gateway.NewGateway(options(writeManifest(t, "query Hello { hello }", "query World { world }")))
+		if err == nil || !strings.Contains(err.Error(), `"World"`) || strings.Contains(err.Error(), `"Hello"`) {
+			t.Errorf("expected only World to fail planning, got %v", err)
+		}
+	})
+
+	t.Run("schema updates breaking operations are rejected", func(t *testing.T) {
+		gw, err := gateway.NewGateway(options(writeManifest(t, "query Hello { hello }")))
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		defer gw.Close()
+
+		hello := func() string {
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query Hello { hello }"}`)))
+			return rec.Body.String()
+		}
+		if body := hello(); !strings.Contains(body, `"world"`) {
+			t.Fatalf("unexpected response %s", body)
+		}
+
+		if err := os.WriteFile(schema, []byte("type Query { world: String }"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hello/apply", nil))
+		if rec.Code == http.StatusOK || !strings.Contains(rec.Body.String(), "persisted operations") {
+			t.Errorf("expected the update to be rejected, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if body := hello(); !strings.Contains(body, `"world"`) {
+			t.Errorf("expected the previous schema to keep serving, got %s", body)
+		}
+	})
+
+	t.Run("invalid manifests fail startup", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		if err := os.WriteFile(path, []byte(`{"format":"other","version":1}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gateway.NewGateway(options(path)); err == nil {
+			t.Error("expected an unsupported manifest to be rejected")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// ExtensionPropagationOption forwards allowlisted client request extensions (e.g.
+// persistedQuery, feature flags, locale) to every subgraph request.
+type ExtensionPropagationOption struct {
+	Extensions []string          `yaml:"extensions"` // Extensions forwarded as-is in the subgraph request body
+	Headers    map[string]string `yaml:"headers"`    // Extension → subgraph request header carrying its value
+}
+
+// extensionPropagation forwards the client extensions allowed by an
+// ExtensionPropagationOption.
+type extensionPropagation struct {
+	extensions []string
+	headers    map[string]string
+}
+
+// newExtensionPropagation returns the propagation of opt, or nil when nothing is forwarded.
+func newExtensionPropagation(opt ExtensionPropagationOption) *extensionPropagation {
+	if len(opt.Extensions) == 0 && len(opt.Headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(opt.Headers))
+	for name, header := range opt.Headers {
+		headers[name] = http.CanonicalHeaderKey(header)
+	}
+	return &extensionPropagation{extensions: opt.Extensions, headers: headers}
+}
+
+// apply attaches the allowlisted entries of extensions to ctx for the subgraph requests.
+func (p *extensionPropagation) apply(ctx context.Context, extensions map[string]any) context.Context {
+	if len(extensions) == 0 {
+		return ctx
+	}
+
+	forwarded := make(map[string]any)
+	for _, name := range p.extensions {
+		if v, ok := extensions[name]; ok {
+			forwarded[name] = v
+		}
+	}
+	if len(forwarded) > 0 {
+		ctx = executor.SetSubgraphExtensionsToContext(ctx, forwarded)
+	}
+
+	header := make(http.Header)
+	for name, key := range p.headers {
+		v, ok := extensions[name]
+		if !ok || v == nil {
+			continue
+		}
+		value, err := extensionHeaderValue(v)
+		if err != nil {
+			continue
+		}
+		header.Set(key, value)
+	}
+	if len(header) > 0 {
+		ctx = executor.SetSubgraphHeadersToContext(ctx, header)
+	}
+
+	return ctx
+}
+
+// extensionHeaderValue renders an extension value as a header value: strings verbatim,
+// anything else as JSON.
+func extensionHeaderValue(v any) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode extension: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,75 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ForwardExtensions(t *testing.T) {
+	var mu sync.Mutex
+	var gotBody map[string]any
+	var gotHeader http.Header
+	subgraph := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdlProducts}}}) //nolint:errcheck
+			return
+		}
+		mu.Lock()
+		gotBody, gotHeader = body, r.Header.Clone()
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"product": map[string]any{"id": "1"}}}) //nolint:errcheck
+	}))
+	t.Cleanup(subgraph.Close)
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: subgraph.URL}},
+		ForwardExtensions: gateway.ExtensionPropagationOption{
+			Extensions: []string{"persistedQuery"},
+			Headers:    map[string]string{"locale": "accept-language", "featureFlags": "X-Feature-Flags"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	body := `{"query":"{ product(id: \"1\") { id } }","extensions":{` +
+		`"persistedQuery":{"version":1,"sha256Hash":"abc"},` +
+		`"locale":"ja-JP",` +
+		`"featureFlags":{"checkout":true},` +
+		`"secret":"dropped"}}`
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	extensions, _ := gotBody["extensions"].(map[string]any)
+	if len(extensions) != 1 {
+		t.Fatalf("expected only persistedQuery to be forwarded in the body, got %v", gotBody["extensions"])
+	}
+	if pq, _ := extensions["persistedQuery"].(map[string]any); pq["sha256Hash"] != "abc" {
+		t.Errorf("expected persistedQuery to be forwarded as-is, got %v", extensions["persistedQuery"])
+	}
+	if got := gotHeader.Get("Accept-Language"); got != "ja-JP" {
+		t.Errorf("expected the locale header, got %q", got)
+	}
+	if got := gotHeader.Get("X-Feature-Flags"); got != `{"checkout":true}` {
+		t.Errorf("expected the feature flags as JSON, got %q", got)
+	}
+	if got := gotHeader.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected Content-Type to be kept, got %q", got)
+	}
+}
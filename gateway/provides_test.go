@@ -0,0 +1,125 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_NestedProvides(t *testing.T) {
+	const (
+		sdlProducts = `
+			type Product @key(fields: "upc") {
+				upc: String!
+				name: String!
+				price: Int!
+				manufacturer: Manufacturer!
+			}
+
+			type Manufacturer @key(fields: "id") {
+				id: ID!
+				name: String!
+				country: String!
+			}
+
+			type Query {
+				topProducts: [Product!]!
+			}
+		`
+		sdlReviews = `
+			type Review {
+				body: String!
+				product: Product! @provides(fields: "name manufacturer { name }")
+			}
+
+			extend type Product @key(fields: "upc") {
+				upc: String! @external
+				name: String! @external
+				manufacturer: Manufacturer! @external
+			}
+
+			extend type Manufacturer @key(fields: "id") {
+				id: ID! @external
+				name: String! @external
+			}
+
+			type Query {
+				reviews: [Review!]!
+			}
+		`
+	)
+
+	var productFetches atomic.Int32
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		productFetches.Add(1)
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		result := make([]any, 0, len(reps))
+		for _, r := range reps {
+			rep := r.(map[string]any)
+			result = append(result, map[string]any{
+				"__typename":   "Product",
+				"upc":          rep["upc"],
+				"price":        10,
+				"manufacturer": map[string]any{"__typename": "Manufacturer", "country": "JP"},
+			})
+		}
+		return map[string]any{"data": map[string]any{"_entities": result}}
+	})
+	reviews := newSubgraphServer(t, sdlReviews, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"reviews": []any{
+			map[string]any{"__typename": "Review", "body": "great", "product": map[string]any{
+				"__typename":   "Product",
+				"upc":          "1",
+				"name":         "Lamp",
+				"manufacturer": map[string]any{"__typename": "Manufacturer", "id": "m1", "name": "Acme"},
+			}},
+		}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "reviews", Host: reviews.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	query := func(q string) string {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"query": q})
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	got := query(`{ reviews { body product { name manufacturer { name } } } }`)
+	want := `{"data":{"reviews":[{"body":"great","product":{"name":"Lamp","manufacturer":{"name":"Acme"}}}]}}`
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if n := productFetches.Load(); n != 0 {
+		t.Errorf("expected provided fields to need no products fetch, got %d", n)
+	}
+
+	got = query(`{ reviews { product { name price manufacturer { name country } } } }`)
+	want = `{"data":{"reviews":[{"product":{"name":"Lamp","price":10,"manufacturer":{"name":"Acme","country":"JP"}}}]}}`
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if n := productFetches.Load(); n != 1 {
+		t.Errorf("expected one products fetch for the fields not provided, got %d", n)
+	}
+}
@@ -0,0 +1,80 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_PruneUnfetchableFields(t *testing.T) {
+	const sdl = `
+		type Query {
+			hello: String
+		}`
+	hello := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"hello": "world"}}
+	})
+
+	query := func(t *testing.T, prune bool, query string) map[string]any {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:               "/graphql",
+			Services:               []gateway.GatewayService{{Name: "hello", Host: hello.URL}},
+			PruneUnfetchableFields: prune,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		resp := query(t, false, `{"query":"{ hello old: removed }"}`)
+		if resp["data"] != nil {
+			t.Errorf("expected the operation to fail, got %v", resp)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		resp := query(t, true, `{"query":"{ hello old: removed }"}`)
+		data, _ := resp["data"].(map[string]any)
+		if data["hello"] != "world" {
+			t.Errorf("expected the other fields to be executed, got %v", resp)
+		}
+		if v, ok := data["old"]; !ok || v != nil {
+			t.Errorf("expected old to be null, got %v", resp)
+		}
+
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 1 {
+			t.Fatalf("expected 1 error, got %v", resp["errors"])
+		}
+		gqlErr := errs[0].(map[string]any)
+		ext, _ := gqlErr["extensions"].(map[string]any)
+		if ext["code"] != "UNFETCHABLE_FIELD" {
+			t.Errorf("expected code UNFETCHABLE_FIELD, got %v", gqlErr)
+		}
+		if path, _ := gqlErr["path"].([]any); len(path) != 1 || path[0] != "old" {
+			t.Errorf("expected path [old], got %v", gqlErr["path"])
+		}
+	})
+
+	t.Run("every field pruned", func(t *testing.T) {
+		resp := query(t, true, `{"query":"{ removed }"}`)
+		data, _ := resp["data"].(map[string]any)
+		if v, ok := data["removed"]; !ok || v != nil {
+			t.Errorf("expected removed to be null, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// QuerySanitizationOption configures the sanitization of query text before it leaves the
+// gateway in logs, usage reports or spans: literal arguments, which may carry emails or
+// tokens, are replaced with placeholders, and variable values with their hashes.
+type QuerySanitizationOption struct {
+	Enable       bool   `yaml:"enable" default:"false"`
+	HashKey      string `yaml:"hash_key"`                      // Key of the HMAC-SHA256 hashing variable values, so that common values cannot be guessed from their hashes; plain SHA-256 when empty
+	SpanDocument bool   `yaml:"span_document" default:"false"` // Attach the sanitized operation to the request span as graphql.document
+}
+
+// Placeholders of the literals of sanitized queries.
+const (
+	sanitizedString = `"` + redactedValue + `"`
+	sanitizedNumber = "0"
+)
+
+// querySanitizer sanitizes query text and variables. A nil sanitizer returns them as
+// they are.
+type querySanitizer struct {
+	hashKey      []byte
+	spanDocument bool
+}
+
+// newQuerySanitizer returns nil when queries are not sanitized.
+func newQuerySanitizer(opt QuerySanitizationOption) *querySanitizer {
+	if !opt.Enable {
+		return nil
+	}
+	return &querySanitizer{hashKey: []byte(opt.HashKey), spanDocument: opt.SpanDocument}
+}
+
+// query returns query with its string and number literals replaced with placeholders and
+// its comments removed.
+func (s *querySanitizer) query(query string) string {
+	if s == nil {
+		return query
+	}
+	return SanitizeQuery(query)
+}
+
+// variables returns variables with each value replaced with its hash, so that requests
+// with the same values can still be told apart. Null values are kept.
+func (s *querySanitizer) variables(variables map[string]any) map[string]any {
+	if s == nil || variables == nil {
+		return variables
+	}
+	hashed := make(map[string]any, len(variables))
+	for name, value := range variables {
+		if value == nil {
+			hashed[name] = nil
+			continue
+		}
+		hashed[name] = s.hash(value)
+	}
+	return hashed
+}
+
+// hash returns the hash of the JSON encoding of value, prefixed with its algorithm.
+func (s *querySanitizer) hash(value any) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return redactedValue
+	}
+	var h hash.Hash
+	prefix := "sha256:"
+	if len(s.hashKey) > 0 {
+		h, prefix = hmac.New(sha256.New, s.hashKey), "hmac-sha256:"
+	} else {
+		h = sha256.New()
+	}
+	h.Write(b)
+	return prefix + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// requestBody returns a subgraph request body with the query and variables of each
+// operation sanitized. Bodies that are not JSON are returned as they are.
+func (s *querySanitizer) requestBody(body []byte) []byte {
+	if s == nil || !json.Valid(body) {
+		return body
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	operations, batched := v.([]any)
+	if !batched {
+		operations = []any{v}
+	}
+	for _, op := range operations {
+		m, ok := op.(map[string]any)
+		if !ok {
+			continue
+		}
+		if query, ok := m["query"].(string); ok {
+			m["query"] = s.query(query)
+		}
+		if variables, ok := m["variables"].(map[string]any); ok {
+			m["variables"] = s.variables(variables)
+		}
+	}
+	sanitized, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return sanitized
+}
+
+// SanitizeQuery returns query with its string literals replaced with "[REDACTED]", its
+// number literals with 0 and its comments removed, keeping the rest of the text as it
+// is. Enum, boolean and null literals and variable references are kept.
+func SanitizeQuery(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+
+		case strings.HasPrefix(query[i:], `"""`):
+			end := i + 3
+			for end < len(query) && !strings.HasPrefix(query[end:], `"""`) {
+				if strings.HasPrefix(query[end:], `\"""`) {
+					end += 4
+					continue
+				}
+				end++
+			}
+			i = min(end+3, len(query))
+			sb.WriteString(sanitizedString)
+
+		case c == '"':
+			end := i + 1
+			for end < len(query) && query[end] != '"' && query[end] != '\n' {
+				if query[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			i = min(end+1, len(query))
+			sb.WriteString(sanitizedString)
+
+		case (isDigit(c) || c == '-' && i+1 < len(query) && isDigit(query[i+1])) && (i == 0 || !isNameChar(query[i-1])):
+			i++
+			for i < len(query) && (isNameChar(query[i]) || query[i] == '.' ||
+				(query[i] == '+' || query[i] == '-') && (query[i-1] == 'e' || query[i-1] == 'E')) {
+				i++
+			}
+			sb.WriteString(sanitizedNumber)
+
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// isDigit reports whether c is a decimal digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isNameChar reports whether c may appear in a GraphQL name.
+func isNameChar(c byte) bool {
+	return c == '_' || isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,114 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestSanitizeQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:     "string and number literals",
+			query:    `{ user(email: "alice@example.com", age: 42, score: -1.5e+3) { name } }`,
+			expected: `{ user(email: "[REDACTED]", age: 0, score: 0) { name } }`,
+		},
+		{
+			name:     "escaped quotes and block strings",
+			query:    `{ login(token: "a\"b", note: """multi "quoted" line""") { ok } }`,
+			expected: `{ login(token: "[REDACTED]", note: "[REDACTED]") { ok } }`,
+		},
+		{
+			name:     "lists, input objects and variable defaults",
+			query:    `query Q($limit: Int = 10) { search(filter: {tags: ["a", "b"], page: 2}, first: $limit) { id } }`,
+			expected: `query Q($limit: Int = 0) { search(filter: {tags: ["[REDACTED]", "[REDACTED]"], page: 0}, first: $limit) { id } }`,
+		},
+		{
+			name:     "names with digits, enums, booleans and null are kept",
+			query:    `query Q2($v0: ID) { item1: item(id: $v0, sort: ASC, deleted: false, parent: null) { ...f0 } }`,
+			expected: `query Q2($v0: ID) { item1: item(id: $v0, sort: ASC, deleted: false, parent: null) { ...f0 } }`,
+		},
+		{
+			name:     "comments are removed",
+			query:    "{\n  # password hunter2\n  me { id }\n}",
+			expected: "{\n  \n  me { id }\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gateway.SanitizeQuery(tt.query); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGateway_QuerySanitization(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{
+			"byVariable": map[string]any{"id": "1", "name": "chair"},
+			"byLiteral":  map[string]any{"id": "2", "name": "desk"},
+		}}
+	})
+	collector, registry := newUsageCollector(t)
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:          "/graphql",
+		Services:          []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		SubgraphLog:       gateway.SubgraphLogOption{Enable: true, MinRequestBytes: 1},
+		UsageReporting:    gateway.UsageReportingOption{Enable: true, Target: "webhook", Endpoint: registry.URL, Interval: "1h"},
+		QuerySanitization: gateway.QuerySanitizationOption{Enable: true, HashKey: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	records := captureSubgraphExchanges(t)
+
+	body, _ := json.Marshal(map[string]any{
+		"query":     `query Products($id: ID!) { byVariable: product(id: $id) { name } byLiteral: product(id: "alice@example.com") { name } }`,
+		"variables": map[string]any{"id": "bob@example.com"},
+	})
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	if !strings.Contains(rec.Body.String(), `"byLiteral":{"name":"desk"}`) {
+		t.Fatalf("expected the literal to reach the subgraph, got %s", rec.Body.String())
+	}
+	gw.Close()
+
+	logged := records()
+	if len(logged) != 1 {
+		t.Fatalf("expected the subgraph exchange to be logged, got %v", logged)
+	}
+	request, _ := logged[0]["request"].(string)
+	if strings.Contains(request, "@example.com") {
+		t.Errorf("expected the logged request to carry no literal or variable value, got %s", request)
+	}
+	if !strings.Contains(request, `(id: \"[REDACTED]\")`) || !strings.Contains(request, `"hmac-sha256:`) {
+		t.Errorf("expected the literal to be redacted and the variable hashed, got %s", request)
+	}
+
+	if len(collector.bodies) != 1 {
+		t.Fatalf("expected 1 usage report, got %d", len(collector.bodies))
+	}
+	var report struct {
+		Operations []struct {
+			Document string `json:"document"`
+		} `json:"operations"`
+	}
+	if err := json.Unmarshal(collector.bodies[0], &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if len(report.Operations) != 1 || strings.Contains(report.Operations[0].Document, "@example.com") ||
+		!strings.Contains(report.Operations[0].Document, `"[REDACTED]"`) {
+		t.Errorf("expected the reported document to be sanitized, got %s", collector.bodies[0])
+	}
+}
@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// FieldRateLimitOption enables the limits subgraph owners set on root fields with
+// @rateLimit(max: 10, window: "1m"): a client selects the field at most max times per
+// window, after which the field resolves to null with a RATE_LIMITED error while the
+// rest of the operation executes.
+type FieldRateLimitOption struct {
+	Enable       bool   `yaml:"enable" default:"false"`
+	ClientHeader string `yaml:"client_header"` // Request header identifying the client; the client identity, then the remote IP, when unset or absent
+}
+
+// fieldRateLimit is a parsed @rateLimit directive.
+type fieldRateLimit struct {
+	max    int
+	window time.Duration
+}
+
+// rateLimitDirective reads the @rateLimit directive in directives. Directives without a
+// positive max or a valid window are ignored.
+func rateLimitDirective(directives []*ast.Directive) (fieldRateLimit, bool) {
+	for _, d := range directives {
+		if d.Name != "rateLimit" {
+			continue
+		}
+		var limit fieldRateLimit
+		for _, arg := range d.Arguments {
+			value := strings.Trim(arg.Value.String(), "\"")
+			switch arg.Name.String() {
+			case "max":
+				limit.max, _ = strconv.Atoi(value)
+			case "window":
+				limit.window, _ = time.ParseDuration(value)
+			}
+		}
+		return limit, limit.max > 0 && limit.window > 0
+	}
+	return fieldRateLimit{}, false
+}
+
+// fieldRateWindowKey identifies the counter of one client for one field.
+type fieldRateWindowKey struct {
+	coordinate string
+	client     string
+}
+
+// fieldRateWindow counts the selections of a field in a fixed window.
+type fieldRateWindow struct {
+	end   time.Time
+	count int
+}
+
+// fieldRateLimiter enforces @rateLimit per client with fixed windows.
+type fieldRateLimiter struct {
+	clientHeader string
+	now          func() time.Time
+
+	mu        sync.Mutex
+	windows   map[fieldRateWindowKey]*fieldRateWindow
+	lastSweep time.Time
+}
+
+// newFieldRateLimiter returns the limiter for opt, or nil when the limits are disabled.
+func newFieldRateLimiter(opt FieldRateLimitOption) *fieldRateLimiter {
+	if !opt.Enable {
+		return nil
+	}
+	return &fieldRateLimiter{
+		clientHeader: opt.ClientHeader,
+		now:          time.Now,
+		windows:      make(map[fieldRateWindowKey]*fieldRateWindow),
+	}
+}
+
+type rateLimitClientContextKey struct{}
+
+// withClient attaches the client of r to ctx.
+func (l *fieldRateLimiter) withClient(ctx context.Context, r *http.Request) context.Context {
+	if l == nil {
+		return ctx
+	}
+	client := ""
+	if l.clientHeader != "" {
+		client = r.Header.Get(l.clientHeader)
+	}
+	if client == "" {
+		client = ClientInfoFromContext(ctx).Name
+	}
+	if client == "" {
+		client, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+	return context.WithValue(ctx, rateLimitClientContextKey{}, client)
+}
+
+// allow counts one selection of coordinate by client and reports whether it is within
+// limit.
+func (l *fieldRateLimiter) allow(coordinate, client string, limit fieldRateLimit) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	key := fieldRateWindowKey{coordinate: coordinate, client: client}
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.end) {
+		w = &fieldRateWindow{end: now.Add(limit.window)}
+		l.windows[key] = w
+	}
+	if w.count >= limit.max {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// sweep drops the expired windows, at most once a minute, so clients that went away
+// do not keep their counters. The caller must hold l.mu.
+func (l *fieldRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, w := range l.windows {
+		if !now.Before(w.end) {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// limitedField is a root field dropped from an operation by its @rateLimit.
+type limitedField struct {
+	responseKey string
+	coordinate  string
+}
+
+// apply counts the @rateLimit root fields op selects for the client of ctx. It returns
+// the document to plan, without the fields over their limit, and those fields. doc is
+// returned unchanged when no field is over its limit, and nil when no field is left.
+func (l *fieldRateLimiter) apply(ctx context.Context, doc *ast.Document, op *ast.OperationDefinition, engine *executionEngine) (*ast.Document, []limitedField) {
+	if l == nil {
+		return doc, nil
+	}
+	client, _ := ctx.Value(rateLimitClientContextKey{}).(string)
+	rootTypeName := engine.superGraph.RootTypeName(op.Operation)
+
+	var limited []limitedField
+	filtered, filteredOp := rewriteRootFields(doc, op, func(field *ast.Field) *ast.Field {
+		fieldDef := engine.superGraph.FieldDefinition(rootTypeName, field.Name.String())
+		if fieldDef == nil {
+			return field
+		}
+		limit, ok := rateLimitDirective(fieldDef.Directives)
+		if !ok {
+			return field
+		}
+		coordinate := rootTypeName + "." + field.Name.String()
+		if l.allow(coordinate, client, limit) {
+			return field
+		}
+		limited = append(limited, limitedField{responseKey: fieldResponseKey(field), coordinate: coordinate})
+		return nil
+	})
+	if len(limited) > 0 && !selectsField(filtered, filteredOp.SelectionSet) {
+		return nil, limited
+	}
+	return filtered, limited
+}
+
+// addLimitedFields sets the fields dropped by their @rateLimit to null in resp, with an
+// error each.
+func addLimitedFields(resp map[string]any, limited []limitedField) {
+	if data, ok := resp["data"].(map[string]any); ok {
+		for _, field := range limited {
+			data[field.responseKey] = nil
+		}
+	} else if _, ok := resp["data"]; !ok {
+		data := make(map[string]any, len(limited))
+		for _, field := range limited {
+			data[field.responseKey] = nil
+		}
+		resp["data"] = data
+	}
+
+	errs, _ := resp["errors"].([]executor.GraphQLError)
+	for _, field := range limited {
+		errs = append(errs, executor.GraphQLError{
+			Message:    fmt.Sprintf("field %s exceeded its rate limit", field.coordinate),
+			Path:       []interface{}{field.responseKey},
+			Extensions: map[string]interface{}{"code": CodeRateLimited},
+		})
+	}
+	resp["errors"] = errs
+}
@@ -0,0 +1,89 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_FieldRateLimits(t *testing.T) {
+	const sdl = `
+		type Query {
+			hello: String
+			report: String @rateLimit(max: 2, window: "1h")
+		}`
+	srv := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		data := map[string]any{}
+		if q, _ := body["query"].(string); strings.Contains(q, "hello") {
+			data["hello"] = "world"
+		}
+		if q, _ := body["query"].(string); strings.Contains(q, "report") {
+			data["report"] = "done"
+		}
+		return map[string]any{"data": data}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:        "/graphql",
+		Services:        []gateway.GatewayService{{Name: "hello", Host: srv.URL}},
+		FieldRateLimits: gateway.FieldRateLimitOption{Enable: true, ClientHeader: "X-Client-Id"},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	query := func(t *testing.T, client, query string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query))
+		req.Header.Set("X-Client-Id", client)
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		resp := query(t, "a", `{"query":"{ hello report }"}`)
+		if data, _ := resp["data"].(map[string]any); data["report"] != "done" || resp["errors"] != nil {
+			t.Fatalf("expected request %d within the limit to execute, got %v", i+1, resp)
+		}
+	}
+
+	resp := query(t, "a", `{"query":"{ hello r: report }"}`)
+	data, _ := resp["data"].(map[string]any)
+	if data["hello"] != "world" {
+		t.Errorf("expected the other fields to be executed, got %v", resp)
+	}
+	if v, ok := data["r"]; !ok || v != nil {
+		t.Errorf("expected the limited field to be null, got %v", resp)
+	}
+	errs, _ := resp["errors"].([]any)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", resp["errors"])
+	}
+	gqlErr := errs[0].(map[string]any)
+	if ext, _ := gqlErr["extensions"].(map[string]any); ext["code"] != "RATE_LIMITED" {
+		t.Errorf("expected code RATE_LIMITED, got %v", gqlErr)
+	}
+	if path, _ := gqlErr["path"].([]any); len(path) != 1 || path[0] != "r" {
+		t.Errorf("expected path [r], got %v", gqlErr["path"])
+	}
+
+	resp = query(t, "a", `{"query":"query { ...F } fragment F on Query { report }"}`)
+	if data, _ := resp["data"].(map[string]any); data == nil || data["report"] != nil || resp["errors"] == nil {
+		t.Errorf("expected the limited field selected through a fragment to be null, got %v", resp)
+	}
+
+	resp = query(t, "b", `{"query":"{ report }"}`)
+	if data, _ := resp["data"].(map[string]any); data["report"] != "done" {
+		t.Errorf("expected another client to keep its own limit, got %v", resp)
+	}
+}
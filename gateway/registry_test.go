@@ -0,0 +1,96 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+func TestGateway_SchemaRegistry(t *testing.T) {
+	var mu sync.Mutex
+	sdl := "type Query { hello: String }"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	store := registry.NewMemoryStore(0)
+	newReplica := func() http.Handler {
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:           "/graphql",
+			EnableSubgraphMode: true,
+			Services:           []gateway.GatewayService{{Name: "hello", Host: srv.URL}},
+			SchemaRegistry:     store,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		t.Cleanup(func() { gw.Close() })
+		return gw
+	}
+	composedSDL := func(gw http.Handler) string {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ _service { sdl } }"}`)))
+		var resp struct {
+			Data struct {
+				Service struct {
+					SDL string `json:"sdl"`
+				} `json:"_service"`
+			} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp) //nolint:errcheck
+		return resp.Data.Service.SDL
+	}
+	waitFor := func(gw http.Handler, want string, present bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for strings.Contains(composedSDL(gw), want) != present {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %q present=%v in %q", want, present, composedSDL(gw))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	first := newReplica()
+	second := newReplica()
+	if current, _ := store.Current(context.Background()); current == nil || current.Version != 1 {
+		t.Fatalf("expected the first replica to publish version 1, got %+v", current)
+	}
+
+	// A schema applied on one replica is installed by the other.
+	mu.Lock()
+	sdl = "type Query { hello: String world: String }"
+	mu.Unlock()
+	rec := httptest.NewRecorder()
+	first.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hello/apply", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected apply to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	waitFor(second, "world: String", true)
+
+	// Rolling back the registry rolls back every replica.
+	if _, err := store.Rollback(context.Background(), 1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	waitFor(first, "world: String", false)
+	waitFor(second, "world: String", false)
+
+	// New replicas start from the current registry version.
+	third := newReplica()
+	if strings.Contains(composedSDL(third), "world: String") {
+		t.Error("expected a new replica to install the current registry version")
+	}
+}
@@ -0,0 +1,371 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// Encodings of the global IDs decoded by the Relay node field.
+const (
+	NodeIDEncodingBase64 = "base64" // base64 of <typename><separator><key values>, Relay's convention
+	NodeIDEncodingPlain  = "plain"  // <typename><separator><key values> as it is
+)
+
+// RelayNodeOption configures the Relay node(id: ID!) root field the gateway serves
+// itself: the global ID names the entity type and the values of its key, and the entity
+// is fetched from its owning subgraph with _entities, so Relay clients get node support
+// even when no subgraph implements it.
+type RelayNodeOption struct {
+	Enable    bool   `yaml:"enable" default:"false"`
+	Encoding  string `yaml:"encoding" default:"base64" validate:"oneof=base64|plain"` // How global IDs are encoded: base64 or plain
+	Separator string `yaml:"separator" default:":"`                                   // Between the typename and each key value, e.g. Flight:NY123:2024-01-01
+}
+
+// NodeIDCodec decodes global IDs into an entity type and the values of the fields of
+// its first @key in the owning subgraph, in key order.
+type NodeIDCodec interface {
+	DecodeNodeID(id string) (typeName string, keyValues []string, err error)
+}
+
+// nodeIDCodec is the NodeIDCodec of a RelayNodeOption.
+type nodeIDCodec struct {
+	base64    bool
+	separator string
+}
+
+// DecodeNodeID implements NodeIDCodec.
+func (c nodeIDCodec) DecodeNodeID(id string) (string, []string, error) {
+	decoded := id
+	if c.base64 {
+		b, err := base64.StdEncoding.DecodeString(id)
+		if err != nil {
+			b, err = base64.RawURLEncoding.DecodeString(id)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("global ID %q is not base64", id)
+		}
+		decoded = string(b)
+	}
+	parts := strings.Split(decoded, c.separator)
+	if len(parts) < 2 || parts[0] == "" {
+		return "", nil, fmt.Errorf("global ID %q does not name a type and a key", id)
+	}
+	return parts[0], parts[1:], nil
+}
+
+// EncodeNodeID returns the global ID of the entity typeName with the given key values,
+// as decoded by the node field of opt, so that subgraphs and tests can build them.
+func EncodeNodeID(opt RelayNodeOption, typeName string, keyValues ...string) string {
+	separator := opt.Separator
+	if separator == "" {
+		separator = ":"
+	}
+	id := strings.Join(append([]string{typeName}, keyValues...), separator)
+	if opt.Encoding == NodeIDEncodingPlain {
+		return id
+	}
+	return base64.StdEncoding.EncodeToString([]byte(id))
+}
+
+// relayNode serves the node root field.
+type relayNode struct {
+	codec NodeIDCodec
+}
+
+// newRelayNode returns nil when the node field is disabled. codec, when set, decodes
+// global IDs instead of the encoding of opt.
+func newRelayNode(opt RelayNodeOption, codec NodeIDCodec) (*relayNode, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+	if codec == nil {
+		switch opt.Encoding {
+		case "", NodeIDEncodingBase64, NodeIDEncodingPlain:
+		default:
+			return nil, fmt.Errorf("unsupported relay_node.encoding %q", opt.Encoding)
+		}
+		separator := opt.Separator
+		if separator == "" {
+			separator = ":"
+		}
+		codec = nodeIDCodec{base64: opt.Encoding != NodeIDEncodingPlain, separator: separator}
+	}
+	return &relayNode{codec: codec}, nil
+}
+
+// serves reports whether the gateway resolves typeName.fieldName as the node field: it
+// does unless a subgraph declares a node root field of its own.
+func (n *relayNode) serves(typeName, fieldName string, engine *executionEngine) bool {
+	return n != nil && fieldName == "node" &&
+		typeName == engine.superGraph.RootTypeName(ast.Query) &&
+		len(engine.superGraph.GetSubGraphsForField(typeName, fieldName)) == 0
+}
+
+// handles reports whether op is a query selecting the node field, and only it besides
+// __typename. Operations mixing it with other root fields are rejected.
+func (n *relayNode) handles(op *ast.OperationDefinition, engine *executionEngine) (bool, error) {
+	if n == nil || op.Operation != ast.Query {
+		return false, nil
+	}
+	queryType := engine.superGraph.RootTypeName(ast.Query)
+	found, others := false, false
+	for _, sel := range op.SelectionSet {
+		field, ok := sel.(*ast.Field)
+		switch {
+		case !ok:
+			others = true
+		case n.serves(queryType, field.Name.String(), engine):
+			found = true
+		case field.Name.String() != "__typename":
+			others = true
+		}
+	}
+	if found && others {
+		return false, fmt.Errorf("node cannot be selected with other root fields")
+	}
+	return found, nil
+}
+
+// executeNode executes op, an operation of doc selecting the node field, as planDoc once
+// the list size and rate limits applied, and completes and reports its response like
+// any other operation. start and timing are those of the parsing and validation.
+func (g *gateway) executeNode(ctx context.Context, engine *executionEngine, req graphQLRequest, doc *ast.Document, op *ast.OperationDefinition, planDoc *ast.Document, limited []limitedField, computed bool, start time.Time, timing ExecutionTiming) (int, any) {
+	planDoc, planOp, err := selectOperation(planDoc, req.OperationName)
+	if err != nil {
+		return requestError(ctx, CodeValidationFailed, err.Error())
+	}
+
+	nodeCtx := ctx
+	var stats *executor.ExecutionStats
+	if len(g.extensionProviders) > 0 || hasCostReport(ctx) || g.usage != nil {
+		stats = executor.NewExecutionStats()
+		nodeCtx = executor.SetExecutionStatsToContext(nodeCtx, stats)
+	}
+	if timeout, ok := g.operationTimeouts[string(ast.Query)]; ok {
+		var cancel context.CancelFunc
+		nodeCtx, cancel = context.WithTimeout(nodeCtx, timeout)
+		defer cancel()
+	}
+	executing := time.Now()
+	resp := g.relayNode.resolve(nodeCtx, planDoc, planOp, req.Variables, engine)
+	if errors.Is(nodeCtx.Err(), context.DeadlineExceeded) && !g.softDeadline {
+		return deadlineExceeded(string(ast.Query))
+	}
+	timing.Execute = time.Since(executing)
+
+	g.completeResponse(ctx, resp, doc, op, engine, limited, computed)
+	if stats != nil {
+		timing.Total = time.Since(start)
+		g.reportExecution(ctx, resp, &ExecutionInfo{
+			OperationName: operationNameOf(op),
+			OperationType: string(op.Operation),
+			Document:      doc,
+			Client:        ClientInfoFromContext(ctx),
+			Timing:        timing,
+			Stats:         stats,
+			engine:        engine,
+		})
+	}
+	return http.StatusOK, executor.OrderedResponse{Response: resp, Document: doc}
+}
+
+// resolve executes the node fields of op, each with a plan fetching the entity its
+// global ID names from the owning subgraph.
+func (n *relayNode) resolve(ctx context.Context, doc *ast.Document, op *ast.OperationDefinition, variables map[string]any, engine *executionEngine) map[string]any {
+	fragmentDefs := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			fragmentDefs[fragDef.Name.String()] = fragDef
+		}
+	}
+
+	data := make(map[string]any)
+	var errs []executor.GraphQLError
+	for _, sel := range op.SelectionSet {
+		field, ok := sel.(*ast.Field)
+		if !ok {
+			continue
+		}
+		responseKey := field.Name.String()
+		if field.Alias != nil && field.Alias.String() != "" {
+			responseKey = field.Alias.String()
+		}
+		if field.Name.String() == "__typename" {
+			data[responseKey] = engine.superGraph.RootTypeName(ast.Query)
+			continue
+		}
+
+		node, nodeErrs, err := n.resolveNode(ctx, doc, field, variables, fragmentDefs, engine)
+		data[responseKey] = node
+		if err != nil {
+			errs = append(errs, executor.GraphQLError{Message: err.Error(), Path: []any{responseKey}})
+			continue
+		}
+		for _, nodeErr := range nodeErrs {
+			// Errors of the entity are reported under the node field
+			if len(nodeErr.Path) >= 2 {
+				nodeErr.Path = append([]any{responseKey}, nodeErr.Path[2:]...)
+			}
+			errs = append(errs, nodeErr)
+		}
+	}
+
+	resp := map[string]any{"data": data}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	return resp
+}
+
+// resolveNode fetches the entity named by the id argument of field.
+func (n *relayNode) resolveNode(ctx context.Context, doc *ast.Document, field *ast.Field, variables map[string]any, fragmentDefs map[string]*ast.FragmentDefinition, engine *executionEngine) (any, []executor.GraphQLError, error) {
+	id, err := nodeIDArgument(field, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+	typeName, keyValues, err := n.codec.DecodeNodeID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only the types clients can select through the node field are resolved, so that
+	// hidden types are not reachable with forged IDs.
+	if !implementsNode(typeName, engine) || engine.superGraph.IsInaccessibleType(typeName) {
+		return nil, nil, fmt.Errorf("global ID %q does not name a Node", id)
+	}
+	owner := engine.superGraph.GetEntityOwnerSubGraph(typeName)
+	if owner == nil {
+		return nil, nil, fmt.Errorf("global ID %q names %s, which is not an entity", id, typeName)
+	}
+	entity, _ := owner.GetEntity(typeName)
+	if len(entity.Keys) == 0 {
+		return nil, nil, fmt.Errorf("entity %s has no key", typeName)
+	}
+	keyFields := strings.Fields(entity.Keys[0].FieldSet)
+	if len(keyFields) != len(keyValues) || strings.Contains(entity.Keys[0].FieldSet, "{") {
+		return nil, nil, fmt.Errorf("global ID %q does not match the key %q of %s", id, entity.Keys[0].FieldSet, typeName)
+	}
+	representation := map[string]any{"__typename": typeName}
+	for i, keyField := range keyFields {
+		var keyType string
+		if def := owner.FieldDefinition(typeName, keyField); def != nil {
+			keyType = unwrapNamedType(def.Type)
+		}
+		value, err := nodeKeyValue(keyValues[i], keyType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("global ID %q: key field %s: %w", id, keyField, err)
+		}
+		representation[keyField] = value
+	}
+
+	// The entity is planned as the _entities field of a document selecting it, so that
+	// the response is shaped by the selections of the node field.
+	entities := &ast.Field{Name: &ast.Name{Value: "_entities"}, SelectionSet: field.SelectionSet}
+	entitiesDoc := &ast.Document{Definitions: []ast.Definition{
+		&ast.OperationDefinition{Operation: ast.Query, SelectionSet: []ast.Selection{entities}},
+	}}
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			entitiesDoc.Definitions = append(entitiesDoc.Definitions, fragDef)
+		}
+	}
+
+	selections := selectionsForType(field.SelectionSet, typeName, fragmentDefs)
+	plan, err := engine.planner.PlanEntities(entitiesDoc, typeName, selections, []map[string]any{representation})
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := engine.executor.Execute(ctx, plan, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var node any
+	if data, ok := resp["data"].(map[string]any); ok {
+		if resolved, _ := data["_entities"].([]any); len(resolved) == 1 {
+			node = resolved[0]
+		}
+	}
+	respErrs, _ := resp["errors"].([]executor.GraphQLError)
+	return node, respErrs, nil
+}
+
+// implementsNode reports whether typeName implements the Node interface of the composed
+// schema.
+func implementsNode(typeName string, engine *executionEngine) bool {
+	for _, possible := range engine.superGraph.PossibleTypes("Node") {
+		if possible == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeKeyValue converts the key value of a global ID to the scalar keyType of its key
+// field, so that representations carry e.g. Int keys as numbers. Other types are kept
+// as strings.
+func nodeKeyValue(value, keyType string) (any, error) {
+	switch keyType {
+	case "Int":
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an Int", value)
+		}
+		return i, nil
+	case "Float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a Float", value)
+		}
+		return f, nil
+	case "Boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a Boolean", value)
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+// nodeIDArgument reads the id argument of a node field, a string literal or variable.
+func nodeIDArgument(field *ast.Field, variables map[string]any) (string, error) {
+	for _, arg := range field.Arguments {
+		if arg.Name.String() != "id" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.StringValue:
+			return value.Value, nil
+		case *ast.Variable:
+			if id, ok := variables[value.Name].(string); ok {
+				return id, nil
+			}
+			return "", fmt.Errorf("variable $%s must be an ID", value.Name)
+		}
+		return "", fmt.Errorf("node id must be a string")
+	}
+	return "", fmt.Errorf("node requires an id argument")
+}
+
+// sdl returns the node field as a type extension, with the Node interface when the
+// composed schema has none, to complete the composed schema.
+func (n *relayNode) sdl(engine *executionEngine) string {
+	if n == nil || !n.serves(engine.superGraph.RootTypeName(ast.Query), "node", engine) {
+		return ""
+	}
+	var sb strings.Builder
+	if engine.superGraph.TypeDefinition("Node") == nil {
+		sb.WriteString("\ninterface Node {\n  id: ID!\n}\n")
+	}
+	fmt.Fprintf(&sb, "\nextend type %s {\n  node(id: ID!): Node\n}\n", engine.superGraph.RootTypeName(ast.Query))
+	return sb.String()
+}
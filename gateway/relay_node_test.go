@@ -0,0 +1,205 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_RelayNode(t *testing.T) {
+	entities := func(resolve func(rep map[string]any) map[string]any) func(body map[string]any) any {
+		return func(body map[string]any) any {
+			vars, _ := body["variables"].(map[string]any)
+			reps, _ := vars["representations"].([]any)
+			result := make([]any, 0, len(reps))
+			for _, rep := range reps {
+				result = append(result, resolve(rep.(map[string]any)))
+			}
+			return map[string]any{"data": map[string]any{"_entities": result}}
+		}
+	}
+	users := newSubgraphServer(t, `
+		extend schema @link(url: "https://specs.apollo.dev/federation/v2.0", import: ["@key", "@inaccessible"])
+
+		interface Node {
+			id: ID!
+		}
+
+		type User implements Node @key(fields: "id") {
+			id: ID!
+			username: String!
+			email: String
+		}
+
+		type Session implements Node @key(fields: "id") @inaccessible {
+			id: ID!
+			token: String!
+		}
+
+		type Account @key(fields: "id") {
+			id: ID!
+			balance: Int!
+		}
+
+		type Query {
+			me: User
+		}
+	`, entities(func(rep map[string]any) map[string]any {
+		id := rep["id"].(string)
+		switch rep["__typename"] {
+		case "Session":
+			return map[string]any{"__typename": "Session", "id": id, "token": "secret"}
+		case "Account":
+			return map[string]any{"__typename": "Account", "id": id, "balance": 100}
+		}
+		return map[string]any{"__typename": "User", "id": id, "username": "user-" + id, "email": id + "@example.com"}
+	}))
+	flights := newSubgraphServer(t, `
+		interface Node {
+			id: ID!
+		}
+
+		type Flight implements Node @key(fields: "number departureDate") {
+			id: ID!
+			number: String!
+			departureDate: String!
+			origin: String!
+		}
+
+		type Seat implements Node @key(fields: "row") {
+			id: ID!
+			row: Int!
+		}
+	`, entities(func(rep map[string]any) map[string]any {
+		if rep["__typename"] == "Seat" {
+			// Int keys are sent as numbers
+			row, ok := rep["row"].(float64)
+			if !ok {
+				return nil
+			}
+			return map[string]any{"__typename": "Seat", "id": "seat", "row": row}
+		}
+		return map[string]any{"__typename": "Flight", "number": rep["number"], "departureDate": rep["departureDate"], "origin": "NRT"}
+	}))
+
+	newGateway := func(t *testing.T, opt gateway.RelayNodeOption, transforms ...gateway.ResponseTransformOption) http.Handler {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{
+				{Name: "users", Host: users.URL},
+				{Name: "flights", Host: flights.URL},
+			},
+			RelayNode:          opt,
+			ResponseTransforms: transforms,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+	post := func(t *testing.T, gw http.Handler, query string, variables map[string]any) string {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	t.Run("base64 global IDs", func(t *testing.T) {
+		opt := gateway.RelayNodeOption{Enable: true}
+		gw := newGateway(t, opt)
+
+		got := post(t, gw, `query ($id: ID!) { node(id: $id) { ... on User { id username } } }`,
+			map[string]any{"id": gateway.EncodeNodeID(opt, "User", "42")})
+		if want := `{"data":{"node":{"id":"42","username":"user-42"}}}`; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+
+		got = post(t, gw, `{ flight: node(id: "`+gateway.EncodeNodeID(opt, "Flight", "NH1", "2024-01-01")+`") { ... on Flight { number origin } } }`, nil)
+		if want := `{"data":{"flight":{"number":"NH1","origin":"NRT"}}}`; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+
+		got = post(t, gw, `{ node(id: "`+gateway.EncodeNodeID(opt, "Seat", "12")+`") { ... on Seat { row } } }`, nil)
+		if want := `{"data":{"node":{"row":12}}}`; got != want {
+			t.Errorf("expected the Int key to be sent as a number, got %s", got)
+		}
+		got = post(t, gw, `{ node(id: "`+gateway.EncodeNodeID(opt, "Seat", "twelve")+`") { ... on Seat { row } } }`, nil)
+		if !strings.Contains(got, `"node":null`) || !strings.Contains(got, `\"twelve\" is not an Int`) {
+			t.Errorf("expected a key that is not an Int to be rejected, got %s", got)
+		}
+	})
+
+	t.Run("plain global IDs", func(t *testing.T) {
+		gw := newGateway(t, gateway.RelayNodeOption{Enable: true, Encoding: gateway.NodeIDEncodingPlain, Separator: "/"})
+
+		got := post(t, gw, `{ node(id: "User/7") { __typename ... on User { username } } }`, nil)
+		if want := `{"data":{"node":{"__typename":"User","username":"user-7"}}}`; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+
+	t.Run("invalid global IDs", func(t *testing.T) {
+		gw := newGateway(t, gateway.RelayNodeOption{Enable: true, Encoding: gateway.NodeIDEncodingPlain})
+
+		var resp struct {
+			Data   map[string]any `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+				Path    []any  `json:"path"`
+			} `json:"errors"`
+		}
+		// Unknown types, entities not implementing Node and @inaccessible types alike
+		for _, id := range []string{"Review:1", "Account:1", "Session:1"} {
+			got := post(t, gw, `{ node(id: "`+id+`") { id } }`, nil)
+			if err := json.Unmarshal([]byte(got), &resp); err != nil {
+				t.Fatalf("failed to decode response %s: %v", got, err)
+			}
+			if resp.Data["node"] != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "does not name a Node") {
+				t.Errorf("expected a null node with an error for %s, got %s", id, got)
+			}
+		}
+	})
+
+	t.Run("masks fields like any other operation", func(t *testing.T) {
+		opt := gateway.RelayNodeOption{Enable: true}
+		gw := newGateway(t, opt, gateway.ResponseTransformOption{Field: "User.email", Mask: true, UnlessHeader: "Authorization"})
+
+		got := post(t, gw, `{ node(id: "`+gateway.EncodeNodeID(opt, "User", "42")+`") { ... on User { username email } } }`, nil)
+		if want := `{"data":{"node":{"username":"user-42","email":null}}}`; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+
+	t.Run("rejects other root fields", func(t *testing.T) {
+		gw := newGateway(t, gateway.RelayNodeOption{Enable: true})
+
+		got := post(t, gw, `{ me { id } node(id: "x") { ... on User { id } } }`, nil)
+		if !strings.Contains(got, "node cannot be selected with other root fields") {
+			t.Errorf("expected the operation to be rejected, got %s", got)
+		}
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:       "/graphql",
+			Services:       []gateway.GatewayService{{Name: "users", Host: users.URL}},
+			RelayNode:      gateway.RelayNodeOption{Enable: true},
+			SchemaEndpoint: gateway.SchemaEndpointOption{Enable: true},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema.graphql", nil))
+		if sdl := rec.Body.String(); !strings.Contains(sdl, "interface Node {") || !strings.Contains(sdl, "node(id: ID!): Node") {
+			t.Errorf("expected the node field in the schema, got %s", sdl)
+		}
+	})
+}
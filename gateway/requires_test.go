@@ -0,0 +1,337 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_RequiresChain(t *testing.T) {
+	const (
+		sdlUsers = `
+			type User @key(fields: "id") {
+				id: ID!
+				username: String!
+			}
+
+			type Query {
+				user(id: ID!): User
+			}
+		`
+		sdlPurchases = `
+			type User @key(fields: "id") {
+				id: ID!
+				purchaseHistory: [Purchase!]!
+			}
+
+			type Purchase {
+				id: ID!
+				productId: ID!
+			}
+		`
+		sdlReviews = `
+			extend type User @key(fields: "id") {
+				id: ID! @external
+				purchaseHistory: [Purchase!]! @external
+				reviews: [Review!]! @requires(fields: "purchaseHistory { productId }")
+			}
+
+			type Purchase {
+				productId: ID!
+			}
+
+			type Review {
+				id: ID!
+				rating: Int!
+			}
+		`
+		sdlAnalytics = `
+			extend type User @key(fields: "id") {
+				id: ID! @external
+				reviews: [Review!]! @external
+				reviewCount: Int! @requires(fields: "reviews { rating }")
+			}
+
+			type Review {
+				rating: Int!
+			}
+		`
+	)
+
+	var mu sync.Mutex
+	representations := make(map[string]map[string]any)
+	entities := func(name string, resolve func(rep map[string]any) map[string]any) func(body map[string]any) any {
+		return func(body map[string]any) any {
+			vars, _ := body["variables"].(map[string]any)
+			reps, _ := vars["representations"].([]any)
+			result := make([]any, 0, len(reps))
+			for _, r := range reps {
+				rep := r.(map[string]any)
+				mu.Lock()
+				representations[name] = rep
+				mu.Unlock()
+				entity := resolve(rep)
+				entity["__typename"] = "User"
+				entity["id"] = rep["id"]
+				result = append(result, entity)
+			}
+			return map[string]any{"data": map[string]any{"_entities": result}}
+		}
+	}
+
+	users := newSubgraphServer(t, sdlUsers, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"user": map[string]any{"id": "1", "username": "alice"}}}
+	})
+	purchases := newSubgraphServer(t, sdlPurchases, entities("purchases", func(rep map[string]any) map[string]any {
+		return map[string]any{"purchaseHistory": []any{
+			map[string]any{"id": "p1", "productId": "a"},
+			map[string]any{"id": "p2", "productId": "b"},
+		}}
+	}))
+	reviews := newSubgraphServer(t, sdlReviews, entities("reviews", func(rep map[string]any) map[string]any {
+		history, _ := rep["purchaseHistory"].([]any)
+		result := make([]any, 0, len(history))
+		for _, p := range history {
+			result = append(result, map[string]any{"id": "r-" + p.(map[string]any)["productId"].(string), "rating": 5})
+		}
+		return map[string]any{"reviews": result}
+	}))
+	analytics := newSubgraphServer(t, sdlAnalytics, entities("analytics", func(rep map[string]any) map[string]any {
+		reviews, _ := rep["reviews"].([]any)
+		return map[string]any{"reviewCount": len(reviews)}
+	}))
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "users", Host: users.URL},
+			{Name: "purchases", Host: purchases.URL},
+			{Name: "reviews", Host: reviews.URL},
+			{Name: "analytics", Host: analytics.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query":"{ user(id: \"1\") { username reviewCount } }"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data struct {
+			User map[string]any `json:"user"`
+		} `json:"data"`
+		Errors []any `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+	want := map[string]any{"username": "alice", "reviewCount": float64(2)}
+	if len(resp.Data.User) != len(want) || resp.Data.User["username"] != want["username"] || resp.Data.User["reviewCount"] != want["reviewCount"] {
+		t.Errorf("expected %v, got %v", want, resp.Data.User)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	history, _ := representations["reviews"]["purchaseHistory"].([]any)
+	if len(history) != 2 || len(history[0].(map[string]any)) != 1 {
+		t.Errorf("expected purchaseHistory { productId } in the reviews representations, got %v", representations["reviews"])
+	}
+	if reviews, _ := representations["analytics"]["reviews"].([]any); len(reviews) != 2 {
+		t.Errorf("expected reviews in the analytics representations, got %v", representations["analytics"])
+	}
+}
+
+func TestGateway_RequiresOnRootFieldOfComputingSubgraph(t *testing.T) {
+	const (
+		sdlShipping = `
+			type Product @key(fields: "id") {
+				id: ID!
+				price: Float! @external
+				weight: Float! @external
+				shippingEstimate: Float! @requires(fields: "price weight")
+			}
+
+			type Query {
+				cheapest: Product
+			}
+		`
+		sdlProducts = `
+			type Product @key(fields: "id") {
+				id: ID!
+				name: String!
+				price: Float!
+				weight: Float!
+			}
+		`
+	)
+
+	var mu sync.Mutex
+	var shippingQueries []string
+	var representation map[string]any
+	shipping := newSubgraphServer(t, sdlShipping, func(body map[string]any) any {
+		q, _ := body["query"].(string)
+		mu.Lock()
+		defer mu.Unlock()
+		shippingQueries = append(shippingQueries, q)
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		if len(reps) == 0 {
+			return map[string]any{"data": map[string]any{"cheapest": map[string]any{"__typename": "Product", "id": "p1"}}}
+		}
+		representation = reps[0].(map[string]any)
+		price, _ := representation["price"].(float64)
+		weight, _ := representation["weight"].(float64)
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "Product", "id": "p1", "shippingEstimate": price/10 + weight},
+		}}}
+	})
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "Product", "id": "p1", "name": "Lamp", "price": 50.0, "weight": 2.0},
+		}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "shipping", Host: shipping.URL},
+			{Name: "products", Host: products.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query":"{ cheapest { name shippingEstimate } }"}`)))
+
+	var resp struct {
+		Data struct {
+			Cheapest map[string]any `json:"cheapest"`
+		} `json:"data"`
+		Errors []any `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+	want := map[string]any{"name": "Lamp", "shippingEstimate": float64(7)}
+	if len(resp.Data.Cheapest) != len(want) || resp.Data.Cheapest["name"] != want["name"] || resp.Data.Cheapest["shippingEstimate"] != want["shippingEstimate"] {
+		t.Errorf("expected %v, got %v", want, resp.Data.Cheapest)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(shippingQueries) != 2 || strings.Contains(shippingQueries[0], "shippingEstimate") {
+		t.Errorf("expected shippingEstimate to be fetched through _entities only, got %v", shippingQueries)
+	}
+	if representation["price"] != 50.0 || representation["weight"] != 2.0 {
+		t.Errorf("expected price and weight in the shipping representation, got %v", representation)
+	}
+}
+
+func TestGateway_RequiresMissingValues(t *testing.T) {
+	const (
+		sdlProducts = `
+			type Product @key(fields: "id") {
+				id: ID!
+				weight: Float!
+			}
+
+			type Query {
+				products: [Product!]!
+			}
+		`
+		sdlShipping = `
+			extend type Product @key(fields: "id") {
+				id: ID! @external
+				weight: Float! @external
+				shippingEstimate: Float! @requires(fields: "weight")
+			}
+		`
+	)
+
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"products": []any{
+			map[string]any{"__typename": "Product", "id": "p1", "weight": 2.0},
+			map[string]any{"__typename": "Product", "id": "p2", "weight": nil},
+			map[string]any{"__typename": "Product", "id": "p3", "weight": 5.0},
+		}}}
+	})
+	var mu sync.Mutex
+	var received []any
+	shipping := newSubgraphServer(t, sdlShipping, func(body map[string]any) any {
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		mu.Lock()
+		received = reps
+		mu.Unlock()
+		result := make([]any, 0, len(reps))
+		for _, r := range reps {
+			rep := r.(map[string]any)
+			result = append(result, map[string]any{"__typename": "Product", "id": rep["id"], "shippingEstimate": rep["weight"].(float64) * 10})
+		}
+		return map[string]any{"data": map[string]any{"_entities": result}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "shipping", Host: shipping.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query":"{ products { id shippingEstimate } }"}`)))
+
+	var resp struct {
+		Data struct {
+			Products []map[string]any `json:"products"`
+		} `json:"data"`
+		Errors []map[string]any `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	mu.Lock()
+	if len(received) != 2 {
+		t.Errorf("expected the product without weight to be left out of the representations, got %v", received)
+	}
+	mu.Unlock()
+
+	want := []any{float64(20), nil, float64(50)}
+	if len(resp.Data.Products) != len(want) {
+		t.Fatalf("expected %d products, got %v", len(want), resp.Data.Products)
+	}
+	for i, product := range resp.Data.Products {
+		if v, ok := product["shippingEstimate"]; !ok || v != want[i] {
+			t.Errorf("expected shippingEstimate %v for product %d, got %v", want[i], i, product)
+		}
+	}
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0]["message"].(string), "required field weight is missing") {
+		t.Errorf("expected an error for the missing required field, got %v", resp.Errors)
+	}
+}
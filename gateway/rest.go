@@ -0,0 +1,535 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+	"github.com/n9te9/graphql-parser/token"
+)
+
+// serviceTypeREST is the type of services resolved by calling a REST API.
+const serviceTypeREST = "rest"
+
+// RESTOption describes a REST API served as a virtual subgraph (a service of type
+// "rest"). The gateway resolves the fields of SDL by calling the endpoints they are
+// mapped to on the service host, without an intermediary GraphQL server.
+type RESTOption struct {
+	// SDL is the schema of the virtual subgraph. Entities it resolves have @key.
+	SDL string `yaml:"sdl"`
+
+	// Headers are sent with every request to the API.
+	Headers map[string]string `yaml:"headers"`
+
+	// Fields maps fields, by coordinate such as Query.user, to endpoints. Every root
+	// field is mapped to an endpoint; fields of other types that are not mapped are
+	// read from the JSON object of their parent under their name.
+	Fields map[string]RESTFieldOption `yaml:"fields"`
+
+	// Entities maps entity types to the endpoint resolving a representation.
+	Entities map[string]RESTFieldOption `yaml:"entities"`
+}
+
+// RESTFieldOption maps a field or an entity to an endpoint.
+type RESTFieldOption struct {
+	// Method is the HTTP method; GET by default. Requests of other methods send the
+	// field arguments (or the representation) as a JSON object body.
+	Method string `yaml:"method"`
+
+	// URL is the endpoint, relative to the service host. {name} placeholders are
+	// replaced by the field argument name or, failing that, the field of the parent
+	// object (or representation) at the dotted path name. When empty, the field is read
+	// from its parent object at Path.
+	URL string `yaml:"url"`
+
+	// Path is the dotted path of the value within the JSON response, e.g. data.user;
+	// the whole response when empty.
+	Path string `yaml:"path"`
+}
+
+// withRESTSubgraphs returns settings with an embedded subgraph resolving every service of
+// type rest through its REST API.
+func (o GatewayOption) withRESTSubgraphs() (GatewayOption, error) {
+	var embedded map[string]EmbeddedSubgraph
+	for _, svc := range o.Services {
+		if svc.Type != serviceTypeREST {
+			continue
+		}
+		if svc.REST == nil {
+			return o, fmt.Errorf("service %q of type rest has no rest mapping", svc.Name)
+		}
+		if _, ok := o.EmbeddedSubgraphs[svc.Name]; ok {
+			return o, fmt.Errorf("service %q of type rest is also an embedded subgraph", svc.Name)
+		}
+		client, err := newHTTPClient(o, svc.Transport.merge(o.Transport))
+		if err != nil {
+			return o, fmt.Errorf("invalid transport for service %q: %w", svc.Name, err)
+		}
+		resolver, err := newRESTResolver(svc.Host, *svc.REST, client)
+		if err != nil {
+			return o, fmt.Errorf("invalid rest mapping for service %q: %w", svc.Name, err)
+		}
+		if embedded == nil {
+			embedded = maps.Clone(o.EmbeddedSubgraphs)
+			if embedded == nil {
+				embedded = make(map[string]EmbeddedSubgraph)
+			}
+		}
+		embedded[svc.Name] = EmbeddedSubgraph{SDL: svc.REST.SDL, Resolver: resolver}
+	}
+	if embedded != nil {
+		o.EmbeddedSubgraphs = embedded
+	}
+	return o, nil
+}
+
+// restResolver resolves GraphQL requests of a virtual subgraph by calling a REST API.
+type restResolver struct {
+	host     string
+	option   RESTOption
+	client   *http.Client
+	types    map[string]map[string]string // Named type of each field, by type
+	abstract map[string]bool              // Interfaces and unions
+	roots    map[ast.OperationType]string
+}
+
+// newRESTResolver validates opt against its SDL and returns its resolver.
+func newRESTResolver(host string, opt RESTOption, client *http.Client) (*restResolver, error) {
+	if opt.SDL == "" {
+		return nil, errors.New("sdl is required")
+	}
+	p := parser.New(lexer.New(opt.SDL))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("failed to parse sdl: %v", p.Errors())
+	}
+
+	r := &restResolver{
+		host:     strings.TrimSuffix(host, "/"),
+		option:   opt,
+		client:   client,
+		types:    make(map[string]map[string]string),
+		abstract: make(map[string]bool),
+		roots:    map[ast.OperationType]string{ast.Query: "Query", ast.Mutation: "Mutation"},
+	}
+	addFields := func(typeName string, fields []*ast.FieldDefinition) {
+		if r.types[typeName] == nil {
+			r.types[typeName] = make(map[string]string)
+		}
+		for _, field := range fields {
+			r.types[typeName][field.Name.String()] = unwrapNamedType(field.Type)
+		}
+	}
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			addFields(d.Name.String(), d.Fields)
+		case *ast.ObjectTypeExtension:
+			addFields(d.Name.String(), d.Fields)
+		case *ast.InterfaceTypeDefinition:
+			addFields(d.Name.String(), d.Fields)
+			r.abstract[d.Name.String()] = true
+		case *ast.UnionTypeDefinition:
+			r.abstract[d.Name.String()] = true
+		case *ast.SchemaDefinition:
+			for _, op := range d.OperationTypes {
+				switch op.Operation {
+				case token.QUERY:
+					r.roots[ast.Query] = op.Type.Name.String()
+				case token.MUTATION:
+					r.roots[ast.Mutation] = op.Type.Name.String()
+				}
+			}
+		}
+	}
+
+	for coordinate, field := range opt.Fields {
+		typeName, fieldName, ok := strings.Cut(coordinate, ".")
+		if !ok {
+			return nil, fmt.Errorf("field %q is not a Type.field coordinate", coordinate)
+		}
+		if _, ok := r.types[typeName][fieldName]; !ok {
+			return nil, fmt.Errorf("field %q is not defined in the sdl", coordinate)
+		}
+		if err := validateRESTEndpoint(field); err != nil {
+			return nil, fmt.Errorf("field %q: %w", coordinate, err)
+		}
+	}
+	for typeName, entity := range opt.Entities {
+		if _, ok := r.types[typeName]; !ok {
+			return nil, fmt.Errorf("entity %q is not defined in the sdl", typeName)
+		}
+		if entity.URL == "" {
+			return nil, fmt.Errorf("entity %q has no url", typeName)
+		}
+		if err := validateRESTEndpoint(entity); err != nil {
+			return nil, fmt.Errorf("entity %q: %w", typeName, err)
+		}
+	}
+	for _, op := range []ast.OperationType{ast.Query, ast.Mutation} {
+		rootName := r.roots[op]
+		for fieldName := range r.types[rootName] {
+			if strings.HasPrefix(fieldName, "_") {
+				continue
+			}
+			if opt.Fields[rootName+"."+fieldName].URL == "" {
+				return nil, fmt.Errorf("root field %s.%s has no url", rootName, fieldName)
+			}
+		}
+	}
+	return r, nil
+}
+
+// validateRESTEndpoint checks the method of an endpoint.
+func validateRESTEndpoint(opt RESTFieldOption) error {
+	switch strings.ToUpper(opt.Method) {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return nil
+	}
+	return fmt.Errorf("unsupported method %q", opt.Method)
+}
+
+// restExecution holds the state of one GraphQL request to a REST subgraph.
+type restExecution struct {
+	*restResolver
+	ctx       context.Context
+	header    http.Header
+	variables map[string]any
+	fragments map[string]*ast.FragmentDefinition
+	errors    []map[string]any
+}
+
+// Resolve implements SubgraphResolver.
+func (r *restResolver) Resolve(ctx context.Context, req *SubgraphRequest) (map[string]any, error) {
+	p := parser.New(lexer.New(req.Query))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("failed to parse query: %v", p.Errors())
+	}
+
+	e := &restExecution{
+		restResolver: r,
+		ctx:          ctx,
+		header:       req.Header,
+		variables:    req.Variables,
+		fragments:    make(map[string]*ast.FragmentDefinition),
+	}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			if req.OperationName == "" || (def.Name != nil && def.Name.String() == req.OperationName) {
+				op = def
+			}
+		case *ast.FragmentDefinition:
+			e.fragments[def.Name.String()] = def
+		}
+	}
+	if op == nil {
+		return nil, errors.New("no operation found")
+	}
+	rootName, ok := r.roots[op.Operation]
+	if !ok || op.Operation == ast.Subscription {
+		return nil, fmt.Errorf("%s operations are not supported by REST subgraphs", op.Operation)
+	}
+
+	data := make(map[string]any)
+	for _, field := range e.fields(op.SelectionSet, rootName) {
+		key := fieldResponseKey(field)
+		path := []any{key}
+		switch field.Name.String() {
+		case "__typename":
+			data[key] = rootName
+		case "_service":
+			data[key] = e.complete(map[string]any{"sdl": r.option.SDL}, "_Service", field.SelectionSet, path)
+		case "_entities":
+			data[key] = e.entities(field, path)
+		default:
+			value, err := e.resolveField(rootName, field, nil)
+			if err != nil {
+				e.fail(err, path)
+				data[key] = nil
+				continue
+			}
+			data[key] = e.complete(value, r.types[rootName][field.Name.String()], field.SelectionSet, path)
+		}
+	}
+
+	resp := map[string]any{"data": data}
+	if len(e.errors) > 0 {
+		resp["errors"] = e.errors
+	}
+	return resp, nil
+}
+
+// entities resolves the representations of an _entities field through the endpoints of
+// their types.
+func (e *restExecution) entities(field *ast.Field, path []any) []any {
+	var reps []any
+	for _, arg := range field.Arguments {
+		if arg.Name.String() == "representations" {
+			reps, _ = e.value(arg.Value).([]any)
+		}
+	}
+
+	entities := make([]any, len(reps))
+	for i, item := range reps {
+		rep, _ := item.(map[string]any)
+		typename, _ := rep["__typename"].(string)
+		endpoint, ok := e.option.Entities[typename]
+		if !ok {
+			e.fail(fmt.Errorf("entity %q is not resolved by this REST subgraph", typename), appendRESTPath(path, i))
+			continue
+		}
+		value, err := e.call(endpoint, nil, rep)
+		if err != nil {
+			e.fail(err, appendRESTPath(path, i))
+			continue
+		}
+		entity, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		entity = maps.Clone(entity)
+		entity["__typename"] = typename
+		entities[i] = e.complete(entity, typename, field.SelectionSet, appendRESTPath(path, i))
+	}
+	return entities
+}
+
+// resolveField returns the value of field on parent, an object of typeName.
+func (e *restExecution) resolveField(typeName string, field *ast.Field, parent map[string]any) (any, error) {
+	endpoint, ok := e.option.Fields[typeName+"."+field.Name.String()]
+	if !ok {
+		return parent[field.Name.String()], nil
+	}
+	if endpoint.URL == "" {
+		return lookupRESTPath(parent, endpoint.Path), nil
+	}
+	return e.call(endpoint, e.arguments(field), parent)
+}
+
+// complete shapes value, of the named type typeName, by selections.
+func (e *restExecution) complete(value any, typeName string, selections []ast.Selection, path []any) any {
+	if len(selections) == 0 || value == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = e.complete(item, typeName, selections, appendRESTPath(path, i))
+		}
+		return out
+	case map[string]any:
+		if typename, ok := v["__typename"].(string); ok && e.abstract[typeName] {
+			typeName = typename
+		}
+		out := make(map[string]any)
+		for _, field := range e.fields(selections, typeName) {
+			key := fieldResponseKey(field)
+			if field.Name.String() == "__typename" {
+				out[key] = typeName
+				continue
+			}
+			fieldValue, err := e.resolveField(typeName, field, v)
+			if err != nil {
+				e.fail(err, appendRESTPath(path, key))
+				out[key] = nil
+				continue
+			}
+			out[key] = e.complete(fieldValue, e.types[typeName][field.Name.String()], field.SelectionSet, appendRESTPath(path, key))
+		}
+		return out
+	}
+	return value
+}
+
+// fields flattens selections into the fields selected on an object of typeName.
+func (e *restExecution) fields(selections []ast.Selection, typeName string) []*ast.Field {
+	var fields []*ast.Field
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			fields = append(fields, sel)
+		case *ast.InlineFragment:
+			if sel.TypeCondition == nil || sel.TypeCondition.Name.String() == typeName || e.abstract[sel.TypeCondition.Name.String()] {
+				fields = append(fields, e.fields(sel.SelectionSet, typeName)...)
+			}
+		case *ast.FragmentSpread:
+			def, ok := e.fragments[sel.Name.String()]
+			if ok && (def.TypeCondition.Name.String() == typeName || e.abstract[def.TypeCondition.Name.String()]) {
+				fields = append(fields, e.fields(def.SelectionSet, typeName)...)
+			}
+		}
+	}
+	return fields
+}
+
+// call requests endpoint with the field arguments args, or the parent object, and
+// returns the value at its path in the response. A 404 response resolves to null.
+func (e *restExecution) call(endpoint RESTFieldOption, args, parent map[string]any) (any, error) {
+	target, err := expandRESTURL(endpoint.URL, args, parent)
+	if err != nil {
+		return nil, err
+	}
+	method := strings.ToUpper(endpoint.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if method != http.MethodGet {
+		payload := args
+		if payload == nil {
+			payload = parent
+		}
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(e.ctx, method, e.host+target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth := e.header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	for name, value := range e.option.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s returned status %d", method, target, resp.StatusCode)
+	}
+	var value any
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode response of %s %s: %w", method, target, err)
+	}
+	return lookupRESTPath(value, endpoint.Path), nil
+}
+
+// expandRESTURL replaces the {name} placeholders of template by the argument name, or the
+// value at the dotted path name of parent, escaped for the part of the URL they are in.
+func expandRESTURL(template string, args, parent map[string]any) (string, error) {
+	var sb strings.Builder
+	inQuery := false
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			sb.WriteString(template)
+			return sb.String(), nil
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder in url %q", template)
+		}
+		end += start
+
+		literal := template[:start]
+		if strings.Contains(literal, "?") {
+			inQuery = true
+		}
+		sb.WriteString(literal)
+
+		name := template[start+1 : end]
+		value, ok := args[name]
+		if !ok {
+			value = lookupRESTPath(parent, name)
+			ok = value != nil
+		}
+		if !ok || value == nil {
+			return "", fmt.Errorf("no value for placeholder {%s}", name)
+		}
+		s := fmt.Sprint(value)
+		if inQuery {
+			sb.WriteString(url.QueryEscape(s))
+		} else {
+			sb.WriteString(url.PathEscape(s))
+		}
+		template = template[end+1:]
+	}
+}
+
+// lookupRESTPath returns the value at the dotted path of value, or value itself for an
+// empty path.
+func lookupRESTPath(value any, path string) any {
+	if path == "" {
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = obj[key]
+	}
+	return value
+}
+
+// arguments evaluates the arguments of field.
+func (e *restExecution) arguments(field *ast.Field) map[string]any {
+	args := make(map[string]any, len(field.Arguments))
+	for _, arg := range field.Arguments {
+		args[arg.Name.String()] = e.value(arg.Value)
+	}
+	return args
+}
+
+// value evaluates an argument value with the request variables.
+func (e *restExecution) value(v ast.Value) any {
+	switch v := v.(type) {
+	case *ast.Variable:
+		return e.variables[v.Name]
+	case *ast.ListValue:
+		list := make([]any, len(v.Values))
+		for i, item := range v.Values {
+			list[i] = e.value(item)
+		}
+		return list
+	case *ast.ObjectValue:
+		obj := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			obj[f.Name.String()] = e.value(f.Value)
+		}
+		return obj
+	}
+	return literalValue(v)
+}
+
+// fail records err as a GraphQL error at path.
+func (e *restExecution) fail(err error, path []any) {
+	e.errors = append(e.errors, map[string]any{"message": err.Error(), "path": path})
+}
+
+// appendRESTPath returns a copy of path with el appended.
+func appendRESTPath(path []any, el any) []any {
+	return append(append(make([]any, 0, len(path)+1), path...), el)
+}
@@ -0,0 +1,143 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+const sdlRESTUsers = `
+type Query {
+	user(id: ID!): User
+}
+
+type User @key(fields: "id") {
+	id: ID!
+	name: String
+	email: String
+	posts: [Post]
+}
+
+type Post {
+	title: String
+}`
+
+func TestGateway_RESTSubgraph(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/users/u1":
+			w.Write([]byte(`{"id":"u1","name":"alice","contact":{"email":"alice@example.com"}}`)) //nolint:errcheck
+		case "/users/u1/posts":
+			w.Write([]byte(`{"items":[{"title":"hello"},{"title":"world"}]}`)) //nolint:errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	reviews := newSubgraphServer(t, `
+		type Query { reviews: [Review] }
+		type Review { body: String author: User }
+		type User @key(fields: "id", resolvable: false) { id: ID! }
+	`, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"reviews": []any{
+			map[string]any{"body": "great", "author": map[string]any{"__typename": "User", "id": "u1"}},
+		}}}
+	})
+
+	rest := &gateway.RESTOption{
+		SDL:     sdlRESTUsers,
+		Headers: map[string]string{"X-Api-Key": "secret"},
+		Fields: map[string]gateway.RESTFieldOption{
+			"Query.user": {URL: "/users/{id}"},
+			"User.email": {Path: "contact.email"},
+			"User.posts": {URL: "/users/{id}/posts", Path: "items"},
+		},
+		Entities: map[string]gateway.RESTFieldOption{
+			"User": {URL: "/users/{id}"},
+		},
+	}
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "users", Host: api.URL, Type: "rest", REST: rest},
+			{Name: "reviews", Host: reviews.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "root field",
+			query: `{ user(id: \"u1\") { name email posts { title } } }`,
+			want:  `{"data":{"user":{"name":"alice","email":"alice@example.com","posts":[{"title":"hello"},{"title":"world"}]}}}`,
+		},
+		{
+			name:  "not found",
+			query: `{ user(id: \"u9\") { name } }`,
+			want:  `{"data":{"user":null}}`,
+		},
+		{
+			name:  "entities",
+			query: `{ reviews { body author { name } } }`,
+			want:  `{"data":{"reviews":[{"body":"great","author":{"name":"alice"}}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"`+tt.query+`"}`)))
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGateway_RESTSubgraphInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rest *gateway.RESTOption
+		want string
+	}{
+		{name: "missing mapping", rest: nil, want: "has no rest mapping"},
+		{
+			name: "unmapped root field",
+			rest: &gateway.RESTOption{SDL: sdlRESTUsers},
+			want: "root field Query.user has no url",
+		},
+		{
+			name: "unknown field",
+			rest: &gateway.RESTOption{SDL: sdlRESTUsers, Fields: map[string]gateway.RESTFieldOption{
+				"Query.user":  {URL: "/users/{id}"},
+				"User.avatar": {Path: "avatar"},
+			}},
+			want: `field "User.avatar" is not defined in the sdl`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gateway.NewGateway(gateway.GatewayOption{
+				Endpoint: "/graphql",
+				Services: []gateway.GatewayService{{Name: "users", Host: "http://localhost:0", Type: "rest", REST: tt.rest}},
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+)
+
+// adminRoute is an endpoint of the gateway besides the GraphQL endpoint. The routes are
+// dispatched by ServeHTTP, served on the admin port by NewServers and described by the
+// OpenAPI document at openAPIPath, so the three cannot drift apart.
+type adminRoute struct {
+	method  string
+	path    string // May hold one {parameter} matching a non-empty part of the path
+	summary string
+	request any // Zero value of the JSON request body; nil without a body
+	// responses lists the documented responses; error responses without a body type
+	// have an adminError body.
+	responses []adminResponse
+	serve     func(g *gateway, w http.ResponseWriter, r *http.Request)
+}
+
+// adminResponse is a documented response of an adminRoute.
+type adminResponse struct {
+	status      int
+	description string
+	body        any  // Zero value of the JSON body; nil for an adminError
+	empty       bool // The response has no body
+}
+
+// adminError is the body of the error responses of admin routes.
+type adminError struct {
+	Error string `json:"error"`
+}
+
+// adminRoutes are the routes of the gateway besides the GraphQL endpoint and the
+// configurable schema endpoint.
+var adminRoutes = []adminRoute{
+	{
+		method:  http.MethodPost,
+		path:    "/{name}/apply",
+		summary: "Fetch the SDL of a subgraph from its schema source and recompose the supergraph",
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The schema was applied", body: okResponse{}},
+			{status: http.StatusInternalServerError, description: "The subgraph is unknown or its schema could not be applied"},
+		},
+		serve: func(g *gateway, w http.ResponseWriter, r *http.Request) {
+			g.handleApply(w, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/apply"))
+		},
+	},
+	{
+		method:  http.MethodGet,
+		path:    schemaVersionsPath,
+		summary: "List the schema versions kept by the schema registry, newest first",
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The schema versions", body: schemaVersionsResponse{}},
+			{status: http.StatusInternalServerError, description: "The registry could not be read"},
+		},
+		serve: (*gateway).handleSchemaVersions,
+	},
+	{
+		method:  http.MethodGet,
+		path:    schemaHealthPath,
+		summary: "Report the freshness of every polled subgraph schema",
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "Every schema is fresh or stale within its bound", body: schemaHealthResponse{}},
+			{status: http.StatusServiceUnavailable, description: "A subgraph schema is degraded", body: schemaHealthResponse{}},
+		},
+		serve: func(g *gateway, w http.ResponseWriter, _ *http.Request) { g.handleSchemaHealth(w) },
+	},
+	{
+		method:  http.MethodGet,
+		path:    deprecatedFieldsPath,
+		summary: "Report the usage of @deprecated fields",
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The usage of every selected @deprecated field, most used first", body: deprecatedFieldsResponse{}},
+			{status: http.StatusNotFound, description: "Deprecated field usage tracking is disabled", empty: true},
+		},
+		serve: func(g *gateway, w http.ResponseWriter, _ *http.Request) { g.handleDeprecatedFields(w) },
+	},
+	{
+		method:  http.MethodGet,
+		path:    faultsPath,
+		summary: "List the injected subgraph faults",
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The injected faults by subgraph", body: faultsResponse{}},
+			{status: http.StatusNotFound, description: "Fault injection is disabled"},
+		},
+		serve: (*gateway).handleFaults,
+	},
+	{
+		method:  http.MethodPut,
+		path:    faultsPath,
+		summary: "Replace the injected subgraph faults; an empty object stops injecting them",
+		request: map[string]FaultOption{},
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The injected faults by subgraph", body: faultsResponse{}},
+			{status: http.StatusBadRequest, description: "The faults are invalid"},
+			{status: http.StatusNotFound, description: "Fault injection is disabled"},
+		},
+		serve: (*gateway).handleFaults,
+	},
+	{
+		method:  http.MethodPost,
+		path:    schemaRollbackPath,
+		summary: "Make a previous schema version current and install it",
+		request: schemaRollback{},
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The version is installed", body: schemaRollbackResponse{}},
+			{status: http.StatusBadRequest, description: "The request is invalid"},
+			{status: http.StatusNotFound, description: "The registry has no such version"},
+			{status: http.StatusConflict, description: "The version could not be installed; the previous one stays current"},
+		},
+		serve: (*gateway).handleSchemaRollback,
+	},
+	{
+		method:  http.MethodPost,
+		path:    composeCheckPath,
+		summary: "Compose a candidate SDL of one subgraph with the live ones without installing it",
+		request: composeCheckRequest{},
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The candidate composes", body: composeCheckResult{}},
+			{status: http.StatusBadRequest, description: "The request is invalid"},
+			{status: http.StatusUnprocessableEntity, description: "The candidate does not compose", body: composeCheckResult{}},
+		},
+		serve: (*gateway).handleComposeCheck,
+	},
+	{
+		method:  http.MethodPost,
+		path:    entityCacheInvalidatePath,
+		summary: "Purge cached entities by type and key fields, or the whole entity cache",
+		request: entityCacheInvalidation{},
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The entities are purged", body: entityCacheInvalidateResponse{}},
+			{status: http.StatusBadRequest, description: "The request is invalid"},
+			{status: http.StatusNotFound, description: "The entity cache is disabled"},
+		},
+		serve: (*gateway).handleEntityCacheInvalidate,
+	},
+	{
+		method:  http.MethodGet,
+		path:    openAPIPath,
+		summary: "Describe the endpoints of the gateway besides the GraphQL endpoint",
+		responses: []adminResponse{
+			{status: http.StatusOK, description: "The OpenAPI document", body: map[string]any{}},
+		},
+		serve: (*gateway).handleOpenAPI,
+	},
+}
+
+// okResponse is the body of admin requests that succeeded without reporting anything.
+type okResponse struct {
+	OK bool `json:"ok"`
+}
+
+// matches reports whether path is the path of the route.
+func (route *adminRoute) matches(path string) bool {
+	open := strings.IndexByte(route.path, '{')
+	if open < 0 {
+		return path == route.path
+	}
+	prefix, suffix := route.path[:open], route.path[strings.IndexByte(route.path, '}')+1:]
+	return len(path) > len(prefix)+len(suffix) && strings.HasPrefix(path, prefix) && strings.HasSuffix(path, suffix)
+}
+
+// parameterized reports whether the path of the route has a parameter.
+func (route *adminRoute) parameterized() bool {
+	return strings.IndexByte(route.path, '{') >= 0
+}
+
+// matchAdminRoute returns the admin route serving r, or nil.
+func matchAdminRoute(r *http.Request) *adminRoute {
+	for i := range adminRoutes {
+		if route := &adminRoutes[i]; route.method == r.Method && route.matches(r.URL.Path) {
+			return route
+		}
+	}
+	return nil
+}
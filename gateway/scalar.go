@@ -0,0 +1,341 @@
+package gateway
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// ScalarFunc validates an input value for a custom scalar and returns the coerced
+// value that is forwarded to subgraphs. It returns an error for invalid input.
+type ScalarFunc func(value any) (any, error)
+
+// ScalarRegistry holds validation/coercion functions for custom scalars.
+// It is safe for concurrent use.
+type ScalarRegistry struct {
+	mu      sync.RWMutex
+	scalars map[string]ScalarFunc
+}
+
+// NewScalarRegistry creates an empty registry. The built-in custom scalars are added
+// with RegisterBuiltins.
+func NewScalarRegistry() *ScalarRegistry {
+	return &ScalarRegistry{scalars: make(map[string]ScalarFunc)}
+}
+
+// builtinScalars are the custom scalars a registry can opt in to with RegisterBuiltins.
+var builtinScalars = map[string]ScalarFunc{
+	"DateTime": coerceDateTime,
+	"JSON":     coerceJSON,
+	"BigInt":   coerceBigInt,
+}
+
+// RegisterBuiltins adds the named built-in custom scalars: DateTime (RFC 3339,
+// normalised to UTC), JSON and BigInt (coerced to a decimal string).
+func (r *ScalarRegistry) RegisterBuiltins(names ...string) error {
+	for _, name := range names {
+		fn, ok := builtinScalars[name]
+		if !ok {
+			return fmt.Errorf("unknown built-in scalar %q", name)
+		}
+		r.Register(name, fn)
+	}
+	return nil
+}
+
+// Register adds or replaces the coercion function for the named scalar.
+func (r *ScalarRegistry) Register(name string, fn ScalarFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scalars[name] = fn
+}
+
+// Lookup returns the coercion function for the named scalar.
+func (r *ScalarRegistry) Lookup(name string) (ScalarFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.scalars[name]
+	return fn, ok
+}
+
+// coerceDateTime accepts RFC 3339 strings and normalises them to UTC.
+func coerceDateTime(value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("DateTime must be a string, got %T", value)
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, fmt.Errorf("DateTime must be RFC 3339: %w", err)
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
+
+// coerceJSON accepts any JSON value as-is.
+func coerceJSON(value any) (any, error) {
+	return value, nil
+}
+
+// coerceBigInt accepts integers given as JSON numbers or decimal strings and
+// coerces them to a decimal string so no precision is lost on the way to subgraphs.
+func coerceBigInt(value any) (any, error) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("BigInt must be an integer, got %v", v)
+		}
+		return strconv.FormatInt(int64(v), 10), nil
+	default:
+		return nil, fmt.Errorf("BigInt must be an integer or string, got %T", value)
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("BigInt must be a decimal integer, got %q", s)
+	}
+	return n.String(), nil
+}
+
+// validateScalars walks the operation and validates every argument whose type is a
+// registered custom scalar (directly, in a list, or inside an input object).
+// Variable values are coerced; the returned map is a copy of variables holding the
+// coerced values. Literal values are validated only, since they are forwarded verbatim.
+func (g *gateway) validateScalars(doc *ast.Document, variables map[string]any, engine *executionEngine) (map[string]any, error) {
+	if g.scalars == nil {
+		return variables, nil
+	}
+
+	coerced := make(map[string]any, len(variables))
+	for k, v := range variables {
+		coerced[k] = v
+	}
+
+	fragmentDefs := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			fragmentDefs[fragDef.Name.String()] = fragDef
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		opDef, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		rootTypeName := engine.superGraph.RootTypeName(opDef.Operation)
+		if err := g.validateScalarSelections(opDef.SelectionSet, rootTypeName, coerced, fragmentDefs, engine, make(map[string]bool)); err != nil {
+			return nil, err
+		}
+	}
+
+	return coerced, nil
+}
+
+// validateScalarSelections validates the arguments of every field in selections.
+func (g *gateway) validateScalarSelections(selections []ast.Selection, parentTypeName string, variables map[string]any, fragmentDefs map[string]*ast.FragmentDefinition, engine *executionEngine, visited map[string]bool) error {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			fieldDef := findFieldDefinition(parentTypeName, s.Name.String(), engine)
+			if fieldDef == nil {
+				continue
+			}
+			for _, arg := range s.Arguments {
+				for _, argDef := range fieldDef.Arguments {
+					if argDef.Name.String() != arg.Name.String() {
+						continue
+					}
+					if err := g.validateScalarArgument(arg.Value, argDef.Type, variables, engine); err != nil {
+						return fmt.Errorf("invalid value for argument %q of field %q: %w", arg.Name.String(), s.Name.String(), err)
+					}
+				}
+			}
+			if len(s.SelectionSet) > 0 {
+				if err := g.validateScalarSelections(s.SelectionSet, g.unwrapTypeName(fieldDef.Type), variables, fragmentDefs, engine, visited); err != nil {
+					return err
+				}
+			}
+		case *ast.InlineFragment:
+			typeCondition := parentTypeName
+			if s.TypeCondition != nil {
+				typeCondition = s.TypeCondition.Name.String()
+			}
+			if err := g.validateScalarSelections(s.SelectionSet, typeCondition, variables, fragmentDefs, engine, visited); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			name := s.Name.String()
+			fragDef, ok := fragmentDefs[name]
+			if !ok || visited[name] {
+				continue
+			}
+			visited[name] = true
+			if err := g.validateScalarSelections(fragDef.SelectionSet, fragDef.TypeCondition.Name.String(), variables, fragmentDefs, engine, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateScalarArgument validates a single argument value against its declared type.
+func (g *gateway) validateScalarArgument(value ast.Value, typ ast.Type, variables map[string]any, engine *executionEngine) error {
+	if variable, ok := value.(*ast.Variable); ok {
+		raw, exists := variables[variable.Name]
+		if !exists {
+			return nil
+		}
+		coerced, err := g.coerceInputValue(raw, typ, engine)
+		if err != nil {
+			return fmt.Errorf("variable $%s: %w", variable.Name, err)
+		}
+		variables[variable.Name] = coerced
+		return nil
+	}
+
+	switch v := value.(type) {
+	case *ast.ListValue:
+		inner := typ
+		if nonNull, ok := inner.(*ast.NonNullType); ok {
+			inner = nonNull.Type
+		}
+		if list, ok := inner.(*ast.ListType); ok {
+			for _, item := range v.Values {
+				if err := g.validateScalarArgument(item, list.Type, variables, engine); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case *ast.ObjectValue:
+		inputDef := findInputObjectDefinition(g.unwrapTypeName(typ), engine)
+		if inputDef == nil {
+			return nil
+		}
+		for _, field := range v.Fields {
+			for _, fieldDef := range inputDef.Fields {
+				if fieldDef.Name.String() == field.Name.String() {
+					if err := g.validateScalarArgument(field.Value, fieldDef.Type, variables, engine); err != nil {
+						return fmt.Errorf("field %q: %w", field.Name.String(), err)
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	_, err := g.coerceInputValue(literalValue(value), typ, engine)
+	return err
+}
+
+// coerceInputValue validates and coerces a runtime input value against typ.
+func (g *gateway) coerceInputValue(value any, typ ast.Type, engine *executionEngine) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch t := typ.(type) {
+	case *ast.NonNullType:
+		return g.coerceInputValue(value, t.Type, engine)
+	case *ast.ListType:
+		list, ok := value.([]any)
+		if !ok {
+			// Input coercion allows a single item where a list is expected.
+			return g.coerceInputValue(value, t.Type, engine)
+		}
+		out := make([]any, len(list))
+		for i, item := range list {
+			c, err := g.coerceInputValue(item, t.Type, engine)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			out[i] = c
+		}
+		return out, nil
+	case *ast.NamedType:
+		typeName := t.Name.String()
+		if fn, ok := g.scalars.Lookup(typeName); ok {
+			return fn(value)
+		}
+		inputDef := findInputObjectDefinition(typeName, engine)
+		obj, isObj := value.(map[string]any)
+		if inputDef == nil || !isObj {
+			return value, nil
+		}
+		out := make(map[string]any, len(obj))
+		for k, v := range obj {
+			out[k] = v
+		}
+		for _, fieldDef := range inputDef.Fields {
+			name := fieldDef.Name.String()
+			v, exists := obj[name]
+			if !exists {
+				continue
+			}
+			c, err := g.coerceInputValue(v, fieldDef.Type, engine)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", name, err)
+			}
+			out[name] = c
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+// literalValue converts an AST literal into its runtime representation.
+func literalValue(value ast.Value) any {
+	switch v := value.(type) {
+	case *ast.StringValue:
+		return v.Value
+	case *ast.IntValue:
+		return v.Value
+	case *ast.FloatValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.ListValue:
+		out := make([]any, 0, len(v.Values))
+		for _, item := range v.Values {
+			out = append(out, literalValue(item))
+		}
+		return out
+	case *ast.ObjectValue:
+		out := make(map[string]any, len(v.Fields))
+		for _, field := range v.Fields {
+			out[field.Name.String()] = literalValue(field.Value)
+		}
+		return out
+	}
+	return nil
+}
+
+// findFieldDefinition returns the definition of typeName.fieldName in the composed schema.
+func findFieldDefinition(typeName, fieldName string, engine *executionEngine) *ast.FieldDefinition {
+	if _, ok := engine.superGraph.TypeDefinition(typeName).(*ast.ObjectTypeDefinition); !ok {
+		return nil
+	}
+	return engine.superGraph.FieldDefinition(typeName, fieldName)
+}
+
+// findInputObjectDefinition returns the input object definition named typeName.
+func findInputObjectDefinition(typeName string, engine *executionEngine) *ast.InputObjectTypeDefinition {
+	inputDef, _ := engine.superGraph.TypeDefinition(typeName).(*ast.InputObjectTypeDefinition)
+	return inputDef
+}
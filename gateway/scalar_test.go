@@ -0,0 +1,241 @@
+package gateway_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestScalarRegistry_BuiltIns(t *testing.T) {
+	r := gateway.NewScalarRegistry()
+	if _, ok := r.Lookup("DateTime"); ok {
+		t.Fatal("expected a new registry to have no built-in scalars")
+	}
+	if err := r.RegisterBuiltins("DateTime", "BigInt", "JSON"); err != nil {
+		t.Fatalf("RegisterBuiltins failed: %v", err)
+	}
+	if err := r.RegisterBuiltins("Email"); err == nil {
+		t.Error("expected an error for an unknown built-in scalar")
+	}
+
+	tests := []struct {
+		scalar  string
+		input   any
+		want    any
+		wantErr bool
+	}{
+		{scalar: "DateTime", input: "2024-01-02T03:04:05+09:00", want: "2024-01-01T18:04:05Z"},
+		{scalar: "DateTime", input: "yesterday", wantErr: true},
+		{scalar: "DateTime", input: float64(1), wantErr: true},
+		{scalar: "BigInt", input: "123456789012345678901234567890", want: "123456789012345678901234567890"},
+		{scalar: "BigInt", input: float64(42), want: "42"},
+		{scalar: "BigInt", input: float64(1.5), wantErr: true},
+		{scalar: "BigInt", input: "12a", wantErr: true},
+		{scalar: "JSON", input: map[string]any{"a": float64(1)}, want: map[string]any{"a": float64(1)}},
+	}
+
+	for _, tt := range tests {
+		fn, ok := r.Lookup(tt.scalar)
+		if !ok {
+			t.Fatalf("scalar %s is not registered", tt.scalar)
+		}
+		got, err := fn(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s(%v): expected error, got %v", tt.scalar, tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s(%v): unexpected error: %v", tt.scalar, tt.input, err)
+			continue
+		}
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(tt.want)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("%s(%v) = %s, want %s", tt.scalar, tt.input, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestGateway_CustomScalars(t *testing.T) {
+	eventsSDL := `
+scalar DateTime
+
+input EventFilter {
+  after: DateTime
+  tags: [String!]
+}
+
+type Event @key(fields: "id") {
+  id: ID!
+  startsAt: DateTime
+}
+
+type Query {
+  events(since: DateTime, filter: EventFilter): [Event!]!
+  eventsByCode(code: Code!): [Event!]!
+}
+
+scalar Code
+`
+	ticketsSDL := `
+scalar DateTime
+
+type Event @key(fields: "id") {
+  id: ID!
+  soldOutAt: DateTime
+}
+`
+
+	var forwarded map[string]any
+	events := newSubgraphServer(t, eventsSDL, func(body map[string]any) any {
+		forwarded, _ = body["variables"].(map[string]any)
+		return map[string]any{"data": map[string]any{"events": []any{}, "eventsByCode": []any{}}}
+	})
+	tickets := newSubgraphServer(t, ticketsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"_entities": []any{}}}
+	})
+
+	registry := gateway.NewScalarRegistry()
+	registry.Register("Code", func(value any) (any, error) {
+		s, ok := value.(string)
+		if !ok || len(s) != 3 {
+			return nil, errors.New("Code must be a 3 letter string")
+		}
+		return strings.ToUpper(s), nil
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:           "/graphql",
+		EnableSubgraphMode: true,
+		Scalars:            registry,
+		BuiltinScalars:     []string{"DateTime"},
+		Services: []gateway.GatewayService{
+			{Name: "events", Host: events.URL},
+			{Name: "tickets", Host: tickets.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	serve := func(body string) map[string]any {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("variables are coerced before forwarding", func(t *testing.T) {
+		forwarded = nil
+		resp := serve(`{
+			"query": "query ($since: DateTime, $filter: EventFilter) { events(since: $since, filter: $filter) { id } }",
+			"variables": {"since": "2024-01-02T03:04:05+09:00", "filter": {"after": "2024-01-01T00:00:00+01:00", "tags": ["a"]}}
+		}`)
+		if resp["errors"] != nil {
+			t.Fatalf("unexpected errors: %v", resp["errors"])
+		}
+		if forwarded["since"] != "2024-01-01T18:04:05Z" {
+			t.Errorf("expected coerced $since, got %v", forwarded["since"])
+		}
+		filter, _ := forwarded["filter"].(map[string]any)
+		if filter["after"] != "2023-12-31T23:00:00Z" {
+			t.Errorf("expected coerced filter.after, got %v", filter["after"])
+		}
+	})
+
+	t.Run("invalid variable is rejected", func(t *testing.T) {
+		resp := serve(`{
+			"query": "query ($since: DateTime) { events(since: $since) { id } }",
+			"variables": {"since": "not-a-date"}
+		}`)
+		errs, _ := resp["errors"].([]any)
+		if len(errs) != 1 {
+			t.Fatalf("expected one error, got %v", resp)
+		}
+		ext, _ := errs[0].(map[string]any)["extensions"].(map[string]any)
+		if ext["code"] != "BAD_USER_INPUT" {
+			t.Errorf("expected BAD_USER_INPUT, got %v", ext["code"])
+		}
+	})
+
+	t.Run("invalid literal is rejected", func(t *testing.T) {
+		resp := serve(`{"query": "{ events(filter: {after: \"soon\"}) { id } }"}`)
+		if resp["errors"] == nil {
+			t.Fatalf("expected error for invalid literal, got %v", resp)
+		}
+	})
+
+	t.Run("user registered scalar", func(t *testing.T) {
+		forwarded = nil
+		resp := serve(`{
+			"query": "query ($code: Code!) { eventsByCode(code: $code) { id } }",
+			"variables": {"code": "abc"}
+		}`)
+		if resp["errors"] != nil {
+			t.Fatalf("unexpected errors: %v", resp["errors"])
+		}
+		if forwarded["code"] != "ABC" {
+			t.Errorf("expected coerced $code, got %v", forwarded["code"])
+		}
+
+		resp = serve(`{"query": "{ eventsByCode(code: \"toolong\") { id } }"}`)
+		if resp["errors"] == nil {
+			t.Errorf("expected error for invalid Code literal, got %v", resp)
+		}
+	})
+
+	t.Run("scalars compose without duplication", func(t *testing.T) {
+		resp := serve(`{"query": "{ _service { sdl } }"}`)
+		data, _ := resp["data"].(map[string]any)
+		service, _ := data["_service"].(map[string]any)
+		sdl, _ := service["sdl"].(string)
+		if n := strings.Count(sdl, "scalar DateTime"); n != 1 {
+			t.Errorf("expected DateTime to be declared once, got %d in %q", n, sdl)
+		}
+	})
+}
+
+func TestGateway_BuiltinScalarsAreOptIn(t *testing.T) {
+	sdl := `
+scalar DateTime
+
+type Query {
+  events(since: DateTime): [String!]!
+}
+`
+	var forwarded map[string]any
+	events := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		forwarded, _ = body["variables"].(map[string]any)
+		return map[string]any{"data": map[string]any{"events": []any{}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "events", Host: events.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	body := `{"query": "query ($since: DateTime) { events(since: $since) }", "variables": {"since": "last week"}}`
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+
+	if strings.Contains(rec.Body.String(), "errors") {
+		t.Fatalf("expected DateTime not to be validated without builtin_scalars, got %s", rec.Body.String())
+	}
+	if forwarded["since"] != "last week" {
+		t.Errorf("expected $since to be forwarded unchanged, got %v", forwarded["since"])
+	}
+}
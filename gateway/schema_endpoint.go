@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+)
+
+// SchemaEndpointOption serves the composed schema as SDL on GET, for codegen and
+// documentation tools.
+type SchemaEndpointOption struct {
+	Enable       bool   `yaml:"enable" default:"false"`
+	Path         string `yaml:"path" default:"/schema.graphql"`
+	Federation   bool   `yaml:"federation" default:"false"`   // Include @link, @key and the _entities and _service machinery
+	Inaccessible bool   `yaml:"inaccessible" default:"false"` // Include @inaccessible types and fields
+	Token        string `yaml:"token"`                        // When set, require "Authorization: Bearer <token>"
+}
+
+// defaultSchemaEndpointPath is where the schema is served when Path is empty.
+const defaultSchemaEndpointPath = "/schema.graphql"
+
+// schemaEndpoint serves the SDL of the current engine.
+type schemaEndpoint struct {
+	path    string
+	options graph.PrintOptions
+	token   string
+}
+
+// newSchemaEndpoint returns nil when the endpoint is disabled.
+func newSchemaEndpoint(opt SchemaEndpointOption) *schemaEndpoint {
+	if !opt.Enable {
+		return nil
+	}
+	path := opt.Path
+	if path == "" {
+		path = defaultSchemaEndpointPath
+	}
+	return &schemaEndpoint{
+		path: path,
+		options: graph.PrintOptions{
+			Federation:   opt.Federation,
+			Inaccessible: opt.Inaccessible,
+		},
+		token: opt.Token,
+	}
+}
+
+// matches reports whether r requests the schema.
+func (e *schemaEndpoint) matches(r *http.Request) bool {
+	return e != nil && r.Method == http.MethodGet && r.URL.Path == e.path
+}
+
+// handleSchema prints the composed schema of the current engine.
+func (g *gateway) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if g.schemaEndpoint.token != "" {
+		want := "Bearer " + g.schemaEndpoint.token
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	engine := g.currentStore().engine
+	sdl := engine.superGraph.PrintSDL(g.schemaEndpoint.options) + g.computedFields.sdl() + g.relayNode.sdl(engine)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(sdl)) //nolint:errcheck
+}
@@ -0,0 +1,103 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_SchemaEndpoint(t *testing.T) {
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			cost: Int @inaccessible
+		}
+
+		type Query {
+			product(id: ID!): Product
+		}
+	`
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{}}
+	})
+
+	newGateway := func(t *testing.T, opt gateway.SchemaEndpointOption) http.Handler {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:       "/graphql",
+			Services:       []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			SchemaEndpoint: opt,
+			HTTPPolicy: gateway.HTTPPolicyOption{
+				GraphQL: gateway.EndpointPolicyOption{CSRFPrevention: true},
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+	get := func(gw http.Handler, path, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		gw := newGateway(t, gateway.SchemaEndpointOption{})
+		if rec := get(gw, "/schema.graphql", ""); rec.Code == http.StatusOK {
+			t.Errorf("expected no schema endpoint, got %d", rec.Code)
+		}
+	})
+
+	t.Run("public schema", func(t *testing.T) {
+		gw := newGateway(t, gateway.SchemaEndpointOption{Enable: true})
+		rec := get(gw, "/schema.graphql", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		sdl := rec.Body.String()
+		if !strings.Contains(sdl, "type Product {") || !strings.Contains(sdl, "product(id: ID!): Product") {
+			t.Errorf("expected the composed schema, got:\n%s", sdl)
+		}
+		for _, unwanted := range []string{"@key", "_entities", "cost"} {
+			if strings.Contains(sdl, unwanted) {
+				t.Errorf("expected the public schema not to contain %q, got:\n%s", unwanted, sdl)
+			}
+		}
+	})
+
+	t.Run("federation and inaccessible", func(t *testing.T) {
+		gw := newGateway(t, gateway.SchemaEndpointOption{
+			Enable:       true,
+			Path:         "/sdl",
+			Federation:   true,
+			Inaccessible: true,
+			Token:        "secret",
+		})
+		if rec := get(gw, "/sdl", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 without a token, got %d", rec.Code)
+		}
+		if rec := get(gw, "/sdl", "Bearer wrong"); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 with a wrong token, got %d", rec.Code)
+		}
+
+		rec := get(gw, "/sdl", "Bearer secret")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		sdl := rec.Body.String()
+		for _, want := range []string{`@key(fields: "id")`, "_entities(representations: [_Any!]!): [_Entity]!", "cost: Int @inaccessible"} {
+			if !strings.Contains(sdl, want) {
+				t.Errorf("expected the schema to contain %q, got:\n%s", want, sdl)
+			}
+		}
+	})
+}
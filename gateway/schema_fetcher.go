@@ -2,11 +2,17 @@ package gateway
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
 )
 
 // serviceSDLResponse is the response body from a subgraph's GraphQL endpoint
@@ -21,51 +27,163 @@ type serviceSDLResponse struct {
 
 // RetryOption defines the retry configuration for SDL fetching.
 type RetryOption struct {
-	Attempts int    `yaml:"attempts" default:"3"`
-	Timeout  string `yaml:"timeout"  default:"5s"`
+	Attempts   int    `yaml:"attempts" default:"3"`
+	Timeout    string `yaml:"timeout"  default:"5s" validate:"duration"`
+	Backoff    string `yaml:"backoff" default:"100ms" validate:"duration"`  // Wait before the first retry, doubled after each failed attempt
+	MaxBackoff string `yaml:"max_backoff" default:"5s" validate:"duration"` // Upper bound of the wait between attempts
 }
 
 // fetchSDL fetches the SDL by sending { _service { sdl } } to the subgraph's GraphQL
-// endpoint (host). It retries up to attempts times, each with a per-attempt timeout.
+// endpoint (host). It retries up to attempts times, each with a per-attempt timeout,
+// waiting with exponential backoff between attempts.
 func fetchSDL(host string, httpClient *http.Client, retry RetryOption) (string, error) {
+	return fetchWithRetry(context.Background(), &introspectionSource{host: host, httpClient: httpClient}, retry)
+}
+
+// fetchWithRetry fetches the SDL from src, retrying up to attempts times, each with a
+// per-attempt timeout, and waiting with exponential backoff between attempts.
+func fetchWithRetry(ctx context.Context, src registry.SchemaSource, retry RetryOption) (string, error) {
 	attempts := retry.Attempts
 	if attempts <= 0 {
 		attempts = 1
 	}
 
-	timeoutDuration := 5 * time.Second
-	if retry.Timeout != "" {
-		if d, err := time.ParseDuration(retry.Timeout); err == nil {
-			timeoutDuration = d
-		}
-	}
-
-	body := []byte(`{"query":"{_service{sdl}}"}`)
+	timeoutDuration := parseDurationOr(retry.Timeout, 5*time.Second)
+	backoff := parseDurationOr(retry.Backoff, 100*time.Millisecond)
+	maxBackoff := parseDurationOr(retry.MaxBackoff, 5*time.Second)
 
 	var lastErr error
 	for i := 0; i < attempts; i++ {
-		sdl, err := doFetchSDL(host, httpClient, body, timeoutDuration)
+		if i > 0 && backoff > 0 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, timeoutDuration)
+		sdl, err := src.Fetch(attemptCtx)
+		cancel()
 		if err == nil {
 			return sdl, nil
 		}
 		lastErr = err
 	}
-	return "", fmt.Errorf("failed to fetch SDL from %s after %d attempt(s): %w", host, attempts, lastErr)
+	return "", fmt.Errorf("failed to fetch SDL from %s after %d attempt(s): %w", src.Name(), attempts, lastErr)
+}
+
+// introspectionSource loads a subgraph's SDL with the federation { _service { sdl } } query.
+type introspectionSource struct {
+	host       string
+	httpClient *http.Client
+}
+
+// Name implements registry.SchemaSource.
+func (s *introspectionSource) Name() string {
+	return s.host
+}
+
+// Fetch implements registry.SchemaSource.
+func (s *introspectionSource) Fetch(ctx context.Context) (string, error) {
+	return doFetchSDL(ctx, s.host, s.httpClient)
+}
+
+// newSchemaSource returns the configured schema source of svc, or introspection of its
+// host when none is configured: _service for federated services, the standard
+// introspection query for stitched ones.
+func newSchemaSource(svc GatewayService, httpClient *http.Client) (registry.SchemaSource, error) {
+	var src registry.SchemaSource
+	switch svc.Type {
+	case "", serviceTypeFederated:
+		if svc.Prefix != "" {
+			return nil, fmt.Errorf("prefix of service %q is only supported for stitched services", svc.Name)
+		}
+		src = &introspectionSource{host: svc.Host, httpClient: httpClient}
+	case serviceTypeStitched:
+		src = &schemaIntrospectionSource{host: svc.Host, httpClient: httpClient}
+	default:
+		return nil, fmt.Errorf("unknown type %q of service %q", svc.Type, svc.Name)
+	}
+
+	if svc.Source != nil {
+		// Schema sources are not subgraphs, so they bypass the subgraph transport (snapshots, tracing).
+		var err error
+		src, err = registry.New(*svc.Source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema source for service %q: %w", svc.Name, err)
+		}
+	}
+	if svc.Type == serviceTypeStitched {
+		src = &stitchedSource{src: src, prefix: svc.Prefix}
+	}
+	return src, nil
+}
+
+// loadSDL fetches the SDL of svc from src. When svc.SchemaCache is set, a fetched SDL is written
+// to that file and the file is used instead when the subgraph cannot be reached, so the
+// gateway can start while a subgraph is temporarily down.
+func loadSDL(svc GatewayService, src registry.SchemaSource) (string, error) {
+	sdl, err := fetchWithRetry(context.Background(), src, svc.Retry)
+	if err == nil {
+		writeSchemaCache(svc.SchemaCache, sdl)
+		return sdl, nil
+	}
+	if svc.SchemaCache == "" {
+		return "", err
+	}
+
+	cached, readErr := os.ReadFile(svc.SchemaCache)
+	if readErr != nil || len(cached) == 0 {
+		return "", fmt.Errorf("%w (no usable schema cache at %s)", err, svc.SchemaCache)
+	}
+	log.Printf("using cached schema %s for service %q: %v", svc.SchemaCache, svc.Name, err)
+	return string(cached), nil
+}
+
+// writeSchemaCache stores sdl at path, if set. Failures are logged, not returned: the
+// cache only matters for a later startup.
+func writeSchemaCache(path, sdl string) {
+	if path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Printf("failed to create schema cache directory for %s: %v", path, err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(sdl), 0o644); err != nil {
+		log.Printf("failed to write schema cache %s: %v", path, err)
+	}
+}
+
+// parseDurationOr parses s, returning fallback when s is empty or invalid.
+func parseDurationOr(s string, fallback time.Duration) time.Duration {
+	if s == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fallback
+	}
+	return d
 }
 
-// doFetchSDL performs a single SDL fetch attempt with the given timeout.
+// doFetchSDL performs a single SDL fetch attempt, bounded by ctx rather than the client timeout.
 // It POSTs the introspection query directly to host (which should be the subgraph's
 // GraphQL endpoint, e.g. http://localhost:8101/query).
-func doFetchSDL(host string, httpClient *http.Client, body []byte, timeout time.Duration) (string, error) {
-	client := httpClient
-	if timeout > 0 {
-		client = &http.Client{
-			Timeout:   timeout,
-			Transport: httpClient.Transport,
-		}
+func doFetchSDL(ctx context.Context, host string, httpClient *http.Client) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, host, bytes.NewBufferString(`{"query":"{_service{sdl}}"}`))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Post(host, "application/json", bytes.NewBuffer(body))
+	client := &http.Client{Transport: httpClient.Transport}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("HTTP request failed: %w", err)
 	}
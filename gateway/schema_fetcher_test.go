@@ -3,10 +3,16 @@ package gateway_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/goccy/go-json"
+
 	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
 )
 
 func TestFetchSDL_Success(t *testing.T) {
@@ -99,3 +105,208 @@ func TestFetchSDL_Timeout(t *testing.T) {
 		t.Fatal("expected timeout error")
 	}
 }
+
+func TestFetchSDL_Backoff(t *testing.T) {
+	var calls []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, time.Now())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	_, err := gateway.FetchSDLForTest(srv.URL, &http.Client{}, gateway.RetryOption{Attempts: 3, Timeout: "5s", Backoff: "20ms", MaxBackoff: "30ms"})
+	if err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(calls))
+	}
+	if d := calls[1].Sub(calls[0]); d < 20*time.Millisecond {
+		t.Errorf("expected at least 20ms before the first retry, got %s", d)
+	}
+	if d := calls[2].Sub(calls[1]); d < 30*time.Millisecond {
+		t.Errorf("expected the doubled backoff capped at 30ms before the second retry, got %s", d)
+	}
+}
+
+func TestNewGateway_SchemaCache(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "schemas", "hello.graphql")
+	up := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"_service":{"sdl":"type Query { hello: String }"}}}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	opt := gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{
+			Name:        "hello",
+			Host:        srv.URL,
+			Retry:       gateway.RetryOption{Attempts: 1},
+			SchemaCache: cache,
+		}},
+	}
+
+	if _, err := gateway.NewGateway(opt); err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	cached, err := os.ReadFile(cache)
+	if err != nil {
+		t.Fatalf("expected the fetched SDL to be cached: %v", err)
+	}
+	if string(cached) != "type Query { hello: String }" {
+		t.Errorf("unexpected cached SDL %q", cached)
+	}
+
+	// The cached SDL is used while the subgraph is down.
+	up = false
+	if _, err := gateway.NewGateway(opt); err != nil {
+		t.Fatalf("expected NewGateway to fall back to the schema cache: %v", err)
+	}
+
+	opt.Services[0].SchemaCache = ""
+	if _, err := gateway.NewGateway(opt); err == nil {
+		t.Error("expected NewGateway to fail without a schema cache")
+	}
+}
+
+func TestNewGateway_SchemaSourcePolling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.graphql")
+	if err := os.WriteFile(path, []byte("type Query { hello: String }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:           "/graphql",
+		EnableSubgraphMode: true,
+		Services: []gateway.GatewayService{{
+			Name:   "hello",
+			Host:   "http://hello.invalid/query",
+			Source: &registry.SourceOption{Type: "file", Path: path, PollInterval: "10ms"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	defer gw.Close()
+
+	sdl := func() string {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ _service { sdl } }"}`)))
+		var resp struct {
+			Data struct {
+				Service struct {
+					SDL string `json:"sdl"`
+				} `json:"_service"`
+			} `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp) //nolint:errcheck
+		return resp.Data.Service.SDL
+	}
+	if got := sdl(); !strings.Contains(got, "hello: String") {
+		t.Fatalf("expected the SDL from the schema file, got %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("type Query { hello: String world: String }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(sdl(), "world: String") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the updated schema file to be applied, got %q", sdl())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The apply endpoint reloads from the schema source as well.
+	if err := os.WriteFile(path, []byte("type Query { hello: String world: String again: String }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hello/apply", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected apply to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := sdl(); !strings.Contains(got, "again: String") {
+		t.Errorf("expected the applied schema, got %q", got)
+	}
+}
+
+func TestNewGateway_CompositionCache(t *testing.T) {
+	const sdl = "type Query { hello: String }"
+	srv := newSubgraphServer(t, sdl, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"hello": "world"}}
+	})
+	cache := filepath.Join(t.TempDir(), "cache", "composition.json")
+	opt := gateway.GatewayOption{
+		Endpoint:         "/graphql",
+		Services:         []gateway.GatewayService{{Name: "hello", Host: srv.URL}},
+		CompositionCache: cache,
+	}
+	hello := func(t *testing.T) string {
+		t.Helper()
+		gw, err := gateway.NewGateway(opt)
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ hello }"}`)))
+		return rec.Body.String()
+	}
+	readCache := func(t *testing.T) map[string]any {
+		t.Helper()
+		data, err := os.ReadFile(cache)
+		if err != nil {
+			t.Fatalf("expected the composition to be cached: %v", err)
+		}
+		var composition map[string]any
+		if err := json.Unmarshal(data, &composition); err != nil {
+			t.Fatalf("failed to decode composition cache %s: %v", data, err)
+		}
+		return composition
+	}
+	writeCache := func(t *testing.T, composition map[string]any) {
+		t.Helper()
+		data, _ := json.Marshal(composition)
+		if err := os.WriteFile(cache, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if body := hello(t); !strings.Contains(body, `"world"`) {
+		t.Fatalf("unexpected response %s", body)
+	}
+	composition := readCache(t)
+	if composition["schemaHash"] != registry.SchemaHash(map[string]string{"hello": sdl}) {
+		t.Errorf("expected the cache to be keyed by the schema hash, got %v", composition["schemaHash"])
+	}
+
+	// A cache of the same SDLs is used as is: without an owner, hello cannot be planned.
+	composition["ownership"] = map[string]any{}
+	writeCache(t, composition)
+	if body := hello(t); strings.Contains(body, `"world"`) {
+		t.Errorf("expected the cached ownership to be used, got %s", body)
+	}
+
+	// A cache of other SDLs is rebuilt.
+	composition["schemaHash"] = "stale"
+	writeCache(t, composition)
+	if body := hello(t); !strings.Contains(body, `"world"`) {
+		t.Errorf("expected a stale cache to be rebuilt, got %s", body)
+	}
+	if got := readCache(t)["schemaHash"]; got != registry.SchemaHash(map[string]string{"hello": sdl}) {
+		t.Errorf("expected the rebuilt composition to be cached, got hash %v", got)
+	}
+
+	// So is a corrupted one.
+	if err := os.WriteFile(cache, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if body := hello(t); !strings.Contains(body, `"world"`) {
+		t.Errorf("expected a corrupted cache to be rebuilt, got %s", body)
+	}
+}
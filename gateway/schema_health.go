@@ -0,0 +1,237 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+// schemaHealthPath reports the freshness of the SDL of every polled subgraph.
+const schemaHealthPath = "/admin/schema/health"
+
+// schemaFreshness tracks the polls of a subgraph schema source. While polls fail the
+// gateway keeps serving the last SDL it fetched, which is then stale; once it has been
+// stale for longer than maxStaleness the subgraph is degraded.
+type schemaFreshness struct {
+	maxStaleness time.Duration
+
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+	failures    int // Consecutive failed polls
+}
+
+// schemaFreshnessStatus is the freshness of a subgraph schema in health reports.
+type schemaFreshnessStatus struct {
+	Stale               bool       `json:"stale"`
+	Degraded            bool       `json:"degraded"`
+	StalenessSeconds    float64    `json:"staleness_seconds"`
+	MaxStalenessSeconds float64    `json:"max_staleness_seconds,omitempty"`
+	LastSuccess         time.Time  `json:"last_success"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// status returns the freshness of the schema at now.
+func (f *schemaFreshness) status(now time.Time) schemaFreshnessStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	st := schemaFreshnessStatus{
+		Stale:               f.failures > 0,
+		MaxStalenessSeconds: f.maxStaleness.Seconds(),
+		LastSuccess:         f.lastSuccess,
+		LastError:           f.lastError,
+		ConsecutiveFailures: f.failures,
+	}
+	if !f.lastFailure.IsZero() {
+		lastFailure := f.lastFailure
+		st.LastFailure = &lastFailure
+	}
+	if st.Stale {
+		staleness := now.Sub(f.lastSuccess)
+		st.StalenessSeconds = staleness.Seconds()
+		st.Degraded = f.maxStaleness > 0 && staleness > f.maxStaleness
+	}
+	return st
+}
+
+// record records the outcome of a poll.
+func (f *schemaFreshness) record(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		f.lastSuccess = time.Now()
+		f.lastError = ""
+		f.failures = 0
+		return
+	}
+	f.lastFailure = time.Now()
+	f.lastError = err.Error()
+	f.failures++
+}
+
+// freshnessSource records the outcome of every fetch of a schema source.
+type freshnessSource struct {
+	registry.SchemaSource
+	name      string
+	freshness *schemaFreshness
+	failures  metric.Int64Counter
+	labels    metric.MeasurementOption
+}
+
+// Fetch fetches the SDL and records whether it succeeded. Fetches cancelled by ctx
+// are not recorded.
+func (s *freshnessSource) Fetch(ctx context.Context) (string, error) {
+	sdl, err := s.SchemaSource.Fetch(ctx)
+	if ctx.Err() != nil {
+		return sdl, err
+	}
+	s.freshness.record(err)
+	if err != nil {
+		s.failures.Add(ctx, 1, metric.WithAttributes(attribute.String("graphql.subgraph.name", s.name)), s.labels)
+		if st := s.freshness.status(time.Now()); st.Degraded {
+			log.Printf("schema of subgraph %q is degraded: polls have failed for %.0fs", s.name, st.StalenessSeconds)
+		}
+	}
+	return sdl, err
+}
+
+// schemaHealth tracks the freshness of the polled subgraph schemas and exposes it in
+// the graphql.subgraph.schema.staleness metric and on schemaHealthPath.
+type schemaHealth struct {
+	subgraphs    map[string]*schemaFreshness
+	registration metric.Registration
+}
+
+// newSchemaHealth wraps the source of every polled service so its fetches are tracked.
+// The SDLs loaded on startup count as fresh.
+func newSchemaHealth(services []GatewayService, sources map[string]registry.SchemaSource, intervals map[string]time.Duration, labels metric.MeasurementOption) (*schemaHealth, error) {
+	h := &schemaHealth{subgraphs: make(map[string]*schemaFreshness)}
+
+	var failures metric.Int64Counter
+	for _, svc := range services {
+		if intervals[svc.Name] <= 0 {
+			continue
+		}
+		maxStaleness, err := svc.Source.Staleness()
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema source for service %q: %w", svc.Name, err)
+		}
+
+		if failures == nil {
+			failures, err = otel.Meter("github.com/n9te9/go-graphql-federation-gateway").Int64Counter(
+				"graphql.subgraph.schema.fetch_failures",
+				metric.WithDescription("Number of failed polls of a subgraph schema source"),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create schema fetch failure counter: %w", err)
+			}
+		}
+
+		freshness := &schemaFreshness{maxStaleness: maxStaleness, lastSuccess: time.Now()}
+		h.subgraphs[svc.Name] = freshness
+		sources[svc.Name] = &freshnessSource{
+			SchemaSource: sources[svc.Name],
+			name:         svc.Name,
+			freshness:    freshness,
+			failures:     failures,
+			labels:       labels,
+		}
+	}
+	if len(h.subgraphs) == 0 {
+		return h, nil
+	}
+
+	meter := otel.Meter("github.com/n9te9/go-graphql-federation-gateway")
+	staleness, err := meter.Float64ObservableGauge(
+		"graphql.subgraph.schema.staleness",
+		metric.WithDescription("Seconds a subgraph has been served with its last known good SDL because polls fail"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema staleness gauge: %w", err)
+	}
+	h.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		now := time.Now()
+		for name, freshness := range h.subgraphs {
+			st := freshness.status(now)
+			o.ObserveFloat64(staleness, st.StalenessSeconds, metric.WithAttributes(
+				attribute.String("graphql.subgraph.name", name),
+				attribute.Bool("graphql.subgraph.degraded", st.Degraded),
+			), labels)
+		}
+		return nil
+	}, staleness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register schema staleness gauge: %w", err)
+	}
+	return h, nil
+}
+
+// close stops reporting the staleness metric.
+func (h *schemaHealth) close() error {
+	if h == nil || h.registration == nil {
+		return nil
+	}
+	return h.registration.Unregister()
+}
+
+// schemaHealthResponse reports the freshness of the polled subgraph schemas.
+type schemaHealthResponse struct {
+	Status    string                           `json:"status"` // ok, stale or degraded
+	Degraded  []string                         `json:"degraded"`
+	Subgraphs map[string]schemaFreshnessStatus `json:"subgraphs"`
+}
+
+// handleSchemaHealth reports the freshness of every polled subgraph schema. It
+// responds 503 when a subgraph is degraded so it can back a readiness probe.
+func (g *gateway) handleSchemaHealth(w http.ResponseWriter) {
+	now := time.Now()
+	names := make([]string, 0, len(g.schemaHealth.subgraphs))
+	for name := range g.schemaHealth.subgraphs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	status := "ok"
+	subgraphs := make(map[string]schemaFreshnessStatus, len(names))
+	degraded := []string{}
+	for _, name := range names {
+		st := g.schemaHealth.subgraphs[name].status(now)
+		subgraphs[name] = st
+		if st.Degraded {
+			degraded = append(degraded, name)
+		} else if st.Stale && status == "ok" {
+			status = "stale"
+		}
+	}
+
+	code := http.StatusOK
+	if len(degraded) > 0 {
+		status = "degraded"
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(schemaHealthResponse{ //nolint:errcheck
+		Status:    status,
+		Degraded:  degraded,
+		Subgraphs: subgraphs,
+	})
+}
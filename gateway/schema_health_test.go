@@ -0,0 +1,126 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+func TestGateway_SchemaHealth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.graphql")
+	if err := os.WriteFile(path, []byte("type Query { hello: String }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:           "/graphql",
+		EnableSubgraphMode: true,
+		Services: []gateway.GatewayService{{
+			Name:   "hello",
+			Host:   "http://hello.invalid/query",
+			Source: &registry.SourceOption{Type: "file", Path: path, PollInterval: "10ms", MaxStaleness: "200ms"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	defer gw.Close()
+
+	type subgraphHealth struct {
+		Stale     bool   `json:"stale"`
+		Degraded  bool   `json:"degraded"`
+		LastError string `json:"last_error"`
+	}
+	type healthReport struct {
+		Status    string                    `json:"status"`
+		Degraded  []string                  `json:"degraded"`
+		Subgraphs map[string]subgraphHealth `json:"subgraphs"`
+	}
+	health := func() (int, healthReport) {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/schema/health", nil))
+		var report healthReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("failed to decode health report %s: %v", rec.Body.String(), err)
+		}
+		return rec.Code, report
+	}
+	waitFor := func(status string) (int, healthReport) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			code, report := health()
+			if report.Status == status {
+				return code, report
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected status %q, got %+v", status, report)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	sdl := func() string {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ _service { sdl } }"}`)))
+		return rec.Body.String()
+	}
+
+	if code, report := health(); code != http.StatusOK || report.Status != "ok" || report.Subgraphs["hello"].Stale {
+		t.Fatalf("expected a fresh schema, got %d %+v", code, report)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	code, report := waitFor("stale")
+	if code != http.StatusOK || report.Subgraphs["hello"].LastError == "" {
+		t.Errorf("expected a stale schema with its poll error, got %d %+v", code, report)
+	}
+	if !strings.Contains(sdl(), "hello: String") {
+		t.Errorf("expected the last known good SDL to be served, got %s", sdl())
+	}
+
+	code, report = waitFor("degraded")
+	if code != http.StatusServiceUnavailable || len(report.Degraded) != 1 || report.Degraded[0] != "hello" {
+		t.Errorf("expected hello to be degraded with 503, got %d %+v", code, report)
+	}
+	if !strings.Contains(sdl(), "hello: String") {
+		t.Errorf("expected a degraded subgraph to keep its SDL, got %s", sdl())
+	}
+
+	if err := os.WriteFile(path, []byte("type Query { hello: String world: String }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, report = waitFor("ok")
+	if code != http.StatusOK || report.Subgraphs["hello"].Stale {
+		t.Errorf("expected the schema to be fresh again, got %d %+v", code, report)
+	}
+}
+
+func TestGateway_SchemaHealth_InvalidMaxStaleness(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.graphql")
+	if err := os.WriteFile(path, []byte("type Query { hello: String }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{
+			Name:   "hello",
+			Host:   "http://hello.invalid/query",
+			Source: &registry.SourceOption{Type: "file", Path: path, PollInterval: "1m", MaxStaleness: "a while"},
+		}},
+	})
+	if err == nil {
+		t.Error("expected an invalid max_staleness error")
+	}
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+const (
+	// schemaVersionsPath lists the schema versions kept for rollback.
+	schemaVersionsPath = "/admin/schema/versions"
+	// schemaRollbackPath installs a previous schema version.
+	schemaRollbackPath = "/admin/schema/rollback"
+)
+
+// schemaVersionSummary describes a schema version without its SDLs.
+type schemaVersionSummary struct {
+	Version   int64             `json:"version"`
+	Hash      string            `json:"hash"`
+	CreatedAt time.Time         `json:"created_at"`
+	Digests   map[string]string `json:"digests"`
+	Current   bool              `json:"current"`
+}
+
+// schemaVersionsResponse lists the schema versions kept by the registry.
+type schemaVersionsResponse struct {
+	Current  int64                  `json:"current"`
+	Versions []schemaVersionSummary `json:"versions"`
+}
+
+// schemaRollbackResponse reports the version installed by a rollback.
+type schemaRollbackResponse struct {
+	OK      bool  `json:"ok"`
+	Version int64 `json:"version"`
+}
+
+// schemaRollback is the body of a rollback request.
+type schemaRollback struct {
+	Version int64 `json:"version"`
+}
+
+// handleSchemaVersions lists the schema versions kept by the registry, newest first.
+func (g *gateway) handleSchemaVersions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	history, err := g.schemaRegistry.History(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]any{"error": err.Error()}) //nolint:errcheck
+		return
+	}
+
+	g.mu.Lock()
+	current := g.schemaVersion
+	g.mu.Unlock()
+
+	versions := make([]schemaVersionSummary, 0, len(history))
+	for _, v := range history {
+		versions = append(versions, schemaVersionSummary{
+			Version:   v.Version,
+			Hash:      v.Hash,
+			CreatedAt: v.CreatedAt,
+			Digests:   v.Digests,
+			Current:   v.Version == current,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schemaVersionsResponse{Current: current, Versions: versions}) //nolint:errcheck
+}
+
+// handleSchemaRollback makes a previous schema version current in the registry and
+// installs it. When it cannot be installed, the registry is pointed back at the
+// version that was installed before.
+func (g *gateway) handleSchemaRollback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req schemaRollback
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"error": fmt.Sprintf("invalid rollback request: %v", err)}) //nolint:errcheck
+		return
+	}
+
+	g.mu.Lock()
+	previous := g.schemaVersion
+	g.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), registryTimeout)
+	defer cancel()
+
+	v, err := g.schemaRegistry.Rollback(ctx, req.Version)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, registry.ErrVersionNotFound) {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"error": err.Error()}) //nolint:errcheck
+		return
+	}
+
+	if err := g.installVersion(v); err != nil {
+		if _, restoreErr := g.schemaRegistry.Rollback(ctx, previous); restoreErr != nil {
+			err = fmt.Errorf("%w (restoring version %d failed: %v)", err, previous, restoreErr)
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{"error": err.Error()}) //nolint:errcheck
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schemaRollbackResponse{OK: true, Version: v.Version}) //nolint:errcheck
+}
@@ -0,0 +1,86 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_SchemaHistory(t *testing.T) {
+	var mu sync.Mutex
+	sdl := "type Query { hello: String }"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:           "/graphql",
+		EnableSubgraphMode: true,
+		Services:           []gateway.GatewayService{{Name: "hello", Host: srv.URL}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	defer gw.Close()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	mu.Lock()
+	sdl = "type Query { hello: String world: String }"
+	mu.Unlock()
+	if rec := serve(http.MethodPost, "/hello/apply", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected apply to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := serve(http.MethodGet, "/admin/schema/versions", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var versions struct {
+		Current  int64 `json:"current"`
+		Versions []struct {
+			Version int64             `json:"version"`
+			Hash    string            `json:"hash"`
+			Digests map[string]string `json:"digests"`
+			Current bool              `json:"current"`
+		} `json:"versions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &versions); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if versions.Current != 2 || len(versions.Versions) != 2 || !versions.Versions[0].Current || versions.Versions[1].Current {
+		t.Fatalf("expected versions [2 (current) 1], got %s", rec.Body.String())
+	}
+	if versions.Versions[0].Hash == versions.Versions[1].Hash || versions.Versions[0].Digests["hello"] == "" {
+		t.Errorf("expected distinct hashes and per-subgraph digests, got %s", rec.Body.String())
+	}
+
+	if rec := serve(http.MethodPost, "/admin/schema/rollback", `{"version": 1}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected rollback to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(http.MethodPost, "/graphql", `{"query":"{ _service { sdl } }"}`)
+	if strings.Contains(rec.Body.String(), "world") {
+		t.Errorf("expected the rolled back schema, got %s", rec.Body.String())
+	}
+
+	if rec := serve(http.MethodPost, "/admin/schema/rollback", `{"version": 99}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown version, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/admin/schema/rollback", `not json`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid body, got %d", rec.Code)
+	}
+}
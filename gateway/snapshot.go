@@ -0,0 +1,331 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+const (
+	// SnapshotModeRecord forwards subgraph traffic to the live services and
+	// writes every request/response pair into the snapshot directory.
+	SnapshotModeRecord = "record"
+	// SnapshotModeReplay serves subgraph traffic entirely from the snapshot
+	// directory without opening any network connection.
+	SnapshotModeReplay = "replay"
+)
+
+// redactedValue replaces the values of redacted keys in recorded exchanges.
+const redactedValue = "[REDACTED]"
+
+// SnapshotOption configures the snapshot mode used for hermetic tests and for
+// reproducing production issues from recorded subgraph traffic.
+type SnapshotOption struct {
+	Mode   string   `yaml:"mode" validate:"oneof=record|replay"` // "", "record" or "replay"
+	Dir    string   `yaml:"dir"`                                 // Directory holding recorded subgraph responses
+	Redact []string `yaml:"redact"`                              // Object keys whose values are redacted in recordings, e.g. email
+
+	// Store records exchanges to and replays them from e.g. an object store. When nil,
+	// they are stored in Dir.
+	Store SnapshotStore `yaml:"-"`
+}
+
+// SnapshotStore stores recorded subgraph exchanges. A key is the hex SHA-256 digest of
+// the request body sent to the service.
+type SnapshotStore interface {
+	SaveSnapshot(ctx context.Context, service, key string, data []byte) error
+	LoadSnapshot(ctx context.Context, service, key string) ([]byte, bool, error)
+}
+
+// DirSnapshots stores recorded exchanges as {dir}/{service}/{key}.json files.
+type DirSnapshots string
+
+// SaveSnapshot writes the exchange through a temporary file, so concurrent recordings
+// of the same request never interleave.
+func (d DirSnapshots) SaveSnapshot(_ context.Context, service, key string, data []byte) error {
+	dir := filepath.Join(string(d), service)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+	f, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(f.Name()) //nolint:errcheck
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write snapshot %s: %w", f.Name(), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot %s: %w", f.Name(), err)
+	}
+	if err := os.Rename(f.Name(), filepath.Join(dir, key+".json")); err != nil {
+		return fmt.Errorf("failed to write snapshot %s: %w", key, err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads the exchange recorded for key.
+func (d DirSnapshots) LoadSnapshot(_ context.Context, service, key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(filepath.Join(string(d), service, key+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read snapshot %s: %w", key, err)
+	}
+	return data, true, nil
+}
+
+// snapshotEntry is the stored format of a single recorded subgraph exchange. Bodies that
+// are not JSON, e.g. HTML error pages, are stored base64-encoded instead, so that they are
+// replayed byte for byte.
+type snapshotEntry struct {
+	Service        string          `json:"service"`
+	Request        json.RawMessage `json:"request,omitempty"`
+	RequestBase64  string          `json:"request_base64,omitempty"`
+	StatusCode     int             `json:"status_code"`
+	ContentType    string          `json:"content_type,omitempty"`
+	Response       json.RawMessage `json:"response,omitempty"`
+	ResponseBase64 string          `json:"response_base64,omitempty"`
+}
+
+// snapshotTransport is an http.RoundTripper that records subgraph traffic into a
+// snapshot directory or replays it from there, depending on mode.
+type snapshotTransport struct {
+	mode     string
+	store    SnapshotStore
+	redact   map[string]bool   // Lower-cased keys whose values are redacted
+	services map[string]string // subgraph host URL → subgraph name
+	next     http.RoundTripper
+}
+
+var _ http.RoundTripper = (*snapshotTransport)(nil)
+
+// newSnapshotTransport builds a snapshotTransport for the configured services.
+// next is only used in record mode to reach the live subgraphs.
+func newSnapshotTransport(opt SnapshotOption, services []GatewayService, next http.RoundTripper) (*snapshotTransport, error) {
+	if opt.Mode != SnapshotModeRecord && opt.Mode != SnapshotModeReplay {
+		return nil, fmt.Errorf("unknown snapshot mode %q", opt.Mode)
+	}
+	store := opt.Store
+	if store == nil {
+		if opt.Dir == "" {
+			return nil, fmt.Errorf("snapshot dir is required for %q mode", opt.Mode)
+		}
+		store = DirSnapshots(opt.Dir)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	hosts := make(map[string]string, len(services))
+	for _, svc := range services {
+		hosts[svc.Host] = svc.Name
+		endpoints := svc.Endpoints.executorEndpoints()
+		for _, op := range []ast.OperationType{ast.Query, ast.Mutation, ast.Subscription} {
+			hosts[endpoints.URL(string(op), svc.Host)] = svc.Name
+		}
+	}
+
+	redact := make(map[string]bool, len(opt.Redact))
+	for _, key := range opt.Redact {
+		redact[strings.ToLower(key)] = true
+	}
+
+	return &snapshotTransport{
+		mode:     opt.Mode,
+		store:    store,
+		redact:   redact,
+		services: hosts,
+		next:     next,
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *snapshotTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body.Close()
+		body = b
+	}
+
+	service := t.serviceName(req.URL)
+	key := snapshotKey(body)
+
+	if t.mode == SnapshotModeReplay {
+		return t.replay(req, service, key)
+	}
+
+	// A RoundTripper must not modify the request of its caller.
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return t.record(out, service, key, body)
+}
+
+// replay serves a recorded response for the request.
+func (t *snapshotTransport) replay(req *http.Request, service, key string) (*http.Response, error) {
+	b, ok, err := t.store.LoadSnapshot(req.Context(), service, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no snapshot recorded for request to %s: %s/%s", req.URL, service, key)
+	}
+
+	var entry snapshotEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s/%s: %w", service, key, err)
+	}
+
+	var body bytes.Buffer
+	if entry.ResponseBase64 != "" {
+		raw, err := base64.StdEncoding.DecodeString(entry.ResponseBase64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot %s/%s: %w", service, key, err)
+		}
+		body.Write(raw)
+	} else if len(entry.Response) > 0 {
+		// Entries are stored indented; hand the executor the compact form.
+		if err := json.Compact(&body, entry.Response); err != nil {
+			return nil, fmt.Errorf("failed to compact snapshot %s/%s: %w", service, key, err)
+		}
+	}
+	contentType := entry.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(entry.StatusCode),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(&body),
+		ContentLength: int64(body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// record forwards the request to the live subgraph and stores the exchange, with the
+// values of redacted keys replaced.
+func (t *snapshotTransport) record(req *http.Request, service, key string, body []byte) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	entry := snapshotEntry{
+		Service:     service,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	entry.Request, entry.RequestBase64 = t.sanitize(body)
+	entry.Response, entry.ResponseBase64 = t.sanitize(respBody)
+	if err := t.write(req.Context(), service, key, entry); err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+	return resp, nil
+}
+
+// write stores a snapshot entry as indented JSON so recordings diff cleanly.
+func (t *snapshotTransport) write(ctx context.Context, service, key string, entry snapshotEntry) error {
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return t.store.SaveSnapshot(ctx, service, key, b)
+}
+
+// sanitize returns b as a RawMessage with the values of redacted keys replaced or, when b
+// is not JSON, base64-encoded as it is.
+func (t *snapshotTransport) sanitize(b []byte) (json.RawMessage, string) {
+	if !json.Valid(b) {
+		return nil, base64.StdEncoding.EncodeToString(b)
+	}
+	if len(t.redact) == 0 {
+		return json.RawMessage(b), ""
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return json.RawMessage(b), ""
+	}
+	redacted, err := json.Marshal(t.redactValue(v))
+	if err != nil {
+		return json.RawMessage(b), ""
+	}
+	return json.RawMessage(redacted), ""
+}
+
+// redactValue replaces the values of redacted keys in v, at any depth.
+func (t *snapshotTransport) redactValue(v any) any {
+	return redactKeys(v, t.redact)
+}
+
+// redactKeys replaces the values of the keys of v in keys, lower-cased, at any depth.
+func redactKeys(v any, keys map[string]bool) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if keys[strings.ToLower(k)] {
+				val[k] = redactedValue
+				continue
+			}
+			val[k] = redactKeys(child, keys)
+		}
+	case []any:
+		for i, child := range val {
+			val[i] = redactKeys(child, keys)
+		}
+	}
+	return v
+}
+
+// serviceName resolves the subgraph name for a request URL, falling back to
+// the URL host when the URL does not match any configured service.
+func (t *snapshotTransport) serviceName(u *url.URL) string {
+	if name, ok := t.services[u.String()]; ok {
+		return name
+	}
+	return strings.NewReplacer(":", "_", "/", "_").Replace(u.Host)
+}
+
+// snapshotKey returns the key of the exchange of a request body.
+func snapshotKey(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,254 @@
+package gateway_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestSnapshotTransport_RecordThenReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"hello":"world"}}`)) //nolint:errcheck
+	}))
+
+	dir := t.TempDir()
+	services := []gateway.GatewayService{{Name: "hello", Host: srv.URL}}
+	body := `{"query":"{hello}"}`
+
+	recorder, err := gateway.NewSnapshotTransportForTest(gateway.SnapshotOption{Mode: gateway.SnapshotModeRecord, Dir: dir}, services, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recordClient := &http.Client{Transport: recorder}
+	resp, err := recordClient.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("record request failed: %v", err)
+	}
+	recorded, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if calls != 1 {
+		t.Fatalf("expected 1 live call while recording, got %d", calls)
+	}
+
+	// Shut the live subgraph down: replay must not need it.
+	srv.Close()
+
+	replayer, err := gateway.NewSnapshotTransportForTest(gateway.SnapshotOption{Mode: gateway.SnapshotModeReplay, Dir: dir}, services, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replayClient := &http.Client{Transport: replayer}
+	resp, err = replayClient.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("replay request failed: %v", err)
+	}
+	replayed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status mismatch: got %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if string(replayed) != string(recorded) {
+		t.Errorf("body mismatch: got %s, want %s", replayed, recorded)
+	}
+}
+
+func TestSnapshotTransport_ReplaysNonJSONBodiesAsTheyAre(t *testing.T) {
+	page := "<html>\n  <body>502 Bad Gateway</body>\n</html>\n\x00\xff"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(page)) //nolint:errcheck
+	}))
+
+	dir := t.TempDir()
+	services := []gateway.GatewayService{{Name: "hello", Host: srv.URL}}
+	body := `{"query":"{hello}"}`
+
+	recorder, err := gateway.NewSnapshotTransportForTest(gateway.SnapshotOption{Mode: gateway.SnapshotModeRecord, Dir: dir}, services, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := (&http.Client{Transport: recorder}).Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("record request failed: %v", err)
+	}
+	resp.Body.Close()
+	srv.Close()
+
+	replayer, err := gateway.NewSnapshotTransportForTest(gateway.SnapshotOption{Mode: gateway.SnapshotModeReplay, Dir: dir}, services, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err = (&http.Client{Transport: replayer}).Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("replay request failed: %v", err)
+	}
+	replayed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("expected the recorded content type, got %q", got)
+	}
+	if string(replayed) != page {
+		t.Errorf("expected %q, got %q", page, replayed)
+	}
+}
+
+// trackedBody is a request body reporting whether it was closed.
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestSnapshotTransport_RecordDoesNotModifyTheRequest(t *testing.T) {
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.Write([]byte(`{"data":{"hello":"world"}}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	recorder, err := gateway.NewSnapshotTransportForTest(gateway.SnapshotOption{Mode: gateway.SnapshotModeRecord, Dir: t.TempDir()}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body := &trackedBody{Reader: strings.NewReader(`{"query":"{hello}"}`)}
+	req, err := http.NewRequest(http.MethodPost, srv.URL, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := recorder.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if req.Body != body {
+		t.Error("expected the body of the request to be left as it is")
+	}
+	if !body.closed {
+		t.Error("expected the body of the request to be closed")
+	}
+	if received != `{"query":"{hello}"}` {
+		t.Errorf("expected the subgraph to receive the body, got %q", received)
+	}
+}
+
+func TestSnapshotTransport_ReplayMissing(t *testing.T) {
+	replayer, err := gateway.NewSnapshotTransportForTest(gateway.SnapshotOption{Mode: gateway.SnapshotModeReplay, Dir: t.TempDir()}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := &http.Client{Transport: replayer}
+	if _, err := client.Post("http://localhost:4001", "application/json", strings.NewReader(`{"query":"{x}"}`)); err == nil {
+		t.Fatal("expected error for unrecorded request")
+	}
+}
+
+func TestSnapshotTransport_InvalidOption(t *testing.T) {
+	if _, err := gateway.NewSnapshotTransportForTest(gateway.SnapshotOption{Mode: "bogus", Dir: "x"}, nil, nil); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+	if _, err := gateway.NewSnapshotTransportForTest(gateway.SnapshotOption{Mode: gateway.SnapshotModeReplay}, nil, nil); err == nil {
+		t.Fatal("expected error for missing dir")
+	}
+}
+
+// memorySnapshots is a SnapshotStore standing in for an object store.
+type memorySnapshots struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (m *memorySnapshots) SaveSnapshot(_ context.Context, service, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[service+"/"+key] = data
+	return nil
+}
+
+func (m *memorySnapshots) LoadSnapshot(_ context.Context, service, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.entries[service+"/"+key]
+	return data, ok, nil
+}
+
+func TestGateway_SnapshotStoreWithRedaction(t *testing.T) {
+	const usersSDL = `
+		type Query {
+			user(email: String!): User
+		}
+
+		type User {
+			id: ID!
+			email: String!
+		}
+	`
+	users := newSubgraphServer(t, usersSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"user": map[string]any{"id": "1", "email": "ada@example.com"}}}
+	})
+
+	store := &memorySnapshots{entries: make(map[string][]byte)}
+	newGateway := func(t *testing.T, mode string) http.Handler {
+		t.Helper()
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint: "/graphql",
+			Services: []gateway.GatewayService{{Name: "users", Host: users.URL}},
+			Snapshot: gateway.SnapshotOption{Mode: mode, Redact: []string{"Email"}, Store: store},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		return gw
+	}
+	query := func(gw http.Handler) string {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(
+			`{"query":"query ($email: String!) { user(email: $email) { id email } }","variables":{"email":"ada@example.com"}}`)))
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	if got := query(newGateway(t, gateway.SnapshotModeRecord)); got != `{"data":{"user":{"id":"1","email":"ada@example.com"}}}` {
+		t.Fatalf("expected the live response while recording, got %s", got)
+	}
+	var recorded int
+	for key, data := range store.entries {
+		if strings.Contains(string(data), "ada@example.com") {
+			t.Errorf("expected the email to be redacted in %s, got %s", key, data)
+		}
+		if strings.Contains(string(data), `"user"`) {
+			recorded++
+			if strings.Count(string(data), "[REDACTED]") != 2 {
+				t.Errorf("expected the variable and the response field to be redacted, got %s", data)
+			}
+		}
+	}
+	if recorded != 1 {
+		t.Fatalf("expected 1 recorded operation, got %d", recorded)
+	}
+
+	users.Close()
+	if got := query(newGateway(t, gateway.SnapshotModeReplay)); got != `{"data":{"user":{"id":"1","email":"[REDACTED]"}}}` {
+		t.Errorf("expected the recorded response, got %s", got)
+	}
+}
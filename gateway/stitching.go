@@ -0,0 +1,343 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+	"github.com/n9te9/graphql-parser/token"
+
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+// Types of GatewayService.
+const (
+	serviceTypeFederated = "federated"
+	serviceTypeStitched  = "stitched"
+)
+
+// stitchedSource loads the schema of a stitched service: a plain GraphQL service whose
+// root fields are delegated as they are. With a prefix, the root fields are renamed in
+// the supergraph and keep their remote name in @stitched(field:), under which the query
+// builder sends them.
+type stitchedSource struct {
+	src    registry.SchemaSource
+	prefix string
+}
+
+// Name implements registry.SchemaSource.
+func (s *stitchedSource) Name() string {
+	return s.src.Name()
+}
+
+// Fetch implements registry.SchemaSource.
+func (s *stitchedSource) Fetch(ctx context.Context) (string, error) {
+	sdl, err := s.src.Fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	return stitchSDL(sdl, s.prefix)
+}
+
+// stitchSDL prefixes the root fields of sdl with prefix, recording their remote name
+// with @stitched(field:).
+func stitchSDL(sdl, prefix string) (string, error) {
+	if prefix == "" {
+		return sdl, nil
+	}
+	p := parser.New(lexer.New(sdl))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		return "", fmt.Errorf("failed to parse stitched schema: %v", p.Errors())
+	}
+
+	rootTypes := map[string]bool{"Query": true, "Mutation": true, "Subscription": true}
+	for _, def := range doc.Definitions {
+		if schema, ok := def.(*ast.SchemaDefinition); ok {
+			rootTypes = make(map[string]bool)
+			for _, op := range schema.OperationTypes {
+				rootTypes[op.Type.Name.String()] = true
+			}
+		}
+	}
+
+	definitions := make([]string, 0, len(doc.Definitions))
+	for _, def := range doc.Definitions {
+		var fields []*ast.FieldDefinition
+		switch d := def.(type) {
+		case *ast.ObjectTypeDefinition:
+			if rootTypes[d.Name.String()] {
+				fields = d.Fields
+			}
+		case *ast.ObjectTypeExtension:
+			if rootTypes[d.Name.String()] {
+				fields = d.Fields
+			}
+		}
+		for _, field := range fields {
+			name := field.Name.String()
+			field.Name = &ast.Name{Token: token.Token{Type: token.IDENT, Literal: prefix + name}, Value: prefix + name}
+			field.Directives = append(field.Directives, &ast.Directive{
+				Name: "stitched",
+				Arguments: []*ast.Argument{{
+					Name:  &ast.Name{Token: token.Token{Type: token.IDENT, Literal: "field"}, Value: "field"},
+					Value: &ast.StringValue{Token: token.Token{Type: token.STRING, Literal: name}, Value: name},
+				}},
+			})
+		}
+		definitions = append(definitions, def.String())
+	}
+	return strings.Join(definitions, "\n\n") + "\n", nil
+}
+
+// schemaIntrospectionQuery is the standard introspection query stitched services are
+// asked for their schema, as they do not implement _service.
+const schemaIntrospectionQuery = `query {
+	__schema {
+		queryType { name }
+		mutationType { name }
+		subscriptionType { name }
+		types {
+			kind
+			name
+			fields(includeDeprecated: true) {
+				name
+				args { name type { ...TypeRef } defaultValue }
+				type { ...TypeRef }
+				isDeprecated
+				deprecationReason
+			}
+			inputFields { name type { ...TypeRef } defaultValue }
+			interfaces { name }
+			enumValues(includeDeprecated: true) { name isDeprecated deprecationReason }
+			possibleTypes { name }
+		}
+	}
+}
+
+fragment TypeRef on __Type {
+	kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } }
+}`
+
+// introspectedSchema is the __schema of an introspection response.
+type introspectedSchema struct {
+	QueryType        *introspectedName  `json:"queryType"`
+	MutationType     *introspectedName  `json:"mutationType"`
+	SubscriptionType *introspectedName  `json:"subscriptionType"`
+	Types            []introspectedType `json:"types"`
+}
+
+type introspectedName struct {
+	Name string `json:"name"`
+}
+
+type introspectedType struct {
+	Kind          string              `json:"kind"`
+	Name          string              `json:"name"`
+	Fields        []introspectedField `json:"fields"`
+	InputFields   []introspectedInput `json:"inputFields"`
+	Interfaces    []introspectedName  `json:"interfaces"`
+	EnumValues    []introspectedEnum  `json:"enumValues"`
+	PossibleTypes []introspectedName  `json:"possibleTypes"`
+}
+
+type introspectedField struct {
+	Name              string              `json:"name"`
+	Args              []introspectedInput `json:"args"`
+	Type              introspectedTypeRef `json:"type"`
+	IsDeprecated      bool                `json:"isDeprecated"`
+	DeprecationReason *string             `json:"deprecationReason"`
+}
+
+type introspectedInput struct {
+	Name         string              `json:"name"`
+	Type         introspectedTypeRef `json:"type"`
+	DefaultValue *string             `json:"defaultValue"`
+}
+
+type introspectedEnum struct {
+	Name              string  `json:"name"`
+	IsDeprecated      bool    `json:"isDeprecated"`
+	DeprecationReason *string `json:"deprecationReason"`
+}
+
+type introspectedTypeRef struct {
+	Kind   string               `json:"kind"`
+	Name   string               `json:"name"`
+	OfType *introspectedTypeRef `json:"ofType"`
+}
+
+// String returns the type reference in SDL, e.g. [String!]!.
+func (t introspectedTypeRef) String() string {
+	switch {
+	case t.Kind == "NON_NULL" && t.OfType != nil:
+		return t.OfType.String() + "!"
+	case t.Kind == "LIST" && t.OfType != nil:
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+// schemaIntrospectionSource loads the SDL of a stitched service through the standard
+// introspection query.
+type schemaIntrospectionSource struct {
+	host       string
+	httpClient *http.Client
+}
+
+// Name implements registry.SchemaSource.
+func (s *schemaIntrospectionSource) Name() string {
+	return s.host
+}
+
+// Fetch implements registry.SchemaSource.
+func (s *schemaIntrospectionSource) Fetch(ctx context.Context) (string, error) {
+	body, err := json.Marshal(map[string]string{"query": schemaIntrospectionQuery})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode introspection query: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.host, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Transport: s.httpClient.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, s.host)
+	}
+
+	var introspection struct {
+		Data struct {
+			Schema *introspectedSchema `json:"__schema"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&introspection); err != nil {
+		return "", fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+	if introspection.Data.Schema == nil {
+		return "", fmt.Errorf("empty introspection returned from %s", s.host)
+	}
+	return introspectionSDL(introspection.Data.Schema), nil
+}
+
+// specifiedScalars are the scalars every schema has, left out of introspected SDL.
+var specifiedScalars = map[string]bool{"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+// introspectionSDL prints an introspected schema as SDL.
+func introspectionSDL(schema *introspectedSchema) string {
+	var sb strings.Builder
+
+	rootName := func(t *introspectedName, name string) string {
+		if t == nil {
+			return ""
+		}
+		if t.Name != name {
+			return t.Name
+		}
+		return ""
+	}
+	query := rootName(schema.QueryType, "Query")
+	mutation := rootName(schema.MutationType, "Mutation")
+	subscription := rootName(schema.SubscriptionType, "Subscription")
+	if query != "" || mutation != "" || subscription != "" {
+		sb.WriteString("schema {\n")
+		for _, op := range []struct {
+			operation string
+			t         *introspectedName
+		}{{"query", schema.QueryType}, {"mutation", schema.MutationType}, {"subscription", schema.SubscriptionType}} {
+			if op.t != nil {
+				fmt.Fprintf(&sb, "  %s: %s\n", op.operation, op.t.Name)
+			}
+		}
+		sb.WriteString("}\n\n")
+	}
+
+	for _, t := range schema.Types {
+		if strings.HasPrefix(t.Name, "__") || specifiedScalars[t.Name] {
+			continue
+		}
+		switch t.Kind {
+		case "SCALAR":
+			fmt.Fprintf(&sb, "scalar %s\n\n", t.Name)
+		case "OBJECT", "INTERFACE":
+			keyword := "type"
+			if t.Kind == "INTERFACE" {
+				keyword = "interface"
+			}
+			sb.WriteString(keyword + " " + t.Name)
+			if len(t.Interfaces) > 0 {
+				names := make([]string, len(t.Interfaces))
+				for i, iface := range t.Interfaces {
+					names[i] = iface.Name
+				}
+				sb.WriteString(" implements " + strings.Join(names, " & "))
+			}
+			sb.WriteString(" {\n")
+			for _, f := range t.Fields {
+				sb.WriteString("  " + f.Name)
+				if len(f.Args) > 0 {
+					args := make([]string, len(f.Args))
+					for i, arg := range f.Args {
+						args[i] = inputValueSDL(arg)
+					}
+					sb.WriteString("(" + strings.Join(args, ", ") + ")")
+				}
+				sb.WriteString(": " + f.Type.String() + deprecatedSDL(f.IsDeprecated, f.DeprecationReason) + "\n")
+			}
+			sb.WriteString("}\n\n")
+		case "UNION":
+			names := make([]string, len(t.PossibleTypes))
+			for i, member := range t.PossibleTypes {
+				names[i] = member.Name
+			}
+			fmt.Fprintf(&sb, "union %s = %s\n\n", t.Name, strings.Join(names, " | "))
+		case "ENUM":
+			sb.WriteString("enum " + t.Name + " {\n")
+			for _, v := range t.EnumValues {
+				sb.WriteString("  " + v.Name + deprecatedSDL(v.IsDeprecated, v.DeprecationReason) + "\n")
+			}
+			sb.WriteString("}\n\n")
+		case "INPUT_OBJECT":
+			sb.WriteString("input " + t.Name + " {\n")
+			for _, f := range t.InputFields {
+				sb.WriteString("  " + inputValueSDL(f) + "\n")
+			}
+			sb.WriteString("}\n\n")
+		}
+	}
+	return sb.String()
+}
+
+// inputValueSDL prints an argument or input field with its default value.
+func inputValueSDL(v introspectedInput) string {
+	s := v.Name + ": " + v.Type.String()
+	if v.DefaultValue != nil {
+		s += " = " + *v.DefaultValue
+	}
+	return s
+}
+
+// deprecatedSDL prints the @deprecated directive of a deprecated field or enum value.
+func deprecatedSDL(deprecated bool, reason *string) string {
+	if !deprecated {
+		return ""
+	}
+	if reason == nil {
+		return " @deprecated"
+	}
+	return " @deprecated(reason: " + strconv.Quote(*reason) + ")"
+}
@@ -0,0 +1,76 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+const stitchedIntrospection = `{"data":{"__schema":{
+	"queryType":{"name":"Query"},"mutationType":null,"subscriptionType":null,
+	"types":[
+		{"kind":"OBJECT","name":"Query","fields":[
+			{"name":"users","args":[{"name":"limit","type":{"kind":"SCALAR","name":"Int","ofType":null},"defaultValue":"10"}],
+			 "type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"LIST","name":null,"ofType":{"kind":"NON_NULL","name":null,"ofType":{"kind":"OBJECT","name":"LegacyUser","ofType":null}}}},
+			 "isDeprecated":false,"deprecationReason":null}
+		],"inputFields":null,"interfaces":[],"enumValues":null,"possibleTypes":null},
+		{"kind":"OBJECT","name":"LegacyUser","fields":[
+			{"name":"id","args":[],"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"ID","ofType":null}},"isDeprecated":false,"deprecationReason":null},
+			{"name":"login","args":[],"type":{"kind":"SCALAR","name":"String","ofType":null},"isDeprecated":true,"deprecationReason":"Use id."}
+		],"inputFields":null,"interfaces":[],"enumValues":null,"possibleTypes":null},
+		{"kind":"SCALAR","name":"String","fields":null,"inputFields":null,"interfaces":null,"enumValues":null,"possibleTypes":null},
+		{"kind":"OBJECT","name":"__Schema","fields":[],"inputFields":null,"interfaces":[],"enumValues":null,"possibleTypes":null}
+	]}}}`
+
+func TestGateway_StitchedService(t *testing.T) {
+	var queries []string
+	legacy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/json")
+		q, _ := body["query"].(string)
+		if strings.Contains(q, "__schema") {
+			w.Write([]byte(stitchedIntrospection)) //nolint:errcheck
+			return
+		}
+		queries = append(queries, q)
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{ //nolint:errcheck
+			"legacy_users": []any{map[string]any{"id": "1", "login": "alice"}},
+		}})
+	}))
+	t.Cleanup(legacy.Close)
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "legacy", Host: legacy.URL, Type: "stitched", Prefix: "legacy_"}},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ legacy_users(limit: 1) { id login } }"}`)))
+
+	want := `{"data":{"legacy_users":[{"id":"1","login":"alice"}]}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], "legacy_users: users(limit: 1)") {
+		t.Errorf("expected the root field to be sent under its remote name, got %q", queries)
+	}
+}
+
+func TestGateway_StitchedServiceInvalidType(t *testing.T) {
+	_, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "legacy", Host: "http://localhost:0", Type: "grpc"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `unknown type "grpc"`) {
+		t.Fatalf("expected an unknown type error, got %v", err)
+	}
+}
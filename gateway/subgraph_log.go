@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// SubgraphLogOption configures the logging of full subgraph exchanges. Only exchanges
+// crossing a threshold, or failing with OnError, are logged, so that production
+// incidents can be debugged without logging every subgraph request.
+type SubgraphLogOption struct {
+	Enable           bool     `yaml:"enable" default:"false"`
+	MinRequestBytes  int      `yaml:"min_request_bytes"`               // Log exchanges whose request body has at least this many bytes; 0 disables the threshold
+	MinResponseBytes int      `yaml:"min_response_bytes"`              // Log exchanges whose response body has at least this many bytes; 0 disables the threshold
+	MinLatency       string   `yaml:"min_latency" validate:"duration"` // Log exchanges taking at least this long; empty disables the threshold
+	OnError          bool     `yaml:"on_error" default:"false"`        // Log exchanges failing in transport, with an error status or with GraphQL errors
+	ScrubVariables   []string `yaml:"scrub_variables"`                 // Variable names and input fields whose values are replaced in logged requests, e.g. password
+	MaxBodyBytes     int      `yaml:"max_body_bytes" default:"65536"`  // Logged bodies are truncated to this many bytes
+}
+
+// Reasons an exchange is logged, listed in the reasons attribute of its record.
+const (
+	subgraphLogReasonRequestSize  = "request_size"
+	subgraphLogReasonResponseSize = "response_size"
+	subgraphLogReasonLatency      = "latency"
+	subgraphLogReasonError        = "error"
+)
+
+// subgraphLog decides which subgraph exchanges are logged.
+type subgraphLog struct {
+	minRequestBytes  int
+	minResponseBytes int
+	minLatency       time.Duration
+	onError          bool
+	scrub            map[string]bool // Lower-cased keys whose values are scrubbed
+	maxBodyBytes     int
+	sanitizer        *querySanitizer // Sanitizes the queries and variables of logged requests
+}
+
+// newSubgraphLog returns nil when subgraph exchanges are not logged.
+func newSubgraphLog(opt SubgraphLogOption, sanitizer *querySanitizer) (*subgraphLog, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+	l := &subgraphLog{
+		minRequestBytes:  opt.MinRequestBytes,
+		minResponseBytes: opt.MinResponseBytes,
+		onError:          opt.OnError,
+		scrub:            make(map[string]bool, len(opt.ScrubVariables)),
+		maxBodyBytes:     opt.MaxBodyBytes,
+		sanitizer:        sanitizer,
+	}
+	if opt.MinLatency != "" {
+		latency, err := time.ParseDuration(opt.MinLatency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subgraph_log.min_latency %q: %w", opt.MinLatency, err)
+		}
+		l.minLatency = latency
+	}
+	if l.minRequestBytes <= 0 && l.minResponseBytes <= 0 && l.minLatency <= 0 && !l.onError {
+		return nil, fmt.Errorf("subgraph_log requires a threshold or on_error")
+	}
+	if l.maxBodyBytes <= 0 {
+		l.maxBodyBytes = 65536
+	}
+	for _, name := range opt.ScrubVariables {
+		l.scrub[strings.ToLower(name)] = true
+	}
+	return l, nil
+}
+
+// transport wraps base so that the exchanges of subGraphName are logged; a nil log
+// returns base.
+func (l *subgraphLog) transport(subGraphName string, base http.RoundTripper) http.RoundTripper {
+	if l == nil {
+		return base
+	}
+	return &subgraphLogTransport{log: l, subGraphName: subGraphName, base: base}
+}
+
+// subgraphLogTransport logs the exchanges of one subgraph selected by its log.
+type subgraphLogTransport struct {
+	log          *subgraphLog
+	subGraphName string
+	base         http.RoundTripper
+}
+
+var _ http.RoundTripper = (*subgraphLogTransport)(nil)
+
+// RoundTrip implements http.RoundTripper. Bodies are buffered to measure and log them;
+// the latency includes reading the response body.
+func (t *subgraphLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		body = b
+		// A RoundTripper must not modify the request it is given.
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	var respBody []byte
+	if err == nil {
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			err = fmt.Errorf("failed to read response body: %w", err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		resp.ContentLength = int64(len(respBody))
+	}
+	latency := time.Since(start)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	if reasons := t.log.reasons(len(body), len(respBody), latency, status, respBody, err); len(reasons) > 0 {
+		attrs := []any{
+			"subgraph", t.subGraphName,
+			"url", req.URL.String(),
+			"reasons", reasons,
+			"status", status,
+			"latency_ms", latency.Milliseconds(),
+			"request_bytes", len(body),
+			"response_bytes", len(respBody),
+			"request", t.log.truncate(t.log.sanitizer.requestBody(t.log.scrubRequest(body))),
+			"response", t.log.truncate(respBody),
+		}
+		level := slog.LevelInfo
+		if err != nil {
+			attrs = append(attrs, "error", err.Error())
+		}
+		if reasons[len(reasons)-1] == subgraphLogReasonError {
+			level = slog.LevelWarn
+		}
+		slog.Log(req.Context(), level, "subgraph exchange", attrs...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// reasons returns why an exchange is logged, or nil when it is not.
+func (l *subgraphLog) reasons(requestBytes, responseBytes int, latency time.Duration, status int, respBody []byte, err error) []string {
+	var reasons []string
+	if l.minRequestBytes > 0 && requestBytes >= l.minRequestBytes {
+		reasons = append(reasons, subgraphLogReasonRequestSize)
+	}
+	if l.minResponseBytes > 0 && responseBytes >= l.minResponseBytes {
+		reasons = append(reasons, subgraphLogReasonResponseSize)
+	}
+	if l.minLatency > 0 && latency >= l.minLatency {
+		reasons = append(reasons, subgraphLogReasonLatency)
+	}
+	if l.onError && (err != nil || status >= http.StatusBadRequest || hasGraphQLErrors(respBody)) {
+		reasons = append(reasons, subgraphLogReasonError)
+	}
+	return reasons
+}
+
+// hasGraphQLErrors reports whether a response body has a non-empty errors list.
+func hasGraphQLErrors(body []byte) bool {
+	if !bytes.Contains(body, []byte(`"errors"`)) {
+		return false
+	}
+	var resp struct {
+		Errors []json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return false
+	}
+	return len(resp.Errors) > 0
+}
+
+// scrubRequest returns a request body with the values of scrubbed keys in its variables
+// replaced, at any depth. Batched requests are scrubbed per operation; bodies that are
+// not JSON are returned as they are.
+func (l *subgraphLog) scrubRequest(body []byte) []byte {
+	if len(l.scrub) == 0 || !json.Valid(body) {
+		return body
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	operations, batched := v.([]any)
+	if !batched {
+		operations = []any{v}
+	}
+	for _, op := range operations {
+		if m, ok := op.(map[string]any); ok && m["variables"] != nil {
+			m["variables"] = redactKeys(m["variables"], l.scrub)
+		}
+	}
+	scrubbed, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return scrubbed
+}
+
+// truncate returns body as a string of at most maxBodyBytes bytes.
+func (l *subgraphLog) truncate(body []byte) string {
+	if len(body) <= l.maxBodyBytes {
+		return string(body)
+	}
+	return string(body[:l.maxBodyBytes]) + "...(truncated)"
+}
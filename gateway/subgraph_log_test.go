@@ -0,0 +1,114 @@
+package gateway_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// captureSubgraphExchanges returns the "subgraph exchange" records logged after it is
+// called.
+func captureSubgraphExchanges(t *testing.T) func() []map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	return func() []map[string]any {
+		var records []map[string]any
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			var record map[string]any
+			if err := json.Unmarshal(line, &record); err != nil {
+				t.Fatalf("failed to decode log record %s: %v", line, err)
+			}
+			if record["msg"] == "subgraph exchange" {
+				records = append(records, record)
+			}
+		}
+		return records
+	}
+}
+
+func TestGateway_SubgraphLog(t *testing.T) {
+	t.Run("logs exchanges crossing a threshold with scrubbed variables", func(t *testing.T) {
+		gw := newSubgraphRequestsGateway(t, gateway.GatewayOption{
+			SubgraphLog: gateway.SubgraphLogOption{
+				Enable:          true,
+				MinRequestBytes: 200,
+				ScrubVariables:  []string{"ID"},
+			},
+		})
+		records := captureSubgraphExchanges(t)
+
+		postTopProducts(t, gw, nil)
+		logged := records()
+		if len(logged) != 1 {
+			t.Fatalf("expected only the entity request to cross the threshold, got %v", logged)
+		}
+		record := logged[0]
+		if record["subgraph"] != "inventory" || record["level"] != "INFO" {
+			t.Errorf("unexpected record %v", record)
+		}
+		if reasons, _ := record["reasons"].([]any); len(reasons) != 1 || reasons[0] != "request_size" {
+			t.Errorf("expected the request size reason, got %v", record["reasons"])
+		}
+		request, _ := record["request"].(string)
+		if !strings.Contains(request, `"id":"[REDACTED]"`) || strings.Contains(request, `"id":"1"`) {
+			t.Errorf("expected the representation id to be scrubbed, got %s", request)
+		}
+		if response, _ := record["response"].(string); !strings.Contains(response, `"inStock":true`) {
+			t.Errorf("expected the response body, got %s", response)
+		}
+	})
+
+	t.Run("logs failed exchanges", func(t *testing.T) {
+		products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+			return map[string]any{"data": nil, "errors": []any{map[string]any{"message": "database unavailable"}}}
+		})
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:    "/graphql",
+			Services:    []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			SubgraphLog: gateway.SubgraphLogOption{Enable: true, OnError: true, MaxBodyBytes: 16},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		records := captureSubgraphExchanges(t)
+
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql",
+			strings.NewReader(`{"query":"{ product(id: \"1\") { name } }"}`)))
+		logged := records()
+		if len(logged) != 1 {
+			t.Fatalf("expected the failed exchange to be logged, got %v", logged)
+		}
+		record := logged[0]
+		if record["level"] != "WARN" || record["subgraph"] != "products" {
+			t.Errorf("unexpected record %v", record)
+		}
+		if response, _ := record["response"].(string); !strings.HasSuffix(response, "...(truncated)") || len(response) != 16+len("...(truncated)") {
+			t.Errorf("expected the response to be truncated, got %q", response)
+		}
+	})
+
+	t.Run("requires a threshold", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:    "/graphql",
+			SubgraphLog: gateway.SubgraphLogOption{Enable: true},
+		})
+		if err == nil || !strings.Contains(err.Error(), "subgraph_log") {
+			t.Errorf("expected a subgraph_log error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// subgraphRequestCapture records the document and variables each step of a plan sends
+// to its subgraph, to return them in extensions.subgraphRequests or log them.
+type subgraphRequestCapture struct {
+	extension   bool   // Return the requests in extensions.subgraphRequests
+	debugHeader string // Only return them to requests carrying this header
+	log         bool   // Log the requests at debug level
+	sanitizer   *querySanitizer
+}
+
+// newSubgraphRequestCapture returns nil when the requests are neither returned nor
+// logged. Logged requests are sanitized by sanitizer; returned requests are not.
+func newSubgraphRequestCapture(opt ResponseExtensionsOption, log bool, sanitizer *querySanitizer) *subgraphRequestCapture {
+	if !opt.SubgraphRequests && !log {
+		return nil
+	}
+	return &subgraphRequestCapture{
+		extension:   opt.SubgraphRequests,
+		debugHeader: opt.DebugHeader,
+		log:         log,
+		sanitizer:   sanitizer,
+	}
+}
+
+type subgraphRequestsExtensionContextKey struct{}
+
+// withRequest marks ctx when the operations of r may receive extensions.subgraphRequests.
+func (c *subgraphRequestCapture) withRequest(ctx context.Context, r *http.Request) context.Context {
+	if c == nil || !c.extension || (c.debugHeader != "" && r.Header.Get(c.debugHeader) == "") {
+		return ctx
+	}
+	return context.WithValue(ctx, subgraphRequestsExtensionContextKey{}, true)
+}
+
+// start attaches a request log for one operation to ctx. It returns a nil log when the
+// requests of the operation are neither returned nor logged.
+func (c *subgraphRequestCapture) start(ctx context.Context) (context.Context, *executor.SubgraphRequestLog) {
+	if c == nil {
+		return ctx, nil
+	}
+	if returned, _ := ctx.Value(subgraphRequestsExtensionContextKey{}).(bool); !returned && !c.log {
+		return ctx, nil
+	}
+	log := executor.NewSubgraphRequestLog()
+	return executor.SetSubgraphRequestLogToContext(ctx, log), log
+}
+
+// finish logs the requests recorded in log and returns the ones to return in
+// extensions.subgraphRequests, if any.
+func (c *subgraphRequestCapture) finish(ctx context.Context, operationName string, log *executor.SubgraphRequestLog) []executor.SubgraphRequest {
+	if log == nil {
+		return nil
+	}
+	requests := log.Requests()
+	if c.log {
+		for _, req := range requests {
+			slog.DebugContext(ctx, "subgraph request",
+				"operation", operationName,
+				"step", req.Step,
+				"subgraph", req.SubGraph,
+				"query", c.sanitizer.query(req.Query),
+				"variables", c.sanitizer.variables(req.Variables),
+			)
+		}
+	}
+	if returned, _ := ctx.Value(subgraphRequestsExtensionContextKey{}).(bool); !returned {
+		return nil
+	}
+	return requests
+}
+
+// subgraphRequestsExtension returns the document and variables sent by each step.
+type subgraphRequestsExtension struct{}
+
+func (subgraphRequestsExtension) Name() string { return "subgraphRequests" }
+
+func (subgraphRequestsExtension) Extension(_ context.Context, info *ExecutionInfo) (any, bool) {
+	requests := info.SubgraphRequests()
+	return requests, len(requests) > 0
+}
@@ -0,0 +1,139 @@
+package gateway_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func newSubgraphRequestsGateway(t *testing.T, opt gateway.GatewayOption) http.Handler {
+	t.Helper()
+	const productsSDL = `
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+
+		type Query {
+			topProducts: [Product!]!
+		}
+	`
+	const inventorySDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			inStock: Boolean!
+		}
+	`
+
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"topProducts": []any{
+			map[string]any{"__typename": "Product", "id": "1", "name": "Table"},
+		}}}
+	})
+	inventory := newSubgraphServer(t, inventorySDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "Product", "id": "1", "inStock": true},
+		}}}
+	})
+
+	opt.Endpoint = "/graphql"
+	opt.Services = []gateway.GatewayService{
+		{Name: "products", Host: products.URL},
+		{Name: "inventory", Host: inventory.URL},
+	}
+	gw, err := gateway.NewGateway(opt)
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	return gw
+}
+
+func postTopProducts(t *testing.T, gw http.Handler, header http.Header) map[string]any {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query Top { topProducts { name inStock } }"}`))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestGateway_SubgraphRequestsExtension(t *testing.T) {
+	gw := newSubgraphRequestsGateway(t, gateway.GatewayOption{
+		ResponseExtensions: gateway.ResponseExtensionsOption{
+			SubgraphRequests: true,
+			DebugHeader:      "X-Debug-Subgraph-Requests",
+		},
+	})
+
+	resp := postTopProducts(t, gw, nil)
+	if ext, _ := resp["extensions"].(map[string]any); ext["subgraphRequests"] != nil {
+		t.Errorf("expected no subgraph requests without the debug header, got %v", ext)
+	}
+
+	resp = postTopProducts(t, gw, http.Header{"X-Debug-Subgraph-Requests": {"1"}})
+	ext, _ := resp["extensions"].(map[string]any)
+	requests, _ := ext["subgraphRequests"].([]any)
+	if len(requests) != 2 {
+		t.Fatalf("expected a request per step, got %v", resp)
+	}
+
+	root := requests[0].(map[string]any)
+	if root["step"] != float64(0) || root["subgraph"] != "products" || !strings.Contains(root["query"].(string), "topProducts") {
+		t.Errorf("unexpected root request %v", root)
+	}
+
+	entities := requests[1].(map[string]any)
+	if entities["subgraph"] != "inventory" || !strings.Contains(entities["query"].(string), "_entities") {
+		t.Errorf("unexpected entity request %v", entities)
+	}
+	vars, _ := entities["variables"].(map[string]any)
+	reps, _ := vars["representations"].([]any)
+	if len(reps) != 1 || reps[0].(map[string]any)["id"] != "1" {
+		t.Errorf("expected the representations in the entity request variables, got %v", vars)
+	}
+}
+
+func TestGateway_LogSubgraphRequests(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	gw := newSubgraphRequestsGateway(t, gateway.GatewayOption{LogSubgraphRequests: true})
+
+	resp := postTopProducts(t, gw, nil)
+	if ext, _ := resp["extensions"].(map[string]any); ext["subgraphRequests"] != nil {
+		t.Errorf("expected logged requests to stay out of the response, got %v", ext)
+	}
+
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("failed to decode log record %s: %v", line, err)
+		}
+		if record["msg"] == "subgraph request" {
+			records = append(records, record)
+		}
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a log record per step, got %s", buf.String())
+	}
+	if records[0]["operation"] != "Top" || records[1]["subgraph"] != "inventory" {
+		t.Errorf("unexpected log records %v", records)
+	}
+}
@@ -0,0 +1,538 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/planner"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/graphqlws"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/websocket"
+	"github.com/n9te9/graphql-parser/ast"
+	"github.com/n9te9/graphql-parser/lexer"
+	"github.com/n9te9/graphql-parser/parser"
+)
+
+// SubscriptionOption configures the WebSocket transport of the gateway, which serves
+// subscriptions (and queries and mutations) with the graphql-transport-ws protocol.
+type SubscriptionOption struct {
+	Enable                        bool   `yaml:"enable" default:"false"`
+	KeepAlive                     string `yaml:"keep_alive" default:"15s" validate:"duration"`   // Interval of the pings sent to clients; "0s" disables them
+	IdleTimeout                   string `yaml:"idle_timeout" validate:"duration"`               // Connections the client sent nothing on, pongs included, for this long are closed; defaults to three keep-alive intervals
+	InitTimeout                   string `yaml:"init_timeout" default:"10s" validate:"duration"` // How long clients have to send connection_init
+	MaxConnections                int    `yaml:"max_connections" default:"0"`                    // Open connections; 0 is unlimited
+	MaxSubscriptionsPerConnection int    `yaml:"max_subscriptions_per_connection" default:"0"`   // Active operations of one connection; 0 is unlimited
+	MaxSubscriptionsPerClient     int    `yaml:"max_subscriptions_per_client" default:"0"`       // Active operations of one client across its connections; 0 is unlimited
+	// AllowedOrigins are the origins of the pages allowed to open connections besides the
+	// gateway's own and those allowed by cors, e.g. https://app.example.com; "*" allows
+	// any. Browsers send cookies with cross-origin upgrades, so others are rejected.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// SubscriptionAuthenticator authorizes the connections of the WebSocket transport from
+// the payload of their connection_init message, e.g. a token browsers cannot send in
+// headers.
+type SubscriptionAuthenticator interface {
+	// Authenticate returns the context the operations of the connection run with and
+	// the ID of the client max_subscriptions_per_client counts the connection against;
+	// an empty ID counts it against its remote IP. An error closes the connection with
+	// 4403 Forbidden.
+	Authenticate(ctx context.Context, r *http.Request, payload map[string]any) (context.Context, string, error)
+}
+
+// SubscriptionAuthenticatorFunc adapts a function to SubscriptionAuthenticator.
+type SubscriptionAuthenticatorFunc func(ctx context.Context, r *http.Request, payload map[string]any) (context.Context, string, error)
+
+// Authenticate calls f.
+func (f SubscriptionAuthenticatorFunc) Authenticate(ctx context.Context, r *http.Request, payload map[string]any) (context.Context, string, error) {
+	return f(ctx, r, payload)
+}
+
+// subscriptionServer tracks the WebSocket connections of the gateway and enforces
+// their limits.
+type subscriptionServer struct {
+	keepAlive        time.Duration
+	idleTimeout      time.Duration
+	initTimeout      time.Duration
+	maxConnections   int
+	maxPerConnection int
+	maxPerClient     int
+	authenticator    SubscriptionAuthenticator
+	allowedOrigins   map[string]bool // Lower-cased origins, or "*"
+
+	mu      sync.Mutex
+	conns   map[*subscriptionConn]struct{}
+	clients map[string]int // Client ID → active operations
+}
+
+// newSubscriptionServer returns the server configured by opt, or nil when the
+// WebSocket transport is disabled.
+func newSubscriptionServer(opt SubscriptionOption, authenticator SubscriptionAuthenticator) (*subscriptionServer, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+
+	s := &subscriptionServer{
+		keepAlive:        15 * time.Second,
+		initTimeout:      10 * time.Second,
+		maxConnections:   opt.MaxConnections,
+		maxPerConnection: opt.MaxSubscriptionsPerConnection,
+		maxPerClient:     opt.MaxSubscriptionsPerClient,
+		authenticator:    authenticator,
+		allowedOrigins:   make(map[string]bool, len(opt.AllowedOrigins)),
+		conns:            make(map[*subscriptionConn]struct{}),
+		clients:          make(map[string]int),
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"keep_alive", opt.KeepAlive, &s.keepAlive},
+		{"idle_timeout", opt.IdleTimeout, &s.idleTimeout},
+		{"init_timeout", opt.InitTimeout, &s.initTimeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subscriptions.%s: %w", d.name, err)
+		}
+		*d.dst = v
+	}
+	if opt.IdleTimeout == "" {
+		s.idleTimeout = 3 * s.keepAlive
+	}
+	for _, origin := range opt.AllowedOrigins {
+		s.allowedOrigins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return s, nil
+}
+
+// allowsOrigin reports whether the upgrade request r may open a connection. Requests
+// without an Origin header, sent by clients other than browsers, and same-origin
+// requests may; cross-origin requests only from the allowed origins or those allowed
+// by cors, so that other sites cannot open connections carrying the user's cookies.
+func (s *subscriptionServer) allowsOrigin(r *http.Request, cors *corsPolicy) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if s.allowedOrigins["*"] || s.allowedOrigins[strings.ToLower(origin)] {
+		return true
+	}
+	return cors != nil && cors.isOriginAllowed(origin)
+}
+
+// open registers conn, or returns false when max_connections are open.
+func (s *subscriptionServer) open(conn *subscriptionConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxConnections > 0 && len(s.conns) >= s.maxConnections {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+// release unregisters conn.
+func (s *subscriptionServer) release(conn *subscriptionConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// acquire counts a new operation of conn against the limits, returning an error
+// message when one is reached.
+func (s *subscriptionServer) acquire(conn *subscriptionConn) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxPerConnection > 0 && len(conn.operations) >= s.maxPerConnection {
+		return fmt.Sprintf("connection exceeds the limit of %d active subscriptions", s.maxPerConnection)
+	}
+	if s.maxPerClient > 0 && s.clients[conn.clientID] >= s.maxPerClient {
+		return fmt.Sprintf("client exceeds the limit of %d active subscriptions", s.maxPerClient)
+	}
+	s.clients[conn.clientID]++
+	return ""
+}
+
+// done releases an operation counted by acquire.
+func (s *subscriptionServer) done(conn *subscriptionConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clients[conn.clientID]--; s.clients[conn.clientID] <= 0 {
+		delete(s.clients, conn.clientID)
+	}
+}
+
+// closeAll closes every connection with code and reason. Clients are expected to
+// reconnect, e.g. to subscribe against a reloaded schema.
+func (s *subscriptionServer) closeAll(code int, reason string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	conns := make([]*subscriptionConn, 0, len(s.conns))
+	for conn := range s.conns {
+		if conn.ws != nil {
+			conns = append(conns, conn)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.close(code, reason)
+	}
+}
+
+// subscriptionConn is a WebSocket connection of a client.
+type subscriptionConn struct {
+	clientID string
+
+	// ws and cancel, which cancels the operations of the connection, are set under the
+	// mutex of the subscriptionServer once the handshake succeeded.
+	ws     *websocket.Conn
+	cancel context.CancelFunc
+	// wg counts the running operations.
+	wg sync.WaitGroup
+
+	// operations maps operation ID → cancel of the running operations; guarded by the
+	// mutex of the subscriptionServer.
+	operations map[string]context.CancelFunc
+}
+
+// close cancels the operations of c and closes its WebSocket.
+func (c *subscriptionConn) close(code int, reason string) {
+	c.cancel()
+	c.ws.Close(code, reason) //nolint:errcheck
+}
+
+// serveSubscriptions upgrades r to a graphql-transport-ws connection and serves its
+// operations until either side closes it.
+func (g *gateway) serveSubscriptions(w http.ResponseWriter, r *http.Request) {
+	s := g.subscriptions
+	if !s.allowsOrigin(r, g.cors) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	conn := &subscriptionConn{operations: make(map[string]context.CancelFunc)}
+	if !s.open(conn) {
+		http.Error(w, "too many subscription connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.release(conn)
+
+	ws, err := websocket.Upgrade(w, r, []string{graphqlws.Subprotocol})
+	if err != nil {
+		return
+	}
+	defer ws.Close(websocket.CloseNormal, "") //nolint:errcheck
+	if ws.Subprotocol() != graphqlws.Subprotocol {
+		ws.Close(graphqlws.CloseSubprotocolNotAcceptable, "Subprotocol not acceptable") //nolint:errcheck
+		return
+	}
+
+	ctx, cancel := context.WithCancel(g.subscriptionContext(r))
+	s.mu.Lock()
+	conn.ws, conn.cancel = ws, cancel
+	s.mu.Unlock()
+	defer func() {
+		cancel()
+		conn.wg.Wait()
+	}()
+
+	ctx, ok := g.initSubscriptionConn(ctx, conn, r)
+	if !ok {
+		return
+	}
+
+	if s.keepAlive > 0 {
+		go conn.keepAlive(ctx, s.keepAlive)
+	}
+
+	for {
+		if s.idleTimeout > 0 {
+			ws.SetReadDeadline(time.Now().Add(s.idleTimeout)) //nolint:errcheck
+		}
+		msg, err := graphqlws.Read(ws)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				conn.close(websocket.CloseGoingAway, "idle timeout")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case graphqlws.Ping:
+			graphqlws.Write(ws, graphqlws.Message{Type: graphqlws.Pong}) //nolint:errcheck
+		case graphqlws.Pong:
+		case graphqlws.ConnectionInit:
+			conn.close(graphqlws.CloseTooManyInitRequests, "Too many initialisation requests")
+			return
+		case graphqlws.Subscribe:
+			var req graphQLRequest
+			if msg.ID == "" || graphqlws.DecodePayload(msg.Payload, &req) != nil {
+				conn.close(graphqlws.CloseBadRequest, "Invalid subscribe message")
+				return
+			}
+			if !g.startOperation(ctx, conn, msg.ID, req) {
+				return
+			}
+		case graphqlws.Complete:
+			s.mu.Lock()
+			if stop, ok := conn.operations[msg.ID]; ok {
+				stop()
+			}
+			s.mu.Unlock()
+		default:
+			conn.close(graphqlws.CloseBadRequest, fmt.Sprintf("Invalid message type %q", msg.Type))
+			return
+		}
+	}
+}
+
+// subscriptionContext returns the context of the operations of the connection of r,
+// with the request values the HTTP transport attaches to operations.
+func (g *gateway) subscriptionContext(r *http.Request) context.Context {
+	ctx := g.clientIdentity.withClient(r.Context(), r)
+	if g.enableHangOverRequestHeader {
+		ctx = executor.SetRequestHeaderToContext(ctx, r.Header)
+	}
+	if g.deprecatedUsage != nil {
+		ctx = g.deprecatedUsage.withClientName(ctx, r)
+	}
+	ctx = g.subgraphRequests.withRequest(ctx, r)
+	ctx = g.responseTransforms.withRequest(ctx, r)
+	ctx = g.fieldRateLimits.withClient(ctx, r)
+	// Request errors are sent as error messages, so they report a status as with
+	// graphQLResponseMediaType.
+	return context.WithValue(ctx, graphQLResponseContextKey{}, true)
+}
+
+// initSubscriptionConn waits for the connection_init message of conn, authenticates
+// its payload and acknowledges it. It returns false when the connection was closed.
+func (g *gateway) initSubscriptionConn(ctx context.Context, conn *subscriptionConn, r *http.Request) (context.Context, bool) {
+	s := g.subscriptions
+	conn.ws.SetReadDeadline(time.Now().Add(s.initTimeout)) //nolint:errcheck
+	msg, err := graphqlws.Read(conn.ws)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			conn.close(graphqlws.CloseInitTimeout, "Connection initialisation timeout")
+		}
+		return ctx, false
+	}
+	if msg.Type != graphqlws.ConnectionInit {
+		conn.close(graphqlws.CloseUnauthorized, "Unauthorized")
+		return ctx, false
+	}
+	conn.ws.SetReadDeadline(time.Time{}) //nolint:errcheck
+
+	var payload map[string]any
+	if msg.Payload != nil {
+		if err := graphqlws.DecodePayload(msg.Payload, &payload); err != nil {
+			conn.close(graphqlws.CloseBadRequest, "Invalid connection_init payload")
+			return ctx, false
+		}
+	}
+
+	var clientID string
+	if s.authenticator != nil {
+		ctx, clientID, err = s.authenticator.Authenticate(ctx, r, payload)
+		if err != nil {
+			conn.close(graphqlws.CloseForbidden, "Forbidden")
+			return ctx, false
+		}
+	}
+	if clientID == "" {
+		clientID, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+	conn.clientID = clientID
+
+	if err := graphqlws.Write(conn.ws, graphqlws.Message{Type: graphqlws.ConnectionAck}); err != nil {
+		return ctx, false
+	}
+	return ctx, true
+}
+
+// keepAlive pings the client of c every interval until ctx is done.
+func (c *subscriptionConn) keepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := graphqlws.Write(c.ws, graphqlws.Message{Type: graphqlws.Ping}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// startOperation runs the operation id of conn in its own goroutine. Operations over
+// a limit are answered with an error message. It returns false when the connection
+// was closed because id is already running.
+func (g *gateway) startOperation(ctx context.Context, conn *subscriptionConn, id string, req graphQLRequest) bool {
+	s := g.subscriptions
+	s.mu.Lock()
+	_, exists := conn.operations[id]
+	s.mu.Unlock()
+	if exists {
+		conn.close(graphqlws.CloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", id))
+		return false
+	}
+
+	if message := s.acquire(conn); message != "" {
+		_, resp := requestError(ctx, CodeTooManySubscriptions, message)
+		graphqlws.Write(conn.ws, graphqlws.Message{ID: id, Type: graphqlws.Error, Payload: resp["errors"]}) //nolint:errcheck
+		return true
+	}
+
+	opCtx, stop := context.WithCancel(ctx)
+	s.mu.Lock()
+	conn.operations[id] = stop
+	s.mu.Unlock()
+
+	conn.wg.Add(1)
+	go func() {
+		defer conn.wg.Done()
+		defer func() {
+			stop()
+			s.mu.Lock()
+			delete(conn.operations, id)
+			s.mu.Unlock()
+			s.done(conn)
+		}()
+		g.runOperation(opCtx, conn, id, req)
+	}()
+	return true
+}
+
+// runOperation executes req and sends its results as next messages followed by
+// complete, or its request errors as an error message. Nothing is sent once the
+// client completed the operation.
+func (g *gateway) runOperation(ctx context.Context, conn *subscriptionConn, id string, req graphQLRequest) {
+	send := func(msgType string, payload any) {
+		if ctx.Err() == nil {
+			graphqlws.Write(conn.ws, graphqlws.Message{ID: id, Type: msgType, Payload: payload}) //nolint:errcheck
+		}
+	}
+
+	engine := g.currentStore().engine
+	if !isSubscription(req) {
+		// Queries and mutations count as in flight for schema updates, like over HTTP.
+		g.inFlight.Add(1)
+		status, resp := g.executeRequest(ctx, engine, req)
+		g.inFlight.Done()
+		if status != http.StatusOK {
+			send(graphqlws.Error, responseErrors(resp))
+			return
+		}
+		send(graphqlws.Next, resp)
+		send(graphqlws.Complete, nil)
+		return
+	}
+
+	resp := g.subscribe(ctx, engine, req, func(event any) error {
+		send(graphqlws.Next, event)
+		return nil
+	})
+	if resp != nil {
+		send(graphqlws.Error, resp)
+		return
+	}
+	send(graphqlws.Complete, nil)
+}
+
+// isSubscription reports whether the operation req selects is a subscription.
+func isSubscription(req graphQLRequest) bool {
+	p := parser.New(lexer.New(req.Query))
+	doc := p.ParseDocument()
+	if len(p.Errors()) > 0 {
+		return false
+	}
+	_, op, err := selectOperation(doc, req.OperationName)
+	return err == nil && op.Operation == ast.Subscription
+}
+
+// responseErrors returns the errors of resp, a response of executeRequest.
+func responseErrors(resp any) any {
+	if ordered, ok := resp.(executor.OrderedResponse); ok {
+		return ordered.Response["errors"]
+	}
+	return nil
+}
+
+// subscribe validates and plans the subscription req and streams its events to send,
+// each encoded in canonical key order. It returns the errors of the subscription when
+// it failed, and nil once it completed or ctx is done.
+func (g *gateway) subscribe(ctx context.Context, engine *executionEngine, req graphQLRequest, send func(event any) error) any {
+	validated, _, errResp := g.validateOperation(ctx, engine, req)
+	if validated == nil {
+		return errResp["errors"]
+	}
+	doc, op, variables := validated.doc, validated.op, validated.variables
+
+	planDoc, _, limited, err := g.limitOperation(ctx, engine, doc, op, variables)
+	if err != nil {
+		_, resp := requestError(ctx, CodeListSizeExceeded, err.Error())
+		return resp["errors"]
+	}
+	if planDoc == nil {
+		resp := map[string]any{}
+		addLimitedFields(resp, limited)
+		return resp["errors"]
+	}
+
+	queryPlanner := engine.planner
+	if labels := engine.superGraph.OverrideLabels(); len(labels) > 0 {
+		queryPlanner = queryPlanner.WithOverrideLabels(g.evaluateOverrideLabels(ctx, labels))
+	}
+	var plan *planner.PlanV2
+	if queryPlanner == engine.planner && planDoc == doc {
+		plan = engine.warmedPlan(req.Query, operationNameOf(op))
+	}
+	if plan == nil {
+		plan, err = queryPlanner.Plan(planDoc, variables)
+		if err != nil {
+			_, resp := requestError(ctx, CodePlanError, err.Error())
+			return resp["errors"]
+		}
+	}
+
+	if g.forwardExtensions != nil {
+		ctx = g.forwardExtensions.apply(ctx, req.Extensions)
+	}
+	// Streams have no operation deadline: they last until either side completes them.
+	err = engine.executor.Subscribe(ctx, plan, variables, func(resp map[string]any) error {
+		g.completeResponse(ctx, resp, doc, op, engine, limited, false)
+		return send(executor.OrderedResponse{Response: resp, Document: doc})
+	})
+
+	var subscriptionErr *executor.SubscriptionError
+	switch {
+	case err == nil || ctx.Err() != nil:
+		return nil
+	case errors.As(err, &subscriptionErr):
+		return subscriptionErr.Errors
+	default:
+		_, resp := requestError(ctx, CodeInternalServerError, err.Error())
+		return resp["errors"]
+	}
+}
@@ -0,0 +1,350 @@
+package gateway_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/graphqlws"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/websocket"
+)
+
+const sdlProductEvents = `
+	type Product @key(fields: "id") {
+		id: ID!
+		name: String
+	}
+
+	type Query {
+		product(id: ID!): Product
+	}
+
+	type Subscription {
+		productAdded: Product
+	}
+`
+
+// newSubscriptionSubgraph serves sdl and answers every subscription with events,
+// keeping it open afterwards until the gateway completes it when hold is set.
+func newSubscriptionSubgraph(t *testing.T, sdl string, events []any, hold bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !websocket.IsUpgrade(r) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+			return
+		}
+		conn, err := websocket.Upgrade(w, r, []string{graphqlws.Subprotocol})
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.CloseNormal, "") //nolint:errcheck
+		if msg, err := graphqlws.Read(conn); err != nil || msg.Type != graphqlws.ConnectionInit {
+			return
+		}
+		graphqlws.Write(conn, graphqlws.Message{Type: graphqlws.ConnectionAck}) //nolint:errcheck
+		msg, err := graphqlws.Read(conn)
+		if err != nil || msg.Type != graphqlws.Subscribe {
+			return
+		}
+		for _, event := range events {
+			graphqlws.Write(conn, graphqlws.Message{ID: msg.ID, Type: graphqlws.Next, Payload: event}) //nolint:errcheck
+		}
+		if hold {
+			graphqlws.Read(conn) //nolint:errcheck
+			return
+		}
+		graphqlws.Write(conn, graphqlws.Message{ID: msg.ID, Type: graphqlws.Complete}) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dialGateway opens a graphql-transport-ws connection to srv and sends
+// connection_init with payload.
+func dialGateway(t *testing.T, srv *httptest.Server, payload map[string]any) *websocket.Conn {
+	t.Helper()
+	conn, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/graphql", []string{graphqlws.Subprotocol}, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close(websocket.CloseNormal, "") }) //nolint:errcheck
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))       //nolint:errcheck
+	if err := graphqlws.Write(conn, graphqlws.Message{Type: graphqlws.ConnectionInit, Payload: payload}); err != nil {
+		t.Fatalf("connection_init failed: %v", err)
+	}
+	return conn
+}
+
+// readMessage reads the next message of conn that is not a keep-alive ping.
+func readMessage(t *testing.T, conn *websocket.Conn) graphqlws.Message {
+	t.Helper()
+	for {
+		msg, err := graphqlws.Read(conn)
+		if err != nil {
+			t.Fatalf("expected a message, got %v", err)
+		}
+		if msg.Type != graphqlws.Ping {
+			return msg
+		}
+	}
+}
+
+// expectClose reads conn until the gateway closes it and checks the close code.
+func expectClose(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+	for {
+		_, err := graphqlws.Read(conn)
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("expected close code %d, got %v", code, err)
+		}
+		if closeErr.Code != code {
+			t.Fatalf("expected close code %d, got %d %s", code, closeErr.Code, closeErr.Reason)
+		}
+		return
+	}
+}
+
+func newSubscriptionGateway(t *testing.T, subgraph *httptest.Server, opt gateway.SubscriptionOption, auth gateway.SubscriptionAuthenticator) *httptest.Server {
+	t.Helper()
+	opt.Enable = true
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:                  "/graphql",
+		Services:                  []gateway.GatewayService{{Name: "products", Host: subgraph.URL}},
+		Subscriptions:             opt,
+		SubscriptionAuthenticator: auth,
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	srv := httptest.NewServer(gw)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGateway_Subscriptions(t *testing.T) {
+	events := []any{
+		map[string]any{"data": map[string]any{"productAdded": map[string]any{"__typename": "Product", "id": "1", "name": "Table"}}},
+		map[string]any{"data": map[string]any{"productAdded": map[string]any{"__typename": "Product", "id": "2", "name": "Chair"}}},
+	}
+
+	t.Run("events", func(t *testing.T) {
+		srv := newSubscriptionGateway(t, newSubscriptionSubgraph(t, sdlProductEvents, events, false), gateway.SubscriptionOption{}, nil)
+		conn := dialGateway(t, srv, nil)
+		if msg := readMessage(t, conn); msg.Type != graphqlws.ConnectionAck {
+			t.Fatalf("expected connection_ack, got %s", msg.Type)
+		}
+
+		graphqlws.Write(conn, graphqlws.Message{ID: "a", Type: graphqlws.Subscribe, Payload: map[string]any{ //nolint:errcheck
+			"query": "subscription { productAdded { name } }",
+		}})
+		for _, want := range []string{`{"data":{"productAdded":{"name":"Table"}}}`, `{"data":{"productAdded":{"name":"Chair"}}}`} {
+			msg := readMessage(t, conn)
+			got, _ := json.Marshal(msg.Payload)
+			if msg.Type != graphqlws.Next || msg.ID != "a" || string(got) != want {
+				t.Fatalf("expected next %s, got %s %s", want, msg.Type, got)
+			}
+		}
+		if msg := readMessage(t, conn); msg.Type != graphqlws.Complete || msg.ID != "a" {
+			t.Fatalf("expected complete, got %s", msg.Type)
+		}
+	})
+
+	t.Run("init payload is authenticated", func(t *testing.T) {
+		auth := gateway.SubscriptionAuthenticatorFunc(func(ctx context.Context, r *http.Request, payload map[string]any) (context.Context, string, error) {
+			if payload["token"] != "secret" {
+				return nil, "", errors.New("invalid token")
+			}
+			return ctx, "client-1", nil
+		})
+		srv := newSubscriptionGateway(t, newSubscriptionSubgraph(t, sdlProductEvents, events, false), gateway.SubscriptionOption{}, auth)
+
+		if msg := readMessage(t, dialGateway(t, srv, map[string]any{"token": "secret"})); msg.Type != graphqlws.ConnectionAck {
+			t.Fatalf("expected connection_ack, got %s", msg.Type)
+		}
+		expectClose(t, dialGateway(t, srv, map[string]any{"token": "wrong"}), graphqlws.CloseForbidden)
+	})
+
+	t.Run("subscriptions per connection are limited", func(t *testing.T) {
+		srv := newSubscriptionGateway(t, newSubscriptionSubgraph(t, sdlProductEvents, events[:1], true), gateway.SubscriptionOption{MaxSubscriptionsPerConnection: 1}, nil)
+		conn := dialGateway(t, srv, nil)
+		readMessage(t, conn)
+
+		subscribe := map[string]any{"query": "subscription { productAdded { name } }"}
+		graphqlws.Write(conn, graphqlws.Message{ID: "a", Type: graphqlws.Subscribe, Payload: subscribe}) //nolint:errcheck
+		if msg := readMessage(t, conn); msg.Type != graphqlws.Next {
+			t.Fatalf("expected next, got %s", msg.Type)
+		}
+		graphqlws.Write(conn, graphqlws.Message{ID: "b", Type: graphqlws.Subscribe, Payload: subscribe}) //nolint:errcheck
+		msg := readMessage(t, conn)
+		got, _ := json.Marshal(msg.Payload)
+		if msg.Type != graphqlws.Error || msg.ID != "b" || !strings.Contains(string(got), gateway.CodeTooManySubscriptions) {
+			t.Fatalf("expected a %s error, got %s %s", gateway.CodeTooManySubscriptions, msg.Type, got)
+		}
+	})
+
+	t.Run("idle connections are closed", func(t *testing.T) {
+		srv := newSubscriptionGateway(t, newSubscriptionSubgraph(t, sdlProductEvents, events, false), gateway.SubscriptionOption{KeepAlive: "20ms", IdleTimeout: "100ms"}, nil)
+		conn := dialGateway(t, srv, nil)
+		readMessage(t, conn)
+
+		// The client never answers the keep-alive pings.
+		msg, err := graphqlws.Read(conn)
+		if err != nil || msg.Type != graphqlws.Ping {
+			t.Fatalf("expected a keep-alive ping, got %v %v", msg.Type, err)
+		}
+		expectClose(t, conn, websocket.CloseGoingAway)
+	})
+
+	t.Run("connections are closed on schema reload", func(t *testing.T) {
+		srv := newSubscriptionGateway(t, newSubscriptionSubgraph(t, sdlProductEvents, events[:1], true), gateway.SubscriptionOption{}, nil)
+		conn := dialGateway(t, srv, nil)
+		readMessage(t, conn)
+		graphqlws.Write(conn, graphqlws.Message{ID: "a", Type: graphqlws.Subscribe, Payload: map[string]any{ //nolint:errcheck
+			"query": "subscription { productAdded { name } }",
+		}})
+		readMessage(t, conn)
+
+		resp, err := http.Post(srv.URL+"/products/apply", "application/json", nil)
+		if err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 from apply, got %d", resp.StatusCode)
+		}
+		expectClose(t, conn, websocket.CloseServiceRestart)
+	})
+}
+
+func TestGateway_SubscriptionOrigins(t *testing.T) {
+	srv := newSubscriptionGateway(t, newSubscriptionSubgraph(t, sdlProductEvents, nil, false), gateway.SubscriptionOption{
+		AllowedOrigins: []string{"https://app.example.com"},
+	}, nil)
+	dial := func(origin string) error {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/graphql", []string{graphqlws.Subprotocol}, header)
+		if err == nil {
+			conn.Close(websocket.CloseNormal, "") //nolint:errcheck
+		}
+		return err
+	}
+
+	for _, origin := range []string{"", srv.URL, "https://app.example.com", "HTTPS://APP.EXAMPLE.COM"} {
+		if err := dial(origin); err != nil {
+			t.Errorf("expected the upgrade from origin %q to be accepted, got %v", origin, err)
+		}
+	}
+	for _, origin := range []string{"https://evil.example.com", "null"} {
+		if err := dial(origin); err == nil {
+			t.Errorf("expected the cross-origin upgrade from %q to be rejected", origin)
+		}
+	}
+}
+
+func TestGateway_SubscriptionValidation(t *testing.T) {
+	subgraph := newSubscriptionSubgraph(t, sdlProductEvents, nil, false)
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:             "/graphql",
+		Services:             []gateway.GatewayService{{Name: "products", Host: subgraph.URL}},
+		Subscriptions:        gateway.SubscriptionOption{Enable: true},
+		DisableIntrospection: true,
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	srv := httptest.NewServer(gw)
+	t.Cleanup(srv.Close)
+
+	conn := dialGateway(t, srv, nil)
+	readMessage(t, conn)
+	// Subscriptions are validated like operations sent over HTTP
+	graphqlws.Write(conn, graphqlws.Message{ID: "a", Type: graphqlws.Subscribe, Payload: map[string]any{ //nolint:errcheck
+		"query": `subscription { productAdded { name } __type(name: "Product") { name } }`,
+	}})
+	msg := readMessage(t, conn)
+	got, _ := json.Marshal(msg.Payload)
+	if msg.Type != graphqlws.Error || !strings.Contains(string(got), "introspection is not allowed") {
+		t.Fatalf("expected the introspection subscription to be rejected, got %s %s", msg.Type, got)
+	}
+}
+
+func TestGateway_SubscriptionEntityEnrichment(t *testing.T) {
+	const reviewsSDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			rating: Int
+		}
+	`
+	var events []any
+	for _, id := range []string{"1", "2", "3"} {
+		events = append(events, map[string]any{"data": map[string]any{"productAdded": map[string]any{"__typename": "Product", "id": id, "name": "product-" + id}}})
+	}
+	products := newSubscriptionSubgraph(t, sdlProductEvents, events, false)
+
+	var entityRequests atomic.Int32
+	reviews := newSubgraphServer(t, reviewsSDL, func(body map[string]any) any {
+		// Events received while the first one is enriched are enriched together.
+		if entityRequests.Add(1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		vars, _ := body["variables"].(map[string]any)
+		reps, _ := vars["representations"].([]any)
+		entities := make([]any, 0, len(reps))
+		for _, rep := range reps {
+			id := rep.(map[string]any)["id"].(string)
+			entities = append(entities, map[string]any{"__typename": "Product", "rating": len(id) * 10})
+		}
+		return map[string]any{"data": map[string]any{"_entities": entities}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "reviews", Host: reviews.URL},
+		},
+		Subscriptions: gateway.SubscriptionOption{Enable: true},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	srv := httptest.NewServer(gw)
+	t.Cleanup(srv.Close)
+
+	conn := dialGateway(t, srv, nil)
+	readMessage(t, conn)
+	graphqlws.Write(conn, graphqlws.Message{ID: "a", Type: graphqlws.Subscribe, Payload: map[string]any{ //nolint:errcheck
+		"query": "subscription { productAdded { name rating } }",
+	}})
+
+	for _, id := range []string{"1", "2", "3"} {
+		msg := readMessage(t, conn)
+		got, _ := json.Marshal(msg.Payload)
+		want := `{"data":{"productAdded":{"name":"product-` + id + `","rating":10}}}`
+		if msg.Type != graphqlws.Next || string(got) != want {
+			t.Fatalf("expected next %s, got %s %s", want, msg.Type, got)
+		}
+	}
+	if msg := readMessage(t, conn); msg.Type != graphqlws.Complete {
+		t.Fatalf("expected complete, got %s", msg.Type)
+	}
+	if n := entityRequests.Load(); n >= 3 {
+		t.Errorf("expected the events to be enriched in fewer than 3 _entities requests, got %d", n)
+	}
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+// SupergraphBundle holds the subgraph SDLs of a composed supergraph. Written ahead of
+// time (e.g. into a serverless deployment package) and set as
+// GatewayOption.SupergraphFile, it lets the gateway start without fetching any SDL.
+type SupergraphBundle struct {
+	Subgraphs  map[string]string `json:"subgraphs"`  // SDL by service name
+	SchemaHash string            `json:"schemaHash"` // registry.SchemaHash of Subgraphs
+}
+
+// ComposeSupergraph fetches the SDL of every service in settings, checks that they
+// compose, and returns them as a bundle.
+func ComposeSupergraph(settings GatewayOption) (*SupergraphBundle, error) {
+	settings, err := settings.withEmbeddedSubgraphs()
+	if err != nil {
+		return nil, err
+	}
+	subGraphClients, err := newSubGraphClients(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	sdls := make(map[string]string, len(settings.Services))
+	hosts := make(map[string]string, len(settings.Services))
+	for _, svc := range settings.Services {
+		src, err := settings.schemaSource(svc, subGraphClients[svc.Name])
+		if err != nil {
+			return nil, err
+		}
+		sdl, err := loadSDL(svc, src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch SDL for service %q: %w", svc.Name, err)
+		}
+		sdls[svc.Name] = sdl
+		hosts[svc.Name] = svc.Host
+	}
+
+	if _, err := buildEngine(sdls, hosts, nil); err != nil {
+		return nil, err
+	}
+	return &SupergraphBundle{Subgraphs: sdls, SchemaHash: registry.SchemaHash(sdls)}, nil
+}
+
+// LoadSupergraphBundle reads a bundle written by WriteFile.
+func LoadSupergraphBundle(path string) (*SupergraphBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read supergraph file: %w", err)
+	}
+	var bundle SupergraphBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode supergraph file %s: %w", path, err)
+	}
+	if hash := registry.SchemaHash(bundle.Subgraphs); bundle.SchemaHash != "" && bundle.SchemaHash != hash {
+		return nil, fmt.Errorf("supergraph file %s is corrupted: schema hash %s does not match its subgraphs (%s)", path, bundle.SchemaHash, hash)
+	}
+	return &bundle, nil
+}
+
+// WriteFile writes the bundle to path as JSON.
+func (b *SupergraphBundle) WriteFile(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode supergraph: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write supergraph file: %w", err)
+	}
+	return nil
+}
+
+// sdl returns the SDL of the named service, if b holds it.
+func (b *SupergraphBundle) sdl(name string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	sdl, ok := b.Subgraphs[name]
+	return sdl, ok
+}
@@ -0,0 +1,103 @@
+package gateway_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestSupergraphBundle(t *testing.T) {
+	var sdlFetches atomic.Int32
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "Table"}}}
+	})
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "_service") {
+			sdlFetches.Add(1)
+		}
+		req, _ := http.NewRequestWithContext(r.Context(), r.Method, products.URL, bytes.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(w, resp.Body) //nolint:errcheck
+	}))
+	t.Cleanup(counting.Close)
+
+	settings := gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+	}
+	bundle, err := gateway.ComposeSupergraph(settings)
+	if err != nil {
+		t.Fatalf("ComposeSupergraph failed: %v", err)
+	}
+	if bundle.Subgraphs["products"] != sdlProducts {
+		t.Errorf("expected the products SDL in the bundle, got %q", bundle.Subgraphs["products"])
+	}
+
+	path := filepath.Join(t.TempDir(), "supergraph.json")
+	if err := bundle.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	t.Run("gateway starts without fetching SDL", func(t *testing.T) {
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:       "/graphql",
+			SupergraphFile: path,
+			Services:       []gateway.GatewayService{{Name: "products", Host: counting.URL}},
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { name } }"}`)))
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		product, _ := resp["data"].(map[string]any)["product"].(map[string]any)
+		if product["name"] != "Table" {
+			t.Errorf("unexpected response: %s", rec.Body.String())
+		}
+		if n := sdlFetches.Load(); n != 0 {
+			t.Errorf("expected no SDL fetch, got %d", n)
+		}
+	})
+
+	t.Run("corrupted bundle", func(t *testing.T) {
+		corrupted := *bundle
+		corrupted.Subgraphs = map[string]string{"products": sdlProducts + "\nscalar Extra"}
+		bad := filepath.Join(t.TempDir(), "supergraph.json")
+		if err := corrupted.WriteFile(bad); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if _, err := gateway.LoadSupergraphBundle(bad); err == nil {
+			t.Error("expected a schema hash mismatch error")
+		}
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "supergraph.json")
+		if err := os.WriteFile(bad, []byte("{"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gateway.NewGateway(gateway.GatewayOption{Endpoint: "/graphql", SupergraphFile: bad}); err == nil {
+			t.Error("expected NewGateway to fail")
+		}
+	})
+}
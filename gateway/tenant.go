@@ -0,0 +1,214 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+// tenantAttribute labels the metrics and spans of a tenant with its name.
+const tenantAttribute = "gateway.tenant"
+
+// TenantOption configures one supergraph served by a multi-tenant gateway. Requests are
+// routed to the first tenant whose Hosts and PathPrefix match; the prefix is stripped
+// before the tenant handles the request, so its admin endpoints live under it too.
+type TenantOption struct {
+	Name       string   `yaml:"name"`
+	Hosts      []string `yaml:"hosts"`       // Host header values routed to the tenant, any when empty
+	PathPrefix string   `yaml:"path_prefix"` // Path prefix routed to the tenant, any when empty
+
+	// GatewayOption is the tenant's own configuration: nothing, including subgraphs,
+	// schemas and caches, is shared with the other tenants.
+	GatewayOption `yaml:",inline"`
+}
+
+// tenant is a gateway serving the requests routed to one TenantOption.
+type tenant struct {
+	name       string
+	hosts      []string
+	pathPrefix string
+	gateway    *gateway
+}
+
+// matches reports whether r is routed to t.
+func (t *tenant) matches(r *http.Request) bool {
+	if len(t.hosts) > 0 && !slices.Contains(t.hosts, requestHostname(r)) {
+		return false
+	}
+	if t.pathPrefix == "" {
+		return true
+	}
+	return r.URL.Path == t.pathPrefix || strings.HasPrefix(r.URL.Path, t.pathPrefix+"/")
+}
+
+// requestHostname returns the lower-cased host of r without its port.
+func requestHostname(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// tenantGateway routes requests to the gateways of its tenants.
+type tenantGateway struct {
+	tenants []*tenant
+}
+
+// NewTenantGateway builds one gateway per tenant in settings.Tenants. Each tenant's
+// metrics carry its name in the gateway.tenant attribute.
+func NewTenantGateway(settings GatewayOption) (*tenantGateway, error) {
+	if len(settings.Tenants) == 0 {
+		return nil, errors.New("no tenants configured")
+	}
+
+	// Validate every tenant before building any gateway so nothing has to be closed.
+	names := make(map[string]bool, len(settings.Tenants))
+	registries := make(map[string]string, len(settings.Tenants))
+	for _, opt := range settings.Tenants {
+		if opt.Name == "" {
+			return nil, errors.New("tenant requires a name")
+		}
+		if names[opt.Name] {
+			return nil, fmt.Errorf("duplicate tenant %q", opt.Name)
+		}
+		names[opt.Name] = true
+		if len(opt.Hosts) == 0 && opt.PathPrefix == "" {
+			return nil, fmt.Errorf("tenant %q requires hosts or a path_prefix", opt.Name)
+		}
+		if len(opt.Tenants) > 0 {
+			return nil, fmt.Errorf("tenant %q cannot have tenants", opt.Name)
+		}
+
+		// A shared Redis registry would install one tenant's schemas in the others.
+		if key, ok := redisRegistryKey(opt.Registry); ok {
+			if other, ok := registries[key]; ok {
+				return nil, fmt.Errorf("tenants %q and %q share a schema registry; set a distinct registry prefix", other, opt.Name)
+			}
+			registries[key] = opt.Name
+		}
+	}
+
+	tg := &tenantGateway{}
+	for _, opt := range settings.Tenants {
+		t, err := newTenant(opt)
+		if err != nil {
+			tg.Close() //nolint:errcheck
+			return nil, fmt.Errorf("failed to build tenant %q: %w", opt.Name, err)
+		}
+		tg.tenants = append(tg.tenants, t)
+	}
+	return tg, nil
+}
+
+// redisRegistryKey identifies the Redis keys used by the registry of opt, if it uses Redis.
+func redisRegistryKey(opt registry.StoreOption) (string, bool) {
+	if opt.Backend != "redis" {
+		return "", false
+	}
+	prefix := opt.Prefix
+	if prefix == "" {
+		prefix = "gateway:schema"
+	}
+	return fmt.Sprintf("%s/%d/%s", opt.Addr, opt.DB, prefix), true
+}
+
+// newTenant builds the gateway of opt.
+func newTenant(opt TenantOption) (*tenant, error) {
+	hosts := make([]string, 0, len(opt.Hosts))
+	for _, host := range opt.Hosts {
+		hosts = append(hosts, strings.ToLower(host))
+	}
+	prefix := strings.TrimSuffix(opt.PathPrefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+
+	settings := opt.GatewayOption
+	settings.MetricLabels = maps.Clone(settings.MetricLabels)
+	if settings.MetricLabels == nil {
+		settings.MetricLabels = make(map[string]string, 1)
+	}
+	settings.MetricLabels[tenantAttribute] = opt.Name
+
+	gw, err := NewGateway(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &tenant{name: opt.Name, hosts: hosts, pathPrefix: prefix, gateway: gw}, nil
+}
+
+// ServeHTTP strips the path prefix of the tenant r is routed to and lets its gateway
+// handle it. Requests matching no tenant get a 404.
+func (tg *tenantGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, t := range tg.tenants {
+		if !t.matches(r) {
+			continue
+		}
+
+		tenantName := attribute.String(tenantAttribute, t.name)
+		trace.SpanFromContext(r.Context()).SetAttributes(tenantName)
+		if labeler, ok := otelhttp.LabelerFromContext(r.Context()); ok {
+			labeler.Add(tenantName)
+		}
+
+		if t.pathPrefix != "" {
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = strings.TrimPrefix(r.URL.Path, t.pathPrefix)
+			r2.URL.RawPath = ""
+			if r2.URL.Path == "" {
+				r2.URL.Path = "/"
+			}
+			r = r2
+		}
+		t.gateway.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+		"errors": []map[string]any{
+			{
+				"message":    "no tenant serves this host and path",
+				"extensions": map[string]string{"code": "TENANT_NOT_FOUND"},
+			},
+		},
+	})
+}
+
+// Close closes the gateway of every tenant.
+func (tg *tenantGateway) Close() error {
+	var errs []error
+	for _, t := range tg.tenants {
+		if err := t.gateway.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %q: %w", t.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// metricLabels returns labels as the attributes added to every metric of a gateway.
+func metricLabels(labels map[string]string) metric.MeasurementOption {
+	attrs := make([]attribute.KeyValue, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		attrs = append(attrs, attribute.String(key, labels[key]))
+	}
+	return metric.WithAttributes(attrs...)
+}
@@ -0,0 +1,140 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+func TestTenantGateway(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "Table"}}}
+	})
+	reviews := newSubgraphServer(t, sdlReviews, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"reviews": []any{map[string]any{"id": "r1", "body": "Great"}}}}
+	})
+
+	gw, err := gateway.NewTenantGateway(gateway.GatewayOption{
+		Tenants: []gateway.TenantOption{
+			{
+				Name:       "shop",
+				PathPrefix: "/shop",
+				GatewayOption: gateway.GatewayOption{
+					Endpoint: "/graphql",
+					Services: []gateway.GatewayService{{Name: "products", Host: products.URL}},
+				},
+			},
+			{
+				Name:  "community",
+				Hosts: []string{"Community.Example.com"},
+				GatewayOption: gateway.GatewayOption{
+					Endpoint: "/graphql",
+					Services: []gateway.GatewayService{{Name: "reviews", Host: reviews.URL}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTenantGateway failed: %v", err)
+	}
+	defer gw.Close()
+
+	post := func(host, path, query string) (int, map[string]any) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"query":"`+query+`"}`))
+		req.Host = host
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		var resp map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+		}
+		return rec.Code, resp
+	}
+
+	t.Run("path prefix", func(t *testing.T) {
+		_, resp := post("gateway.example.com", "/shop/graphql", `{ product(id: \"1\") { name } }`)
+		product, _ := resp["data"].(map[string]any)["product"].(map[string]any)
+		if product["name"] != "Table" {
+			t.Errorf("expected the shop tenant to resolve product, got %v", resp)
+		}
+	})
+
+	t.Run("host", func(t *testing.T) {
+		_, resp := post("community.example.com:8080", "/graphql", `{ reviews { body } }`)
+		data, _ := resp["data"].(map[string]any)
+		if reviews, _ := data["reviews"].([]any); len(reviews) != 1 {
+			t.Errorf("expected the community tenant to resolve reviews, got %v", resp)
+		}
+	})
+
+	t.Run("schemas are not shared", func(t *testing.T) {
+		_, resp := post("gateway.example.com", "/shop/graphql", `{ reviews { body } }`)
+		if errs, _ := resp["errors"].([]any); len(errs) == 0 {
+			t.Errorf("expected the shop tenant to reject reviews, got %v", resp)
+		}
+	})
+
+	t.Run("admin endpoints are per tenant", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shop/admin/schema/versions", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected the shop schema versions, got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("no tenant", func(t *testing.T) {
+		code, resp := post("gateway.example.com", "/shopping/graphql", `{ reviews { body } }`)
+		if code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d %v", code, resp)
+		}
+	})
+}
+
+func TestTenantGateway_Config(t *testing.T) {
+	var settings gateway.GatewayOption
+	err := yaml.Unmarshal([]byte(`
+tenants:
+  - name: brand-a
+    path_prefix: /brand-a
+    endpoint: /graphql
+    metric_labels:
+      environment: production
+    services:
+      - name: products
+        host: http://products:4001/query
+`), &settings)
+	if err != nil {
+		t.Fatalf("failed to unmarshal settings: %v", err)
+	}
+	if len(settings.Tenants) != 1 {
+		t.Fatalf("expected one tenant, got %+v", settings.Tenants)
+	}
+	tenant := settings.Tenants[0]
+	if tenant.Name != "brand-a" || tenant.PathPrefix != "/brand-a" || tenant.Endpoint != "/graphql" ||
+		len(tenant.Services) != 1 || tenant.MetricLabels["environment"] != "production" {
+		t.Errorf("unexpected tenant %+v", tenant)
+	}
+
+	for name, tenants := range map[string][]gateway.TenantOption{
+		"none":      nil,
+		"unnamed":   {{PathPrefix: "/a"}},
+		"unrouted":  {{Name: "a"}},
+		"duplicate": {{Name: "a", PathPrefix: "/a"}, {Name: "a", PathPrefix: "/b"}},
+		"shared registry": {
+			{Name: "a", PathPrefix: "/a", GatewayOption: gateway.GatewayOption{Registry: registry.StoreOption{Backend: "redis", Addr: "redis:6379"}}},
+			{Name: "b", PathPrefix: "/b", GatewayOption: gateway.GatewayOption{Registry: registry.StoreOption{Backend: "redis", Addr: "redis:6379"}}},
+		},
+	} {
+		if _, err := gateway.NewTenantGateway(gateway.GatewayOption{Tenants: tenants}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
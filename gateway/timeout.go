@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Phases of an operation bounded by their own deadline.
+const (
+	timeoutPhasePlanning  = "planning"
+	timeoutPhaseExecution = "execution"
+)
+
+// timeoutCounter counts operations exceeding their deadline, in the
+// graphql.request.planning_timeout and graphql.request.execution_timeout metrics.
+type timeoutCounter struct {
+	planning  metric.Int64Counter
+	execution metric.Int64Counter
+	labels    metric.MeasurementOption
+}
+
+func newTimeoutCounter(labels metric.MeasurementOption) (*timeoutCounter, error) {
+	meter := otel.Meter("github.com/n9te9/go-graphql-federation-gateway")
+	planning, err := meter.Int64Counter(
+		"graphql.request.planning_timeout",
+		metric.WithDescription("Number of operations whose query planning exceeded the planning timeout"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create planning timeout counter: %w", err)
+	}
+	execution, err := meter.Int64Counter(
+		"graphql.request.execution_timeout",
+		metric.WithDescription("Number of operations whose execution exceeded the operation timeout"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create execution timeout counter: %w", err)
+	}
+	return &timeoutCounter{planning: planning, execution: execution, labels: labels}, nil
+}
+
+// record counts one operation of operationType exceeding the deadline of phase.
+func (c *timeoutCounter) record(ctx context.Context, phase, operationType string) {
+	counter := c.execution
+	if phase == timeoutPhasePlanning {
+		counter = c.planning
+	}
+	attrs := append([]attribute.KeyValue{attribute.String("graphql.operation.type", operationType)},
+		ClientInfoFromContext(ctx).attributes()...)
+	counter.Add(context.WithoutCancel(ctx), 1,
+		metric.WithAttributes(attrs...),
+		c.labels,
+	)
+}
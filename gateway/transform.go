@@ -0,0 +1,231 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// ResponseTransformOption is a transformation applied to one field of every executed
+// response, e.g. to redact a sensitive field for unauthenticated requests or to keep
+// the old name of a renamed field for existing clients.
+type ResponseTransformOption struct {
+	Field        string `yaml:"field"`         // Field coordinate, e.g. User.email
+	Mask         bool   `yaml:"mask"`          // Replace the value with MaskValue
+	MaskValue    any    `yaml:"mask_value"`    // Value of masked fields; null when unset
+	Rename       string `yaml:"rename"`        // Return the field under this response key
+	UnlessHeader string `yaml:"unless_header"` // Only transform responses to requests without this header, e.g. Authorization
+}
+
+// ResponseField is a field of an executed response offered to a ResponseTransformer.
+// Changing Key renames the field, changing Value replaces it and setting Remove drops
+// the field from the response.
+type ResponseField struct {
+	TypeName  string // Type the field is selected on
+	FieldName string
+	Path      []any // Response path of the field, including its key
+	Key       string
+	Value     any
+	Remove    bool
+}
+
+// Coordinate returns the schema coordinate of the field, e.g. User.email.
+func (f *ResponseField) Coordinate() string {
+	return f.TypeName + "." + f.FieldName
+}
+
+// ResponseTransformer rewrites the fields of executed responses before they are
+// encoded. Fields are offered parent first, with the selections of the client; the
+// children of a replaced value are offered from the new value.
+type ResponseTransformer interface {
+	TransformField(ctx context.Context, field *ResponseField)
+}
+
+// ResponseTransformerFunc adapts a function to a ResponseTransformer.
+type ResponseTransformerFunc func(ctx context.Context, field *ResponseField)
+
+// TransformField calls f(ctx, field).
+func (f ResponseTransformerFunc) TransformField(ctx context.Context, field *ResponseField) {
+	f(ctx, field)
+}
+
+// responseTransforms runs the configured rules, then the programmatic transformers.
+type responseTransforms struct {
+	transformers []ResponseTransformer
+	// headers is set when a rule depends on the request headers.
+	headers bool
+}
+
+// newResponseTransforms returns nil when there is nothing to transform.
+func newResponseTransforms(rules []ResponseTransformOption, transformers []ResponseTransformer) (*responseTransforms, error) {
+	t := &responseTransforms{}
+	for i, rule := range rules {
+		typeName, fieldName, ok := strings.Cut(rule.Field, ".")
+		if !ok || typeName == "" || fieldName == "" {
+			return nil, fmt.Errorf("response_transforms[%d]: field %q is not a Type.field coordinate", i, rule.Field)
+		}
+		if !rule.Mask && rule.Rename == "" {
+			return nil, fmt.Errorf("response_transforms[%d]: %s sets neither mask nor rename", i, rule.Field)
+		}
+		t.transformers = append(t.transformers, transformRule(rule))
+		t.headers = t.headers || rule.UnlessHeader != ""
+	}
+	t.transformers = append(t.transformers, transformers...)
+	if len(t.transformers) == 0 {
+		return nil, nil
+	}
+	return t, nil
+}
+
+type requestHeaderContextKey struct{}
+
+// withRequest attaches the headers of r to ctx when a rule depends on them.
+func (t *responseTransforms) withRequest(ctx context.Context, r *http.Request) context.Context {
+	if t == nil || !t.headers {
+		return ctx
+	}
+	return context.WithValue(ctx, requestHeaderContextKey{}, r.Header)
+}
+
+// transformRule applies a configured rule.
+func transformRule(rule ResponseTransformOption) ResponseTransformerFunc {
+	return func(ctx context.Context, field *ResponseField) {
+		if field.Coordinate() != rule.Field {
+			return
+		}
+		if rule.UnlessHeader != "" {
+			if header, _ := ctx.Value(requestHeaderContextKey{}).(http.Header); header.Get(rule.UnlessHeader) != "" {
+				return
+			}
+		}
+		if rule.Mask && field.Value != nil {
+			field.Value = rule.MaskValue
+		}
+		if rule.Rename != "" {
+			field.Key = rule.Rename
+		}
+	}
+}
+
+// apply transforms the data of resp in place, following the selections of op.
+func (t *responseTransforms) apply(ctx context.Context, resp map[string]any, doc *ast.Document, op *ast.OperationDefinition, engine *executionEngine) {
+	if t == nil || op == nil {
+		return
+	}
+	data, ok := resp["data"].(map[string]any)
+	if !ok {
+		return
+	}
+
+	fragmentDefs := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragDef, ok := def.(*ast.FragmentDefinition); ok {
+			fragmentDefs[fragDef.Name.String()] = fragDef
+		}
+	}
+
+	w := &transformWalker{ctx: ctx, transformers: t.transformers, fragmentDefs: fragmentDefs, engine: engine}
+	w.walkObject(data, op.SelectionSet, engine.superGraph.RootTypeName(op.Operation), nil)
+}
+
+type transformWalker struct {
+	ctx          context.Context
+	transformers []ResponseTransformer
+	fragmentDefs map[string]*ast.FragmentDefinition
+	engine       *executionEngine
+}
+
+// walkObject transforms the fields of obj selected by selections on typeName.
+func (w *transformWalker) walkObject(obj map[string]any, selections []ast.Selection, typeName string, path []any) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			w.walkField(obj, s, typeName, path)
+		case *ast.InlineFragment:
+			fragmentType := typeName
+			if s.TypeCondition != nil {
+				fragmentType = s.TypeCondition.Name.String()
+			}
+			if w.matchesType(obj, fragmentType) {
+				w.walkObject(obj, s.SelectionSet, fragmentType, path)
+			}
+		case *ast.FragmentSpread:
+			def, ok := w.fragmentDefs[s.Name.String()]
+			if !ok {
+				continue
+			}
+			fragmentType := def.TypeCondition.Name.String()
+			if w.matchesType(obj, fragmentType) {
+				w.walkObject(obj, def.SelectionSet, fragmentType, path)
+			}
+		}
+	}
+}
+
+// matchesType reports whether obj may be of typeName. Without __typename in the
+// response, a fragment is assumed to apply.
+func (w *transformWalker) matchesType(obj map[string]any, typeName string) bool {
+	actual, ok := obj["__typename"].(string)
+	if !ok || actual == typeName {
+		return true
+	}
+	return isPossibleType(w.engine, typeName, actual)
+}
+
+// fieldTypeName returns the named type of typeName.fieldName, which may be an
+// interface field.
+func (w *transformWalker) fieldTypeName(typeName, fieldName string) string {
+	if fieldDef := w.engine.superGraph.FieldDefinition(typeName, fieldName); fieldDef != nil {
+		return unwrapNamedType(fieldDef.Type)
+	}
+	return ""
+}
+
+func (w *transformWalker) walkField(obj map[string]any, field *ast.Field, typeName string, path []any) {
+	fieldName := field.Name.String()
+	if strings.HasPrefix(fieldName, "__") {
+		return
+	}
+	key := fieldName
+	if field.Alias != nil && field.Alias.String() != "" {
+		key = field.Alias.String()
+	}
+	value, ok := obj[key]
+	if !ok {
+		return
+	}
+
+	fieldPath := append(append(make([]any, 0, len(path)+1), path...), key)
+	f := &ResponseField{TypeName: typeName, FieldName: fieldName, Path: fieldPath, Key: key, Value: value}
+	for _, transformer := range w.transformers {
+		transformer.TransformField(w.ctx, f)
+		if f.Remove {
+			delete(obj, key)
+			return
+		}
+	}
+	if f.Key != key {
+		delete(obj, key)
+		fieldPath[len(fieldPath)-1] = f.Key
+	}
+	obj[f.Key] = f.Value
+
+	if len(field.SelectionSet) > 0 {
+		w.walkValue(f.Value, field.SelectionSet, w.fieldTypeName(typeName, fieldName), fieldPath)
+	}
+}
+
+// walkValue transforms the objects of value, which may be nested in lists.
+func (w *transformWalker) walkValue(value any, selections []ast.Selection, typeName string, path []any) {
+	switch v := value.(type) {
+	case map[string]any:
+		w.walkObject(v, selections, typeName, path)
+	case []any:
+		for i, item := range v {
+			w.walkValue(item, selections, typeName, append(append(make([]any, 0, len(path)+1), path...), i))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_ResponseTransforms(t *testing.T) {
+	const usersSDL = `
+		type Query {
+			me: User
+			users: [User!]!
+		}
+
+		type User {
+			id: ID!
+			email: String
+			phone: String
+			handle: String!
+		}
+	`
+	users := newSubgraphServer(t, usersSDL, func(body map[string]any) any {
+		user := map[string]any{"id": "1", "email": "ada@example.com", "phone": "555-0100", "handle": "ada"}
+		return map[string]any{"data": map[string]any{"me": user, "users": []any{user, map[string]any{"id": "2", "email": nil, "phone": "555-0101", "handle": "bob"}}}}
+	})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{{Name: "users", Host: users.URL}},
+		ResponseTransforms: []gateway.ResponseTransformOption{
+			{Field: "User.email", Mask: true, UnlessHeader: "Authorization"},
+			{Field: "User.handle", Rename: "username"},
+		},
+		ResponseTransformers: []gateway.ResponseTransformer{
+			gateway.ResponseTransformerFunc(func(ctx context.Context, field *gateway.ResponseField) {
+				if field.Coordinate() == "User.phone" {
+					field.Value = "***"
+				}
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		authorization string
+		want          string
+	}{
+		{
+			name:  "masked without authorization",
+			query: `{ me { id email } }`,
+			want:  `{"data":{"me":{"id":"1","email":null}}}`,
+		},
+		{
+			name:          "kept with authorization",
+			query:         `{ me { id email } }`,
+			authorization: "Bearer token",
+			want:          `{"data":{"me":{"id":"1","email":"ada@example.com"}}}`,
+		},
+		{
+			name:  "renamed in lists",
+			query: `{ users { handle } }`,
+			want:  `{"data":{"users":[{"username":"ada"},{"username":"bob"}]}}`,
+		},
+		{
+			name:  "aliases and fragments",
+			query: `{ me { ...contact } } fragment contact on User { mail: email phone }`,
+			want:  `{"data":{"me":{"mail":null,"phone":"***"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]any{"query": tt.query})
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, req)
+
+			var got, want any
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+			}
+			json.Unmarshal([]byte(tt.want), &want) //nolint:errcheck
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("expected %s, got %s", tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("invalid rule", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:           "/graphql",
+			Services:           []gateway.GatewayService{{Name: "users", Host: users.URL}},
+			ResponseTransforms: []gateway.ResponseTransformOption{{Field: "email", Mask: true}},
+		})
+		if err == nil {
+			t.Error("expected an error for a field without a type")
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// TransportOption tunes the HTTP transport used for subgraph requests. Zero values keep
+// the http.DefaultTransport settings.
+type TransportOption struct {
+	MaxIdleConns          int    `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost   int    `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost       int    `yaml:"max_conns_per_host"`
+	IdleConnTimeout       string `yaml:"idle_conn_timeout" validate:"duration"`
+	TLSHandshakeTimeout   string `yaml:"tls_handshake_timeout" validate:"duration"`
+	ResponseHeaderTimeout string `yaml:"response_header_timeout" validate:"duration"`
+	DisableKeepAlives     bool   `yaml:"disable_keep_alives"`
+	DisableHTTP2          bool   `yaml:"disable_http2"`
+}
+
+// merge returns o with its zero fields taken from defaults.
+func (o TransportOption) merge(defaults TransportOption) TransportOption {
+	if o.MaxIdleConns == 0 {
+		o.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if o.MaxIdleConnsPerHost == 0 {
+		o.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
+	}
+	if o.MaxConnsPerHost == 0 {
+		o.MaxConnsPerHost = defaults.MaxConnsPerHost
+	}
+	if o.IdleConnTimeout == "" {
+		o.IdleConnTimeout = defaults.IdleConnTimeout
+	}
+	if o.TLSHandshakeTimeout == "" {
+		o.TLSHandshakeTimeout = defaults.TLSHandshakeTimeout
+	}
+	if o.ResponseHeaderTimeout == "" {
+		o.ResponseHeaderTimeout = defaults.ResponseHeaderTimeout
+	}
+	o.DisableKeepAlives = o.DisableKeepAlives || defaults.DisableKeepAlives
+	o.DisableHTTP2 = o.DisableHTTP2 || defaults.DisableHTTP2
+	return o
+}
+
+// newTransport builds an http.Transport from opt, starting from http.DefaultTransport.
+func newTransport(opt TransportOption) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opt.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opt.MaxIdleConns
+	}
+	if opt.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opt.MaxIdleConnsPerHost
+	}
+	if opt.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = opt.MaxConnsPerHost
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"idle_conn_timeout", opt.IdleConnTimeout, &transport.IdleConnTimeout},
+		{"tls_handshake_timeout", opt.TLSHandshakeTimeout, &transport.TLSHandshakeTimeout},
+		{"response_header_timeout", opt.ResponseHeaderTimeout, &transport.ResponseHeaderTimeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", d.name, d.value, err)
+		}
+		*d.dst = duration
+	}
+	transport.DisableKeepAlives = opt.DisableKeepAlives
+	if opt.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		// A non-nil, empty TLSNextProto map disables HTTP/2 negotiation.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return transport, nil
+}
+
+// newHTTPClient builds a client for subgraph requests with the transport described by
+// opt, wrapped for tracing and snapshots as configured in settings.
+func newHTTPClient(settings GatewayOption, opt TransportOption) (*http.Client, error) {
+	transport, err := newTransport(opt)
+	if err != nil {
+		return nil, err
+	}
+	return newHTTPClientWithTransport(settings, transport)
+}
+
+// newHTTPClientWithTransport is newHTTPClient with an existing transport.
+func newHTTPClientWithTransport(settings GatewayOption, transport http.RoundTripper) (*http.Client, error) {
+	client := &http.Client{
+		Timeout:   3 * time.Second,
+		Transport: transport,
+	}
+	if settings.Opentelemetry.TracingSetting.Enable {
+		client.Transport = otelhttp.NewTransport(client.Transport)
+	}
+	if settings.Snapshot.Mode != "" {
+		snapshot, err := newSnapshotTransport(settings.Snapshot, settings.Services, client.Transport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure snapshot mode: %w", err)
+		}
+		client.Transport = snapshot
+	}
+	return client, nil
+}
@@ -0,0 +1,117 @@
+package gateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestNewTransport(t *testing.T) {
+	transport, err := gateway.NewTransportForTest(gateway.TransportOption{
+		MaxIdleConnsPerHost: 64,
+		MaxConnsPerHost:     128,
+		IdleConnTimeout:     "2m",
+		TLSHandshakeTimeout: "3s",
+		DisableHTTP2:        true,
+	})
+	if err != nil {
+		t.Fatalf("newTransport failed: %v", err)
+	}
+	if transport.MaxIdleConnsPerHost != 64 || transport.MaxConnsPerHost != 128 {
+		t.Errorf("unexpected pool sizes %d/%d", transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 2*time.Minute || transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Errorf("unexpected timeouts %s/%s", transport.IdleConnTimeout, transport.TLSHandshakeTimeout)
+	}
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Error("expected HTTP/2 to be disabled")
+	}
+	if transport.MaxIdleConns != http.DefaultTransport.(*http.Transport).MaxIdleConns {
+		t.Errorf("expected unset fields to keep the default transport settings, got MaxIdleConns %d", transport.MaxIdleConns)
+	}
+
+	if _, err := gateway.NewTransportForTest(gateway.TransportOption{IdleConnTimeout: "forever"}); err == nil {
+		t.Error("expected error for an invalid duration")
+	}
+}
+
+func TestTransportOption_Merge(t *testing.T) {
+	merged := gateway.MergeTransportForTest(
+		gateway.TransportOption{MaxIdleConnsPerHost: 8},
+		gateway.TransportOption{MaxIdleConnsPerHost: 32, IdleConnTimeout: "30s", DisableKeepAlives: true},
+	)
+	if merged.MaxIdleConnsPerHost != 8 {
+		t.Errorf("expected the service setting to win, got %d", merged.MaxIdleConnsPerHost)
+	}
+	if merged.IdleConnTimeout != "30s" || !merged.DisableKeepAlives {
+		t.Errorf("expected unset service fields to fall back to the defaults, got %+v", merged)
+	}
+}
+
+func TestGateway_SubgraphTransports(t *testing.T) {
+	var mu sync.Mutex
+	closed := make(map[string]bool)
+	newServer := func(name, sdl string, data map[string]any) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+			w.Header().Set("Content-Type", "application/json")
+			if q, _ := body["query"].(string); strings.Contains(q, "_service") {
+				json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"_service": map[string]any{"sdl": sdl}}}) //nolint:errcheck
+				return
+			}
+			mu.Lock()
+			closed[name] = r.Close
+			mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{"data": data}) //nolint:errcheck
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	products := newServer("products", sdlProducts, map[string]any{"product": map[string]any{"id": "1", "name": "p"}})
+	reviews := newServer("reviews", sdlReviews, map[string]any{"reviews": []any{map[string]any{"body": "good"}}})
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL, Transport: gateway.TransportOption{DisableKeepAlives: true}},
+			{Name: "reviews", Host: reviews.URL},
+		},
+		Transport: gateway.TransportOption{MaxIdleConnsPerHost: 16},
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	defer gw.Close()
+
+	for _, query := range []string{`{ product(id: \"1\") { name } }`, `{ reviews { body } }`} {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"`+query+`"}`)))
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "errors") {
+			t.Fatalf("query %s failed: %d %s", query, rec.Code, rec.Body.String())
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !closed["products"] {
+		t.Error("expected requests to products to disable keep-alives")
+	}
+	if closed["reviews"] {
+		t.Error("expected requests to reviews to keep connections alive")
+	}
+
+	_, err = gateway.NewGateway(gateway.GatewayOption{
+		Services: []gateway.GatewayService{{Name: "products", Host: products.URL, Transport: gateway.TransportOption{TLSHandshakeTimeout: "soon"}}},
+	})
+	if err == nil {
+		t.Error("expected error for an invalid service transport")
+	}
+}
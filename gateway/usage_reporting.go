@@ -0,0 +1,624 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/graphql-parser/ast"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+	"github.com/n9te9/go-graphql-federation-gateway/federation/graph"
+)
+
+// UsageReportingOption configures reporting of operation usage and schema metadata to an
+// external schema registry.
+type UsageReportingOption struct {
+	Enable   bool   `yaml:"enable" default:"false"`
+	Target   string `yaml:"target" validate:"oneof=hive|graphos|webhook"` // hive, graphos or webhook
+	Endpoint string `yaml:"endpoint"`                                     // Defaults to the usage API of Hive and GraphOS; required for webhooks
+	// Token authorizes the reports: a Hive access token or a webhook token sent as
+	// "Authorization: Bearer <token>", or a GraphOS API key sent as X-Api-Key.
+	Token        string `yaml:"token"`
+	GraphRef     string `yaml:"graph_ref"` // GraphOS graph ref, e.g. my-graph@production
+	Interval     string `yaml:"interval" default:"10s" validate:"duration"`
+	MaxBatchSize int    `yaml:"max_batch_size" default:"1000"` // Operations buffered before a report is sent early
+	// ClientNameHeader and ClientVersionHeader override the client identity in reports.
+	ClientNameHeader    string `yaml:"client_name_header"`
+	ClientVersionHeader string `yaml:"client_version_header"`
+}
+
+// Targets of UsageReportingOption.
+const (
+	usageTargetHive    = "hive"
+	usageTargetGraphOS = "graphos"
+	usageTargetWebhook = "webhook"
+)
+
+// defaultUsageEndpoints are the usage APIs of the registries.
+var defaultUsageEndpoints = map[string]string{
+	usageTargetHive:    "https://app.graphql-hive.com/usage",
+	usageTargetGraphOS: "https://usage-reporting.api.apollographql.com/api/ingress/traces",
+}
+
+const (
+	defaultUsageInterval     = 10 * time.Second
+	defaultUsageMaxBatchSize = 1000
+	usageAgentVersion        = "go-graphql-federation-gateway"
+)
+
+// operationUsage is one executed operation to report.
+type operationUsage struct {
+	Name          string              `json:"name,omitempty"`
+	Type          string              `json:"type"`
+	Document      string              `json:"document"` // Normalized operation
+	Fields        map[string][]string `json:"fields"`   // Referenced fields by type
+	Timestamp     time.Time           `json:"timestamp"`
+	Duration      time.Duration       `json:"-"`
+	DurationMS    float64             `json:"durationMs"`
+	Errors        int                 `json:"errors"`
+	ClientName    string              `json:"clientName,omitempty"`
+	ClientVersion string              `json:"clientVersion,omitempty"`
+	SchemaHash    string              `json:"schemaHash"`
+	interfaces    map[string]bool
+	sdl           func() string
+}
+
+// usageClient identifies the client of a request in usage reports.
+type usageClient struct {
+	name    string
+	version string
+}
+
+type usageClientContextKey struct{}
+
+// usageReporter buffers the usage of executed operations and reports it periodically.
+type usageReporter struct {
+	target              string
+	endpoint            string
+	token               string
+	graphRef            string
+	clientNameHeader    string
+	clientVersionHeader string
+	maxBatchSize        int
+	httpClient          *http.Client
+	sanitizer           *querySanitizer // Sanitizes the reported documents
+
+	mu             sync.Mutex
+	pending        []operationUsage
+	reportedSchema string // Schema hash whose SDL was last sent to the webhook
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// newUsageReporter validates opt and starts its reporter, or returns nil when reporting
+// is disabled.
+func newUsageReporter(opt UsageReportingOption, httpClient *http.Client, sanitizer *querySanitizer) (*usageReporter, error) {
+	if !opt.Enable {
+		return nil, nil
+	}
+	switch opt.Target {
+	case usageTargetHive, usageTargetGraphOS, usageTargetWebhook:
+	default:
+		return nil, fmt.Errorf("unknown usage reporting target %q: use hive, graphos or webhook", opt.Target)
+	}
+	endpoint := opt.Endpoint
+	if endpoint == "" {
+		endpoint = defaultUsageEndpoints[opt.Target]
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("usage reporting target %s requires an endpoint", opt.Target)
+	}
+	if opt.Target == usageTargetGraphOS && opt.GraphRef == "" {
+		return nil, fmt.Errorf("usage reporting target graphos requires a graph_ref")
+	}
+	interval := defaultUsageInterval
+	if opt.Interval != "" {
+		d, err := time.ParseDuration(opt.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid usage reporting interval %q", opt.Interval)
+		}
+		interval = d
+	}
+
+	r := &usageReporter{
+		target:              opt.Target,
+		endpoint:            endpoint,
+		token:               opt.Token,
+		graphRef:            opt.GraphRef,
+		clientNameHeader:    opt.ClientNameHeader,
+		clientVersionHeader: opt.ClientVersionHeader,
+		maxBatchSize:        opt.MaxBatchSize,
+		httpClient:          httpClient,
+		sanitizer:           sanitizer,
+		flush:               make(chan struct{}, 1),
+		stop:                make(chan struct{}),
+		done:                make(chan struct{}),
+	}
+	if r.maxBatchSize <= 0 {
+		r.maxBatchSize = defaultUsageMaxBatchSize
+	}
+	go r.run(interval)
+	return r, nil
+}
+
+// run sends a report every interval, or earlier when a batch is full, until close.
+func (r *usageReporter) run(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.flush:
+		case <-r.stop:
+			r.report()
+			return
+		}
+		r.report()
+	}
+}
+
+// close sends the buffered usage and stops the reporter.
+func (r *usageReporter) close() {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+// withClient attaches the client of r to ctx: the client identity, overridden by the
+// headers configured for reports.
+func (r *usageReporter) withClient(ctx context.Context, req *http.Request) context.Context {
+	if r == nil {
+		return ctx
+	}
+	info := ClientInfoFromContext(ctx)
+	client := usageClient{name: info.Name, version: info.Version}
+	if r.clientNameHeader != "" {
+		client.name = req.Header.Get(r.clientNameHeader)
+	}
+	if r.clientVersionHeader != "" {
+		client.version = req.Header.Get(r.clientVersionHeader)
+	}
+	return context.WithValue(ctx, usageClientContextKey{}, client)
+}
+
+// record buffers the usage of an executed operation.
+func (r *usageReporter) record(ctx context.Context, info *ExecutionInfo, resp map[string]any) {
+	if r == nil {
+		return
+	}
+	engine := info.engine
+	document, err := engine.planner.NormalizedQuery(info.Document, info.OperationName)
+	if err != nil {
+		return
+	}
+	client, _ := ctx.Value(usageClientContextKey{}).(usageClient)
+
+	fields, interfaces := referencedFields(info.Document, engine)
+	usage := operationUsage{
+		Name:          info.OperationName,
+		Type:          info.OperationType,
+		Document:      r.sanitizer.query(document),
+		Fields:        fields,
+		Timestamp:     time.Now().Add(-info.Timing.Total),
+		Duration:      info.Timing.Total,
+		DurationMS:    float64(info.Timing.Total.Microseconds()) / 1000,
+		Errors:        responseErrorCount(resp),
+		ClientName:    client.name,
+		ClientVersion: client.version,
+		SchemaHash:    engine.schemaHash,
+		interfaces:    interfaces,
+		sdl:           func() string { return engine.superGraph.PrintSDL(graph.PrintOptions{}) },
+	}
+
+	r.mu.Lock()
+	r.pending = append(r.pending, usage)
+	full := len(r.pending) >= r.maxBatchSize
+	r.mu.Unlock()
+	if full {
+		select {
+		case r.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// responseErrorCount returns the number of errors in resp.
+func responseErrorCount(resp map[string]any) int {
+	switch errs := resp["errors"].(type) {
+	case []executor.GraphQLError:
+		return len(errs)
+	case []map[string]any:
+		return len(errs)
+	case []any:
+		return len(errs)
+	}
+	return 0
+}
+
+// report sends the buffered usage.
+func (r *usageReporter) report() {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	var body []byte
+	var err error
+	header := make(http.Header)
+	switch r.target {
+	case usageTargetHive:
+		body, err = hiveUsageReport(pending)
+		header.Set("Content-Type", "application/json")
+		header.Set("X-Usage-API-Version", "2")
+		if r.token != "" {
+			header.Set("Authorization", "Bearer "+r.token)
+		}
+	case usageTargetGraphOS:
+		body, err = graphOSUsageReport(pending, r.graphRef)
+		header.Set("Content-Type", "application/protobuf")
+		header.Set("Content-Encoding", "gzip")
+		header.Set("X-Api-Key", r.token)
+	default:
+		body, err = r.webhookUsageReport(pending)
+		header.Set("Content-Type", "application/json")
+		if r.token != "" {
+			header.Set("Authorization", "Bearer "+r.token)
+		}
+	}
+	if err != nil {
+		log.Printf("failed to encode usage report: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("failed to create usage report request: %v", err)
+		return
+	}
+	req.Header = header
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		log.Printf("failed to send usage report to %s: %v", r.endpoint, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("usage report rejected by %s with status %d", r.endpoint, resp.StatusCode)
+	}
+}
+
+// hiveUsageReport encodes operations for the GraphQL Hive usage API.
+func hiveUsageReport(operations []operationUsage) ([]byte, error) {
+	type hiveOperation struct {
+		Operation     string   `json:"operation"`
+		OperationName string   `json:"operationName,omitempty"`
+		Fields        []string `json:"fields"`
+	}
+	type hiveClient struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	type hiveExecution struct {
+		OK          bool  `json:"ok"`
+		Duration    int64 `json:"duration"` // Nanoseconds
+		ErrorsTotal int   `json:"errorsTotal"`
+	}
+	type hiveRecord struct {
+		OperationMapKey string        `json:"operationMapKey"`
+		Timestamp       int64         `json:"timestamp"` // Milliseconds
+		Execution       hiveExecution `json:"execution"`
+		Metadata        *struct {
+			Client hiveClient `json:"client"`
+		} `json:"metadata,omitempty"`
+	}
+
+	operationMap := make(map[string]hiveOperation)
+	records := make([]hiveRecord, 0, len(operations))
+	for _, op := range operations {
+		sum := sha256.Sum256([]byte(op.Name + "\n" + op.Document))
+		key := hex.EncodeToString(sum[:])
+		if _, ok := operationMap[key]; !ok {
+			var fields []string
+			for _, typeName := range sortedKeys(op.Fields) {
+				fields = append(fields, typeName)
+				for _, field := range op.Fields[typeName] {
+					fields = append(fields, typeName+"."+field)
+				}
+			}
+			operationMap[key] = hiveOperation{Operation: op.Document, OperationName: op.Name, Fields: fields}
+		}
+		record := hiveRecord{
+			OperationMapKey: key,
+			Timestamp:       op.Timestamp.UnixMilli(),
+			Execution:       hiveExecution{OK: op.Errors == 0, Duration: op.Duration.Nanoseconds(), ErrorsTotal: op.Errors},
+		}
+		if op.ClientName != "" {
+			record.Metadata = &struct {
+				Client hiveClient `json:"client"`
+			}{Client: hiveClient{Name: op.ClientName, Version: op.ClientVersion}}
+		}
+		records = append(records, record)
+	}
+	return json.Marshal(map[string]any{
+		"size":       len(records),
+		"map":        operationMap,
+		"operations": records,
+	})
+}
+
+// webhookUsageReport encodes operations for a generic webhook. The SDL of the schema is
+// included whenever it changed since the last report.
+func (r *usageReporter) webhookUsageReport(operations []operationUsage) ([]byte, error) {
+	report := map[string]any{"operations": operations}
+	last := operations[len(operations)-1]
+	if last.SchemaHash != r.reportedSchema {
+		report["schema"] = map[string]string{"hash": last.SchemaHash, "sdl": last.sdl()}
+		r.reportedSchema = last.SchemaHash
+	}
+	return json.Marshal(report)
+}
+
+// graphOSUsageReport encodes operations as a gzipped Apollo usage report (reports.proto),
+// aggregated by operation signature and client.
+func graphOSUsageReport(operations []operationUsage, graphRef string) ([]byte, error) {
+	type statsKey struct {
+		client  string
+		version string
+	}
+	type queryStats struct {
+		fields     map[string][]string
+		interfaces map[string]bool
+		stats      map[statsKey]*latencyStats
+	}
+	queries := make(map[string]*queryStats)
+	for _, op := range operations {
+		name := op.Name
+		if name == "" {
+			name = "-"
+		}
+		key := "# " + name + "\n" + op.Document
+		q, ok := queries[key]
+		if !ok {
+			q = &queryStats{fields: op.Fields, interfaces: op.interfaces, stats: make(map[statsKey]*latencyStats)}
+			queries[key] = q
+		}
+		sk := statsKey{client: op.ClientName, version: op.ClientVersion}
+		if q.stats[sk] == nil {
+			q.stats[sk] = &latencyStats{}
+		}
+		q.stats[sk].add(op.Duration, op.Errors > 0)
+	}
+
+	hostname, _ := os.Hostname()
+	var header []byte
+	header = protowire.AppendTag(header, 5, protowire.BytesType)
+	header = protowire.AppendString(header, hostname)
+	header = protowire.AppendTag(header, 6, protowire.BytesType)
+	header = protowire.AppendString(header, usageAgentVersion)
+	header = protowire.AppendTag(header, 8, protowire.BytesType)
+	header = protowire.AppendString(header, runtime.Version())
+	if schemaHash := operations[len(operations)-1].SchemaHash; schemaHash != "" {
+		header = protowire.AppendTag(header, 11, protowire.BytesType)
+		header = protowire.AppendString(header, schemaHash)
+	}
+	header = protowire.AppendTag(header, 12, protowire.BytesType)
+	header = protowire.AppendString(header, graphRef)
+
+	var report []byte
+	report = protowire.AppendTag(report, 1, protowire.BytesType)
+	report = protowire.AppendBytes(report, header)
+
+	now := time.Now()
+	var endTime []byte
+	endTime = protowire.AppendTag(endTime, 1, protowire.VarintType)
+	endTime = protowire.AppendVarint(endTime, uint64(now.Unix()))
+	endTime = protowire.AppendTag(endTime, 2, protowire.VarintType)
+	endTime = protowire.AppendVarint(endTime, uint64(now.Nanosecond()))
+	report = protowire.AppendTag(report, 2, protowire.BytesType)
+	report = protowire.AppendBytes(report, endTime)
+
+	for _, key := range sortedKeys(queries) {
+		q := queries[key]
+		var tracesAndStats []byte
+		for sk, stats := range q.stats {
+			var context []byte
+			context = protowire.AppendTag(context, 2, protowire.BytesType)
+			context = protowire.AppendString(context, sk.client)
+			context = protowire.AppendTag(context, 3, protowire.BytesType)
+			context = protowire.AppendString(context, sk.version)
+
+			var contextualized []byte
+			contextualized = protowire.AppendTag(contextualized, 1, protowire.BytesType)
+			contextualized = protowire.AppendBytes(contextualized, context)
+			contextualized = protowire.AppendTag(contextualized, 2, protowire.BytesType)
+			contextualized = protowire.AppendBytes(contextualized, stats.marshal())
+
+			tracesAndStats = protowire.AppendTag(tracesAndStats, 2, protowire.BytesType)
+			tracesAndStats = protowire.AppendBytes(tracesAndStats, contextualized)
+		}
+		for _, typeName := range sortedKeys(q.fields) {
+			var referenced []byte
+			for _, field := range q.fields[typeName] {
+				referenced = protowire.AppendTag(referenced, 1, protowire.BytesType)
+				referenced = protowire.AppendString(referenced, field)
+			}
+			if q.interfaces[typeName] {
+				referenced = protowire.AppendTag(referenced, 2, protowire.VarintType)
+				referenced = protowire.AppendVarint(referenced, 1)
+			}
+			tracesAndStats = protowire.AppendTag(tracesAndStats, 4, protowire.BytesType)
+			tracesAndStats = protowire.AppendBytes(tracesAndStats, mapEntry(typeName, referenced))
+		}
+
+		report = protowire.AppendTag(report, 5, protowire.BytesType)
+		report = protowire.AppendBytes(report, mapEntry(key, tracesAndStats))
+	}
+	report = protowire.AppendTag(report, 6, protowire.VarintType)
+	report = protowire.AppendVarint(report, uint64(len(operations)))
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(report); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mapEntry encodes an entry of a protobuf map with string keys and message values.
+func mapEntry(key string, value []byte) []byte {
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, key)
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	return protowire.AppendBytes(entry, value)
+}
+
+// latencyBuckets is the size of the latency histograms of Apollo usage reports, whose
+// buckets grow by 10% from 1µs.
+const latencyBuckets = 384
+
+// latencyStats aggregates the requests of one operation and client (QueryLatencyStats).
+type latencyStats struct {
+	requests   uint64
+	withErrors uint64
+	latencies  [latencyBuckets]int64
+}
+
+// add counts a request of duration d.
+func (s *latencyStats) add(d time.Duration, hasErrors bool) {
+	s.requests++
+	if hasErrors {
+		s.withErrors++
+	}
+	bucket := 0
+	if micros := float64(d.Nanoseconds()) / 1000; micros > 0 {
+		bucket = int(math.Ceil(math.Log(micros) / math.Log(1.1)))
+	}
+	s.latencies[min(max(bucket, 0), latencyBuckets-1)]++
+}
+
+// marshal encodes the stats as a QueryLatencyStats message. Runs of empty buckets in
+// the histogram are encoded as their negated length, and trailing ones are dropped.
+func (s *latencyStats) marshal() []byte {
+	var counts []byte
+	zeros := int64(0)
+	for _, count := range s.latencies {
+		if count == 0 {
+			zeros++
+			continue
+		}
+		if zeros == 1 {
+			counts = protowire.AppendVarint(counts, protowire.EncodeZigZag(0))
+		} else if zeros > 1 {
+			counts = protowire.AppendVarint(counts, protowire.EncodeZigZag(-zeros))
+		}
+		zeros = 0
+		counts = protowire.AppendVarint(counts, protowire.EncodeZigZag(count))
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, s.requests)
+	if s.withErrors > 0 {
+		b = protowire.AppendTag(b, 8, protowire.VarintType)
+		b = protowire.AppendVarint(b, s.withErrors)
+	}
+	b = protowire.AppendTag(b, 13, protowire.BytesType)
+	return protowire.AppendBytes(b, counts)
+}
+
+// referencedFields returns the fields the operations of doc select, by parent type, and
+// which of those types are interfaces.
+func referencedFields(doc *ast.Document, engine *executionEngine) (map[string][]string, map[string]bool) {
+	fragmentDefs := fragmentDefinitions(doc)
+	seen := make(map[string]bool)
+	fields := make(map[string][]string)
+	interfaces := make(map[string]bool)
+
+	var collect func(selections []ast.Selection, parentTypeName string, visited map[string]bool)
+	collect = func(selections []ast.Selection, parentTypeName string, visited map[string]bool) {
+		for _, sel := range selections {
+			switch s := sel.(type) {
+			case *ast.Field:
+				name := s.Name.String()
+				if name == "__typename" {
+					continue
+				}
+				fieldDef := findFieldDefinition(parentTypeName, name, engine)
+				if fieldDef == nil {
+					continue
+				}
+				if coordinate := parentTypeName + "." + name; !seen[coordinate] {
+					seen[coordinate] = true
+					fields[parentTypeName] = append(fields[parentTypeName], name)
+					if _, ok := engine.superGraph.TypeDefinition(parentTypeName).(*ast.InterfaceTypeDefinition); ok {
+						interfaces[parentTypeName] = true
+					}
+				}
+				if len(s.SelectionSet) > 0 {
+					collect(s.SelectionSet, unwrapNamedType(fieldDef.Type), visited)
+				}
+			case *ast.InlineFragment:
+				typeCondition := parentTypeName
+				if s.TypeCondition != nil {
+					typeCondition = s.TypeCondition.Name.String()
+				}
+				collect(s.SelectionSet, typeCondition, visited)
+			case *ast.FragmentSpread:
+				name := s.Name.String()
+				fragDef, ok := fragmentDefs[name]
+				if !ok || visited[name] {
+					continue
+				}
+				visited[name] = true
+				collect(fragDef.SelectionSet, fragDef.TypeCondition.Name.String(), visited)
+			}
+		}
+	}
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			collect(op.SelectionSet, engine.superGraph.RootTypeName(op.Operation), make(map[string]bool))
+		}
+	}
+	for typeName := range fields {
+		sort.Strings(fields[typeName])
+	}
+	return fields, interfaces
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
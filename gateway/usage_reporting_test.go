@@ -0,0 +1,189 @@
+package gateway_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+// usageCollector records the usage reports it receives.
+type usageCollector struct {
+	mu      sync.Mutex
+	headers []http.Header
+	bodies  [][]byte
+}
+
+func newUsageCollector(t *testing.T) (*usageCollector, *httptest.Server) {
+	t.Helper()
+	c := &usageCollector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		c.headers = append(c.headers, r.Header.Clone())
+		c.bodies = append(c.bodies, body)
+		c.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func TestGateway_UsageReporting(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"id": "1", "name": "chair"}}}
+	})
+	const query = `{"query":"query Product { product(id: \"1\") { name } }","operationName":"Product"}`
+
+	run := func(t *testing.T, opt gateway.UsageReportingOption) *usageCollector {
+		t.Helper()
+		collector, registry := newUsageCollector(t)
+		opt.Enable = true
+		opt.Endpoint = registry.URL
+		opt.Interval = "1h"
+		gw, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:       "/graphql",
+			Services:       []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			UsageReporting: opt,
+		})
+		if err != nil {
+			t.Fatalf("NewGateway failed: %v", err)
+		}
+		for range 2 {
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(query))
+			req.Header.Set("apollographql-client-name", "web")
+			req.Header.Set("apollographql-client-version", "1.2.0")
+			gw.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		// Closing the gateway sends the buffered usage.
+		gw.Close()
+
+		if len(collector.bodies) != 1 {
+			t.Fatalf("expected 1 report, got %d", len(collector.bodies))
+		}
+		return collector
+	}
+
+	t.Run("hive", func(t *testing.T) {
+		collector := run(t, gateway.UsageReportingOption{Target: "hive", Token: "hive-token"})
+		if got := collector.headers[0].Get("Authorization"); got != "Bearer hive-token" {
+			t.Errorf("expected the token as bearer, got %q", got)
+		}
+
+		var report struct {
+			Size int `json:"size"`
+			Map  map[string]struct {
+				Operation     string   `json:"operation"`
+				OperationName string   `json:"operationName"`
+				Fields        []string `json:"fields"`
+			} `json:"map"`
+			Operations []struct {
+				OperationMapKey string `json:"operationMapKey"`
+				Execution       struct {
+					OK bool `json:"ok"`
+				} `json:"execution"`
+				Metadata struct {
+					Client struct {
+						Name    string `json:"name"`
+						Version string `json:"version"`
+					} `json:"client"`
+				} `json:"metadata"`
+			} `json:"operations"`
+		}
+		if err := json.Unmarshal(collector.bodies[0], &report); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
+		}
+		if report.Size != 2 || len(report.Operations) != 2 || len(report.Map) != 1 {
+			t.Fatalf("expected 2 operations sharing 1 document, got %s", collector.bodies[0])
+		}
+		op := report.Map[report.Operations[0].OperationMapKey]
+		wantFields := []string{"Product", "Product.name", "Query", "Query.product"}
+		if op.OperationName != "Product" || strings.Join(op.Fields, ",") != strings.Join(wantFields, ",") {
+			t.Errorf("expected operation Product with fields %v, got %s", wantFields, collector.bodies[0])
+		}
+		if client := report.Operations[0].Metadata.Client; client.Name != "web" || client.Version != "1.2.0" || !report.Operations[0].Execution.OK {
+			t.Errorf("expected a successful operation of client web 1.2.0, got %s", collector.bodies[0])
+		}
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		collector := run(t, gateway.UsageReportingOption{Target: "webhook"})
+
+		var report struct {
+			Schema struct {
+				Hash string `json:"hash"`
+				SDL  string `json:"sdl"`
+			} `json:"schema"`
+			Operations []struct {
+				Name       string `json:"name"`
+				SchemaHash string `json:"schemaHash"`
+				ClientName string `json:"clientName"`
+			} `json:"operations"`
+		}
+		if err := json.Unmarshal(collector.bodies[0], &report); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
+		}
+		if report.Schema.Hash == "" || !strings.Contains(report.Schema.SDL, "product(id: ID!): Product") {
+			t.Errorf("expected the schema metadata, got %s", collector.bodies[0])
+		}
+		if len(report.Operations) != 2 || report.Operations[0].SchemaHash != report.Schema.Hash || report.Operations[0].ClientName != "web" {
+			t.Errorf("expected 2 operations of client web, got %s", collector.bodies[0])
+		}
+	})
+
+	t.Run("graphos", func(t *testing.T) {
+		collector := run(t, gateway.UsageReportingOption{Target: "graphos", Token: "service:key", GraphRef: "shop@prod"})
+		if got := collector.headers[0].Get("X-Api-Key"); got != "service:key" {
+			t.Errorf("expected the API key, got %q", got)
+		}
+
+		zr, err := gzip.NewReader(bytes.NewReader(collector.bodies[0]))
+		if err != nil {
+			t.Fatalf("expected a gzipped report: %v", err)
+		}
+		report, _ := io.ReadAll(zr)
+
+		var keys []string
+		var operationCount uint64
+		for len(report) > 0 {
+			num, typ, n := protowire.ConsumeTag(report)
+			report = report[n:]
+			switch {
+			case num == 5 && typ == protowire.BytesType:
+				entry, n := protowire.ConsumeBytes(report)
+				report = report[n:]
+				_, _, tn := protowire.ConsumeTag(entry)
+				key, _ := protowire.ConsumeString(entry[tn:])
+				keys = append(keys, key)
+			case num == 6 && typ == protowire.VarintType:
+				v, n := protowire.ConsumeVarint(report)
+				report = report[n:]
+				operationCount = v
+			default:
+				report = report[protowire.ConsumeFieldValue(num, typ, report):]
+			}
+		}
+		if len(keys) != 1 || !strings.HasPrefix(keys[0], "# Product\n") || operationCount != 2 {
+			t.Errorf("expected 2 operations under one Product signature, got %q and %d", keys, operationCount)
+		}
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		_, err := gateway.NewGateway(gateway.GatewayOption{
+			Endpoint:       "/graphql",
+			Services:       []gateway.GatewayService{{Name: "products", Host: products.URL}},
+			UsageReporting: gateway.UsageReportingOption{Enable: true, Target: "datadog"},
+		})
+		if err == nil || !strings.Contains(err.Error(), `unknown usage reporting target "datadog"`) {
+			t.Fatalf("expected an unknown target error, got %v", err)
+		}
+	})
+}
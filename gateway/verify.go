@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/n9te9/go-graphql-federation-gateway/federation/executor"
+)
+
+// newResponseVerifier returns the verifier of subgraph responses, or nil when
+// verify_subgraph_responses is off. Mismatches are logged and counted in the
+// graphql.subgraph.response.mismatch metric; responses are served unchanged.
+func newResponseVerifier(settings GatewayOption, labels metric.MeasurementOption) (*executor.ResponseVerifier, error) {
+	if !settings.VerifySubgraphResponses {
+		return nil, nil
+	}
+	counter, err := otel.Meter("github.com/n9te9/go-graphql-federation-gateway").Int64Counter(
+		"graphql.subgraph.response.mismatch",
+		metric.WithDescription("Number of subgraph response values their subgraph schema does not allow"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create response mismatch counter: %w", err)
+	}
+	return executor.NewResponseVerifier(func(ctx context.Context, mismatch executor.ResponseMismatch) {
+		log.Printf("response of subgraph %q does not match its schema at %v: %s", mismatch.SubGraph, mismatch.Path, mismatch.Reason)
+		counter.Add(context.WithoutCancel(ctx), 1,
+			metric.WithAttributes(attribute.String("graphql.subgraph.name", mismatch.SubGraph)),
+			labels,
+		)
+	}), nil
+}
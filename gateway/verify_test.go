@@ -0,0 +1,108 @@
+package gateway_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+)
+
+func TestGateway_VerifySubgraphResponses(t *testing.T) {
+	const productsSDL = `
+		enum Color { RED BLUE }
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String!
+			color: Color
+			tags: [String!]
+		}
+
+		type Query {
+			product: Product
+		}
+	`
+	const reviewsSDL = `
+		extend type Product @key(fields: "id") {
+			id: ID! @external
+			rating: Int!
+		}
+	`
+	products := newSubgraphServer(t, productsSDL, func(body map[string]any) any {
+		// The subgraph drifted: name became nullable, color gained a value, tags is a
+		// string and a field was added that the SDL does not declare.
+		return map[string]any{"data": map[string]any{"product": map[string]any{
+			"__typename": "Product", "id": "1", "name": nil, "color": "GREEN", "tags": "sale", "stock": 3,
+		}}}
+	})
+	reviews := newSubgraphServer(t, reviewsSDL, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"_entities": []any{
+			map[string]any{"__typename": "Product", "rating": 4.5},
+		}}}
+	})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint: "/graphql",
+		Services: []gateway.GatewayService{
+			{Name: "products", Host: products.URL},
+			{Name: "reviews", Host: reviews.URL},
+		},
+		VerifySubgraphResponses: true,
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product { name color tags rating } }"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	for _, want := range []string{
+		`"products" does not match its schema at [product name]: null for non-null type String!`,
+		`"products" does not match its schema at [product color]: invalid Color value`,
+		`"products" does not match its schema at [product tags]: expected a list for type [String!]`,
+		`"products" does not match its schema at [product stock]: field stock is not defined on type Product`,
+		`"reviews" does not match its schema at [_entities 0 rating]: invalid Int value`,
+		`"reviews" does not match its schema at [_entities 0 id]: selected field id is missing`,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected the mismatch %q to be logged, got:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestGateway_VerifySubgraphResponsesMatching(t *testing.T) {
+	products := newSubgraphServer(t, sdlProducts, func(body map[string]any) any {
+		return map[string]any{"data": map[string]any{"product": map[string]any{"__typename": "Product", "id": "1", "name": nil}}}
+	})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	gw, err := gateway.NewGateway(gateway.GatewayOption{
+		Endpoint:                "/graphql",
+		Services:                []gateway.GatewayService{{Name: "products", Host: products.URL}},
+		VerifySubgraphResponses: true,
+	})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ product(id: \"1\") { id name } }"}`)))
+	if strings.Contains(logs.String(), "does not match its schema") {
+		t.Errorf("expected no mismatch for a response matching the schema, got:\n%s", logs.String())
+	}
+}
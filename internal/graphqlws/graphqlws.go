@@ -0,0 +1,87 @@
+// Package graphqlws defines the messages of the graphql-transport-ws protocol, used to
+// run GraphQL operations, subscriptions in particular, over a WebSocket.
+// See https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+package graphqlws
+
+import (
+	"bytes"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/internal/websocket"
+)
+
+// Subprotocol is the WebSocket subprotocol of graphql-transport-ws.
+const Subprotocol = "graphql-transport-ws"
+
+// Message types.
+const (
+	ConnectionInit = "connection_init"
+	ConnectionAck  = "connection_ack"
+	Ping           = "ping"
+	Pong           = "pong"
+	Subscribe      = "subscribe"
+	Next           = "next"
+	Error          = "error"
+	Complete       = "complete"
+)
+
+// Close codes.
+const (
+	CloseBadRequest               = 4400
+	CloseUnauthorized             = 4401
+	CloseForbidden                = 4403
+	CloseSubprotocolNotAcceptable = 4406
+	CloseInitTimeout              = 4408
+	CloseSubscriberExists         = 4409
+	CloseTooManyInitRequests      = 4429
+)
+
+// Message is a graphql-transport-ws message. Payload is decoded with numbers kept as
+// json.Number.
+type Message struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Payload any    `json:"payload,omitempty"`
+}
+
+// SubscribePayload is the payload of a subscribe message.
+type SubscribePayload struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// Read reads the next message of conn.
+func Read(conn *websocket.Conn) (Message, error) {
+	var msg Message
+	data, err := conn.ReadMessage()
+	if err != nil {
+		return msg, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = dec.Decode(&msg)
+	return msg, err
+}
+
+// Write sends msg over conn.
+func Write(conn *websocket.Conn, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(data)
+}
+
+// DecodePayload decodes payload, as read by Read, into v.
+func DecodePayload(payload any, v any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
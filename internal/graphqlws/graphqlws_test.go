@@ -0,0 +1,175 @@
+package graphqlws_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/internal/graphqlws"
+	"github.com/n9te9/go-graphql-federation-gateway/internal/websocket"
+)
+
+// newServer returns a server running each subscription it receives by sending its
+// variables back in two next messages before completing it.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, []string{graphqlws.Subprotocol})
+		if err != nil {
+			return
+		}
+		if conn.Subprotocol() != graphqlws.Subprotocol {
+			conn.Close(graphqlws.CloseSubprotocolNotAcceptable, "subprotocol not acceptable") //nolint:errcheck
+			return
+		}
+
+		msg, err := graphqlws.Read(conn)
+		if err != nil || msg.Type != graphqlws.ConnectionInit {
+			conn.Close(graphqlws.CloseUnauthorized, "unauthorized") //nolint:errcheck
+			return
+		}
+		graphqlws.Write(conn, graphqlws.Message{Type: graphqlws.ConnectionAck}) //nolint:errcheck
+
+		for {
+			msg, err := graphqlws.Read(conn)
+			if err != nil {
+				return
+			}
+			switch msg.Type {
+			case graphqlws.Ping:
+				graphqlws.Write(conn, graphqlws.Message{Type: graphqlws.Pong}) //nolint:errcheck
+			case graphqlws.Subscribe:
+				var payload graphqlws.SubscribePayload
+				if err := graphqlws.DecodePayload(msg.Payload, &payload); err != nil || payload.Query == "" {
+					conn.Close(graphqlws.CloseBadRequest, "invalid subscribe payload") //nolint:errcheck
+					return
+				}
+				for i := 0; i < 2; i++ {
+					graphqlws.Write(conn, graphqlws.Message{ID: msg.ID, Type: graphqlws.Next, Payload: map[string]any{ //nolint:errcheck
+						"data": map[string]any{"index": i, "variables": payload.Variables},
+					}})
+				}
+				graphqlws.Write(conn, graphqlws.Message{ID: msg.ID, Type: graphqlws.Complete}) //nolint:errcheck
+			default:
+				conn.Close(graphqlws.CloseBadRequest, "unexpected "+msg.Type) //nolint:errcheck
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), []string{graphqlws.Subprotocol}, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))       //nolint:errcheck
+	t.Cleanup(func() { conn.Close(websocket.CloseNormal, "") }) //nolint:errcheck
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn) graphqlws.Message {
+	t.Helper()
+	msg, err := graphqlws.Read(conn)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	return msg
+}
+
+func TestMessageFlow(t *testing.T) {
+	conn := dial(t, newServer(t))
+
+	if err := graphqlws.Write(conn, graphqlws.Message{Type: graphqlws.ConnectionInit, Payload: map[string]any{"token": "t"}}); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if msg := read(t, conn); msg.Type != graphqlws.ConnectionAck {
+		t.Fatalf("expected %s, got %s", graphqlws.ConnectionAck, msg.Type)
+	}
+
+	graphqlws.Write(conn, graphqlws.Message{Type: graphqlws.Ping}) //nolint:errcheck
+	if msg := read(t, conn); msg.Type != graphqlws.Pong {
+		t.Fatalf("expected %s, got %s", graphqlws.Pong, msg.Type)
+	}
+
+	graphqlws.Write(conn, graphqlws.Message{ID: "1", Type: graphqlws.Subscribe, Payload: graphqlws.SubscribePayload{ //nolint:errcheck
+		Query:     `subscription ($id: ID!) { reviewAdded(productId: $id) { body } }`,
+		Variables: map[string]any{"id": "p1", "limit": 9007199254740993},
+	}})
+	for i := 0; i < 2; i++ {
+		msg := read(t, conn)
+		if msg.ID != "1" || msg.Type != graphqlws.Next {
+			t.Fatalf("expected next for 1, got %s for %q", msg.Type, msg.ID)
+		}
+		var payload struct {
+			Data struct {
+				Index     json.Number    `json:"index"`
+				Variables map[string]any `json:"variables"`
+			} `json:"data"`
+		}
+		if err := graphqlws.DecodePayload(msg.Payload, &payload); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		if payload.Data.Index.String() != []string{"0", "1"}[i] {
+			t.Errorf("expected index %d, got %s", i, payload.Data.Index)
+		}
+		// Numbers are kept as json.Number, so that large integers keep their precision.
+		if got := payload.Data.Variables["limit"]; got != json.Number("9007199254740993") {
+			t.Errorf("expected limit 9007199254740993, got %v (%T)", got, got)
+		}
+	}
+	if msg := read(t, conn); msg.ID != "1" || msg.Type != graphqlws.Complete {
+		t.Fatalf("expected complete for 1, got %s for %q", msg.Type, msg.ID)
+	}
+}
+
+func TestMessageFlow_SubscribeBeforeInit(t *testing.T) {
+	conn := dial(t, newServer(t))
+
+	graphqlws.Write(conn, graphqlws.Message{ID: "1", Type: graphqlws.Subscribe, Payload: graphqlws.SubscribePayload{Query: `subscription { a }`}}) //nolint:errcheck
+
+	_, err := graphqlws.Read(conn)
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != graphqlws.CloseUnauthorized {
+		t.Fatalf("expected the connection to be closed with %d, got %v", graphqlws.CloseUnauthorized, err)
+	}
+}
+
+func TestRead_InvalidJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, []string{graphqlws.Subprotocol})
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.CloseNormal, "") //nolint:errcheck
+		conn.WriteMessage([]byte(`{"type":`))       //nolint:errcheck
+		conn.ReadMessage()                          //nolint:errcheck
+	}))
+	defer srv.Close()
+	conn := dial(t, srv)
+
+	if _, err := graphqlws.Read(conn); err == nil {
+		t.Fatal("expected an error for a message that is not JSON")
+	}
+}
+
+func TestMessage_OmitsEmptyFields(t *testing.T) {
+	b, err := json.Marshal(graphqlws.Message{Type: graphqlws.ConnectionAck})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"type":"connection_ack"}` {
+		t.Errorf("expected %s, got %s", `{"type":"connection_ack"}`, b)
+	}
+}
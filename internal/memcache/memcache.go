@@ -0,0 +1,121 @@
+// Package memcache implements the get and set commands of the memcached text protocol.
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds a command when the context has no deadline.
+const commandTimeout = 5 * time.Second
+
+// Conn is a connection to a memcached server.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Dial connects to the memcached server at addr.
+func Dial(ctx context.Context, addr string) (*Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to memcached at %s: %w", addr, err)
+	}
+	return &Conn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// setDeadline bounds the next command by the deadline of ctx.
+func (c *Conn) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(commandTimeout)
+	}
+	c.conn.SetDeadline(deadline) //nolint:errcheck
+}
+
+// Get returns the value stored under key, and whether there is one.
+func (c *Conn) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.setDeadline(ctx)
+	defer c.conn.SetDeadline(time.Time{}) //nolint:errcheck
+
+	if _, err := fmt.Fprintf(c.conn, "get %s\r\n", key); err != nil {
+		return nil, false, err
+	}
+
+	var value []byte
+	found := false
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, false, err
+		}
+		if line == "END" {
+			return value, found, nil
+		}
+		// VALUE <key> <flags> <bytes> [<cas unique>]
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "VALUE" {
+			return nil, false, fmt.Errorf("memcached: unexpected reply %q", line)
+		}
+		n, err := strconv.Atoi(fields[3])
+		if err != nil || n < 0 {
+			return nil, false, fmt.Errorf("memcached: malformed value length %q", fields[3])
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, false, err
+		}
+		value, found = buf[:n], true
+	}
+}
+
+// Set stores value under key. A positive ttl expires it, rounded up to whole seconds.
+func (c *Conn) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.setDeadline(ctx)
+	defer c.conn.SetDeadline(time.Time{}) //nolint:errcheck
+
+	exptime := 0
+	if ttl > 0 {
+		exptime = int((ttl + time.Second - 1) / time.Second)
+	}
+	buf := fmt.Appendf(nil, "set %s 0 %d %d\r\n", key, exptime, len(value))
+	buf = append(buf, value...)
+	buf = append(buf, '\r', '\n')
+	if _, err := c.conn.Write(buf); err != nil {
+		return err
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if line != "STORED" {
+		return fmt.Errorf("memcached: set %s failed: %s", key, line)
+	}
+	return nil
+}
+
+// readLine reads a reply line without its CRLF. Error replies are returned as errors.
+func (c *Conn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR") || strings.HasPrefix(line, "SERVER_ERROR") {
+		return "", fmt.Errorf("memcached: %s", line)
+	}
+	return line, nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
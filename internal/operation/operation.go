@@ -0,0 +1,66 @@
+// Package operation selects the operation of a GraphQL request document. It is shared by
+// the gateway and the federation library API.
+package operation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/n9te9/graphql-parser/ast"
+)
+
+// ErrNameRequired is returned when a document holds several operations and the request
+// does not say which one to run.
+var ErrNameRequired = errors.New("must provide operation name if query contains multiple operations")
+
+// Select picks the operation to execute from doc by operationName and returns a document
+// holding only that operation plus every fragment, so that planning, execution and
+// pruning never see the other operations.
+func Select(doc *ast.Document, operationName string) (*ast.Document, *ast.OperationDefinition, error) {
+	ops := make([]*ast.OperationDefinition, 0, 1)
+	others := make([]ast.Definition, 0)
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			ops = append(ops, op)
+			continue
+		}
+		others = append(others, def)
+	}
+
+	var selected *ast.OperationDefinition
+	switch {
+	case len(ops) == 0:
+		return nil, nil, errors.New("no operation found")
+	case operationName == "" && len(ops) > 1:
+		return nil, nil, ErrNameRequired
+	case operationName == "":
+		selected = ops[0]
+	default:
+		for _, op := range ops {
+			if Name(op) == operationName {
+				selected = op
+				break
+			}
+		}
+		if selected == nil {
+			return nil, nil, fmt.Errorf("unknown operation named %q", operationName)
+		}
+	}
+
+	if len(ops) == 1 {
+		return doc, selected, nil
+	}
+
+	definitions := make([]ast.Definition, 0, len(others)+1)
+	definitions = append(definitions, selected)
+	definitions = append(definitions, others...)
+	return &ast.Document{Definitions: definitions}, selected, nil
+}
+
+// Name returns the name of op, or "" for anonymous operations.
+func Name(op *ast.OperationDefinition) string {
+	if op == nil || op.Name == nil {
+		return ""
+	}
+	return op.Name.String()
+}
@@ -0,0 +1,199 @@
+// Package redis implements a minimal client of the Redis serialization protocol (RESP2),
+// shared by the schema registry and the plan cache.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// commandTimeout bounds a Redis command when the context has no deadline.
+const commandTimeout = 5 * time.Second
+
+// Error is an error reply from Redis.
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// TLSConfig returns the TLS config of the connections to addr. The server name defaults
+// to the host of addr; caFile, when set, holds the PEM certificates trusted instead of
+// the system roots.
+func TLSConfig(addr, serverName, caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec // opt-in for testing
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis addr %q: %w", addr, err)
+		}
+		cfg.ServerName = host
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis CA file %s contains no certificates", caFile)
+		}
+	}
+	return cfg, nil
+}
+
+// Conn is a RESP client connection. Replies are decoded to string
+// (simple and bulk strings), nil (null bulk string or array), int64 and []interface{}.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// DialOptions describe how to connect and authenticate to Redis.
+type DialOptions struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	TLS      *tls.Config // nil for plain TCP
+}
+
+// Dial connects to opts.Addr, authenticating and selecting the db when set.
+func Dial(ctx context.Context, opts DialOptions) (*Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", opts.Addr, err)
+	}
+	if opts.TLS != nil {
+		tlsConn := tls.Client(conn, opts.TLS)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed TLS handshake with redis at %s: %w", opts.Addr, err)
+		}
+		conn = tlsConn
+	}
+	c := &Conn{conn: conn, r: bufio.NewReader(conn)}
+
+	if opts.Password != "" {
+		args := []string{"AUTH", opts.Password}
+		if opts.Username != "" {
+			args = []string{"AUTH", opts.Username, opts.Password}
+		}
+		if _, err := c.Do(ctx, args...); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if opts.DB != 0 {
+		if _, err := c.Do(ctx, "SELECT", strconv.Itoa(opts.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Do sends a command and reads its reply.
+func (c *Conn) Do(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(commandTimeout)
+	}
+	c.conn.SetDeadline(deadline)          //nolint:errcheck
+	defer c.conn.SetDeadline(time.Time{}) //nolint:errcheck
+
+	if err := c.Write(args...); err != nil {
+		return nil, err
+	}
+	return c.Read()
+}
+
+// Write sends a command as an array of bulk strings.
+func (c *Conn) Write(args ...string) error {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// Read decodes one reply. Error replies are returned as Error.
+func (c *Conn) Read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, Error(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := c.Read()
+			var redisErr Error
+			if err != nil && !errors.As(err, &redisErr) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
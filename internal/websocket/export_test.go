@@ -0,0 +1,12 @@
+package websocket
+
+import (
+	"bufio"
+	"net"
+)
+
+// NewConnForTest returns a connection over conn, masking the frames it sends when
+// client is set, as if its handshake had completed.
+func NewConnForTest(conn net.Conn, client bool) *Conn {
+	return &Conn{conn: conn, br: bufio.NewReader(conn), client: client, MaxMessageSize: DefaultMaxMessageSize}
+}
@@ -0,0 +1,459 @@
+// Package websocket implements the subset of RFC 6455 used to serve and consume GraphQL
+// subscriptions: the opening handshake, text messages, ping and pong, and the closing
+// handshake. No extension (e.g. permessage-deflate) is negotiated.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Close codes used by the gateway; applications define their own from 4000.
+const (
+	CloseNormal         = 1000
+	CloseGoingAway      = 1001
+	CloseProtocolError  = 1002
+	CloseMessageTooBig  = 1009
+	CloseServiceRestart = 1012
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// acceptGUID is appended to the client key to compute Sec-WebSocket-Accept.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// DefaultMaxMessageSize bounds the messages read from a connection.
+const DefaultMaxMessageSize = 16 << 20
+
+// CloseError is returned by ReadMessage once the peer closed the connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed with %d %s", e.Code, e.Reason)
+}
+
+// Conn is a WebSocket connection. ReadMessage must be called from one goroutine at a
+// time; WriteMessage, Ping and Close are safe for concurrent use.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	client   bool // Frames sent by a client are masked
+	protocol string
+
+	// MaxMessageSize bounds the size of a message read; larger messages close the
+	// connection with CloseMessageTooBig.
+	MaxMessageSize int64
+
+	wmu    sync.Mutex
+	closed bool // A close frame was sent
+}
+
+// Upgrade answers the opening handshake of r and returns the connection. The
+// subprotocol is the first one offered by the client that protocols lists; it is empty
+// when none is. Failed handshakes are answered with 400 Bad Request.
+func Upgrade(w http.ResponseWriter, r *http.Request, protocols []string) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !IsUpgrade(r) || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "bad websocket handshake", http.StatusBadRequest)
+		return nil, errors.New("bad websocket handshake")
+	}
+
+	var protocol string
+	for _, offered := range headerTokens(r.Header, "Sec-WebSocket-Protocol") {
+		for _, p := range protocols {
+			if protocol == "" && offered == p {
+				protocol = p
+			}
+		}
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("response writer cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	resp.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if protocol != "" {
+		resp.WriteString("Sec-WebSocket-Protocol: " + protocol + "\r\n")
+	}
+	resp.WriteString("\r\n")
+	if _, err := conn.Write([]byte(resp.String())); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+
+	return &Conn{conn: conn, br: rw.Reader, protocol: protocol, MaxMessageSize: DefaultMaxMessageSize}, nil
+}
+
+// IsUpgrade reports whether r asks to switch to the WebSocket protocol.
+func IsUpgrade(r *http.Request) bool {
+	upgrade := false
+	for _, token := range headerTokens(r.Header, "Connection") {
+		if strings.EqualFold(token, "upgrade") {
+			upgrade = true
+		}
+	}
+	return upgrade && strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// Dial opens a connection to the ws:// or wss:// URL rawURL, offering protocols and
+// sending header with the handshake. ctx bounds the handshake only.
+func Dial(ctx context.Context, rawURL string, protocols []string, header http.Header) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid websocket URL %q: %w", rawURL, err)
+	}
+	host := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, fmt.Errorf("invalid websocket URL %q: scheme must be ws or wss", rawURL)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", host, err)
+	}
+	if u.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("tls handshake with %s failed: %w", host, err)
+		}
+		conn = tlsConn
+	}
+
+	c, err := handshake(ctx, conn, u, protocols, header)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// handshake sends the opening handshake of a client over conn.
+func handshake(ctx context.Context, conn net.Conn, u *url.URL, protocols []string, header http.Header) (*Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)          //nolint:errcheck
+		defer conn.SetDeadline(time.Time{}) //nolint:errcheck
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(protocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(protocols, ", "))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handshake response: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("websocket handshake failed with status %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, errors.New("websocket handshake failed: invalid Sec-WebSocket-Accept")
+	}
+
+	return &Conn{
+		conn:           conn,
+		br:             br,
+		client:         true,
+		protocol:       resp.Header.Get("Sec-WebSocket-Protocol"),
+		MaxMessageSize: DefaultMaxMessageSize,
+	}, nil
+}
+
+// Subprotocol returns the subprotocol negotiated by the handshake.
+func (c *Conn) Subprotocol() string {
+	return c.protocol
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline of the reads of ReadMessage.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered and pongs
+// skipped while waiting. A close frame from the peer is answered and returned as a
+// *CloseError.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeErr := &CloseError{Code: 1005}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.Close(closeErr.Code, "") //nolint:errcheck
+			return nil, closeErr
+		case opText, opBinary:
+			if started {
+				c.Close(CloseProtocolError, "expected a continuation frame") //nolint:errcheck
+				return nil, errors.New("websocket: expected a continuation frame")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				c.Close(CloseProtocolError, "unexpected continuation frame") //nolint:errcheck
+				return nil, errors.New("websocket: unexpected continuation frame")
+			}
+		default:
+			c.Close(CloseProtocolError, "unknown opcode") //nolint:errcheck
+			return nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+
+		if int64(len(message)+len(payload)) > c.MaxMessageSize {
+			c.Close(CloseMessageTooBig, "message too big") //nolint:errcheck
+			return nil, errors.New("websocket: message too big")
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame reads one frame, unmasking its payload.
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+
+	if head[0]&0x70 != 0 {
+		c.Close(CloseProtocolError, "reserved bits set") //nolint:errcheck
+		return false, 0, nil, errors.New("websocket: reserved bits set")
+	}
+	// Clients mask every frame and servers none.
+	if masked == c.client {
+		c.Close(CloseProtocolError, "invalid masking") //nolint:errcheck
+		return false, 0, nil, errors.New("websocket: invalid masking")
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if op >= opClose && (length > 125 || !fin) {
+		c.Close(CloseProtocolError, "invalid control frame") //nolint:errcheck
+		return false, 0, nil, errors.New("websocket: invalid control frame")
+	}
+	if length < 0 || length > c.MaxMessageSize {
+		c.Close(CloseMessageTooBig, "message too big") //nolint:errcheck
+		return false, 0, nil, errors.New("websocket: message too big")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// WriteMessage sends data as a text message.
+func (c *Conn) WriteMessage(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// Ping sends a ping frame.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// writeFrame sends payload in a single frame of op.
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return errors.New("websocket: connection closed")
+	}
+	return c.writeFrameLocked(op, payload)
+}
+
+func (c *Conn) writeFrameLocked(op byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|op)
+
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close sends a close frame with code and reason, unless one was already sent, and
+// closes the underlying connection.
+func (c *Conn) Close(code int, reason string) error {
+	c.wmu.Lock()
+	if !c.closed {
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+		if len(payload) > 125 {
+			payload = payload[:125]
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second)) //nolint:errcheck
+		c.writeFrameLocked(opClose, payload)                 //nolint:errcheck
+		c.closed = true
+	}
+	c.wmu.Unlock()
+	return c.conn.Close()
+}
+
+// acceptKey returns the Sec-WebSocket-Accept value answering key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerTokens returns the comma-separated tokens of the header name.
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+	for _, value := range header.Values(name) {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
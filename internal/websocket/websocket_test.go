@@ -0,0 +1,490 @@
+package websocket_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/internal/websocket"
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// rawFrame is a frame as written on the wire.
+type rawFrame struct {
+	fin     bool
+	op      byte
+	payload []byte
+	mask    []byte // Masks the payload when set
+}
+
+func (f rawFrame) bytes() []byte {
+	var b []byte
+	head := f.op
+	if f.fin {
+		head |= 0x80
+	}
+	b = append(b, head)
+	maskBit := byte(0)
+	if f.mask != nil {
+		maskBit = 0x80
+	}
+	switch n := len(f.payload); {
+	case n <= 125:
+		b = append(b, maskBit|byte(n))
+	case n <= 0xffff:
+		b = append(b, maskBit|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, maskBit|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	if f.mask == nil {
+		return append(b, f.payload...)
+	}
+	b = append(b, f.mask...)
+	for i, c := range f.payload {
+		b = append(b, c^f.mask[i%4])
+	}
+	return b
+}
+
+var testMask = []byte{0x12, 0x34, 0x56, 0x78}
+
+// connPair returns a connection, reading frames as a server or a client, and the raw
+// end of its peer.
+func connPair(t *testing.T, client bool) (*websocket.Conn, net.Conn, *bufio.Reader) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+	peer, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, ok := <-accepted
+	if !ok {
+		t.Fatal("failed to accept connection")
+	}
+	c := websocket.NewConnForTest(conn, client)
+	t.Cleanup(func() {
+		c.Close(websocket.CloseNormal, "") //nolint:errcheck
+		peer.Close()
+	})
+	peer.SetDeadline(time.Now().Add(5 * time.Second))  //nolint:errcheck
+	c.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	return c, peer, bufio.NewReader(peer)
+}
+
+// writeFrames writes frames to peer without waiting for them to be read.
+func writeFrames(peer net.Conn, frames ...rawFrame) {
+	var b []byte
+	for _, f := range frames {
+		b = append(b, f.bytes()...)
+	}
+	go peer.Write(b) //nolint:errcheck
+}
+
+// readFrame reads a frame sent to the peer, unmasking its payload.
+func readFrame(t *testing.T, br *bufio.Reader) rawFrame {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	f := rawFrame{fin: head[0]&0x80 != 0, op: head[0] & 0x0f}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(br, ext[:]) //nolint:errcheck
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(br, ext[:]) //nolint:errcheck
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if head[1]&0x80 != 0 {
+		f.mask = make([]byte, 4)
+		io.ReadFull(br, f.mask) //nolint:errcheck
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(br, f.payload); err != nil {
+		t.Fatalf("failed to read frame payload: %v", err)
+	}
+	for i := range f.payload {
+		if f.mask != nil {
+			f.payload[i] ^= f.mask[i%4]
+		}
+	}
+	return f
+}
+
+// expectClose reads the close frame sent to the peer and checks its code.
+func expectClose(t *testing.T, br *bufio.Reader, code int) {
+	t.Helper()
+	f := readFrame(t, br)
+	if f.op != opClose || len(f.payload) < 2 {
+		t.Fatalf("expected a close frame, got opcode %d with %q", f.op, f.payload)
+	}
+	if got := int(binary.BigEndian.Uint16(f.payload)); got != code {
+		t.Fatalf("expected close code %d, got %d (%s)", code, got, f.payload[2:])
+	}
+}
+
+func TestConn_ReadMessage(t *testing.T) {
+	medium := bytes.Repeat([]byte("m"), 300)
+	large := bytes.Repeat([]byte("l"), 70000)
+
+	tests := []struct {
+		name   string
+		frames []rawFrame
+		want   []byte
+	}{
+		{
+			name:   "masked text frame",
+			frames: []rawFrame{{fin: true, op: opText, payload: []byte(`{"type":"ping"}`), mask: testMask}},
+			want:   []byte(`{"type":"ping"}`),
+		},
+		{
+			name:   "16-bit length",
+			frames: []rawFrame{{fin: true, op: opText, payload: medium, mask: testMask}},
+			want:   medium,
+		},
+		{
+			name:   "64-bit length",
+			frames: []rawFrame{{fin: true, op: opText, payload: large, mask: testMask}},
+			want:   large,
+		},
+		{
+			name: "fragmented message",
+			frames: []rawFrame{
+				{op: opText, payload: []byte("hel"), mask: testMask},
+				{op: opContinuation, payload: []byte("lo, "), mask: testMask},
+				{fin: true, op: opContinuation, payload: []byte("world"), mask: testMask},
+			},
+			want: []byte("hello, world"),
+		},
+		{
+			name: "pong between fragments",
+			frames: []rawFrame{
+				{op: opText, payload: []byte("a"), mask: testMask},
+				{fin: true, op: opPong, mask: testMask},
+				{fin: true, op: opContinuation, payload: []byte("b"), mask: testMask},
+			},
+			want: []byte("ab"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, peer, _ := connPair(t, false)
+			writeFrames(peer, tt.frames...)
+
+			got, err := c.ReadMessage()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("expected a message of %d bytes, got %d bytes", len(tt.want), len(got))
+			}
+		})
+	}
+}
+
+func TestConn_ReadMessage_Ping(t *testing.T) {
+	c, peer, br := connPair(t, false)
+	writeFrames(peer,
+		rawFrame{op: opText, payload: []byte("a"), mask: testMask},
+		rawFrame{fin: true, op: opPing, payload: []byte("keep-alive"), mask: testMask},
+		rawFrame{fin: true, op: opContinuation, payload: []byte("b"), mask: testMask},
+	)
+
+	got, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "ab" {
+		t.Errorf("expected %q, got %q", "ab", got)
+	}
+
+	pong := readFrame(t, br)
+	if pong.op != opPong || string(pong.payload) != "keep-alive" {
+		t.Errorf("expected a pong echoing the ping, got opcode %d with %q", pong.op, pong.payload)
+	}
+	if pong.mask != nil {
+		t.Error("expected the frames of a server to be unmasked")
+	}
+}
+
+func TestConn_ReadMessage_Close(t *testing.T) {
+	c, peer, br := connPair(t, false)
+	payload := binary.BigEndian.AppendUint16(nil, websocket.CloseGoingAway)
+	writeFrames(peer, rawFrame{fin: true, op: opClose, payload: append(payload, "bye"...), mask: testMask})
+
+	_, err := c.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		t.Fatalf("expected a *CloseError, got %v", err)
+	}
+	if closeErr.Code != websocket.CloseGoingAway || closeErr.Reason != "bye" {
+		t.Errorf("expected 1001 bye, got %d %s", closeErr.Code, closeErr.Reason)
+	}
+	expectClose(t, br, websocket.CloseGoingAway)
+
+	if err := c.WriteMessage([]byte("late")); err == nil {
+		t.Error("expected writes after the closing handshake to fail")
+	}
+}
+
+func TestConn_ReadMessage_CloseWithoutCode(t *testing.T) {
+	c, peer, _ := connPair(t, false)
+	writeFrames(peer, rawFrame{fin: true, op: opClose, mask: testMask})
+
+	_, err := c.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != 1005 {
+		t.Fatalf("expected a *CloseError with 1005, got %v", err)
+	}
+}
+
+func TestConn_ReadMessage_ProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  bool
+		frames  []rawFrame
+		maxSize int64
+		code    int
+	}{
+		{
+			name:   "unmasked frame from a client",
+			frames: []rawFrame{{fin: true, op: opText, payload: []byte("x")}},
+			code:   websocket.CloseProtocolError,
+		},
+		{
+			name:   "masked frame from a server",
+			client: true,
+			frames: []rawFrame{{fin: true, op: opText, payload: []byte("x"), mask: testMask}},
+			code:   websocket.CloseProtocolError,
+		},
+		{
+			name:   "reserved bits",
+			frames: []rawFrame{{fin: true, op: 0x40 | opText, payload: []byte("x"), mask: testMask}},
+			code:   websocket.CloseProtocolError,
+		},
+		{
+			name:   "unknown opcode",
+			frames: []rawFrame{{fin: true, op: 0x3, payload: []byte("x"), mask: testMask}},
+			code:   websocket.CloseProtocolError,
+		},
+		{
+			name:   "fragmented control frame",
+			frames: []rawFrame{{op: opPing, payload: []byte("x"), mask: testMask}},
+			code:   websocket.CloseProtocolError,
+		},
+		{
+			name:   "control frame over 125 bytes",
+			frames: []rawFrame{{fin: true, op: opPing, payload: bytes.Repeat([]byte("p"), 126), mask: testMask}},
+			code:   websocket.CloseProtocolError,
+		},
+		{
+			name:   "continuation without a message",
+			frames: []rawFrame{{fin: true, op: opContinuation, payload: []byte("x"), mask: testMask}},
+			code:   websocket.CloseProtocolError,
+		},
+		{
+			name: "new message inside a fragmented one",
+			frames: []rawFrame{
+				{op: opText, payload: []byte("a"), mask: testMask},
+				{fin: true, op: opText, payload: []byte("b"), mask: testMask},
+			},
+			code: websocket.CloseProtocolError,
+		},
+		{
+			name:    "oversized frame",
+			frames:  []rawFrame{{fin: true, op: opText, payload: bytes.Repeat([]byte("x"), 300), mask: testMask}},
+			maxSize: 256,
+			code:    websocket.CloseMessageTooBig,
+		},
+		{
+			name: "oversized fragmented message",
+			frames: []rawFrame{
+				{op: opText, payload: bytes.Repeat([]byte("x"), 200), mask: testMask},
+				{fin: true, op: opContinuation, payload: bytes.Repeat([]byte("x"), 200), mask: testMask},
+			},
+			maxSize: 256,
+			code:    websocket.CloseMessageTooBig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, peer, br := connPair(t, tt.client)
+			if tt.maxSize > 0 {
+				c.MaxMessageSize = tt.maxSize
+			}
+			writeFrames(peer, tt.frames...)
+
+			if _, err := c.ReadMessage(); err == nil {
+				t.Fatal("expected an error")
+			}
+			expectClose(t, br, tt.code)
+		})
+	}
+}
+
+func TestConn_ReadMessage_OversizedLengthIsNotAllocated(t *testing.T) {
+	c, peer, br := connPair(t, false)
+	// Only the header is sent: the length alone must close the connection.
+	head := []byte{0x80 | opText, 0x80 | 127}
+	head = binary.BigEndian.AppendUint64(head, 1<<62)
+	go peer.Write(head) //nolint:errcheck
+
+	if _, err := c.ReadMessage(); err == nil {
+		t.Fatal("expected an error")
+	}
+	expectClose(t, br, websocket.CloseMessageTooBig)
+}
+
+func TestConn_WriteMessage(t *testing.T) {
+	for _, size := range []int{5, 300, 70000} {
+		for _, client := range []bool{false, true} {
+			c, _, br := connPair(t, client)
+			payload := bytes.Repeat([]byte("w"), size)
+			go c.WriteMessage(payload) //nolint:errcheck
+
+			f := readFrame(t, br)
+			if !f.fin || f.op != opText {
+				t.Errorf("expected a final text frame, got fin %v and opcode %d", f.fin, f.op)
+			}
+			if masked := f.mask != nil; masked != client {
+				t.Errorf("expected masked to be %v for a client of %v, got %v", client, client, masked)
+			}
+			if !bytes.Equal(f.payload, payload) {
+				t.Errorf("expected a payload of %d bytes, got %d bytes", size, len(f.payload))
+			}
+		}
+	}
+}
+
+func TestConn_Ping(t *testing.T) {
+	c, _, br := connPair(t, false)
+	if err := c.Ping(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f := readFrame(t, br); f.op != opPing || len(f.payload) != 0 {
+		t.Errorf("expected an empty ping, got opcode %d with %q", f.op, f.payload)
+	}
+}
+
+func TestConn_CloseTruncatesReason(t *testing.T) {
+	c, _, br := connPair(t, false)
+	go c.Close(websocket.CloseServiceRestart, strings.Repeat("r", 200)) //nolint:errcheck
+
+	f := readFrame(t, br)
+	if f.op != opClose || len(f.payload) != 125 {
+		t.Errorf("expected a close frame of 125 bytes, got opcode %d with %d bytes", f.op, len(f.payload))
+	}
+}
+
+func TestDialAndUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, []string{"graphql-transport-ws"})
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.CloseNormal, "")
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(append([]byte("echo: "), msg...)); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), []string{"other", "graphql-transport-ws"}, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	if got := conn.Subprotocol(); got != "graphql-transport-ws" {
+		t.Errorf("expected subprotocol graphql-transport-ws, got %q", got)
+	}
+
+	large := strings.Repeat("x", 70000)
+	for _, msg := range []string{"hello", large} {
+		if err := conn.WriteMessage([]byte(msg)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if string(got) != "echo: "+msg {
+			t.Errorf("expected the echo of a message of %d bytes, got %d bytes", len(msg), len(got))
+		}
+	}
+
+	conn.Close(websocket.CloseNormal, "") //nolint:errcheck
+}
+
+func TestUpgrade_RejectsBadHandshakes(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+	}{
+		{name: "not an upgrade", method: http.MethodGet, header: map[string]string{"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "a2V5"}},
+		{name: "wrong version", method: http.MethodGet, header: map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "a2V5"}},
+		{name: "missing key", method: http.MethodGet, header: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13"}},
+		{name: "POST", method: http.MethodPost, header: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "a2V5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			if _, err := websocket.Upgrade(w, r, nil); err == nil {
+				t.Fatal("expected an error")
+			}
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
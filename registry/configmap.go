@@ -0,0 +1,107 @@
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// serviceAccountDir holds the credentials Kubernetes mounts into every pod.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// ConfigMapSource reads the SDL from a key of a Kubernetes ConfigMap through the API
+// server, so that schema updates are picked up without waiting for volume syncs.
+type ConfigMapSource struct {
+	Namespace string
+	ConfigMap string
+	Key       string
+	APIServer string
+	TokenFile string
+
+	Client *http.Client
+}
+
+// NewConfigMapSource creates a ConfigMap source. Empty values default to the in-cluster
+// configuration: the pod namespace, the API server from KUBERNETES_SERVICE_HOST and
+// KUBERNETES_SERVICE_PORT, and the service account token and CA certificate.
+func NewConfigMapSource(namespace, configMap, key, apiServer, tokenFile string) (*ConfigMapSource, error) {
+	if namespace == "" {
+		b, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("configmap source requires namespace outside a cluster: %w", err)
+		}
+		namespace = strings.TrimSpace(string(b))
+	}
+	if tokenFile == "" {
+		tokenFile = serviceAccountDir + "/token"
+	}
+
+	client := http.DefaultClient
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("configmap source requires api_server outside a cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+
+		ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cluster CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	}
+
+	return &ConfigMapSource{
+		Namespace: namespace,
+		ConfigMap: configMap,
+		Key:       key,
+		APIServer: apiServer,
+		TokenFile: tokenFile,
+		Client:    client,
+	}, nil
+}
+
+// Name implements SchemaSource.
+func (s *ConfigMapSource) Name() string {
+	return "configmap://" + s.Namespace + "/" + s.ConfigMap + "/" + s.Key
+}
+
+// Fetch implements SchemaSource.
+func (s *ConfigMapSource) Fetch(ctx context.Context) (string, error) {
+	rawURL := fmt.Sprintf("%s/api/v1/namespaces/%s/configmaps/%s",
+		strings.TrimSuffix(s.APIServer, "/"), url.PathEscape(s.Namespace), url.PathEscape(s.ConfigMap))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if b, err := os.ReadFile(s.TokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(b)))
+	}
+
+	body, err := doGet(s.Client, req)
+	if err != nil {
+		return "", err
+	}
+	var cm struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &cm); err != nil {
+		return "", fmt.Errorf("failed to decode ConfigMap: %w", err)
+	}
+	sdl, ok := cm.Data[s.Key]
+	if !ok || sdl == "" {
+		return "", fmt.Errorf("ConfigMap %s/%s has no key %q", s.Namespace, s.ConfigMap, s.Key)
+	}
+	return sdl, nil
+}
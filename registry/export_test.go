@@ -0,0 +1,11 @@
+package registry
+
+import "time"
+
+// SignV4ForTest exposes signV4 for external tests.
+var SignV4ForTest = signV4
+
+// SetNowForTest fixes the clock used to sign S3 requests.
+func (s *S3Source) SetNowForTest(now time.Time) {
+	s.now = func() time.Time { return now }
+}
@@ -0,0 +1,29 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// FileSource reads the SDL from a local file, e.g. a mounted ConfigMap volume.
+type FileSource struct {
+	Path string
+}
+
+// Name implements SchemaSource.
+func (s *FileSource) Name() string {
+	return "file://" + s.Path
+}
+
+// Fetch implements SchemaSource.
+func (s *FileSource) Fetch(ctx context.Context) (string, error) {
+	b, err := os.ReadFile(s.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema file: %w", err)
+	}
+	if len(b) == 0 {
+		return "", fmt.Errorf("empty schema file %s", s.Path)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// gceMetadataTokenURL serves access tokens for the default service account on GCP.
+const gceMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCSSource downloads the SDL from a Google Cloud Storage bucket through the JSON API.
+// The bearer token is read from TokenFile or the GOOGLE_OAUTH_ACCESS_TOKEN variable, or
+// requested from the GCE metadata server when MetadataToken is set; otherwise the object
+// must be public.
+type GCSSource struct {
+	Bucket        string
+	Object        string
+	Endpoint      string // Defaults to https://storage.googleapis.com
+	TokenFile     string
+	MetadataToken bool
+
+	Client *http.Client
+}
+
+// Name implements SchemaSource.
+func (s *GCSSource) Name() string {
+	return "gs://" + s.Bucket + "/" + s.Object
+}
+
+// Fetch implements SchemaSource.
+func (s *GCSSource) Fetch(ctx context.Context) (string, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	rawURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media",
+		strings.TrimSuffix(endpoint, "/"), url.PathEscape(s.Bucket), url.PathEscape(s.Object))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	token, err := s.token(ctx)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return doGet(s.Client, req)
+}
+
+// token returns the bearer token for the request, or "" for anonymous access.
+func (s *GCSSource) token(ctx context.Context) (string, error) {
+	if s.TokenFile != "" {
+		b, err := os.ReadFile(s.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	if !s.MetadataToken {
+		return "", nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gceMetadataTokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create metadata request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	body, err := doGet(s.Client, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch metadata token: %w", err)
+	}
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return "", fmt.Errorf("failed to decode metadata token: %w", err)
+	}
+	return resp.AccessToken, nil
+}
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPSource downloads the SDL with a GET request.
+type HTTPSource struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Name implements SchemaSource.
+func (s *HTTPSource) Name() string {
+	return s.URL
+}
+
+// Fetch implements SchemaSource.
+func (s *HTTPSource) Fetch(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	return doGet(s.Client, req)
+}
+
+// doGet sends req and returns the body of a 200 response.
+func doGet(client *http.Client, req *http.Request) (string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, req.URL.Redacted())
+	}
+	if len(body) == 0 {
+		return "", fmt.Errorf("empty schema returned from %s", req.URL.Redacted())
+	}
+	return string(body), nil
+}
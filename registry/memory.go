@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps schema versions in process memory. It gives a single gateway a
+// version history to roll back to; use RedisStore to share versions between replicas.
+type MemoryStore struct {
+	maxVersions int
+
+	mu          sync.Mutex
+	seq         int64
+	versions    []*SchemaVersion // oldest first
+	current     *SchemaVersion
+	subscribers map[chan struct{}]struct{} // signalled when the current version changes
+	now         func() time.Time
+}
+
+// NewMemoryStore creates a store keeping the last maxVersions versions (20 when not positive).
+func NewMemoryStore(maxVersions int) *MemoryStore {
+	if maxVersions <= 0 {
+		maxVersions = 20
+	}
+	return &MemoryStore{
+		maxVersions: maxVersions,
+		subscribers: make(map[chan struct{}]struct{}),
+		now:         time.Now,
+	}
+}
+
+// Publish implements Store.
+func (s *MemoryStore) Publish(ctx context.Context, sdls map[string]string) (*SchemaVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	v := newSchemaVersion(s.seq, copySDLs(sdls), s.now())
+	s.versions = append(s.versions, v)
+	if len(s.versions) > s.maxVersions {
+		s.versions = s.versions[len(s.versions)-s.maxVersions:]
+	}
+	s.makeCurrent(v)
+	return v, nil
+}
+
+// Current implements Store.
+func (s *MemoryStore) Current(ctx context.Context) (*SchemaVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current, nil
+}
+
+// Rollback implements Store.
+func (s *MemoryStore) Rollback(ctx context.Context, version int64) (*SchemaVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, v := range s.versions {
+		if v.Version == version {
+			s.makeCurrent(v)
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+}
+
+// History implements Store.
+func (s *MemoryStore) History(ctx context.Context) ([]*SchemaVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := make([]*SchemaVersion, 0, len(s.versions))
+	for i := len(s.versions) - 1; i >= 0; i-- {
+		history = append(history, s.versions[i])
+	}
+	return history, nil
+}
+
+// makeCurrent sets the current version and signals subscribers without blocking the
+// caller, as a pub/sub backend would. The caller must hold s.mu.
+func (s *MemoryStore) makeCurrent(v *SchemaVersion) {
+	s.current = v
+	for ch := range s.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default: // a signal is already pending
+		}
+	}
+}
+
+// Subscribe implements Store. Changes in quick succession may be coalesced, so fn
+// always receives the version that is current when it runs.
+func (s *MemoryStore) Subscribe(ctx context.Context, fn func(*SchemaVersion)) error {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ch:
+			s.mu.Lock()
+			v := s.current
+			s.mu.Unlock()
+			fn(v)
+		}
+	}
+}
+
+// Close implements Store.
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func copySDLs(sdls map[string]string) map[string]string {
+	copied := make(map[string]string, len(sdls))
+	for k, v := range sdls {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,76 @@
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+func TestMemoryStore_History(t *testing.T) {
+	store := registry.NewMemoryStore(2)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := store.Publish(ctx, map[string]string{"products": fmt.Sprintf("type Query { v%d: Int }", i)}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	history, err := store.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 3 || history[1].Version != 2 {
+		t.Fatalf("expected versions [3 2], got %v", history)
+	}
+	if history[0].Digests["products"] == "" || history[0].Digests["products"] == history[1].Digests["products"] {
+		t.Errorf("expected distinct per-subgraph digests, got %v and %v", history[0].Digests, history[1].Digests)
+	}
+
+	v, err := store.Rollback(ctx, 2)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if current, _ := store.Current(ctx); current != v {
+		t.Errorf("expected version 2 to be current, got %+v", current)
+	}
+	if _, err := store.Rollback(ctx, 1); !errors.Is(err, registry.ErrVersionNotFound) {
+		t.Errorf("expected ErrVersionNotFound for a trimmed version, got %v", err)
+	}
+}
+
+func TestMemoryStore_Subscribe(t *testing.T) {
+	store := registry.NewMemoryStore(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan int64, 10)
+	subscribed := make(chan struct{})
+	go func() {
+		close(subscribed)
+		store.Subscribe(ctx, func(v *registry.SchemaVersion) { received <- v.Version }) //nolint:errcheck
+	}()
+	<-subscribed
+	time.Sleep(10 * time.Millisecond)
+
+	store.Publish(ctx, map[string]string{"products": "type Query { a: Int }"}) //nolint:errcheck
+	store.Publish(ctx, map[string]string{"products": "type Query { b: Int }"}) //nolint:errcheck
+	store.Rollback(ctx, 1)                                                     //nolint:errcheck
+
+	// Notifications may be coalesced, but the last one carries the current version.
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case v := <-received:
+			if v == 1 && len(received) == 0 {
+				return
+			}
+		case <-deadline:
+			t.Fatal("expected the subscriber to observe the rollback to version 1")
+		}
+	}
+}
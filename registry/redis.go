@@ -0,0 +1,337 @@
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/internal/redis"
+)
+
+// RedisStore keeps schema versions in Redis and notifies replicas through pub/sub.
+// Keys, relative to the prefix:
+//
+//	seq           version counter
+//	version:<n>   JSON-encoded SchemaVersion
+//	current       number of the current version
+//	history       published version numbers, newest first
+//	updates       pub/sub channel carrying the number of each new current version
+type RedisStore struct {
+	dial        redis.DialOptions
+	tls         RedisTLSOption
+	prefix      string
+	maxVersions int
+
+	mu   sync.Mutex
+	conn *redis.Conn
+	now  func() time.Time
+}
+
+// NewRedisStore creates a Redis store. Connections are opened on first use.
+func NewRedisStore(opt StoreOption) *RedisStore {
+	prefix := opt.Prefix
+	if prefix == "" {
+		prefix = "gateway:schema"
+	}
+	maxVersions := opt.MaxVersions
+	if maxVersions <= 0 {
+		maxVersions = 20
+	}
+	return &RedisStore{
+		dial: redis.DialOptions{
+			Addr:     opt.Addr,
+			Username: opt.Username,
+			Password: opt.Password,
+			DB:       opt.DB,
+		},
+		tls:         opt.TLS,
+		prefix:      prefix,
+		maxVersions: maxVersions,
+		now:         time.Now,
+	}
+}
+
+// connect opens a connection to Redis, over TLS when enabled.
+func (s *RedisStore) connect(ctx context.Context) (*redis.Conn, error) {
+	opts := s.dial
+	tlsConfig, err := s.tls.config(opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	opts.TLS = tlsConfig
+	return redis.Dial(ctx, opts)
+}
+
+// config returns the TLS config of the connections to addr, or nil when TLS is disabled.
+func (o RedisTLSOption) config(addr string) (*tls.Config, error) {
+	if !o.Enable {
+		return nil, nil
+	}
+	return redis.TLSConfig(addr, o.ServerName, o.CAFile, o.InsecureSkipVerify)
+}
+
+func (s *RedisStore) key(name string) string {
+	return s.prefix + ":" + name
+}
+
+func (s *RedisStore) versionKey(version int64) string {
+	return s.key("version:" + strconv.FormatInt(version, 10))
+}
+
+// do runs a command on the shared connection, reconnecting after network errors.
+func (s *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.conn = conn
+	}
+	reply, err := s.conn.Do(ctx, args...)
+	var redisErr redis.Error
+	if err != nil && !errors.As(err, &redisErr) {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return reply, err
+}
+
+// Publish implements Store. The version is written before it becomes current, so
+// replicas never observe a partially published version.
+func (s *RedisStore) Publish(ctx context.Context, sdls map[string]string) (*SchemaVersion, error) {
+	reply, err := s.do(ctx, "INCR", s.key("seq"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate schema version: %w", err)
+	}
+	number, ok := reply.(int64)
+	if !ok {
+		return nil, fmt.Errorf("unexpected INCR reply %v", reply)
+	}
+
+	version := newSchemaVersion(number, sdls, s.now())
+	b, err := json.Marshal(version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema version: %w", err)
+	}
+	if _, err := s.do(ctx, "SET", s.versionKey(number), string(b)); err != nil {
+		return nil, fmt.Errorf("failed to store schema version %d: %w", number, err)
+	}
+	if _, err := s.do(ctx, "LPUSH", s.key("history"), strconv.FormatInt(number, 10)); err != nil {
+		return nil, fmt.Errorf("failed to record schema version %d: %w", number, err)
+	}
+	if err := s.trimHistory(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.makeCurrent(ctx, number); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// trimHistory drops the oldest versions beyond maxVersions.
+func (s *RedisStore) trimHistory(ctx context.Context) error {
+	for {
+		reply, err := s.do(ctx, "LLEN", s.key("history"))
+		if err != nil {
+			return fmt.Errorf("failed to read schema history: %w", err)
+		}
+		if n, _ := reply.(int64); n <= int64(s.maxVersions) {
+			return nil
+		}
+		reply, err = s.do(ctx, "RPOP", s.key("history"))
+		if err != nil {
+			return fmt.Errorf("failed to trim schema history: %w", err)
+		}
+		if oldest, ok := reply.(string); ok {
+			if _, err := s.do(ctx, "DEL", s.key("version:"+oldest)); err != nil {
+				return fmt.Errorf("failed to delete schema version %s: %w", oldest, err)
+			}
+		}
+	}
+}
+
+// makeCurrent points current at version and notifies subscribers.
+func (s *RedisStore) makeCurrent(ctx context.Context, version int64) error {
+	number := strconv.FormatInt(version, 10)
+	if _, err := s.do(ctx, "SET", s.key("current"), number); err != nil {
+		return fmt.Errorf("failed to set current schema version: %w", err)
+	}
+	if _, err := s.do(ctx, "PUBLISH", s.key("updates"), number); err != nil {
+		return fmt.Errorf("failed to notify schema version %d: %w", version, err)
+	}
+	return nil
+}
+
+// get returns the stored version, or nil when it does not exist.
+func (s *RedisStore) get(ctx context.Context, version int64) (*SchemaVersion, error) {
+	reply, err := s.do(ctx, "GET", s.versionKey(version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version %d: %w", version, err)
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, nil
+	}
+	var v SchemaVersion
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return nil, fmt.Errorf("failed to decode schema version %d: %w", version, err)
+	}
+	return &v, nil
+}
+
+// Current implements Store.
+func (s *RedisStore) Current(ctx context.Context) (*SchemaVersion, error) {
+	reply, err := s.do(ctx, "GET", s.key("current"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current schema version: %w", err)
+	}
+	number, ok := reply.(string)
+	if !ok {
+		return nil, nil
+	}
+	version, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid current schema version %q", number)
+	}
+	return s.get(ctx, version)
+}
+
+// Rollback implements Store.
+func (s *RedisStore) Rollback(ctx context.Context, version int64) (*SchemaVersion, error) {
+	v, err := s.get(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+	if err := s.makeCurrent(ctx, version); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// History implements Store.
+func (s *RedisStore) History(ctx context.Context) ([]*SchemaVersion, error) {
+	reply, err := s.do(ctx, "LRANGE", s.key("history"), "0", "-1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema history: %w", err)
+	}
+	numbers, _ := reply.([]interface{})
+
+	versions := make([]*SchemaVersion, 0, len(numbers))
+	for _, n := range numbers {
+		number, _ := n.(string)
+		version, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			continue
+		}
+		v, err := s.get(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// Subscribe implements Store. It reconnects after connection failures and returns
+// nil once ctx is done. After every (re)subscription the current version is read, and
+// fn is called when it is not the last version delivered, so versions published while
+// the connection was down are not missed.
+func (s *RedisStore) Subscribe(ctx context.Context, fn func(*SchemaVersion)) error {
+	var last int64
+	deliver := func(v *SchemaVersion) {
+		if v != nil && v.Version != last {
+			last = v.Version
+			fn(v)
+		}
+	}
+	for {
+		err := s.subscribe(ctx, deliver)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("redis schema subscription failed, reconnecting: %v", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// subscribe listens on one connection until it fails or ctx is done.
+func (s *RedisStore) subscribe(ctx context.Context, deliver func(*SchemaVersion)) error {
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock the read below when ctx is done.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := conn.Write("SUBSCRIBE", s.key("updates")); err != nil {
+		return err
+	}
+	for {
+		reply, err := conn.Read()
+		if err != nil {
+			return err
+		}
+		msg, ok := reply.([]interface{})
+		if !ok || len(msg) != 3 {
+			continue
+		}
+		if msg[0] == "subscribe" {
+			// Catch up with the versions published before the subscription took effect.
+			current, err := s.Current(ctx)
+			if err != nil {
+				log.Printf("failed to load the current schema version: %v", err)
+				continue
+			}
+			deliver(current)
+			continue
+		}
+		if msg[0] != "message" {
+			continue
+		}
+		number, _ := msg[2].(string)
+		version, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			continue
+		}
+		v, err := s.get(ctx, version)
+		if err != nil {
+			log.Printf("failed to load schema version %d: %v", version, err)
+			continue
+		}
+		deliver(v)
+	}
+}
+
+// Close implements Store.
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,397 @@
+package registry_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n9te9/go-graphql-federation-gateway/registry"
+)
+
+// fakeRedis implements the subset of Redis used by RedisStore.
+type fakeRedis struct {
+	ln net.Listener
+
+	mu          sync.Mutex
+	strings     map[string]string
+	lists       map[string][]string
+	subscribers map[string][]net.Conn
+	auth        []string // arguments of the last AUTH command
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	return startFakeRedis(t, ln)
+}
+
+// newFakeRedisTLS starts a fake Redis behind TLS and returns it with the path of a PEM
+// file holding its certificate.
+func newFakeRedisTLS(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+	return startFakeRedis(t, tls.NewListener(ln, srv.TLS)), caFile
+}
+
+func startFakeRedis(t *testing.T, ln net.Listener) *fakeRedis {
+	t.Helper()
+	f := &fakeRedis{
+		ln:          ln,
+		strings:     make(map[string]string),
+		lists:       make(map[string][]string),
+		subscribers: make(map[string][]net.Conn),
+	}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) addr() string {
+	return f.ln.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		conn.Write([]byte(f.exec(conn, args))) //nolint:errcheck
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func (f *fakeRedis) exec(conn net.Conn, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		v, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SET":
+		f.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case "INCR":
+		n, _ := strconv.ParseInt(f.strings[args[1]], 10, 64)
+		n++
+		f.strings[args[1]] = strconv.FormatInt(n, 10)
+		return fmt.Sprintf(":%d\r\n", n)
+	case "DEL":
+		_, ok := f.strings[args[1]]
+		delete(f.strings, args[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "LPUSH":
+		f.lists[args[1]] = append([]string{args[2]}, f.lists[args[1]]...)
+		return fmt.Sprintf(":%d\r\n", len(f.lists[args[1]]))
+	case "LLEN":
+		return fmt.Sprintf(":%d\r\n", len(f.lists[args[1]]))
+	case "RPOP":
+		list := f.lists[args[1]]
+		if len(list) == 0 {
+			return "$-1\r\n"
+		}
+		f.lists[args[1]] = list[:len(list)-1]
+		return bulk(list[len(list)-1])
+	case "LRANGE":
+		list := f.lists[args[1]]
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "*%d\r\n", len(list))
+		for _, item := range list {
+			sb.WriteString(bulk(item))
+		}
+		return sb.String()
+	case "PUBLISH":
+		for _, sub := range f.subscribers[args[1]] {
+			sub.Write([]byte("*3\r\n" + bulk("message") + bulk(args[1]) + bulk(args[2]))) //nolint:errcheck
+		}
+		return fmt.Sprintf(":%d\r\n", len(f.subscribers[args[1]]))
+	case "AUTH":
+		f.auth = args[1:]
+		return "+OK\r\n"
+	case "SUBSCRIBE":
+		f.subscribers[args[1]] = append(f.subscribers[args[1]], conn)
+		return "*3\r\n" + bulk("subscribe") + bulk(args[1]) + ":1\r\n"
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func (f *fakeRedis) subscriberCount(channel string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subscribers[channel])
+}
+
+// dropSubscribers closes the connections subscribed to channel, as a Redis failover would.
+func (f *fakeRedis) dropSubscribers(channel string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.subscribers[channel] {
+		conn.Close()
+	}
+	delete(f.subscribers, channel)
+}
+
+// waitForSubscriber waits until a connection is subscribed to channel.
+func (f *fakeRedis) waitForSubscriber(t *testing.T, channel string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for f.subscriberCount(channel) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRedisStore_PublishAndRollback(t *testing.T) {
+	f := newFakeRedis(t)
+	store := registry.NewRedisStore(registry.StoreOption{Addr: f.addr(), MaxVersions: 2})
+	defer store.Close()
+	ctx := context.Background()
+
+	current, err := store.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current failed: %v", err)
+	}
+	if current != nil {
+		t.Fatalf("expected no current version, got %+v", current)
+	}
+
+	for i := 1; i <= 3; i++ {
+		v, err := store.Publish(ctx, map[string]string{"products": fmt.Sprintf("type Query { v%d: Int }", i)})
+		if err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		if v.Version != int64(i) {
+			t.Errorf("expected version %d, got %d", i, v.Version)
+		}
+	}
+
+	current, err = store.Current(ctx)
+	if err != nil {
+		t.Fatalf("Current failed: %v", err)
+	}
+	if current == nil || current.Version != 3 || current.SDLs["products"] != "type Query { v3: Int }" {
+		t.Fatalf("expected version 3 to be current, got %+v", current)
+	}
+	if current.Hash != registry.SchemaHash(current.SDLs) {
+		t.Errorf("expected the version hash to match its SDLs")
+	}
+
+	if _, err := store.Rollback(ctx, 2); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	current, _ = store.Current(ctx)
+	if current == nil || current.Version != 2 {
+		t.Errorf("expected version 2 to be current after rollback, got %+v", current)
+	}
+
+	history, err := store.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 3 || history[1].Version != 2 {
+		t.Errorf("expected history [3 2], got %v", history)
+	}
+
+	// Only MaxVersions versions are kept.
+	if _, err := store.Rollback(ctx, 1); err == nil {
+		t.Error("expected rollback to a trimmed version to fail")
+	}
+}
+
+func TestRedisStore_Subscribe(t *testing.T) {
+	f := newFakeRedis(t)
+	publisher := registry.NewRedisStore(registry.StoreOption{Addr: f.addr()})
+	defer publisher.Close()
+	subscriber := registry.NewRedisStore(registry.StoreOption{Addr: f.addr()})
+	defer subscriber.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan *registry.SchemaVersion, 1)
+	done := make(chan struct{})
+	go func() {
+		subscriber.Subscribe(ctx, func(v *registry.SchemaVersion) { received <- v }) //nolint:errcheck
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for f.subscriberCount("gateway:schema:updates") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	published, err := publisher.Publish(context.Background(), map[string]string{"products": "type Query { a: Int }"})
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	select {
+	case v := <-received:
+		if v.Version != published.Version || v.SDLs["products"] != "type Query { a: Int }" {
+			t.Errorf("unexpected version %+v", v)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the subscriber to receive the published version")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Subscribe to return after cancellation")
+	}
+}
+
+func TestRedisStore_SubscribeCatchesUpAfterReconnect(t *testing.T) {
+	f := newFakeRedis(t)
+	publisher := registry.NewRedisStore(registry.StoreOption{Addr: f.addr()})
+	defer publisher.Close()
+	subscriber := registry.NewRedisStore(registry.StoreOption{Addr: f.addr()})
+	defer subscriber.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan *registry.SchemaVersion, 4)
+	go subscriber.Subscribe(ctx, func(v *registry.SchemaVersion) { received <- v }) //nolint:errcheck
+
+	f.waitForSubscriber(t, "gateway:schema:updates")
+
+	// The version is published while the subscription is down, so no message reaches it.
+	f.dropSubscribers("gateway:schema:updates")
+	published, err := publisher.Publish(context.Background(), map[string]string{"products": "type Query { a: Int }"})
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	select {
+	case v := <-received:
+		if v.Version != published.Version {
+			t.Errorf("expected version %d after reconnecting, got %d", published.Version, v.Version)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected the version published during the disconnect to be delivered")
+	}
+
+	f.waitForSubscriber(t, "gateway:schema:updates")
+	select {
+	case v := <-received:
+		t.Errorf("expected version %d to be delivered once, got it again", v.Version)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRedisStore_TLS(t *testing.T) {
+	f, caFile := newFakeRedisTLS(t)
+	store := registry.NewRedisStore(registry.StoreOption{
+		Addr:     f.addr(),
+		Username: "gateway",
+		Password: "secret",
+		TLS:      registry.RedisTLSOption{Enable: true, CAFile: caFile},
+	})
+	defer store.Close()
+
+	published, err := store.Publish(context.Background(), map[string]string{"products": "type Query { a: Int }"})
+	if err != nil {
+		t.Fatalf("Publish over TLS failed: %v", err)
+	}
+	current, err := store.Current(context.Background())
+	if err != nil || current == nil || current.Version != published.Version {
+		t.Fatalf("expected current version %d, got %v (err %v)", published.Version, current, err)
+	}
+
+	f.mu.Lock()
+	auth := f.auth
+	f.mu.Unlock()
+	if fmt.Sprint(auth) != "[gateway secret]" {
+		t.Errorf("expected AUTH with the ACL user, got %v", auth)
+	}
+
+	plain := registry.NewRedisStore(registry.StoreOption{Addr: f.addr(), TLS: registry.RedisTLSOption{Enable: true}})
+	defer plain.Close()
+	if _, err := plain.Current(context.Background()); err == nil {
+		t.Error("expected a certificate from an unknown CA to be rejected")
+	}
+
+	if _, err := registry.NewStore(registry.StoreOption{
+		Backend: "redis",
+		Addr:    f.addr(),
+		TLS:     registry.RedisTLSOption{Enable: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+	}); err == nil {
+		t.Error("expected NewStore to reject a missing CA file")
+	}
+}
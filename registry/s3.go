@@ -0,0 +1,178 @@
+package registry
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unsignedPayload is the x-amz-content-sha256 value for requests whose body is not hashed.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Source downloads the SDL from an S3 (or S3-compatible) bucket. Requests are signed
+// with AWS Signature Version 4 when credentials are set; otherwise the object must be public.
+type S3Source struct {
+	Bucket   string
+	Key      string
+	Region   string
+	Endpoint string // Path-style endpoint, e.g. http://minio:9000; virtual-hosted AWS URLs when empty
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	Client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Source creates an S3 source. The region and credentials default to the
+// AWS_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN variables.
+func NewS3Source(bucket, key, region, endpoint string, client *http.Client) *S3Source {
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Source{
+		Bucket:          bucket,
+		Key:             key,
+		Region:          region,
+		Endpoint:        endpoint,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Client:          client,
+		now:             time.Now,
+	}
+}
+
+// Name implements SchemaSource.
+func (s *S3Source) Name() string {
+	return "s3://" + s.Bucket + "/" + s.Key
+}
+
+// Fetch implements SchemaSource.
+func (s *S3Source) Fetch(ctx context.Context) (string, error) {
+	var rawURL string
+	if s.Endpoint != "" {
+		rawURL = strings.TrimSuffix(s.Endpoint, "/") + "/" + s.Bucket + "/" + awsURIEncode(s.Key, false)
+	} else {
+		rawURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.Region, awsURIEncode(s.Key, false))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.AccessKeyID != "" && s.SecretAccessKey != "" {
+		now := time.Now
+		if s.now != nil {
+			now = s.now
+		}
+		req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+		if s.SessionToken != "" {
+			req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+		}
+		signV4(req, s.AccessKeyID, s.SecretAccessKey, s.Region, "s3", unsignedPayload, now().UTC())
+	}
+	return doGet(s.Client, req)
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req, signing the host
+// and every header already set on req.
+func signV4(req *http.Request, accessKeyID, secretAccessKey, region, service, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.Path, false),
+		canonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the query string of req with sorted, URI-encoded parameters.
+func canonicalQuery(req *http.Request) string {
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes every byte except unreserved characters, and '/' unless
+// encodeSlash is set, as required by Signature Version 4.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
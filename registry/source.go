@@ -0,0 +1,141 @@
+// Package registry loads subgraph schemas from pluggable sources (local files, HTTP(S)
+// URLs, S3 and GCS buckets, Kubernetes ConfigMaps) and watches them for changes.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SchemaSource loads the SDL of a subgraph.
+type SchemaSource interface {
+	// Name describes the source in logs and errors, e.g. "s3://bucket/products.graphql".
+	Name() string
+	// Fetch returns the current SDL.
+	Fetch(ctx context.Context) (string, error)
+}
+
+// SourceOption configures a schema source in gateway.yaml.
+type SourceOption struct {
+	Type         string `yaml:"type" validate:"oneof=file|http|s3|gcs|configmap"` // file, http, s3, gcs or configmap
+	PollInterval string `yaml:"poll_interval" validate:"duration"`                // Polls the source for changes when set
+	MaxStaleness string `yaml:"max_staleness" validate:"duration"`                // Marks the subgraph degraded when polls fail for longer
+
+	Path    string            `yaml:"path"`    // file: path of the SDL file
+	URL     string            `yaml:"url"`     // http: URL returning the SDL
+	Headers map[string]string `yaml:"headers"` // http: extra request headers
+
+	Bucket   string `yaml:"bucket"`   // s3, gcs: bucket name
+	Key      string `yaml:"key"`      // s3, gcs: object name; configmap: data key
+	Region   string `yaml:"region"`   // s3: bucket region, defaults to AWS_REGION or us-east-1
+	Endpoint string `yaml:"endpoint"` // s3, gcs: API endpoint override (e.g. MinIO or an emulator)
+
+	Namespace string `yaml:"namespace"`  // configmap: defaults to the pod namespace
+	ConfigMap string `yaml:"configmap"`  // configmap: ConfigMap name
+	APIServer string `yaml:"api_server"` // configmap: defaults to the in-cluster API server
+
+	TokenFile     string `yaml:"token_file"`     // gcs, configmap: file holding a bearer token
+	MetadataToken bool   `yaml:"metadata_token"` // gcs: fetch a token from the GCE metadata server
+}
+
+// Interval returns the poll interval, or zero when the source is not polled.
+func (o SourceOption) Interval() (time.Duration, error) {
+	if o.PollInterval == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(o.PollInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid poll_interval %q: %w", o.PollInterval, err)
+	}
+	return d, nil
+}
+
+// Staleness returns how long polls may fail before the subgraph is marked degraded,
+// or zero when it never is.
+func (o SourceOption) Staleness() (time.Duration, error) {
+	if o.MaxStaleness == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(o.MaxStaleness)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_staleness %q: %w", o.MaxStaleness, err)
+	}
+	return d, nil
+}
+
+// New creates the schema source described by opt. httpClient is used by the remote
+// sources; http.DefaultClient is used when it is nil.
+func New(opt SourceOption, httpClient *http.Client) (SchemaSource, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	switch opt.Type {
+	case "file":
+		if opt.Path == "" {
+			return nil, fmt.Errorf("file source requires path")
+		}
+		return &FileSource{Path: opt.Path}, nil
+	case "http":
+		if opt.URL == "" {
+			return nil, fmt.Errorf("http source requires url")
+		}
+		return &HTTPSource{URL: opt.URL, Headers: opt.Headers, Client: httpClient}, nil
+	case "s3":
+		if opt.Bucket == "" || opt.Key == "" {
+			return nil, fmt.Errorf("s3 source requires bucket and key")
+		}
+		return NewS3Source(opt.Bucket, opt.Key, opt.Region, opt.Endpoint, httpClient), nil
+	case "gcs":
+		if opt.Bucket == "" || opt.Key == "" {
+			return nil, fmt.Errorf("gcs source requires bucket and key")
+		}
+		return &GCSSource{
+			Bucket:        opt.Bucket,
+			Object:        opt.Key,
+			Endpoint:      opt.Endpoint,
+			TokenFile:     opt.TokenFile,
+			MetadataToken: opt.MetadataToken,
+			Client:        httpClient,
+		}, nil
+	case "configmap":
+		if opt.ConfigMap == "" || opt.Key == "" {
+			return nil, fmt.Errorf("configmap source requires configmap and key")
+		}
+		return NewConfigMapSource(opt.Namespace, opt.ConfigMap, opt.Key, opt.APIServer, opt.TokenFile)
+	default:
+		return nil, fmt.Errorf("unknown schema source type %q", opt.Type)
+	}
+}
+
+// Watch polls src every interval until ctx is done and calls onChange with the SDL
+// whenever it differs from the previous one, starting from last. Fetch errors are
+// logged and the previous SDL is kept.
+func Watch(ctx context.Context, src SchemaSource, interval time.Duration, last string, onChange func(sdl string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		sdl, err := src.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("failed to poll schema source %s: %v", src.Name(), err)
+			}
+			continue
+		}
+		if sdl == last {
+			continue
+		}
+		last = sdl
+		onChange(sdl)
+	}
+}
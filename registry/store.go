@@ -0,0 +1,114 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrVersionNotFound is returned when rolling back to a version that is not kept.
+var ErrVersionNotFound = errors.New("schema version not found")
+
+// SchemaVersion is a set of subgraph SDLs published to a Store. Every replica that
+// installs a version serves the same composed supergraph.
+type SchemaVersion struct {
+	Version   int64             `json:"version"`
+	Hash      string            `json:"hash"`
+	CreatedAt time.Time         `json:"created_at"`
+	Digests   map[string]string `json:"digests"` // subgraph name → SDL digest
+	SDLs      map[string]string `json:"sdls"`
+}
+
+// newSchemaVersion describes sdls as version number.
+func newSchemaVersion(number int64, sdls map[string]string, now time.Time) *SchemaVersion {
+	digests := make(map[string]string, len(sdls))
+	for name, sdl := range sdls {
+		sum := sha256.Sum256([]byte(sdl))
+		digests[name] = hex.EncodeToString(sum[:])
+	}
+	return &SchemaVersion{
+		Version:   number,
+		Hash:      SchemaHash(sdls),
+		CreatedAt: now.UTC(),
+		Digests:   digests,
+		SDLs:      sdls,
+	}
+}
+
+// Store shares schema versions between gateway replicas.
+type Store interface {
+	// Publish stores sdls as a new version, makes it current and notifies subscribers.
+	Publish(ctx context.Context, sdls map[string]string) (*SchemaVersion, error)
+	// Current returns the current version, or nil when nothing has been published.
+	Current(ctx context.Context) (*SchemaVersion, error)
+	// Rollback makes a previously published version current and notifies subscribers.
+	Rollback(ctx context.Context, version int64) (*SchemaVersion, error)
+	// History returns the versions kept for rollback, newest first.
+	History(ctx context.Context) ([]*SchemaVersion, error)
+	// Subscribe calls fn with every version that becomes current until ctx is done.
+	Subscribe(ctx context.Context, fn func(*SchemaVersion)) error
+	// Close releases the connections of the store.
+	Close() error
+}
+
+// StoreOption configures the schema registry in gateway.yaml.
+type StoreOption struct {
+	Backend     string `yaml:"backend" validate:"oneof=memory|redis"` // memory (default) or redis
+	Addr        string `yaml:"addr"`                                  // redis: host:port
+	Username    string `yaml:"username"`                              // redis: ACL user sent with AUTH, for Redis 6 and later
+	Password    string `yaml:"password"`                              // redis: AUTH password
+	DB          int    `yaml:"db"`                                    // redis: database number
+	Prefix      string `yaml:"prefix"`                                // Key and channel prefix, defaults to gateway:schema
+	MaxVersions int    `yaml:"max_versions"`                          // Versions kept for rollback, defaults to 20
+
+	// TLS encrypts the connections to Redis, as required by most managed Redis services.
+	TLS RedisTLSOption `yaml:"tls"`
+}
+
+// RedisTLSOption configures TLS for the connections of the redis backend.
+type RedisTLSOption struct {
+	Enable             bool   `yaml:"enable"`
+	ServerName         string `yaml:"server_name"`          // Name verified in the server certificate, defaults to the host of Addr
+	CAFile             string `yaml:"ca_file"`              // PEM bundle of the CAs trusted instead of the system roots
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // Skip certificate verification, for testing only
+}
+
+// NewStore creates the store described by opt.
+func NewStore(opt StoreOption) (Store, error) {
+	switch opt.Backend {
+	case "", "memory":
+		return NewMemoryStore(opt.MaxVersions), nil
+	case "redis":
+		if opt.Addr == "" {
+			return nil, fmt.Errorf("redis registry requires addr")
+		}
+		if _, err := opt.TLS.config(opt.Addr); err != nil {
+			return nil, err
+		}
+		return NewRedisStore(opt), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend %q", opt.Backend)
+	}
+}
+
+// SchemaHash returns a digest identifying a set of subgraph SDLs.
+func SchemaHash(sdls map[string]string) string {
+	names := make([]string, 0, len(sdls))
+	for name := range sdls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(sdls[name]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
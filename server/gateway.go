@@ -12,7 +12,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/goccy/go-yaml"
 	"github.com/n9te9/go-graphql-federation-gateway/gateway"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
@@ -20,7 +19,7 @@ import (
 const gatewayVersion = "v0.1.0"
 
 func Run() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	logger := slog.New(gateway.NewContextLogHandler(slog.NewJSONHandler(os.Stdout, nil)))
 	slog.SetDefault(logger)
 
 	settings, err := loadGatewaySetting()
@@ -28,10 +27,11 @@ func Run() {
 		log.Fatalf("failed to load gateway settings: %v", err)
 	}
 
-	gw, err := gateway.NewGateway(*settings)
+	gw, err := newGateway(*settings)
 	if err != nil {
 		log.Fatalf("failed to build gateway: %v", err)
 	}
+	defer gw.Close()
 
 	gwHandler := http.Handler(gw)
 	if settings.Opentelemetry.TracingSetting.Enable {
@@ -43,9 +43,9 @@ func Run() {
 		log.Fatalf("failed to parse timeout duration: %v", err)
 	}
 
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", settings.Port),
-		Handler: gwHandler,
+	servers, err := gateway.NewServers(*settings, gwHandler)
+	if err != nil {
+		log.Fatalf("failed to configure listeners: %v", err)
 	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill, syscall.SIGTERM)
@@ -56,12 +56,14 @@ func Run() {
 		log.Fatalf("failed to initialize tracer: %v", err)
 	}
 
-	go func() {
-		log.Printf("starting gateway server on port %d", settings.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("gateway server failed: %v", err)
-		}
-	}()
+	for _, srv := range servers {
+		go func() {
+			log.Printf("starting gateway server on %s", srv.Addr)
+			if err := listenAndServe(srv); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("gateway server failed: %v", err)
+			}
+		}()
+	}
 
 	<-ctx.Done()
 
@@ -69,8 +71,10 @@ func Run() {
 	defer cancel()
 
 	log.Println("shutting down gateway server...")
-	if err := srv.Shutdown(timeoutCtx); err != nil {
-		log.Fatalf("failed to shutdown gateway server: %v", err)
+	for _, srv := range servers {
+		if err := srv.Shutdown(timeoutCtx); err != nil {
+			log.Fatalf("failed to shutdown gateway server: %v", err)
+		}
 	}
 
 	if err := shutdown(timeoutCtx); err != nil {
@@ -80,6 +84,26 @@ func Run() {
 	log.Println("gateway server stopped")
 }
 
+// listenAndServe serves srv, terminating TLS when it has a TLS configuration.
+func listenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// newGateway builds the gateway described by settings, routing to one gateway per
+// tenant when tenants are configured.
+func newGateway(settings gateway.GatewayOption) (interface {
+	http.Handler
+	Close() error
+}, error) {
+	if len(settings.Tenants) > 0 {
+		return gateway.NewTenantGateway(settings)
+	}
+	return gateway.NewGateway(settings)
+}
+
 func loadGatewaySetting() (*gateway.GatewayOption, error) {
 	f, err := os.Open("gateway.yaml")
 	if err != nil {
@@ -92,10 +116,10 @@ func loadGatewaySetting() (*gateway.GatewayOption, error) {
 		return nil, fmt.Errorf("failed to read gateway settings file: %w", err)
 	}
 
-	var settings gateway.GatewayOption
-	if err := yaml.Unmarshal(b, &settings); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal gateway settings: %w", err)
+	settings, err := gateway.LoadGatewayOption(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gateway settings:\n%w", err)
 	}
 
-	return &settings, nil
+	return settings, nil
 }
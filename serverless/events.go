@@ -0,0 +1,69 @@
+package serverless
+
+// APIGatewayV2Request is the payload of an API Gateway HTTP API invocation (payload
+// format version 2.0).
+type APIGatewayV2Request struct {
+	Version               string                     `json:"version"`
+	RawPath               string                     `json:"rawPath"`
+	RawQueryString        string                     `json:"rawQueryString"`
+	Cookies               []string                   `json:"cookies,omitempty"`
+	Headers               map[string]string          `json:"headers"`
+	RequestContext        APIGatewayV2RequestContext `json:"requestContext"`
+	Body                  string                     `json:"body"`
+	IsBase64Encoded       bool                       `json:"isBase64Encoded"`
+	QueryStringParameters map[string]string          `json:"queryStringParameters,omitempty"`
+}
+
+// APIGatewayV2RequestContext is the request context of an APIGatewayV2Request.
+type APIGatewayV2RequestContext struct {
+	RequestID string                  `json:"requestId"`
+	HTTP      APIGatewayV2HTTPContext `json:"http"`
+}
+
+// APIGatewayV2HTTPContext describes the HTTP request of an APIGatewayV2Request.
+type APIGatewayV2HTTPContext struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	SourceIP string `json:"sourceIp"`
+}
+
+// APIGatewayV2Response is the response to an API Gateway HTTP API invocation.
+type APIGatewayV2Response struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Cookies         []string          `json:"cookies,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// ALBRequest is the payload of an Application Load Balancer target group invocation.
+// MultiValueHeaders and MultiValueQueryStringParameters are set instead of Headers and
+// QueryStringParameters when the target group has multi-value headers enabled.
+type ALBRequest struct {
+	HTTPMethod                      string              `json:"httpMethod"`
+	Path                            string              `json:"path"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters,omitempty"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters,omitempty"`
+	Headers                         map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders,omitempty"`
+	RequestContext                  ALBRequestContext   `json:"requestContext"`
+	Body                            string              `json:"body"`
+	IsBase64Encoded                 bool                `json:"isBase64Encoded"`
+}
+
+// ALBRequestContext is the request context of an ALBRequest.
+type ALBRequestContext struct {
+	ELB struct {
+		TargetGroupArn string `json:"targetGroupArn"`
+	} `json:"elb"`
+}
+
+// ALBResponse is the response to an Application Load Balancer invocation.
+type ALBResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	StatusDescription string              `json:"statusDescription"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
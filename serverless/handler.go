@@ -0,0 +1,279 @@
+// Package serverless runs the gateway in AWS Lambda behind an API Gateway HTTP API
+// (payload format 2.0) or an Application Load Balancer.
+//
+// Handler implements the Invoke method of the aws-lambda-go lambda.Handler interface,
+// so it is started with lambda.StartHandler(serverless.NewGatewayHandler(settings)).
+package serverless
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/goccy/go-json"
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Handler serves Lambda invocations with an http.Handler.
+type Handler struct {
+	build func() (http.Handler, error)
+
+	mu      sync.Mutex
+	handler http.Handler
+}
+
+// NewHandler returns a Handler serving invocations with h.
+func NewHandler(h http.Handler) *Handler {
+	return &Handler{handler: h}
+}
+
+// NewLazyHandler returns a Handler that calls build on the first invocation instead of
+// during initialization, keeping cold starts short. When build fails, the invocation
+// fails and the next one calls build again.
+func NewLazyHandler(build func() (http.Handler, error)) *Handler {
+	return &Handler{build: build}
+}
+
+// NewGatewayHandler returns a Handler that builds the gateway described by settings on
+// the first invocation. Setting settings.SupergraphFile to a bundle shipped in the
+// deployment package (see gateway.ComposeSupergraph) avoids fetching subgraph SDLs
+// during that invocation.
+func NewGatewayHandler(settings gateway.GatewayOption) *Handler {
+	return NewLazyHandler(func() (http.Handler, error) {
+		gw, err := gateway.NewGateway(settings)
+		if err != nil {
+			return nil, err
+		}
+		if settings.Opentelemetry.TracingSetting.Enable {
+			return otelhttp.NewHandler(gw, settings.ServiceName), nil
+		}
+		return gw, nil
+	})
+}
+
+// httpHandler returns the handler, building it if needed.
+func (h *Handler) httpHandler() (http.Handler, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.handler != nil {
+		return h.handler, nil
+	}
+	handler, err := h.build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build handler: %w", err)
+	}
+	h.handler = handler
+	return handler, nil
+}
+
+// Invoke serves a raw Lambda invocation, detecting whether payload is an API Gateway
+// HTTP API or an ALB event, and returns the matching response.
+func (h *Handler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	var probe struct {
+		Version        string `json:"version"`
+		RequestContext struct {
+			HTTP *struct{} `json:"http"`
+			ELB  *struct{} `json:"elb"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	switch {
+	case probe.RequestContext.ELB != nil:
+		var req ALBRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("failed to decode ALB event: %w", err)
+		}
+		resp, err := h.ServeALB(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(resp)
+	case probe.Version == "2.0" || probe.RequestContext.HTTP != nil:
+		var req APIGatewayV2Request
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("failed to decode API Gateway event: %w", err)
+		}
+		resp, err := h.ServeAPIGatewayV2(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(resp)
+	default:
+		return nil, fmt.Errorf("unsupported event: expected an API Gateway HTTP API (2.0) or ALB event")
+	}
+}
+
+// ServeAPIGatewayV2 serves an API Gateway HTTP API invocation.
+func (h *Handler) ServeAPIGatewayV2(ctx context.Context, event APIGatewayV2Request) (APIGatewayV2Response, error) {
+	header := make(http.Header, len(event.Headers)+1)
+	for k, v := range event.Headers {
+		header.Set(k, v)
+	}
+	if len(event.Cookies) > 0 {
+		header.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+
+	path := event.RawPath
+	if path == "" {
+		path = event.RequestContext.HTTP.Path
+	}
+	req, err := newRequest(ctx, event.RequestContext.HTTP.Method, path, event.RawQueryString, header, event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return APIGatewayV2Response{}, err
+	}
+	if event.RequestContext.HTTP.SourceIP != "" {
+		req.RemoteAddr = event.RequestContext.HTTP.SourceIP
+	}
+
+	rw, err := h.serve(req)
+	if err != nil {
+		return APIGatewayV2Response{}, err
+	}
+
+	resp := APIGatewayV2Response{StatusCode: rw.status, Headers: make(map[string]string, len(rw.header))}
+	for k, values := range rw.header {
+		if k == "Set-Cookie" {
+			resp.Cookies = append(resp.Cookies, values...)
+			continue
+		}
+		resp.Headers[k] = strings.Join(values, ",")
+	}
+	resp.Body, resp.IsBase64Encoded = encodeBody(rw.body.Bytes())
+	return resp, nil
+}
+
+// ServeALB serves an Application Load Balancer invocation. The response uses
+// multi-value headers when the request does.
+func (h *Handler) ServeALB(ctx context.Context, event ALBRequest) (ALBResponse, error) {
+	multiValue := event.MultiValueHeaders != nil
+
+	header := make(http.Header)
+	for k, v := range event.Headers {
+		header.Set(k, v)
+	}
+	for k, values := range event.MultiValueHeaders {
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+
+	// ALB passes query parameters as they appear in the request, still URL-encoded.
+	var query []string
+	for k, v := range event.QueryStringParameters {
+		query = append(query, k+"="+v)
+	}
+	for k, values := range event.MultiValueQueryStringParameters {
+		for _, v := range values {
+			query = append(query, k+"="+v)
+		}
+	}
+
+	req, err := newRequest(ctx, event.HTTPMethod, event.Path, strings.Join(query, "&"), header, event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return ALBResponse{}, err
+	}
+
+	rw, err := h.serve(req)
+	if err != nil {
+		return ALBResponse{}, err
+	}
+
+	resp := ALBResponse{
+		StatusCode:        rw.status,
+		StatusDescription: fmt.Sprintf("%d %s", rw.status, http.StatusText(rw.status)),
+	}
+	if multiValue {
+		resp.MultiValueHeaders = map[string][]string(rw.header)
+	} else {
+		resp.Headers = make(map[string]string, len(rw.header))
+		for k, values := range rw.header {
+			resp.Headers[k] = strings.Join(values, ",")
+		}
+	}
+	resp.Body, resp.IsBase64Encoded = encodeBody(rw.body.Bytes())
+	return resp, nil
+}
+
+// serve runs req through the handler.
+func (h *Handler) serve(req *http.Request) (*responseWriter, error) {
+	handler, err := h.httpHandler()
+	if err != nil {
+		return nil, err
+	}
+	rw := &responseWriter{header: make(http.Header)}
+	handler.ServeHTTP(rw, req)
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	return rw, nil
+}
+
+// newRequest builds the HTTP request of an event.
+func newRequest(ctx context.Context, method, path, rawQuery string, header http.Header, body string, base64Encoded bool) (*http.Request, error) {
+	payload := []byte(body)
+	if base64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode request body: %w", err)
+		}
+		payload = decoded
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request path %q: %w", path, err)
+	}
+	u.RawQuery = rawQuery
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header = header
+	req.Host = header.Get("Host")
+	req.RequestURI = u.RequestURI()
+	return req, nil
+}
+
+// encodeBody returns body as a string, base64 encoded unless it is valid UTF-8.
+func encodeBody(body []byte) (string, bool) {
+	if utf8.Valid(body) {
+		return string(body), false
+	}
+	return base64.StdEncoding.EncodeToString(body), true
+}
+
+// responseWriter buffers a response.
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
@@ -0,0 +1,254 @@
+package serverless_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/n9te9/go-graphql-federation-gateway/gateway"
+	"github.com/n9te9/go-graphql-federation-gateway/serverless"
+)
+
+// echoHandler answers with the request it received.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Set-Cookie", "a=1")
+	w.Header().Add("Set-Cookie", "b=2")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"query":  r.URL.RawQuery,
+		"host":   r.Host,
+		"cookie": r.Header.Get("Cookie"),
+		"body":   string(body),
+	})
+})
+
+func decodeEcho(t *testing.T, body string) map[string]string {
+	t.Helper()
+	var got map[string]string
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("failed to decode body %q: %v", body, err)
+	}
+	return got
+}
+
+func TestHandler_APIGatewayV2(t *testing.T) {
+	event := `{
+		"version": "2.0",
+		"rawPath": "/graphql",
+		"rawQueryString": "a=1&b=x%20y",
+		"cookies": ["session=abc", "theme=dark"],
+		"headers": {"host": "api.example.com", "content-type": "application/json"},
+		"requestContext": {"requestId": "id", "http": {"method": "POST", "path": "/graphql", "sourceIp": "10.0.0.1"}},
+		"body": "` + base64.StdEncoding.EncodeToString([]byte(`{"query":"{ a }"}`)) + `",
+		"isBase64Encoded": true
+	}`
+
+	out, err := serverless.NewHandler(echoHandler).Invoke(t.Context(), []byte(event))
+	if err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	var resp serverless.APIGatewayV2Response
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", resp.StatusCode)
+	}
+	if resp.Headers["Content-Type"] != "application/json" {
+		t.Errorf("unexpected headers: %v", resp.Headers)
+	}
+	if len(resp.Cookies) != 2 || resp.Cookies[0] != "a=1" || resp.Cookies[1] != "b=2" {
+		t.Errorf("expected Set-Cookie values in cookies, got %v", resp.Cookies)
+	}
+	if _, ok := resp.Headers["Set-Cookie"]; ok {
+		t.Error("expected Set-Cookie to be moved out of headers")
+	}
+
+	got := decodeEcho(t, resp.Body)
+	want := map[string]string{
+		"method": "POST",
+		"path":   "/graphql",
+		"query":  "a=1&b=x%20y",
+		"host":   "api.example.com",
+		"cookie": "session=abc; theme=dark",
+		"body":   `{"query":"{ a }"}`,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, got[k])
+		}
+	}
+}
+
+func TestHandler_ALB(t *testing.T) {
+	t.Run("single-value headers", func(t *testing.T) {
+		event := `{
+			"requestContext": {"elb": {"targetGroupArn": "arn"}},
+			"httpMethod": "POST",
+			"path": "/graphql",
+			"queryStringParameters": {"q": "x%20y"},
+			"headers": {"host": "alb.example.com"},
+			"body": "{}",
+			"isBase64Encoded": false
+		}`
+		out, err := serverless.NewHandler(echoHandler).Invoke(t.Context(), []byte(event))
+		if err != nil {
+			t.Fatalf("Invoke failed: %v", err)
+		}
+		var resp serverless.ALBResponse
+		if err := json.Unmarshal(out, &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusCreated || resp.StatusDescription != "201 Created" {
+			t.Errorf("unexpected status %d %q", resp.StatusCode, resp.StatusDescription)
+		}
+		if resp.MultiValueHeaders != nil {
+			t.Errorf("expected no multi-value headers, got %v", resp.MultiValueHeaders)
+		}
+		if resp.Headers["Set-Cookie"] != "a=1,b=2" {
+			t.Errorf("unexpected headers: %v", resp.Headers)
+		}
+		got := decodeEcho(t, resp.Body)
+		if got["query"] != "q=x%20y" || got["host"] != "alb.example.com" || got["body"] != "{}" {
+			t.Errorf("unexpected request: %v", got)
+		}
+	})
+
+	t.Run("multi-value headers", func(t *testing.T) {
+		event := `{
+			"requestContext": {"elb": {"targetGroupArn": "arn"}},
+			"httpMethod": "GET",
+			"path": "/graphql",
+			"multiValueQueryStringParameters": {"q": ["1", "2"]},
+			"multiValueHeaders": {"host": ["alb.example.com"]},
+			"body": ""
+		}`
+		out, err := serverless.NewHandler(echoHandler).Invoke(t.Context(), []byte(event))
+		if err != nil {
+			t.Fatalf("Invoke failed: %v", err)
+		}
+		var resp serverless.ALBResponse
+		if err := json.Unmarshal(out, &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if cookies := resp.MultiValueHeaders["Set-Cookie"]; len(cookies) != 2 {
+			t.Errorf("expected both Set-Cookie values, got %v", resp.MultiValueHeaders)
+		}
+		if got := decodeEcho(t, resp.Body); got["query"] != "q=1&q=2" {
+			t.Errorf("unexpected query %q", got["query"])
+		}
+	})
+}
+
+func TestHandler_BinaryBody(t *testing.T) {
+	binary := []byte{0xff, 0xfe, 0x00}
+	h := serverless.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary) //nolint:errcheck
+	}))
+
+	resp, err := h.ServeAPIGatewayV2(t.Context(), serverless.APIGatewayV2Request{
+		Version:        "2.0",
+		RawPath:        "/",
+		RequestContext: serverless.APIGatewayV2RequestContext{HTTP: serverless.APIGatewayV2HTTPContext{Method: "GET"}},
+	})
+	if err != nil {
+		t.Fatalf("ServeAPIGatewayV2 failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if !resp.IsBase64Encoded || resp.Body != base64.StdEncoding.EncodeToString(binary) {
+		t.Errorf("expected a base64 encoded body, got %q (encoded=%v)", resp.Body, resp.IsBase64Encoded)
+	}
+}
+
+func TestHandler_UnsupportedEvent(t *testing.T) {
+	if _, err := serverless.NewHandler(echoHandler).Invoke(t.Context(), []byte(`{"Records": []}`)); err == nil {
+		t.Error("expected an error for an unsupported event")
+	}
+}
+
+func TestLazyHandler(t *testing.T) {
+	builds := 0
+	h := serverless.NewLazyHandler(func() (http.Handler, error) {
+		builds++
+		if builds == 1 {
+			return nil, errors.New("subgraph unavailable")
+		}
+		return echoHandler, nil
+	})
+	req := serverless.APIGatewayV2Request{
+		Version:        "2.0",
+		RawPath:        "/",
+		RequestContext: serverless.APIGatewayV2RequestContext{HTTP: serverless.APIGatewayV2HTTPContext{Method: "GET"}},
+	}
+
+	if builds != 0 {
+		t.Fatalf("expected no build before the first invocation, got %d", builds)
+	}
+	if _, err := h.ServeAPIGatewayV2(t.Context(), req); err == nil {
+		t.Fatal("expected the failed build to fail the invocation")
+	}
+	for range 2 {
+		if _, err := h.ServeAPIGatewayV2(t.Context(), req); err != nil {
+			t.Fatalf("ServeAPIGatewayV2 failed: %v", err)
+		}
+	}
+	if builds != 2 {
+		t.Errorf("expected a retry after the failure and no build afterwards, got %d builds", builds)
+	}
+}
+
+func TestGatewayHandler(t *testing.T) {
+	subgraph := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data":{"product":{"name":"Table"}}}`) //nolint:errcheck
+	}))
+	t.Cleanup(subgraph.Close)
+
+	bundle := &gateway.SupergraphBundle{Subgraphs: map[string]string{"products": `
+		type Query {
+			product(id: ID!): Product
+		}
+
+		type Product @key(fields: "id") {
+			id: ID!
+			name: String
+		}`}}
+	path := t.TempDir() + "/supergraph.json"
+	if err := bundle.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	h := serverless.NewGatewayHandler(gateway.GatewayOption{
+		Endpoint:       "/graphql",
+		SupergraphFile: path,
+		Services:       []gateway.GatewayService{{Name: "products", Host: subgraph.URL}},
+	})
+	resp, err := h.ServeAPIGatewayV2(t.Context(), serverless.APIGatewayV2Request{
+		Version:        "2.0",
+		RawPath:        "/graphql",
+		Headers:        map[string]string{"content-type": "application/json"},
+		RequestContext: serverless.APIGatewayV2RequestContext{HTTP: serverless.APIGatewayV2HTTPContext{Method: "POST"}},
+		Body:           `{"query":"{ product(id: \"1\") { name } }"}`,
+	})
+	if err != nil {
+		t.Fatalf("ServeAPIGatewayV2 failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Body, `"name":"Table"`) {
+		t.Errorf("unexpected response %d: %s", resp.StatusCode, resp.Body)
+	}
+}